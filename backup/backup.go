@@ -0,0 +1,312 @@
+// Package backup implements consistent dumps and restores of the application tables.
+//
+// A backup is a gzip-compressed NDJSON stream: the first line is a header describing
+// the dump, followed by one line per row and a closing manifest line. Rows are stored as
+// plain column maps so that soft-deleted rows and columns hidden from the API
+// (deleted_at, message_id) survive a round trip unchanged.
+//
+// The manifest records the row count and a SHA-256 checksum of the row lines of every
+// table, so a backup can be proven intact before it is restored.
+package backup
+
+import (
+	"api-contact-form/models"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// formatVersion is bumped whenever the backup layout changes incompatibly.
+const formatVersion = 2
+
+// Tables lists the tables included in a backup, in restore order.
+var Tables = []string{
+	models.Contact{}.TableName(),
+	models.RejectedSubmission{}.TableName(),
+	models.APIKey{}.TableName(),
+	models.WebhookSubscription{}.TableName(),
+	models.WebhookDelivery{}.TableName(),
+	models.AdminUser{}.TableName(),
+	models.AdminRecoveryCode{}.TableName(),
+	models.AdminSession{}.TableName(),
+	models.AdminLoginEvent{}.TableName(),
+	models.Attachment{}.TableName(),
+	models.IdempotencyKey{}.TableName(),
+	models.AuditLog{}.TableName(),
+	models.ReplyDraft{}.TableName(),
+	models.APIUsage{}.TableName(),
+	models.WebhookOutboxEvent{}.TableName(),
+	models.InboxEntry{}.TableName(),
+	models.AutoReplyTemplate{}.TableName(),
+	models.ExportSchedule{}.TableName(),
+	models.SeenCredential{}.TableName(),
+	models.AbuseReport{}.TableName(),
+	models.FollowUp{}.TableName(),
+	models.SystemChange{}.TableName(),
+	models.ContactStar{}.TableName(),
+	models.EmailMessage{}.TableName(),
+}
+
+// Snapshot describes the content of a backup.
+type Snapshot struct {
+	// CreatedAt is the time the backup transaction started.
+	CreatedAt time.Time `json:"created_at"`
+	// RowCounts is the number of rows per table.
+	RowCounts map[string]int `json:"row_counts"`
+	// Checksums is the hex SHA-256 of the row lines of each table, in dump order.
+	Checksums map[string]string `json:"checksums"`
+}
+
+// header is the first line of a backup.
+type header struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Tables    []string  `json:"tables"`
+}
+
+// record is a row line, or the closing manifest line, of a backup.
+type record struct {
+	Table    string                 `json:"table,omitempty"`
+	Row      map[string]interface{} `json:"row,omitempty"`
+	Manifest *Snapshot              `json:"manifest,omitempty"`
+}
+
+// checksums accumulates the per-table row counts and hashes of a backup.
+type checksums struct {
+	counts map[string]int
+	hashes map[string]hash.Hash
+}
+
+// newChecksums creates an empty accumulator.
+func newChecksums() *checksums {
+	return &checksums{counts: map[string]int{}, hashes: map[string]hash.Hash{}}
+}
+
+// add records a single row line of table.
+func (c *checksums) add(table string, line []byte) {
+	h, ok := c.hashes[table]
+	if !ok {
+		h = sha256.New()
+		c.hashes[table] = h
+	}
+	h.Write(line)
+	c.counts[table]++
+}
+
+// snapshot returns the accumulated counts and checksums.
+func (c *checksums) snapshot(createdAt time.Time) *Snapshot {
+	s := &Snapshot{CreatedAt: createdAt, RowCounts: c.counts, Checksums: map[string]string{}}
+	for table, h := range c.hashes {
+		s.Checksums[table] = hex.EncodeToString(h.Sum(nil))
+	}
+	return s
+}
+
+// Dump writes a backup of all Tables to w and returns its snapshot.
+//
+// All tables are read inside a single read-only, repeatable-read transaction so the dump
+// reflects one consistent point in time even while the API keeps accepting submissions.
+func Dump(db *gorm.DB, w io.Writer) (*Snapshot, error) {
+	gz := gzip.NewWriter(w)
+	sums := newChecksums()
+	createdAt := time.Now().UTC()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := writeLine(gz, header{Version: formatVersion, CreatedAt: createdAt, Tables: Tables}); err != nil {
+			return err
+		}
+
+		for _, table := range Tables {
+			rows, err := tx.Table(table).Order("id").Rows()
+			if err != nil {
+				return fmt.Errorf("read %s: %w", table, err)
+			}
+
+			for rows.Next() {
+				row := map[string]interface{}{}
+				if err := tx.ScanRows(rows, &row); err != nil {
+					rows.Close()
+					return fmt.Errorf("scan %s: %w", table, err)
+				}
+				line, err := json.Marshal(record{Table: table, Row: row})
+				if err != nil {
+					rows.Close()
+					return err
+				}
+				if _, err := gz.Write(append(line, '\n')); err != nil {
+					rows.Close()
+					return err
+				}
+				sums.add(table, line)
+			}
+			if err := rows.Close(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := sums.snapshot(createdAt)
+	if err := writeLine(gz, record{Manifest: snapshot}); err != nil {
+		return nil, err
+	}
+	return snapshot, gz.Close()
+}
+
+// Verify reads a whole backup and checks its row counts and checksums against the manifest.
+// It returns the verified snapshot without touching the database.
+func Verify(r io.Reader) (*Snapshot, error) {
+	return read(r, func(string, map[string]interface{}) error { return nil })
+}
+
+// Restore loads a backup produced by Dump and returns its verified snapshot.
+//
+// Rows keep their original primary keys; rows whose key already exists are skipped, so
+// restoring into a database that still holds part of the data only fills in the gaps.
+// The whole restore runs in one transaction and is rolled back on any error, including
+// a checksum or row count that does not match the manifest.
+func Restore(db *gorm.DB, r io.Reader) (*Snapshot, error) {
+	var snapshot *Snapshot
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		snapshot, err = read(r, func(table string, row map[string]interface{}) error {
+			err := tx.Table(table).Clauses(clause.OnConflict{DoNothing: true}).Create(row).Error
+			if err != nil {
+				return fmt.Errorf("restore %s: %w", table, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return resetSequences(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// read parses a backup, calling fn for every row, and verifies the manifest at the end.
+func read(r io.Reader, fn func(table string, row map[string]interface{}) error) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open backup: %w", err)
+	}
+	defer gz.Close()
+	lines := bufio.NewReader(gz)
+
+	var h header
+	line, err := readRawLine(lines)
+	if err == nil {
+		err = decodeLine(line, &h)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	if h.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", h.Version)
+	}
+
+	sums := newChecksums()
+	for {
+		var rec record
+		line, err := readRawLine(lines)
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("backup is truncated: manifest missing")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := decodeLine(line, &rec); err != nil {
+			return nil, fmt.Errorf("read row: %w", err)
+		}
+
+		if rec.Manifest != nil {
+			return verifyManifest(rec.Manifest, sums.snapshot(h.CreatedAt))
+		}
+
+		if !slices.Contains(Tables, rec.Table) {
+			return nil, fmt.Errorf("unknown table %q in backup", rec.Table)
+		}
+		sums.add(rec.Table, line)
+		if err := fn(rec.Table, rec.Row); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// verifyManifest compares the recorded manifest with what was actually read.
+func verifyManifest(recorded, actual *Snapshot) (*Snapshot, error) {
+	for _, table := range Tables {
+		if recorded.RowCounts[table] != actual.RowCounts[table] {
+			return nil, fmt.Errorf("%s: manifest lists %d rows, backup holds %d",
+				table, recorded.RowCounts[table], actual.RowCounts[table])
+		}
+		if recorded.Checksums[table] != actual.Checksums[table] {
+			return nil, fmt.Errorf("%s: checksum mismatch", table)
+		}
+	}
+	return recorded, nil
+}
+
+// writeLine writes v as a single JSON line.
+func writeLine(w io.Writer, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// readRawLine returns the next line without its trailing newline.
+func readRawLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadBytes('\n')
+	if errors.Is(err, io.EOF) && len(line) > 0 {
+		err = nil
+	}
+	return bytes.TrimSuffix(line, []byte("\n")), err
+}
+
+// decodeLine decodes a JSON line into v.
+// Numbers are kept as json.Number so integer columns stay exact.
+func decodeLine(line []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// resetSequences moves the Postgres id sequences past the restored primary keys so that
+// new inserts do not collide with restored rows.
+func resetSequences(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, table := range Tables {
+		query := fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %[1]s",
+			table,
+		)
+		if err := tx.Exec(query).Error; err != nil {
+			return fmt.Errorf("reset %s sequence: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package backup
+
+import (
+	"api-contact-form/internal/benchdata"
+	"io"
+	"testing"
+)
+
+func BenchmarkDump(b *testing.B) {
+	db := benchdata.OpenDB(b)
+	benchdata.Load(b, db, 1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Dump(db, io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
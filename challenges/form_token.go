@@ -0,0 +1,126 @@
+// Package challenges implements server-issued challenges that public clients must
+// complete before a submission is accepted.
+//
+// This file provides FormTokens: a signed, timestamped token handed out when the form
+// is rendered. A submission must carry it and is rejected when it arrives faster than
+// a human could fill in the form, or after the token expired.
+package challenges
+
+import (
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrFormTokenInvalid is returned for form tokens that are missing, malformed or not signed by this server.
+	ErrFormTokenInvalid = errors.New("invalid form token")
+	// ErrFormTokenTooFast is returned when the form was submitted before the minimum fill time.
+	ErrFormTokenTooFast = errors.New("form submitted too quickly")
+	// ErrFormTokenExpired is returned for form tokens past their expiry.
+	ErrFormTokenExpired = errors.New("form token expired")
+	// ErrFormTokenReused is returned when a form token is submitted a second time.
+	ErrFormTokenReused = errors.New("form token already used")
+)
+
+// FormToken is a token issued when a form is rendered.
+type FormToken struct {
+	// Token is the signed value to send back in the X-Form-Token header.
+	Token string `json:"token"`
+	// NotBefore is the earliest time the form may be submitted.
+	NotBefore time.Time `json:"not_before"`
+	// ExpiresAt is the time after which the token is no longer accepted.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// FormTokens issues and verifies signed form-render tokens.
+type FormTokens struct {
+	secret      []byte
+	minFillTime time.Duration
+	ttl         time.Duration
+	used        *redeemed
+}
+
+// NewFormTokens creates FormTokens rejecting submissions made less than minFillTime
+// after the token was issued, or more than ttl after. All replicas of the API must
+// share the same secret.
+func NewFormTokens(secret []byte, minFillTime, ttl time.Duration) *FormTokens {
+	return &FormTokens{
+		secret:      secret,
+		minFillTime: minFillTime,
+		ttl:         ttl,
+		used:        newRedeemed(),
+	}
+}
+
+// Issue creates a new form token.
+func (f *FormTokens) Issue() (FormToken, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return FormToken{}, err
+	}
+
+	issuedAt := time.Now()
+	payload := fmt.Sprintf("%s.%d", hex.EncodeToString(nonce), issuedAt.UnixMilli())
+
+	return FormToken{
+		Token:     payload + "." + sign(f.secret, payload),
+		NotBefore: issuedAt.Add(f.minFillTime),
+		ExpiresAt: issuedAt.Add(f.ttl),
+	}, nil
+}
+
+// Verify checks the signature and timing of token and redeems it,
+// so that each token is accepted only once.
+func (f *FormTokens) Verify(token string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrFormTokenInvalid
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(f.secret, payload))) {
+		return ErrFormTokenInvalid
+	}
+
+	issuedMilli, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ErrFormTokenInvalid
+	}
+	issuedAt := time.UnixMilli(issuedMilli)
+	elapsed := time.Since(issuedAt)
+	if elapsed < f.minFillTime {
+		return ErrFormTokenTooFast
+	}
+	if elapsed > f.ttl {
+		return ErrFormTokenExpired
+	}
+
+	if !f.used.redeem(token, issuedAt.Add(f.ttl)) {
+		return ErrFormTokenReused
+	}
+	return nil
+}
+
+// Middleware rejects requests whose X-Form-Token header does not carry a valid,
+// timely form token with a 403 status code.
+func (f *FormTokens) Middleware() router.HandlerFunc {
+	return func(c *router.Context) {
+		if err := f.Verify(c.GetHeader("X-Form-Token")); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, responses.APIResponse{
+				Code:    "FORBIDDEN",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,91 @@
+package challenges
+
+import (
+	"api-contact-form/router"
+	"api-contact-form/router/nethttp"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// formToken signs a token issued at issuedAt with secret.
+func formToken(secret []byte, issuedAt time.Time) string {
+	payload := fmt.Sprintf("00112233.%d", issuedAt.UnixMilli())
+	return payload + "." + sign(secret, payload)
+}
+
+func TestFormTokensVerify(t *testing.T) {
+	tokens := NewFormTokens(testSecret, 5*time.Second, time.Hour)
+	now := time.Now()
+
+	issued, err := tokens.Issue()
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if !issued.NotBefore.After(now) || issued.ExpiresAt.Sub(issued.NotBefore) != time.Hour-5*time.Second {
+		t.Errorf("Issue() = %+v, want a window from 5s to 1h", issued)
+	}
+	if err := tokens.Verify(issued.Token); !errors.Is(err, ErrFormTokenTooFast) {
+		t.Errorf("Verify(just issued) = %v, want ErrFormTokenTooFast", err)
+	}
+
+	valid := formToken(testSecret, now.Add(-time.Minute))
+	if err := tokens.Verify(valid); err != nil {
+		t.Errorf("Verify(valid) = %v", err)
+	}
+	if err := tokens.Verify(valid); !errors.Is(err, ErrFormTokenReused) {
+		t.Errorf("Verify(valid again) = %v, want ErrFormTokenReused", err)
+	}
+
+	if err := tokens.Verify(formToken(testSecret, now.Add(-2*time.Hour))); !errors.Is(err, ErrFormTokenExpired) {
+		t.Errorf("Verify(expired) = %v, want ErrFormTokenExpired", err)
+	}
+
+	// Backdating the issue time invalidates the signature.
+	parts := strings.Split(issued.Token, ".")
+	parts[1] = fmt.Sprint(now.Add(-time.Minute).UnixMilli())
+	for name, token := range map[string]string{
+		"backdated token":                  strings.Join(parts, "."),
+		"token signed with another secret": formToken([]byte("other-secret"), now.Add(-time.Minute)),
+		"token with a bad issue time":      "00112233.soon." + sign(testSecret, "00112233.soon"),
+		"malformed token":                  "not-a-token",
+		"empty token":                      "",
+	} {
+		if err := tokens.Verify(token); !errors.Is(err, ErrFormTokenInvalid) {
+			t.Errorf("Verify(%s) = %v, want ErrFormTokenInvalid", name, err)
+		}
+	}
+}
+
+func TestFormTokensMiddleware(t *testing.T) {
+	tokens := NewFormTokens(testSecret, 0, time.Hour)
+	engine := router.New(nethttp.New())
+	engine.POST("/contacts", tokens.Middleware(), func(c *router.Context) {
+		c.Status(http.StatusCreated)
+	})
+	issued, err := tokens.Issue()
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name, token string
+		status      int
+	}{
+		{"no token", "", http.StatusForbidden},
+		{"valid token", issued.Token, http.StatusCreated},
+		{"reused token", issued.Token, http.StatusForbidden},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/contacts", nil)
+		r.Header.Set("X-Form-Token", tt.token)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package challenges implements server-issued challenges that public clients must
+// complete before a submission is accepted.
+//
+// It includes ProofOfWork, a captcha-free anti-spam puzzle: the server hands out a
+// signed token and the client must find a solution whose SHA-256 hash, together with
+// the token, starts with a given number of zero bits before it may POST a contact.
+package challenges
+
+import (
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrChallengeInvalid is returned for tokens that are malformed or not signed by this server.
+	ErrChallengeInvalid = errors.New("invalid proof-of-work token")
+	// ErrChallengeExpired is returned for tokens past their expiry.
+	ErrChallengeExpired = errors.New("proof-of-work token expired")
+	// ErrChallengeUnsolved is returned when the solution does not satisfy the difficulty.
+	ErrChallengeUnsolved = errors.New("proof-of-work solution is incorrect")
+	// ErrChallengeReused is returned when a solved token is presented a second time.
+	ErrChallengeReused = errors.New("proof-of-work token already used")
+)
+
+// Challenge is a puzzle issued to a client.
+type Challenge struct {
+	// Token is the signed puzzle the client must solve.
+	Token string `json:"token"`
+	// Difficulty is the number of leading zero bits sha256(token + solution) must have.
+	Difficulty int `json:"difficulty"`
+	// ExpiresAt is the time after which the token is no longer accepted.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ProofOfWork issues and verifies stateless hash puzzles.
+// Tokens are signed with an HMAC secret; only the set of already redeemed tokens
+// is kept in memory, until they expire.
+type ProofOfWork struct {
+	secret     []byte
+	difficulty int
+	ttl        time.Duration
+	used       *redeemed
+}
+
+// NewProofOfWork creates a ProofOfWork issuing puzzles of the given difficulty that
+// stay valid for ttl. All replicas of the API must share the same secret.
+func NewProofOfWork(secret []byte, difficulty int, ttl time.Duration) *ProofOfWork {
+	return &ProofOfWork{
+		secret:     secret,
+		difficulty: difficulty,
+		ttl:        ttl,
+		used:       newRedeemed(),
+	}
+}
+
+// Issue creates a new challenge.
+func (p *ProofOfWork) Issue() (Challenge, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return Challenge{}, err
+	}
+
+	expiresAt := time.Now().Add(p.ttl).Truncate(time.Second)
+	payload := fmt.Sprintf("%s.%d.%d", hex.EncodeToString(nonce), expiresAt.Unix(), p.difficulty)
+
+	return Challenge{
+		Token:      payload + "." + sign(p.secret, payload),
+		Difficulty: p.difficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// Verify checks that solution solves the puzzle of token and redeems the token,
+// so that each solved challenge is accepted only once.
+func (p *ProofOfWork) Verify(token, solution string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return ErrChallengeInvalid
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(sign(p.secret, payload))) {
+		return ErrChallengeInvalid
+	}
+
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ErrChallengeInvalid
+	}
+	expiresAt := time.Unix(expiry, 0)
+	if time.Now().After(expiresAt) {
+		return ErrChallengeExpired
+	}
+
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return ErrChallengeInvalid
+	}
+	if leadingZeroBits(sha256.Sum256([]byte(token+solution))) < difficulty {
+		return ErrChallengeUnsolved
+	}
+
+	if !p.used.redeem(token, expiresAt) {
+		return ErrChallengeReused
+	}
+	return nil
+}
+
+// Middleware rejects requests that do not carry a solved challenge in the
+// X-PoW-Token and X-PoW-Solution headers with a 403 status code.
+func (p *ProofOfWork) Middleware() router.HandlerFunc {
+	return func(c *router.Context) {
+		err := p.Verify(c.GetHeader("X-PoW-Token"), c.GetHeader("X-PoW-Solution"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, responses.APIResponse{
+				Code:    "FORBIDDEN",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// leadingZeroBits counts the zero bits at the start of sum.
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,150 @@
+package challenges
+
+import (
+	"api-contact-form/router"
+	"api-contact-form/router/nethttp"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("test-secret")
+
+// solve finds a solution to the puzzle of token with the given difficulty.
+func solve(t *testing.T, token string, difficulty int) string {
+	t.Helper()
+	for i := range 1 << 20 {
+		solution := strconv.Itoa(i)
+		if leadingZeroBits(sha256.Sum256([]byte(token+solution))) >= difficulty {
+			return solution
+		}
+	}
+	t.Fatalf("no solution found for %s", token)
+	return ""
+}
+
+// unsolved returns a solution that does not solve the puzzle of token with the given difficulty.
+func unsolved(token string, difficulty int) string {
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		if leadingZeroBits(sha256.Sum256([]byte(token+solution))) < difficulty {
+			return solution
+		}
+	}
+}
+
+func TestProofOfWorkVerify(t *testing.T) {
+	pow := NewProofOfWork(testSecret, 8, time.Minute)
+	issue := func() Challenge {
+		t.Helper()
+		challenge, err := pow.Issue()
+		if err != nil {
+			t.Fatalf("Issue: %v", err)
+		}
+		return challenge
+	}
+
+	valid := issue()
+	if valid.Difficulty != 8 || time.Until(valid.ExpiresAt) > time.Minute {
+		t.Errorf("Issue() = %+v, want difficulty 8 and an expiry within a minute", valid)
+	}
+	if err := pow.Verify(valid.Token, solve(t, valid.Token, 8)); err != nil {
+		t.Errorf("Verify(solved) = %v", err)
+	}
+	if err := pow.Verify(valid.Token, solve(t, valid.Token, 8)); !errors.Is(err, ErrChallengeReused) {
+		t.Errorf("Verify(solved again) = %v, want ErrChallengeReused", err)
+	}
+
+	other := issue()
+	if err := pow.Verify(other.Token, unsolved(other.Token, 8)); !errors.Is(err, ErrChallengeUnsolved) {
+		t.Errorf("Verify(unsolved) = %v, want ErrChallengeUnsolved", err)
+	}
+	// A failed attempt does not redeem the token.
+	if err := pow.Verify(other.Token, solve(t, other.Token, 8)); err != nil {
+		t.Errorf("Verify(solved after a failed attempt) = %v", err)
+	}
+
+	// Lowering the difficulty invalidates the signature.
+	parts := strings.Split(issue().Token, ".")
+	parts[2] = "0"
+	lowered := strings.Join(parts, ".")
+	if err := pow.Verify(lowered, "0"); !errors.Is(err, ErrChallengeInvalid) {
+		t.Errorf("Verify(lowered difficulty) = %v, want ErrChallengeInvalid", err)
+	}
+
+	payload := fmt.Sprintf("00.%d.0", time.Now().Add(-time.Second).Unix())
+	if err := pow.Verify(payload+"."+sign(testSecret, payload), "0"); !errors.Is(err, ErrChallengeExpired) {
+		t.Errorf("Verify(expired) = %v, want ErrChallengeExpired", err)
+	}
+
+	foreign := NewProofOfWork([]byte("other-secret"), 8, time.Minute)
+	challenge, err := foreign.Issue()
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	for name, token := range map[string]string{
+		"token signed with another secret": challenge.Token,
+		"malformed token":                  "not.a.token",
+		"empty token":                      "",
+	} {
+		if err := pow.Verify(token, "0"); !errors.Is(err, ErrChallengeInvalid) {
+			t.Errorf("Verify(%s) = %v, want ErrChallengeInvalid", name, err)
+		}
+	}
+}
+
+func TestProofOfWorkMiddleware(t *testing.T) {
+	pow := NewProofOfWork(testSecret, 4, time.Minute)
+	engine := router.New(nethttp.New())
+	engine.POST("/contacts", pow.Middleware(), func(c *router.Context) {
+		c.Status(http.StatusCreated)
+	})
+	challenge, err := pow.Issue()
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name, solution string
+		status         int
+	}{
+		{"unsolved", unsolved(challenge.Token, 4), http.StatusForbidden},
+		{"solved", solve(t, challenge.Token, 4), http.StatusCreated},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/contacts", nil)
+		r.Header.Set("X-PoW-Token", challenge.Token)
+		r.Header.Set("X-PoW-Solution", tt.solution)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		prefix []byte
+		want   int
+	}{
+		{[]byte{0x80}, 0},
+		{[]byte{0x01}, 7},
+		{[]byte{0x00, 0x10}, 11},
+	}
+	for _, tt := range tests {
+		var sum [sha256.Size]byte
+		copy(sum[:], tt.prefix)
+		if got := leadingZeroBits(sum); got != tt.want {
+			t.Errorf("leadingZeroBits(%x...) = %d, want %d", tt.prefix, got, tt.want)
+		}
+	}
+	if got := leadingZeroBits([sha256.Size]byte{}); got != 8*sha256.Size {
+		t.Errorf("leadingZeroBits(zero) = %d, want %d", got, 8*sha256.Size)
+	}
+}
@@ -0,0 +1,52 @@
+// Package challenges implements server-issued challenges that public clients must
+// complete before a submission is accepted.
+//
+// This file provides the helpers shared by all challenge types: token signing and the
+// redeemed set, which makes sure a token is only accepted once during its lifetime.
+package challenges
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// sign returns the hex HMAC-SHA256 of payload under secret.
+func sign(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// redeemed remembers tokens that were already accepted, until they expire.
+type redeemed struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+}
+
+// newRedeemed creates an empty redeemed set.
+func newRedeemed() *redeemed {
+	return &redeemed{tokens: make(map[string]time.Time)}
+}
+
+// redeem marks token as used until expiresAt and reports whether it was unused,
+// pruning expired entries along the way.
+func (r *redeemed) redeem(token string, expiresAt time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for t, exp := range r.tokens {
+		if now.After(exp) {
+			delete(r.tokens, t)
+		}
+	}
+
+	if _, ok := r.tokens[token]; ok {
+		return false
+	}
+	r.tokens[token] = expiresAt
+	return true
+}
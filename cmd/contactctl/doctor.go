@@ -0,0 +1,256 @@
+// Package main implements contactctl, the operator command line tool for the API Contact Form.
+//
+// This file implements "contactctl doctor", which checks the external dependencies the
+// configuration relies on before an instance goes live: the database and its extensions,
+// the SMTP server, the attachment storage, the CAPTCHA secret and the webhook endpoints.
+// Nothing is written, migrated or sent, except for a temporary file in local storage.
+package main
+
+import (
+	"api-contact-form/config"
+	"api-contact-form/helpers"
+	"api-contact-form/middleware"
+	"api-contact-form/models"
+	"api-contact-form/notifications"
+	"api-contact-form/repositories"
+	"api-contact-form/storage"
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The outcomes of a doctor check. Warnings and skipped checks do not fail the run.
+const (
+	doctorPass = "PASS"
+	doctorWarn = "WARN"
+	doctorFail = "FAIL"
+	doctorSkip = "SKIP"
+)
+
+// doctorResult is the outcome of a single check.
+type doctorResult struct {
+	status string
+	check  string
+	detail string
+}
+
+// doctor runs the checks and collects their results.
+type doctor struct {
+	timeout time.Duration
+	results []doctorResult
+}
+
+// report records the outcome of a check.
+func (d *doctor) report(status, check, detail string) {
+	d.results = append(d.results, doctorResult{status: status, check: check, detail: detail})
+}
+
+// run runs fn with the timeout of a check, and reports it passed with the returned detail
+// or failed with the returned error.
+func (d *doctor) run(check string, fn func(ctx context.Context) (string, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	detail, err := fn(ctx)
+	if err != nil {
+		d.report(doctorFail, check, err.Error())
+		return
+	}
+	d.report(doctorPass, check, detail)
+}
+
+// runDoctor implements "contactctl doctor". It prints a report of every check and fails
+// when any of them failed.
+func runDoctor(args []string) error {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of each check")
+	_ = flags.Parse(args)
+
+	d := &doctor{timeout: *timeout}
+
+	// Connect without migrating, retrying or waiting, as the schema may not exist yet
+	cfg := config.LoadConfig()
+	cfg.AutoMigrate = false
+	cfg.ConnectAttempts = 1
+	var db *gorm.DB
+	d.run("database", func(ctx context.Context) (string, error) {
+		var err error
+		if db, err = config.NewContext(ctx, cfg); err != nil {
+			return "", err
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return "", err
+		}
+		return "connected to " + cfg.Driver, sqlDB.PingContext(ctx)
+	})
+	if db != nil {
+		defer config.Close(db)
+	}
+
+	d.checkExtensions(db, cfg.Driver)
+	d.checkSMTP()
+	d.checkStorage()
+	d.checkCaptcha()
+	d.checkWebhooks(db)
+
+	// Print the report and fail when any check failed
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	failed := 0
+	for _, result := range d.results {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.status, result.check, result.detail)
+		if result.status == doctorFail {
+			failed++
+		}
+	}
+	w.Flush()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(d.results))
+	}
+	return nil
+}
+
+// checkExtensions checks that pg_trgm, which duplicate detection and contact search rely
+// on, is installed or can be installed by the migrations.
+func (d *doctor) checkExtensions(db *gorm.DB, driver string) {
+	if driver != config.DriverPostgres {
+		d.report(doctorSkip, "extension pg_trgm", "not used by "+driver)
+		return
+	}
+	if db == nil {
+		d.report(doctorSkip, "extension pg_trgm", "no database connection")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+	var installed, available int64
+	err := db.WithContext(ctx).Raw("SELECT COUNT(*) FROM pg_extension WHERE extname = 'pg_trgm'").Scan(&installed).Error
+	if err == nil && installed == 0 {
+		err = db.WithContext(ctx).Raw("SELECT COUNT(*) FROM pg_available_extensions WHERE name = 'pg_trgm'").Scan(&available).Error
+	}
+	switch {
+	case err != nil:
+		d.report(doctorFail, "extension pg_trgm", err.Error())
+	case installed > 0:
+		d.report(doctorPass, "extension pg_trgm", "installed")
+	case available > 0:
+		d.report(doctorWarn, "extension pg_trgm", "not installed; the migrations install it if the database user may create extensions")
+	default:
+		d.report(doctorFail, "extension pg_trgm", "not available on the server; duplicate detection and contact search will not work")
+	}
+}
+
+// checkSMTP checks the SMTP server used by the email notifications and the admin setup
+// emails, when either is enabled.
+func (d *doctor) checkSMTP() {
+	notify := helpers.GetEnvBool("NOTIFY_EMAIL_ENABLED", false)
+	if !notify && !helpers.GetEnvBool("ADMIN_SETUP_EMAIL_ENABLED", false) {
+		d.report(doctorSkip, "smtp", "email notifications and setup emails are disabled")
+		return
+	}
+
+	smtpConfig := config.LoadSMTPConfig()
+	d.run("smtp", func(ctx context.Context) (string, error) {
+		if notify {
+			if _, err := notifications.NewEmailNotifier(smtpConfig); err != nil {
+				return "", err
+			}
+		}
+		if err := notifications.CheckSMTP(ctx, smtpConfig); err != nil {
+			return "", err
+		}
+		return "connected to " + smtpConfig.Host + ":" + smtpConfig.Port, nil
+	})
+}
+
+// checkStorage checks the attachment storage, when attachments are enabled.
+func (d *doctor) checkStorage() {
+	if !helpers.GetEnvBool("ATTACHMENTS_ENABLED", false) {
+		d.report(doctorSkip, "storage", "attachments are disabled")
+		return
+	}
+
+	d.run("storage", func(ctx context.Context) (string, error) {
+		store, err := storage.NewFromEnv()
+		if err != nil {
+			return "", err
+		}
+		if err := store.Check(ctx); err != nil {
+			return "", err
+		}
+		return config.GetEnv("STORAGE_DRIVER", "local") + " storage is writable", nil
+	})
+}
+
+// checkCaptcha checks that the CAPTCHA provider accepts the secret, when CAPTCHA is enabled.
+func (d *doctor) checkCaptcha() {
+	provider := config.GetEnv("CAPTCHA_PROVIDER", "")
+	if provider == "" {
+		d.report(doctorSkip, "captcha", "CAPTCHA_PROVIDER is not set")
+		return
+	}
+
+	d.run("captcha", func(ctx context.Context) (string, error) {
+		verifier, err := middleware.NewCaptchaVerifier(provider, config.GetEnv("CAPTCHA_SECRET", ""), 0, nil)
+		if err != nil {
+			return "", err
+		}
+		if err := verifier.CheckSecret(ctx); err != nil {
+			return "", err
+		}
+		return provider + " accepted the secret", nil
+	})
+}
+
+// checkWebhooks checks that the URL of every active webhook subscription answers. Any HTTP
+// response passes, as endpoints may reject the HEAD request used; server errors warn.
+func (d *doctor) checkWebhooks(db *gorm.DB) {
+	if db == nil {
+		d.report(doctorSkip, "webhooks", "no database connection")
+		return
+	}
+	if !db.Migrator().HasTable(&models.WebhookSubscription{}) {
+		d.report(doctorSkip, "webhooks", "the database is not migrated yet")
+		return
+	}
+	subscriptions, err := repositories.NewWebhookRepository(db).FindActiveSubscriptions()
+	if err != nil {
+		d.report(doctorFail, "webhooks", err.Error())
+		return
+	}
+	if len(subscriptions) == 0 {
+		d.report(doctorSkip, "webhooks", "no active subscriptions")
+		return
+	}
+
+	client := &http.Client{Timeout: d.timeout}
+	for _, subscription := range subscriptions {
+		check := fmt.Sprintf("webhook %d", subscription.ID)
+		status, err := headStatus(client, subscription.URL)
+		switch {
+		case err != nil:
+			d.report(doctorFail, check, err.Error())
+		case status >= http.StatusInternalServerError:
+			d.report(doctorWarn, check, fmt.Sprintf("%s answered HTTP %d", subscription.URL, status))
+		default:
+			d.report(doctorPass, check, fmt.Sprintf("%s answered HTTP %d", subscription.URL, status))
+		}
+	}
+}
+
+// headStatus sends a HEAD request to url and returns the status code of the response.
+func headStatus(client *http.Client, url string) (int, error) {
+	resp, err := client.Head(url)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
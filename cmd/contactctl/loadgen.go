@@ -0,0 +1,269 @@
+// Package main implements contactctl, the operator command line tool for the API Contact Form.
+//
+// This file implements "contactctl loadgen", which sends generated or replayed contact
+// submissions to a running instance at a fixed rate and reports latency percentiles.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// loadgenPayload is a contact submission sent by loadgen.
+type loadgenPayload struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Phone   string `json:"phone"`
+	Message string `json:"message"`
+}
+
+// loadgenResult is the outcome of a single submission.
+type loadgenResult struct {
+	status  int
+	latency time.Duration
+	err     error
+}
+
+// spamPhrases are mixed into generated spam payloads.
+var spamPhrases = []string{
+	"Buy cheap followers now at http://spam.example/offer",
+	"Limited time crypto investment, guaranteed 500% returns!!!",
+	"Click here http://spam.example/win to claim your prize",
+}
+
+// loremWords build the generated message bodies.
+var loremWords = strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua")
+
+// runLoadgen implements "contactctl loadgen".
+//
+// Payloads are either generated from a seed (message size and spam ratio are configurable)
+// or replayed from an NDJSON fixture file. Generated payloads can be saved as a fixture so
+// that the exact same traffic can be replayed against another instance later.
+//
+// The target must not require proof-of-work or form tokens, since loadgen does not solve them.
+func runLoadgen(args []string) error {
+	flags := flag.NewFlagSet("loadgen", flag.ExitOnError)
+	target := flags.String("target", "http://localhost:8080", "base URL of the instance under test")
+	rate := flags.Int("rate", 10, "submissions per second")
+	duration := flags.Duration("duration", 30*time.Second, "how long to send traffic")
+	concurrency := flags.Int("concurrency", 50, "maximum number of requests in flight")
+	minSize := flags.Int("min-size", 50, "minimum generated message size in bytes")
+	maxSize := flags.Int("max-size", 2000, "maximum generated message size in bytes")
+	spamRatio := flags.Float64("spam-ratio", 0.1, "fraction of generated submissions that look like spam")
+	seed := flags.Int64("seed", 1, "seed of the payload generator")
+	save := flags.String("save", "", "write the generated payloads to this NDJSON fixture file")
+	replay := flags.String("replay", "", "replay the payloads of this NDJSON fixture file instead of generating them")
+	_ = flags.Parse(args)
+
+	if *rate <= 0 || *concurrency <= 0 || *minSize <= 0 || *maxSize < *minSize {
+		return errors.New("rate, concurrency and sizes must be positive, with max-size >= min-size")
+	}
+
+	// Prepare the payload source.
+	var next func() (loadgenPayload, bool)
+	if *replay != "" {
+		payloads, err := readFixture(*replay)
+		if err != nil {
+			return err
+		}
+		i := 0
+		next = func() (loadgenPayload, bool) {
+			if i >= len(payloads) {
+				return loadgenPayload{}, false
+			}
+			i++
+			return payloads[i-1], true
+		}
+	} else {
+		generator := rand.New(rand.NewSource(*seed))
+		n := 0
+		next = func() (loadgenPayload, bool) {
+			n++
+			return generatePayload(generator, n, *minSize, *maxSize, *spamRatio), true
+		}
+	}
+
+	var fixture *json.Encoder
+	if *save != "" {
+		file, err := os.Create(*save)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		fixture = json.NewEncoder(file)
+	}
+
+	// Send submissions at the configured rate until the duration elapses or the fixture runs out.
+	client := &http.Client{Timeout: 30 * time.Second}
+	url := strings.TrimRight(*target, "/") + "/contacts"
+	inFlight := make(chan struct{}, *concurrency)
+	results := make(chan loadgenResult, *concurrency)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	ticker := time.NewTicker(time.Second / time.Duration(*rate))
+	defer ticker.Stop()
+	deadline := time.After(*duration)
+
+	collected := make(chan []loadgenResult)
+	go func() {
+		var all []loadgenResult
+		for result := range results {
+			all = append(all, result)
+		}
+		collected <- all
+	}()
+
+send:
+	for {
+		select {
+		case <-deadline:
+			break send
+		case <-ticker.C:
+		}
+
+		payload, ok := next()
+		if !ok {
+			break send
+		}
+		if fixture != nil {
+			if err := fixture.Encode(payload); err != nil {
+				return err
+			}
+		}
+
+		inFlight <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-inFlight }()
+			results <- submit(client, url, payload)
+		}()
+	}
+
+	wg.Wait()
+	close(results)
+	printLoadgenReport(<-collected, time.Since(start))
+	return nil
+}
+
+// generatePayload builds the n-th generated submission.
+func generatePayload(r *rand.Rand, n, minSize, maxSize int, spamRatio float64) loadgenPayload {
+	size := minSize + r.Intn(maxSize-minSize+1)
+
+	var message strings.Builder
+	if r.Float64() < spamRatio {
+		for message.Len() < size {
+			message.WriteString(spamPhrases[r.Intn(len(spamPhrases))])
+			message.WriteString(" ")
+		}
+	} else {
+		for message.Len() < size {
+			message.WriteString(loremWords[r.Intn(len(loremWords))])
+			message.WriteString(" ")
+		}
+	}
+
+	return loadgenPayload{
+		Name:    fmt.Sprintf("Load Test %d", n),
+		Email:   fmt.Sprintf("loadtest+%d@example.com", n),
+		Phone:   fmt.Sprintf("+6281%08d", r.Intn(100000000)),
+		Message: strings.TrimSpace(message.String()[:size]),
+	}
+}
+
+// submit posts a single payload and measures its latency.
+func submit(client *http.Client, url string, payload loadgenPayload) loadgenResult {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return loadgenResult{err: err}
+	}
+
+	start := time.Now()
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return loadgenResult{latency: time.Since(start), err: err}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return loadgenResult{status: resp.StatusCode, latency: time.Since(start)}
+}
+
+// readFixture loads the payloads of an NDJSON fixture file.
+func readFixture(path string) ([]loadgenPayload, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var payloads []loadgenPayload
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var payload loadgenPayload
+		if err := json.Unmarshal(scanner.Bytes(), &payload); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, len(payloads)+1, err)
+		}
+		payloads = append(payloads, payload)
+	}
+	return payloads, scanner.Err()
+}
+
+// printLoadgenReport prints throughput over elapsed, status code counts and latency percentiles.
+func printLoadgenReport(results []loadgenResult, elapsed time.Duration) {
+	if len(results) == 0 {
+		fmt.Println("no requests sent")
+		return
+	}
+
+	statuses := map[string]int{}
+	latencies := make([]time.Duration, 0, len(results))
+	for _, result := range results {
+		if result.err != nil {
+			statuses["error"]++
+			continue
+		}
+		statuses[fmt.Sprint(result.status)]++
+		latencies = append(latencies, result.latency)
+	}
+	slices.Sort(latencies)
+
+	fmt.Printf("requests: %d (%.1f/s)\n", len(results), float64(len(results))/elapsed.Seconds())
+	codes := make([]string, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		fmt.Printf("  %s: %d\n", code, statuses[code])
+	}
+
+	if len(latencies) == 0 {
+		return
+	}
+	fmt.Printf("latency p50=%s p90=%s p99=%s max=%s\n",
+		percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99), latencies[len(latencies)-1])
+}
+
+// percentile returns the p-th percentile of sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p + 99) / 100
+	if i > 0 {
+		i--
+	}
+	return sorted[i]
+}
@@ -0,0 +1,264 @@
+// Package main implements contactctl, the operator command line tool for the API Contact Form.
+//
+// It connects to the same database as the API, using the same environment variables,
+// and provides maintenance commands that do not belong on the HTTP API.
+//
+// Usage:
+//
+//	contactctl backup -o contacts.ndjson.gz
+//	contactctl verify -i contacts.ndjson.gz
+//	contactctl restore -i contacts.ndjson.gz
+//	contactctl export-settings -o settings.yaml
+//	contactctl import-settings -i settings.yaml
+//	contactctl loadgen -target http://localhost:8080 -rate 50 -duration 1m
+//	contactctl doctor
+package main
+
+import (
+	"api-contact-form/backup"
+	"api-contact-form/config"
+	"api-contact-form/rules"
+	"api-contact-form/settings"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// usage is printed when no or an unknown command is given.
+const usage = `Usage: contactctl <command> [flags]
+
+Commands:
+  backup           Write a consistent dump of the database to a file
+  verify           Check a dump against its recorded row counts and checksums
+  restore          Verify and load a dump produced by backup into the database
+  export-settings  Write the validation rules and webhooks as a YAML bundle
+  import-settings  Apply a YAML bundle produced by export-settings
+  loadgen          Send generated or replayed submissions to an instance and report latencies
+  doctor           Check the database, SMTP, storage, CAPTCHA and webhooks before going live
+
+Run "contactctl <command> -h" for the flags of a command.
+`
+
+// main dispatches to the requested subcommand.
+func main() {
+	// Load environment variables from the .env file, like the API does.
+	_ = godotenv.Load()
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "backup":
+		err = runBackup(os.Args[2:])
+	case "verify":
+		err = runVerify(os.Args[2:])
+	case "restore":
+		err = runRestore(os.Args[2:])
+	case "export-settings":
+		err = runExportSettings(os.Args[2:])
+	case "import-settings":
+		err = runImportSettings(os.Args[2:])
+	case "loadgen":
+		err = runLoadgen(os.Args[2:])
+	case "doctor":
+		err = runDoctor(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("contactctl %s: %v", os.Args[1], err)
+	}
+}
+
+// runBackup implements "contactctl backup".
+func runBackup(args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := flags.String("o", "-", "file to write the backup to, - for stdout")
+	_ = flags.Parse(args)
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	db, err := config.New(config.LoadConfig())
+	if err != nil {
+		return err
+	}
+	defer config.Close(db)
+
+	snapshot, err := backup.Dump(db, w)
+	if err != nil {
+		return err
+	}
+
+	printSnapshot(snapshot)
+	return nil
+}
+
+// runVerify implements "contactctl verify".
+func runVerify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	input := flags.String("i", "-", "backup file to verify, - for stdin")
+	_ = flags.Parse(args)
+
+	r, closeInput, err := openInput(*input)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	snapshot, err := backup.Verify(r)
+	if err != nil {
+		return err
+	}
+
+	printSnapshot(snapshot)
+	return nil
+}
+
+// runRestore implements "contactctl restore".
+func runRestore(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	input := flags.String("i", "-", "backup file to restore, - for stdin")
+	_ = flags.Parse(args)
+
+	r, closeInput, err := openInput(*input)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	db, err := config.New(config.LoadConfig())
+	if err != nil {
+		return err
+	}
+	defer config.Close(db)
+
+	snapshot, err := backup.Restore(db, r)
+	if err != nil {
+		return err
+	}
+
+	printSnapshot(snapshot)
+	return nil
+}
+
+// runExportSettings implements "contactctl export-settings". The rules are read from
+// RULES_FILE, like the API does.
+func runExportSettings(args []string) error {
+	flags := flag.NewFlagSet("export-settings", flag.ExitOnError)
+	output := flags.String("o", "-", "file to write the bundle to, - for stdout")
+	secrets := flags.Bool("secrets", false, "include the signing secrets of the webhooks")
+	_ = flags.Parse(args)
+
+	var current *rules.Rules
+	if rulesFile := config.GetEnv("RULES_FILE", ""); rulesFile != "" {
+		store, err := rules.NewStore(rulesFile)
+		if err != nil {
+			return err
+		}
+		current = store.Current()
+	}
+
+	db, err := config.New(config.LoadConfig())
+	if err != nil {
+		return err
+	}
+	defer config.Close(db)
+
+	bundle, err := settings.Export(db, current, *secrets)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := settings.Encode(w, bundle); err != nil {
+		return err
+	}
+
+	log.Printf("exported %d webhooks, rules included: %t", len(bundle.Webhooks), bundle.Rules != nil)
+	return nil
+}
+
+// runImportSettings implements "contactctl import-settings". The rules are written to
+// RULES_FILE, which running instances reload on their next check.
+func runImportSettings(args []string) error {
+	flags := flag.NewFlagSet("import-settings", flag.ExitOnError)
+	input := flags.String("i", "-", "bundle file to import, - for stdin")
+	_ = flags.Parse(args)
+
+	r, closeInput, err := openInput(*input)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	bundle, err := settings.Decode(r)
+	if err != nil {
+		return err
+	}
+
+	var saveRules func(*rules.Rules) error
+	if rulesFile := config.GetEnv("RULES_FILE", ""); rulesFile != "" {
+		saveRules = func(current *rules.Rules) error {
+			return rules.Save(rulesFile, current)
+		}
+	}
+
+	db, err := config.New(config.LoadConfig())
+	if err != nil {
+		return err
+	}
+	defer config.Close(db)
+
+	result, err := settings.Import(db, bundle, saveRules)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("webhooks created: %d, updated: %d, rules replaced: %t", result.WebhooksCreated, result.WebhooksUpdated, result.RulesReplaced)
+	return nil
+}
+
+// openInput opens the named file for reading, or stdin for "-".
+func openInput(name string) (io.Reader, func(), error) {
+	if name == "-" {
+		return os.Stdin, func() {}, nil
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, func() { file.Close() }, nil
+}
+
+// printSnapshot logs the timestamp, row counts and checksums of a backup.
+func printSnapshot(snapshot *backup.Snapshot) {
+	log.Printf("snapshot taken at %s", snapshot.CreatedAt.Format(time.RFC3339))
+	for _, table := range backup.Tables {
+		log.Printf("  %s: %d rows, sha256 %s", table, snapshot.RowCounts[table], snapshot.Checksums[table])
+	}
+}
@@ -1,24 +1,55 @@
 // Package config handles the initialization and configuration of the database connection.
 //
-// It establishes a connection to a PostgreSQL database using GORM, configures the connection pool,
-// and performs automatic migrations for the Contact model.
+// It establishes a connection to a PostgreSQL, MySQL or SQLite database using GORM, configures
+// the connection pool, and performs automatic migrations for the Contact model.
 package config
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"api-contact-form/models"
+	"api-contact-form/repositories"
 
-	"github.com/joho/godotenv"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 )
 
-// DB is a global variable that holds the database connection instance.
-var DB *gorm.DB
+// maxConnectBackoff caps the wait between two connection attempts.
+const maxConnectBackoff = 30 * time.Second
+
+// schemaModels lists the models whose tables are migrated and checked at startup.
+var schemaModels = []interface{}{
+	&models.Contact{},
+	&models.RejectedSubmission{},
+	&models.APIKey{},
+	&models.WebhookSubscription{},
+	&models.WebhookDelivery{},
+	&models.AdminUser{},
+	&models.AdminRecoveryCode{},
+	&models.AdminSession{},
+	&models.AdminLoginEvent{},
+	&models.Attachment{},
+	&models.IdempotencyKey{},
+	&models.AuditLog{},
+	&models.ReplyDraft{},
+	&models.APIUsage{},
+	&models.WebhookOutboxEvent{},
+	&models.InboxEntry{},
+	&models.AutoReplyTemplate{},
+	&models.ExportSchedule{},
+	&models.SeenCredential{},
+	&models.AbuseReport{},
+	&models.FollowUp{},
+	&models.SystemChange{},
+	&models.ContactStar{},
+	&models.EmailMessage{},
+}
 
 // GetEnv is assumed to exist elsewhere in your codebase. If not, uncomment this.
 // func GetEnv(key, def string) string {
@@ -28,58 +59,294 @@ var DB *gorm.DB
 // 	return def
 // }
 
-// InitDB initializes the PostgreSQL connection using environment variables.
+// Config holds the settings of the database connection.
+type Config struct {
+	// Driver selects the database: postgres, mysql or sqlite.
+	Driver string
+	// Host, Port, User, Password and Name locate the Postgres or MySQL database. An empty
+	// Port selects the default port of the driver.
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	// SSLMode is the sslmode of Postgres connections.
+	SSLMode string
+	// TimeZone is the session time zone of Postgres and MySQL connections.
+	TimeZone string
+	// Path is the SQLite database file, or ":memory:".
+	Path string
+
+	// MaxOpenConns, MaxIdleConns and ConnMaxLifetime tune the connection pool. SQLite
+	// always uses a single connection.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// ConnectAttempts is the number of times connecting is tried before giving up, and
+	// ConnectBackoff the wait before the first retry, doubled after every failure. Retrying
+	// covers databases that start after the application, e.g. with Docker Compose.
+	ConnectAttempts int
+	ConnectBackoff  time.Duration
+
+	// AutoMigrate migrates the schema on connect; disable it when the schema is migrated
+	// outside the application. Missing indexes are reported either way.
+	AutoMigrate bool
+	// MigrationLockTimeout is the longest wait for another instance to finish migrating
+	// the schema, when several start at the same time.
+	MigrationLockTimeout time.Duration
+	// UniqueOpenEmail allows a single open contact per email address.
+	UniqueOpenEmail bool
+}
+
+// LoadConfig reads the database settings from the environment, with safe defaults for local dev.
+func LoadConfig() Config {
+	return Config{
+		Driver:          GetEnv("DB_DRIVER", DriverPostgres),
+		Host:            GetEnv("DB_HOST", "127.0.0.1"),
+		Port:            GetEnv("DB_PORT", ""),
+		User:            GetEnv("DB_USER", "appuser"),
+		Password:        GetEnv("DB_PASSWORD", "appsecret"),
+		Name:            GetEnv("DB_NAME", "contactsdb"),
+		SSLMode:         GetEnv("DB_SSLMODE", "disable"), // local dev: disable TLS
+		TimeZone:        GetEnv("DB_TZ", "Asia/Jakarta"),
+		Path:            GetEnv("DB_PATH", "contacts.db"),
+		MaxOpenConns:    envInt("DB_MAX_OPEN_CONNS", 10),
+		MaxIdleConns:    envInt("DB_MAX_IDLE_CONNS", 5),
+		ConnMaxLifetime: envDuration("DB_CONN_MAX_LIFETIME", time.Hour),
+		ConnectAttempts: envInt("DB_CONNECT_ATTEMPTS", 10),
+		ConnectBackoff:  envDuration("DB_CONNECT_BACKOFF", time.Second),
+		AutoMigrate:     GetEnv("DB_AUTO_MIGRATE", "true") != "false",
+		UniqueOpenEmail: GetEnv("CONTACT_UNIQUE_OPEN_EMAIL", "false") == "true",
+
+		MigrationLockTimeout: envDuration("DB_MIGRATION_LOCK_TIMEOUT", 5*time.Minute),
+	}
+}
+
+// New connects to the database described by cfg. See NewContext.
+func New(cfg Config) (*gorm.DB, error) {
+	return NewContext(context.Background(), cfg)
+}
+
+// NewContext connects to the database described by cfg, retrying until the connection
+// succeeds, the attempts are exhausted or ctx is cancelled.
 // Steps:
-// 1) Read env
-// 2) Build Postgres DSN (with sslmode & TimeZone suitable for local dev)
-// 3) Open DB with GORM + SingularTable naming
-// 4) Tune connection pool
-// 5) Auto-migrate models
-func InitDB() {
-	_ = godotenv.Load() // ensure .env is loaded when running compiled binary
-
-	// Retrieve configuration with safe defaults for local dev
-	dbUser := GetEnv("DB_USER", "appuser")
-	dbPassword := GetEnv("DB_PASSWORD", "appsecret")
-	dbHost := GetEnv("DB_HOST", "127.0.0.1")
-	dbPort := GetEnv("DB_PORT", "5432")
-	dbName := GetEnv("DB_NAME", "contactsdb")
-	sslmode := GetEnv("DB_SSLMODE", "disable")    // local dev: disable TLS
-	tz := GetEnv("DB_TZ", "Asia/Jakarta")         // GORM Postgres supports TimeZone in DSN
-
-	// DSN format per GORM Postgres driver
-	// Example: host=127.0.0.1 user=appuser password=appsecret dbname=contactsdb port=5432 sslmode=disable TimeZone=Asia/Jakarta
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=%s",
-		dbHost, dbUser, dbPassword, dbName, dbPort, sslmode, tz,
-	)
-
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		NamingStrategy: schema.NamingStrategy{
-			SingularTable: true, // keep your existing singular tables
-		},
-		// You can add Logger or other options here if needed
-	})
+// 1) Build the DSN of the driver
+// 2) Open DB with GORM + SingularTable naming, with retry/backoff
+// 3) Tune connection pool
+// 4) Auto-migrate models and drop superseded indexes, one instance at a time (unless cfg.AutoMigrate is false)
+// 5) Warn about missing indexes
+func NewContext(ctx context.Context, cfg Config) (*gorm.DB, error) {
+	dialector, target, err := openDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := connect(ctx, dialector, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", target, err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	// Connection pool tuning
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	if cfg.Driver == DriverSQLite {
+		// SQLite allows a single writer, and every connection to ":memory:" opens its own database
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+	}
+
+	// Auto-migrate your models, unless the schema is managed outside the application.
+	// Instances starting together wait for each other instead of racing on the schema.
+	if cfg.AutoMigrate {
+		if err := Migrate(ctx, db, cfg); err != nil {
+			_ = sqlDB.Close()
+			return nil, err
+		}
+	}
+
+	// Warn about indexes the models declare but the database lacks
+	warnMissingIndexes(db, schemaModels...)
+
+	log.Printf("Connected to %s", target)
+	return db, nil
+}
+
+// Close closes the connection pool of db.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Warmup opens the idle connections of the pool of db ahead of the first requests, so that
+// they do not pay for connecting. It opens n connections, or fewer when the pool is
+// limited to fewer.
+func Warmup(ctx context.Context, db *gorm.DB, n int) error {
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Failed to connect to Postgres: %v", err)
+		return err
+	}
+	if limit := sqlDB.Stats().MaxOpenConnections; limit > 0 {
+		n = min(n, limit)
+	}
+
+	// Hold the connections until every one is open, so that the pool cannot reuse them.
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+	for range n {
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, conn)
+		if err := conn.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connect opens dialector, retrying with exponential backoff while the database is unreachable.
+func connect(ctx context.Context, dialector gorm.Dialector, cfg Config) (*gorm.DB, error) {
+	backoff := cfg.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		db, err := gorm.Open(dialector, &gorm.Config{
+			NamingStrategy: schema.NamingStrategy{
+				SingularTable: true, // keep your existing singular tables
+			},
+			// You can add Logger or other options here if needed
+		})
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= cfg.ConnectAttempts {
+			return nil, err
+		}
+
+		log.Printf("Database not reachable (attempt %d of %d), retrying in %s: %v", attempt, cfg.ConnectAttempts, backoff, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
+// Migrate migrates the schema of db, of the cfg.Driver database, as NewContext does when
+// cfg.AutoMigrate is set: one instance at a time, waiting at most cfg.MigrationLockTimeout
+// for the others. It is meant for applications that open the database themselves.
+func Migrate(ctx context.Context, db *gorm.DB, cfg Config) error {
+	return withMigrationLock(ctx, db, cfg, func(conn *gorm.DB) error {
+		return migrate(conn, cfg)
+	})
+}
+
+// migrate auto-migrates the models, drops superseded indexes and applies the optional indexes,
+// recording the schema changes it made in the changelog.
+func migrate(db *gorm.DB, cfg Config) error {
+	changes := pendingSchemaChanges(db, schemaModels...)
+	if err := db.AutoMigrate(schemaModels...); err != nil {
+		return fmt.Errorf("auto-migrate: %w", err)
+	}
+
+	// Drop indexes that were replaced by newer definitions; AutoMigrate only adds indexes.
+	for _, name := range []string{"idx_contact_messages_deleted_at"} {
+		if db.Migrator().HasIndex(&models.Contact{}, name) {
+			if err := db.Migrator().DropIndex(&models.Contact{}, name); err != nil {
+				return fmt.Errorf("drop index %s: %w", name, err)
+			}
+			changes = append(changes, fmt.Sprintf("drop index %s on contact_messages", name))
+		}
+	}
+
+	// Provide the trigram similarity used by duplicate detection and search, and the
+	// full-text index of the messages
+	if cfg.Driver == DriverPostgres {
+		enableTrigram(db)
+		applyFullTextIndex(db)
+	}
+
+	// Allow a single open contact per email address when the instance asks for it
+	hadOpenEmailIndex := db.Migrator().HasIndex(&models.Contact{}, repositories.OpenEmailIndex)
+	if err := applyOpenEmailIndex(db, cfg.UniqueOpenEmail); err != nil {
+		return fmt.Errorf("apply the unique open email index: %w", err)
+	}
+	if hasOpenEmailIndex := db.Migrator().HasIndex(&models.Contact{}, repositories.OpenEmailIndex); hasOpenEmailIndex != hadOpenEmailIndex {
+		action := "drop"
+		if hasOpenEmailIndex {
+			action = "create"
+		}
+		changes = append(changes, fmt.Sprintf("%s index %s on contact_messages", action, repositories.OpenEmailIndex))
+	}
+
+	// Strip the angle brackets the Message-IDs of contacts created by the inbound email
+	// webhooks were once stored with
+	if err := normalizeMessageIDs(db); err != nil {
+		return fmt.Errorf("normalize message ids: %w", err)
 	}
 
-	sqlDB, err := DB.DB()
+	recordSchemaChanges(db, changes)
+	return nil
+}
+
+// normalizeMessageIDs stores the Message-IDs of contacts without their angle brackets, as
+// they are compared. A Message-ID already stored in both forms is the same email ingested
+// twice; the bracketed copy is left as is, since the unique index rejects the other one.
+func normalizeMessageIDs(db *gorm.DB) error {
+	var contacts []models.Contact
+	err := db.Unscoped().Select("id", "message_id").Where("message_id LIKE ?", "<%>").Find(&contacts).Error
 	if err != nil {
-		log.Fatal("Failed to get database instance!")
+		return err
 	}
 
-	// Connection pool tuning (reasonable local defaults)
-	sqlDB.SetMaxOpenConns(10)
-	sqlDB.SetMaxIdleConns(5)
-	sqlDB.SetConnMaxLifetime(1 * time.Hour)
+	for _, contact := range contacts {
+		messageID := strings.TrimSuffix(strings.TrimPrefix(*contact.MessageID, "<"), ">")
+		var taken int64
+		if err := db.Unscoped().Model(&models.Contact{}).Where("message_id = ?", messageID).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			log.Printf("WARNING: contact %d duplicates the email %s of another contact", contact.ID, messageID)
+			continue
+		}
+		err := db.Unscoped().Model(&models.Contact{}).Where("id = ?", contact.ID).UpdateColumn("message_id", messageID).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Auto-migrate your models
-	if err := DB.AutoMigrate(&models.Contact{}); err != nil {
-		log.Fatalf("AutoMigrate failed: %v", err)
+// envInt reads an integer environment variable, falling back to defaultValue when it is
+// unset or invalid. The helpers package cannot be used here, as it imports config.
+func envInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(GetEnv(key, ""))
+	if err != nil {
+		return defaultValue
 	}
+	return value
+}
 
-	log.Printf("Connected to Postgres %s:%s db=%s as %s (sslmode=%s, tz=%s)",
-		dbHost, dbPort, dbName, dbUser, sslmode, tz)
+// envDuration reads a duration environment variable, falling back to defaultValue when it
+// is unset or invalid.
+func envDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(GetEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
 }
@@ -0,0 +1,62 @@
+// Package config handles the initialization and configuration of the database connection.
+//
+// This file builds the connection string of the selected database driver in the format
+// each driver expects.
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Supported values of DB_DRIVER.
+const (
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+)
+
+// openDialector returns the GORM dialector of the driver of cfg, together with a description
+// of the connection target for the startup log.
+func openDialector(cfg Config) (gorm.Dialector, string, error) {
+	switch cfg.Driver {
+	case DriverPostgres:
+		port := cmp.Or(cfg.Port, "5432")
+
+		// DSN format per GORM Postgres driver
+		// Example: host=127.0.0.1 user=appuser password=appsecret dbname=contactsdb port=5432 sslmode=disable TimeZone=Asia/Jakarta
+		dsn := fmt.Sprintf(
+			"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=%s",
+			cfg.Host, cfg.User, cfg.Password, cfg.Name, port, cfg.SSLMode, cfg.TimeZone,
+		)
+		target := fmt.Sprintf("Postgres %s:%s db=%s as %s (sslmode=%s, tz=%s)", cfg.Host, port, cfg.Name, cfg.User, cfg.SSLMode, cfg.TimeZone)
+		return postgres.Open(dsn), target, nil
+
+	case DriverMySQL:
+		port := cmp.Or(cfg.Port, "3306")
+
+		// DSN format per go-sql-driver/mysql; parseTime scans DATETIME columns into time.Time
+		// Example: appuser:appsecret@tcp(127.0.0.1:3306)/contactsdb?charset=utf8mb4&parseTime=True&loc=Asia%2FJakarta
+		dsn := fmt.Sprintf(
+			"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=%s",
+			cfg.User, cfg.Password, cfg.Host, port, cfg.Name, url.QueryEscape(cfg.TimeZone),
+		)
+		target := fmt.Sprintf("MySQL %s:%s db=%s as %s (tz=%s)", cfg.Host, port, cfg.Name, cfg.User, cfg.TimeZone)
+		return mysql.Open(dsn), target, nil
+
+	case DriverSQLite:
+		// Enforce foreign keys and wait for locks instead of failing with SQLITE_BUSY
+		dsn := cfg.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+		return sqlite.Open(dsn), "SQLite " + cfg.Path, nil
+
+	default:
+		return nil, "", fmt.Errorf("unsupported DB_DRIVER %q, expected %s, %s or %s",
+			cfg.Driver, DriverPostgres, DriverMySQL, DriverSQLite)
+	}
+}
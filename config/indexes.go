@@ -0,0 +1,97 @@
+// Package config handles the initialization and configuration of the database connection.
+//
+// This file provides the startup check that compares the indexes declared on the
+// models with the indexes present in the live database, and the optional and
+// Postgres-specific indexes that cannot be declared with GORM tags.
+package config
+
+import (
+	"api-contact-form/repositories"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// warnMissingIndexes logs a warning for every index declared on the models that does
+// not exist in the database. It catches schemas that are migrated by hand, or whose
+// indexes were dropped, before the missing index shows up as slow queries.
+func warnMissingIndexes(db *gorm.DB, models ...interface{}) {
+	for _, model := range models {
+		// Parse the model to learn its table and declared indexes.
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			log.Printf("Index check skipped for %T: %v", model, err)
+			continue
+		}
+
+		for _, index := range stmt.Schema.ParseIndexes() {
+			if !db.Migrator().HasIndex(model, index.Name) {
+				log.Printf("WARNING: expected index %s is missing on table %s", index.Name, stmt.Schema.Table)
+			}
+		}
+	}
+}
+
+// applyOpenEmailIndex creates or drops the partial unique index that allows a single
+// open (not soft-deleted) contact per email address. The comparison is case-insensitive.
+// Creating the index fails while duplicate open contacts exist.
+//
+// MySQL has no partial indexes, so the index is not available there.
+func applyOpenEmailIndex(db *gorm.DB, enabled bool) error {
+	if db.Dialector.Name() == DriverMySQL {
+		if enabled {
+			log.Printf("WARNING: CONTACT_UNIQUE_OPEN_EMAIL is not supported by MySQL, open contacts are not unique per email")
+		}
+		return nil
+	}
+
+	if !enabled {
+		return db.Exec("DROP INDEX IF EXISTS " + repositories.OpenEmailIndex).Error
+	}
+
+	return db.Exec(fmt.Sprintf(
+		"CREATE UNIQUE INDEX IF NOT EXISTS %s ON contact_messages (LOWER(email_address)) WHERE deleted_at IS NULL",
+		repositories.OpenEmailIndex,
+	)).Error
+}
+
+// enableTrigram installs the pg_trgm extension, which provides the similarity functions
+// used to find duplicate submissions and to search contacts, and the trigram indexes of
+// the searched columns. Failing to install them is not fatal: only the features relying
+// on them are unavailable or slower.
+func enableTrigram(db *gorm.DB) {
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		log.Printf("WARNING: pg_trgm extension not installed, duplicate detection and contact search are unavailable: %v", err)
+		return
+	}
+
+	for name, column := range trigramIndexes {
+		err := db.Exec(fmt.Sprintf(
+			"CREATE INDEX IF NOT EXISTS %s ON contact_messages USING gin (%s gin_trgm_ops)", name, column,
+		)).Error
+		if err != nil {
+			log.Printf("WARNING: trigram index %s not created: %v", name, err)
+		}
+	}
+}
+
+// applyFullTextIndex creates the GIN index of the text search vector of the messages,
+// used by the full-text search of contacts. Failing to create it is not fatal: searches
+// still work, scanning every message.
+func applyFullTextIndex(db *gorm.DB) {
+	err := db.Exec(fmt.Sprintf(
+		"CREATE INDEX IF NOT EXISTS %s ON contact_messages USING gin (to_tsvector('%s', message_text))",
+		repositories.FullTextIndex, repositories.FullTextConfig,
+	)).Error
+	if err != nil {
+		log.Printf("WARNING: full-text index %s not created: %v", repositories.FullTextIndex, err)
+	}
+}
+
+// trigramIndexes maps the names of the trigram indexes to the column they cover.
+var trigramIndexes = map[string]string{
+	"idx_contact_messages_full_name_trgm": "full_name",
+	"idx_contact_messages_email_trgm":     "email_address",
+	"idx_contact_messages_message_trgm":   "message_text",
+}
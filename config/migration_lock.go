@@ -0,0 +1,103 @@
+// Package config handles the initialization and configuration of the database connection.
+//
+// This file serializes the migrations of replicas starting at the same time. Before
+// migrating, a replica takes a database-wide lock: a session-level advisory lock on
+// Postgres, a named lock on MySQL. The other replicas wait for it and then migrate in
+// turn, which finds the schema up to date. SQLite databases are a local file used by a
+// single process, so they are migrated without a lock.
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationLockID is the key of the Postgres advisory lock, and migrationLockName the
+// name of the MySQL lock. Both are arbitrary, but must not change between versions.
+const (
+	migrationLockID   int64 = 4218730051
+	migrationLockName       = "api-contact-form:migrate"
+)
+
+// migrationLockPoll is the wait between two attempts to take the lock.
+const migrationLockPoll = time.Second
+
+// withMigrationLock runs fn with the migration lock held, on a single connection of db,
+// waiting at most cfg.MigrationLockTimeout for the lock.
+func withMigrationLock(ctx context.Context, db *gorm.DB, cfg Config, fn func(conn *gorm.DB) error) error {
+	if cfg.Driver == DriverSQLite {
+		return fn(db)
+	}
+
+	// Session-level locks belong to a connection, so take and release it on the same one
+	return db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := waitMigrationLock(ctx, conn, cfg); err != nil {
+			return err
+		}
+		defer func() {
+			// Releasing uses a fresh context, so that the lock is freed even when ctx is done
+			if err := unlockMigrations(conn.WithContext(context.Background()), cfg.Driver); err != nil {
+				log.Printf("Failed to release the migration lock: %v", err)
+			}
+		}()
+		return fn(conn)
+	})
+}
+
+// waitMigrationLock tries to take the migration lock until it succeeds, the timeout
+// expires or ctx is cancelled.
+func waitMigrationLock(ctx context.Context, conn *gorm.DB, cfg Config) error {
+	deadline := time.Now().Add(cfg.MigrationLockTimeout)
+	for logged := false; ; logged = true {
+		locked, err := tryLockMigrations(conn, cfg.Driver)
+		if err != nil {
+			return fmt.Errorf("take the migration lock: %w", err)
+		}
+		if locked {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("take the migration lock: still held by another instance after %s", cfg.MigrationLockTimeout)
+		}
+
+		if !logged {
+			log.Printf("Waiting for another instance to finish migrating the database")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(migrationLockPoll):
+		}
+	}
+}
+
+// tryLockMigrations takes the migration lock without waiting, and reports whether it did.
+func tryLockMigrations(conn *gorm.DB, driver string) (bool, error) {
+	var locked bool
+	var err error
+	switch driver {
+	case DriverPostgres:
+		err = conn.Raw("SELECT pg_try_advisory_lock(?)", migrationLockID).Scan(&locked).Error
+	case DriverMySQL:
+		// GET_LOCK returns 1 when the lock was taken and 0 when it is held elsewhere
+		var result int
+		err = conn.Raw("SELECT GET_LOCK(?, 0)", migrationLockName).Scan(&result).Error
+		locked = result == 1
+	}
+	return locked, err
+}
+
+// unlockMigrations releases the migration lock taken by tryLockMigrations.
+func unlockMigrations(conn *gorm.DB, driver string) error {
+	switch driver {
+	case DriverPostgres:
+		return conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockID).Error
+	case DriverMySQL:
+		return conn.Exec("SELECT RELEASE_LOCK(?)", migrationLockName).Error
+	}
+	return nil
+}
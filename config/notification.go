@@ -0,0 +1,96 @@
+// Package config handles the initialization and configuration of the database connection.
+//
+// This file reads the settings of the notifications sent when a contact is submitted.
+package config
+
+import (
+	"net/smtp"
+	"strings"
+)
+
+// SMTPConfig holds the settings of the email notifications sent for new contacts.
+type SMTPConfig struct {
+	// Host and Port locate the SMTP server. STARTTLS is used when the server offers it.
+	Host string
+	Port string
+	// Username and Password authenticate with the server; no authentication is
+	// attempted when Username is empty.
+	Username string
+	Password string
+	// From is the sender address of the notifications.
+	From string
+	// Recipients are the addresses notified of every new contact.
+	Recipients []string
+	// SubjectTemplate is the text/template of the subject line.
+	SubjectTemplate string
+	// BodyTemplateFile is the path of a text/template file for the body; the built-in
+	// body is used when it is empty.
+	BodyTemplateFile string
+	// SendMail sends a message as smtp.SendMail does, which is used when it is nil. It is
+	// replaced to simulate failures of the SMTP server.
+	SendMail SendMailFunc
+}
+
+// SendMailFunc is the signature of smtp.SendMail.
+type SendMailFunc func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+
+// LoadSMTPConfig reads the notification settings from the environment.
+func LoadSMTPConfig() SMTPConfig {
+	var recipients []string
+	for _, recipient := range strings.Split(GetEnv("NOTIFY_RECIPIENTS", ""), ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+
+	return SMTPConfig{
+		Host:             GetEnv("SMTP_HOST", ""),
+		Port:             GetEnv("SMTP_PORT", "587"),
+		Username:         GetEnv("SMTP_USERNAME", ""),
+		Password:         GetEnv("SMTP_PASSWORD", ""),
+		From:             GetEnv("SMTP_FROM", ""),
+		Recipients:       recipients,
+		SubjectTemplate:  GetEnv("NOTIFY_SUBJECT", "New contact from {{.FullName}}"),
+		BodyTemplateFile: GetEnv("NOTIFY_BODY_TEMPLATE_FILE", ""),
+	}
+}
+
+// TeamsConfig holds the settings of the Microsoft Teams notifications sent for new contacts.
+type TeamsConfig struct {
+	// WebhookURL is the incoming webhook of the Teams channel.
+	WebhookURL string
+	// AdminURLTemplate is the text/template of the link to a contact in the admin view,
+	// e.g. "https://admin.example.com/contacts/{{.ID}}". No link is added when it is empty.
+	AdminURLTemplate string
+}
+
+// LoadTeamsConfig reads the Teams notification settings from the environment.
+func LoadTeamsConfig() TeamsConfig {
+	return TeamsConfig{
+		WebhookURL:       GetEnv("TEAMS_WEBHOOK_URL", ""),
+		AdminURLTemplate: GetEnv("NOTIFY_ADMIN_URL", ""),
+	}
+}
+
+// MatrixConfig holds the settings of the Matrix notifications sent for new contacts.
+type MatrixConfig struct {
+	// HomeserverURL is the base URL of the Matrix homeserver, e.g. "https://matrix.example.org".
+	HomeserverURL string
+	// AccessToken authenticates the bot account posting the notifications.
+	AccessToken string
+	// RoomID is the ID of the room notified, e.g. "!abcdef:example.org". The bot account must have joined it.
+	RoomID string
+	// AdminURLTemplate is the text/template of the link to a contact in the admin view.
+	// No link is added when it is empty.
+	AdminURLTemplate string
+}
+
+// LoadMatrixConfig reads the Matrix notification settings from the environment.
+func LoadMatrixConfig() MatrixConfig {
+	return MatrixConfig{
+		HomeserverURL:    GetEnv("MATRIX_HOMESERVER_URL", ""),
+		AccessToken:      GetEnv("MATRIX_ACCESS_TOKEN", ""),
+		RoomID:           GetEnv("MATRIX_ROOM_ID", ""),
+		AdminURLTemplate: GetEnv("NOTIFY_ADMIN_URL", ""),
+	}
+}
@@ -0,0 +1,73 @@
+// Package config handles the initialization and configuration of the database connection.
+//
+// This file records the changes the migrations make to the schema in the changelog of
+// the instance, so that operators can tell when a table, column or index appeared.
+package config
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// migrationActor is the actor recorded for the schema changes made by the migrations.
+const migrationActor = "system:migrate"
+
+// pendingSchemaChanges lists the tables, columns and indexes of the models that are
+// missing from the database, and which AutoMigrate is about to create.
+func pendingSchemaChanges(db *gorm.DB, models ...interface{}) []string {
+	var changes []string
+	for _, model := range models {
+		// Parse the model to learn its table, columns and declared indexes.
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			log.Printf("Schema changes of %T not recorded: %v", model, err)
+			continue
+		}
+
+		migrator := db.Migrator()
+		if !migrator.HasTable(model) {
+			changes = append(changes, fmt.Sprintf("create table %s", stmt.Schema.Table))
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !migrator.HasColumn(model, field.DBName) {
+				changes = append(changes, fmt.Sprintf("add column %s.%s", stmt.Schema.Table, field.DBName))
+			}
+		}
+		for _, index := range stmt.Schema.ParseIndexes() {
+			if !migrator.HasIndex(model, index.Name) {
+				changes = append(changes, fmt.Sprintf("create index %s on %s", index.Name, stmt.Schema.Table))
+			}
+		}
+	}
+	return changes
+}
+
+// recordSchemaChanges adds the schema changes made by the migrations to the changelog.
+// The schema is already changed, so failures are only logged.
+func recordSchemaChanges(db *gorm.DB, changes []string) {
+	if len(changes) == 0 {
+		return
+	}
+	details, err := json.Marshal(map[string][]string{"changes": changes})
+	if err != nil {
+		log.Printf("Failed to encode the schema changes: %v", err)
+		return
+	}
+	change := &models.SystemChange{
+		Kind:    models.ChangeMigration,
+		Actor:   migrationActor,
+		Subject: "schema",
+		Summary: fmt.Sprintf("Migrations applied %d schema changes", len(changes)),
+		Details: string(details),
+	}
+	if err := repositories.NewSystemChangeRepository(db).Create(context.Background(), change); err != nil {
+		log.Printf("Failed to record the schema changes: %v", err)
+	}
+}
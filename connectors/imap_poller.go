@@ -0,0 +1,228 @@
+// Package connectors provides integrations that pull contact submissions from external systems.
+//
+// It includes the IMAPPoller, which periodically fetches unseen messages from a mailbox
+// and turns them into contacts for teams that cannot configure provider webhooks.
+package connectors
+
+import (
+	"api-contact-form/repositories"
+	"api-contact-form/requests"
+	"api-contact-form/rules"
+	"api-contact-form/services"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	_ "github.com/emersion/go-message/charset" // decode non-UTF-8 message bodies
+	"github.com/emersion/go-message/mail"
+)
+
+// IMAPConfig holds the connection settings of the mailbox polled by IMAPPoller.
+type IMAPConfig struct {
+	// Host is the IMAP server hostname.
+	Host string
+	// Port is the IMAP server port, typically 993 for implicit TLS.
+	Port string
+	// Username and Password are the mailbox login credentials.
+	Username string
+	Password string
+	// Mailbox is the folder to poll, e.g. "INBOX".
+	Mailbox string
+	// Interval is the time between two polls.
+	Interval time.Duration
+}
+
+// IMAPPoller fetches unseen messages from an IMAP mailbox and creates contacts from them.
+type IMAPPoller struct {
+	config  IMAPConfig
+	service services.ContactService
+}
+
+// NewIMAPPoller creates a new IMAPPoller with the provided configuration and ContactService.
+func NewIMAPPoller(config IMAPConfig, service services.ContactService) *IMAPPoller {
+	return &IMAPPoller{config: config, service: service}
+}
+
+// Run polls the mailbox every configured interval until ctx is cancelled.
+// Poll errors are logged and retried on the next tick.
+func (p *IMAPPoller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Poll(ctx); err != nil {
+			log.Printf("IMAP poll of %s failed: %v", p.config.Mailbox, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll connects to the mailbox once, creates a contact for every unseen message, or adds
+// it to the conversation of the contact it replies to, and marks the processed messages
+// as seen.
+//
+// Messages whose Message-ID was already ingested are marked as seen without creating a contact.
+func (p *IMAPPoller) Poll(ctx context.Context) error {
+	c, err := client.DialTLS(p.config.Host+":"+p.config.Port, nil)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer c.Logout()
+
+	if err := c.Login(p.config.Username, p.config.Password); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+
+	if _, err := c.Select(p.config.Mailbox, false); err != nil {
+		return fmt.Errorf("select: %w", err)
+	}
+
+	// Look up the messages that have not been seen yet.
+	criteria := imap.NewSearchCriteria()
+	criteria.WithoutFlags = []string{imap.SeenFlag}
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+	if len(uids) == 0 {
+		return nil
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uids...)
+
+	// Fetch the full messages without setting the \Seen flag; it is only set once
+	// the contact was stored so that failed messages are picked up again.
+	section := &imap.BodySectionName{Peek: true}
+	messages := make(chan *imap.Message, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.UidFetch(seqSet, []imap.FetchItem{imap.FetchUid, section.FetchItem()}, messages)
+	}()
+
+	processed := new(imap.SeqSet)
+	for msg := range messages {
+		if err := p.ingest(ctx, msg.GetBody(section)); err != nil {
+			log.Printf("IMAP message %d skipped: %v", msg.Uid, err)
+			continue
+		}
+		processed.AddNum(msg.Uid)
+	}
+	if err := <-done; err != nil {
+		return fmt.Errorf("fetch: %w", err)
+	}
+
+	if processed.Empty() {
+		return nil
+	}
+	flags := []interface{}{imap.SeenFlag}
+	if err := c.UidStore(processed, imap.FormatFlagsOp(imap.AddFlags, true), flags, nil); err != nil {
+		return fmt.Errorf("mark seen: %w", err)
+	}
+	return nil
+}
+
+// ingest parses a raw message and adds it to the conversation it replies to, or creates a
+// contact from it.
+// A message that was already ingested, that fails validation or custom validation rules, or whose sender
+// already has an open contact, is treated as processed so that it is not fetched again on every poll.
+func (p *IMAPPoller) ingest(ctx context.Context, body imap.Literal) error {
+	if body == nil {
+		return errors.New("empty message body")
+	}
+
+	req, err := parseMessage(body)
+	if err != nil {
+		return err
+	}
+
+	// Add replies to the conversation of their contact, and create a contact for other emails.
+	_, err = p.service.AddEmailReply(ctx, req)
+	if errors.Is(err, services.ErrNoEmailThread) {
+		_, err = p.service.CreateContactFromEmail(ctx, req)
+	}
+	var ruleErr *rules.ValidationError
+	var validationErr *services.ValidationError
+	if errors.As(err, &ruleErr) || errors.As(err, &validationErr) {
+		log.Printf("IMAP message from %s rejected: %v", req.FromEmail, err)
+		return nil
+	}
+	if errors.Is(err, repositories.ErrOpenContactExists) {
+		log.Printf("IMAP message from %s rejected: %v", req.FromEmail, err)
+		return nil
+	}
+	if errors.Is(err, services.ErrDuplicateMessage) {
+		return nil
+	}
+	return err
+}
+
+// parseMessage reads the sender, subject, Message-ID, threading headers and first plain-text
+// part of a message.
+func parseMessage(r io.Reader) (*requests.InboundEmailRequest, error) {
+	mr, err := mail.CreateReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse message: %w", err)
+	}
+	defer mr.Close()
+
+	from, err := mr.Header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return nil, errors.New("missing sender address")
+	}
+	subject, _ := mr.Header.Subject()
+	messageID, _ := mr.Header.MessageID()
+	inReplyTo, _ := mr.Header.MsgIDList("In-Reply-To")
+	references, _ := mr.Header.MsgIDList("References")
+
+	req := &requests.InboundEmailRequest{
+		FromName:   from[0].Name,
+		FromEmail:  from[0].Address,
+		Subject:    subject,
+		MessageID:  messageID,
+		References: references,
+	}
+	if len(inReplyTo) > 0 {
+		req.InReplyTo = inReplyTo[0]
+	}
+
+	// Use the first inline text/plain part as the message body.
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read part: %w", err)
+		}
+
+		header, ok := part.Header.(*mail.InlineHeader)
+		if !ok {
+			continue
+		}
+		contentType, _, _ := header.ContentType()
+		if contentType != "text/plain" {
+			continue
+		}
+
+		text, err := io.ReadAll(part.Body)
+		if err != nil {
+			return nil, fmt.Errorf("read body: %w", err)
+		}
+		req.Body = strings.TrimSpace(string(text))
+		break
+	}
+
+	return req, nil
+}
@@ -0,0 +1,205 @@
+// Package contactform embeds the contact form backend into another Go application, such
+// as an existing monolith, instead of running the API as a separate binary.
+//
+// New builds the backend on the application's own *gorm.DB: the ContactService, for the
+// application to create and manage contacts in its own code, and an http.Handler serving
+// the contact routes of the API, to be mounted on the application's router. The host
+// application authenticates the admin routes with the function given to WithAdminAuth.
+//
+// The embedded backend covers the contacts themselves. The notifications, webhooks,
+// custom rules and other optional features of the binary are enabled by passing the
+// corresponding services.ContactServiceOption values to WithServiceOptions.
+//
+// Unlike the binary, the backend does not guard the public POST /contacts route: it has no
+// rate limit, no spam or challenge checks, such as the honeypot field, CAPTCHA or
+// proof-of-work, and no replay of submissions repeated with an Idempotency-Key header.
+// Applications exposing the route to the internet pass the guards they need, such as those
+// of package middleware, to WithSubmissionGuards:
+//
+//	backend, err := contactform.New(db,
+//		contactform.WithAdminAuth(auth),
+//		contactform.WithSubmissionGuards(
+//			middleware.NewRateLimiter(5, 3, nil).Middleware(),
+//			middleware.Honeypot("website", nil),
+//		),
+//	)
+//
+// The Handler matches the requests with the standard library alone. Applications built on
+// Gin or Echo pass the adapter of their framework to WithRouter instead, such as
+// ginrouter.New(gin.New()).
+//
+// Applications using the Snowflake ID strategy also enable ids.SetJSONStrings, so that
+// JavaScript clients do not round the IDs of the responses.
+package contactform
+
+import (
+	"api-contact-form/config"
+	"api-contact-form/handlers"
+	"api-contact-form/middleware"
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/router"
+	"api-contact-form/router/nethttp"
+	"api-contact-form/server"
+	"api-contact-form/services"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AdminAuthFunc authenticates the caller of an admin request. It returns the subject
+// recorded as the actor of the changes, such as "user:42", and whether the caller is
+// allowed to manage the contacts.
+type AdminAuthFunc func(r *http.Request) (subject string, ok bool)
+
+// Backend is the contact form backend embedded into an application.
+type Backend struct {
+	// Contacts is the service layer of the contacts.
+	Contacts services.ContactService
+	// Handler serves the contact routes of the API under the base path.
+	Handler http.Handler
+}
+
+// options holds the settings of New.
+type options struct {
+	autoMigrate    bool
+	basePath       string
+	maxBodySize    int64
+	adminAuth      AdminAuthFunc
+	adapter        router.Adapter
+	guards         []router.HandlerFunc
+	serviceOptions []services.ContactServiceOption
+}
+
+// Option configures the backend built by New.
+type Option func(*options)
+
+// WithAutoMigrate migrates the schema of the contact form tables when the backend is
+// built, which is the default. Disable it when the schema is migrated with the
+// application's own tooling.
+func WithAutoMigrate(enabled bool) Option {
+	return func(o *options) {
+		o.autoMigrate = enabled
+	}
+}
+
+// WithBasePath serves the routes under path, such as "/api/contact-form", for
+// applications mounting the Handler without stripping the prefix.
+func WithBasePath(path string) Option {
+	return func(o *options) {
+		o.basePath = path
+	}
+}
+
+// WithMaxBodySize limits the size of request bodies to n bytes; 1 MiB by default.
+func WithMaxBodySize(n int64) Option {
+	return func(o *options) {
+		o.maxBodySize = n
+	}
+}
+
+// WithAdminAuth authenticates the admin routes with auth. Without it, the admin routes
+// answer every request with a 401 status code.
+func WithAdminAuth(auth AdminAuthFunc) Option {
+	return func(o *options) {
+		o.adminAuth = auth
+	}
+}
+
+// WithRouter serves the routes with adapter, one of the adapters of the subpackages of
+// package router; nethttp by default.
+func WithRouter(adapter router.Adapter) Option {
+	return func(o *options) {
+		o.adapter = adapter
+	}
+}
+
+// WithSubmissionGuards runs guards, in order, before the public POST /contacts route,
+// after the body size limit. A guard rejects a submission by aborting the request, as the
+// rate limiter, spam checks and idempotency of package middleware do.
+func WithSubmissionGuards(guards ...router.HandlerFunc) Option {
+	return func(o *options) {
+		o.guards = append(o.guards, guards...)
+	}
+}
+
+// WithServiceOptions configures the ContactService with opts, after the defaults of the
+// backend.
+func WithServiceOptions(opts ...services.ContactServiceOption) Option {
+	return func(o *options) {
+		o.serviceOptions = append(o.serviceOptions, opts...)
+	}
+}
+
+// New builds the backend on db. See NewContext.
+func New(db *gorm.DB, opts ...Option) (*Backend, error) {
+	return NewContext(context.Background(), db, opts...)
+}
+
+// NewContext builds the backend on db, a connection opened by the application to a
+// Postgres, MySQL or SQLite database, and migrates the schema unless disabled, waiting for
+// the other instances migrating it at most until ctx is done.
+//
+// The Handler serves:
+//   - POST /contacts, public, for the contact form, behind the guards given to
+//     WithSubmissionGuards;
+//   - the admin routes listing, searching, exporting, merging, importing, changing,
+//     deleting, restoring and purging contacts, registered by server.ContactRoutes as in
+//     the API.
+func NewContext(ctx context.Context, db *gorm.DB, opts ...Option) (*Backend, error) {
+	o := options{autoMigrate: true, maxBodySize: 1 << 20}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if db == nil {
+		return nil, errors.New("contactform: a database is required")
+	}
+
+	// Migrate the schema of the contact form tables.
+	if o.autoMigrate {
+		cfg := config.Config{Driver: db.Dialector.Name(), MigrationLockTimeout: 5 * time.Minute}
+		if err := config.Migrate(ctx, db, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// Build the service layer, recording the audit trail and the email conversations.
+	serviceOptions := append([]services.ContactServiceOption{
+		services.WithAuditLog(repositories.NewAuditLogRepository(db)),
+		services.WithEmailThreads(repositories.NewEmailMessageRepository(db)),
+	}, o.serviceOptions...)
+	contacts := services.NewContactService(repositories.NewContactRepository(db), serviceOptions...)
+
+	// Route the public submissions and the admin routes.
+	contactHandler := handlers.NewContactHandler(contacts, nil, nil)
+	exportHandler := handlers.NewExportHandler(contacts, nil)
+
+	if o.adapter == nil {
+		o.adapter = nethttp.New()
+	}
+	engine := router.New(o.adapter)
+	engine.Use(middleware.Recovery(), middleware.BodyLimit(o.maxBodySize))
+	group := engine.Group(o.basePath)
+	group.POST("/contacts", append(o.guards, contactHandler.CreateContact)...)
+
+	admin := group.Group("", adminAuth(o.adminAuth), middleware.RequireRole(models.RoleAdmin))
+	server.ContactRoutes(admin, admin, nil, contactHandler, exportHandler)
+
+	return &Backend{Contacts: contacts, Handler: engine}, nil
+}
+
+// adminAuth records the caller authenticated by auth as an admin, for RequireRole and the
+// audit trail. Callers it does not accept, and every caller without auth, stay anonymous.
+func adminAuth(auth AdminAuthFunc) router.HandlerFunc {
+	return func(c *router.Context) {
+		if auth != nil {
+			if subject, ok := auth(c.Request); ok {
+				middleware.SetIdentity(c, &middleware.Identity{Subject: subject, Role: models.RoleAdmin})
+			}
+		}
+		c.Next()
+	}
+}
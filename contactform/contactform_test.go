@@ -0,0 +1,131 @@
+package contactform_test
+
+import (
+	"api-contact-form/contactform"
+	"api-contact-form/router"
+	"api-contact-form/server"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// submission is a valid body of the public POST /contacts route.
+const submission = `{"name":"Alice","email":"alice@example.com","phone":"+628123456789","message":"Hello"}`
+
+// newHandler builds a backend on an empty SQLite database, and mounts it under /forms on a
+// net/http ServeMux, as an application embedding it would.
+func newHandler(t *testing.T, opts ...contactform.Option) http.Handler {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "contacts.db")), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	backend, err := contactform.NewContext(t.Context(), db, opts...)
+	if err != nil {
+		t.Fatalf("NewContext: %v", err)
+	}
+	mux := http.NewServeMux()
+	server.Mount(mux, "/forms", backend.Handler)
+	return mux
+}
+
+// serve sends a request to handler and returns the status code of the response.
+func serve(handler http.Handler, method, target, body string, header http.Header) int {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+// tokenAuth accepts the admin requests bearing the token "secret".
+func tokenAuth(r *http.Request) (string, bool) {
+	return "user:1", r.Header.Get("Authorization") == "Bearer secret"
+}
+
+func TestHandler(t *testing.T) {
+	admin := http.Header{"Authorization": {"Bearer secret"}}
+	tests := []struct {
+		name   string
+		opts   []contactform.Option
+		method string
+		target string
+		body   string
+		header http.Header
+		want   int
+	}{
+		{"public submission", nil, http.MethodPost, "/forms/contacts", submission, nil, http.StatusCreated},
+		{"invalid submission", nil, http.MethodPost, "/forms/contacts", `{"name":"Alice"}`, nil, http.StatusUnprocessableEntity},
+		{"admin route without WithAdminAuth", nil, http.MethodGet, "/forms/contacts", "", admin, http.StatusUnauthorized},
+		{"admin route with a rejected caller", []contactform.Option{contactform.WithAdminAuth(tokenAuth)}, http.MethodGet, "/forms/contacts", "", nil, http.StatusUnauthorized},
+		{"admin route with an accepted caller", []contactform.Option{contactform.WithAdminAuth(tokenAuth)}, http.MethodGet, "/forms/contacts", "", admin, http.StatusOK},
+		{"submission guard", []contactform.Option{contactform.WithSubmissionGuards(func(c *router.Context) {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+		})}, http.MethodPost, "/forms/contacts", submission, nil, http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newHandler(t, tt.opts...)
+			if got := serve(handler, tt.method, tt.target, tt.body, tt.header); got != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminRoutesSeeSubmissions(t *testing.T) {
+	handler := newHandler(t, contactform.WithAdminAuth(tokenAuth))
+	if got := serve(handler, http.MethodPost, "/forms/contacts", submission, nil); got != http.StatusCreated {
+		t.Fatalf("POST /forms/contacts = %d, want %d", got, http.StatusCreated)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/forms/contacts/1", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "alice@example.com") {
+		t.Errorf("GET /forms/contacts/1 = %d %s, want the submitted contact", w.Code, w.Body)
+	}
+}
+
+func TestWithRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, name := range server.Routers {
+		t.Run(name, func(t *testing.T) {
+			adapter, err := server.NewAdapter(name)
+			if err != nil {
+				t.Fatalf("NewAdapter: %v", err)
+			}
+			handler := newHandler(t, contactform.WithRouter(adapter), contactform.WithAdminAuth(tokenAuth))
+			admin := http.Header{"Authorization": {"Bearer secret"}}
+			for _, tt := range []struct {
+				method, target, body string
+				want                 int
+			}{
+				{http.MethodPost, "/forms/contacts", submission, http.StatusCreated},
+				{http.MethodGet, "/forms/contacts/1", "", http.StatusOK},
+				{http.MethodGet, "/forms/contacts/trash", "", http.StatusOK},
+				{http.MethodPatch, "/forms/contacts/1/status", `{"status":"read"}`, http.StatusOK},
+				{http.MethodGet, "/forms/unknown", "", http.StatusNotFound},
+			} {
+				if got := serve(handler, tt.method, tt.target, tt.body, admin); got != tt.want {
+					t.Errorf("%s %s = %d, want %d", tt.method, tt.target, got, tt.want)
+				}
+			}
+		})
+	}
+}
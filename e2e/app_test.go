@@ -0,0 +1,140 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	// adminKey is the admin API key the application is started with.
+	adminKey = "e2e-admin-key"
+	// startTimeout is how long the application may take to become ready.
+	startTimeout = 30 * time.Second
+)
+
+// binary is the path of the application built by TestMain.
+var binary string
+
+// TestMain builds the application once for every scenario.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "api-contact-form-e2e")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	binary = filepath.Join(dir, "api-contact-form")
+	build := exec.Command("go", "build", "-o", binary, ".")
+	build.Dir = ".."
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "build the application: %v\n", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// app is a running instance of the application and of the fake servers it talks to.
+type app struct {
+	baseURL  string
+	mail     *fakeSMTP
+	webhooks *webhookReceiver
+}
+
+// startApp starts the application with an empty database, sending its emails to a fake
+// SMTP server, with the settings of env on top of the defaults. The application is
+// stopped, and its logs shown on failure, when the test ends.
+func startApp(t *testing.T, env map[string]string) *app {
+	t.Helper()
+	dir := t.TempDir()
+	mail := startFakeSMTP(t)
+	webhooks := startWebhookReceiver(t)
+	port := freePort(t)
+
+	settings := map[string]string{
+		"APP_PORT":              strconv.Itoa(port),
+		"GIN_MODE":              "release",
+		"DB_DRIVER":             "sqlite",
+		"DB_PATH":               filepath.Join(dir, "contacts.db"),
+		"ADMIN_API_KEYS":        adminKey,
+		"JWT_SECRET":            "e2e-jwt-secret",
+		"CORS_ALLOWED_ORIGINS":  "http://e2e.example",
+		"SMTP_HOST":             "127.0.0.1",
+		"SMTP_PORT":             strconv.Itoa(mail.port),
+		"SMTP_FROM":             "forms@e2e.example",
+		"WEBHOOK_RETRY_BACKOFF": "100ms",
+	}
+	for key, value := range env {
+		settings[key] = value
+	}
+	// Only pass what the go tool and the application need, so that the environment of
+	// the developer does not change the outcome.
+	environ := []string{"PATH=" + os.Getenv("PATH"), "HOME=" + dir, "TMPDIR=" + dir}
+	for key, value := range settings {
+		environ = append(environ, key+"="+value)
+	}
+
+	var logs bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, binary)
+	cmd.Dir = dir
+	cmd.Env = environ
+	cmd.Stdout, cmd.Stderr = &logs, &logs
+	if err := cmd.Start(); err != nil {
+		cancel()
+		t.Fatalf("start the application: %v", err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		_ = cmd.Wait()
+		if t.Failed() {
+			t.Logf("application logs:\n%s", logs.String())
+		}
+	})
+
+	a := &app{baseURL: fmt.Sprintf("http://127.0.0.1:%d", port), mail: mail, webhooks: webhooks}
+	a.waitReady(t)
+	return a
+}
+
+// waitReady waits for the readiness probe of the application to succeed.
+func (a *app) waitReady(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(startTimeout)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(a.baseURL + "/readyz")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("the application was not ready after %s", startTimeout)
+}
+
+// freePort returns a TCP port nothing listens on.
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,14 @@
+// Package e2e runs end-to-end scenarios against the whole application.
+//
+// Every scenario boots the application binary with its own SQLite database, a fake SMTP
+// server capturing the emails it sends and a receiver recording the webhooks it
+// delivers, then plays the steps of a script from testdata/scenarios: requests to the API
+// with their expected responses, and the emails and webhooks they must cause. The
+// scenarios protect the flows spanning several packages, such as a submission being spam
+// checked, notified, answered and exported, from regressions.
+//
+// The tests build the application first, so they are kept out of the unit tests behind
+// the e2e build tag:
+//
+//	go test -tags e2e ./e2e
+package e2e
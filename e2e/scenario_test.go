@@ -0,0 +1,403 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+const (
+	// waitTimeout is how long a step waits for the emails or webhooks it expects.
+	waitTimeout = 10 * time.Second
+	// settleTime is how long a step expecting an exact count of emails or webhooks waits
+	// for more to arrive.
+	settleTime = time.Second
+)
+
+// variablePattern matches the references to variables in the strings of scenarios.
+var variablePattern = regexp.MustCompile(`\$\{([a-z0-9_]+)\}`)
+
+// scenario is a script of steps played against a fresh instance of the application.
+type scenario struct {
+	// Env overrides the default settings of the application.
+	Env   map[string]string `yaml:"env"`
+	Steps []step            `yaml:"steps"`
+}
+
+// step is one step of a scenario: a request, or the emails or webhooks to wait for.
+type step struct {
+	Name    string       `yaml:"name"`
+	Request *requestStep `yaml:"request"`
+	Mail    *mailStep    `yaml:"mail"`
+	Webhook *webhookStep `yaml:"webhook"`
+}
+
+// requestStep sends a request and checks the response.
+type requestStep struct {
+	Method string `yaml:"method"`
+	Path   string `yaml:"path"`
+	// Admin authenticates the request with the admin API key.
+	Admin   bool              `yaml:"admin"`
+	Headers map[string]string `yaml:"headers"`
+	// Body is sent as JSON.
+	Body any `yaml:"body"`
+
+	// Status is the expected status code.
+	Status int `yaml:"status"`
+	// JSON maps dotted paths of the JSON response, such as "data.status", to their
+	// expected values.
+	JSON map[string]any `yaml:"json"`
+	// Contains lists text the raw response must contain.
+	Contains []string `yaml:"contains"`
+	// Save maps variable names to the dotted paths of the JSON response they are set to.
+	Save map[string]string `yaml:"save"`
+}
+
+// mailStep waits for the emails matching To and Contains.
+type mailStep struct {
+	To       string   `yaml:"to"`
+	Contains []string `yaml:"contains"`
+	// Count is the number of matching emails to wait for, 1 by default. With Exact, no
+	// more may arrive.
+	Count *int `yaml:"count"`
+	Exact bool `yaml:"exact"`
+}
+
+// webhookStep waits for the webhooks of Event matching JSON.
+type webhookStep struct {
+	Event string         `yaml:"event"`
+	JSON  map[string]any `yaml:"json"`
+	// Secret, when set, is the secret the webhooks must be signed with.
+	Secret string `yaml:"secret"`
+	// Count is the number of matching webhooks to wait for, 1 by default. With Exact, no
+	// more may arrive.
+	Count *int `yaml:"count"`
+	Exact bool `yaml:"exact"`
+}
+
+// TestScenarios plays every scenario of testdata/scenarios.
+func TestScenarios(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "scenarios", "*.yaml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no scenario found: %v", err)
+	}
+	for _, file := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".yaml"), func(t *testing.T) {
+			t.Parallel()
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s scenario
+			if err := yaml.UnmarshalWithOptions(data, &s, yaml.Strict()); err != nil {
+				t.Fatalf("parse %s: %v", file, err)
+			}
+			newRun(t, startApp(t, s.Env)).play(s.Steps)
+		})
+	}
+}
+
+// run is the state of a scenario being played.
+type run struct {
+	t    *testing.T
+	app  *app
+	vars map[string]string
+}
+
+// newRun prepares playing a scenario against app, with the variable webhook_url set to
+// the URL of the webhook receiver.
+func newRun(t *testing.T, app *app) *run {
+	return &run{t: t, app: app, vars: map[string]string{"webhook_url": app.webhooks.url}}
+}
+
+// play plays steps in order, stopping at the first failing one.
+func (r *run) play(steps []step) {
+	for i, s := range steps {
+		name := fmt.Sprintf("step %d", i+1)
+		if s.Name != "" {
+			name += " (" + s.Name + ")"
+		}
+		var err error
+		switch {
+		case s.Request != nil:
+			err = r.request(s.Request)
+		case s.Mail != nil:
+			err = r.mail(s.Mail)
+		case s.Webhook != nil:
+			err = r.webhook(s.Webhook)
+		default:
+			err = fmt.Errorf("no request, mail or webhook")
+		}
+		if err != nil {
+			r.t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+// request sends the request of s and checks its response.
+func (r *run) request(s *requestStep) error {
+	var body io.Reader
+	if s.Body != nil {
+		data, err := json.Marshal(r.expand(s.Body))
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	method := s.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, r.app.baseURL+r.expandString(s.Path), body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.Admin {
+		req.Header.Set("X-API-Key", adminKey)
+	}
+	for key, value := range s.Headers {
+		req.Header.Set(key, r.expandString(value))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	call := method + " " + req.URL.Path
+
+	if s.Status != 0 && resp.StatusCode != s.Status {
+		return fmt.Errorf("%s returned %d, want %d: %s", call, resp.StatusCode, s.Status, data)
+	}
+	for _, text := range s.Contains {
+		if text = r.expandString(text); !strings.Contains(string(data), text) {
+			return fmt.Errorf("%s returned %s, want it to contain %q", call, data, text)
+		}
+	}
+	if len(s.JSON) == 0 && len(s.Save) == 0 {
+		return nil
+	}
+
+	document, err := decodeJSON(data)
+	if err != nil {
+		return fmt.Errorf("%s returned %s: %v", call, data, err)
+	}
+	if err := r.match(document, s.JSON); err != nil {
+		return fmt.Errorf("%s returned %s: %v", call, data, err)
+	}
+	for name, path := range s.Save {
+		value, ok := lookup(document, path)
+		if !ok {
+			return fmt.Errorf("%s returned %s, without %s to save", call, data, path)
+		}
+		r.vars[name] = format(value)
+	}
+	return nil
+}
+
+// mail waits for the emails expected by s.
+func (r *run) mail(s *mailStep) error {
+	to := r.expandString(s.To)
+	matching := func() int {
+		count := 0
+		for _, e := range r.app.mail.Emails() {
+			if to != "" && !containsAddress(e.To, to) {
+				continue
+			}
+			if r.containsAll(e.Data, s.Contains) {
+				count++
+			}
+		}
+		return count
+	}
+	return r.wait("emails", matching, s.Count, s.Exact)
+}
+
+// webhook waits for the webhooks expected by s.
+func (r *run) webhook(s *webhookStep) error {
+	secret := r.expandString(s.Secret)
+	var failure error
+	matching := func() int {
+		count := 0
+		for _, d := range r.app.webhooks.Deliveries() {
+			if d.Event != s.Event {
+				continue
+			}
+			if secret != "" && !d.Verify(secret) {
+				failure = fmt.Errorf("%s webhook not signed with the secret of the subscription", d.Event)
+				continue
+			}
+			document, err := decodeJSON(d.Body)
+			if err != nil || r.match(document, s.JSON) != nil {
+				continue
+			}
+			count++
+		}
+		return count
+	}
+	if err := r.wait(s.Event+" webhooks", matching, s.Count, s.Exact); err != nil {
+		if failure != nil {
+			return failure
+		}
+		return err
+	}
+	return nil
+}
+
+// wait waits for count to return want, 1 by default. With exact, it then waits for
+// settleTime and fails if more arrived.
+func (r *run) wait(what string, count func() int, want *int, exact bool) error {
+	wanted := 1
+	if want != nil {
+		wanted = *want
+	}
+	got := count()
+	for deadline := time.Now().Add(waitTimeout); got < wanted && time.Now().Before(deadline); got = count() {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if got < wanted {
+		return fmt.Errorf("got %d matching %s after %s, want %d", got, what, waitTimeout, wanted)
+	}
+	if exact {
+		time.Sleep(settleTime)
+		if got = count(); got != wanted {
+			return fmt.Errorf("got %d matching %s, want exactly %d", got, what, wanted)
+		}
+	}
+	return nil
+}
+
+// match checks that the values of document at the paths of expected are the expected ones.
+func (r *run) match(document any, expected map[string]any) error {
+	for path, want := range expected {
+		value, ok := lookup(document, path)
+		if !ok {
+			if want == nil {
+				continue
+			}
+			return fmt.Errorf("%s is missing, want %v", path, want)
+		}
+		if got, wanted := format(value), format(r.expand(want)); got != wanted {
+			return fmt.Errorf("%s = %s, want %s", path, got, wanted)
+		}
+	}
+	return nil
+}
+
+// containsAll reports whether text contains every one of the expanded parts.
+func (r *run) containsAll(text string, parts []string) bool {
+	for _, part := range parts {
+		if !strings.Contains(text, r.expandString(part)) {
+			return false
+		}
+	}
+	return true
+}
+
+// expand replaces the references to variables in the strings of value.
+func (r *run) expand(value any) any {
+	switch v := value.(type) {
+	case string:
+		return r.expandString(v)
+	case map[string]any:
+		expanded := make(map[string]any, len(v))
+		for key, item := range v {
+			expanded[key] = r.expand(item)
+		}
+		return expanded
+	case []any:
+		expanded := make([]any, len(v))
+		for i, item := range v {
+			expanded[i] = r.expand(item)
+		}
+		return expanded
+	}
+	return value
+}
+
+// expandString replaces the references to variables in s, failing the test on unknown
+// variables.
+func (r *run) expandString(s string) string {
+	return variablePattern.ReplaceAllStringFunc(s, func(reference string) string {
+		name := variablePattern.FindStringSubmatch(reference)[1]
+		value, ok := r.vars[name]
+		if !ok {
+			r.t.Fatalf("unknown variable %s", reference)
+		}
+		return value
+	})
+}
+
+// decodeJSON decodes data, keeping numbers as written so that large IDs stay exact.
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document any
+	err := decoder.Decode(&document)
+	return document, err
+}
+
+// lookup returns the value at the dotted path of document, whose parts are object keys or
+// array indexes.
+func lookup(document any, path string) (any, bool) {
+	value := document
+	for _, part := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			item, ok := v[part]
+			if !ok {
+				return nil, false
+			}
+			value = item
+		case []any:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// format returns the text of a JSON or YAML value, so that both compare alike.
+func format(value any) string {
+	switch value.(type) {
+	case map[string]any, []any:
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
+
+// containsAddress reports whether addresses contains address, case-insensitively.
+func containsAddress(addresses []string, address string) bool {
+	for _, a := range addresses {
+		if strings.EqualFold(a, address) {
+			return true
+		}
+	}
+	return false
+}
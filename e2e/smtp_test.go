@@ -0,0 +1,111 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// email is a message received by the fakeSMTP.
+type email struct {
+	From string
+	To   []string
+	// Data is the message, headers and body, as sent.
+	Data string
+}
+
+// fakeSMTP is an SMTP server accepting every message without authentication or TLS, and
+// keeping them for the scenarios to check.
+type fakeSMTP struct {
+	port int
+
+	mu     sync.Mutex
+	emails []email
+}
+
+// startFakeSMTP starts a fakeSMTP, stopped when the test ends.
+func startFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("start the fake SMTP server: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeSMTP{port: listener.Addr().(*net.TCPAddr).Port}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+// Emails returns a copy of the messages received so far.
+func (s *fakeSMTP) Emails() []email {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]email(nil), s.emails...)
+}
+
+// serve speaks the subset of SMTP used by net/smtp.SendMail on conn.
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	var current email
+
+	_ = text.PrintfLine("220 fake SMTP ready")
+	for {
+		line, err := reader.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			_ = text.PrintfLine("250 fake SMTP")
+		case "MAIL":
+			current = email{From: address(arg)}
+			_ = text.PrintfLine("250 OK")
+		case "RCPT":
+			current.To = append(current.To, address(arg))
+			_ = text.PrintfLine("250 OK")
+		case "DATA":
+			_ = text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := reader.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			current.Data = string(data)
+			s.mu.Lock()
+			s.emails = append(s.emails, current)
+			s.mu.Unlock()
+			_ = text.PrintfLine("250 OK")
+		case "RSET", "NOOP":
+			_ = text.PrintfLine("250 OK")
+		case "QUIT":
+			_ = text.PrintfLine("221 Bye")
+			return
+		default:
+			_ = text.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// address extracts the address of the argument of a MAIL or RCPT command, such as
+// "FROM:<a@example.com> BODY=8BITMIME".
+func address(arg string) string {
+	_, rest, _ := strings.Cut(arg, "<")
+	addr, _, _ := strings.Cut(rest, ">")
+	return addr
+}
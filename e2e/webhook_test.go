@@ -0,0 +1,69 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"api-contact-form/webhooks"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// delivery is a webhook received by the webhookReceiver.
+type delivery struct {
+	Event     string
+	Timestamp string
+	Signature string
+	Body      []byte
+}
+
+// Verify reports whether the delivery is signed with secret.
+func (d delivery) Verify(secret string) bool {
+	return d.Signature == "sha256="+webhooks.Sign(secret, d.Timestamp, d.Body)
+}
+
+// webhookReceiver is an HTTP endpoint accepting every webhook, and keeping them for the
+// scenarios to check.
+type webhookReceiver struct {
+	url string
+
+	mu         sync.Mutex
+	deliveries []delivery
+}
+
+// startWebhookReceiver starts a webhookReceiver, stopped when the test ends.
+func startWebhookReceiver(t *testing.T) *webhookReceiver {
+	t.Helper()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(http.HandlerFunc(receiver.receive))
+	t.Cleanup(server.Close)
+	receiver.url = server.URL
+	return receiver
+}
+
+// Deliveries returns a copy of the webhooks received so far.
+func (r *webhookReceiver) Deliveries() []delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]delivery(nil), r.deliveries...)
+}
+
+// receive records a webhook and acknowledges it.
+func (r *webhookReceiver) receive(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.mu.Lock()
+	r.deliveries = append(r.deliveries, delivery{
+		Event:     req.Header.Get("X-Webhook-Event"),
+		Timestamp: req.Header.Get("X-Webhook-Timestamp"),
+		Signature: req.Header.Get("X-Webhook-Signature"),
+		Body:      body,
+	})
+	r.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,93 @@
+// Package enrichment finds the company of submitters from the domain of their email
+// address, so that sales can triage contacts from prospects without looking them up.
+//
+// This file implements the APIProvider, which looks companies up with a company data
+// API answering like the Clearbit Company API.
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// APIProvider looks companies up with an HTTP API. The API answers a GET request with a
+// JSON object holding the "name" of the company and its number of employees, as
+// "metrics.employeesRange", "metrics.employees" or "size", and with a 404 status code for
+// unknown domains.
+type APIProvider struct {
+	urlTemplate string
+	apiKey      string
+	client      *http.Client
+}
+
+// NewAPIProvider creates an APIProvider requesting urlTemplate, in which "{domain}" is
+// replaced by the domain looked up, such as
+// "https://company.clearbit.com/v2/companies/find?domain={domain}". The API key, when not
+// empty, is sent as a bearer token.
+func NewAPIProvider(urlTemplate, apiKey string) (*APIProvider, error) {
+	if !strings.Contains(urlTemplate, "{domain}") {
+		return nil, errors.New("enrichment API URL must contain {domain}")
+	}
+	if _, err := url.Parse(strings.ReplaceAll(urlTemplate, "{domain}", "example.com")); err != nil {
+		return nil, fmt.Errorf("invalid enrichment API URL: %w", err)
+	}
+	return &APIProvider{urlTemplate: urlTemplate, apiKey: apiKey, client: &http.Client{}}, nil
+}
+
+// apiCompany is the part of the answer of the API used by the APIProvider.
+type apiCompany struct {
+	Name    string `json:"name"`
+	Size    string `json:"size"`
+	Metrics struct {
+		Employees      *int   `json:"employees"`
+		EmployeesRange string `json:"employeesRange"`
+	} `json:"metrics"`
+}
+
+// Lookup implements Provider.
+func (p *APIProvider) Lookup(ctx context.Context, domain string) (Company, error) {
+	target := strings.ReplaceAll(p.urlTemplate, "{domain}", url.QueryEscape(domain))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return Company{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Company{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return Company{Domain: domain}, nil
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Company{}, fmt.Errorf("enrichment API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var answer apiCompany
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&answer); err != nil {
+		return Company{}, fmt.Errorf("decode enrichment API response: %w", err)
+	}
+
+	company := Company{Domain: domain, Name: answer.Name, Size: answer.Size}
+	switch {
+	case answer.Metrics.EmployeesRange != "":
+		company.Size = answer.Metrics.EmployeesRange
+	case answer.Metrics.Employees != nil:
+		company.Size = strconv.Itoa(*answer.Metrics.Employees)
+	}
+	return company, nil
+}
@@ -0,0 +1,197 @@
+// Package enrichment finds the company of submitters from the domain of their email
+// address, so that sales can triage contacts from prospects without looking them up.
+//
+// The Enricher derives the company name from the domain itself when a contact is
+// created, and optionally looks the company up with a Provider, such as a company data
+// API, in the background, so that a slow or unavailable API never delays a submission.
+package enrichment
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/jobs"
+	"api-contact-form/repositories"
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Company describes the company behind an email domain.
+type Company struct {
+	// Domain is the domain registered by the company, without the subdomains of its
+	// email addresses.
+	Domain string
+	// Name is the name of the company.
+	Name string
+	// Size is the number of employees of the company, usually as a range such as "51-250".
+	// It is only known from providers.
+	Size string
+}
+
+// Provider looks companies up by domain.
+type Provider interface {
+	// Lookup returns the company behind domain. Unknown domains return a Company with an
+	// empty Name and a nil error.
+	Lookup(ctx context.Context, domain string) (Company, error)
+}
+
+// secondLevelDomains lists the labels used below country code top-level domains before
+// the name of the registrant, as in "acme.co.uk".
+var secondLevelDomains = map[string]bool{
+	"ac": true, "co": true, "com": true, "go": true, "gov": true, "net": true, "or": true, "org": true,
+}
+
+// lookup is a company waiting to be looked up for a contact.
+type lookup struct {
+	contactID uint
+	domain    string
+}
+
+// cached is a company looked up by the provider, remembered until expiresAt.
+type cached struct {
+	company   Company
+	expiresAt time.Time
+}
+
+// Enricher derives the company of new contacts and looks it up with its provider in the
+// background. A nil Enricher is disabled.
+type Enricher struct {
+	provider   Provider
+	repository repositories.ContactRepository
+	timeout    time.Duration
+	cacheTTL   time.Duration
+
+	queue *jobs.Queue[lookup]
+	wg    sync.WaitGroup
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+// NewEnricher creates an Enricher looking companies up with provider, which may be nil to
+// only derive them from the domains, and recording them with repository. Each lookup is
+// cancelled after timeout and its result is reused for cacheTTL. At most queueSize lookups
+// wait to be made.
+func NewEnricher(provider Provider, repository repositories.ContactRepository, timeout, cacheTTL time.Duration, queueSize int) *Enricher {
+	return &Enricher{
+		provider:   provider,
+		repository: repository,
+		timeout:    timeout,
+		cacheTTL:   cacheTTL,
+		queue:      jobs.NewQueue[lookup]("enrichment", queueSize),
+		cache:      make(map[string]cached),
+	}
+}
+
+// Derive returns the company behind the domain of email, named after the domain registered
+// by the company, such as "Acme Corp" at "acme-corp.co.uk" for "jane@mail.acme-corp.co.uk".
+// Addresses of free email providers, and every address for a nil Enricher, return an empty
+// Company.
+func (e *Enricher) Derive(email string) Company {
+	domain := helpers.EmailDomain(email)
+	if e == nil || domain == "" || helpers.MatchesDomain(domain, helpers.FreeEmailDomains) {
+		return Company{}
+	}
+
+	// Skip the top-level domain and the label below country code top-level domains to
+	// find the label registered by the company, and drop the subdomains before it.
+	labels := strings.Split(domain, ".")
+	registered := max(len(labels)-2, 0)
+	if registered > 0 && len(labels[len(labels)-1]) == 2 && secondLevelDomains[labels[registered]] {
+		registered--
+	}
+	labels = labels[registered:]
+
+	words := strings.FieldsFunc(labels[0], func(r rune) bool { return r == '-' || r == '_' })
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return Company{Domain: strings.Join(labels, "."), Name: strings.Join(words, " ")}
+}
+
+// Enqueue queues the lookup of the company behind the email domain of a contact without
+// blocking, when the Enricher has a provider. When the queue is full the lookup is dropped
+// and logged.
+func (e *Enricher) Enqueue(contactID uint, domain string) {
+	if e == nil || e.provider == nil || domain == "" {
+		return
+	}
+	if !e.queue.Push(lookup{contactID: contactID, domain: domain}) {
+		log.Printf("Enrichment queue full, company of contact %d not looked up", contactID)
+	}
+}
+
+// Start launches the given number of workers making the queued lookups. They stop once
+// ctx is cancelled; lookups still queued at that time are dropped.
+func (e *Enricher) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			for {
+				next, ok := e.queue.Next(ctx)
+				if !ok {
+					return
+				}
+				e.enrich(ctx, next)
+			}
+		}()
+	}
+}
+
+// Wait blocks until the workers have stopped.
+func (e *Enricher) Wait() {
+	e.wg.Wait()
+}
+
+// QueueStats returns the health of the queue of the lookups.
+func (e *Enricher) QueueStats() jobs.Stats {
+	return e.queue.Stats()
+}
+
+// enrich looks up the company of a contact and records it when the provider knows it.
+func (e *Enricher) enrich(ctx context.Context, next lookup) {
+	company, err := e.lookup(ctx, next.domain)
+	if err != nil {
+		log.Printf("Enrichment of contact %d failed: %v", next.contactID, err)
+		e.queue.Failed()
+		return
+	}
+	if company.Name == "" {
+		return
+	}
+
+	if err := e.repository.SetCompany(ctx, next.contactID, company.Name, company.Size); err != nil {
+		log.Printf("Enrichment of contact %d not recorded: %v", next.contactID, err)
+		e.queue.Failed()
+	}
+}
+
+// lookup returns the company behind domain from the cache, or from the provider.
+func (e *Enricher) lookup(ctx context.Context, domain string) (Company, error) {
+	now := time.Now()
+	e.mu.Lock()
+	entry, ok := e.cache[domain]
+	e.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.company, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	company, err := e.provider.Lookup(ctx, domain)
+	if err != nil {
+		return Company{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for key, entry := range e.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(e.cache, key)
+		}
+	}
+	e.cache[domain] = cached{company: company, expiresAt: now.Add(e.cacheTTL)}
+	return company, nil
+}
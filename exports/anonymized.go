@@ -0,0 +1,91 @@
+// Package exports writes contacts to spreadsheet, address book and calendar files for people
+// outside the admin view.
+//
+// This file implements the anonymized layout, which lets analysts study submission
+// patterns without handling personal data.
+package exports
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// AnonymizedColumns are the header of anonymized exports, in column order.
+//
+// The name, email address, phone number and IP address are replaced with keyed hashes,
+// which tell submissions of the same person apart without revealing them. The message
+// is replaced with its length.
+var AnonymizedColumns = []string{
+	"name_hash", "email_hash", "phone_hash", "ip_hash", "name_length", "message_length",
+	"message_words", "channel", "status", "spam", "duplicate", "email_issue",
+	"consent_given", "consent_version", "created_at", "updated_at", "status_changed_at",
+}
+
+// hashLength is the number of bytes of the HMAC kept in hashes, which is plenty to
+// tell values apart.
+const hashLength = 16
+
+// NewAnonymizedWriter creates a Writer producing a file of the given format on w, with
+// the AnonymizedColumns. Personal data is hashed with HMAC-SHA256 under key, so that
+// the hashes cannot be reversed by hashing guesses without it. An empty key is replaced
+// with a random one, so that the hashes only match within the file.
+func NewAnonymizedWriter(format Format, w io.Writer, key []byte) (Writer, error) {
+	if format == FormatVCard {
+		return nil, ErrUnknownAnonymizedFormat
+	}
+	if len(key) == 0 {
+		key = make([]byte, sha256.Size)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return newWriter(format, w, layout{columns: AnonymizedColumns, row: anonymizedRow(key)})
+}
+
+// anonymizedRow returns a function returning the cells of a contact, in the order of
+// AnonymizedColumns. Times are written as RFC 3339 in the application timezone.
+func anonymizedRow(key []byte) func(contact *models.Contact) []string {
+	hash := func(value string) string {
+		if value == "" {
+			return ""
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil)[:hashLength])
+	}
+
+	return func(contact *models.Contact) []string {
+		statusChangedAt := ""
+		if contact.StatusChangedAt != nil {
+			statusChangedAt = contact.StatusChangedAt.In(helpers.AppTimezone()).Format(time.RFC3339)
+		}
+		return []string{
+			hash(strings.ToLower(strings.TrimSpace(contact.FullName))),
+			hash(strings.ToLower(strings.TrimSpace(contact.Email))),
+			hash(strings.TrimSpace(contact.Phone)),
+			hash(contact.ConsentIP),
+			strconv.Itoa(utf8.RuneCountInString(contact.FullName)),
+			strconv.Itoa(utf8.RuneCountInString(contact.Message)),
+			strconv.Itoa(len(strings.Fields(contact.Message))),
+			string(contact.Channel),
+			string(contact.Status),
+			strconv.FormatBool(contact.Status == models.StatusSpam),
+			strconv.FormatBool(contact.DuplicateOfID != nil),
+			string(contact.EmailIssue),
+			strconv.FormatBool(contact.ConsentGiven),
+			contact.ConsentVersion,
+			contact.CreatedAt.In(helpers.AppTimezone()).Format(time.RFC3339),
+			contact.UpdatedAt.In(helpers.AppTimezone()).Format(time.RFC3339),
+			statusChangedAt,
+		}
+	}
+}
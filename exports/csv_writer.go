@@ -0,0 +1,41 @@
+// Package exports writes contacts to spreadsheet, address book and calendar files for people
+// outside the admin view.
+//
+// This file implements the CSV format.
+package exports
+
+import (
+	"api-contact-form/models"
+	"encoding/csv"
+	"io"
+)
+
+// csvWriter writes contacts as CSV rows.
+type csvWriter struct {
+	w   *csv.Writer
+	row func(contact *models.Contact) []string
+}
+
+// newCSVWriter creates a csvWriter and writes the header row of layout.
+func newCSVWriter(w io.Writer, layout layout) (*csvWriter, error) {
+	writer := &csvWriter{w: csv.NewWriter(w), row: layout.row}
+	if err := writer.w.Write(layout.columns); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write appends a row for contact. Rows are flushed to the underlying writer as its buffer fills.
+func (w *csvWriter) Write(contact *models.Contact) error {
+	cells := w.row(contact)
+	for i := range cells {
+		cells[i] = sanitize(cells[i])
+	}
+	return w.w.Write(cells)
+}
+
+// Close flushes the buffered rows.
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
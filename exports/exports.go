@@ -0,0 +1,125 @@
+// Package exports writes contacts to spreadsheet, address book and calendar files for people
+// outside the admin view.
+//
+// Contacts are written one at a time through a Writer, so that an export can be
+// streamed from the repository in batches instead of being built in memory.
+package exports
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/models"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a file format contacts can be exported to.
+type Format string
+
+const (
+	// FormatCSV is comma-separated values with a header row.
+	FormatCSV Format = "csv"
+	// FormatXLSX is an Excel workbook with a single sheet.
+	FormatXLSX Format = "xlsx"
+	// FormatVCard is a vCard 4.0 address book with the name, email and phone of contacts.
+	FormatVCard Format = "vcf"
+)
+
+// ErrUnknownFormat is returned by NewWriter for formats other than csv, xlsx and vcf.
+var ErrUnknownFormat = errors.New("format must be csv, xlsx or vcf")
+
+// ErrUnknownAnonymizedFormat is returned by NewAnonymizedWriter for formats other than csv
+// and xlsx, as an anonymized address book would be of no use.
+var ErrUnknownAnonymizedFormat = errors.New("format of anonymized exports must be csv or xlsx")
+
+// ContentType returns the MIME type of files of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatVCard:
+		return "text/vcard; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Columns are the header of every export, in column order.
+var Columns = []string{
+	"id", "name", "email", "phone", "message", "channel", "status",
+	"consent_given", "consent_version", "created_at", "updated_at",
+}
+
+// layout is the columns of an export and how contacts are written to them.
+type layout struct {
+	columns []string
+	row     func(contact *models.Contact) []string
+}
+
+// fullLayout writes every column of Columns.
+var fullLayout = layout{columns: Columns, row: row}
+
+// Writer writes contacts to an export file.
+type Writer interface {
+	// Write appends a row for contact.
+	Write(contact *models.Contact) error
+	// Close completes the file. It must be called once every contact was written.
+	Close() error
+}
+
+// NewWriter creates a Writer producing a file of the given format on w.
+// The header row of spreadsheets is written before the first contact.
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	if format == FormatVCard {
+		return newVCardWriter(w), nil
+	}
+	return newWriter(format, w, fullLayout)
+}
+
+// newWriter creates a Writer producing a file of the given format and layout on w.
+func newWriter(format Format, w io.Writer, layout layout) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, layout)
+	case FormatXLSX:
+		return newXLSXWriter(w, layout)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// row returns the cells of contact, in the order of Columns.
+// Times are written as RFC 3339 in the application timezone.
+func row(contact *models.Contact) []string {
+	return []string{
+		strconv.FormatUint(uint64(contact.ID), 10),
+		contact.FullName,
+		contact.Email,
+		contact.Phone,
+		contact.Message,
+		string(contact.Channel),
+		string(contact.Status),
+		strconv.FormatBool(contact.ConsentGiven),
+		contact.ConsentVersion,
+		contact.CreatedAt.In(helpers.AppTimezone()).Format(time.RFC3339),
+		contact.UpdatedAt.In(helpers.AppTimezone()).Format(time.RFC3339),
+	}
+}
+
+// numberPattern matches values that are plain numbers, such as phone numbers,
+// which spreadsheet programs do not evaluate as formulas.
+var numberPattern = regexp.MustCompile(`^[+-]?[0-9 ]+$`)
+
+// sanitize neutralizes CSV cells that spreadsheet programs would evaluate as a formula,
+// since submissions are written by the public.
+func sanitize(value string) string {
+	if value == "" || numberPattern.MatchString(value) {
+		return value
+	}
+	if strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,93 @@
+// Package exports writes contacts to spreadsheet, address book and calendar files for people
+// outside the admin view.
+//
+// This file implements the iCalendar feed of follow-ups, to which agents subscribe from
+// their calendar application to be reminded of the contacts to get back to.
+package exports
+
+import (
+	"api-contact-form/models"
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CalendarContentType is the MIME type of iCalendar files.
+const CalendarContentType = "text/calendar; charset=utf-8"
+
+// followUpDuration is the duration of the events of follow-ups, which only stand for a
+// reminder.
+const followUpDuration = "PT15M"
+
+// calendarRefreshInterval is how often calendar applications are asked to fetch the feed.
+const calendarRefreshInterval = "PT1H"
+
+// iCalendarTime is the layout of the UTC times of iCalendars.
+const iCalendarTime = "20060102T150405Z"
+
+// WriteFollowUpCalendar writes followUps to w as an iCalendar named name, with an event
+// and a reminder at the due time of each. The follow-ups must have their contact loaded;
+// now stamps the events.
+func WriteFollowUpCalendar(w io.Writer, name string, followUps []models.FollowUp, now time.Time) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//api-contact-form//Follow-ups//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"NAME:" + textEscaper.Replace(name),
+		"X-WR-CALNAME:" + textEscaper.Replace(name),
+		"REFRESH-INTERVAL;VALUE=DURATION:" + calendarRefreshInterval,
+		"X-PUBLISHED-TTL:" + calendarRefreshInterval,
+	}
+	for i := range followUps {
+		lines = append(lines, followUpEvent(&followUps[i], now)...)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	buffered := bufio.NewWriter(w)
+	for _, line := range lines {
+		if _, err := buffered.WriteString(foldLine(line)); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
+
+// followUpEvent returns the lines of the event of followUp.
+func followUpEvent(followUp *models.FollowUp, now time.Time) []string {
+	summary := "Follow up with contact #" + strconv.FormatUint(uint64(followUp.ContactID), 10)
+	var details []string
+	if followUp.Note != "" {
+		details = append(details, followUp.Note, "")
+	}
+	if contact := followUp.Contact; contact != nil {
+		summary = "Follow up with " + contact.FullName
+		if contact.Email != "" {
+			details = append(details, "Email: "+contact.Email)
+		}
+		if contact.Phone != "" {
+			details = append(details, "Phone: "+contact.Phone)
+		}
+	}
+	summary = textEscaper.Replace(summary)
+
+	return []string{
+		"BEGIN:VEVENT",
+		"UID:follow-up-" + strconv.FormatUint(uint64(followUp.ID), 10) + "@api-contact-form",
+		"DTSTAMP:" + now.UTC().Format(iCalendarTime),
+		"LAST-MODIFIED:" + followUp.UpdatedAt.UTC().Format(iCalendarTime),
+		"DTSTART:" + followUp.DueAt.UTC().Format(iCalendarTime),
+		"DURATION:" + followUpDuration,
+		"SUMMARY:" + summary,
+		"DESCRIPTION:" + textEscaper.Replace(strings.TrimSpace(strings.Join(details, "\n"))),
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"DESCRIPTION:" + summary,
+		"TRIGGER:PT0S",
+		"END:VALARM",
+		"END:VEVENT",
+	}
+}
@@ -0,0 +1,99 @@
+// Package exports writes contacts to spreadsheet, address book and calendar files for people
+// outside the admin view.
+//
+// This file implements the vCard format, which address books of phones and email clients
+// import directly.
+package exports
+
+import (
+	"api-contact-form/models"
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// contentLineLength is the largest number of bytes of a line of a vCard or an iCalendar,
+// longer lines being folded as required by RFC 6350 and RFC 5545.
+const contentLineLength = 75
+
+// textEscaper escapes the characters with a meaning in the text values of vCards and
+// iCalendars.
+var textEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// vCardWriter writes contacts as vCard 4.0 entries.
+type vCardWriter struct {
+	w *bufio.Writer
+}
+
+// newVCardWriter creates a vCardWriter. vCards have no header, so the layout is not used:
+// only the name, email address and phone number of contacts are written.
+func newVCardWriter(w io.Writer) *vCardWriter {
+	return &vCardWriter{w: bufio.NewWriter(w)}
+}
+
+// Write appends the vCard of contact.
+func (w *vCardWriter) Write(contact *models.Contact) error {
+	lines := []string{
+		"BEGIN:VCARD",
+		"VERSION:4.0",
+		"KIND:individual",
+		"UID:urn:contact:" + strconv.FormatUint(uint64(contact.ID), 10),
+		"FN:" + textEscaper.Replace(contact.FullName),
+		"N:" + vCardName(contact.FullName),
+	}
+	if contact.Email != "" {
+		lines = append(lines, "EMAIL;TYPE=home:"+textEscaper.Replace(contact.Email))
+	}
+	if contact.Phone != "" {
+		lines = append(lines, "TEL;VALUE=uri;TYPE=voice:tel:"+contact.Phone)
+	}
+	lines = append(lines, "REV:"+contact.UpdatedAt.UTC().Format("20060102T150405Z"), "END:VCARD")
+
+	for _, line := range lines {
+		if _, err := w.w.WriteString(foldLine(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes the buffered vCards.
+func (w *vCardWriter) Close() error {
+	return w.w.Flush()
+}
+
+// vCardName returns the structured N value of a full name: the last word is taken as the
+// family name and the others as the given names, which suits most names submitted through
+// a single field.
+func vCardName(fullName string) string {
+	words := strings.Fields(fullName)
+	if len(words) == 0 {
+		return ";;;;"
+	}
+	family := words[len(words)-1]
+	given := strings.Join(words[:len(words)-1], " ")
+	return textEscaper.Replace(family) + ";" + textEscaper.Replace(given) + ";;;"
+}
+
+// foldLine terminates line with CRLF, folding it into lines of at most
+// contentLineLength bytes, continued with a space, without splitting a UTF-8 character.
+func foldLine(line string) string {
+	var folded strings.Builder
+	limit := contentLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		folded.WriteString(line[:cut])
+		folded.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with the space.
+		limit = contentLineLength - 1
+	}
+	folded.WriteString(line)
+	folded.WriteString("\r\n")
+	return folded.String()
+}
@@ -0,0 +1,74 @@
+// Package exports writes contacts to spreadsheet, address book and calendar files for people
+// outside the admin view.
+//
+// This file implements the Excel format.
+package exports
+
+import (
+	"api-contact-form/models"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetName is the name of the single sheet of Excel exports.
+const sheetName = "Contacts"
+
+// xlsxWriter writes contacts as rows of an Excel sheet.
+//
+// The stream writer of excelize keeps rows in a temporary file rather than in memory;
+// the workbook is written to the underlying writer on Close.
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	cells  func(contact *models.Contact) []string
+	row    int
+}
+
+// newXLSXWriter creates an xlsxWriter and writes the header row of layout.
+func newXLSXWriter(w io.Writer, layout layout) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	if err := file.SetSheetName("Sheet1", sheetName); err != nil {
+		return nil, err
+	}
+	stream, err := file.NewStreamWriter(sheetName)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &xlsxWriter{out: w, file: file, stream: stream, cells: layout.row}
+	if err := writer.writeRow(layout.columns); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write appends a row for contact. Cells are stored as text, so they need no
+// protection against formulas.
+func (w *xlsxWriter) Write(contact *models.Contact) error {
+	return w.writeRow(w.cells(contact))
+}
+
+// Close completes the sheet and writes the workbook.
+func (w *xlsxWriter) Close() error {
+	defer w.file.Close()
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	return w.file.Write(w.out)
+}
+
+// writeRow writes cells as text to the next row of the sheet.
+func (w *xlsxWriter) writeRow(cells []string) error {
+	w.row++
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(cells))
+	for i, value := range cells {
+		values[i] = value
+	}
+	return w.stream.SetRow(cell, values)
+}
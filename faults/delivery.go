@@ -0,0 +1,56 @@
+// Package faults simulates failures of the database, the SMTP server and the webhook
+// receivers, so the retries, outbox and buffering of the API can be exercised in staging.
+//
+// This file wraps the SMTP and HTTP clients to fail emails and time out webhooks.
+package faults
+
+import (
+	"api-contact-form/config"
+	"fmt"
+	"net/http"
+	"net/smtp"
+)
+
+// SendMail returns send failing with ErrInjected at SMTPFailureRate, as an unreachable
+// SMTP server does. A nil send stands for smtp.SendMail.
+func (i *Injector) SendMail(send config.SendMailFunc) config.SendMailFunc {
+	if send == nil {
+		send = smtp.SendMail
+	}
+	if i == nil || i.config.SMTPFailureRate <= 0 {
+		return send
+	}
+	return func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		if hit(i.config.SMTPFailureRate, "smtp", "failure") {
+			return fmt.Errorf("dial %s: %w", addr, ErrInjected)
+		}
+		return send(addr, auth, from, to, msg)
+	}
+}
+
+// Transport returns transport hanging at WebhookTimeoutRate until the request is cancelled,
+// as an unresponsive receiver does, so that the client timeout expires. A nil transport
+// stands for http.DefaultTransport.
+func (i *Injector) Transport(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if i == nil || i.config.WebhookTimeoutRate <= 0 {
+		return transport
+	}
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		if hit(i.config.WebhookTimeoutRate, "webhook", "timeout") {
+			<-req.Context().Done()
+			return nil, fmt.Errorf("%w: %w", ErrInjected, req.Context().Err())
+		}
+		return transport.RoundTrip(req)
+	})
+}
+
+// roundTripper adapts a function to http.RoundTripper.
+type roundTripper func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,85 @@
+// Package faults simulates failures of the database, the SMTP server and the webhook
+// receivers, so the retries, outbox and buffering of the API can be exercised in staging.
+//
+// Fault injection is off unless FAULTS_ENABLED is set. Each kind of fault then happens
+// at its own configurable rate, between 0 (never) and 1 (always). The SMTP and HTTP
+// wrappers of a nil *Injector inject nothing.
+package faults
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/observability"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrInjected is the error returned by the simulated failures.
+var ErrInjected = errors.New("fault injected")
+
+// Config holds the rates and durations of the simulated failures.
+type Config struct {
+	// DBLatency is the delay added to database statements, at DBLatencyRate.
+	DBLatency     time.Duration
+	DBLatencyRate float64
+	// DBErrorRate is the rate of database statements failing with ErrInjected.
+	DBErrorRate float64
+	// SMTPFailureRate is the rate of emails failing to be sent.
+	SMTPFailureRate float64
+	// WebhookTimeoutRate is the rate of webhook deliveries hanging until they time out.
+	WebhookTimeoutRate float64
+}
+
+// Injector decides which operations fail, according to its Config.
+type Injector struct {
+	config Config
+}
+
+// New creates an Injector simulating the failures of cfg. Rates must be between 0 and 1.
+func New(cfg Config) (*Injector, error) {
+	for name, rate := range map[string]float64{
+		"database latency": cfg.DBLatencyRate,
+		"database error":   cfg.DBErrorRate,
+		"SMTP failure":     cfg.SMTPFailureRate,
+		"webhook timeout":  cfg.WebhookTimeoutRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("%s rate %v is not between 0 and 1", name, rate)
+		}
+	}
+	if cfg.DBLatency < 0 {
+		return nil, fmt.Errorf("database latency %v is negative", cfg.DBLatency)
+	}
+	return &Injector{config: cfg}, nil
+}
+
+// NewFromEnv creates the Injector configured by the FAULT_* variables, or returns nil
+// when FAULTS_ENABLED is not set.
+func NewFromEnv() (*Injector, error) {
+	if !helpers.GetEnvBool("FAULTS_ENABLED", false) {
+		return nil, nil
+	}
+	injector, err := New(Config{
+		DBLatency:          helpers.GetEnvDuration("FAULT_DB_LATENCY", 500*time.Millisecond),
+		DBLatencyRate:      helpers.GetEnvFloat("FAULT_DB_LATENCY_RATE", 0),
+		DBErrorRate:        helpers.GetEnvFloat("FAULT_DB_ERROR_RATE", 0),
+		SMTPFailureRate:    helpers.GetEnvFloat("FAULT_SMTP_FAILURE_RATE", 0),
+		WebhookTimeoutRate: helpers.GetEnvFloat("FAULT_WEBHOOK_TIMEOUT_RATE", 0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Warning: fault injection is enabled: %+v", injector.config)
+	return injector, nil
+}
+
+// hit reports whether an operation fails at rate, counting it in FaultsInjected.
+func hit(rate float64, target, kind string) bool {
+	if rate <= 0 || rand.Float64() >= rate {
+		return false
+	}
+	observability.FaultsInjected.WithLabelValues(target, kind).Inc()
+	return true
+}
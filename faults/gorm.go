@@ -0,0 +1,55 @@
+// Package faults simulates failures of the database, the SMTP server and the webhook
+// receivers, so the retries, outbox and buffering of the API can be exercised in staging.
+//
+// This file implements the GORM plugin slowing down and failing database statements.
+package faults
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Name implements gorm.Plugin.
+func (i *Injector) Name() string {
+	return "faults:db"
+}
+
+// Initialize registers the callback delaying or failing the create, query, update,
+// delete, row and raw statements before they are run.
+func (i *Injector) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	register := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register},
+	}
+	for _, r := range register {
+		if err := r.before("faults:before_"+r.operation, i.statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statement delays the statement by DBLatency and fails it with ErrInjected, each at its
+// configured rate. The GORM callbacks skip statements having an error.
+func (i *Injector) statement(db *gorm.DB) {
+	if hit(i.config.DBLatencyRate, "db", "latency") {
+		timer := time.NewTimer(i.config.DBLatency)
+		select {
+		case <-timer.C:
+		case <-db.Statement.Context.Done():
+			timer.Stop()
+		}
+	}
+	if hit(i.config.DBErrorRate, "db", "error") {
+		db.AddError(ErrInjected)
+	}
+}
@@ -0,0 +1,193 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the AbuseReportHandler lets site visitors report the misuse of an embedded
+// form, and the admins of the instance list these reports and resolve or dismiss them.
+package handlers
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/requests"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultAbuseReportLimit is the number of reports GetAbuseReports returns by default.
+	defaultAbuseReportLimit = 50
+	// maxAbuseReportLimit is the largest number of reports GetAbuseReports returns.
+	maxAbuseReportLimit = 500
+)
+
+// AbuseReportHandler handles HTTP requests related to abuse reports.
+type AbuseReportHandler struct {
+	service services.AbuseReportService
+}
+
+// NewAbuseReportHandler creates a new instance of AbuseReportHandler with the provided AbuseReportService.
+func NewAbuseReportHandler(service services.AbuseReportService) *AbuseReportHandler {
+	return &AbuseReportHandler{service: service}
+}
+
+// CreateAbuseReport records the report of a visitor that an embedded form is misused.
+//
+// It expects a JSON payload matching the AbuseReportRequest structure. Invalid payloads are
+// answered with a 400 status code. On success, it returns the ID of the report with a 201
+// status code; the other details of the report are only shown to admins.
+func (h *AbuseReportHandler) CreateAbuseReport(c *router.Context) {
+	var req requests.AbuseReportRequest
+
+	// Bind the JSON payload to the AbuseReportRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to record the report.
+	report, err := h.service.Report(c.Request.Context(), &req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAbuseReport) {
+			c.JSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, responses.APIResponse{
+		Code:    "CREATED",
+		Message: "Thank you, the report was sent to the administrators",
+		Data:    router.H{"id": report.ID},
+	})
+}
+
+// GetAbuseReports retrieves the abuse reports, newest first.
+//
+// The query string filters with "status", and pages with "limit" (50 by default, at most
+// 500) and "offset". Invalid parameters are answered with a 400 status code. On success,
+// it returns the reports with a 200 status code.
+func (h *AbuseReportHandler) GetAbuseReports(c *router.Context) {
+	// Read the filter and paging from the query string.
+	limit := defaultAbuseReportLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxAbuseReportLimit {
+			c.JSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: fmt.Sprintf("Invalid limit, expected a number between 1 and %d", maxAbuseReportLimit),
+				Data:    nil,
+			})
+			return
+		}
+		limit = parsed
+	}
+	offset, err := nonNegativeQuery(c, "offset")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch the reports using the service layer.
+	reports, err := h.service.ListReports(c.Request.Context(), models.AbuseReportStatus(c.Query("status")), limit, offset)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAbuseReportStatus) {
+			c.JSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Abuse reports retrieved successfully",
+		Data:    responses.AbuseReportResponsesFromModels(reports),
+	})
+}
+
+// UpdateAbuseReportStatus resolves, dismisses or reopens an abuse report by its ID.
+//
+// It expects a JSON payload matching the AbuseReportStatusRequest structure. Invalid
+// payloads are answered with a 400 status code, and missing reports with a 404 status
+// code. On success, it returns the report with a 200 status code.
+func (h *AbuseReportHandler) UpdateAbuseReportStatus(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+
+	var req requests.AbuseReportStatusRequest
+
+	// Bind the JSON payload to the AbuseReportStatusRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to change the status.
+	report, err := h.service.SetStatus(c.Request.Context(), auditActor(c), id, req.Status)
+	switch {
+	case errors.Is(err, services.ErrInvalidAbuseReportStatus):
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Abuse report not found",
+			Data:    nil,
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Abuse report updated successfully",
+		Data:    responses.AbuseReportResponseFromModel(report),
+	})
+}
@@ -0,0 +1,825 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the AdminUserHandler manages the admin user accounts, so that admins can
+// be invited, disabled and reset without direct database access, signs users in, and lets
+// them manage their two-factor authentication, their password and their sessions.
+package handlers
+
+import (
+	"api-contact-form/middleware"
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/requests"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultLoginEventLimit is the number of sign-in attempts GetLoginEvents returns by default.
+	defaultLoginEventLimit = 100
+	// maxLoginEventLimit is the largest number of sign-in attempts GetLoginEvents returns.
+	maxLoginEventLimit = 1000
+)
+
+// AdminUserHandler handles HTTP requests related to admin users and their sign-in.
+type AdminUserHandler struct {
+	service       services.AdminUserService
+	authenticator *middleware.Authenticator
+}
+
+// NewAdminUserHandler creates a new instance of AdminUserHandler with the provided
+// AdminUserService and the Authenticator used to issue JWTs on sign-in.
+func NewAdminUserHandler(service services.AdminUserService, authenticator *middleware.Authenticator) *AdminUserHandler {
+	return &AdminUserHandler{service: service, authenticator: authenticator}
+}
+
+// GetUsers retrieves every admin user, including disabled ones, with their last sign-in.
+//
+// On success, it returns the list of users with a 200 status code.
+func (h *AdminUserHandler) GetUsers(c *router.Context) {
+	// Fetch the users using the service layer.
+	users, err := h.service.ListUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Convert the user models to response formats.
+	userResponses := make([]responses.AdminUserResponse, 0, len(users))
+	for i := range users {
+		userResponses = append(userResponses, responses.AdminUserResponseFromModel(&users[i]))
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Admin users retrieved successfully",
+		Data:    userResponses,
+	})
+}
+
+// InviteUser creates an admin user and emails them a one-time setup link.
+//
+// It expects a JSON payload matching the InviteUserRequest structure.
+// If the email address is already in use, it returns a 409 status code. On success, it
+// returns the user with a 201 status code; this is the only response, besides the password
+// reset, that contains the setup link, so that it can be passed on when it was not emailed.
+func (h *AdminUserHandler) InviteUser(c *router.Context) {
+	var req requests.InviteUserRequest
+
+	// Bind the JSON payload to the InviteUserRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to invite the user.
+	user, link, err := h.service.InviteUser(req.Email, req.Name)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrUserExists) {
+		c.JSON(http.StatusConflict, responses.APIResponse{
+			Code:    "CONFLICT",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	response := responses.AdminUserResponseFromModel(user)
+	response.SetupLink = responses.SetupLinkResponseFromLink(link)
+	c.JSON(http.StatusCreated, responses.APIResponse{
+		Code:    "CREATED",
+		Message: "Admin user invited successfully",
+		Data:    response,
+	})
+}
+
+// DisableUser disables or re-enables an admin user by their ID.
+//
+// It expects a JSON payload matching the DisableUserRequest structure. Disabled users can
+// no longer sign in and their tokens are rejected immediately.
+// On success, it returns the user with a 200 status code.
+func (h *AdminUserHandler) DisableUser(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Bind the JSON payload to the DisableUserRequest struct.
+	var req requests.DisableUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to update the user.
+	user, err := h.service.SetDisabled(uint(id), *req.Disabled)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if respondUserError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Admin user updated successfully",
+		Data:    responses.AdminUserResponseFromModel(user),
+	})
+}
+
+// ResetPassword clears the password of an admin user by their ID and emails them a new
+// one-time setup link.
+//
+// The old password stops working immediately. On success, it returns the user with the
+// setup link and a 200 status code.
+func (h *AdminUserHandler) ResetPassword(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to reset the password.
+	user, link, err := h.service.ResetPassword(uint(id))
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if respondUserError(c, err) {
+		return
+	}
+
+	response := responses.AdminUserResponseFromModel(user)
+	response.SetupLink = responses.SetupLinkResponseFromLink(link)
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Admin user password reset successfully",
+		Data:    response,
+	})
+}
+
+// SetupPassword sets the password of an admin user with the token of their setup link.
+//
+// It expects a JSON payload matching the SetupPasswordRequest structure. Unknown, used and
+// expired tokens are rejected with a 400 status code, and passwords not satisfying the
+// password policy with a 422 status code listing the missed requirements.
+// On success, it returns the user with a 200 status code.
+func (h *AdminUserHandler) SetupPassword(c *router.Context) {
+	var req requests.SetupPasswordRequest
+
+	// Bind the JSON payload to the SetupPasswordRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to set the password.
+	user, err := h.service.CompleteSetup(req.Token, req.Password)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if respondValidationErrors(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrInvalidSetupToken) {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Password set successfully",
+		Data:    responses.AdminUserResponseFromModel(user),
+	})
+}
+
+// Login signs an admin user in with their email address and password.
+//
+// It expects a JSON payload matching the LoginRequest structure. Wrong credentials and
+// disabled users are rejected with a 401 status code, as are users with two-factor
+// authentication who sent no code, with the TWO_FACTOR_REQUIRED code. Locked accounts and
+// blocked client addresses are refused with a 429 status code and a Retry-After header.
+// Users who must set up two-factor authentication first are rejected with a 403 status code; when JWTs
+// are not enabled, a 404 status code is returned. On success, it starts a session for the
+// device and returns a JWT bearer token with its refresh token and a 201 status code.
+func (h *AdminUserHandler) Login(c *router.Context) {
+	var req requests.LoginRequest
+
+	// Bind the JSON payload to the LoginRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Verify the credentials using the service layer.
+	user, err := h.service.Login(req.Email, req.Password, req.Code, loginClient(c))
+	if respondTwoFactorError(c, err) {
+		return
+	}
+
+	if !h.authenticator.TokensEnabled() {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: middleware.ErrTokensDisabled.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Start the session of the device and issue its tokens.
+	session, refreshToken, err := h.service.StartSession(user, loginClient(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	h.respondSessionTokens(c, user, session, refreshToken, http.StatusCreated, "CREATED", "Signed in successfully")
+}
+
+// RefreshToken renews the token of an admin user with the refresh token of their session.
+//
+// It expects a JSON payload matching the RefreshTokenRequest structure. Unknown, used,
+// revoked and expired refresh tokens are rejected with a 401 status code; when JWTs are
+// not enabled, a 404 status code is returned. On success, it returns a new JWT bearer token
+// with a new refresh token and a 200 status code; the previous refresh token stops working.
+func (h *AdminUserHandler) RefreshToken(c *router.Context) {
+	var req requests.RefreshTokenRequest
+
+	// Bind the JSON payload to the RefreshTokenRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if !h.authenticator.TokensEnabled() {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: middleware.ErrTokensDisabled.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Rotate the refresh token using the service layer.
+	user, session, refreshToken, err := h.service.RefreshSession(req.RefreshToken, loginClient(c))
+	if respondSessionError(c, err) {
+		return
+	}
+	h.respondSessionTokens(c, user, session, refreshToken, http.StatusOK, "SUCCESS", "Token refreshed successfully")
+}
+
+// GetSessions retrieves the active sessions of the signed-in admin user, marking the
+// session of the request as current.
+//
+// Callers authenticated with an API key rather than a user token are rejected with a 403
+// status code. On success, it returns the sessions with a 200 status code.
+func (h *AdminUserHandler) GetSessions(c *router.Context) {
+	identity, ok := sessionIdentity(c)
+	if !ok {
+		return
+	}
+
+	// Fetch the sessions using the service layer.
+	sessions, err := h.service.ListSessions(identity.UserID)
+	if respondSessionError(c, err) {
+		return
+	}
+
+	// Convert the session models to response formats.
+	sessionResponses := make([]responses.AdminSessionResponse, 0, len(sessions))
+	for i := range sessions {
+		sessionResponses = append(sessionResponses, responses.AdminSessionResponseFromModel(&sessions[i], identity.SessionID))
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Sessions retrieved successfully",
+		Data:    sessionResponses,
+	})
+}
+
+// RevokeSession signs a session of the signed-in admin user out by its ID; its tokens
+// are rejected immediately.
+//
+// Sessions of other users and inactive sessions give a 404 status code. On success, it
+// returns a 200 status code.
+func (h *AdminUserHandler) RevokeSession(c *router.Context) {
+	identity, ok := sessionIdentity(c)
+	if !ok {
+		return
+	}
+
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to revoke the session.
+	if respondSessionError(c, h.service.RevokeSession(identity.UserID, uint(id))) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Session revoked successfully",
+		Data:    nil,
+	})
+}
+
+// RevokeOtherSessions signs out every session of the signed-in admin user but the session
+// of the request.
+//
+// On success, it returns a 200 status code.
+func (h *AdminUserHandler) RevokeOtherSessions(c *router.Context) {
+	identity, ok := sessionIdentity(c)
+	if !ok {
+		return
+	}
+
+	// Use the service layer to revoke the sessions.
+	if respondSessionError(c, h.service.RevokeSessions(identity.UserID, identity.SessionID)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Other sessions revoked successfully",
+		Data:    nil,
+	})
+}
+
+// ChangePassword changes the password of the signed-in admin user and signs out their
+// other sessions.
+//
+// It expects a JSON payload matching the ChangePasswordRequest structure. A wrong current
+// password is rejected with a 403 status code, and a new password not satisfying the
+// password policy with a 422 status code. On success, it returns a 200 status code.
+func (h *AdminUserHandler) ChangePassword(c *router.Context) {
+	identity, ok := sessionIdentity(c)
+	if !ok {
+		return
+	}
+
+	// Bind the JSON payload to the ChangePasswordRequest struct.
+	var req requests.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to change the password.
+	err := h.service.ChangePassword(identity.UserID, identity.SessionID, req.CurrentPassword, req.Password)
+	if respondValidationErrors(c, err) {
+		return
+	}
+	if respondSessionError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Password changed; other sessions were signed out",
+		Data:    nil,
+	})
+}
+
+// EnrollTwoFactor starts setting up two-factor authentication for the user of the
+// credentials in the TwoFactorRequest payload.
+//
+// On success, it returns the secret of the authenticator app and its otpauth:// URL with
+// a 200 status code. The secret is only required at sign-in once ActivateTwoFactor confirms it.
+func (h *AdminUserHandler) EnrollTwoFactor(c *router.Context) {
+	var req requests.TwoFactorRequest
+
+	// Bind the JSON payload to the TwoFactorRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Generate the secret using the service layer.
+	enrollment, err := h.service.EnrollTwoFactor(req.Email, req.Password, loginClient(c))
+	if respondTwoFactorError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Add the secret to your authenticator app, then activate it with a code",
+		Data: responses.TwoFactorEnrollmentResponse{
+			Secret: enrollment.Secret,
+			URL:    enrollment.URL,
+		},
+	})
+}
+
+// ActivateTwoFactor completes setting up two-factor authentication with a code of the
+// newly added authenticator app.
+//
+// On success, it returns the recovery codes with a 200 status code; they are only shown once.
+func (h *AdminUserHandler) ActivateTwoFactor(c *router.Context) {
+	var req requests.TwoFactorRequest
+
+	// Bind the JSON payload to the TwoFactorRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Activate the second factor using the service layer.
+	codes, err := h.service.ActivateTwoFactor(req.Email, req.Password, req.Code, loginClient(c))
+	if respondTwoFactorError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Two-factor authentication enabled; store the recovery codes safely",
+		Data:    responses.RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, e.g. when they ran out.
+//
+// On success, it returns the new recovery codes with a 200 status code.
+func (h *AdminUserHandler) RegenerateRecoveryCodes(c *router.Context) {
+	var req requests.TwoFactorRequest
+
+	// Bind the JSON payload to the TwoFactorRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Replace the recovery codes using the service layer.
+	codes, err := h.service.RegenerateRecoveryCodes(req.Email, req.Password, req.Code, loginClient(c))
+	if respondTwoFactorError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Recovery codes regenerated; the previous codes no longer work",
+		Data:    responses.RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+// DisableTwoFactor turns off the two-factor authentication of the user.
+//
+// When the instance requires two-factor authentication, it returns a 403 status code.
+// On success, it returns a 200 status code.
+func (h *AdminUserHandler) DisableTwoFactor(c *router.Context) {
+	var req requests.TwoFactorRequest
+
+	// Bind the JSON payload to the TwoFactorRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Disable the second factor using the service layer.
+	if respondTwoFactorError(c, h.service.DisableTwoFactor(req.Email, req.Password, req.Code, loginClient(c))) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Two-factor authentication disabled",
+		Data:    nil,
+	})
+}
+
+// ResetTwoFactor turns off the two-factor authentication of an admin user by their ID,
+// for users who lost both their authenticator app and their recovery codes.
+//
+// On success, it returns the user with a 200 status code.
+func (h *AdminUserHandler) ResetTwoFactor(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to reset the second factor.
+	user, err := h.service.ResetTwoFactor(uint(id))
+	if respondUserError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Admin user two-factor authentication reset successfully",
+		Data:    responses.AdminUserResponseFromModel(user),
+	})
+}
+
+// UnlockUser ends the lockout of an admin user by their ID, after too many failed sign-in
+// attempts, and resets their failed attempts.
+//
+// On success, it returns the user with a 200 status code.
+func (h *AdminUserHandler) UnlockUser(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to unlock the user.
+	user, err := h.service.UnlockUser(uint(id))
+	if respondUserError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Admin user unlocked successfully",
+		Data:    responses.AdminUserResponseFromModel(user),
+	})
+}
+
+// GetLoginEvents retrieves the audit log of the sign-in attempts, newest first.
+//
+// The query string filters with "email", "ip" and "outcome" (success, failure,
+// account_locked or ip_blocked), and limits the number of events with "limit" (100 by
+// default, at most 1000). Invalid parameters are answered with a 400 status code.
+// On success, it returns the events with a 200 status code.
+func (h *AdminUserHandler) GetLoginEvents(c *router.Context) {
+	// Read the filters from the query string.
+	filter := repositories.LoginEventFilter{
+		Email:    strings.TrimSpace(c.Query("email")),
+		ClientIP: c.Query("ip"),
+		Outcome:  models.LoginOutcome(c.Query("outcome")),
+		Limit:    defaultLoginEventLimit,
+	}
+	if filter.Outcome != "" && !filter.Outcome.Valid() {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid outcome",
+			Data:    nil,
+		})
+		return
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxLoginEventLimit {
+			c.JSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: fmt.Sprintf("Invalid limit, expected a number between 1 and %d", maxLoginEventLimit),
+				Data:    nil,
+			})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	// Fetch the events using the service layer.
+	events, err := h.service.ListLoginEvents(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Convert the event models to response formats.
+	eventResponses := make([]responses.LoginEventResponse, 0, len(events))
+	for i := range events {
+		eventResponses = append(eventResponses, responses.LoginEventResponseFromModel(&events[i]))
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Sign-in attempts retrieved successfully",
+		Data:    eventResponses,
+	})
+}
+
+// respondSessionTokens issues the JWT of user in session and writes it with the refresh
+// token of the session.
+func (h *AdminUserHandler) respondSessionTokens(c *router.Context, user *models.AdminUser, session *models.AdminSession, refreshToken string, status int, code, message string) {
+	token, expiresAt, err := h.authenticator.IssueToken(middleware.UserIdentity(user, session))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(status, responses.APIResponse{
+		Code:    code,
+		Message: message,
+		Data: responses.TokenResponse{
+			Token:            token,
+			TokenType:        "Bearer",
+			ExpiresAt:        expiresAt,
+			RefreshToken:     refreshToken,
+			RefreshExpiresAt: &session.ExpiresAt,
+		},
+	})
+}
+
+// sessionIdentity returns the identity of an admin user signed in with a session. Other
+// callers, e.g. those authenticated with an API key, are rejected with a 403 status code.
+func sessionIdentity(c *router.Context) (*middleware.Identity, bool) {
+	identity := middleware.CurrentIdentity(c)
+	if identity == nil || identity.SessionID == 0 {
+		c.JSON(http.StatusForbidden, responses.APIResponse{
+			Code:    "FORBIDDEN",
+			Message: "Sessions are only available to admin users signed in with a password",
+			Data:    nil,
+		})
+		return nil, false
+	}
+	return identity, true
+}
+
+// respondSessionError writes the response for an error of refreshing a token or of
+// managing sessions, and reports whether err was non-nil.
+func respondSessionError(c *router.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	status, code, message := http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", err.Error()
+	switch {
+	case errors.Is(err, services.ErrInvalidRefreshToken):
+		status, code = http.StatusUnauthorized, "UNAUTHORIZED"
+	case errors.Is(err, services.ErrIncorrectPassword):
+		status, code = http.StatusForbidden, "FORBIDDEN"
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status, code, message = http.StatusNotFound, "NOT_FOUND", "Session not found"
+	}
+	c.JSON(status, responses.APIResponse{
+		Code:    code,
+		Message: message,
+		Data:    nil,
+	})
+	return true
+}
+
+// loginClient describes the client of the request for the sign-in audit and throttling.
+func loginClient(c *router.Context) services.LoginClient {
+	return services.LoginClient{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// respondTwoFactorError writes the response for an error of signing in or of managing
+// two-factor authentication, and reports whether err was non-nil.
+func respondTwoFactorError(c *router.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	status, code := http.StatusInternalServerError, "INTERNAL_SERVER_ERROR"
+	var locked *services.LoginLockedError
+	switch {
+	case errors.As(err, &locked):
+		status, code = http.StatusTooManyRequests, "TOO_MANY_REQUESTS"
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	case errors.Is(err, services.ErrInvalidCredentials):
+		status, code = http.StatusUnauthorized, "UNAUTHORIZED"
+	case errors.Is(err, services.ErrTwoFactorRequired):
+		status, code = http.StatusUnauthorized, "TWO_FACTOR_REQUIRED"
+	case errors.Is(err, services.ErrTwoFactorEnrollmentRequired):
+		status, code = http.StatusForbidden, "TWO_FACTOR_ENROLLMENT_REQUIRED"
+	case errors.Is(err, services.ErrTwoFactorPolicy):
+		status, code = http.StatusForbidden, "FORBIDDEN"
+	case errors.Is(err, services.ErrTwoFactorEnabled), errors.Is(err, services.ErrTwoFactorNotEnabled):
+		status, code = http.StatusConflict, "CONFLICT"
+	}
+	c.JSON(status, responses.APIResponse{
+		Code:    code,
+		Message: err.Error(),
+		Data:    nil,
+	})
+	return true
+}
+
+// respondUserError writes the response for an error of a user looked up by ID:
+// gorm.ErrRecordNotFound gives a 404 status code and other errors a 500 status code.
+// It reports whether err was non-nil.
+func respondUserError(c *router.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Admin user not found",
+			Data:    nil,
+		})
+		return true
+	}
+	c.JSON(http.StatusInternalServerError, responses.APIResponse{
+		Code:    "INTERNAL_SERVER_ERROR",
+		Message: err.Error(),
+		Data:    nil,
+	})
+	return true
+}
@@ -0,0 +1,272 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the APIKeyHandler manages the API keys used to authenticate against the
+// API, so that keys can be issued, rotated and revoked without redeploying.
+package handlers
+
+import (
+	"api-contact-form/middleware"
+	"api-contact-form/requests"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyHandler handles HTTP requests related to API keys and tokens.
+type APIKeyHandler struct {
+	service       services.APIKeyService
+	authenticator *middleware.Authenticator
+}
+
+// NewAPIKeyHandler creates a new instance of APIKeyHandler with the provided APIKeyService
+// and the Authenticator used to issue JWTs.
+func NewAPIKeyHandler(service services.APIKeyService, authenticator *middleware.Authenticator) *APIKeyHandler {
+	return &APIKeyHandler{service: service, authenticator: authenticator}
+}
+
+// GetAPIKeys retrieves every issued API key, including revoked and expired ones.
+//
+// The keys themselves are never returned, only their prefix.
+// On success, it returns the list of keys with a 200 status code.
+func (h *APIKeyHandler) GetAPIKeys(c *router.Context) {
+	// Fetch the keys using the service layer.
+	keys, err := h.service.ListKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Convert the key models to response formats.
+	keyResponses := make([]responses.APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		keyResponses = append(keyResponses, responses.APIKeyResponseFromModel(&keys[i]))
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "API keys retrieved successfully",
+		Data:    keyResponses,
+	})
+}
+
+// CreateAPIKey issues a new API key.
+//
+// It expects a JSON payload matching the APIKeyRequest structure.
+// On success, it returns the key with a 201 status code; this is the only response
+// that contains the key itself.
+func (h *APIKeyHandler) CreateAPIKey(c *router.Context) {
+	var req requests.APIKeyRequest
+
+	// Bind the JSON payload to the APIKeyRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to issue the key.
+	record, key, err := h.service.IssueKey(req.Name, req.Role, req.ExpiresAt)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrInvalidRole) {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	response := responses.APIKeyResponseFromModel(record)
+	response.Key = key
+	c.JSON(http.StatusCreated, responses.APIResponse{
+		Code:    "CREATED",
+		Message: "API key created successfully",
+		Data:    response,
+	})
+}
+
+// RotateAPIKey replaces an API key by its ID.
+//
+// It accepts an optional JSON payload matching the RotateAPIKeyRequest structure; the old key
+// stays valid for its grace period, or is revoked immediately without one.
+// On success, it returns the replacement key with a 201 status code.
+func (h *APIKeyHandler) RotateAPIKey(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Bind the optional payload and parse the grace period.
+	var req requests.RotateAPIKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+	}
+	var grace time.Duration
+	if req.GracePeriod != "" {
+		grace, err = time.ParseDuration(req.GracePeriod)
+		if err != nil || grace < 0 {
+			c.JSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: "Invalid grace_period",
+				Data:    nil,
+			})
+			return
+		}
+	}
+
+	// Use the service layer to rotate the key.
+	record, key, err := h.service.RotateKey(uint(id), grace)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, services.ErrInvalidAPIKey) {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Active API key not found",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	response := responses.APIKeyResponseFromModel(record)
+	response.Key = key
+	c.JSON(http.StatusCreated, responses.APIResponse{
+		Code:    "CREATED",
+		Message: "API key rotated successfully",
+		Data:    response,
+	})
+}
+
+// RevokeAPIKey immediately invalidates an API key by its ID.
+//
+// Revoking a key that is already revoked has no effect.
+// On success, it returns the revoked key with a 200 status code.
+func (h *APIKeyHandler) RevokeAPIKey(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to revoke the key.
+	record, err := h.service.RevokeKey(uint(id))
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "API key not found",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "API key revoked successfully",
+		Data:    responses.APIKeyResponseFromModel(record),
+	})
+}
+
+// IssueToken exchanges the API key of the request for a short-lived JWT bearer token
+// carrying the same role, e.g. for browser-based admin tools that should not hold the key.
+//
+// Requests authenticated with a JWT are rejected with a 403 status code, so that tokens
+// cannot be renewed indefinitely. When JWTs are not enabled, a 404 status code is returned.
+func (h *APIKeyHandler) IssueToken(c *router.Context) {
+	identity := middleware.CurrentIdentity(c)
+	if identity.FromToken {
+		c.JSON(http.StatusForbidden, responses.APIResponse{
+			Code:    "FORBIDDEN",
+			Message: "Tokens can only be issued for API keys",
+			Data:    nil,
+		})
+		return
+	}
+
+	token, expiresAt, err := h.authenticator.IssueToken(identity)
+	if errors.Is(err, middleware.ErrTokensDisabled) {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, responses.APIResponse{
+		Code:    "CREATED",
+		Message: "Token issued successfully",
+		Data: responses.TokenResponse{
+			Token:     token,
+			TokenType: "Bearer",
+			ExpiresAt: expiresAt,
+		},
+	})
+}
@@ -0,0 +1,116 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the APIUsageHandler reports the requests and errors per API caller and
+// endpoint, to diagnose noisy integrations and plan quota tiers.
+package handlers
+
+import (
+	"api-contact-form/repositories"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultAPIUsageLimit is the number of endpoints GetAPIUsage returns by default.
+	defaultAPIUsageLimit = 100
+	// maxAPIUsageLimit is the largest number of endpoints GetAPIUsage returns.
+	maxAPIUsageLimit = 1000
+	// defaultAPIUsagePeriod is the period GetAPIUsage covers by default, up to now.
+	defaultAPIUsagePeriod = 24 * time.Hour
+)
+
+// APIUsageHandler handles HTTP requests related to the usage statistics of the API.
+type APIUsageHandler struct {
+	service services.APIUsageService
+}
+
+// NewAPIUsageHandler creates a new instance of APIUsageHandler with the provided APIUsageService.
+func NewAPIUsageHandler(service services.APIUsageService) *APIUsageHandler {
+	return &APIUsageHandler{service: service}
+}
+
+// GetAPIUsage retrieves the number of requests, client errors and server errors per
+// caller and endpoint, busiest first.
+//
+// The query string selects the period with "from" and "to" (RFC 3339 times or YYYY-MM-DD
+// dates, the last 24 hours by default), which are rounded to whole hours, filters with
+// "caller" (such as key:2, user:1 or anonymous) and "route" (such as /contacts/:id), and
+// limits the number of endpoints with "limit" (100 by default, at most 1000). Counts are
+// stored every API_USAGE_FLUSH_INTERVAL, so the latest requests may be missing. Invalid
+// parameters are answered with a 400 status code. On success, it returns the usage with
+// a 200 status code.
+func (h *APIUsageHandler) GetAPIUsage(c *router.Context) {
+	// Read the period and filters from the query string.
+	filter := repositories.APIUsageFilter{
+		Caller: c.Query("caller"),
+		Route:  c.Query("route"),
+		Limit:  defaultAPIUsageLimit,
+	}
+	from, err := timeQuery(c, "from", false)
+	if err == nil {
+		filter.To, err = timeQuery(c, "to", true)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if filter.To.IsZero() {
+		filter.To = time.Now()
+	}
+	if from.IsZero() {
+		from = filter.To.Add(-defaultAPIUsagePeriod)
+	}
+	// Rollups cover whole hours: include the hours the bounds fall in.
+	filter.From = from.UTC().Truncate(time.Hour)
+	if to := filter.To.UTC().Truncate(time.Hour); to.Equal(filter.To) {
+		filter.To = to
+	} else {
+		filter.To = to.Add(time.Hour)
+	}
+	if !filter.From.Before(filter.To) {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid period, from must be before to",
+			Data:    nil,
+		})
+		return
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAPIUsageLimit {
+			c.JSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: fmt.Sprintf("Invalid limit, expected a number between 1 and %d", maxAPIUsageLimit),
+				Data:    nil,
+			})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	// Fetch the usage using the service layer.
+	summaries, err := h.service.SummarizeUsage(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "API usage retrieved successfully",
+		Data:    responses.APIUsageResponseFromSummaries(filter.From, filter.To, summaries),
+	})
+}
@@ -0,0 +1,156 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the AttachmentHandler lists the files attached to a contact and streams
+// them, or the previews of the images, to the team.
+package handlers
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"api-contact-form/storage"
+	"api-contact-form/thumbnails"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// AttachmentHandler handles HTTP requests related to the attachments of contacts.
+type AttachmentHandler struct {
+	service services.AttachmentService
+}
+
+// NewAttachmentHandler creates a new instance of AttachmentHandler with the provided AttachmentService.
+func NewAttachmentHandler(service services.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{service: service}
+}
+
+// GetAttachments retrieves the attachments of a contact by its ID.
+//
+// On success, it returns the list of attachments, which is empty for unknown contacts,
+// with a 200 status code.
+func (h *AttachmentHandler) GetAttachments(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch the attachments using the service layer.
+	attachments, err := h.service.List(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Convert the attachment models to response formats.
+	attachmentResponses := make([]responses.AttachmentResponse, 0, len(attachments))
+	for i := range attachments {
+		attachmentResponses = append(attachmentResponses, responses.AttachmentResponseFromModel(&attachments[i]))
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Attachments retrieved successfully",
+		Data:    attachmentResponses,
+	})
+}
+
+// DownloadAttachment streams an attachment of a contact by their IDs.
+//
+// The file is always sent as a download with its detected type, so that uploaded content
+// is never rendered by the browser of the team. If the attachment does not exist, or its
+// file is missing from the storage, it returns a 404 status code.
+func (h *AttachmentHandler) DownloadAttachment(c *router.Context) {
+	h.stream(c, h.service.Open, func(attachment *models.Attachment) (string, int64, string) {
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})
+		return attachment.ContentType, attachment.Size, disposition
+	})
+}
+
+// DownloadThumbnail streams the preview of an image attached to a contact by their IDs.
+//
+// The preview is generated by the API as a JPEG image, so it is sent to be displayed
+// inline. If the attachment does not exist or has no preview, such as a PDF or an image
+// whose preview is not generated yet, it returns a 404 status code.
+func (h *AttachmentHandler) DownloadThumbnail(c *router.Context) {
+	h.stream(c, h.service.OpenThumbnail, func(attachment *models.Attachment) (string, int64, string) {
+		return thumbnails.ContentType, -1, "inline"
+	})
+}
+
+// stream opens a file of the attachment identified by the 'id' and 'attachmentId'
+// parameters with open, and streams it with the content type, length and disposition
+// returned by headers. A negative length is not sent.
+func (h *AttachmentHandler) stream(c *router.Context,
+	open func(ctx context.Context, contactID, id uint) (*models.Attachment, io.ReadCloser, error),
+	headers func(attachment *models.Attachment) (string, int64, string)) {
+	// Retrieve the 'id' and 'attachmentId' parameters from the URL.
+	contactID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid attachment ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Open the file using the service layer.
+	attachment, reader, err := open(c.Request.Context(), uint(contactID), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Attachment not found",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	defer reader.Close()
+
+	// Stream the file.
+	contentType, length, disposition := headers(attachment)
+	c.Header("Content-Type", contentType)
+	if length >= 0 {
+		c.Header("Content-Length", strconv.FormatInt(length, 10))
+	}
+	c.Header("Content-Disposition", disposition)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, reader); err != nil {
+		log.Printf("Failed to stream attachment %d: %v", attachment.ID, err)
+	}
+}
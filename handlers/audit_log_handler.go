@@ -0,0 +1,104 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the AuditLogHandler lets admins read the audit trail of the changes made
+// to contacts, by contact, actor or kind of change.
+package handlers
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// defaultAuditLogLimit is the number of entries GetAuditLogs returns by default.
+	defaultAuditLogLimit = 100
+	// maxAuditLogLimit is the largest number of entries GetAuditLogs returns.
+	maxAuditLogLimit = 1000
+)
+
+// AuditLogHandler handles HTTP requests related to the audit trail.
+type AuditLogHandler struct {
+	service services.AuditService
+}
+
+// NewAuditLogHandler creates a new instance of AuditLogHandler with the provided AuditService.
+func NewAuditLogHandler(service services.AuditService) *AuditLogHandler {
+	return &AuditLogHandler{service: service}
+}
+
+// GetAuditLogs retrieves the audit trail of the changes made to contacts, newest first.
+//
+// The query string filters with "contact_id", "actor" (such as user:1 or key:2) and
+// "action" (update, status, legal_hold, pin, snooze, delete, merge, restore, purge, anonymize,
+// attachments_purge or email_issue), and limits the number of entries with "limit" (100 by
+// default, at most 1000). Invalid parameters are answered with a 400 status code. On success, it returns
+// the entries with a 200 status code.
+func (h *AuditLogHandler) GetAuditLogs(c *router.Context) {
+	// Read the filters from the query string.
+	filter := repositories.AuditLogFilter{
+		Actor:  c.Query("actor"),
+		Action: models.AuditAction(c.Query("action")),
+		Limit:  defaultAuditLogLimit,
+	}
+	if filter.Action != "" && !filter.Action.Valid() {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid action",
+			Data:    nil,
+		})
+		return
+	}
+	if value := c.Query("contact_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			c.JSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: "Invalid contact_id",
+				Data:    nil,
+			})
+			return
+		}
+		filter.ContactID = uint(id)
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditLogLimit {
+			c.JSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: fmt.Sprintf("Invalid limit, expected a number between 1 and %d", maxAuditLogLimit),
+				Data:    nil,
+			})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	// Fetch the entries using the service layer.
+	entries, err := h.service.ListAuditLogs(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Convert the entry models to response formats.
+	entryResponses := make([]responses.AuditLogResponse, 0, len(entries))
+	for i := range entries {
+		entryResponses = append(entryResponses, responses.AuditLogResponseFromModel(&entries[i]))
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Audit log retrieved successfully",
+		Data:    entryResponses,
+	})
+}
@@ -0,0 +1,153 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the AutoReplyHandler manages the templates of the auto-replies emailed to
+// submitters, one per language code.
+package handlers
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/requests"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"errors"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// AutoReplyHandler handles HTTP requests related to the auto-reply templates.
+type AutoReplyHandler struct {
+	service services.AutoReplyService
+	changes services.ChangeLogService
+}
+
+// NewAutoReplyHandler creates a new instance of AutoReplyHandler with the provided AutoReplyService,
+// recording the template edits in changes.
+func NewAutoReplyHandler(service services.AutoReplyService, changes services.ChangeLogService) *AutoReplyHandler {
+	return &AutoReplyHandler{service: service, changes: changes}
+}
+
+// GetAutoReplyTemplates retrieves the auto-reply template of every language.
+//
+// On success, it returns the templates ordered by language with a 200 status code.
+func (h *AutoReplyHandler) GetAutoReplyTemplates(c *router.Context) {
+	// Fetch the templates using the service layer.
+	templates, err := h.service.ListTemplates(c.Request.Context())
+	if respondAutoReplyError(c, err) {
+		return
+	}
+
+	data := make([]responses.AutoReplyTemplateResponse, 0, len(templates))
+	for i := range templates {
+		data = append(data, responses.AutoReplyTemplateResponseFromModel(&templates[i]))
+	}
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Auto-reply templates retrieved successfully",
+		Data:    data,
+	})
+}
+
+// GetAutoReplyTemplate retrieves the auto-reply template of the language in the URL.
+//
+// Invalid language codes are answered with a 400 status code, and languages without a
+// template with a 404 status code. On success, it returns the template with a 200 status code.
+func (h *AutoReplyHandler) GetAutoReplyTemplate(c *router.Context) {
+	// Fetch the template using the service layer.
+	template, err := h.service.GetTemplate(c.Request.Context(), c.Param("lang"))
+	if respondAutoReplyError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Auto-reply template retrieved successfully",
+		Data:    responses.AutoReplyTemplateResponseFromModel(template),
+	})
+}
+
+// SaveAutoReplyTemplate creates or replaces the auto-reply template of the language in the URL.
+//
+// It expects a JSON payload matching the AutoReplyTemplateRequest structure, whose subject
+// and body are text/templates executed with the contact, such as "Hello {{.FullName}}".
+// Invalid language codes, payloads and templates are answered with a 400 status code. On
+// success, it returns the template with a 200 status code.
+func (h *AutoReplyHandler) SaveAutoReplyTemplate(c *router.Context) {
+	var req requests.AutoReplyTemplateRequest
+
+	// Bind the JSON payload to the AutoReplyTemplateRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to save the template.
+	template, err := h.service.SaveTemplate(c.Request.Context(), c.Param("lang"), &req)
+	if respondAutoReplyError(c, err) {
+		return
+	}
+	response := responses.AutoReplyTemplateResponseFromModel(template)
+	h.changes.Record(c.Request.Context(), models.ChangeTemplate, auditActor(c), "auto-reply:"+template.Language,
+		"Auto-reply template saved for "+template.Language, response)
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Auto-reply template saved successfully",
+		Data:    response,
+	})
+}
+
+// DeleteAutoReplyTemplate removes the auto-reply template of the language in the URL.
+// Contacts in that language then receive the template of the default language.
+//
+// Invalid language codes are answered with a 400 status code, and languages without a
+// template with a 404 status code. On success, it returns a 200 status code.
+func (h *AutoReplyHandler) DeleteAutoReplyTemplate(c *router.Context) {
+	// Use the service layer to delete the template.
+	if respondAutoReplyError(c, h.service.DeleteTemplate(c.Request.Context(), c.Param("lang"))) {
+		return
+	}
+	language := services.NormalizeLanguage(c.Param("lang"))
+	h.changes.Record(c.Request.Context(), models.ChangeTemplate, auditActor(c), "auto-reply:"+language,
+		"Auto-reply template deleted for "+language, nil)
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Auto-reply template deleted successfully",
+		Data:    nil,
+	})
+}
+
+// respondAutoReplyError responds to the errors of the auto-reply service: a 400 status code
+// for invalid language codes and templates, and a 404 status code for languages without a
+// template. It reports whether a response was written.
+func respondAutoReplyError(c *router.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrInvalidLanguage), errors.Is(err, services.ErrInvalidAutoReplyTemplate):
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Auto-reply template not found",
+			Data:    nil,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the ChallengeHandler issues the anti-spam challenges that public
+// clients must complete before submitting a contact.
+package handlers
+
+import (
+	"api-contact-form/challenges"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"net/http"
+)
+
+// ChallengeHandler handles HTTP requests for anti-spam challenges.
+type ChallengeHandler struct {
+	proofOfWork *challenges.ProofOfWork
+	formTokens  *challenges.FormTokens
+}
+
+// NewChallengeHandler creates a new instance of ChallengeHandler.
+// Either challenge may be nil when it is disabled; its endpoint must then not be routed.
+func NewChallengeHandler(proofOfWork *challenges.ProofOfWork, formTokens *challenges.FormTokens) *ChallengeHandler {
+	return &ChallengeHandler{proofOfWork: proofOfWork, formTokens: formTokens}
+}
+
+// IssueProofOfWork responds with a new proof-of-work challenge.
+//
+// The client must find a solution such that sha256(token + solution) starts with
+// "difficulty" zero bits, then send the token and solution in the X-PoW-Token and
+// X-PoW-Solution headers when creating a contact.
+//
+// Example Response:
+//
+//	{
+//	    "code": "SUCCESS",
+//	    "message": "Challenge issued successfully",
+//	    "data": {"token": "...", "difficulty": 16, "expires_at": "..."}
+//	}
+func (h *ChallengeHandler) IssueProofOfWork(c *router.Context) {
+	challenge, err := h.proofOfWork.Issue()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Challenge issued successfully",
+		Data:    challenge,
+	})
+}
+
+// IssueFormToken responds with a new signed form-render token.
+//
+// Clients request it when the form is rendered and send it back in the X-Form-Token
+// header when creating a contact, no earlier than "not_before" and no later than "expires_at".
+//
+// Example Response:
+//
+//	{
+//	    "code": "SUCCESS",
+//	    "message": "Form token issued successfully",
+//	    "data": {"token": "...", "not_before": "...", "expires_at": "..."}
+//	}
+func (h *ChallengeHandler) IssueFormToken(c *router.Context) {
+	token, err := h.formTokens.Issue()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Form token issued successfully",
+		Data:    token,
+	})
+}
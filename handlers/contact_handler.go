@@ -5,35 +5,76 @@
 package handlers
 
 import (
+	"api-contact-form/helpers"
+	"api-contact-form/ids"
+	"api-contact-form/middleware"
+	"api-contact-form/models"
+	"api-contact-form/publicid"
+	"api-contact-form/repositories"
 	"api-contact-form/requests"
 	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/rules"
 	"api-contact-form/services"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // ContactHandler handles HTTP requests related to contact operations.
 type ContactHandler struct {
-	service services.ContactService
+	service   services.ContactService
+	publicIDs publicid.Scheme
+	buffer    *services.SubmissionBuffer
 }
 
 // NewContactHandler creates a new instance of ContactHandler with the provided ContactService.
-func NewContactHandler(service services.ContactService) *ContactHandler {
-	return &ContactHandler{service}
+// The IDs shown to submitters are the references of publicIDs; when it is nil, submitters
+// see the numeric IDs. Submissions received while the database is unavailable
+// are queued in buffer; when it is nil, they fail.
+func NewContactHandler(service services.ContactService, publicIDs publicid.Scheme, buffer *services.SubmissionBuffer) *ContactHandler {
+	return &ContactHandler{service: service, publicIDs: publicIDs, buffer: buffer}
 }
 
 // CreateContact handles the creation of a new contact.
 //
-// It expects a JSON payload matching the ContactRequest structure.
-// Upon successful creation, it returns the created contact with a 201 status code.
+// It expects a JSON payload matching the ContactRequest structure, or a multipart/form-data
+// form with the same fields and the uploaded files in "attachments".
+// Upon successful creation, it returns the created contact with a 201 status code, identified
+// by its public reference when public references are enabled.
+// Malformed JSON is answered with a 400 status code, and invalid fields, including those
+// rejected by the custom rules, with a 422 status code listing them.
+// Bodies over the size limit are answered with a 413 status code, and submissions rejected
+// as duplicates of a recent one with a 409 status code. Submissions received while the
+// database is unavailable are answered with a 202 status code when the write-behind buffer
+// is enabled and they could be queued, and stored once the database is back.
 // If there's an error in binding the request or creating the contact, it returns an appropriate error response.
-func (h *ContactHandler) CreateContact(c *gin.Context) {
+func (h *ContactHandler) CreateContact(c *router.Context) {
 	var req requests.ContactRequest
 
-	// Bind the JSON payload to the ContactRequest struct.
-	if err := c.ShouldBindJSON(&req); err != nil {
+	// Bind the JSON payload or the form to the ContactRequest struct.
+	var err error
+	if c.ContentType() == router.MIMEMultipartPOSTForm {
+		err = bindContactForm(c, &req)
+	} else {
+		err = c.ShouldBindJSON(&req)
+	}
+	if middleware.BodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, responses.APIResponse{
+			Code:    "PAYLOAD_TOO_LARGE",
+			Message: "Request body too large",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusBadRequest, responses.APIResponse{
 			Code:    "BAD_REQUEST",
 			Message: err.Error(),
@@ -42,8 +83,50 @@ func (h *ContactHandler) CreateContact(c *gin.Context) {
 		return
 	}
 
+	// Submissions authenticated with an API key come from integrations.
+	meta := requests.SubmissionMeta{ClientIP: c.ClientIP()}
+	if middleware.CurrentIdentity(c) != nil {
+		meta.Channel = models.ChannelAPI
+	}
+
 	// Use the service layer to create a new contact.
-	contact, err := h.service.CreateContact(&req)
+	contact, err := h.service.CreateContact(c.Request.Context(), &req, meta)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if respondValidationErrors(c, err) {
+		return
+	}
+	if respondRuleViolations(c, err) {
+		return
+	}
+	if respondOpenContactExists(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrDuplicateSubmission) {
+		c.JSON(http.StatusConflict, responses.APIResponse{
+			Code:    "CONFLICT",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if errors.Is(err, services.ErrConsentRequired) {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if h.buffer.Buffer(c.Request.Context(), &req, meta, err) {
+		c.JSON(http.StatusAccepted, responses.APIResponse{
+			Code:    "ACCEPTED",
+			Message: "Submission received and will be stored shortly",
+			Data:    nil,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, responses.APIResponse{
 			Code:    "INTERNAL_SERVER_ERROR",
@@ -54,21 +137,52 @@ func (h *ContactHandler) CreateContact(c *gin.Context) {
 	}
 
 	// Respond with the created contact and a success message.
+	var data interface{} = responses.ContactResponseFromModel(contact)
+	if h.publicIDs != nil {
+		data = responses.ContactReceiptResponseFromModel(contact, h.publicIDs.Reference(contact))
+	}
 	c.JSON(http.StatusCreated, responses.APIResponse{
 		Code:    "CREATED",
 		Message: "Contact created successfully",
-		Data:    responses.ContactResponseFromModel(contact),
+		Data:    data,
 	})
 }
 
-// GetContacts retrieves all contacts.
+// GetContacts retrieves a page of contacts.
 //
-// It interacts with the service layer to fetch all contact records.
-// On success, it returns the list of contacts with a 200 status code.
-// In case of an error, it responds with a 500 status code and an error message.
-func (h *ContactHandler) GetContacts(c *gin.Context) {
-	// Fetch all contacts using the service layer.
-	contacts, err := h.service.GetAllContacts()
+// It interacts with the service layer to fetch a sorted page of contact records.
+// The query string selects the page with "limit" and either "offset" or the "cursor"
+// returned with the previous page, sorts with "sort" (created_at or full_name) and
+// "order" (asc or desc), and filters with "channel", "status", "fingerprint", "email", "q"
+// (free text in the message, or a possibly misspelled name or email address, matched with
+// a trigram "similarity" between 0 and 1, 0.3 by default), "min_lead_score" (the smallest
+// lead score), "starred" (true to list only the contacts starred by the current user),
+// and "from"/"to" (RFC 3339 times or YYYY-MM-DD dates, "to" being inclusive for dates).
+// Pinned contacts are listed in their usual place; the inbox lists them first.
+// On success, it returns the page of contacts with the total count and a 200 status code.
+// Invalid parameters are answered with a 400 status code; other errors with a 500 status code.
+func (h *ContactHandler) GetContacts(c *router.Context) {
+	// Read the paging, sorting and filters from the query string.
+	params, err := parseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch the page of contacts using the service layer.
+	page, err := h.service.ListContacts(c.Request.Context(), params)
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid cursor",
+			Data:    nil,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, responses.APIResponse{
 			Code:    "INTERNAL_SERVER_ERROR",
@@ -78,17 +192,51 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 		return
 	}
 
-	// Convert the contact models to response formats.
-	var contactResponses []responses.ContactResponse
-	for _, contact := range contacts {
-		contactResponses = append(contactResponses, responses.ContactResponseFromModel(&contact))
+	// Respond with the page of contacts.
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Contacts retrieved successfully",
+		Data:    responses.ContactPageResponseFromPage(page),
+	})
+}
+
+// SearchContacts searches the messages of the contacts for the words of "q".
+//
+// The query accepts quoted phrases, "or" between alternatives and -excluded words, and is
+// matched with full-text search on Postgres, or as a case-insensitive substring of every
+// word on other databases. It pages with "limit" and "offset", and filters with the
+// parameters of GetContacts except "similarity".
+// On success, it returns the matching contacts, most relevant first, with the total
+// count and a 200 status code. A missing query or invalid parameters are answered with
+// a 400 status code; other errors with a 500 status code.
+func (h *ContactHandler) SearchContacts(c *router.Context) {
+	// Read the query, paging and filters from the query string.
+	params, err := parseSearchParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Search the contacts using the service layer.
+	page, err := h.service.SearchContacts(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
 	}
 
-	// Respond with the list of contacts.
+	// Respond with the page of results.
 	c.JSON(http.StatusOK, responses.APIResponse{
 		Code:    "SUCCESS",
-		Message: "Contacts retrieved successfully",
-		Data:    contactResponses,
+		Message: "Contacts searched successfully",
+		Data:    responses.SearchPageResponseFromPage(page, params.Offset),
 	})
 }
 
@@ -97,9 +245,11 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 // It expects the contact ID as a URL parameter.
 // If the ID is invalid or the contact does not exist, it returns an appropriate error response.
 // On success, it returns the contact details with a 200 status code.
-func (h *ContactHandler) GetContact(c *gin.Context) {
-	// Retrieve the 'id' parameter from the URL.
-	idParam := c.Param("id")
+// With an ID ending with .vcf, such as /contacts/42.vcf, it downloads the contact as a
+// vCard instead, which only admins may do.
+func (h *ContactHandler) GetContact(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL, which ends with .vcf for vCards.
+	idParam, vCard := strings.CutSuffix(c.Param("id"), ".vcf")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, responses.APIResponse{
@@ -110,8 +260,18 @@ func (h *ContactHandler) GetContact(c *gin.Context) {
 		return
 	}
 
+	// vCards carry the email and phone unredacted, so they are exported to admins only.
+	if identity := middleware.CurrentIdentity(c); vCard && identity != nil && identity.Role != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, responses.APIResponse{
+			Code:    "FORBIDDEN",
+			Message: "The admin role is required",
+			Data:    nil,
+		})
+		return
+	}
+
 	// Fetch the contact by ID using the service layer.
-	contact, err := h.service.GetContactByID(uint(id))
+	contact, err := h.service.GetContactByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, responses.APIResponse{
 			Code:    "NOT_FOUND",
@@ -121,6 +281,11 @@ func (h *ContactHandler) GetContact(c *gin.Context) {
 		return
 	}
 
+	if vCard {
+		writeContactVCard(c, contact)
+		return
+	}
+
 	// Respond with the contact details.
 	c.JSON(http.StatusOK, responses.APIResponse{
 		Code:    "SUCCESS",
@@ -134,7 +299,7 @@ func (h *ContactHandler) GetContact(c *gin.Context) {
 // It expects the contact ID as a URL parameter and a JSON payload matching the ContactRequest structure.
 // If the ID is invalid or the contact does not exist, it returns an appropriate error response.
 // On successful update, it returns the updated contact with a 200 status code.
-func (h *ContactHandler) UpdateContact(c *gin.Context) {
+func (h *ContactHandler) UpdateContact(c *router.Context) {
 	// Retrieve the 'id' parameter from the URL.
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
@@ -160,7 +325,19 @@ func (h *ContactHandler) UpdateContact(c *gin.Context) {
 	}
 
 	// Use the service layer to update the contact.
-	contact, err := h.service.UpdateContact(uint(id), &req)
+	contact, err := h.service.UpdateContact(c.Request.Context(), auditActor(c), uint(id), &req)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if respondValidationErrors(c, err) {
+		return
+	}
+	if respondRuleViolations(c, err) {
+		return
+	}
+	if respondOpenContactExists(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, responses.APIResponse{
 			Code:    "INTERNAL_SERVER_ERROR",
@@ -182,8 +359,9 @@ func (h *ContactHandler) UpdateContact(c *gin.Context) {
 //
 // It expects the contact ID as a URL parameter.
 // If the ID is invalid or the contact does not exist, it returns an appropriate error response.
+// Contacts under legal hold are not deleted and a 409 status code is returned.
 // On successful deletion, it returns a success message with a 200 status code.
-func (h *ContactHandler) DeleteContact(c *gin.Context) {
+func (h *ContactHandler) DeleteContact(c *router.Context) {
 	// Retrieve the 'id' parameter from the URL.
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
@@ -197,7 +375,18 @@ func (h *ContactHandler) DeleteContact(c *gin.Context) {
 	}
 
 	// Use the service layer to delete the contact.
-	err = h.service.DeleteContact(uint(id))
+	err = h.service.DeleteContact(c.Request.Context(), auditActor(c), uint(id))
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrLegalHold) {
+		c.JSON(http.StatusConflict, responses.APIResponse{
+			Code:    "CONFLICT",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, responses.APIResponse{
 			Code:    "INTERNAL_SERVER_ERROR",
@@ -214,3 +403,1092 @@ func (h *ContactHandler) DeleteContact(c *gin.Context) {
 		Data:    nil,
 	})
 }
+
+// SetLegalHold places or lifts the legal hold of a contact by its ID.
+//
+// It expects the contact ID as a URL parameter and a JSON payload matching the LegalHoldRequest structure.
+// If the ID is invalid or the contact does not exist, it returns an appropriate error response.
+// On success, it returns the updated contact with a 200 status code.
+func (h *ContactHandler) SetLegalHold(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	var req requests.LegalHoldRequest
+
+	// Bind the JSON payload to the LegalHoldRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to update the legal hold.
+	contact, err := h.service.SetLegalHold(c.Request.Context(), auditActor(c), uint(id), *req.LegalHold)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Contact not found",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Respond with the updated contact and a success message.
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Legal hold updated successfully",
+		Data:    responses.ContactResponseFromModel(contact),
+	})
+}
+
+// GetEmailThread retrieves the emails of the conversation of a contact by its ID.
+//
+// The conversation holds the auto-reply sent to the submitter and the replies received from
+// them, oldest first; the email a contact was created from is its message.
+// If the ID is invalid or the contact does not exist, it returns an appropriate error response.
+// On success, it returns the emails with a 200 status code.
+func (h *ContactHandler) GetEmailThread(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch the conversation using the service layer.
+	messages, err := h.service.GetEmailThread(c.Request.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Contact not found",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: "Failed to retrieve emails",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Respond with the emails of the conversation.
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Emails retrieved successfully",
+		Data:    responses.EmailMessageResponsesFromModels(messages),
+	})
+}
+
+// SetPinned pins a contact by its ID to the top of the inbox of the whole team, or unpins it.
+//
+// It expects the contact ID as a URL parameter and a JSON payload matching the PinRequest structure.
+// If the ID is invalid or the contact does not exist, it returns an appropriate error response.
+// On success, it returns the updated contact with a 200 status code.
+func (h *ContactHandler) SetPinned(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	var req requests.PinRequest
+
+	// Bind the JSON payload to the PinRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to update the pin.
+	contact, err := h.service.SetPinned(c.Request.Context(), auditActor(c), uint(id), *req.Pinned)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Contact not found",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Respond with the updated contact and a success message.
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Pin updated successfully",
+		Data:    responses.ContactResponseFromModel(contact),
+	})
+}
+
+// SnoozeContact hides a contact by its ID from the default inbox until a given time, when
+// it returns to the new status and the team is notified again.
+//
+// It expects the contact ID as a URL parameter and a JSON payload matching the SnoozeRequest structure.
+// If the ID or time is invalid or the contact does not exist, it returns an appropriate error response.
+// Contacts whose status may not go back to new are answered with a 422 status code.
+// On success, it returns the updated contact with a 200 status code.
+func (h *ContactHandler) SnoozeContact(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	var req requests.SnoozeRequest
+
+	// Bind the JSON payload to the SnoozeRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to snooze the contact.
+	contact, err := h.service.Snooze(c.Request.Context(), auditActor(c), uint(id), req.Until)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrSnoozeInPast) {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidStatusTransition) {
+		c.JSON(http.StatusUnprocessableEntity, responses.APIResponse{
+			Code:    "UNPROCESSABLE_ENTITY",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Contact not found",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Respond with the updated contact and a success message.
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Contact snoozed successfully",
+		Data:    responses.ContactResponseFromModel(contact),
+	})
+}
+
+// StarContact stars a contact by its ID for the current user.
+//
+// Stars are personal: each user lists the contacts they starred with GET /contacts?starred=true.
+// If the ID is invalid or the contact does not exist, it returns an appropriate error response.
+// On success, it returns a 204 status code; starring a starred contact changes nothing.
+func (h *ContactHandler) StarContact(c *router.Context) {
+	h.setStarred(c, true)
+}
+
+// UnstarContact removes the star the current user gave to a contact, by its ID.
+//
+// If the ID is invalid or the contact does not exist, it returns an appropriate error response.
+// On success, it returns a 204 status code, also when the contact was not starred.
+func (h *ContactHandler) UnstarContact(c *router.Context) {
+	h.setStarred(c, false)
+}
+
+// setStarred stars or unstars the contact identified by the 'id' URL parameter for the
+// current user.
+func (h *ContactHandler) setStarred(c *router.Context, starred bool) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to update the star of the current user.
+	err = h.service.SetStarred(c.Request.Context(), auditActor(c), uint(id), starred)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Contact not found",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: "Failed to update star",
+			Data:    nil,
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UpdateStatus changes the status of a contact by its ID.
+//
+// It expects the contact ID as a URL parameter and a JSON payload matching the StatusRequest structure.
+// If the ID or status is invalid or the contact does not exist, it returns an appropriate error response.
+// Transitions the workflow does not allow, or only allows with "reviewed": true, are
+// answered with a 422 status code.
+// On success, it returns the updated contact with a 200 status code.
+func (h *ContactHandler) UpdateStatus(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	var req requests.StatusRequest
+
+	// Bind the JSON payload to the StatusRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if !req.Status.Valid() {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid status",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to change the status.
+	contact, err := h.service.UpdateStatus(c.Request.Context(), auditActor(c), uint(id), req.Status, req.Reviewed)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrInvalidStatusTransition) {
+		c.JSON(http.StatusUnprocessableEntity, responses.APIResponse{
+			Code:    "UNPROCESSABLE_ENTITY",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Contact not found",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Respond with the updated contact and a success message.
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Contact status updated successfully",
+		Data:    responses.ContactResponseFromModel(contact),
+	})
+}
+
+// GetDeletedContacts retrieves a page of the contacts in the trash.
+//
+// It interacts with the service layer to fetch the soft-deleted contacts, most recently deleted first,
+// paged like GetContacts with "limit" and either "cursor" or "offset".
+// On success, it returns the page of contacts with the total count and a 200 status code.
+// Invalid paging parameters are answered with a 400 status code; other errors with a 500 status code.
+func (h *ContactHandler) GetDeletedContacts(c *router.Context) {
+	// Read the paging from the query string.
+	params := repositories.ListParams{Cursor: c.Query("cursor")}
+	var err error
+	if params.Limit, err = nonNegativeQuery(c, "limit"); err == nil {
+		params.Offset, err = nonNegativeQuery(c, "offset")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch the page of deleted contacts using the service layer.
+	page, err := h.service.GetDeletedContacts(c.Request.Context(), params)
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid cursor",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Respond with the page of deleted contacts.
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Deleted contacts retrieved successfully",
+		Data:    responses.ContactPageResponseFromPage(page),
+	})
+}
+
+// RestoreContact moves a deleted contact out of the trash by its ID.
+//
+// It expects the contact ID as a URL parameter.
+// If the ID is invalid or no deleted contact has it, it returns an appropriate error response.
+// When only one open contact per email address is allowed and another one is open, a 409 status code is returned.
+// On success, it returns the restored contact with a 200 status code.
+func (h *ContactHandler) RestoreContact(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to restore the contact.
+	contact, err := h.service.RestoreContact(c.Request.Context(), auditActor(c), uint(id))
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if respondOpenContactExists(c, err) {
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Deleted contact not found",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Respond with the restored contact and a success message.
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Contact restored successfully",
+		Data:    responses.ContactResponseFromModel(contact),
+	})
+}
+
+// PurgeContact permanently removes a deleted contact by its ID.
+//
+// It expects the contact ID as a URL parameter. Only contacts in the trash that are not
+// under legal hold can be purged; for any other ID a 404 status code is returned.
+// With "dry_run=true" in the query string, the contact is only checked and reported.
+// On success, it returns a success message with a 200 status code.
+func (h *ContactHandler) PurgeContact(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+	dryRun, ok := bindDryRun(c)
+	if !ok {
+		return
+	}
+
+	// Use the service layer to purge the contact.
+	err = h.service.PurgeContact(c.Request.Context(), auditActor(c), uint(id), dryRun)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Deleted contact not found",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Respond with a success message, or the contact that would be purged.
+	if dryRun {
+		c.JSON(http.StatusOK, responses.APIResponse{
+			Code:    "SUCCESS",
+			Message: "Dry run: contact would be purged",
+			Data:    responses.DryRunResponseFromResult(&services.BulkResult{Succeeded: []uint{uint(id)}}),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Contact purged successfully",
+		Data:    nil,
+	})
+}
+
+// GetDuplicates retrieves the groups of contacts that are likely duplicates of each other.
+//
+// Contacts are likely duplicates when they were submitted with the same email address
+// within "within" of each other (a duration, 24h by default) and the trigram similarity
+// of their messages is at least "threshold" (between 0 and 1, 0.6 by default).
+// On success, it returns the groups, most recent first, with a 200 status code.
+// Invalid parameters are answered with a 400 status code, databases without trigram
+// similarity with a 501 status code, and other errors with a 500 status code.
+func (h *ContactHandler) GetDuplicates(c *router.Context) {
+	// Read the criteria from the query string.
+	criteria := repositories.DuplicateCriteria{Within: 24 * time.Hour, Threshold: 0.6}
+	if value := c.Query("within"); value != "" {
+		within, err := time.ParseDuration(value)
+		if err != nil || within <= 0 {
+			c.JSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: "Invalid within",
+				Data:    nil,
+			})
+			return
+		}
+		criteria.Within = within
+	}
+	if value := c.Query("threshold"); value != "" {
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			c.JSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: "Invalid threshold",
+				Data:    nil,
+			})
+			return
+		}
+		criteria.Threshold = threshold
+	}
+
+	// Fetch the duplicate groups using the service layer.
+	groups, err := h.service.FindDuplicates(c.Request.Context(), criteria)
+	if errors.Is(err, repositories.ErrUnsupportedByDriver) {
+		c.JSON(http.StatusNotImplemented, responses.APIResponse{
+			Code:    "NOT_IMPLEMENTED",
+			Message: "Duplicate detection requires Postgres",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Respond with the duplicate groups.
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Duplicates retrieved successfully",
+		Data:    responses.DuplicateGroupResponsesFromGroups(groups),
+	})
+}
+
+// MergeContacts merges duplicate contacts into a single contact.
+//
+// It expects a JSON payload matching the MergeRequest structure. The duplicates are
+// deleted and record the ID of the contact they were merged into.
+// If any contact does not exist, it returns a 404 status code; if a duplicate is under
+// legal hold, a 409 status code. When the contacts have different names, emails or phones
+// not settled by "resolutions", it returns a 409 MERGE_CONFLICT status code listing the
+// values of each field, and invalid resolutions are answered with a 400 status code.
+// On success, it returns the kept contact with a 200 status code.
+func (h *ContactHandler) MergeContacts(c *router.Context) {
+	var req requests.MergeRequest
+
+	// Bind the JSON payload to the MergeRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to merge the contacts.
+	resolutions := make(map[string]uint, len(req.Resolutions))
+	for field, id := range req.Resolutions {
+		resolutions[field] = uint(id)
+	}
+	contact, err := h.service.MergeContacts(c.Request.Context(), auditActor(c), uint(req.KeepID), ids.Uints(req.IDs), resolutions)
+	var conflictErr *services.MergeConflictError
+	if errors.As(err, &conflictErr) {
+		c.JSON(http.StatusConflict, responses.APIResponse{
+			Code:    "MERGE_CONFLICT",
+			Message: err.Error(),
+			Data:    responses.MergeConflictResponsesFromConflicts(conflictErr.Conflicts),
+		})
+		return
+	}
+	if respondBulkError(c, err) {
+		return
+	}
+
+	// Respond with the kept contact and a success message.
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Contacts merged successfully",
+		Data:    responses.ContactResponseFromModel(contact),
+	})
+}
+
+// DeleteContacts deletes several contacts at once.
+//
+// It expects a JSON payload matching the BulkDeleteRequest structure. Contacts that do not
+// exist or are under legal hold are skipped; the others are deleted in a single transaction.
+// It returns the deleted and skipped contacts, with a 200 status code when none was
+// skipped and a 207 status code otherwise. With "dry_run=true" in the query string,
+// nothing is deleted and a DryRunResponse is returned with a 200 status code.
+func (h *ContactHandler) DeleteContacts(c *router.Context) {
+	var req requests.BulkDeleteRequest
+	dryRun, ok := bindDryRun(c)
+	if !ok {
+		return
+	}
+
+	// Bind the JSON payload to the BulkDeleteRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to delete the contacts.
+	result, err := h.service.DeleteContacts(c.Request.Context(), auditActor(c), ids.Uints(req.IDs), dryRun)
+	if respondBulkError(c, err) {
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, responses.APIResponse{
+			Code:    "SUCCESS",
+			Message: "Dry run: no contacts were deleted",
+			Data:    responses.DryRunResponseFromResult(result),
+		})
+		return
+	}
+
+	respondBulkResult(c, "Contacts deleted", len(result.Failed) > 0, responses.BulkResultResponseFromResult(result))
+}
+
+// UpdateStatuses changes the status of several contacts at once.
+//
+// It expects a JSON payload matching the BulkStatusRequest structure. Contacts that do not
+// exist or cannot move to the status are skipped; the others are updated in a single
+// transaction. It returns the updated and skipped contacts, with a 200 status code when
+// none was skipped and a 207 status code otherwise.
+func (h *ContactHandler) UpdateStatuses(c *router.Context) {
+	var req requests.BulkStatusRequest
+
+	// Bind the JSON payload to the BulkStatusRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if !req.Status.Valid() {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid status",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to change the statuses.
+	result, err := h.service.UpdateStatuses(c.Request.Context(), auditActor(c), ids.Uints(req.IDs), req.Status, req.Reviewed)
+	if respondBulkError(c, err) {
+		return
+	}
+
+	respondBulkResult(c, "Contact statuses updated", len(result.Failed) > 0, responses.BulkResultResponseFromResult(result))
+}
+
+// ImportContacts creates contacts from legacy data.
+//
+// It expects a JSON payload matching the ImportContactsRequest structure, with at most
+// 1000 contacts. Invalid contacts are skipped and reported by their position; the others
+// are inserted in batches in a single transaction. It returns the created and rejected
+// contacts, with a 201 status code when none was rejected and a 207 status code otherwise.
+func (h *ContactHandler) ImportContacts(c *router.Context) {
+	var req requests.ImportContactsRequest
+
+	// Bind the JSON payload to the ImportContactsRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to import the contacts.
+	result, err := h.service.ImportContacts(c.Request.Context(), req.Contacts)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if respondOpenContactExists(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	if len(result.Failed) == 0 {
+		c.JSON(http.StatusCreated, responses.APIResponse{
+			Code:    "CREATED",
+			Message: "Contacts imported successfully",
+			Data:    responses.ImportResultResponseFromResult(result),
+		})
+		return
+	}
+	respondBulkResult(c, "Contacts imported", true, responses.ImportResultResponseFromResult(result))
+}
+
+// bindContactForm binds a multipart/form-data submission to req. The fields carry the
+// same names as in JSON; consent is parsed as a boolean and the fingerprint as JSON.
+func bindContactForm(c *router.Context, req *requests.ContactRequest) error {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return err
+	}
+
+	value := func(key string) string {
+		if values := form.Value[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	req.Name = value("name")
+	req.Email = value("email")
+	req.Phone = value("phone")
+	req.Message = value("message")
+	req.ConsentVersion = value("consent_version")
+	if consent := value("consent"); consent != "" {
+		given, err := strconv.ParseBool(consent)
+		if err != nil {
+			return fmt.Errorf("invalid consent: %w", err)
+		}
+		req.Consent = &given
+	}
+	if fingerprint := value("fingerprint"); fingerprint != "" {
+		if !json.Valid([]byte(fingerprint)) {
+			return errors.New("invalid fingerprint: not valid JSON")
+		}
+		req.Fingerprint = json.RawMessage(fingerprint)
+	}
+	req.Attachments = form.File["attachments"]
+	return nil
+}
+
+// bindDryRun parses the optional "dry_run" query parameter of destructive operations.
+// Invalid values are answered with a 400 status code; it reports whether the value was valid.
+func bindDryRun(c *router.Context) (dryRun bool, ok bool) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid dry_run, expected true or false",
+			Data:    nil,
+		})
+		return false, false
+	}
+	return dryRun, true
+}
+
+// respondBulkResult responds with the result of a bulk operation: a 200 status code when
+// every item was processed, and a 207 status code when some were skipped.
+func respondBulkResult(c *router.Context, action string, partial bool, data interface{}) {
+	if partial {
+		c.JSON(http.StatusMultiStatus, responses.APIResponse{
+			Code:    "PARTIAL_SUCCESS",
+			Message: action + " partially; see the failed items",
+			Data:    data,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: action + " successfully",
+		Data:    data,
+	})
+}
+
+// respondBulkError responds to the errors of operations on several contacts.
+// It reports whether a response was written.
+func respondBulkError(c *router.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case respondConstraintViolation(c, err):
+	case errors.Is(err, services.ErrMergeIntoItself), errors.Is(err, services.ErrInvalidResolution):
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Contact not found",
+			Data:    nil,
+		})
+	case errors.Is(err, services.ErrLegalHold):
+		c.JSON(http.StatusConflict, responses.APIResponse{
+			Code:    "CONFLICT",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	}
+	return true
+}
+
+// respondValidationErrors responds with a 422 status code listing the invalid fields when
+// err is a request validation failure. It reports whether a response was written.
+func respondValidationErrors(c *router.Context, err error) bool {
+	var validationErr *services.ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, responses.APIResponse{
+		Code:    "UNPROCESSABLE_ENTITY",
+		Message: validationErr.Error(),
+		Data:    validationErr.Fields,
+	})
+	return true
+}
+
+// respondRuleViolations responds like respondValidationErrors, with a 422 status code
+// listing the invalid fields, when err is a custom validation rule failure. It reports
+// whether a response was written.
+func respondRuleViolations(c *router.Context, err error) bool {
+	var ruleErr *rules.ValidationError
+	if !errors.As(err, &ruleErr) {
+		return false
+	}
+
+	validationErr := &services.ValidationError{Fields: make([]services.FieldError, 0, len(ruleErr.Violations))}
+	for _, violation := range ruleErr.Violations {
+		validationErr.Fields = append(validationErr.Fields, services.FieldError{Field: violation.Field, Message: violation.Message})
+	}
+	return respondValidationErrors(c, validationErr)
+}
+
+// auditActor names the caller of the request in the audit trail by the subject of its
+// identity, or as services.AnonymousActor when authentication is disabled.
+func auditActor(c *router.Context) string {
+	if identity := middleware.CurrentIdentity(c); identity != nil {
+		return identity.Subject
+	}
+	return services.AnonymousActor
+}
+
+// respondOpenContactExists responds with a 409 status code when err reports that the
+// submitter already has an open contact. It reports whether a response was written.
+func respondOpenContactExists(c *router.Context, err error) bool {
+	if !errors.Is(err, repositories.ErrOpenContactExists) {
+		return false
+	}
+
+	c.JSON(http.StatusConflict, responses.APIResponse{
+		Code:    "CONFLICT",
+		Message: err.Error(),
+		Data:    nil,
+	})
+	return true
+}
+
+// constraintFields maps the columns whose request field has another name to that name.
+var constraintFields = map[string]string{
+	"full_name":     "name",
+	"email_address": "email",
+	"phone_number":  "phone",
+	"message_text":  "message",
+}
+
+// respondConstraintViolation responds when err reports a write rejected by a constraint of
+// the database: with a 409 status code for a value already taken, and with a 422 status
+// code for a missing reference or a rejected value, listing the offending field when the
+// database reports it. It reports whether a response was written.
+func respondConstraintViolation(c *router.Context, err error) bool {
+	var violation *repositories.ConstraintError
+	if !errors.As(err, &violation) {
+		return false
+	}
+
+	status, code := http.StatusUnprocessableEntity, "UNPROCESSABLE_ENTITY"
+	if errors.Is(violation, repositories.ErrUniqueViolation) {
+		status, code = http.StatusConflict, "CONFLICT"
+	}
+	fields := []services.FieldError{}
+	if violation.Column != "" {
+		field := cmp.Or(constraintFields[violation.Column], violation.Column)
+		fields = append(fields, services.FieldError{Field: field, Message: violation.Err.Error()})
+	}
+	c.JSON(status, responses.APIResponse{
+		Code:    code,
+		Message: violation.Err.Error(),
+		Data:    fields,
+	})
+	return true
+}
+
+// parseListParams reads the paging, sorting and filter query parameters of GetContacts.
+func parseListParams(c *router.Context) (repositories.ListParams, error) {
+	filter, err := parseContactFilter(c)
+	if err != nil {
+		return repositories.ListParams{}, err
+	}
+	params := repositories.ListParams{
+		Filter: filter,
+		SortBy: repositories.SortField(c.Query("sort")),
+		Cursor: c.Query("cursor"),
+	}
+
+	if params.SortBy != "" && !params.SortBy.Valid() {
+		return params, errors.New("Invalid sort, expected created_at or full_name")
+	}
+
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+		params.Ascending = true
+	case "desc":
+	default:
+		return params, errors.New("Invalid order, expected asc or desc")
+	}
+
+	if params.Limit, err = nonNegativeQuery(c, "limit"); err != nil {
+		return params, err
+	}
+	if params.Offset, err = nonNegativeQuery(c, "offset"); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+// parseSearchParams reads the query, paging and filter query parameters of SearchContacts.
+func parseSearchParams(c *router.Context) (repositories.SearchParams, error) {
+	filter, err := parseContactFilter(c)
+	if err != nil {
+		return repositories.SearchParams{}, err
+	}
+	params := repositories.SearchParams{Query: strings.TrimSpace(filter.Search)}
+	filter.Search, filter.SearchThreshold = "", 0
+	params.Filter = filter
+
+	if params.Query == "" {
+		return params, errors.New("The q parameter is required")
+	}
+	if params.Limit, err = nonNegativeQuery(c, "limit"); err != nil {
+		return params, err
+	}
+	if params.Offset, err = nonNegativeQuery(c, "offset"); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+// parseContactFilter reads the filter query parameters shared by GetContacts and the export.
+func parseContactFilter(c *router.Context) (repositories.ContactFilter, error) {
+	filter := repositories.ContactFilter{
+		Channel:         models.Channel(c.Query("channel")),
+		FingerprintHash: c.Query("fingerprint"),
+		Status:          models.Status(c.Query("status")),
+		Email:           c.Query("email"),
+		Search:          c.Query("q"),
+	}
+
+	if filter.Channel != "" && !filter.Channel.Valid() {
+		return filter, errors.New("Invalid channel")
+	}
+	if filter.Status != "" && !filter.Status.Valid() {
+		return filter, errors.New("Invalid status")
+	}
+	if value := c.Query("similarity"); value != "" {
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			return filter, errors.New("Invalid similarity, expected a number between 0 and 1")
+		}
+		filter.SearchThreshold = threshold
+	}
+	if value := c.Query("min_lead_score"); value != "" {
+		score, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, errors.New("Invalid min_lead_score, expected an integer")
+		}
+		filter.MinLeadScore = &score
+	}
+	if value := c.Query("starred"); value != "" {
+		starred, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("Invalid starred, expected true or false")
+		}
+		if starred {
+			filter.StarredBy = auditActor(c)
+		}
+	}
+
+	var err error
+	if filter.CreatedFrom, err = timeQuery(c, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = timeQuery(c, "to", true); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// nonNegativeQuery reads an optional non-negative integer query parameter.
+func nonNegativeQuery(c *router.Context, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid %s", key)
+	}
+	return n, nil
+}
+
+// timeQuery reads an optional RFC 3339 time or YYYY-MM-DD date query parameter.
+// Dates are read in the application timezone; an end date covers the whole day.
+func timeQuery(c *router.Context, key string, end bool) (time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	day, err := time.ParseInLocation(time.DateOnly, value, helpers.AppTimezone())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid %s, expected an RFC 3339 time or YYYY-MM-DD date", key)
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
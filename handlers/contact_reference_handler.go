@@ -0,0 +1,88 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, this file lets submitters follow their contact, and the team find it, by
+// the public reference shown in the receipt of the submission.
+package handlers
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/publicid"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"context"
+	"net/http"
+)
+
+// GetContactStatus retrieves the progress of a contact by its public reference.
+//
+// It is public: it only shows whether the contact was received, is in review, was answered
+// or is closed, and when. References that are invalid or of no contact are answered with
+// a 404 status code alike, so that they cannot be told apart. On success, it returns the
+// status with a 200 status code.
+func (h *ContactHandler) GetContactStatus(c *router.Context) {
+	// Resolve the 'reference' parameter of the URL.
+	reference := c.Param("reference")
+	lookup, err := h.publicIDs.Resolve(reference)
+	if err == nil {
+		// Fetch the contact using the service layer.
+		contact, err := h.findByReference(c.Request.Context(), lookup)
+		if err == nil {
+			c.JSON(http.StatusOK, responses.APIResponse{
+				Code:    "SUCCESS",
+				Message: "Contact status retrieved successfully",
+				Data:    responses.ContactStatusResponseFromModel(contact, reference),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, responses.APIResponse{
+		Code:    "NOT_FOUND",
+		Message: "Contact not found",
+		Data:    nil,
+	})
+}
+
+// GetContactByReference retrieves a single contact by its public reference, as quoted
+// by a submitter.
+//
+// If the reference is invalid, it returns a 400 status code, and if the contact does not
+// exist, a 404 status code. On success, it returns the contact details with a 200 status code.
+func (h *ContactHandler) GetContactByReference(c *router.Context) {
+	// Resolve the 'reference' parameter of the URL.
+	lookup, err := h.publicIDs.Resolve(c.Param("reference"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid reference",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch the contact using the service layer.
+	contact, err := h.findByReference(c.Request.Context(), lookup)
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Contact not found",
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Contact retrieved successfully",
+		Data:    responses.ContactResponseFromModel(contact),
+	})
+}
+
+// findByReference fetches the contact of a resolved reference, by its ID or by its stored
+// public ID.
+func (h *ContactHandler) findByReference(ctx context.Context, lookup publicid.Lookup) (*models.Contact, error) {
+	if lookup.ID != 0 {
+		return h.service.GetContactByID(ctx, lookup.ID)
+	}
+	return h.service.GetContactByPublicID(ctx, lookup.PublicID)
+}
@@ -0,0 +1,134 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the ExportHandler streams contacts as CSV or Excel files for
+// people working outside the admin view, optionally anonymized for analysts, or as vCards
+// imported into address books.
+package handlers
+
+import (
+	"api-contact-form/exports"
+	"api-contact-form/helpers"
+	"api-contact-form/models"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ExportHandler handles HTTP requests for contact exports.
+type ExportHandler struct {
+	service services.ContactService
+	hashKey []byte
+}
+
+// NewExportHandler creates a new instance of ExportHandler with the provided ContactService.
+// Personal data of anonymized exports is hashed under hashKey; with an empty key, the
+// hashes of every export differ.
+func NewExportHandler(service services.ContactService, hashKey []byte) *ExportHandler {
+	return &ExportHandler{service: service, hashKey: hashKey}
+}
+
+// ExportContacts streams the non-deleted contacts as a file download.
+//
+// The query string selects the "format" (csv, the default, xlsx, or vcf for a vCard 4.0
+// address book) and the "mode": full, the default, or anonymized, which hashes the name,
+// email address, phone number and IP address and replaces the message with its length;
+// vCards cannot be anonymized. It also accepts the filters of
+// GetContacts: "channel", "status", "fingerprint", "email", "q", and "from"/"to".
+// Contacts are read from the database in batches and written in ID order.
+// Invalid parameters are answered with a 400 status code. Errors that occur once the
+// download has started can only abort it.
+func (h *ExportHandler) ExportContacts(c *router.Context) {
+	// Read the format and filters from the query string.
+	format := exports.Format(c.DefaultQuery("format", string(exports.FormatCSV)))
+	filter, err := parseContactFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Create the writer of the requested mode.
+	var writer exports.Writer
+	mode := c.DefaultQuery("mode", "full")
+	switch mode {
+	case "full":
+		writer, err = exports.NewWriter(format, c.Writer)
+	case "anonymized":
+		writer, err = exports.NewAnonymizedWriter(format, c.Writer, h.hashKey)
+	default:
+		err = errors.New("mode must be full or anonymized")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Name the download after the mode and export time.
+	name := "contacts"
+	if mode == "anonymized" {
+		name = "contacts-anonymized"
+	}
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().In(helpers.AppTimezone()).Format("20060102-150405"), format)
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "no-store")
+
+	// Stream the contacts through the writer.
+	err = h.service.ExportContacts(c.Request.Context(), filter, func(contact *models.Contact) error {
+		return writer.Write(contact)
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		if !c.Writer.Written() {
+			c.Header("Content-Type", "")
+			c.Header("Content-Disposition", "")
+			c.JSON(http.StatusInternalServerError, responses.APIResponse{
+				Code:    "INTERNAL_SERVER_ERROR",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		log.Printf("Contact export aborted: %v", err)
+		c.Abort()
+	}
+}
+
+// writeContactVCard responds with the vCard of contact as a file download.
+func writeContactVCard(c *router.Context, contact *models.Contact) {
+	var file bytes.Buffer
+	writer, err := exports.NewWriter(exports.FormatVCard, &file)
+	if err == nil {
+		err = writer.Write(contact)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="contact-%d.vcf"`, contact.ID))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, exports.FormatVCard.ContentType(), file.Bytes())
+}
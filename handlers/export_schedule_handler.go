@@ -0,0 +1,231 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the ExportScheduleHandler lets admins schedule recurring exports of
+// contacts delivered to managers by email or to S3, and run them on demand.
+package handlers
+
+import (
+	"api-contact-form/requests"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"errors"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// ExportScheduleHandler handles HTTP requests related to scheduled exports.
+type ExportScheduleHandler struct {
+	service services.ExportScheduleService
+}
+
+// NewExportScheduleHandler creates a new instance of ExportScheduleHandler with the provided ExportScheduleService.
+func NewExportScheduleHandler(service services.ExportScheduleService) *ExportScheduleHandler {
+	return &ExportScheduleHandler{service: service}
+}
+
+// GetExportSchedules retrieves every scheduled export.
+//
+// On success, it returns the schedules with the outcome of their last run with a 200
+// status code.
+func (h *ExportScheduleHandler) GetExportSchedules(c *router.Context) {
+	// Fetch the schedules using the service layer.
+	schedules, err := h.service.ListSchedules(c.Request.Context())
+	if respondExportScheduleError(c, err) {
+		return
+	}
+
+	data := make([]responses.ExportScheduleResponse, 0, len(schedules))
+	for i := range schedules {
+		data = append(data, responses.ExportScheduleResponseFromModel(&schedules[i]))
+	}
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Export schedules retrieved successfully",
+		Data:    data,
+	})
+}
+
+// GetExportSchedule retrieves a scheduled export by its ID.
+//
+// If the schedule does not exist, it returns a 404 status code. On success, it returns
+// the schedule with a 200 status code.
+func (h *ExportScheduleHandler) GetExportSchedule(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+
+	// Fetch the schedule using the service layer.
+	schedule, err := h.service.GetSchedule(c.Request.Context(), id)
+	if respondExportScheduleError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Export schedule retrieved successfully",
+		Data:    responses.ExportScheduleResponseFromModel(schedule),
+	})
+}
+
+// CreateExportSchedule registers a new scheduled export.
+//
+// It expects a JSON payload matching the ExportScheduleRequest structure. Invalid settings
+// are answered with a 400 status code, and destinations the instance is not configured
+// for with a 422 status code. On success, it returns the schedule with a 201 status code.
+func (h *ExportScheduleHandler) CreateExportSchedule(c *router.Context) {
+	var req requests.ExportScheduleRequest
+
+	// Bind the JSON payload to the ExportScheduleRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to register the schedule.
+	schedule, err := h.service.CreateSchedule(c.Request.Context(), &req)
+	if respondExportScheduleError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, responses.APIResponse{
+		Code:    "CREATED",
+		Message: "Export schedule created successfully",
+		Data:    responses.ExportScheduleResponseFromModel(schedule),
+	})
+}
+
+// UpdateExportSchedule changes a scheduled export by its ID.
+//
+// It expects a JSON payload matching the ExportScheduleRequest structure; the outcome of
+// the last run is kept. It answers like CreateExportSchedule, and with a 404 status code
+// when the schedule does not exist. On success, it returns the schedule with a 200 status code.
+func (h *ExportScheduleHandler) UpdateExportSchedule(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+
+	var req requests.ExportScheduleRequest
+
+	// Bind the JSON payload to the ExportScheduleRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to update the schedule.
+	schedule, err := h.service.UpdateSchedule(c.Request.Context(), id, &req)
+	if respondExportScheduleError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Export schedule updated successfully",
+		Data:    responses.ExportScheduleResponseFromModel(schedule),
+	})
+}
+
+// DeleteExportSchedule removes a scheduled export by its ID.
+//
+// If the schedule does not exist, it returns a 404 status code. On success, it returns a
+// 200 status code.
+func (h *ExportScheduleHandler) DeleteExportSchedule(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+
+	// Use the service layer to delete the schedule.
+	if respondExportScheduleError(c, h.service.DeleteSchedule(c.Request.Context(), id)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Export schedule deleted successfully",
+		Data:    nil,
+	})
+}
+
+// RunExportSchedule runs a scheduled export by its ID right away, such as to try it out.
+//
+// The report is exported and delivered before the response. If the schedule does not
+// exist, it returns a 404 status code, and while another run of it is starting, a 409
+// status code. Otherwise it returns the schedule with a 200 status code; a failed export
+// or delivery is reported in its last_error.
+func (h *ExportScheduleHandler) RunExportSchedule(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+
+	// Use the service layer to run the schedule.
+	schedule, err := h.service.RunSchedule(c.Request.Context(), id)
+	if respondExportScheduleError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Export schedule run",
+		Data:    responses.ExportScheduleResponseFromModel(schedule),
+	})
+}
+
+// respondExportScheduleError responds to the errors of the export schedule service: a 400
+// status code for invalid settings, a 422 status code for unavailable destinations, a 404
+// status code for missing schedules and a 409 status code for runs in progress. It reports
+// whether a response was written.
+func respondExportScheduleError(c *router.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrInvalidExportSchedule):
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	case errors.Is(err, services.ErrExportDestinationUnavailable):
+		c.JSON(http.StatusUnprocessableEntity, responses.APIResponse{
+			Code:    "UNPROCESSABLE_ENTITY",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Export schedule not found",
+			Data:    nil,
+		})
+	case errors.Is(err, services.ErrExportRunInProgress):
+		c.JSON(http.StatusConflict, responses.APIResponse{
+			Code:    "CONFLICT",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	}
+	return true
+}
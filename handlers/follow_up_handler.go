@@ -0,0 +1,208 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the FollowUpHandler lets agents plan when to get back to contacts, and
+// publishes their follow-ups as an iCalendar feed their calendar application subscribes to.
+package handlers
+
+import (
+	"api-contact-form/exports"
+	"api-contact-form/requests"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// followUpCalendarPath is the path of the calendar feed of follow-ups.
+const followUpCalendarPath = "/follow-ups/calendar.ics"
+
+// FollowUpHandler handles HTTP requests related to the follow-ups of contacts.
+type FollowUpHandler struct {
+	service      services.FollowUpService
+	calendarName string
+}
+
+// NewFollowUpHandler creates a new instance of FollowUpHandler with the provided
+// FollowUpService. The calendar feeds are named calendarName.
+func NewFollowUpHandler(service services.FollowUpService, calendarName string) *FollowUpHandler {
+	return &FollowUpHandler{service: service, calendarName: calendarName}
+}
+
+// GetFollowUps retrieves the follow-ups planned by the caller, by due date.
+//
+// On success, it returns the follow-ups with the name of their contact with a 200 status code.
+func (h *FollowUpHandler) GetFollowUps(c *router.Context) {
+	// Fetch the follow-ups using the service layer.
+	followUps, err := h.service.ListFollowUps(c.Request.Context(), auditActor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Follow-ups retrieved successfully",
+		Data:    responses.FollowUpResponsesFromModels(followUps),
+	})
+}
+
+// SetFollowUp plans or reschedules the caller's follow-up of a contact by its ID.
+//
+// It expects a JSON payload matching the FollowUpRequest structure. Invalid payloads are
+// answered with a 400 status code, and missing contacts with a 404 status code. On
+// success, it returns the follow-up with a 200 status code.
+func (h *FollowUpHandler) SetFollowUp(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+
+	var req requests.FollowUpRequest
+
+	// Bind the JSON payload to the FollowUpRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to save the follow-up.
+	followUp, err := h.service.SetFollowUp(c.Request.Context(), auditActor(c), id, &req)
+	if respondFollowUpError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Follow-up saved successfully",
+		Data:    responses.FollowUpResponseFromModel(followUp),
+	})
+}
+
+// ClearFollowUp removes the caller's follow-up of a contact by its ID.
+//
+// If the caller planned no follow-up of the contact, it returns a 404 status code. On
+// success, it returns the removed follow-up with a 200 status code.
+func (h *FollowUpHandler) ClearFollowUp(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+
+	// Use the service layer to remove the follow-up.
+	followUp, err := h.service.ClearFollowUp(c.Request.Context(), auditActor(c), id)
+	if respondFollowUpError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Follow-up removed successfully",
+		Data:    responses.FollowUpResponseFromModel(followUp),
+	})
+}
+
+// GetFollowUpFeed returns the URL of the caller's calendar feed of follow-ups.
+//
+// The URL carries a token, since calendar applications cannot send credentials; it is
+// answered with a 200 status code.
+func (h *FollowUpHandler) GetFollowUpFeed(c *router.Context) {
+	// Build the URL on the host the request was sent to.
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	feed := url.URL{
+		Scheme:   scheme,
+		Host:     c.Request.Host,
+		Path:     followUpCalendarPath,
+		RawQuery: url.Values{"token": {h.service.FeedToken(auditActor(c))}}.Encode(),
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Follow-up calendar feed retrieved successfully",
+		Data:    responses.FollowUpFeedResponse{URL: feed.String()},
+	})
+}
+
+// GetFollowUpCalendar serves the iCalendar feed of the follow-ups of the agent identified
+// by the "token" query parameter.
+//
+// Invalid or revoked tokens are answered with a 401 status code. On success, it returns
+// the calendar with a 200 status code.
+func (h *FollowUpHandler) GetFollowUpCalendar(c *router.Context) {
+	// Identify the agent from the token.
+	owner, err := h.service.FeedOwner(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFeedToken) {
+			c.JSON(http.StatusUnauthorized, responses.APIResponse{
+				Code:    "UNAUTHORIZED",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch the follow-ups and write them as a calendar.
+	followUps, err := h.service.ListFollowUps(c.Request.Context(), owner)
+	var calendar bytes.Buffer
+	if err == nil {
+		err = exports.WriteFollowUpCalendar(&calendar, h.calendarName, followUps, time.Now())
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, exports.CalendarContentType, calendar.Bytes())
+}
+
+// respondFollowUpError responds to the errors of the follow-up service: a 404 status code
+// for missing contacts or follow-ups. It reports whether a response was written.
+func respondFollowUpError(c *router.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Contact or follow-up not found",
+			Data:    nil,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the FormSchemaHandler describes the contact form as JSON, so that
+// frontends and the embed widget render it from the schema defined by the server instead
+// of hard-coding its fields and spam protection.
+package handlers
+
+import (
+	"api-contact-form/requests"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// textareaMinLength is the smallest maximum length of the text fields rendered as a
+// textarea rather than a single-line input.
+const textareaMinLength = 500
+
+// FormSettings holds the settings of the contact form described by its schema.
+type FormSettings struct {
+	// ConsentRequired is set when submissions must carry consent=true, and PolicyVersion
+	// and TermsVersion are the versions of the texts agreed to.
+	ConsentRequired bool
+	PolicyVersion   string
+	TermsVersion    string
+	// Attachments is the policy of the attachments, or nil when they are not accepted.
+	Attachments *services.AttachmentPolicy
+	// CaptchaProvider and CaptchaSiteKey configure the CAPTCHA, if any.
+	CaptchaProvider string
+	CaptchaSiteKey  string
+	// HoneypotField is the name of the hidden field that must be left empty, if any.
+	HoneypotField string
+	// ProofOfWork and FormToken are set when submissions need these challenges.
+	ProofOfWork bool
+	FormToken   bool
+}
+
+// FormSchemaHandler handles HTTP requests related to the schema of the contact form.
+type FormSchemaHandler struct {
+	schema responses.FormSchemaResponse
+}
+
+// NewFormSchemaHandler creates a new instance of FormSchemaHandler describing the contact
+// form, identified by slug, with settings.
+func NewFormSchemaHandler(slug string, settings FormSettings) *FormSchemaHandler {
+	schema := responses.FormSchemaResponse{
+		Slug:     slug,
+		Endpoint: "/contacts",
+		Fields:   formFields(reflect.TypeFor[requests.ContactRequest](), settings.ConsentRequired),
+		Consent: responses.FormConsentResponse{
+			Required:      settings.ConsentRequired,
+			PolicyVersion: settings.PolicyVersion,
+			TermsVersion:  settings.TermsVersion,
+		},
+		HoneypotField: settings.HoneypotField,
+		Challenges:    []responses.FormChallengeResponse{},
+	}
+	if policy := settings.Attachments; policy != nil {
+		schema.Attachments = &responses.FormAttachmentsResponse{
+			Field:        "attachments",
+			MaxCount:     policy.MaxCount,
+			MaxSize:      policy.MaxSize,
+			AllowedTypes: policy.AllowedTypes,
+		}
+	}
+	if settings.CaptchaProvider != "" {
+		schema.Captcha = &responses.FormCaptchaResponse{
+			Provider: settings.CaptchaProvider,
+			SiteKey:  settings.CaptchaSiteKey,
+			Header:   "X-Captcha-Token",
+		}
+	}
+	if settings.ProofOfWork {
+		schema.Challenges = append(schema.Challenges, responses.FormChallengeResponse{Name: "proof_of_work", Endpoint: "/challenges/pow"})
+	}
+	if settings.FormToken {
+		schema.Challenges = append(schema.Challenges, responses.FormChallengeResponse{Name: "form_token", Endpoint: "/challenges/form-token"})
+	}
+	return &FormSchemaHandler{schema: schema}
+}
+
+// GetFormSchema retrieves the schema of a form by its slug.
+//
+// If no form has the slug, it returns a 404 status code. On success, it returns the fields
+// of the form, their validation rules and labels, and the spam protection to pass with a
+// 200 status code.
+func (h *FormSchemaHandler) GetFormSchema(c *router.Context) {
+	if c.Param("slug") != h.schema.Slug {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Form not found",
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Form schema retrieved successfully",
+		Data:    h.schema,
+	})
+}
+
+// formFields describes the labelled fields of the request struct t from their json,
+// validate and label tags, so that the schema follows the validation of the service layer.
+// The consent checkbox is required when consentRequired is set.
+func formFields(t reflect.Type, consentRequired bool) []responses.FormFieldResponse {
+	var fields []responses.FormFieldResponse
+	for i := range t.NumField() {
+		field := t.Field(i)
+		label := field.Tag.Get("label")
+		if label == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		schema := responses.FormFieldResponse{Name: name, Type: "text", Label: label}
+
+		// Translate the validation rules.
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			rule, param, _ := strings.Cut(rule, "=")
+			switch rule {
+			case "required":
+				schema.Required = true
+			case "max":
+				schema.MaxLength, _ = strconv.Atoi(param)
+			case "email":
+				schema.Type, schema.Format = "email", "email"
+			case "e164":
+				schema.Type, schema.Format = "tel", "e164"
+			}
+		}
+		switch {
+		case field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Bool:
+			schema.Type, schema.Required = "checkbox", consentRequired
+		case schema.Type == "text" && schema.MaxLength >= textareaMinLength:
+			schema.Type = "textarea"
+		}
+		fields = append(fields, schema)
+	}
+	return fields
+}
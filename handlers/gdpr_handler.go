@@ -0,0 +1,128 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the GDPRHandler serves data subject access requests by exporting
+// everything stored about an email address, and runs the data retention policy on demand.
+package handlers
+
+import (
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"net/http"
+	"net/mail"
+)
+
+// GDPRHandler handles HTTP requests related to data protection obligations.
+type GDPRHandler struct {
+	service services.ContactService
+}
+
+// NewGDPRHandler creates a new instance of GDPRHandler with the provided ContactService.
+func NewGDPRHandler(service services.ContactService) *GDPRHandler {
+	return &GDPRHandler{service}
+}
+
+// ExportSubjectData gathers every record related to an email address.
+//
+// It expects the email address in the "email" query parameter.
+// On success, it returns a SubjectAccessExport bundle with a 200 status code,
+// including soft-deleted contacts, the metadata of their attachments, the emails of their
+// conversations and their audit trail.
+// An invalid email returns a 400 status code.
+func (h *GDPRHandler) ExportSubjectData(c *router.Context) {
+	// Retrieve and validate the 'email' query parameter.
+	email := c.Query("email")
+	if _, err := mail.ParseAddress(email); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid email",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch all contacts of the email address using the service layer.
+	contacts, err := h.service.GetContactsByEmail(c.Request.Context(), email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch the metadata of the files uploaded with the contacts.
+	contactIDs := make([]uint, len(contacts))
+	for i, contact := range contacts {
+		contactIDs[i] = contact.ID
+	}
+	attachments, err := h.service.GetAttachments(c.Request.Context(), contactIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch the emails exchanged with the submitter of the contacts.
+	messages, err := h.service.GetEmailThreads(c.Request.Context(), contactIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch the audit trail of the contacts, which records the values they had.
+	audits, err := h.service.GetAuditTrails(c.Request.Context(), contactIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Respond with the export bundle.
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Subject data exported successfully",
+		Data:    responses.SubjectAccessExportFromModels(email, contacts, attachments, messages, audits),
+	})
+}
+
+// RunRetention applies the data retention policy right away, on behalf of the caller.
+//
+// With "dry_run=true" in the query string, the contacts that would be purged or
+// anonymized are only reported. On success, it returns a RetentionReportResponse with the
+// number of contacts of each kind and a sample of their IDs, with a 200 status code.
+// Invalid parameters are answered with a 400 status code.
+func (h *GDPRHandler) RunRetention(c *router.Context) {
+	dryRun, ok := bindDryRun(c)
+	if !ok {
+		return
+	}
+
+	// Purge and anonymize the expired contacts using the service layer.
+	report, err := h.service.ApplyRetention(c.Request.Context(), auditActor(c), dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Retention policy applied successfully",
+		Data:    responses.RetentionReportResponseFromReport(report),
+	})
+}
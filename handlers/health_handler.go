@@ -1,22 +1,55 @@
 // Package handlers contains the HTTP handler implementations for various endpoints.
 //
 // Specifically, the HealthHandler provides a health check endpoint to verify
-// that the API is running correctly.
+// that the API is running correctly, the liveness and readiness probes that also
+// check the database connection, the startup probe, and the health of the background
+// job queues.
 package handlers
 
 import (
+	"api-contact-form/jobs"
 	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/startup"
+	"context"
 	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
 
-	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // HealthHandler handles HTTP requests related to health checks.
-type HealthHandler struct{}
+type HealthHandler struct {
+	db       *gorm.DB
+	timeout  time.Duration
+	starting *startup.Tracker
+	draining atomic.Bool
 
-// NewHealthHandler creates a new instance of HealthHandler.
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+	jobQueues  []jobs.Monitor
+	stallAfter time.Duration
+}
+
+// NewHealthHandler creates a new instance of HealthHandler pinging db, with the given
+// timeout, for the liveness and readiness probes, and reporting the initialization
+// tracked by starting for the startup probe.
+func NewHealthHandler(db *gorm.DB, timeout time.Duration, starting *startup.Tracker) *HealthHandler {
+	return &HealthHandler{db: db, timeout: timeout, starting: starting}
+}
+
+// Drain makes the readiness probe fail from now on, so that load balancers stop sending
+// requests while the server shuts down.
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// WatchJobQueues reports the health of queues on the job health endpoint, where a queue
+// whose oldest job has been waiting for longer than stallAfter is stalled. It must be
+// called before the server starts.
+func (h *HealthHandler) WatchJobQueues(stallAfter time.Duration, queues ...jobs.Monitor) {
+	h.jobQueues = queues
+	h.stallAfter = stallAfter
 }
 
 // HealthCheck responds with a simple message indicating that the API is running.
@@ -30,9 +63,118 @@ func NewHealthHandler() *HealthHandler {
 //	    "code": "SUCCESS",
 //	    "message": "API is running."
 //	}
-func (h *HealthHandler) HealthCheck(c *gin.Context) {
+func (h *HealthHandler) HealthCheck(c *router.Context) {
 	c.JSON(http.StatusOK, responses.APIResponse{
 		Code:    "SUCCESS",
 		Message: "API is running.",
 	})
-}
\ No newline at end of file
+}
+
+// Healthz is the liveness probe. It pings the database and responds with a 200 status
+// code when it answers within the timeout, and a 503 status code otherwise.
+func (h *HealthHandler) Healthz(c *router.Context) {
+	if err := h.ping(c.Request.Context()); err != nil {
+		c.JSON(http.StatusServiceUnavailable, responses.APIResponse{
+			Code:    "SERVICE_UNAVAILABLE",
+			Message: "Database unreachable: " + err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "API is alive.",
+		Data:    nil,
+	})
+}
+
+// Readyz is the readiness probe. It responds like Healthz, but also with a 503 status code
+// once the server started shutting down.
+func (h *HealthHandler) Readyz(c *router.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, responses.APIResponse{
+			Code:    "SERVICE_UNAVAILABLE",
+			Message: "API is shutting down.",
+			Data:    nil,
+		})
+		return
+	}
+	if err := h.ping(c.Request.Context()); err != nil {
+		c.JSON(http.StatusServiceUnavailable, responses.APIResponse{
+			Code:    "SERVICE_UNAVAILABLE",
+			Message: "Database unreachable: " + err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "API is ready.",
+		Data:    nil,
+	})
+}
+
+// Startupz is the startup probe. It responds with a 200 status code and the completed
+// steps of the initialization once the server started, and a 503 status code otherwise.
+// Until then, the requests are answered by the startup.Tracker, which also fails the
+// readiness probe and passes the liveness probe.
+func (h *HealthHandler) Startupz(c *router.Context) {
+	if !h.starting.Started() {
+		c.JSON(http.StatusServiceUnavailable, responses.APIResponse{
+			Code:    "SERVICE_UNAVAILABLE",
+			Message: "API is starting.",
+			Data:    h.starting.Status(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "API is started.",
+		Data:    h.starting.Status(),
+	})
+}
+
+// Jobz reports the health of the background job queues, such as those of the
+// notifications and webhooks: the jobs waiting in each of them, the age of the oldest one
+// and the jobs the workers failed. It responds with a 200 status code, or a 503 status code
+// naming the stalled queues when any is.
+func (h *HealthHandler) Jobz(c *router.Context) {
+	queues := make([]responses.JobQueueResponse, 0, len(h.jobQueues))
+	var stalled []string
+	for _, queue := range h.jobQueues {
+		response := responses.JobQueueResponseFromStats(queue.QueueStats(), h.stallAfter)
+		if response.Stalled {
+			stalled = append(stalled, response.Name)
+		}
+		queues = append(queues, response)
+	}
+
+	if len(stalled) > 0 {
+		c.JSON(http.StatusServiceUnavailable, responses.APIResponse{
+			Code:    "SERVICE_UNAVAILABLE",
+			Message: "Job queues stalled: " + strings.Join(stalled, ", "),
+			Data:    queues,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Job queues are healthy.",
+		Data:    queues,
+	})
+}
+
+// ping pings the database connection pool, giving up after the timeout.
+func (h *HealthHandler) ping(ctx context.Context) error {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,362 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the InboundEmailHandler receives emails forwarded by SendGrid or Mailgun
+// inbound parse webhooks and turns them into contact records, or adds them to the
+// conversation of the contact they reply to, and receives the bounces and spam complaints
+// reported by their event webhooks.
+package handlers
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/ids"
+	"api-contact-form/middleware"
+	"api-contact-form/models"
+	"api-contact-form/requests"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"cmp"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strings"
+)
+
+// InboundEmailHandler handles inbound parse webhooks from email providers.
+type InboundEmailHandler struct {
+	service services.ContactService
+	token   string
+}
+
+// NewInboundEmailHandler creates a new instance of InboundEmailHandler.
+//
+// Webhook calls must carry token in the "token" query parameter, which is how the shared
+// secret is embedded in the webhook URL configured at the provider. When token is empty,
+// every call is rejected, as anyone could otherwise create contacts.
+func NewInboundEmailHandler(service services.ContactService, token string) *InboundEmailHandler {
+	return &InboundEmailHandler{service: service, token: token}
+}
+
+// ReceiveEmail converts an inbound email into a new contact, or adds it to the conversation
+// of the contact it replies to.
+//
+// It accepts the form-encoded or multipart payloads posted by SendGrid ("from", "subject", "text")
+// and Mailgun ("from"/"sender", "subject", "stripped-text"/"body-plain").
+// The attached files, posted as "attachment1", "attachment2", ... by SendGrid and as
+// "attachment-1", "attachment-2", ... by Mailgun, are stored with the contact when the
+// attachment policy allows them; see services.ContactService.CreateContactFromEmail.
+// On success, it returns the created contact with a 201 status code.
+// An email whose In-Reply-To or References headers name an email of the conversation of a
+// contact is added to that contact, which is returned with a 200 status code.
+// An email whose Message-ID was already ingested is acknowledged with a 200 status code so
+// that the provider does not keep retrying it.
+func (h *InboundEmailHandler) ReceiveEmail(c *router.Context) {
+	// Reject calls that do not carry the configured shared secret.
+	if !h.authorize(c) {
+		return
+	}
+
+	// Normalize the provider-specific fields.
+	req, err := parseInboundEmail(c)
+	if middleware.BodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, responses.APIResponse{
+			Code:    "PAYLOAD_TOO_LARGE",
+			Message: "Request body too large",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to add a reply to its conversation, or else create a new contact.
+	contact, err := h.service.AddEmailReply(c.Request.Context(), req)
+	created := errors.Is(err, services.ErrNoEmailThread)
+	if created {
+		contact, err = h.service.CreateContactFromEmail(c.Request.Context(), req)
+	}
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if respondValidationErrors(c, err) {
+		return
+	}
+	if respondRuleViolations(c, err) {
+		return
+	}
+	if respondOpenContactExists(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrDuplicateMessage) {
+		c.JSON(http.StatusOK, responses.APIResponse{
+			Code:    "SUCCESS",
+			Message: "Inbound email already received",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	if !created {
+		c.JSON(http.StatusOK, responses.APIResponse{
+			Code:    "SUCCESS",
+			Message: "Inbound email added to the conversation of the contact",
+			Data:    responses.ContactResponseFromModel(contact),
+		})
+		return
+	}
+
+	// Respond with the created contact and a success message.
+	c.JSON(http.StatusCreated, responses.APIResponse{
+		Code:    "CREATED",
+		Message: "Inbound email received successfully",
+		Data:    responses.ContactResponseFromModel(contact),
+	})
+}
+
+// ReceiveEmailEvents marks the contacts of the addresses reported by the email provider
+// as undeliverable.
+//
+// It accepts the JSON array of events posted by the SendGrid event webhook, where "bounce"
+// events, except blocked ones, and "spamreport" events are handled, and the JSON event
+// posted by Mailgun webhooks, where permanent "failed" events and "complained" events are
+// handled. Other events are acknowledged and ignored. On success, it returns the IDs of
+// the contacts newly marked with a 200 status code.
+// Like ReceiveEmail, it rejects the calls without the shared secret, and it is mounted
+// behind middleware.BodyLimit, whose limit it answers with a 413 status code.
+func (h *InboundEmailHandler) ReceiveEmailEvents(c *router.Context) {
+	// Reject calls that do not carry the configured shared secret.
+	if !h.authorize(c) {
+		return
+	}
+
+	// Normalize the provider-specific events.
+	body, err := io.ReadAll(c.Request.Body)
+	if middleware.BodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, responses.APIResponse{
+			Code:    "PAYLOAD_TOO_LARGE",
+			Message: "Request body too large",
+			Data:    nil,
+		})
+		return
+	}
+	var events []requests.EmailEventRequest
+	if err == nil {
+		events, err = parseEmailEvents(body)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to mark the contacts of every event.
+	result := responses.EmailEventsResponse{Events: len(events), Marked: []ids.ID{}}
+	for _, event := range events {
+		marked, err := h.service.ReportEmailIssue(c.Request.Context(), services.EmailEventsActor, event.Email, event.Issue)
+		if err != nil {
+			// The provider retries the batch, and the events already handled are skipped.
+			c.JSON(http.StatusInternalServerError, responses.APIResponse{
+				Code:    "INTERNAL_SERVER_ERROR",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		result.Marked = append(result.Marked, ids.IDsOf(marked)...)
+	}
+
+	// Respond with the contacts marked.
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Email events received successfully",
+		Data:    result,
+	})
+}
+
+// authorize reports whether the call carries the configured shared secret, and responds
+// with a 401 status code when it does not or no secret is configured.
+func (h *InboundEmailHandler) authorize(c *router.Context) bool {
+	if h.token != "" && subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(h.token)) == 1 {
+		return true
+	}
+	c.JSON(http.StatusUnauthorized, responses.APIResponse{
+		Code:    "UNAUTHORIZED",
+		Message: "Invalid inbound email token",
+		Data:    nil,
+	})
+	return false
+}
+
+// sendGridEvent is an event of the SendGrid event webhook.
+type sendGridEvent struct {
+	Email string `json:"email"`
+	Event string `json:"event"`
+	// Type tells bounces from blocked messages, which are temporary, in "bounce" events.
+	Type string `json:"type"`
+}
+
+// mailgunEvent is the payload of a Mailgun webhook.
+type mailgunEvent struct {
+	EventData struct {
+		Event     string `json:"event"`
+		Severity  string `json:"severity"`
+		Recipient string `json:"recipient"`
+	} `json:"event-data"`
+}
+
+// parseEmailEvents reads the bounces and complaints of a SendGrid or Mailgun event payload.
+func parseEmailEvents(body []byte) ([]requests.EmailEventRequest, error) {
+	var events []requests.EmailEventRequest
+	add := func(email string, issue models.EmailIssue) {
+		if email != "" {
+			events = append(events, requests.EmailEventRequest{Email: email, Issue: issue})
+		}
+	}
+
+	// SendGrid posts an array of events, Mailgun a single event.
+	if trimmed := strings.TrimSpace(string(body)); strings.HasPrefix(trimmed, "[") {
+		var batch []sendGridEvent
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, fmt.Errorf("invalid SendGrid events: %w", err)
+		}
+		for _, event := range batch {
+			switch {
+			case event.Event == "bounce" && event.Type != "blocked":
+				add(event.Email, models.EmailBounced)
+			case event.Event == "spamreport":
+				add(event.Email, models.EmailComplained)
+			}
+		}
+		return events, nil
+	}
+
+	var event mailgunEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid Mailgun event: %w", err)
+	}
+	switch data := event.EventData; {
+	case data.Event == "failed" && data.Severity == "permanent":
+		add(data.Recipient, models.EmailBounced)
+	case data.Event == "complained":
+		add(data.Recipient, models.EmailComplained)
+	}
+	return events, nil
+}
+
+// parseInboundEmail reads the SendGrid or Mailgun form fields into an InboundEmailRequest.
+func parseInboundEmail(c *router.Context) (*requests.InboundEmailRequest, error) {
+	// Read the form first, so that a body over the limit is not mistaken for missing fields.
+	form, err := c.MultipartForm()
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return nil, fmt.Errorf("invalid form: %w", err)
+	}
+
+	from := firstNonEmpty(c.PostForm("from"), c.PostForm("sender"))
+	if from == "" {
+		return nil, fmt.Errorf("missing sender address")
+	}
+
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	// Prefer Mailgun's reply-stripped text, then the full plain-text body, then HTML.
+	body := firstNonEmpty(
+		c.PostForm("stripped-text"),
+		c.PostForm("body-plain"),
+		c.PostForm("text"),
+		c.PostForm("body-html"),
+		c.PostForm("html"),
+	)
+
+	// Mailgun posts the Message-Id and threading headers as their own fields; SendGrid only
+	// includes the raw headers.
+	headers := c.PostForm("headers")
+	messageID := firstNonEmpty(c.PostForm("Message-Id"), rawHeader(headers, "Message-Id"))
+	inReplyTo := helpers.ParseMessageIDs(firstNonEmpty(c.PostForm("In-Reply-To"), rawHeader(headers, "In-Reply-To")))
+	references := helpers.ParseMessageIDs(firstNonEmpty(c.PostForm("References"), rawHeader(headers, "References")))
+
+	req := &requests.InboundEmailRequest{
+		FromName:    address.Name,
+		FromEmail:   address.Address,
+		Subject:     c.PostForm("subject"),
+		MessageID:   messageID,
+		References:  references,
+		Body:        body,
+		Attachments: inboundAttachments(form),
+	}
+	if len(inReplyTo) > 0 {
+		req.InReplyTo = inReplyTo[0]
+	}
+	return req, nil
+}
+
+// inboundAttachments returns the files of the "attachment" parts of a multipart form, such
+// as SendGrid's "attachment1" or Mailgun's "attachment-1", in their numbered order.
+func inboundAttachments(form *multipart.Form) []*multipart.FileHeader {
+	if form == nil {
+		return nil
+	}
+	var keys []string
+	for key := range form.File {
+		if strings.HasPrefix(key, "attachment") {
+			keys = append(keys, key)
+		}
+	}
+	// Sort "attachment10" after "attachment9".
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(a), len(b)), strings.Compare(a, b))
+	})
+
+	var files []*multipart.FileHeader
+	for _, key := range keys {
+		files = append(files, form.File[key]...)
+	}
+	return files
+}
+
+// rawHeader returns the value of the named header from a raw header block, or an empty string.
+func rawHeader(headers, name string) string {
+	if headers == "" {
+		return ""
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(headers + "\r\n\r\n"))
+	if err != nil {
+		return ""
+	}
+	return msg.Header.Get(name)
+}
+
+// firstNonEmpty returns the first non-empty string of values.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
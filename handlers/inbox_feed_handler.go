@@ -0,0 +1,115 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the InboxFeedHandler pushes the unread counter of the admin inbox and the
+// summaries of the contacts that change over a WebSocket, so that the admin view updates
+// in near real time without polling.
+package handlers
+
+import (
+	"api-contact-form/inboxfeed"
+	"api-contact-form/middleware"
+	"api-contact-form/models"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"encoding/json"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// inboxFeedKeepAlive is the interval of the pings sent on idle inbox feed connections, so
+// that proxies do not close them.
+const inboxFeedKeepAlive = 30 * time.Second
+
+// InboxFeedHandler handles the WebSocket connections of the inbox feed.
+type InboxFeedHandler struct {
+	feed     *inboxfeed.Feed
+	service  services.InboxService
+	policies map[models.Role]middleware.RedactionPolicy
+}
+
+// NewInboxFeedHandler creates a new instance of InboxFeedHandler with the provided Feed
+// and InboxService. The messages sent to callers whose role has a policy in policies are
+// redacted as the responses of the API.
+func NewInboxFeedHandler(feed *inboxfeed.Feed, service services.InboxService, policies map[models.Role]middleware.RedactionPolicy) *InboxFeedHandler {
+	return &InboxFeedHandler{feed: feed, service: service, policies: policies}
+}
+
+// Stream upgrades the request to a WebSocket pushing the changes of the inbox.
+//
+// Browsers cannot set headers on WebSocket requests, so the JWT of the caller may also be
+// passed in the "access_token" query parameter. The first message is a "counters"
+// InboxUpdateResponse with the number of unread contacts, followed by an "update" one
+// every time a contact enters, changes in or leaves the inbox. Messages from the client
+// are ignored. The connection ends when the client disconnects or the server shuts down.
+func (h *InboxFeedHandler) Stream(c *router.Context) {
+	var policy middleware.RedactionPolicy
+	if identity := middleware.CurrentIdentity(c); identity != nil {
+		policy = h.policies[identity.Role]
+	}
+
+	server := websocket.Server{Handler: func(conn *websocket.Conn) {
+		// Subscribe before counting, so that no change is missed in between.
+		updates, unsubscribe := h.feed.Subscribe()
+		defer unsubscribe()
+
+		unread, err := h.service.CountUnread(c.Request.Context())
+		if err != nil {
+			return
+		}
+		if sendInboxUpdate(conn, policy, responses.InboxUpdateResponse{Type: "counters", Unread: unread}) != nil {
+			return
+		}
+
+		// Read until the client disconnects, as control frames are only handled while reading.
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			var ignored []byte
+			for websocket.Message.Receive(conn, &ignored) == nil {
+			}
+		}()
+
+		keepAlive := time.NewTicker(inboxFeedKeepAlive)
+		defer keepAlive.Stop()
+
+		submitted := 0
+		for {
+			select {
+			case <-closed:
+				return
+			case update, open := <-updates:
+				if !open {
+					return
+				}
+				if update.Event == models.EventContactCreated {
+					submitted++
+				}
+				if sendInboxUpdate(conn, policy, responses.InboxUpdateResponseFromUpdate(&update, submitted)) != nil {
+					return
+				}
+			case <-keepAlive.C:
+				conn.PayloadType = websocket.PingFrame
+				_, err := conn.Write(nil)
+				conn.PayloadType = websocket.TextFrame
+				if err != nil {
+					return
+				}
+			}
+		}
+	}}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// sendInboxUpdate sends message as a JSON text frame, redacted with policy.
+func sendInboxUpdate(conn *websocket.Conn, policy middleware.RedactionPolicy, message responses.InboxUpdateResponse) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if len(policy) > 0 {
+		body = policy.Rewrite(body)
+	}
+	return websocket.Message.Send(conn, string(body))
+}
@@ -0,0 +1,128 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the InboxHandler lists the admin inbox from its projection, and rebuilds
+// the projection from the contacts.
+package handlers
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// defaultInboxLimit is the number of entries GetInbox returns by default.
+	defaultInboxLimit = 50
+	// maxInboxLimit is the largest number of entries GetInbox returns.
+	maxInboxLimit = 500
+)
+
+// InboxHandler handles HTTP requests related to the admin inbox.
+type InboxHandler struct {
+	service services.InboxService
+}
+
+// NewInboxHandler creates a new instance of InboxHandler with the provided InboxService.
+func NewInboxHandler(service services.InboxService) *InboxHandler {
+	return &InboxHandler{service: service}
+}
+
+// GetInbox retrieves the entries of the admin inbox: the contacts pinned by the team
+// first, then the most recently active first.
+//
+// Snoozed contacts are left out, and listed alone with "snoozed=true". The query string
+// also filters with "unread=true" and "status", and pages with "limit" (50 by default, at
+// most 500) and "offset". Invalid parameters are answered with a 400 status code. On
+// success, it returns the entries with a 200 status code.
+func (h *InboxHandler) GetInbox(c *router.Context) {
+	// Read the filters and paging from the query string.
+	filter, err := parseInboxFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch the entries using the service layer.
+	entries, err := h.service.ListInbox(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Inbox retrieved successfully",
+		Data:    responses.InboxEntryResponsesFromModels(entries),
+	})
+}
+
+// RebuildInbox rebuilds the projection of the admin inbox from the contacts, to repair it
+// after failed updates or changes made outside of the API.
+//
+// On success, it returns the number of entries with a 200 status code.
+func (h *InboxHandler) RebuildInbox(c *router.Context) {
+	// Rebuild the projection using the service layer.
+	count, err := h.service.RebuildInbox(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Inbox rebuilt successfully",
+		Data:    router.H{"entries": count},
+	})
+}
+
+// parseInboxFilter reads the filter and paging query parameters of GetInbox.
+func parseInboxFilter(c *router.Context) (repositories.InboxFilter, error) {
+	filter := repositories.InboxFilter{
+		Status: models.Status(c.Query("status")),
+		Limit:  defaultInboxLimit,
+	}
+	if filter.Status != "" && !filter.Status.Valid() {
+		return filter, errors.New("Invalid status")
+	}
+
+	var err error
+	if value := c.Query("snoozed"); value != "" {
+		if filter.Snoozed, err = strconv.ParseBool(value); err != nil {
+			return filter, errors.New("Invalid snoozed, expected true or false")
+		}
+	}
+	if value := c.Query("unread"); value != "" {
+		if filter.UnreadOnly, err = strconv.ParseBool(value); err != nil {
+			return filter, errors.New("Invalid unread, expected true or false")
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxInboxLimit {
+			return filter, fmt.Errorf("Invalid limit, expected a number between 1 and %d", maxInboxLimit)
+		}
+		filter.Limit = limit
+	}
+	if filter.Offset, err = nonNegativeQuery(c, "offset"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
@@ -7,9 +7,8 @@ package handlers
 
 import (
 	"api-contact-form/responses"
+	"api-contact-form/router"
 	"net/http"
-
-	"github.com/gin-gonic/gin"
 )
 
 // MainHandler handles HTTP requests for the main/root endpoint.
@@ -30,7 +29,7 @@ func NewMainHandler() *MainHandler {
 //	    "code": "SUCCESS",
 //	    "message": "API Contact Form is running."
 //	}
-func (h *MainHandler) MainHandler(c *gin.Context) {
+func (h *MainHandler) MainHandler(c *router.Context) {
 	c.JSON(http.StatusOK, responses.APIResponse{
 		Code:    "SUCCESS",
 		Message: "API Contact Form is running.",
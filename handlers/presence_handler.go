@@ -0,0 +1,166 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the PresenceHandler lets agents announce that they are viewing or replying
+// to a contact, and streams these announcements, so that two agents do not both answer
+// the same message.
+package handlers
+
+import (
+	"api-contact-form/presence"
+	"api-contact-form/requests"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// presenceKeepAlive is the interval of the comments sent on idle presence streams, so that
+// proxies do not close them.
+const presenceKeepAlive = 15 * time.Second
+
+// PresenceHandler handles HTTP requests related to the presence of agents on contacts.
+type PresenceHandler struct {
+	tracker  *presence.Tracker
+	contacts services.ContactService
+}
+
+// NewPresenceHandler creates a new instance of PresenceHandler with the provided Tracker
+// and ContactService.
+func NewPresenceHandler(tracker *presence.Tracker, contacts services.ContactService) *PresenceHandler {
+	return &PresenceHandler{tracker: tracker, contacts: contacts}
+}
+
+// Heartbeat records that the caller is viewing or replying to a contact by its ID.
+//
+// Clients call it while the contact is open, more often than the presence TTL, and with
+// the "replying" activity while a reply is written. It expects an optional JSON payload
+// matching the PresenceRequest structure. If the contact does not exist, it returns a 404
+// status code. On success, it returns the agents present on the contact with a 200 status
+// code; other agents than the caller mean a collision.
+func (h *PresenceHandler) Heartbeat(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+
+	var req requests.PresenceRequest
+
+	// Bind the optional JSON payload to the PresenceRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if req.Activity == "" {
+		req.Activity = presence.Viewing
+	}
+	if !req.Activity.Valid() {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid activity, expected viewing or replying",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Only contacts that exist can be viewed.
+	if _, err := h.contacts.GetContactByID(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Contact not found",
+			Data:    nil,
+		})
+		return
+	}
+
+	viewers := h.tracker.Heartbeat(id, auditActor(c), req.Activity)
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Presence recorded successfully",
+		Data:    responses.PresenceResponseFromViewers(id, viewers),
+	})
+}
+
+// Leave records that the caller closed a contact by its ID.
+//
+// It returns the agents still present on the contact with a 200 status code.
+func (h *PresenceHandler) Leave(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+
+	h.tracker.Leave(id, auditActor(c))
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Presence removed successfully",
+		Data:    responses.PresenceResponseFromViewers(id, h.tracker.Viewers(id)),
+	})
+}
+
+// GetPresence retrieves the agents present on a contact by its ID.
+//
+// It returns the agents, possibly none, with a 200 status code.
+func (h *PresenceHandler) GetPresence(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Presence retrieved successfully",
+		Data:    responses.PresenceResponseFromViewers(id, h.tracker.Viewers(id)),
+	})
+}
+
+// Stream streams the presence of agents on contacts as server-sent events.
+//
+// It first sends a "presence" event for every contact with agents present, then one every
+// time the agents present on a contact change, including when the last one leaves. The
+// data of the events is a PresenceResponse. The stream ends when the client disconnects
+// or the server shuts down.
+func (h *PresenceHandler) Stream(c *router.Context) {
+	// Subscribe before taking the snapshot, so that no change is missed in between.
+	changes, unsubscribe := h.tracker.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, change := range h.tracker.Snapshot() {
+		c.SSEvent("presence", responses.PresenceResponseFromViewers(change.ContactID, change.Viewers))
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(presenceKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case change, open := <-changes:
+			if !open {
+				return
+			}
+			c.SSEvent("presence", responses.PresenceResponseFromViewers(change.ContactID, change.Viewers))
+		case <-keepAlive.C:
+			_, _ = io.WriteString(c.Writer, ": keep-alive\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
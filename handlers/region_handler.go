@@ -0,0 +1,85 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the RegionHandler reports the replication lag of a standby region, for the
+// failover tooling and monitoring, and promotes the standby to primary.
+package handlers
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/region"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// RegionHandler handles HTTP requests related to the region of the instance.
+type RegionHandler struct {
+	region  *region.Region
+	changes services.ChangeLogService
+}
+
+// NewRegionHandler creates a new instance of RegionHandler for the provided Region,
+// recording the promotions in changes.
+func NewRegionHandler(r *region.Region, changes services.ChangeLogService) *RegionHandler {
+	return &RegionHandler{region: r, changes: changes}
+}
+
+// GetReplicationStatus is the replication probe. It responds with the role of the region
+// and, for standbys, the replication lag of the database, with a 200 status code when the
+// lag is within the configured limit and a 503 status code otherwise. The primary region
+// always answers with a 200 status code.
+func (h *RegionHandler) GetReplicationStatus(c *router.Context) {
+	status := h.region.Status(c.Request.Context())
+	if !status.Healthy {
+		c.JSON(http.StatusServiceUnavailable, responses.APIResponse{
+			Code:    "SERVICE_UNAVAILABLE",
+			Message: "Replication lag is above the limit or cannot be measured.",
+			Data:    status,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Replication status retrieved successfully",
+		Data:    status,
+	})
+}
+
+// PromoteRegion promotes the standby region to primary, so that it accepts writes.
+//
+// It is meant to be called by the failover tooling once the primary region is down.
+// Regions that are already the primary are answered with a 409 status code, and failed
+// database promotions with a 500 status code, leaving the region a standby. On success,
+// it returns the promotion with a 200 status code, including the error of the promotion
+// hook when it failed.
+func (h *RegionHandler) PromoteRegion(c *router.Context) {
+	promotion, err := h.region.Promote(c.Request.Context(), auditActor(c))
+	if errors.Is(err, region.ErrNotStandby) {
+		c.JSON(http.StatusConflict, responses.APIResponse{
+			Code:    "CONFLICT",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	h.changes.Record(c.Request.Context(), models.ChangeFailover, auditActor(c), "region:"+promotion.Region,
+		fmt.Sprintf("Region %s promoted to primary", promotion.Region), promotion)
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Region promoted successfully",
+		Data:    promotion,
+	})
+}
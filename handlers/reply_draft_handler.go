@@ -0,0 +1,182 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the ReplyDraftHandler lets agents save the draft of a reply to a contact
+// and resume it later, and shows teammates that a reply is in progress.
+package handlers
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/requests"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// ReplyDraftHandler handles HTTP requests related to the reply drafts of contacts.
+type ReplyDraftHandler struct {
+	service services.ReplyDraftService
+}
+
+// NewReplyDraftHandler creates a new instance of ReplyDraftHandler with the provided ReplyDraftService.
+func NewReplyDraftHandler(service services.ReplyDraftService) *ReplyDraftHandler {
+	return &ReplyDraftHandler{service: service}
+}
+
+// GetReplyDraft retrieves the draft of the reply to a contact by its ID.
+//
+// If the contact does not exist or has no draft, it returns a 404 status code.
+// On success, it returns the draft with its author with a 200 status code.
+func (h *ReplyDraftHandler) GetReplyDraft(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+
+	// Fetch the draft using the service layer.
+	draft, err := h.service.GetDraft(c.Request.Context(), id)
+	if respondReplyDraftError(c, err, draft) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Reply draft retrieved successfully",
+		Data:    responses.ReplyDraftResponseFromModel(draft),
+	})
+}
+
+// SaveReplyDraft creates or updates the draft of the reply to a contact by its ID.
+//
+// It expects a JSON payload matching the ReplyDraftRequest structure. While a teammate's
+// draft is in progress, it returns that draft with a 409 status code, unless "force=true"
+// is given in the query string to take it over. If the contact does not exist, it returns
+// a 404 status code. On success, it returns the draft with a 200 status code.
+func (h *ReplyDraftHandler) SaveReplyDraft(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+	force, ok := bindForce(c)
+	if !ok {
+		return
+	}
+
+	var req requests.ReplyDraftRequest
+
+	// Bind the JSON payload to the ReplyDraftRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to save the draft.
+	draft, err := h.service.SaveDraft(c.Request.Context(), auditActor(c), id, &req, force)
+	if respondReplyDraftError(c, err, draft) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Reply draft saved successfully",
+		Data:    responses.ReplyDraftResponseFromModel(draft),
+	})
+}
+
+// DiscardReplyDraft removes the draft of the reply to a contact by its ID.
+//
+// While a teammate's draft is in progress, it returns that draft with a 409 status code,
+// unless "force=true" is given in the query string. If the contact has no draft, it
+// returns a 404 status code. On success, it returns the discarded draft with a 200 status code.
+func (h *ReplyDraftHandler) DiscardReplyDraft(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, ok := bindID(c)
+	if !ok {
+		return
+	}
+	force, ok := bindForce(c)
+	if !ok {
+		return
+	}
+
+	// Use the service layer to discard the draft.
+	draft, err := h.service.DiscardDraft(c.Request.Context(), auditActor(c), id, force)
+	if respondReplyDraftError(c, err, draft) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Reply draft discarded successfully",
+		Data:    responses.ReplyDraftResponseFromModel(draft),
+	})
+}
+
+// bindID parses the 'id' parameter of the URL. Invalid values are answered with a
+// 400 status code; it reports whether the value was valid.
+func bindID(c *router.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// bindForce parses the optional "force" query parameter. Invalid values are answered with
+// a 400 status code; it reports whether the value was valid.
+func bindForce(c *router.Context) (force bool, ok bool) {
+	force, err := strconv.ParseBool(c.DefaultQuery("force", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid force, expected true or false",
+			Data:    nil,
+		})
+		return false, false
+	}
+	return force, true
+}
+
+// respondReplyDraftError responds to the errors of the reply draft service: a 404 status
+// code for missing contacts or drafts, and a 409 status code with the teammate's draft for
+// drafts in progress. It reports whether a response was written.
+func respondReplyDraftError(c *router.Context, err error, draft *models.ReplyDraft) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Contact or reply draft not found",
+			Data:    nil,
+		})
+	case errors.Is(err, services.ErrReplyDraftInProgress):
+		c.JSON(http.StatusConflict, responses.APIResponse{
+			Code:    "CONFLICT",
+			Message: err.Error(),
+			Data:    responses.ReplyDraftResponseFromModel(draft),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the SettingsHandler exports the instance-level settings as a YAML bundle
+// and imports such a bundle, for promoting a configuration from one instance to another.
+package handlers
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/middleware"
+	"api-contact-form/models"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/rules"
+	"api-contact-form/services"
+	"api-contact-form/settings"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxBundleSize is the largest settings bundle accepted by ImportSettings.
+const maxBundleSize = 1 << 20
+
+// SettingsHandler handles HTTP requests related to the settings bundle.
+type SettingsHandler struct {
+	db      *gorm.DB
+	rules   *rules.Store
+	changes services.ChangeLogService
+}
+
+// NewSettingsHandler creates a new instance of SettingsHandler exporting and importing
+// the settings stored in db and the rules of store, which is nil when no rules file is
+// configured. Imports are recorded in changes.
+func NewSettingsHandler(db *gorm.DB, store *rules.Store, changes services.ChangeLogService) *SettingsHandler {
+	return &SettingsHandler{db: db, rules: store, changes: changes}
+}
+
+// ExportSettings downloads the settings of the instance as a YAML bundle.
+//
+// The signing secrets of the webhooks are only included with "secrets=true" in the query
+// string. Invalid parameters are answered with a 400 status code.
+func (h *SettingsHandler) ExportSettings(c *router.Context) {
+	includeSecrets, err := strconv.ParseBool(c.DefaultQuery("secrets", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid secrets, expected true or false",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Collect and encode the bundle before answering, so that errors get a JSON response.
+	bundle, err := settings.Export(h.db, h.rules.Current(), includeSecrets)
+	var encoded bytes.Buffer
+	if err == nil {
+		err = settings.Encode(&encoded, bundle)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Name the download after the export time.
+	filename := fmt.Sprintf("settings-%s.yaml", time.Now().In(helpers.AppTimezone()).Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/yaml", encoded.Bytes())
+}
+
+// ImportSettings applies a YAML bundle, sent as the request body, to the instance.
+//
+// Webhook subscriptions are created or updated by URL, and the validation rules are
+// replaced and activated immediately. Bundles that cannot be parsed or are invalid are
+// answered with a 400 status code, and bundles with rules when the instance has no rules
+// file with a 409 status code. On success, it returns the changes made with a 200 status code.
+func (h *SettingsHandler) ImportSettings(c *router.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBundleSize)
+	bundle, err := settings.Decode(c.Request.Body)
+	if middleware.BodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, responses.APIResponse{
+			Code:    "PAYLOAD_TOO_LARGE",
+			Message: "Request body too large",
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Apply the bundle, activating the rules right away.
+	var saveRules func(*rules.Rules) error
+	if h.rules != nil {
+		saveRules = h.rules.Replace
+	}
+	result, err := settings.Import(h.db, bundle, saveRules)
+	if errors.Is(err, settings.ErrRulesNotConfigured) {
+		c.JSON(http.StatusConflict, responses.APIResponse{
+			Code:    "CONFLICT",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if errors.Is(err, settings.ErrInvalidBundle) {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	summary := fmt.Sprintf("Settings bundle imported: %d webhooks created, %d updated", result.WebhooksCreated, result.WebhooksUpdated)
+	if result.RulesReplaced {
+		summary += ", validation rules replaced"
+	}
+	h.changes.Record(c.Request.Context(), models.ChangeSettings, auditActor(c), "settings", summary, result)
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Settings imported successfully",
+		Data:    result,
+	})
+}
@@ -0,0 +1,105 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the SystemChangeHandler lets operators read the changelog of the instance:
+// the schema migrations, settings changes and template edits, to tell what changed
+// before an incident.
+package handlers
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// defaultSystemChangeLimit is the number of entries GetSystemChanges returns by default.
+	defaultSystemChangeLimit = 100
+	// maxSystemChangeLimit is the largest number of entries GetSystemChanges returns.
+	maxSystemChangeLimit = 1000
+)
+
+// SystemChangeHandler handles HTTP requests related to the changelog.
+type SystemChangeHandler struct {
+	service services.ChangeLogService
+}
+
+// NewSystemChangeHandler creates a new instance of SystemChangeHandler with the provided ChangeLogService.
+func NewSystemChangeHandler(service services.ChangeLogService) *SystemChangeHandler {
+	return &SystemChangeHandler{service: service}
+}
+
+// GetSystemChanges retrieves the changelog of the instance, newest first.
+//
+// The query string filters with "kind" (migration, settings, template or failover), "actor" (such
+// as user:1 or system:migrate), and "from" and "to", RFC 3339 times or YYYY-MM-DD dates,
+// and limits the number of entries with "limit" (100 by default, at most 1000). Invalid
+// parameters are answered with a 400 status code. On success, it returns the entries
+// with a 200 status code.
+func (h *SystemChangeHandler) GetSystemChanges(c *router.Context) {
+	// Read the filters from the query string.
+	filter := repositories.SystemChangeFilter{
+		Kind:  models.ChangeKind(c.Query("kind")),
+		Actor: c.Query("actor"),
+		Limit: defaultSystemChangeLimit,
+	}
+	if filter.Kind != "" && !filter.Kind.Valid() {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid kind",
+			Data:    nil,
+		})
+		return
+	}
+	var err error
+	if filter.Since, err = timeQuery(c, "from", false); err == nil {
+		filter.Until, err = timeQuery(c, "to", true)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxSystemChangeLimit {
+			c.JSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: fmt.Sprintf("Invalid limit, expected a number between 1 and %d", maxSystemChangeLimit),
+				Data:    nil,
+			})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	// Fetch the entries using the service layer.
+	changes, err := h.service.ListChanges(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Convert the entry models to response formats.
+	changeResponses := make([]responses.SystemChangeResponse, 0, len(changes))
+	for i := range changes {
+		changeResponses = append(changeResponses, responses.SystemChangeResponseFromModel(&changes[i]))
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "System changes retrieved successfully",
+		Data:    changeResponses,
+	})
+}
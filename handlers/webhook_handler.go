@@ -0,0 +1,392 @@
+// Package handlers contains the HTTP handler implementations for various endpoints.
+//
+// Specifically, the WebhookHandler lets admins register the URLs that receive contact
+// lifecycle events, inspect the delivery log of each subscription and replay its events.
+package handlers
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/requests"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"api-contact-form/webhooks"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultDeliveryLimit is the number of delivery attempts returned when no limit is requested.
+const defaultDeliveryLimit = 50
+
+// WebhookHandler handles HTTP requests related to webhook subscriptions.
+type WebhookHandler struct {
+	service services.WebhookService
+	changes services.ChangeLogService
+}
+
+// NewWebhookHandler creates a new instance of WebhookHandler with the provided WebhookService,
+// recording the changes made to the subscriptions in changes.
+func NewWebhookHandler(service services.WebhookService, changes services.ChangeLogService) *WebhookHandler {
+	return &WebhookHandler{service: service, changes: changes}
+}
+
+// GetWebhooks retrieves every webhook subscription.
+//
+// On success, it returns the list of subscriptions with a 200 status code.
+func (h *WebhookHandler) GetWebhooks(c *router.Context) {
+	// Fetch the subscriptions using the service layer.
+	subscriptions, err := h.service.ListSubscriptions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Convert the subscription models to response formats.
+	webhookResponses := make([]responses.WebhookResponse, 0, len(subscriptions))
+	for i := range subscriptions {
+		webhookResponses = append(webhookResponses, responses.WebhookResponseFromModel(&subscriptions[i]))
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Webhooks retrieved successfully",
+		Data:    webhookResponses,
+	})
+}
+
+// CreateWebhook registers a new webhook subscription.
+//
+// It expects a JSON payload matching the WebhookRequest structure.
+// On success, it returns the subscription with a 201 status code; this is the only
+// response that contains its signing secret.
+func (h *WebhookHandler) CreateWebhook(c *router.Context) {
+	var req requests.WebhookRequest
+
+	// Bind the JSON payload to the WebhookRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to register the subscription.
+	subscription, err := h.service.CreateSubscription(&req)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if respondWebhookError(c, err) {
+		return
+	}
+
+	response := responses.WebhookResponseFromModel(subscription)
+	h.changes.Record(c.Request.Context(), models.ChangeSettings, auditActor(c), webhookSubject(subscription.ID),
+		"Webhook subscription created for "+subscription.URL, response)
+	response.Secret = subscription.Secret
+	c.JSON(http.StatusCreated, responses.APIResponse{
+		Code:    "CREATED",
+		Message: "Webhook created successfully",
+		Data:    response,
+	})
+}
+
+// UpdateWebhook changes a webhook subscription by its ID.
+//
+// It expects a JSON payload matching the WebhookRequest structure; the signing secret is kept.
+// On success, it returns the updated subscription with a 200 status code.
+func (h *WebhookHandler) UpdateWebhook(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	var req requests.WebhookRequest
+
+	// Bind the JSON payload to the WebhookRequest struct.
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to update the subscription.
+	subscription, err := h.service.UpdateSubscription(uint(id), &req)
+	if respondConstraintViolation(c, err) {
+		return
+	}
+	if respondWebhookError(c, err) {
+		return
+	}
+
+	response := responses.WebhookResponseFromModel(subscription)
+	h.changes.Record(c.Request.Context(), models.ChangeSettings, auditActor(c), webhookSubject(subscription.ID),
+		"Webhook subscription updated for "+subscription.URL, response)
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Webhook updated successfully",
+		Data:    response,
+	})
+}
+
+// DeleteWebhook removes a webhook subscription and its delivery log by its ID.
+//
+// On success, it returns a success message with a 200 status code.
+func (h *WebhookHandler) DeleteWebhook(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to delete the subscription.
+	if respondWebhookError(c, h.service.DeleteSubscription(uint(id))) {
+		return
+	}
+	h.changes.Record(c.Request.Context(), models.ChangeSettings, auditActor(c), webhookSubject(uint(id)),
+		"Webhook subscription deleted", nil)
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Webhook deleted successfully",
+		Data:    nil,
+	})
+}
+
+// GetWebhookDeliveries retrieves the latest delivery attempts of a webhook subscription by its ID.
+//
+// The optional "limit" query parameter sets the number of attempts returned, 50 by default
+// and at most 500. On success, it returns the attempts, newest first, with a 200 status code.
+func (h *WebhookHandler) GetWebhookDeliveries(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	limit, err := nonNegativeQuery(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+	if limit == 0 {
+		limit = defaultDeliveryLimit
+	}
+	limit = min(limit, 500)
+
+	// Fetch the delivery log using the service layer.
+	deliveries, err := h.service.ListDeliveries(uint(id), limit)
+	if respondWebhookError(c, err) {
+		return
+	}
+
+	deliveryResponses := make([]responses.WebhookDeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		deliveryResponses = append(deliveryResponses, responses.WebhookDeliveryResponseFromModel(&deliveries[i]))
+	}
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Webhook deliveries retrieved successfully",
+		Data:    deliveryResponses,
+	})
+}
+
+// ReplayWebhook sends the events of a period again to a webhook subscription by its ID,
+// such as after its receiver was down.
+//
+// The query string sets the period with "from" (required) and "to" (now by default), as
+// RFC 3339 times or YYYY-MM-DD dates. Only the events the subscription is interested in
+// and still in the outbox are sent, with their original ID, at the rate set by
+// WEBHOOK_REPLAY_RATE. While a replay of the subscription is in progress, it returns a
+// 409 status code. On success, it returns the progress of the queued replay with a 202
+// status code; GetWebhookReplay reports it afterwards.
+func (h *WebhookHandler) ReplayWebhook(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Read the period from the query string.
+	from, err := timeQuery(c, "from", false)
+	var to time.Time
+	if err == nil {
+		to, err = timeQuery(c, "to", true)
+	}
+	if err == nil && from.IsZero() {
+		err = errors.New("from is required")
+	}
+	if err == nil && to.IsZero() {
+		to = time.Now()
+	}
+	if err == nil && !from.Before(to) {
+		err = errors.New("Invalid period, from must be before to")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to queue the replay.
+	progress, err := h.service.ReplayEvents(uint(id), from, to)
+	if respondWebhookError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusAccepted, responses.APIResponse{
+		Code:    "ACCEPTED",
+		Message: "Webhook replay queued successfully",
+		Data:    progress,
+	})
+}
+
+// GetWebhookReplay retrieves the progress of the last replay of a webhook subscription by its ID.
+//
+// If the subscription was never replayed since the server started, it returns a 404 status
+// code. On success, it returns the progress with a 200 status code.
+func (h *WebhookHandler) GetWebhookReplay(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Fetch the progress using the service layer.
+	progress, err := h.service.GetReplay(uint(id))
+	if respondWebhookError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Webhook replay retrieved successfully",
+		Data:    progress,
+	})
+}
+
+// CancelWebhookReplay stops the replay in progress of a webhook subscription by its ID.
+//
+// If the subscription has no replay in progress, it returns a 404 status code. On
+// success, it returns the progress of the replay with a 200 status code.
+func (h *WebhookHandler) CancelWebhookReplay(c *router.Context) {
+	// Retrieve the 'id' parameter from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: "Invalid ID",
+			Data:    nil,
+		})
+		return
+	}
+
+	// Use the service layer to cancel the replay.
+	progress, err := h.service.CancelReplay(uint(id))
+	if respondWebhookError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Webhook replay cancelled successfully",
+		Data:    progress,
+	})
+}
+
+// respondWebhookError responds to the errors of the webhook service.
+// It reports whether a response was written.
+func respondWebhookError(c *router.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrInvalidWebhookURL), errors.Is(err, services.ErrInvalidWebhookEvent):
+		c.JSON(http.StatusBadRequest, responses.APIResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Webhook not found",
+			Data:    nil,
+		})
+	case errors.Is(err, services.ErrReplayNotFound):
+		c.JSON(http.StatusNotFound, responses.APIResponse{
+			Code:    "NOT_FOUND",
+			Message: "Webhook replay not found",
+			Data:    nil,
+		})
+	case errors.Is(err, webhooks.ErrReplayInProgress):
+		c.JSON(http.StatusConflict, responses.APIResponse{
+			Code:    "CONFLICT",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	case errors.Is(err, webhooks.ErrReplayQueueFull):
+		c.JSON(http.StatusServiceUnavailable, responses.APIResponse{
+			Code:    "SERVICE_UNAVAILABLE",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, responses.APIResponse{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: err.Error(),
+			Data:    nil,
+		})
+	}
+	return true
+}
+
+// webhookSubject is the subject of the changelog entries of a webhook subscription.
+func webhookSubject(id uint) string {
+	return "webhook:" + strconv.FormatUint(uint64(id), 10)
+}
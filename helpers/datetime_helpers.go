@@ -38,4 +38,10 @@ func init() {
 //   - A string representing the formatted time.
 func FormatTimeHuman(t time.Time) string {
 	return t.In(appTimezone).Format("2006-01-02 15:04:05")
-}
\ No newline at end of file
+}
+
+// AppTimezone returns the application's configured timezone, used to interpret
+// dates supplied without a timezone.
+func AppTimezone() *time.Location {
+	return appTimezone
+}
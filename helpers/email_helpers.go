@@ -0,0 +1,72 @@
+// helpers/helpers.go
+// Package helpers provides utility functions for the API Contact Form application.
+//
+// It includes functions for telling the domain of an email address and whether it
+// belongs to a free email provider rather than to a company, and for generating and
+// reading the Message-IDs that thread emails together.
+
+package helpers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+// FreeEmailDomains lists the domains of common free email providers, whose addresses do
+// not tell the company of their owner.
+var FreeEmailDomains = []string{
+	"aol.com", "gmail.com", "gmx.com", "googlemail.com", "hotmail.com", "icloud.com",
+	"live.com", "mail.com", "me.com", "msn.com", "outlook.com", "proton.me",
+	"protonmail.com", "yahoo.com", "yandex.com", "zoho.com",
+}
+
+// EmailDomain returns the lowercase domain of an email address, or an empty string when
+// it has none.
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
+
+// MatchesDomain reports whether domain is one of domains or one of their subdomains.
+func MatchesDomain(domain string, domains []string) bool {
+	for _, d := range domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewMessageID returns a new unique Message-ID, without angle brackets, in the domain of
+// the email address from, or of localhost when it has none.
+func NewMessageID(from string) string {
+	domain := EmailDomain(strings.Trim(from, "<> "))
+	if domain == "" {
+		domain = "localhost"
+	}
+	return fmt.Sprintf("%s@%s", rand.Text(), domain)
+}
+
+// NormalizeMessageID returns a Message-ID without its surrounding spaces and angle
+// brackets, as Message-IDs are compared.
+func NormalizeMessageID(id string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(id), "<"), ">")
+}
+
+// ParseMessageIDs returns the Message-IDs of an In-Reply-To or References header, such as
+// "<a@example.com> <b@example.com>", normalized and in order.
+func ParseMessageIDs(header string) []string {
+	var ids []string
+	for _, field := range strings.FieldsFunc(header, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\r' || r == '\n' || r == ','
+	}) {
+		if id := NormalizeMessageID(field); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
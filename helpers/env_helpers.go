@@ -10,6 +10,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ParseEnvList parses a comma-separated environment variable into a slice of strings.
@@ -52,4 +53,70 @@ func GetEnvBool(key string, defaultValue bool) bool {
 		return defaultValue
 	}
 	return parsedVal
-}
\ No newline at end of file
+}
+
+// GetEnvInt retrieves an integer environment variable.
+// It returns the defaultValue if the environment variable is not set or cannot be parsed.
+//
+// Parameters:
+//   - key: The name of the environment variable to retrieve.
+//   - defaultValue: The default integer value to return if the variable is not set or invalid.
+//
+// Returns:
+//   - An int representing the environment variable's value or the default value.
+func GetEnvInt(key string, defaultValue int) int {
+	val, exists := os.LookupEnv(key)
+	if !exists || val == "" {
+		return defaultValue
+	}
+	parsedVal, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		log.Printf("Warning: Could not parse integer value for %s: %v. Using default: %v", key, err, defaultValue)
+		return defaultValue
+	}
+	return parsedVal
+}
+
+// GetEnvDuration retrieves a duration environment variable such as "30s" or "5m".
+// It returns the defaultValue if the environment variable is not set or cannot be parsed.
+//
+// Parameters:
+//   - key: The name of the environment variable to retrieve.
+//   - defaultValue: The default duration to return if the variable is not set or invalid.
+//
+// Returns:
+//   - A time.Duration representing the environment variable's value or the default value.
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	val, exists := os.LookupEnv(key)
+	if !exists || val == "" {
+		return defaultValue
+	}
+	parsedVal, err := time.ParseDuration(val)
+	if err != nil {
+		log.Printf("Warning: Could not parse duration value for %s: %v. Using default: %v", key, err, defaultValue)
+		return defaultValue
+	}
+	return parsedVal
+}
+
+// GetEnvFloat retrieves a floating-point environment variable such as "0.25".
+// It returns the defaultValue if the environment variable is not set or cannot be parsed.
+//
+// Parameters:
+//   - key: The name of the environment variable to retrieve.
+//   - defaultValue: The default value to return if the variable is not set or invalid.
+//
+// Returns:
+//   - A float64 representing the environment variable's value or the default value.
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	val, exists := os.LookupEnv(key)
+	if !exists || val == "" {
+		return defaultValue
+	}
+	parsedVal, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+	if err != nil {
+		log.Printf("Warning: Could not parse float value for %s: %v. Using default: %v", key, err, defaultValue)
+		return defaultValue
+	}
+	return parsedVal
+}
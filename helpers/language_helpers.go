@@ -0,0 +1,85 @@
+// helpers/helpers.go
+// Package helpers provides utility functions for the API Contact Form application.
+//
+// It includes functions for detecting the language of a message from its most common
+// words, and for listing the language codes to try for a language tag.
+
+package helpers
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minLanguageMatches is the number of common words a message must contain before its
+// language is trusted.
+const minLanguageMatches = 2
+
+// languageWords lists the most common words of the languages DetectLanguage knows, left
+// out when they are just as common in another of the languages.
+var languageWords = map[string][]string{
+	"de": {"und", "ich", "nicht", "ist", "das", "ein", "eine", "mit", "sie", "für", "auf", "wir", "bitte", "danke", "haben", "ihr", "ihre", "sind", "wie", "kann"},
+	"en": {"the", "and", "is", "you", "your", "for", "with", "have", "this", "that", "are", "please", "thanks", "would", "can", "about", "hello", "what", "we", "our"},
+	"es": {"el", "los", "las", "una", "por", "con", "para", "es", "usted", "gracias", "hola", "quiero", "del", "pero", "como", "muy", "su", "sus", "estoy", "tengo"},
+	"fr": {"le", "les", "une", "et", "est", "je", "vous", "pour", "avec", "dans", "sur", "merci", "bonjour", "nous", "votre", "vos", "pas", "mais", "suis", "très"},
+	"id": {"yang", "dan", "saya", "untuk", "dengan", "tidak", "ini", "itu", "ada", "kami", "anda", "bisa", "terima", "kasih", "mohon", "apakah", "dari", "akan", "sudah", "halo"},
+	"it": {"il", "gli", "che", "è", "sono", "per", "con", "grazie", "buongiorno", "vorrei", "non", "della", "mio", "mia", "questo", "anche", "come", "ciao", "sua", "suo"},
+	"nl": {"het", "een", "ik", "niet", "ook", "voor", "met", "zijn", "wij", "jullie", "graag", "bedankt", "hallo", "mijn", "uw", "maar", "wat", "kunt", "hebben", "dank"},
+	"pt": {"os", "uma", "não", "você", "obrigado", "obrigada", "olá", "gostaria", "com", "para", "meu", "minha", "são", "muito", "está", "tenho", "também", "seu", "sua", "pelo"},
+}
+
+// languageIndex maps every common word to the languages it belongs to.
+var languageIndex = func() map[string][]string {
+	index := make(map[string][]string)
+	for language, words := range languageWords {
+		for _, word := range words {
+			index[word] = append(index[word], language)
+		}
+	}
+	return index
+}()
+
+// DetectLanguage returns the code of the language text is most likely written in, such as
+// "en" or "id", from the common words it contains. It returns an empty string when text is
+// too short or too ambiguous to tell.
+func DetectLanguage(text string) string {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for _, language := range languageIndex[word] {
+			counts[language]++
+		}
+	}
+
+	// Keep the language with the most matches, unless another one has as many.
+	best, bestCount, tied := "", 0, false
+	for language, count := range counts {
+		switch {
+		case count > bestCount:
+			best, bestCount, tied = language, count, false
+		case count == bestCount:
+			tied = true
+		}
+	}
+	if bestCount < minLanguageMatches || tied {
+		return ""
+	}
+	return best
+}
+
+// LanguageFallbacks returns the language codes to try for tag, from the most to the least
+// specific, such as "pt-br" then "pt" for "pt-BR". It returns nil for an empty tag.
+func LanguageFallbacks(tag string) []string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	var fallbacks []string
+	for tag != "" {
+		fallbacks = append(fallbacks, tag)
+		dash := strings.LastIndex(tag, "-")
+		if dash < 0 {
+			break
+		}
+		tag = tag[:dash]
+	}
+	return fallbacks
+}
@@ -0,0 +1,127 @@
+// Package hooks lets deployments inject custom logic into the submission pipeline,
+// such as custom scoring or extra enrichment, without forking the handler or service code.
+//
+// Hooks are plain functions registered on a Registry for a stage of the pipeline.
+// Custom Go packages implement Plugin and register themselves on Default from an
+// init function, so enabling one only takes a blank import in package main:
+//
+//	import _ "example.com/acme/contactscoring"
+package hooks
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/requests"
+	"log"
+	"sync"
+)
+
+// PreValidateFunc runs before a submission is validated. It may modify the request,
+// for example to normalize or enrich fields, and rejects the submission by returning
+// an error. Return a *rules.ValidationError to reject it with a 422 listing the violations.
+type PreValidateFunc func(req *requests.ContactRequest, meta requests.SubmissionMeta) error
+
+// PostCreateFunc runs after a contact was stored. The contact is already created, so
+// errors are only logged.
+type PostCreateFunc func(contact *models.Contact) error
+
+// PreNotifyFunc runs before notifications about a new contact are sent.
+// Returning false suppresses the notifications of that contact.
+type PreNotifyFunc func(contact *models.Contact) bool
+
+// Plugin is implemented by custom Go packages that hook into the submission pipeline.
+type Plugin interface {
+	// Name identifies the plugin in logs.
+	Name() string
+	// Register adds the hooks of the plugin to the registry.
+	Register(r *Registry)
+}
+
+// Default is the registry used by the application. Plugins register on it from init.
+var Default = &Registry{}
+
+// Registry holds the hooks of every stage, run in registration order.
+// It is safe for concurrent use. A nil Registry has no hooks.
+type Registry struct {
+	mu          sync.RWMutex
+	preValidate []PreValidateFunc
+	postCreate  []PostCreateFunc
+	preNotify   []PreNotifyFunc
+}
+
+// Use registers the hooks of the given plugins.
+func (r *Registry) Use(plugins ...Plugin) {
+	for _, plugin := range plugins {
+		plugin.Register(r)
+		log.Printf("Plugin %s registered", plugin.Name())
+	}
+}
+
+// OnPreValidate registers a hook run before submissions are validated.
+func (r *Registry) OnPreValidate(fn PreValidateFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.preValidate = append(r.preValidate, fn)
+}
+
+// OnPostCreate registers a hook run after contacts are stored.
+func (r *Registry) OnPostCreate(fn PostCreateFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.postCreate = append(r.postCreate, fn)
+}
+
+// OnPreNotify registers a hook run before notifications about new contacts are sent.
+func (r *Registry) OnPreNotify(fn PreNotifyFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.preNotify = append(r.preNotify, fn)
+}
+
+// RunPreValidate runs the pre-validate hooks and returns the first error, which
+// rejects the submission. Later hooks are not run after an error.
+func (r *Registry) RunPreValidate(req *requests.ContactRequest, meta requests.SubmissionMeta) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, fn := range r.preValidate {
+		if err := fn(req, meta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunPostCreate runs every post-create hook, logging the errors they return.
+func (r *Registry) RunPostCreate(contact *models.Contact) {
+	if r == nil {
+		return
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, fn := range r.postCreate {
+		if err := fn(contact); err != nil {
+			log.Printf("Post-create hook failed for contact %d: %v", contact.ID, err)
+		}
+	}
+}
+
+// RunPreNotify runs the pre-notify hooks and reports whether notifications should be
+// sent. It stops at the first hook that suppresses them.
+func (r *Registry) RunPreNotify(contact *models.Contact) bool {
+	if r == nil {
+		return true
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, fn := range r.preNotify {
+		if !fn(contact) {
+			return false
+		}
+	}
+	return true
+}
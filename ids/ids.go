@@ -0,0 +1,162 @@
+// Package ids generates the primary keys and public IDs of new rows, according to the
+// strategy selected in the configuration.
+//
+// Primary keys are assigned by the database (auto-increment) by default. Deployments
+// writing to several regions at once use Snowflake IDs instead, which replicas generate
+// without coordinating, and contacts may be given a UUIDv7 public ID shown to submitters.
+// The Plugin assigns the generated IDs to every row created through GORM.
+package ids
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Strategy is a way of generating IDs.
+type Strategy string
+
+const (
+	// StrategyAutoIncrement lets the database assign increasing primary keys.
+	StrategyAutoIncrement Strategy = "autoincrement"
+	// StrategySnowflake generates 63-bit primary keys made of a timestamp, the node
+	// generating them and a sequence number.
+	StrategySnowflake Strategy = "snowflake"
+	// StrategyUUIDv7 generates time-ordered UUIDs, as public IDs: they do not fit the
+	// integer primary keys.
+	StrategyUUIDv7 Strategy = "uuidv7"
+)
+
+// publicIDColumn is the column holding the generated public IDs of the tables having one.
+const publicIDColumn = "public_id"
+
+// backfillBatchSize is the number of rows BackfillPublicIDs reads per query.
+const backfillBatchSize = 500
+
+// ErrUUIDPrimaryKeys is returned by New for StrategyUUIDv7, whose IDs do not fit the
+// integer primary keys of the tables.
+var ErrUUIDPrimaryKeys = errors.New("uuidv7 IDs do not fit the integer primary keys; use them as public IDs")
+
+// Generator generates the primary keys of new rows.
+type Generator interface {
+	// NextID returns the ID of a new row.
+	NextID() uint
+}
+
+// New creates the Generator of primary keys of strategy, identifying the generating node
+// with node when the strategy needs it. StrategyAutoIncrement returns a nil Generator, as
+// the database assigns the IDs.
+func New(strategy Strategy, node uint) (Generator, error) {
+	switch strategy {
+	case StrategyAutoIncrement, "":
+		return nil, nil
+	case StrategySnowflake:
+		return NewSnowflake(node)
+	case StrategyUUIDv7:
+		return nil, ErrUUIDPrimaryKeys
+	}
+	return nil, fmt.Errorf("unknown ID strategy %q, expected autoincrement, snowflake or uuidv7", strategy)
+}
+
+// Plugin is a GORM plugin assigning generated IDs to the rows being created, unless they
+// already have one.
+type Plugin struct {
+	// Primary generates the integer primary keys; when nil, the database assigns them.
+	Primary Generator
+	// PublicID generates the public IDs of the tables having a public_id column; when nil,
+	// the column is left NULL.
+	PublicID func() string
+}
+
+// Name implements gorm.Plugin.
+func (Plugin) Name() string {
+	return "ids:generator"
+}
+
+// Initialize registers the callback assigning the IDs before rows are inserted.
+func (p Plugin) Initialize(db *gorm.DB) error {
+	if p.Primary == nil && p.PublicID == nil {
+		return nil
+	}
+	return db.Callback().Create().Before("gorm:create").Register("ids:assign", p.assign)
+}
+
+// assign sets the missing IDs of the rows of the statement, be it a single row or a batch.
+func (p Plugin) assign(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	primary := db.Statement.Schema.PrioritizedPrimaryField
+	if p.Primary == nil || primary == nil || primary.DataType != schema.Uint {
+		primary = nil
+	}
+	var public *schema.Field
+	if p.PublicID != nil {
+		public = db.Statement.Schema.LookUpField(publicIDColumn)
+	}
+	if primary == nil && public == nil {
+		return
+	}
+
+	ctx := db.Statement.Context
+	rows := reflect.Indirect(db.Statement.ReflectValue)
+	switch rows.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rows.Len(); i++ {
+			p.assignRow(ctx, db, reflect.Indirect(rows.Index(i)), primary, public)
+		}
+	case reflect.Struct:
+		p.assignRow(ctx, db, rows, primary, public)
+	}
+}
+
+// assignRow sets the missing primary key and public ID of row.
+func (p Plugin) assignRow(ctx context.Context, db *gorm.DB, row reflect.Value, primary, public *schema.Field) {
+	if primary != nil {
+		if _, zero := primary.ValueOf(ctx, row); zero {
+			if err := primary.Set(ctx, row, p.Primary.NextID()); err != nil {
+				db.AddError(err)
+			}
+		}
+	}
+	if public != nil {
+		if _, zero := public.ValueOf(ctx, row); zero {
+			if err := public.Set(ctx, row, p.PublicID()); err != nil {
+				db.AddError(err)
+			}
+		}
+	}
+}
+
+// BackfillPublicIDs gives the rows of model, including soft-deleted ones, that were created
+// before public IDs were generated a public ID from generate. It returns the number of
+// rows updated.
+func BackfillPublicIDs(ctx context.Context, db *gorm.DB, model interface{}, generate func() string) (int64, error) {
+	var updated int64
+	for {
+		var ids []uint
+		err := db.WithContext(ctx).Unscoped().Model(model).
+			Where(publicIDColumn+" IS NULL").
+			Order("id").
+			Limit(backfillBatchSize).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return updated, err
+		}
+
+		for _, id := range ids {
+			// Skip the rows given a public ID concurrently, such as by another replica.
+			result := db.WithContext(ctx).Unscoped().Model(model).
+				Where("id = ? AND "+publicIDColumn+" IS NULL", id).
+				UpdateColumn(publicIDColumn, generate())
+			if result.Error != nil {
+				return updated, result.Error
+			}
+			updated += result.RowsAffected
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package ids generates the primary keys and public IDs of new rows, according to the
+// strategy selected in the configuration.
+//
+// This file implements ID, the type of the IDs in API requests and responses, which is
+// sent as a JSON string when the IDs may exceed the integers JavaScript numbers represent
+// exactly.
+package ids
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// maxSafeInteger is 2^53 - 1, the largest integer JavaScript numbers represent exactly.
+const maxSafeInteger = 1<<53 - 1
+
+// jsonStrings makes IDs marshal as JSON strings.
+var jsonStrings atomic.Bool
+
+// SetJSONStrings makes IDs marshal as JSON strings, such as "7249184032980717568", instead
+// of numbers. It is enabled with StrategySnowflake, whose IDs exceed 2^53, so that clients
+// written in JavaScript do not silently round them.
+func SetJSONStrings(enabled bool) {
+	jsonStrings.Store(enabled)
+}
+
+// ID is the primary key of a row in API requests and responses. It marshals as a JSON
+// number, or as a string once SetJSONStrings is enabled, and unmarshals from either.
+type ID uint
+
+// MarshalJSON implements json.Marshaler.
+func (id ID) MarshalJSON() ([]byte, error) {
+	text := strconv.FormatUint(uint64(id), 10)
+	if jsonStrings.Load() {
+		return []byte(`"` + text + `"`), nil
+	}
+	return []byte(text), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Numbers above 2^53 are rejected, as clients
+// sending them as numbers may already have rounded them.
+func (id *ID) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	quoted := len(data) > 0 && data[0] == '"'
+	if quoted {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		data = []byte(text)
+	}
+
+	value, err := strconv.ParseUint(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid ID %s", data)
+	}
+	if !quoted && value > maxSafeInteger {
+		return fmt.Errorf("ID %d must be sent as a string", value)
+	}
+	*id = ID(value)
+	return nil
+}
+
+// IDOf returns a pointer to the ID of value, or nil when value is nil.
+func IDOf(value *uint) *ID {
+	if value == nil {
+		return nil
+	}
+	id := ID(*value)
+	return &id
+}
+
+// IDsOf converts values to IDs. The result is never nil, so that it marshals as an array.
+func IDsOf(values []uint) []ID {
+	result := make([]ID, len(values))
+	for i, value := range values {
+		result[i] = ID(value)
+	}
+	return result
+}
+
+// Uints converts ids back to integers.
+func Uints(ids []ID) []uint {
+	if ids == nil {
+		return nil
+	}
+	result := make([]uint, len(ids))
+	for i, id := range ids {
+		result[i] = uint(id)
+	}
+	return result
+}
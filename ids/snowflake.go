@@ -0,0 +1,75 @@
+// Package ids generates the primary keys and public IDs of new rows, according to the
+// strategy selected in the configuration.
+//
+// This file implements Snowflake IDs, which every node generates independently: a
+// millisecond timestamp comes first, so that IDs keep increasing over time, followed by
+// the ID of the node and a sequence number within the millisecond.
+package ids
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// snowflakeNodeBits and snowflakeSequenceBits are the sizes of the node ID and of the
+	// sequence number; the timestamp takes the remaining 41 bits of the 63.
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+
+	// MaxSnowflakeNode is the largest node ID.
+	MaxSnowflakeNode = 1<<snowflakeNodeBits - 1
+	// maxSnowflakeSequence is the largest sequence number within a millisecond.
+	maxSnowflakeSequence = 1<<snowflakeSequenceBits - 1
+)
+
+// snowflakeEpoch is the time the timestamps of Snowflake IDs count from, which lasts them
+// until 2093.
+var snowflakeEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake generates Snowflake IDs. It is safe for concurrent use.
+//
+// IDs exceed 2^53, the largest integer JavaScript numbers represent exactly, so the API
+// sends them as strings; see SetJSONStrings.
+type Snowflake struct {
+	node uint64
+
+	mu       sync.Mutex
+	last     int64
+	sequence uint64
+}
+
+// NewSnowflake creates a Snowflake generating IDs for node, which must be unique among the
+// replicas writing to the database, and at most MaxSnowflakeNode.
+func NewSnowflake(node uint) (*Snowflake, error) {
+	if node > MaxSnowflakeNode {
+		return nil, fmt.Errorf("snowflake node must be between 0 and %d", MaxSnowflakeNode)
+	}
+	return &Snowflake{node: uint64(node)}, nil
+}
+
+// NextID returns the next ID. When the sequence of the millisecond is exhausted, it waits
+// for the next one; when the clock moves backwards, it keeps counting from the last
+// millisecond, so that IDs never repeat nor decrease.
+func (s *Snowflake) NextID() uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Since(snowflakeEpoch).Milliseconds()
+	if now < s.last {
+		now = s.last
+	}
+	if now == s.last {
+		s.sequence = (s.sequence + 1) & maxSnowflakeSequence
+		for s.sequence == 0 && now <= s.last {
+			time.Sleep(100 * time.Microsecond)
+			now = time.Since(snowflakeEpoch).Milliseconds()
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.last = now
+
+	return uint(uint64(now)<<(snowflakeNodeBits+snowflakeSequenceBits) | s.node<<snowflakeSequenceBits | s.sequence)
+}
@@ -0,0 +1,22 @@
+// Package ids generates the primary keys and public IDs of new rows, according to the
+// strategy selected in the configuration.
+//
+// This file implements the UUIDv7 public IDs: their millisecond timestamp keeps them in
+// creation order in indexes, and their random bits make them impossible to guess.
+package ids
+
+import "github.com/google/uuid"
+
+// NewUUIDv7 returns a new UUIDv7 in its canonical text form.
+func NewUUIDv7() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// ValidUUID reports whether s is a UUID in its canonical text form.
+func ValidUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	parsed, err := uuid.Parse(s)
+	return err == nil && parsed.String() == s
+}
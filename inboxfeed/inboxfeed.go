@@ -0,0 +1,106 @@
+// Package inboxfeed broadcasts the changes of the admin inbox, so that the admin view
+// updates its unread counter and its list of contacts without polling.
+//
+// The inbox projection publishes an Update for every contact it saves or removes, with
+// the number of unread contacts after the change, and the subscribers, such as the
+// WebSocket connections of the admin view, receive it. Updates are kept in memory: with
+// several replicas, a connection only receives the changes made through the replica it is
+// connected to, as with the presence of agents.
+package inboxfeed
+
+import (
+	"api-contact-form/models"
+	"sync"
+)
+
+// subscriberBuffer is the number of updates a slow subscriber may lag behind before
+// updates are dropped for it.
+const subscriberBuffer = 64
+
+// Update is a change of the inbox.
+type Update struct {
+	// Event is the lifecycle event of the contact that changed the inbox.
+	Event models.WebhookEvent
+	// Entry is the inbox entry of the contact after the change.
+	Entry models.InboxEntry
+	// Removed is set when the contact left the inbox, such as when it was deleted.
+	Removed bool
+	// Unread is the number of unread contacts after the change.
+	Unread int64
+}
+
+// Feed broadcasts inbox updates to its subscribers. It is safe for concurrent use, and a
+// nil Feed has no subscribers.
+type Feed struct {
+	mu          sync.Mutex
+	subscribers map[chan Update]struct{}
+	closed      bool
+}
+
+// NewFeed creates a Feed without subscribers.
+func NewFeed() *Feed {
+	return &Feed{subscribers: make(map[chan Update]struct{})}
+}
+
+// Listening reports whether the Feed has subscribers, so that publishers skip the work
+// of building updates nobody receives.
+func (f *Feed) Listening() bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subscribers) > 0
+}
+
+// Publish sends update to the subscribers without blocking.
+func (f *Feed) Publish(update Update) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving every update, and a function to call once done
+// with it. Updates are dropped for subscribers that do not keep up. The channel is closed
+// when the Feed is closed.
+func (f *Feed) Subscribe() (<-chan Update, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan Update, subscriberBuffer)
+	if f.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	f.subscribers[ch] = struct{}{}
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subscribers[ch]; ok {
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close closes the channels of the subscribers, so that long-lived connections end when
+// the server shuts down.
+func (f *Feed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	for ch := range f.subscribers {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,97 @@
+// Package benchdata provides the reproducible dataset and database setup shared by
+// the benchmarks of the data layer.
+//
+// Database benchmarks run against the Postgres database named by BENCH_DATABASE_DSN
+// and are skipped when it is not set. The database is emptied by every benchmark,
+// so never point it at real data.
+package benchdata
+
+import (
+	"api-contact-form/models"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// Seed is the seed of the benchmark dataset. Changing it changes every benchmark baseline.
+const Seed = 42
+
+// channels is the channel mix of the dataset: mostly web, some email and API.
+var channels = []models.Channel{
+	models.ChannelWeb, models.ChannelWeb, models.ChannelWeb, models.ChannelWeb,
+	models.ChannelEmail, models.ChannelAPI,
+}
+
+// words build the message bodies of the dataset.
+var words = strings.Fields("hello we would like a quote for your services please call back regarding the invoice thanks")
+
+// Contacts generates n contacts deterministically from Seed.
+func Contacts(n int) []models.Contact {
+	r := rand.New(rand.NewSource(Seed))
+	contacts := make([]models.Contact, n)
+	for i := range contacts {
+		var message strings.Builder
+		for j := 20 + r.Intn(200); j > 0; j-- {
+			message.WriteString(words[r.Intn(len(words))])
+			message.WriteString(" ")
+		}
+
+		contacts[i] = models.Contact{
+			FullName: fmt.Sprintf("Bench Contact %d", i),
+			Email:    fmt.Sprintf("bench%d@example.com", i),
+			Phone:    fmt.Sprintf("+6281%08d", r.Intn(100000000)),
+			Message:  strings.TrimSpace(message.String()),
+			Channel:  channels[r.Intn(len(channels))],
+		}
+	}
+	return contacts
+}
+
+// OpenDB connects to the database named by BENCH_DATABASE_DSN, migrates it and
+// empties the contact table. The benchmark is skipped when the variable is not set.
+func OpenDB(b *testing.B) *gorm.DB {
+	b.Helper()
+
+	dsn := os.Getenv("BENCH_DATABASE_DSN")
+	if dsn == "" {
+		b.Skip("BENCH_DATABASE_DSN not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		b.Fatalf("connect: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Contact{}, &models.RejectedSubmission{}, &models.APIKey{}, &models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.AdminUser{}, &models.AdminRecoveryCode{}, &models.AdminSession{}, &models.AdminLoginEvent{}, &models.Attachment{}, &models.IdempotencyKey{}, &models.AuditLog{}, &models.ReplyDraft{}, &models.APIUsage{}, &models.WebhookOutboxEvent{}, &models.InboxEntry{}, &models.AutoReplyTemplate{}, &models.ExportSchedule{}, &models.SeenCredential{}, &models.AbuseReport{}, &models.FollowUp{}, &models.SystemChange{}, &models.ContactStar{}, &models.EmailMessage{}); err != nil {
+		b.Fatalf("migrate: %v", err)
+	}
+	if err := db.Exec("TRUNCATE TABLE " + models.Contact{}.TableName() + " RESTART IDENTITY").Error; err != nil {
+		b.Fatalf("truncate: %v", err)
+	}
+
+	b.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// Load inserts the first n contacts of the dataset into db.
+func Load(b *testing.B, db *gorm.DB, n int) {
+	b.Helper()
+
+	contacts := Contacts(n)
+	if err := db.CreateInBatches(&contacts, 500).Error; err != nil {
+		b.Fatalf("load dataset: %v", err)
+	}
+}
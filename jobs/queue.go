@@ -0,0 +1,96 @@
+// Package jobs provides the queue of the background workers of the API, such as those
+// sending the notifications and delivering the webhooks, which keeps track of its health.
+//
+// A Queue reports the jobs waiting in it, the age of the oldest of them and the jobs its
+// workers failed, so that a stuck pipeline shows in the metrics and the health endpoint
+// rather than only in the logs.
+package jobs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats describes the health of a Queue at a point in time.
+type Stats struct {
+	// Name identifies the queue, such as "notifications".
+	Name string
+	// Depth is the number of jobs waiting in the queue.
+	Depth int
+	// Capacity is the number of jobs the queue holds before dropping new ones.
+	Capacity int
+	// OldestPending is the time the oldest waiting job has been queued for, or zero.
+	OldestPending time.Duration
+	// Failures is the number of jobs the workers failed since the start.
+	Failures uint64
+}
+
+// Monitor is implemented by the components processing a Queue in the background.
+type Monitor interface {
+	// QueueStats returns the health of the queue.
+	QueueStats() Stats
+}
+
+// Queue is a bounded FIFO queue of jobs of type T, taken by background workers.
+type Queue[T any] struct {
+	name  string
+	items chan T
+
+	// queuedAt holds the times the waiting jobs were queued, oldest first. It is updated
+	// while holding mu together with items, so that both keep the same order.
+	mu       sync.Mutex
+	queuedAt []time.Time
+	failures atomic.Uint64
+}
+
+// NewQueue creates a Queue identified by name holding at most size jobs.
+func NewQueue[T any](name string, size int) *Queue[T] {
+	return &Queue[T]{name: name, items: make(chan T, size)}
+}
+
+// Push queues job without blocking, and reports whether it was queued: it is not when the
+// queue is full.
+func (q *Queue[T]) Push(job T) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case q.items <- job:
+		q.queuedAt = append(q.queuedAt, time.Now())
+		return true
+	default:
+		return false
+	}
+}
+
+// Next waits for the next job and returns it, or returns false once ctx is cancelled.
+func (q *Queue[T]) Next(ctx context.Context) (T, bool) {
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+	case job := <-q.items:
+		q.mu.Lock()
+		q.queuedAt = q.queuedAt[1:]
+		q.mu.Unlock()
+		return job, true
+	}
+}
+
+// Failed records that a worker failed a job, after its retries if any.
+func (q *Queue[T]) Failed() {
+	q.failures.Add(1)
+}
+
+// Stats returns the health of the queue.
+func (q *Queue[T]) Stats() Stats {
+	stats := Stats{Name: q.name, Capacity: cap(q.items), Failures: q.failures.Load()}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats.Depth = len(q.queuedAt)
+	if stats.Depth > 0 {
+		stats.OldestPending = time.Since(q.queuedAt[0])
+	}
+	return stats
+}
@@ -0,0 +1,99 @@
+package jobs_test
+
+import (
+	"api-contact-form/jobs"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	queue := jobs.NewQueue[int]("test", 2)
+	if stats := queue.Stats(); stats != (jobs.Stats{Name: "test", Capacity: 2}) {
+		t.Fatalf("Stats() of an empty queue = %+v", stats)
+	}
+
+	if !queue.Push(1) || !queue.Push(2) {
+		t.Fatal("Push() = false, want true while the queue has room")
+	}
+	if queue.Push(3) {
+		t.Fatal("Push() = true, want false once the queue is full")
+	}
+	time.Sleep(20 * time.Millisecond)
+	stats := queue.Stats()
+	if stats.Depth != 2 || stats.OldestPending < 20*time.Millisecond {
+		t.Errorf("Stats() = %+v, want a depth of 2 and the oldest job pending for 20ms", stats)
+	}
+
+	// The jobs come out in order, and the age follows the oldest job left
+	if job, ok := queue.Next(context.Background()); !ok || job != 1 {
+		t.Fatalf("Next() = %d, %v, want 1, true", job, ok)
+	}
+	pushed := time.Now()
+	queue.Push(4)
+	if job, ok := queue.Next(context.Background()); !ok || job != 2 {
+		t.Fatalf("Next() = %d, %v, want 2, true", job, ok)
+	}
+	stats = queue.Stats()
+	if stats.Depth != 1 || stats.OldestPending > time.Since(pushed) {
+		t.Errorf("Stats() = %+v, want a depth of 1 and the age of the last job", stats)
+	}
+	if job, ok := queue.Next(context.Background()); !ok || job != 4 {
+		t.Fatalf("Next() = %d, %v, want 4, true", job, ok)
+	}
+	if stats := queue.Stats(); stats.Depth != 0 || stats.OldestPending != 0 {
+		t.Errorf("Stats() of a drained queue = %+v", stats)
+	}
+
+	queue.Failed()
+	queue.Failed()
+	if stats := queue.Stats(); stats.Failures != 2 {
+		t.Errorf("Failures = %d, want 2", stats.Failures)
+	}
+}
+
+func TestQueueNextStopsWithContext(t *testing.T) {
+	queue := jobs.NewQueue[int]("test", 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ok := queue.Next(ctx); ok {
+		t.Error("Next() = true, want false once the context is cancelled")
+	}
+}
+
+func TestQueueConcurrentWorkers(t *testing.T) {
+	queue := jobs.NewQueue[int]("test", 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	taken := make(chan int, 1000)
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				job, ok := queue.Next(ctx)
+				if !ok {
+					return
+				}
+				taken <- job
+			}
+		}()
+	}
+	for i := range 1000 {
+		for !queue.Push(i) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for range 1000 {
+		<-taken
+	}
+	cancel()
+	wg.Wait()
+
+	if stats := queue.Stats(); stats.Depth != 0 || stats.OldestPending != 0 {
+		t.Errorf("Stats() once every job was taken = %+v", stats)
+	}
+}
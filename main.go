@@ -1,31 +1,38 @@
 // Package main serves as the entry point for the API Contact Form application.
 //
 // It initializes the necessary configurations, sets up the database connection,
-// configures repositories, services, and handlers, and starts the HTTP server
-// using the Gin framework.
+// wires the API with package server, and starts the HTTP server.
 
 package main
 
 import (
 	"api-contact-form/config"
-	"api-contact-form/handlers"
 	"api-contact-form/helpers"
-	"api-contact-form/repositories"
-	"api-contact-form/services"
+	"api-contact-form/observability"
+	"api-contact-form/region"
+	"api-contact-form/server"
+	"api-contact-form/startup"
+	"context"
+	"errors"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
 // main is the entry point of the application.
 // It performs the following steps:
 // 1. Loads environment variables from the .env file.
-// 2. Initializes the database connection.
-// 3. Sets up repositories, services, and handlers.
-// 4. Configures the Gin router with necessary middleware and routes.
-// 5. Starts the HTTP server on the specified port.
+// 2. Starts the HTTP server on the specified port, answering the probes while starting.
+// 3. Initializes the database connection.
+// 4. Wires the repositories, services, handlers and routes of the API, and serves them.
+// 5. Opens the idle database connections and preloads the response cache before the first requests.
+// 6. Shuts down gracefully on SIGINT or SIGTERM, draining in-flight requests.
 func main() {
 	// Load environment variables from the .env file.
 	err := godotenv.Load()
@@ -33,46 +40,110 @@ func main() {
 		log.Println("Error loading .env file")
 	}
 
-	// Initialize the database connection.
-	config.InitDB()
-
-	// Initialize repositories, services, and handlers.
-	mainHandler := handlers.NewMainHandler()
-	healthHandler := handlers.NewHealthHandler()
-	contactRepository := repositories.NewContactRepository(config.DB)
-	contactService := services.NewContactService(contactRepository)
-	contactHandler := handlers.NewContactHandler(contactService)
-
-	// Create a new Gin router with default middleware (logger and recovery).
-	router := gin.Default()
-
-	// Configure CORS (Cross-Origin Resource Sharing) settings.
-	corsConfig := cors.Config{
-		AllowOrigins:     helpers.ParseEnvList("CORS_ALLOWED_ORIGINS"),
-		AllowMethods:     helpers.ParseEnvList("CORS_ALLOWED_METHODS"),
-		AllowHeaders:     helpers.ParseEnvList("CORS_ALLOWED_HEADERS"),
-		AllowCredentials: helpers.GetEnvBool("CORS_ALLOW_CREDENTIALS", false),
-		ExposeHeaders:    helpers.ParseEnvList("CORS_EXPOSE_HEADERS"),
-		MaxAge:           12 * 60 * 60, // 12 hours
+	// Write structured JSON logs, including those of the standard logger, with the trace of
+	// the request they were written for.
+	logger := newLogger(config.GetEnv("LOG_FORMAT", "json"))
+	slog.SetDefault(logger)
+
+	// Retrieve the application port from environment variables with a default value of "8080".
+	appPort := config.GetEnv("APP_PORT", "8080")
+
+	// Start the HTTP server on the specified port right away, so that the probes are answered
+	// while the server initializes. Requests are routed to the application once it started.
+	starting := startup.NewTracker("migrations", "scheduler", "warmup")
+	httpServer := &http.Server{Addr: ":" + appPort, Handler: starting}
+	go func() {
+		log.Printf("Listening and serving HTTP on %s", httpServer.Addr)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to run the server: %v", err)
+		}
+	}()
+
+	// Initialize the database connection, waiting for the database to come up. Standby
+	// regions run against a read-only replica, whose schema is migrated by the primary.
+	dbConfig := config.LoadConfig()
+	regionConfig := region.Config{
+		Name:               config.GetEnv("REGION_NAME", ""),
+		Role:               region.Role(config.GetEnv("REGION_ROLE", string(region.RolePrimary))),
+		MaxReplicationLag:  helpers.GetEnvDuration("REGION_MAX_REPLICATION_LAG", 30*time.Second),
+		PromoteDatabase:    helpers.GetEnvBool("REGION_PROMOTE_DATABASE", false),
+		PromoteHookURL:     config.GetEnv("REGION_PROMOTE_HOOK_URL", ""),
+		PromoteHookTimeout: helpers.GetEnvDuration("REGION_PROMOTE_HOOK_TIMEOUT", 10*time.Second),
+	}
+	if regionConfig.Role == region.RoleStandby && dbConfig.AutoMigrate {
+		log.Println("REGION_ROLE is standby; the schema is not migrated")
+		dbConfig.AutoMigrate = false
+	}
+	db, err := config.New(dbConfig)
+	if err != nil {
+		log.Fatalf("Failed to connect to the database: %v", err)
+	}
+	starting.Done("migrations")
+	if err := db.Use(observability.DBMetrics{}); err != nil {
+		log.Fatalf("Failed to register the database metrics: %v", err)
 	}
 
-	// Apply the CORS middleware to the router.
-	router.Use(cors.New(corsConfig))
+	// Refuse every write while the region is a standby, until it is promoted.
+	currentRegion, err := region.New(db, regionConfig)
+	if err != nil {
+		log.Fatalf("Invalid region settings: %v", err)
+	}
+	if err := db.Use(region.ReadOnly{Region: currentRegion}); err != nil {
+		log.Fatalf("Failed to register the standby write guard: %v", err)
+	}
 
-	// Define application routes and associate them with their respective handlers.
-	router.GET("/", mainHandler.MainHandler)
-	router.GET("/health", healthHandler.HealthCheck)
-	router.GET("/contacts", contactHandler.GetContacts)
-	router.GET("/contacts/:id", contactHandler.GetContact)
-	router.POST("/contacts", contactHandler.CreateContact)
-	router.PUT("/contacts/:id", contactHandler.UpdateContact)
-	router.DELETE("/contacts/:id", contactHandler.DeleteContact)
+	// Wire the API, and stop its background workers once the server has shut down.
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	api, err := server.New(workers, db, server.Config{Region: currentRegion, Startup: starting, Logger: logger})
+	if err != nil {
+		log.Fatalf("Failed to configure the API: %v", err)
+	}
 
-	// Retrieve the application port from environment variables with a default value of "8080".
-	appPort := config.GetEnv("APP_PORT", "8080")
+	// Open the idle database connections and preload the response cache before the first
+	// requests, then serve them.
+	warmup, cancelWarmup := context.WithTimeout(context.Background(), helpers.GetEnvDuration("STARTUP_WARMUP_TIMEOUT", 30*time.Second))
+	if err := config.Warmup(warmup, db, dbConfig.MaxIdleConns); err != nil {
+		log.Printf("Database warmup failed: %v", err)
+	} else {
+		starting.Done("warmup")
+	}
+	api.WarmCache(warmup)
+	cancelWarmup()
+	// End the presence streams and inbox feeds on shutdown, as they would otherwise never drain.
+	httpServer.RegisterOnShutdown(api.Close)
+	starting.Serve(api.Handler)
+
+	// Wait for an interrupt, then drain the in-flight requests before stopping.
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	<-signals.Done()
+	log.Println("Shutting down, draining in-flight requests")
+
+	// Report unready first, and give load balancers time to notice before closing the listener.
+	api.Drain()
+	time.Sleep(helpers.GetEnvDuration("SHUTDOWN_DRAIN_DELAY", 0))
+
+	shutdown, cancel := context.WithTimeout(context.Background(), helpers.GetEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second))
+	defer cancel()
+	if err := httpServer.Shutdown(shutdown); err != nil {
+		log.Printf("Server shutdown incomplete: %v", err)
+	}
+
+	// Stop the background workers and close the database once nothing uses it anymore.
+	stopWorkers()
+	api.Wait()
+	if err := config.Close(db); err != nil {
+		log.Printf("Closing the database failed: %v", err)
+	}
+}
 
-	// Start the HTTP server on the specified port.
-	if err := router.Run(":" + appPort); err != nil {
-		log.Fatalf("Failed to run the server: %v", err)
+// newLogger returns the logger of the application, writing JSON, or logfmt-style text when
+// format is "text", to the standard error. Records logged with the context of a request
+// carry its trace_id and span_id.
+func newLogger(format string) *slog.Logger {
+	if format == "text" {
+		return slog.New(observability.NewTraceHandler(slog.NewTextHandler(os.Stderr, nil)))
 	}
-}
\ No newline at end of file
+	return slog.New(observability.NewTraceHandler(slog.NewJSONHandler(os.Stderr, nil)))
+}
@@ -0,0 +1,149 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the APIUsageTracker, which counts the requests and errors per
+// caller and endpoint into hourly rollups.
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/router"
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The callers and routes of requests without an identity or a matching route.
+const (
+	AnonymousCaller = "anonymous"
+	UnmatchedRoute  = "unmatched"
+)
+
+// usageKey identifies an hourly rollup.
+type usageKey struct {
+	hour   time.Time
+	caller string
+	method string
+	route  string
+}
+
+// APIUsageTracker counts requests in memory and adds the counts to the stored hourly
+// rollups every interval, so that counting costs no database write per request. Counts
+// not yet stored are lost when the process crashes. It is safe for concurrent use.
+type APIUsageTracker struct {
+	repository repositories.APIUsageRepository
+	interval   time.Duration
+
+	mu     sync.Mutex
+	counts map[usageKey]*models.APIUsage
+	done   chan struct{}
+}
+
+// NewAPIUsageTracker creates an APIUsageTracker storing its counts every interval.
+func NewAPIUsageTracker(repository repositories.APIUsageRepository, interval time.Duration) *APIUsageTracker {
+	return &APIUsageTracker{
+		repository: repository,
+		interval:   interval,
+		counts:     make(map[usageKey]*models.APIUsage),
+		done:       make(chan struct{}),
+	}
+}
+
+// Middleware counts every request once it is handled, under the caller identified by
+// the authentication middleware, which may run after it.
+func (t *APIUsageTracker) Middleware() router.HandlerFunc {
+	return func(c *router.Context) {
+		c.Next()
+
+		key := usageKey{
+			hour:   time.Now().UTC().Truncate(time.Hour),
+			caller: AnonymousCaller,
+			method: c.Request.Method,
+			route:  c.FullPath(),
+		}
+		if identity := CurrentIdentity(c); identity != nil {
+			key.caller = identity.Subject
+		}
+		if key.route == "" {
+			key.route = UnmatchedRoute
+		}
+		status := c.Writer.Status()
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		usage := t.counts[key]
+		if usage == nil {
+			usage = &models.APIUsage{Hour: key.hour, Caller: key.caller, Method: key.method, Route: key.route}
+			t.counts[key] = usage
+		}
+		usage.Requests++
+		switch {
+		case status >= http.StatusInternalServerError:
+			usage.ServerErrors++
+		case status >= http.StatusBadRequest:
+			usage.ClientErrors++
+		}
+	}
+}
+
+// Run stores the counts every interval until ctx is cancelled, then stores the last ones.
+func (t *APIUsageTracker) Run(ctx context.Context) {
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.flush(context.Background())
+			return
+		case <-ticker.C:
+			t.flush(ctx)
+		}
+	}
+}
+
+// Wait blocks until Run has stored the last counts.
+func (t *APIUsageTracker) Wait() {
+	<-t.done
+}
+
+// flush stores the counts taken since the previous flush. On failure, they are kept for
+// the next one.
+func (t *APIUsageTracker) flush(ctx context.Context) {
+	t.mu.Lock()
+	counts := t.counts
+	t.counts = make(map[usageKey]*models.APIUsage)
+	t.mu.Unlock()
+
+	if len(counts) == 0 {
+		return
+	}
+	usage := make([]models.APIUsage, 0, len(counts))
+	for _, count := range counts {
+		usage = append(usage, *count)
+	}
+	if err := t.repository.Add(ctx, usage); err != nil {
+		log.Printf("API usage not recorded: %v", err)
+		t.restore(counts)
+	}
+}
+
+// restore adds counts that could not be stored back to the current ones.
+func (t *APIUsageTracker) restore(counts map[usageKey]*models.APIUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, count := range counts {
+		usage := t.counts[key]
+		if usage == nil {
+			t.counts[key] = count
+			continue
+		}
+		usage.Requests += count.Requests
+		usage.ClientErrors += count.ClientErrors
+		usage.ServerErrors += count.ServerErrors
+	}
+}
@@ -0,0 +1,278 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the Authenticator, which accepts API keys and JWT bearer tokens,
+// and RequireRole, which restricts routes to the given roles.
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// identityKey is the router context key of the authenticated Identity.
+const identityKey = "identity"
+
+// userSubjectPrefix starts the subject of the tokens issued to admin users, followed by their ID.
+const userSubjectPrefix = "user:"
+
+// ErrTokensDisabled is returned by IssueToken when no JWT secret is configured.
+var ErrTokensDisabled = errors.New("JWT tokens are not enabled")
+
+// Identity describes the authenticated caller of a request.
+type Identity struct {
+	// Subject names the caller: "key:<id>" for stored keys, "env" for keys configured
+	// in the environment, or the subject of a JWT, "user:<id>" for admin users.
+	Subject string
+	// Role is the role granted to the caller.
+	Role models.Role
+	// KeyID is the ID of the stored API key used, if any.
+	KeyID uint
+	// FromToken reports whether the caller authenticated with a JWT rather than an API key.
+	FromToken bool
+	// UserID is the ID of the admin user the token was issued to, if any.
+	UserID uint
+	// SessionID is the ID of the session of the admin user the token was issued in, if any.
+	SessionID uint
+}
+
+// tokenClaims are the claims of the JWTs accepted and issued by the Authenticator.
+type tokenClaims struct {
+	Role models.Role `json:"role"`
+	// SessionID is the session of the admin user the token was issued in.
+	SessionID uint `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Authenticator verifies the credentials of requests.
+//
+// Credentials are read from the X-API-Key header or an "Authorization: Bearer" header
+// and may be an API key stored in the database, an admin key configured in the environment,
+// or an HS256 JWT carrying a "role" claim when a JWT secret is configured.
+type Authenticator struct {
+	keys       services.APIKeyService
+	users      services.AdminUserService
+	staticKeys map[string]bool
+	jwtSecret  []byte
+	tokenTTL   time.Duration
+}
+
+// NewAuthenticator creates an Authenticator backed by the stored keys of keys and the
+// admin users of users, whose tokens are rejected once the user is disabled or their
+// session revoked.
+// adminKeys are accepted with the admin role in addition to the stored keys; they are meant
+// for bootstrapping the first stored key. An empty jwtSecret disables JWT bearer tokens;
+// tokens issued with IssueToken are valid for tokenTTL.
+func NewAuthenticator(keys services.APIKeyService, users services.AdminUserService, adminKeys []string, jwtSecret []byte, tokenTTL time.Duration) *Authenticator {
+	staticKeys := make(map[string]bool, len(adminKeys))
+	for _, key := range adminKeys {
+		staticKeys[services.HashAPIKey(key)] = true
+	}
+	return &Authenticator{
+		keys:       keys,
+		users:      users,
+		staticKeys: staticKeys,
+		jwtSecret:  jwtSecret,
+		tokenTTL:   tokenTTL,
+	}
+}
+
+// Middleware authenticates the credentials of the request, if any, and stores the
+// Identity of the caller in the context. Requests without credentials continue
+// anonymously; requests with invalid credentials are rejected with a 401 status code.
+func (a *Authenticator) Middleware() router.HandlerFunc {
+	return func(c *router.Context) {
+		credential, bearer := credentials(c)
+		if credential == "" {
+			c.Next()
+			return
+		}
+
+		identity, err := a.authenticate(credential, bearer)
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, responses.APIResponse{
+				Code:    "UNAUTHORIZED",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, responses.APIResponse{
+				Code:    "INTERNAL_SERVER_ERROR",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		c.Set(identityKey, identity)
+		c.Next()
+	}
+}
+
+// TokensEnabled reports whether a JWT secret is configured, so that IssueToken succeeds.
+func (a *Authenticator) TokensEnabled() bool {
+	return len(a.jwtSecret) > 0
+}
+
+// IssueToken signs a JWT for identity that expires after the configured token lifetime.
+func (a *Authenticator) IssueToken(identity *Identity) (string, time.Time, error) {
+	if !a.TokensEnabled() {
+		return "", time.Time{}, ErrTokensDisabled
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(a.tokenTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenClaims{
+		Role:      identity.Role,
+		SessionID: identity.SessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   identity.Subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	signed, err := token.SignedString(a.jwtSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// authenticate resolves a credential to the Identity it grants.
+// JWTs are only accepted as bearer tokens, and recognized by their three dot-separated parts.
+func (a *Authenticator) authenticate(credential string, bearer bool) (*Identity, error) {
+	if a.staticKeys[services.HashAPIKey(credential)] {
+		return &Identity{Subject: "env", Role: models.RoleAdmin}, nil
+	}
+
+	if bearer && len(a.jwtSecret) > 0 && strings.Count(credential, ".") == 2 {
+		return a.parseToken(credential)
+	}
+
+	key, err := a.keys.Authenticate(credential)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{Subject: fmt.Sprintf("key:%d", key.ID), Role: key.Role, KeyID: key.ID}, nil
+}
+
+// parseToken verifies the signature, expiry and role of a JWT, and that the admin user
+// it was issued to, if any, is still enabled and signed in.
+func (a *Authenticator) parseToken(credential string) (*Identity, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(credential, &claims,
+		func(*jwt.Token) (any, error) { return a.jwtSecret, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !claims.Role.Valid() {
+		return nil, services.ErrInvalidAPIKey
+	}
+
+	identity := &Identity{Subject: claims.Subject, Role: claims.Role, FromToken: true}
+	if userID, ok := strings.CutPrefix(claims.Subject, userSubjectPrefix); ok && a.users != nil {
+		id, err := strconv.ParseUint(userID, 10, 0)
+		if err != nil {
+			return nil, services.ErrInvalidAPIKey
+		}
+		active, err := a.users.Active(uint(id), claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, services.ErrInvalidAPIKey
+		}
+		identity.UserID = uint(id)
+		identity.SessionID = claims.SessionID
+	}
+	return identity, nil
+}
+
+// credentials returns the credential presented by the request and whether it was a bearer token.
+// WebSocket handshakes may pass a JWT in the "access_token" query parameter instead, as
+// browsers cannot set their headers; other credentials are not accepted there, so that API
+// keys do not end up in URLs.
+func credentials(c *router.Context) (string, bool) {
+	if key := strings.TrimSpace(c.GetHeader("X-API-Key")); key != "" {
+		return key, false
+	}
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token), true
+	}
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		if token := c.Query("access_token"); strings.Count(token, ".") == 2 {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// CurrentIdentity returns the authenticated caller of the request, or nil for anonymous requests.
+func CurrentIdentity(c *router.Context) *Identity {
+	value, ok := c.Get(identityKey)
+	if !ok {
+		return nil
+	}
+	identity, _ := value.(*Identity)
+	return identity
+}
+
+// SetIdentity records identity as the authenticated caller of the request, for the
+// applications authenticating callers themselves.
+func SetIdentity(c *router.Context, identity *Identity) {
+	c.Set(identityKey, identity)
+}
+
+// RequireRole rejects anonymous requests with a 401 status code and requests of
+// callers without the given role with a 403 status code.
+func RequireRole(roles ...models.Role) router.HandlerFunc {
+	return func(c *router.Context) {
+		identity := CurrentIdentity(c)
+		if identity == nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, responses.APIResponse{
+				Code:    "UNAUTHORIZED",
+				Message: "Authentication required",
+				Data:    nil,
+			})
+			return
+		}
+		if !slices.Contains(roles, identity.Role) {
+			names := make([]string, len(roles))
+			for i, role := range roles {
+				names[i] = string(role)
+			}
+			c.AbortWithStatusJSON(http.StatusForbidden, responses.APIResponse{
+				Code:    "FORBIDDEN",
+				Message: fmt.Sprintf("The %s role is required", strings.Join(names, " or ")),
+				Data:    nil,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// UserIdentity returns the Identity of an admin user signed in with session, for IssueToken.
+func UserIdentity(user *models.AdminUser, session *models.AdminSession) *Identity {
+	return &Identity{
+		Subject:   fmt.Sprintf("%s%d", userSubjectPrefix, user.ID),
+		Role:      models.RoleAdmin,
+		UserID:    user.ID,
+		SessionID: session.ID,
+	}
+}
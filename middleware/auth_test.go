@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/router"
+	"api-contact-form/router/nethttp"
+	"api-contact-form/services"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// jwtSecret signs the tokens of the tests.
+var jwtSecret = []byte("test-secret")
+
+// openDB opens an empty SQLite database with the tables of models.
+func openDB(t *testing.T, models ...any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "middleware.db")), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// sessions is an AdminUserService whose Active reports the sessions it holds as active.
+type sessions struct {
+	services.AdminUserService
+	active map[uint]bool
+	err    error
+}
+
+func (s *sessions) Active(id, sessionID uint) (bool, error) {
+	return s.active[sessionID], s.err
+}
+
+// signToken signs claims with secret.
+func signToken(t *testing.T, secret []byte, claims tokenClaims) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed
+}
+
+// tokenFor returns the claims of a token of subject with role, expiring after ttl.
+func tokenFor(subject string, role models.Role, sessionID uint, ttl time.Duration) tokenClaims {
+	return tokenClaims{
+		Role:      role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+	}
+}
+
+func TestAuthenticator(t *testing.T) {
+	keys := services.NewAPIKeyService(repositories.NewAPIKeyRepository(openDB(t, &models.APIKey{})))
+	_, adminKey, err := keys.IssueKey("admin", models.RoleAdmin, nil)
+	if err != nil {
+		t.Fatalf("IssueKey: %v", err)
+	}
+	_, viewerKey, err := keys.IssueKey("viewer", models.RoleViewer, nil)
+	if err != nil {
+		t.Fatalf("IssueKey: %v", err)
+	}
+	revoked, revokedKey, err := keys.IssueKey("revoked", models.RoleAdmin, nil)
+	if err != nil {
+		t.Fatalf("IssueKey: %v", err)
+	}
+	if _, err := keys.RevokeKey(revoked.ID); err != nil {
+		t.Fatalf("RevokeKey: %v", err)
+	}
+	past := time.Now().Add(-time.Second)
+	_, expiredKey, err := keys.IssueKey("expired", models.RoleAdmin, &past)
+	if err != nil {
+		t.Fatalf("IssueKey: %v", err)
+	}
+
+	users := &sessions{active: map[uint]bool{1: true}}
+	auth := NewAuthenticator(keys, users, []string{"env-key"}, jwtSecret, time.Hour)
+	issued, _, err := auth.IssueToken(UserIdentity(&models.AdminUser{ID: 7}, &models.AdminSession{ID: 1}))
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+
+	engine := router.New(nethttp.New())
+	engine.Use(auth.Middleware())
+	engine.GET("/public", func(c *router.Context) {
+		subject := "anonymous"
+		if identity := CurrentIdentity(c); identity != nil {
+			subject = identity.Subject
+		}
+		c.Data(http.StatusOK, "text/plain", []byte(subject))
+	})
+	engine.GET("/admin", RequireRole(models.RoleAdmin), func(c *router.Context) {
+		c.Data(http.StatusOK, "text/plain", []byte(CurrentIdentity(c).Subject))
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		status int
+		body   string
+	}{
+		{"anonymous public request", "/public", nil, http.StatusOK, "anonymous"},
+		{"anonymous admin request", "/admin", nil, http.StatusUnauthorized, ""},
+		{"environment key", "/admin", http.Header{"X-Api-Key": {"env-key"}}, http.StatusOK, "env"},
+		{"stored key", "/admin", http.Header{"X-Api-Key": {adminKey}}, http.StatusOK, ""},
+		{"stored key as bearer token", "/admin", http.Header{"Authorization": {"Bearer " + adminKey}}, http.StatusOK, ""},
+		{"key without the role", "/admin", http.Header{"X-Api-Key": {viewerKey}}, http.StatusForbidden, ""},
+		{"key without the role on a public route", "/public", http.Header{"X-Api-Key": {viewerKey}}, http.StatusOK, ""},
+		{"revoked key", "/public", http.Header{"X-Api-Key": {revokedKey}}, http.StatusUnauthorized, ""},
+		{"expired key", "/public", http.Header{"X-Api-Key": {expiredKey}}, http.StatusUnauthorized, ""},
+		{"unknown key", "/public", http.Header{"X-Api-Key": {"acf_unknown"}}, http.StatusUnauthorized, ""},
+		{"token of an active session", "/admin", http.Header{"Authorization": {"Bearer " + issued}}, http.StatusOK, "user:7"},
+		{"token of a revoked session", "/admin", http.Header{"Authorization": {"Bearer " +
+			signToken(t, jwtSecret, tokenFor("user:7", models.RoleAdmin, 2, time.Hour))}}, http.StatusUnauthorized, ""},
+		{"service token", "/admin", http.Header{"Authorization": {"Bearer " +
+			signToken(t, jwtSecret, tokenFor("ci", models.RoleAdmin, 0, time.Hour))}}, http.StatusOK, "ci"},
+		{"token with a viewer role", "/admin", http.Header{"Authorization": {"Bearer " +
+			signToken(t, jwtSecret, tokenFor("ci", models.RoleViewer, 0, time.Hour))}}, http.StatusForbidden, ""},
+		{"expired token", "/public", http.Header{"Authorization": {"Bearer " +
+			signToken(t, jwtSecret, tokenFor("ci", models.RoleAdmin, 0, -time.Minute))}}, http.StatusUnauthorized, ""},
+		{"token signed with another secret", "/public", http.Header{"Authorization": {"Bearer " +
+			signToken(t, []byte("other-secret"), tokenFor("ci", models.RoleAdmin, 0, time.Hour))}}, http.StatusUnauthorized, ""},
+		{"token with an unknown role", "/public", http.Header{"Authorization": {"Bearer " +
+			signToken(t, jwtSecret, tokenFor("ci", models.Role("root"), 0, time.Hour))}}, http.StatusUnauthorized, ""},
+		{"token without expiry", "/public", http.Header{"Authorization": {"Bearer " +
+			signToken(t, jwtSecret, tokenClaims{Role: models.RoleAdmin, RegisteredClaims: jwt.RegisteredClaims{Subject: "ci"}})}}, http.StatusUnauthorized, ""},
+		{"token in the query of a WebSocket handshake", "/admin?access_token=" + issued, http.Header{"Upgrade": {"websocket"}}, http.StatusOK, "user:7"},
+		{"key in the query of a WebSocket handshake", "/admin?access_token=" + adminKey, http.Header{"Upgrade": {"websocket"}}, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for key, values := range tt.header {
+				r.Header[key] = values
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", w.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorSessionCheckError(t *testing.T) {
+	users := &sessions{err: errors.New("database is down")}
+	auth := NewAuthenticator(nil, users, nil, jwtSecret, time.Hour)
+	engine := router.New(nethttp.New())
+	engine.Use(auth.Middleware())
+	engine.GET("/", func(c *router.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+signToken(t, jwtSecret, tokenFor("user:7", models.RoleAdmin, 1, time.Hour)))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestIssueTokenWithoutSecret(t *testing.T) {
+	auth := NewAuthenticator(nil, nil, nil, nil, time.Hour)
+	if _, _, err := auth.IssueToken(&Identity{Subject: "ci", Role: models.RoleAdmin}); !errors.Is(err, ErrTokensDisabled) {
+		t.Errorf("IssueToken = %v, want ErrTokensDisabled", err)
+	}
+}
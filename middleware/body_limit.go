@@ -0,0 +1,51 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the request body limit, which keeps oversized submissions, such
+// as uploads with too many or too large attachments, from being read into memory or disk.
+package middleware
+
+import (
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"errors"
+	"net/http"
+)
+
+// BodyLimit rejects requests whose body is larger than maxBytes with a 413 status code.
+// Bodies without a declared length are cut off once they exceed the limit, which makes
+// reading them fail with an *http.MaxBytesError; see BodyTooLarge.
+func BodyLimit(maxBytes int64) router.HandlerFunc {
+	return func(c *router.Context) {
+		if c.Request.ContentLength > maxBytes {
+			abortBodyTooLarge(c)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// BodyTooLarge reports whether err was caused by a body exceeding the BodyLimit.
+func BodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// abortBodyTooLarge aborts the request with a 413 status code.
+func abortBodyTooLarge(c *router.Context) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, responses.APIResponse{
+		Code:    "PAYLOAD_TOO_LARGE",
+		Message: "Request body too large",
+		Data:    nil,
+	})
+}
+
+// abortBodyError aborts a request whose body could not be read, with a 413 status code
+// when it exceeds the BodyLimit and a 400 status code otherwise.
+func abortBodyError(c *router.Context, err error) {
+	if BodyTooLarge(err) {
+		abortBodyTooLarge(c)
+		return
+	}
+	c.AbortWithStatus(http.StatusBadRequest)
+}
@@ -0,0 +1,143 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the CaptchaVerifier, which checks reCAPTCHA v3 or hCaptcha
+// tokens with the provider before a submission is accepted.
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// verifyURLs maps the supported CAPTCHA providers to their verification endpoints.
+var verifyURLs = map[string]string{
+	"recaptcha": "https://www.google.com/recaptcha/api/siteverify",
+	"hcaptcha":  "https://api.hcaptcha.com/siteverify",
+}
+
+// captchaResult is the response of a provider verification endpoint.
+type captchaResult struct {
+	Success bool `json:"success"`
+	// Score is the likelihood, between 0 and 1, that the client is human. It is only
+	// returned by reCAPTCHA v3.
+	Score *float64 `json:"score"`
+	// ErrorCodes explain why the verification failed.
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// CaptchaVerifier verifies the CAPTCHA tokens sent with submissions.
+type CaptchaVerifier struct {
+	verifyURL  string
+	secret     string
+	minScore   float64
+	client     *http.Client
+	rejections *RejectionLog
+}
+
+// NewCaptchaVerifier creates a CaptchaVerifier for the given provider, "recaptcha" or
+// "hcaptcha". reCAPTCHA v3 tokens must score at least minScore. Rejected submissions
+// are recorded in rejections.
+func NewCaptchaVerifier(provider, secret string, minScore float64, rejections *RejectionLog) (*CaptchaVerifier, error) {
+	verifyURL, ok := verifyURLs[provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported CAPTCHA provider %q", provider)
+	}
+	if secret == "" {
+		return nil, errors.New("CAPTCHA secret is required")
+	}
+
+	return &CaptchaVerifier{
+		verifyURL:  verifyURL,
+		secret:     secret,
+		minScore:   minScore,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		rejections: rejections,
+	}, nil
+}
+
+// Verify asks the provider whether token, solved by the client at remoteIP, is valid.
+func (v *CaptchaVerifier) Verify(token, remoteIP string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+
+	resp, err := v.client.PostForm(v.verifyURL, url.Values{
+		"secret":   {v.secret},
+		"response": {token},
+		"remoteip": {remoteIP},
+	})
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var result captchaResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	if result.Score != nil && *result.Score < v.minScore {
+		return false, nil
+	}
+	return result.Success, nil
+}
+
+// CheckSecret verifies a dummy token, which the provider rejects, and reports an error
+// when the rejection is caused by the secret rather than by the token.
+func (v *CaptchaVerifier) CheckSecret(ctx context.Context) error {
+	form := url.Values{"secret": {v.secret}, "response": {"contactctl-doctor"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result captchaResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	for _, code := range []string{"missing-input-secret", "invalid-input-secret"} {
+		if slices.Contains(result.ErrorCodes, code) {
+			return fmt.Errorf("the provider rejected the secret: %s", code)
+		}
+	}
+	return nil
+}
+
+// Middleware rejects requests without a valid token in the X-Captcha-Token header
+// with a 403 status code. When the provider cannot be reached, a 503 status code is
+// returned instead so that clients retry rather than being marked as bots.
+func (v *CaptchaVerifier) Middleware() router.HandlerFunc {
+	return func(c *router.Context) {
+		ok, err := v.Verify(c.GetHeader("X-Captcha-Token"), c.ClientIP())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, responses.APIResponse{
+				Code:    "SERVICE_UNAVAILABLE",
+				Message: "CAPTCHA verification is unavailable, please retry later",
+				Data:    nil,
+			})
+			return
+		}
+		if !ok {
+			reject(c, v.rejections, models.RejectionCaptcha, http.StatusForbidden,
+				"FORBIDDEN", "CAPTCHA verification failed")
+			return
+		}
+		c.Next()
+	}
+}
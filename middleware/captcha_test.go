@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/router"
+	"api-contact-form/router/nethttp"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// captchaProvider serves a verification endpoint answering with the JSON result of each
+// token, and records the secret it receives.
+func captchaProvider(t *testing.T, results map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("secret") != "secret" {
+			t.Errorf("secret = %q, want %q", r.PostFormValue("secret"), "secret")
+		}
+		result, ok := results[r.PostFormValue("response")]
+		if !ok {
+			result = `{"success":false,"error-codes":["invalid-input-response"]}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(result))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCaptchaVerifier(t *testing.T) {
+	provider := captchaProvider(t, map[string]string{
+		"human":    `{"success":true,"score":0.9}`,
+		"bot":      `{"success":true,"score":0.1}`,
+		"hcaptcha": `{"success":true}`,
+	})
+	rejections := NewRejectionLog(nil, 10)
+	verifier, err := NewCaptchaVerifier("recaptcha", "secret", 0.5, rejections)
+	if err != nil {
+		t.Fatalf("NewCaptchaVerifier: %v", err)
+	}
+	verifier.verifyURL = provider.URL
+
+	engine := router.New(nethttp.New())
+	engine.POST("/contacts", verifier.Middleware(), func(c *router.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	tests := []struct {
+		name, token string
+		status      int
+	}{
+		{"token with a high score", "human", http.StatusCreated},
+		{"token without a score", "hcaptcha", http.StatusCreated},
+		{"token with a low score", "bot", http.StatusForbidden},
+		{"invalid token", "forged", http.StatusForbidden},
+		{"no token", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/contacts", nil)
+			if tt.token != "" {
+				r.Header.Set("X-Captcha-Token", tt.token)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			reasons := rejected(rejections)
+			if tt.status == http.StatusForbidden && !slices.Equal(reasons, []models.RejectionReason{models.RejectionCaptcha}) {
+				t.Errorf("rejections = %v, want the CAPTCHA", reasons)
+			}
+		})
+	}
+}
+
+func TestCaptchaVerifierProviderUnavailable(t *testing.T) {
+	provider := captchaProvider(t, nil)
+	verifier, err := NewCaptchaVerifier("hcaptcha", "secret", 0, nil)
+	if err != nil {
+		t.Fatalf("NewCaptchaVerifier: %v", err)
+	}
+	verifier.verifyURL = provider.URL
+	provider.Close()
+
+	engine := router.New(nethttp.New())
+	engine.POST("/contacts", verifier.Middleware(), func(c *router.Context) {
+		c.Status(http.StatusCreated)
+	})
+	r := httptest.NewRequest(http.MethodPost, "/contacts", nil)
+	r.Header.Set("X-Captcha-Token", "human")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestNewCaptchaVerifierErrors(t *testing.T) {
+	if _, err := NewCaptchaVerifier("turnstile", "secret", 0, nil); err == nil {
+		t.Error("NewCaptchaVerifier(turnstile) succeeded, want an error")
+	}
+	if _, err := NewCaptchaVerifier("recaptcha", "", 0, nil); err == nil {
+		t.Error("NewCaptchaVerifier without a secret succeeded, want an error")
+	}
+}
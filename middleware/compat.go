@@ -0,0 +1,183 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the compatibility mode, which rewrites JSON requests and responses
+// for frontends built against the legacy schema: contact fields named after their database
+// columns, and responses without the code/message/data envelope.
+package middleware
+
+import (
+	"api-contact-form/router"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// legacyFieldNames maps the JSON names of the contact fields to their legacy names.
+var legacyFieldNames = map[string]string{
+	"name":    "full_name",
+	"email":   "email_address",
+	"phone":   "phone_number",
+	"message": "message_text",
+}
+
+// CompatConfig selects the legacy behaviors of the compatibility mode.
+type CompatConfig struct {
+	// LegacyFieldNames emits the contact fields under their legacy names, e.g. email_address
+	// instead of email, and accepts the legacy names in request bodies.
+	LegacyFieldNames bool
+	// Flat responds with the bare data of successful responses instead of the envelope.
+	// Error responses keep the envelope so that their code and message stay readable.
+	Flat bool
+	// MaxBodySize limits the request bodies read to rename their legacy fields, which
+	// happens before the BodyLimit of the routes, as BodyLimit does. It must be set with
+	// LegacyFieldNames.
+	MaxBodySize int64
+}
+
+// Enabled reports whether any legacy behavior is selected.
+func (cfg CompatConfig) Enabled() bool {
+	return cfg.LegacyFieldNames || cfg.Flat
+}
+
+// Compat applies cfg to the JSON bodies of requests and responses. Other bodies, such as
+// exports, pass through unchanged and are still streamed.
+func Compat(cfg CompatConfig) router.HandlerFunc {
+	return func(c *router.Context) {
+		// Rename the legacy fields of the request body, leaving it intact when it is not a JSON object.
+		if cfg.LegacyFieldNames && isJSON(c.ContentType()) {
+			if c.Request.ContentLength > cfg.MaxBodySize {
+				abortBodyTooLarge(c)
+				return
+			}
+			body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, cfg.MaxBodySize))
+			if err != nil {
+				abortBodyError(c, err)
+				return
+			}
+			body = acceptLegacyFields(body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			c.Request.ContentLength = int64(len(body))
+		}
+
+		// Buffer the JSON response so that it can be rewritten once the handler is done.
+		writer := &compatWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.body.Len() == 0 {
+			return
+		}
+		_, _ = c.Writer.Write(cfg.rewrite(writer.body.Bytes(), c.Writer.Status()))
+	}
+}
+
+// compatWriter buffers JSON response bodies and writes other bodies through.
+type compatWriter struct {
+	router.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write buffers data when the response is JSON.
+func (w *compatWriter) Write(data []byte) (int, error) {
+	if !isJSON(w.Header().Get("Content-Type")) {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+// WriteString buffers s when the response is JSON.
+func (w *compatWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// rewrite applies the configuration to a JSON response body with the given status.
+// Bodies that cannot be decoded are returned unchanged.
+func (cfg CompatConfig) rewrite(body []byte, status int) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // keep integers exact
+	var value interface{}
+	if dec.Decode(&value) != nil {
+		return body
+	}
+
+	// Unwrap the data of successful enveloped responses.
+	if envelope, ok := value.(map[string]interface{}); ok && cfg.Flat && status < http.StatusBadRequest {
+		if data, ok := envelope["data"]; ok {
+			value = data
+		}
+	}
+
+	if cfg.LegacyFieldNames {
+		value = renameContactFields(value)
+	}
+
+	out, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// renameContactFields renames the contact fields of every contact object found in value,
+// and the fields named by validation errors. Contact objects are recognized by their email
+// field, which keeps the message of the envelope and of validation errors untouched.
+func renameContactFields(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for i, item := range v {
+			v[i] = renameContactFields(item)
+		}
+	case map[string]interface{}:
+		_, isContact := v["email"]
+		renamed := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if legacy, ok := legacyFieldNames[key]; ok && isContact {
+				key = legacy
+			}
+			renamed[key] = renameContactFields(item)
+		}
+		if field, ok := renamed["field"].(string); ok && legacyFieldNames[field] != "" {
+			renamed["field"] = legacyFieldNames[field]
+		}
+		return renamed
+	}
+	return value
+}
+
+// acceptLegacyFields renames the legacy fields of a JSON request object to their current
+// names, unless the current name is also present. Other bodies are returned unchanged.
+func acceptLegacyFields(body []byte) []byte {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return body
+	}
+
+	renamed := false
+	for name, legacy := range legacyFieldNames {
+		value, ok := fields[legacy]
+		if _, current := fields[name]; !ok || current {
+			continue
+		}
+		fields[name] = value
+		delete(fields, legacy)
+		renamed = true
+	}
+	if !renamed {
+		return body
+	}
+
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// isJSON reports whether contentType is a JSON media type.
+func isJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json"
+}
@@ -0,0 +1,166 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements CORS (Cross-Origin Resource Sharing), with the behaviour of the
+// gin-contrib/cors middleware of Gin, which the API used before it could run on other
+// HTTP frameworks.
+package middleware
+
+import (
+	"api-contact-form/router"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig lists the origins allowed to call the API from a browser, and the methods and
+// headers of their requests. An origin is "*" for every origin, a scheme and host such as
+// "https://example.com", or a regular expression between slashes.
+type CORSConfig struct {
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	AllowCredentials bool
+	ExposeHeaders    []string
+	MaxAge           time.Duration
+}
+
+// corsOriginPattern matches the origins given as regular expressions.
+var corsOriginPattern = regexp.MustCompile(`^/(.+)/[gimuy]?$`)
+
+// cors is the compiled CORSConfig.
+type cors struct {
+	allowAll         bool
+	origins          []string
+	patterns         []*regexp.Regexp
+	normalHeaders    http.Header
+	preflightHeaders http.Header
+}
+
+// CORS adds the CORS headers to the responses to the allowed origins, answers their
+// preflight requests with a 204 status code, and rejects the requests of other origins
+// with a 403 status code. Requests without an Origin header, or from the host of the API
+// itself, are left alone.
+func CORS(config CORSConfig) (router.HandlerFunc, error) {
+	if len(config.AllowOrigins) == 0 {
+		return nil, errors.New("no allowed origins")
+	}
+	policy := &cors{}
+	for _, origin := range normalizeCORS(config.AllowOrigins) {
+		switch {
+		case origin == "*":
+			policy.allowAll = true
+		case corsOriginPattern.MatchString(origin):
+			pattern, err := regexp.Compile(corsOriginPattern.FindStringSubmatch(origin)[1])
+			if err != nil {
+				return nil, fmt.Errorf("bad origin %q: %w", origin, err)
+			}
+			policy.patterns = append(policy.patterns, pattern)
+		case strings.HasPrefix(origin, "http://"), strings.HasPrefix(origin, "https://"):
+			policy.origins = append(policy.origins, origin)
+		default:
+			return nil, fmt.Errorf("bad origin %q: origins must be * or include http:// or https://", origin)
+		}
+	}
+	policy.normalHeaders, policy.preflightHeaders = corsHeaders(config, policy.allowAll)
+
+	return func(c *router.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || origin == "http://"+c.Request.Host || origin == "https://"+c.Request.Host {
+			return
+		}
+		if !policy.allowed(origin) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		headers := policy.normalHeaders
+		if c.Request.Method == http.MethodOptions {
+			headers = policy.preflightHeaders
+		}
+		for key, values := range headers {
+			c.Writer.Header()[key] = values
+		}
+		if !policy.allowAll {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+		}
+	}, nil
+}
+
+// allowed reports whether the origin may call the API.
+func (p *cors) allowed(origin string) bool {
+	if p.allowAll {
+		return true
+	}
+	for _, allowed := range p.origins {
+		if allowed == origin {
+			return true
+		}
+	}
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsHeaders returns the headers of the responses to the allowed origins, and those of
+// the responses to their preflight requests.
+func corsHeaders(config CORSConfig, allowAll bool) (normal, preflight http.Header) {
+	normal, preflight = make(http.Header), make(http.Header)
+	if config.AllowCredentials {
+		normal.Set("Access-Control-Allow-Credentials", "true")
+		preflight.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(config.ExposeHeaders) > 0 {
+		normal.Set("Access-Control-Expose-Headers", joinCORS(config.ExposeHeaders, http.CanonicalHeaderKey))
+	}
+	if len(config.AllowMethods) > 0 {
+		preflight.Set("Access-Control-Allow-Methods", joinCORS(config.AllowMethods, strings.ToUpper))
+	}
+	if len(config.AllowHeaders) > 0 {
+		preflight.Set("Access-Control-Allow-Headers", joinCORS(config.AllowHeaders, http.CanonicalHeaderKey))
+	}
+	if config.MaxAge > 0 {
+		preflight.Set("Access-Control-Max-Age", strconv.FormatInt(int64(config.MaxAge/time.Second), 10))
+	}
+	if allowAll {
+		normal.Set("Access-Control-Allow-Origin", "*")
+		preflight.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		normal.Set("Vary", "Origin")
+		preflight.Add("Vary", "Origin")
+		preflight.Add("Vary", "Access-Control-Request-Method")
+		preflight.Add("Vary", "Access-Control-Request-Headers")
+	}
+	return normal, preflight
+}
+
+// normalizeCORS trims, lowercases and deduplicates the values of a CORS setting.
+func normalizeCORS(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	normalized := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if !seen[value] {
+			normalized = append(normalized, value)
+			seen[value] = true
+		}
+	}
+	return normalized
+}
+
+// joinCORS returns the values of a CORS setting as a header value.
+func joinCORS(values []string, convert func(string) string) string {
+	normalized := normalizeCORS(values)
+	for i, value := range normalized {
+		normalized[i] = convert(value)
+	}
+	return strings.Join(normalized, ",")
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"api-contact-form/router"
+	"api-contact-form/router/nethttp"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	cors, err := CORS(CORSConfig{
+		AllowOrigins:  []string{"https://app.example.com", "/^https://[a-z]+\\.example\\.org$/"},
+		AllowMethods:  []string{"get", "post"},
+		AllowHeaders:  []string{"content-type", "x-api-key"},
+		ExposeHeaders: []string{"content-length"},
+		MaxAge:        12 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("CORS: %v", err)
+	}
+	engine := router.New(nethttp.New())
+	engine.Use(cors)
+	engine.GET("/contacts", func(c *router.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name, method, origin string
+		status               int
+		header               map[string]string
+	}{
+		{"no origin", http.MethodGet, "", http.StatusOK, map[string]string{"Access-Control-Allow-Origin": ""}},
+		{"same host", http.MethodGet, "http://api.example.com", http.StatusOK, map[string]string{"Access-Control-Allow-Origin": ""}},
+		{"allowed origin", http.MethodGet, "https://app.example.com", http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin":   "https://app.example.com",
+			"Access-Control-Expose-Headers": "Content-Length",
+			"Access-Control-Max-Age":        "",
+			"Vary":                          "Origin",
+		}},
+		{"origin matching a pattern", http.MethodGet, "https://forms.example.org", http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin": "https://forms.example.org",
+		}},
+		{"preflight request", http.MethodOptions, "https://app.example.com", http.StatusNoContent, map[string]string{
+			"Access-Control-Allow-Origin":  "https://app.example.com",
+			"Access-Control-Allow-Methods": "GET,POST",
+			"Access-Control-Allow-Headers": "Content-Type,X-Api-Key",
+			"Access-Control-Max-Age":       "43200",
+		}},
+		{"other origin", http.MethodGet, "https://evil.example.net", http.StatusForbidden, map[string]string{"Access-Control-Allow-Origin": ""}},
+		{"other origin preflight", http.MethodOptions, "https://evil.example.org.net", http.StatusForbidden, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "http://api.example.com/contacts", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			for key, want := range tt.header {
+				if got := w.Header().Get(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSConfigErrors(t *testing.T) {
+	for name, origins := range map[string][]string{
+		"no origin":             nil,
+		"origin with no scheme": {"app.example.com"},
+		"invalid pattern":       {"/^https://(/"},
+	} {
+		if _, err := CORS(CORSConfig{AllowOrigins: origins}); err == nil {
+			t.Errorf("%s: CORS(%q) succeeded, want an error", name, origins)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the FormSchedule, which opens and closes the contact form at
+// given times and closes it once it received a given number of submissions, as for the
+// registration to an event.
+package middleware
+
+import (
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// The reasons a FormSchedule rejects a submission.
+const (
+	FormNotOpen      = "not_open"
+	FormEnded        = "ended"
+	FormLimitReached = "limit_reached"
+)
+
+// FormClosedData is the data of the response to a submission rejected by a FormSchedule.
+type FormClosedData struct {
+	// Reason is FormNotOpen, FormEnded or FormLimitReached.
+	Reason   string     `json:"reason"`
+	OpensAt  *time.Time `json:"opens_at,omitempty"`
+	ClosesAt *time.Time `json:"closes_at,omitempty"`
+}
+
+// FormSchedule rejects submissions outside of the opening hours of the form, or once it
+// received its maximum number of submissions. It is safe for concurrent use.
+type FormSchedule struct {
+	opensAt        time.Time
+	closesAt       time.Time
+	maxSubmissions int64
+	message        string
+	count          func(ctx context.Context, since time.Time) (int64, error)
+
+	// full caches that the limit was reached, as the count never decreases.
+	full atomic.Bool
+}
+
+// NewFormSchedule creates a FormSchedule accepting submissions from opensAt until
+// closesAt, and at most maxSubmissions of them since opensAt, as counted by count.
+// Zero times and limits are not enforced. Rejected submissions are answered with message.
+//
+// The limit is checked before the submission is processed, so that concurrent
+// submissions may exceed it slightly.
+func NewFormSchedule(opensAt, closesAt time.Time, maxSubmissions int, message string, count func(ctx context.Context, since time.Time) (int64, error)) *FormSchedule {
+	return &FormSchedule{
+		opensAt:        opensAt,
+		closesAt:       closesAt,
+		maxSubmissions: int64(maxSubmissions),
+		message:        message,
+		count:          count,
+	}
+}
+
+// Enabled reports whether the schedule enforces any time or limit.
+func (f *FormSchedule) Enabled() bool {
+	return !f.opensAt.IsZero() || !f.closesAt.IsZero() || f.maxSubmissions > 0
+}
+
+// Closed returns the reason the form does not accept submissions, or an empty string
+// when it is open.
+func (f *FormSchedule) Closed(ctx context.Context) (string, error) {
+	now := time.Now()
+	switch {
+	case !f.opensAt.IsZero() && now.Before(f.opensAt):
+		return FormNotOpen, nil
+	case !f.closesAt.IsZero() && !now.Before(f.closesAt):
+		return FormEnded, nil
+	case f.maxSubmissions <= 0:
+		return "", nil
+	case f.full.Load():
+		return FormLimitReached, nil
+	}
+
+	count, err := f.count(ctx, f.opensAt)
+	if err != nil {
+		return "", err
+	}
+	if count >= f.maxSubmissions {
+		f.full.Store(true)
+		return FormLimitReached, nil
+	}
+	return "", nil
+}
+
+// Middleware rejects submissions with a 403 status code while the form is closed,
+// reporting the reason and the opening hours in the data of the response.
+func (f *FormSchedule) Middleware() router.HandlerFunc {
+	return func(c *router.Context) {
+		reason, err := f.Closed(c.Request.Context())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, responses.APIResponse{
+				Code:    "INTERNAL_SERVER_ERROR",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		if reason == "" {
+			c.Next()
+			return
+		}
+
+		data := FormClosedData{Reason: reason}
+		if !f.opensAt.IsZero() {
+			data.OpensAt = &f.opensAt
+		}
+		if !f.closesAt.IsZero() {
+			data.ClosesAt = &f.closesAt
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, responses.APIResponse{
+			Code:    "FORM_CLOSED",
+			Message: f.message,
+			Data:    data,
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the honeypot check: the contact form renders a field hidden
+// from humans, and submissions that fill it in are assumed to come from bots.
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/router"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// Honeypot rejects JSON and multipart submissions whose field named field is not empty
+// with a 400 status code, and records them in rejections. The body is left intact, or
+// parsed into the multipart form, for the handler.
+func Honeypot(field string, rejections *RejectionLog) router.HandlerFunc {
+	return func(c *router.Context) {
+		if c.ContentType() == router.MIMEMultipartPOSTForm {
+			form, err := c.MultipartForm()
+			if err != nil {
+				// Malformed forms are left for the handler to reject, oversized ones are not.
+				if BodyTooLarge(err) {
+					abortBodyError(c, err)
+					return
+				}
+				c.Next()
+				return
+			}
+			if values := form.Value[field]; len(values) > 0 && values[0] != "" {
+				reject(c, rejections, models.RejectionHoneypot, http.StatusBadRequest,
+					"BAD_REQUEST", "Submission rejected")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortBodyError(c, err)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Malformed bodies are left for the handler to reject.
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(body, &fields) != nil {
+			c.Next()
+			return
+		}
+
+		if value, ok := fields[field]; ok && !isEmptyJSON(value) {
+			reject(c, rejections, models.RejectionHoneypot, http.StatusBadRequest,
+				"BAD_REQUEST", "Submission rejected")
+			return
+		}
+		c.Next()
+	}
+}
+
+// isEmptyJSON reports whether a JSON value is null, false or an empty string.
+func isEmptyJSON(value json.RawMessage) bool {
+	switch string(bytes.TrimSpace(value)) {
+	case "null", "false", `""`:
+		return true
+	}
+	return false
+}
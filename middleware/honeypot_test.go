@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/router"
+	"api-contact-form/router/nethttp"
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// rejected returns the reasons of the rejections queued in rejections so far.
+func rejected(rejections *RejectionLog) []models.RejectionReason {
+	var reasons []models.RejectionReason
+	for {
+		select {
+		case rejection := <-rejections.queue:
+			reasons = append(reasons, rejection.Reason)
+		default:
+			return reasons
+		}
+	}
+}
+
+// multipartForm encodes fields as a multipart form and returns it with its content type.
+func multipartForm(t *testing.T, fields map[string]string) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatalf("write field: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close form: %v", err)
+	}
+	return &body, writer.FormDataContentType()
+}
+
+func TestHoneypot(t *testing.T) {
+	rejections := NewRejectionLog(nil, 10)
+	engine := router.New(nethttp.New())
+	engine.POST("/contacts", Honeypot("website", rejections), func(c *router.Context) {
+		// The body must still be readable by the handler.
+		if c.ContentType() == router.MIMEMultipartPOSTForm {
+			c.Data(http.StatusOK, "text/plain", []byte(c.PostForm("name")))
+			return
+		}
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, "text/plain", body)
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"field missing", `{"name":"Ada"}`, http.StatusOK},
+		{"field empty", `{"name":"Ada","website":""}`, http.StatusOK},
+		{"field null", `{"name":"Ada","website":null}`, http.StatusOK},
+		{"field filled in", `{"name":"Ada","website":"https://spam.example.com"}`, http.StatusBadRequest},
+		{"field set to a number", `{"name":"Ada","website":1}`, http.StatusBadRequest},
+		{"malformed body", `{"name":`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/contacts", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			reasons := rejected(rejections)
+			if tt.status == http.StatusOK {
+				if w.Body.String() != tt.body {
+					t.Errorf("body seen by the handler = %q, want %q", w.Body, tt.body)
+				}
+				if len(reasons) != 0 {
+					t.Errorf("rejections = %v, want none", reasons)
+				}
+			} else if !slices.Equal(reasons, []models.RejectionReason{models.RejectionHoneypot}) {
+				t.Errorf("rejections = %v, want the honeypot", reasons)
+			}
+		})
+	}
+}
+
+func TestHoneypotMultipart(t *testing.T) {
+	rejections := NewRejectionLog(nil, 10)
+	engine := router.New(nethttp.New())
+	engine.POST("/contacts", Honeypot("website", rejections), func(c *router.Context) {
+		c.Data(http.StatusOK, "text/plain", []byte(c.PostForm("name")))
+	})
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		status int
+	}{
+		{"field empty", map[string]string{"name": "Ada", "website": ""}, http.StatusOK},
+		{"field filled in", map[string]string{"name": "Ada", "website": "https://spam.example.com"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := multipartForm(t, tt.fields)
+			r := httptest.NewRequest(http.MethodPost, "/contacts", body)
+			r.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && w.Body.String() != "Ada" {
+				t.Errorf("name seen by the handler = %q, want %q", w.Body, "Ada")
+			}
+			want := 0
+			if tt.status != http.StatusOK {
+				want = 1
+			}
+			if got := len(rejected(rejections)); got != want {
+				t.Errorf("%d rejections recorded, want %d", got, want)
+			}
+		})
+	}
+}
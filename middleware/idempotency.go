@@ -0,0 +1,210 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the Idempotency middleware, which lets clients retry a request
+// safely by sending an Idempotency-Key header: repeated requests with the same key are
+// answered with the response of the first one instead of being processed again.
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the idempotency key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from a previous request.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength is the longest idempotency key accepted.
+	maxIdempotencyKeyLength = 255
+	// idempotencySweepInterval is how often Run removes the expired keys.
+	idempotencySweepInterval = time.Hour
+)
+
+// Idempotency stores the requests made with an Idempotency-Key header and their
+// responses for a limited time.
+type Idempotency struct {
+	repository repositories.IdempotencyRepository
+	ttl        time.Duration
+}
+
+// NewIdempotency creates an Idempotency middleware remembering keys for ttl.
+func NewIdempotency(repository repositories.IdempotencyRepository, ttl time.Duration) *Idempotency {
+	return &Idempotency{repository: repository, ttl: ttl}
+}
+
+// Run removes the expired keys every hour until ctx is cancelled.
+func (i *Idempotency) Run(ctx context.Context) {
+	ticker := time.NewTicker(idempotencySweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := i.repository.DeleteExpired(time.Now()); err != nil {
+				log.Printf("Expired idempotency keys not removed: %v", err)
+			}
+		}
+	}
+}
+
+// Middleware processes requests with an Idempotency-Key header once per key.
+//
+// Keys are scoped to the route and the authenticated caller, if any. The first request
+// with a key is processed and its response stored; repeated requests get the stored
+// response with the Idempotent-Replayed header. A repeated request arriving while the first
+// one is still processed is rejected with a 409 status code, and a key reused for a
+// different request with a 422 status code. Responses with a 5xx or 429 status code are
+// not stored, so that the request can be retried with the same key.
+//
+// The body is read into memory to be hashed, so the middleware must follow BodyLimit.
+// Requests without the header are processed normally, as are all requests when the keys
+// cannot be stored.
+func (i *Idempotency) Middleware() router.HandlerFunc {
+	return func(c *router.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, responses.APIResponse{
+				Code:    "BAD_REQUEST",
+				Message: "Idempotency-Key must not be longer than 255 characters",
+				Data:    nil,
+			})
+			return
+		}
+
+		// Read the body, so that it can be hashed and still be bound by the handler.
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortBodyError(c, err)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Reserve the key, or find the request that reserved it first.
+		record := &models.IdempotencyKey{
+			KeyHash:     hashIdempotencyKey(c, key),
+			RequestHash: hashIdempotentRequest(c, body),
+			ExpiresAt:   time.Now().Add(i.ttl),
+		}
+		reserved, existing, err := i.repository.Reserve(record)
+		if err != nil {
+			log.Printf("Idempotency key not stored, processing the request without it: %v", err)
+			c.Next()
+			return
+		}
+		if !reserved {
+			replay(c, existing, record.RequestHash)
+			return
+		}
+
+		// Process the request, capturing its response. Should it fail, the key is
+		// released so that the request can be retried.
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		completed := false
+		defer func() {
+			if !completed {
+				if err := i.repository.Delete(record.KeyHash); err != nil {
+					log.Printf("Idempotency key not released: %v", err)
+				}
+			}
+		}()
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			return
+		}
+		if err := i.repository.Complete(record.KeyHash, status, writer.Header().Get("Content-Type"), writer.body.Bytes()); err != nil {
+			log.Printf("Idempotent response not stored: %v", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replay answers a repeated request with the response stored for its key.
+func replay(c *router.Context, existing *models.IdempotencyKey, requestHash string) {
+	if existing.RequestHash != requestHash {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, responses.APIResponse{
+			Code:    "UNPROCESSABLE_ENTITY",
+			Message: "Idempotency-Key was already used for a different request",
+			Data:    nil,
+		})
+		return
+	}
+	if !existing.Completed() {
+		c.AbortWithStatusJSON(http.StatusConflict, responses.APIResponse{
+			Code:    "CONFLICT",
+			Message: "A request with this Idempotency-Key is still being processed",
+			Data:    nil,
+		})
+		return
+	}
+
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(existing.StatusCode, existing.ContentType, existing.ResponseBody)
+	c.Abort()
+}
+
+// hashIdempotencyKey returns the hex SHA-256 of key scoped to the route and the
+// authenticated caller of c, so that different callers cannot see each other's responses.
+func hashIdempotencyKey(c *router.Context, key string) string {
+	subject := ""
+	if identity := CurrentIdentity(c); identity != nil {
+		subject = identity.Subject
+	}
+	sum := sha256.Sum256([]byte(c.Request.Method + " " + c.FullPath() + "\n" + subject + "\n" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashIdempotentRequest returns the hex SHA-256 of the content type and body of the request
+// of c. The boundary of multipart bodies is left out, as clients pick a new one for every
+// request, even when they retry.
+func hashIdempotentRequest(c *router.Context, body []byte) string {
+	mediaType, params, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if boundary := params["boundary"]; boundary != "" && strings.HasPrefix(mediaType, "multipart/") {
+		body = bytes.ReplaceAll(body, []byte(boundary), nil)
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(mediaType + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// capturingWriter is a router.ResponseWriter keeping a copy of the response body.
+type capturingWriter struct {
+	router.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write writes data to the response and to the copy.
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes s to the response and to the copy.
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
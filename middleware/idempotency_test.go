@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/router"
+	"api-contact-form/router/nethttp"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// idempotentEngine routes POST /contacts through an Idempotency middleware to handler.
+func idempotentEngine(t *testing.T, handler router.HandlerFunc) *router.Engine {
+	t.Helper()
+	idempotency := NewIdempotency(repositories.NewIdempotencyRepository(openDB(t, &models.IdempotencyKey{})), time.Hour)
+	engine := router.New(nethttp.New())
+	engine.POST("/contacts", idempotency.Middleware(), handler)
+	return engine
+}
+
+// submit posts body to /contacts with the idempotency key, if any.
+func submit(engine http.Handler, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/contacts", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		r.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	var calls atomic.Int32
+	engine := idempotentEngine(t, func(c *router.Context) {
+		calls.Add(1)
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusCreated, "application/json", body)
+	})
+
+	first := submit(engine, "key-1", `{"name":"Ada"}`)
+	if first.Code != http.StatusCreated || first.Body.String() != `{"name":"Ada"}` {
+		t.Fatalf("first request = %d %q, want 201 with the body", first.Code, first.Body)
+	}
+	if first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("first request marked as replayed")
+	}
+
+	repeated := submit(engine, "key-1", `{"name":"Ada"}`)
+	if repeated.Code != http.StatusCreated || repeated.Body.String() != first.Body.String() {
+		t.Errorf("repeated request = %d %q, want the first response", repeated.Code, repeated.Body)
+	}
+	if repeated.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q, want the stored one", repeated.Header().Get("Content-Type"))
+	}
+	if repeated.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Error("repeated request not marked as replayed")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times, want once", calls.Load())
+	}
+
+	// Other keys and requests without a key are processed.
+	submit(engine, "key-2", `{"name":"Ada"}`)
+	submit(engine, "", `{"name":"Ada"}`)
+	if calls.Load() != 3 {
+		t.Errorf("handler called %d times, want 3", calls.Load())
+	}
+}
+
+func TestIdempotencyRejectsConflictingBody(t *testing.T) {
+	var calls atomic.Int32
+	engine := idempotentEngine(t, func(c *router.Context) {
+		calls.Add(1)
+		c.Status(http.StatusCreated)
+	})
+
+	submit(engine, "key-1", `{"name":"Ada"}`)
+	if w := submit(engine, "key-1", `{"name":"Grace"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times, want once", calls.Load())
+	}
+}
+
+func TestIdempotencyRejectsRequestInProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	engine := idempotentEngine(t, func(c *router.Context) {
+		close(started)
+		<-release
+		c.Status(http.StatusCreated)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- submit(engine, "key-1", `{}`) }()
+	<-started
+
+	if w := submit(engine, "key-1", `{}`); w.Code != http.StatusConflict {
+		t.Errorf("status while in progress = %d, want %d", w.Code, http.StatusConflict)
+	}
+	close(release)
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Errorf("status of the first request = %d, want %d", w.Code, http.StatusCreated)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnServerError(t *testing.T) {
+	var calls atomic.Int32
+	engine := idempotentEngine(t, func(c *router.Context) {
+		if calls.Add(1) == 1 {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	if w := submit(engine, "key-1", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("first status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	w := submit(engine, "key-1", `{}`)
+	if w.Code != http.StatusCreated || w.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("retry = %d replayed %q, want the request processed again", w.Code, w.Header().Get(IdempotentReplayedHeader))
+	}
+}
+
+func TestIdempotencyRejectsLongKey(t *testing.T) {
+	engine := idempotentEngine(t, func(c *router.Context) {
+		c.Status(http.StatusCreated)
+	})
+	if w := submit(engine, strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,91 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the LoadShedder, which rejects low-priority requests with a
+// 503 status code while the service is over its in-flight or latency budget, so that
+// expensive reads such as exports cannot starve contact submissions.
+package middleware
+
+import (
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// latencyWeight is the weight of a new sample in the moving average of the latency, as 1/latencyWeight.
+const latencyWeight = 8
+
+// LoadShedder tracks the requests in flight and a moving average of their latency.
+// It is safe for concurrent use.
+type LoadShedder struct {
+	maxInFlight   int64
+	latencyBudget time.Duration
+	retryAfter    time.Duration
+
+	inFlight   atomic.Int64
+	avgLatency atomic.Int64
+}
+
+// NewLoadShedder creates a LoadShedder that considers the service overloaded when more
+// than maxInFlight requests are in flight or the average latency exceeds latencyBudget.
+// A zero limit disables that budget. Rejected clients are asked to retry after retryAfter.
+func NewLoadShedder(maxInFlight int, latencyBudget, retryAfter time.Duration) *LoadShedder {
+	return &LoadShedder{
+		maxInFlight:   int64(maxInFlight),
+		latencyBudget: latencyBudget,
+		retryAfter:    retryAfter,
+	}
+}
+
+// Overloaded reports whether the service currently exceeds one of its budgets.
+func (l *LoadShedder) Overloaded() bool {
+	if l.maxInFlight > 0 && l.inFlight.Load() > l.maxInFlight {
+		return true
+	}
+	return l.latencyBudget > 0 && time.Duration(l.avgLatency.Load()) > l.latencyBudget
+}
+
+// Track measures every request. It must be registered on the router before any route.
+// Aborted requests, such as shed ones, are counted in flight but not in the latency,
+// since their fast rejection would hide the actual load.
+func (l *LoadShedder) Track() router.HandlerFunc {
+	return func(c *router.Context) {
+		l.inFlight.Add(1)
+		defer l.inFlight.Add(-1)
+
+		start := time.Now()
+		c.Next()
+		if c.IsAborted() {
+			return
+		}
+
+		// Update the moving average of the latency.
+		sample := int64(time.Since(start))
+		for {
+			old := l.avgLatency.Load()
+			if l.avgLatency.CompareAndSwap(old, old+(sample-old)/latencyWeight) {
+				return
+			}
+		}
+	}
+}
+
+// Shed rejects requests with a 503 status code while the service is overloaded.
+// It is registered on low-priority routes only.
+func (l *LoadShedder) Shed() router.HandlerFunc {
+	return func(c *router.Context) {
+		if !l.Overloaded() {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(int(l.retryAfter.Seconds())))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, responses.APIResponse{
+			Code:    "SERVICE_UNAVAILABLE",
+			Message: "Service is under heavy load, please retry later",
+			Data:    nil,
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the observability of requests: a correlation ID per request, the
+// W3C trace context of requests, structured JSON request logs, and the Prometheus request
+// metrics.
+package middleware
+
+import (
+	"api-contact-form/observability"
+	"api-contact-form/router"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// TraceparentHeader is the W3C Trace Context header carrying the trace of a request, and
+// TraceresponseHeader the header returning the trace and span of the request to the client.
+const (
+	TraceparentHeader   = "traceparent"
+	TraceresponseHeader = "traceresponse"
+)
+
+// RequestIDHeader is the header carrying the correlation ID of a request, both in the
+// request, when a proxy already assigned one, and in the response.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the router context key of the correlation ID.
+const requestIDKey = "request_id"
+
+// validRequestID matches the correlation IDs accepted from clients; others are replaced,
+// so that logs cannot be forged through the header.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID assigns a correlation ID to every request: the X-Request-ID sent by the client
+// or proxy when it is valid, or a new random one. It is echoed in the response.
+func RequestID() router.HandlerFunc {
+	return func(c *router.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// CurrentRequestID returns the correlation ID of the request, or an empty string when
+// the RequestID middleware did not run.
+func CurrentRequestID(c *router.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// Tracing starts the span of every request in the trace of its traceparent header, or in a
+// new trace, and adds it to the context of the request, so that the logs and the metrics
+// of the request carry its trace ID. The span is returned in the traceresponse header.
+func Tracing() router.HandlerFunc {
+	return func(c *router.Context) {
+		span := observability.StartSpan(c.GetHeader(TraceparentHeader))
+		c.Request = c.Request.WithContext(observability.ContextWithSpan(c.Request.Context(), span))
+		c.Header(TraceresponseHeader, span.Traceparent())
+		c.Next()
+	}
+}
+
+// RequestLogger logs every request with logger once it is handled, with its correlation
+// ID, route, status code and duration, and the trace and span IDs of Tracing. Server errors are logged at the error level and
+// client errors at the warning level.
+func RequestLogger(logger *slog.Logger) router.HandlerFunc {
+	return func(c *router.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("request_id", CurrentRequestID(c)),
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if identity := CurrentIdentity(c); identity != nil {
+			attrs = append(attrs, slog.String("subject", identity.Subject))
+		}
+		logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Metrics records every request in the HTTP metrics of the observability package. Requests
+// are labeled with their route pattern rather than their path, to keep the label values
+// bounded; unmatched requests share the "unmatched" route. Durations of sampled traces
+// carry their trace ID as exemplar.
+func Metrics() router.HandlerFunc {
+	return func(c *router.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		observability.ObserveWithTrace(c.Request.Context(), observability.HTTPDuration.WithLabelValues(c.Request.Method, route), time.Since(start).Seconds())
+		observability.HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(status)).Inc()
+		if status >= 400 {
+			observability.HTTPErrors.WithLabelValues(c.Request.Method, route, strconv.Itoa(status)).Inc()
+		}
+	}
+}
+
+// newRequestID returns a random correlation ID.
+func newRequestID() string {
+	random := make([]byte, 16)
+	_, _ = rand.Read(random)
+	return hex.EncodeToString(random)
+}
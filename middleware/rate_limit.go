@@ -0,0 +1,128 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the RateLimiter, which limits the submissions of every client
+// IP address with a token bucket.
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/router"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// bucket is the token bucket of a client IP address.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter allows each client IP address a burst of requests, refilled at a steady rate.
+// The limit can be tightened temporarily, such as during a spam attack. It is safe for
+// concurrent use.
+type RateLimiter struct {
+	rate       float64
+	burst      float64
+	rejections *RejectionLog
+
+	mu        sync.Mutex
+	scale     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates a RateLimiter allowing every IP address perMinute requests per
+// minute, with bursts of up to burst requests. Rejected requests are recorded in rejections.
+func NewRateLimiter(perMinute float64, burst int, rejections *RejectionLog) *RateLimiter {
+	return &RateLimiter{
+		rate:       perMinute / 60,
+		burst:      float64(burst),
+		rejections: rejections,
+		scale:      1,
+		buckets:    map[string]*bucket{},
+		lastSweep:  time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket of ip. It reports whether a token was available
+// and, if not, how long it takes until the next one is.
+func (l *RateLimiter) Allow(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+	rate, burst := l.limits()
+
+	// Refill the bucket for the time elapsed since it was last used.
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &bucket{tokens: burst}
+		l.buckets[ip] = b
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// SetScale multiplies the rate and burst of every IP address by scale, such as 0.25 to
+// allow a quarter of the usual submissions, until it is called again with 1. Bursts are
+// never tightened below a single request. Scales outside (0, 1] are ignored.
+func (l *RateLimiter) SetScale(scale float64) {
+	if scale <= 0 || scale > 1 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.scale = scale
+	_, burst := l.limits()
+	for _, b := range l.buckets {
+		b.tokens = math.Min(burst, b.tokens)
+	}
+}
+
+// limits returns the rate per second and the burst under the current scale. The caller
+// must hold mu.
+func (l *RateLimiter) limits() (float64, float64) {
+	return l.rate * l.scale, math.Max(1, l.burst*l.scale)
+}
+
+// sweep forgets the buckets that are full again, at most once a minute, so that
+// the memory used stays proportional to the recently active clients.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	rate, burst := l.limits()
+	for ip, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= burst {
+			delete(l.buckets, ip)
+		}
+	}
+}
+
+// Middleware rejects requests over the limit of their client IP address with a 429 status code.
+func (l *RateLimiter) Middleware() router.HandlerFunc {
+	return func(c *router.Context) {
+		allowed, retryAfter := l.Allow(c.ClientIP())
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			reject(c, l.rejections, models.RejectionRateLimit, http.StatusTooManyRequests,
+				"TOO_MANY_REQUESTS", "Too many submissions, please retry later")
+			return
+		}
+		c.Next()
+	}
+}
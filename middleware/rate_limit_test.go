@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/router"
+	"api-contact-form/router/nethttp"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// rewind moves the last use of the bucket of ip back by d, as if d had elapsed.
+func (l *RateLimiter) rewind(ip string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buckets[ip].last = l.buckets[ip].last.Add(-d)
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	rejections := NewRejectionLog(nil, 10)
+	limiter := NewRateLimiter(6, 2, rejections)
+	engine := router.New(nethttp.New())
+	engine.POST("/contacts", limiter.Middleware(), func(c *router.Context) {
+		c.Status(http.StatusCreated)
+	})
+	submit := func(ip string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/contacts", nil)
+		r.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := range 2 {
+		if w := submit("192.0.2.1"); w.Code != http.StatusCreated {
+			t.Fatalf("submission %d: status = %d, want %d", i+1, w.Code, http.StatusCreated)
+		}
+	}
+	w := submit("192.0.2.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("submission over the burst: status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	// A token is refilled every 10 seconds.
+	if got := w.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("Retry-After = %q, want %q", got, "10")
+	}
+	if reasons := rejected(rejections); !slices.Equal(reasons, []models.RejectionReason{models.RejectionRateLimit}) {
+		t.Errorf("rejections = %v, want the rate limit", reasons)
+	}
+	if w := submit("192.0.2.2"); w.Code != http.StatusCreated {
+		t.Errorf("other address: status = %d, want %d", w.Code, http.StatusCreated)
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	limiter := NewRateLimiter(6, 2, nil)
+	for range 2 {
+		limiter.Allow("192.0.2.1")
+	}
+	if ok, _ := limiter.Allow("192.0.2.1"); ok {
+		t.Fatal("Allow over the burst succeeded")
+	}
+
+	limiter.rewind("192.0.2.1", 10*time.Second)
+	if ok, _ := limiter.Allow("192.0.2.1"); !ok {
+		t.Fatal("Allow after a token was refilled failed")
+	}
+	if ok, _ := limiter.Allow("192.0.2.1"); ok {
+		t.Fatal("Allow after the refilled token was used succeeded")
+	}
+
+	// Refills never exceed the burst.
+	limiter.rewind("192.0.2.1", time.Hour)
+	for i := range 2 {
+		if ok, _ := limiter.Allow("192.0.2.1"); !ok {
+			t.Fatalf("Allow %d after a full refill failed", i+1)
+		}
+	}
+	if ok, _ := limiter.Allow("192.0.2.1"); ok {
+		t.Error("Allow over the burst after a full refill succeeded")
+	}
+}
+
+func TestRateLimiterScale(t *testing.T) {
+	limiter := NewRateLimiter(60, 8, nil)
+	limiter.SetScale(0.25)
+	for i := range 2 {
+		if ok, _ := limiter.Allow("192.0.2.1"); !ok {
+			t.Fatalf("Allow %d under the scaled burst failed", i+1)
+		}
+	}
+	ok, retryAfter := limiter.Allow("192.0.2.1")
+	if ok {
+		t.Fatal("Allow over the scaled burst succeeded")
+	}
+	if retryAfter <= 3*time.Second || retryAfter > 4*time.Second {
+		t.Errorf("retry after %s, want 4s at a quarter of the rate", retryAfter)
+	}
+
+	limiter.SetScale(1)
+	limiter.rewind("192.0.2.1", time.Hour)
+	for i := range 8 {
+		if ok, _ := limiter.Allow("192.0.2.1"); !ok {
+			t.Fatalf("Allow %d after the scale was reset failed", i+1)
+		}
+	}
+}
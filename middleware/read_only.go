@@ -0,0 +1,35 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the ReadOnly middleware, which refuses the requests changing data
+// while the region serving them is a read-only standby.
+package middleware
+
+import (
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"net/http"
+	"slices"
+)
+
+// ReadOnly answers the POST, PUT, PATCH and DELETE requests with a 503 status code while
+// standby reports true, so that clients retry against the primary region. The routes
+// listed in exempt, such as the promotion endpoint, are always served.
+func ReadOnly(standby func() bool, exempt ...string) router.HandlerFunc {
+	return func(c *router.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if !standby() || slices.Contains(exempt, c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, responses.APIResponse{
+			Code:    "SERVICE_UNAVAILABLE",
+			Message: "This region is a read-only standby, please retry against the primary region",
+			Data:    nil,
+		})
+	}
+}
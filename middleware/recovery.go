@@ -0,0 +1,36 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the recovery from the panics of handlers.
+package middleware
+
+import (
+	"api-contact-form/router"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"syscall"
+)
+
+// Recovery answers the requests whose handlers panic with a 500 status code, after logging
+// the panic and its stack. Panics caused by a client closing the connection are only
+// logged, since no response can reach it.
+func Recovery() router.HandlerFunc {
+	return func(c *router.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			err, _ := recovered.(error)
+			if err != nil && (errors.Is(err, http.ErrAbortHandler) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)) {
+				log.Printf("request %s %s aborted: %v", c.Request.Method, c.Request.URL.Path, err)
+				c.Abort()
+				return
+			}
+			log.Printf("panic serving %s %s: %v\n%s", c.Request.Method, c.Request.URL.Path, recovered, debug.Stack())
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,159 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the redaction of contact fields in JSON responses according to the
+// role of the caller, so that low-privilege credentials such as viewer keys see masked
+// emails and phones, e.g. j***@acme.com, instead of the personal data of the submitters.
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/router"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// RedactionMode is how a redacted field is shown.
+type RedactionMode string
+
+const (
+	// RedactMask keeps a hint of the value, such as the first letter and the domain of an
+	// email or the last four digits of a phone.
+	RedactMask RedactionMode = "mask"
+	// RedactHide leaves the field out of the response.
+	RedactHide RedactionMode = "hide"
+)
+
+// phoneVisibleDigits is the number of trailing characters of a phone kept by RedactMask.
+const phoneVisibleDigits = 4
+
+// RedactionPolicy maps the JSON names of the contact fields redacted for a role, such as
+// email or message, to how they are redacted.
+type RedactionPolicy map[string]RedactionMode
+
+// ParseRedactionPolicy parses a comma-separated list of field names, each optionally
+// followed by ":mask" or ":hide", such as "email,phone,message:hide". Fields without a
+// mode are masked.
+func ParseRedactionPolicy(spec string) (RedactionPolicy, error) {
+	policy := make(RedactionPolicy)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		field, mode, found := strings.Cut(entry, ":")
+		if !found {
+			mode = string(RedactMask)
+		}
+		field = strings.TrimSpace(field)
+		switch RedactionMode(strings.TrimSpace(mode)) {
+		case RedactMask, RedactHide:
+		default:
+			return nil, fmt.Errorf("unknown redaction mode %q for field %q", mode, field)
+		}
+		if field == "" {
+			return nil, fmt.Errorf("missing field name in %q", entry)
+		}
+		policy[field] = RedactionMode(strings.TrimSpace(mode))
+	}
+	return policy, nil
+}
+
+// Redaction redacts the contact fields of the JSON responses sent to callers whose role
+// has a policy in policies. It must run after the Authenticator middleware; responses to
+// anonymous callers and to roles without a policy, and bodies other than JSON, pass
+// through unchanged.
+func Redaction(policies map[models.Role]RedactionPolicy) router.HandlerFunc {
+	return func(c *router.Context) {
+		identity := CurrentIdentity(c)
+		if identity == nil || len(policies[identity.Role]) == 0 {
+			c.Next()
+			return
+		}
+		policy := policies[identity.Role]
+
+		// Buffer the JSON response so that it can be redacted once the handler is done.
+		writer := &compatWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.body.Len() == 0 {
+			return
+		}
+		_, _ = c.Writer.Write(policy.Rewrite(writer.body.Bytes()))
+	}
+}
+
+// Rewrite redacts a JSON body, such as a response or a message pushed to the admin view.
+// Bodies that cannot be decoded are returned unchanged.
+func (p RedactionPolicy) Rewrite(body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // keep integers exact
+	var value interface{}
+	if dec.Decode(&value) != nil {
+		return body
+	}
+
+	out, err := json.Marshal(p.redact(value))
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// redact redacts the fields of every contact object found in value. Contact objects are
+// recognized by their email field, as in the compatibility mode, which keeps the fields of
+// other objects, such as the name of a webhook, untouched.
+func (p RedactionPolicy) redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for i, item := range v {
+			v[i] = p.redact(item)
+		}
+	case map[string]interface{}:
+		_, isContact := v["email"]
+		for key, item := range v {
+			mode, ok := p[key]
+			switch {
+			case !ok || !isContact:
+				v[key] = p.redact(item)
+			case mode == RedactHide:
+				delete(v, key)
+			default:
+				if s, ok := item.(string); ok {
+					v[key] = maskValue(key, s)
+				}
+			}
+		}
+	}
+	return value
+}
+
+// maskValue masks the value of the contact field named field: emails keep the first
+// letter of their local part and their domain, phones their last digits, and other
+// fields their first letter.
+func maskValue(field, value string) string {
+	if value == "" {
+		return value
+	}
+	switch field {
+	case "email":
+		if at := strings.LastIndex(value, "@"); at > 0 {
+			return maskValue("", value[:at]) + value[at:]
+		}
+	case "phone":
+		if len(value) > phoneVisibleDigits {
+			prefix := ""
+			if strings.HasPrefix(value, "+") {
+				prefix = "+"
+			}
+			hidden := len(value) - phoneVisibleDigits - len(prefix)
+			return prefix + strings.Repeat("*", hidden) + value[len(value)-phoneVisibleDigits:]
+		}
+	}
+	_, size := utf8.DecodeRuneInString(value)
+	return value[:size] + "***"
+}
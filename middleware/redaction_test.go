@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"api-contact-form/router/nethttp"
+	"api-contact-form/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRedaction(t *testing.T) {
+	keys := services.NewAPIKeyService(repositories.NewAPIKeyRepository(openDB(t, &models.APIKey{})))
+	_, viewerKey, err := keys.IssueKey("viewer", models.RoleViewer, nil)
+	if err != nil {
+		t.Fatalf("IssueKey: %v", err)
+	}
+	_, adminKey, err := keys.IssueKey("admin", models.RoleAdmin, nil)
+	if err != nil {
+		t.Fatalf("IssueKey: %v", err)
+	}
+
+	viewerPolicy, err := ParseRedactionPolicy("email,phone,message:hide")
+	if err != nil {
+		t.Fatalf("ParseRedactionPolicy: %v", err)
+	}
+	engine := router.New(nethttp.New())
+	engine.Use(NewAuthenticator(keys, nil, nil, nil, time.Hour).Middleware(),
+		Redaction(map[models.Role]RedactionPolicy{models.RoleViewer: viewerPolicy, models.RoleAdmin: {}}))
+	engine.GET("/contacts", func(c *router.Context) {
+		c.JSON(http.StatusOK, responses.APIResponse{
+			Code:    "SUCCESS",
+			Message: "Contacts retrieved",
+			Data: []map[string]any{{
+				"id":      9007199254740993,
+				"name":    "Jane Doe",
+				"email":   "jane.doe@acme.com",
+				"phone":   "+15551234567",
+				"message": "Call me",
+			}},
+		})
+	})
+	engine.GET("/webhooks", func(c *router.Context) {
+		c.JSON(http.StatusOK, responses.APIResponse{Code: "SUCCESS", Data: map[string]any{"phone": "+15551234567"}})
+	})
+
+	const unredacted = `{"code":"SUCCESS","message":"Contacts retrieved","data":[{"email":"jane.doe@acme.com","id":9007199254740993,"message":"Call me","name":"Jane Doe","phone":"+15551234567"}]}`
+	tests := []struct {
+		name, path, key, body string
+	}{
+		{"viewer", "/contacts", viewerKey,
+			`{"code":"SUCCESS","data":[{"email":"j***@acme.com","id":9007199254740993,"name":"Jane Doe","phone":"+*******4567"}],"message":"Contacts retrieved"}`},
+		{"admin", "/contacts", adminKey, unredacted},
+		{"anonymous caller", "/contacts", "", unredacted},
+		{"viewer reading an object that is not a contact", "/webhooks", viewerKey,
+			`{"code":"SUCCESS","data":{"phone":"+15551234567"},"message":""}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("body = %s\nwant %s", w.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestMaskValue(t *testing.T) {
+	tests := []struct {
+		field, value, want string
+	}{
+		{"email", "jane.doe@acme.com", "j***@acme.com"},
+		{"email", "élodie@acme.fr", "é***@acme.fr"},
+		{"email", "not an email", "n***"},
+		{"phone", "+15551234567", "+*******4567"},
+		{"phone", "5551234567", "******4567"},
+		{"phone", "1234", "1***"},
+		{"name", "Jane Doe", "J***"},
+		{"name", "", ""},
+	}
+	for _, tt := range tests {
+		if got := maskValue(tt.field, tt.value); got != tt.want {
+			t.Errorf("maskValue(%q, %q) = %q, want %q", tt.field, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseRedactionPolicy(t *testing.T) {
+	policy, err := ParseRedactionPolicy(" email , message:hide,,phone:mask")
+	if err != nil {
+		t.Fatalf("ParseRedactionPolicy: %v", err)
+	}
+	want := RedactionPolicy{"email": RedactMask, "message": RedactHide, "phone": RedactMask}
+	if len(policy) != len(want) {
+		t.Fatalf("policy = %v, want %v", policy, want)
+	}
+	for field, mode := range want {
+		if policy[field] != mode {
+			t.Errorf("policy[%s] = %q, want %q", field, policy[field], mode)
+		}
+	}
+
+	for _, spec := range []string{"email:blur", ":hide"} {
+		if _, err := ParseRedactionPolicy(spec); err == nil {
+			t.Errorf("ParseRedactionPolicy(%q) succeeded, want an error", spec)
+		}
+	}
+}
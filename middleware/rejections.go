@@ -0,0 +1,76 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the RejectionLog, which records the submissions rejected by
+// the spam protection middleware.
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/responses"
+	"api-contact-form/router"
+	"context"
+	"log"
+)
+
+// RejectionLog stores rejected submissions in the background, so that a flood of bot
+// traffic cannot turn into a flood of synchronous database writes. When its queue is
+// full, rejections are dropped. A nil RejectionLog records nothing.
+type RejectionLog struct {
+	repository repositories.RejectionRepository
+	queue      chan models.RejectedSubmission
+}
+
+// NewRejectionLog creates a RejectionLog keeping at most queueSize rejections waiting to be stored.
+func NewRejectionLog(repository repositories.RejectionRepository, queueSize int) *RejectionLog {
+	return &RejectionLog{
+		repository: repository,
+		queue:      make(chan models.RejectedSubmission, queueSize),
+	}
+}
+
+// Run stores queued rejections until ctx is cancelled.
+func (l *RejectionLog) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case rejection := <-l.queue:
+			if err := l.repository.Create(&rejection); err != nil {
+				log.Printf("Rejected submission not recorded: %v", err)
+			}
+		}
+	}
+}
+
+// Record queues the rejection of the request of c without blocking.
+func (l *RejectionLog) Record(c *router.Context, reason models.RejectionReason) {
+	if l == nil {
+		return
+	}
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	select {
+	case l.queue <- models.RejectedSubmission{
+		Reason:    reason,
+		ClientIP:  c.ClientIP(),
+		UserAgent: userAgent,
+		Path:      c.FullPath(),
+	}:
+	default:
+	}
+}
+
+// reject records the rejection of the request of c and aborts it with the given status code.
+func reject(c *router.Context, rejections *RejectionLog, reason models.RejectionReason, status int, code, message string) {
+	rejections.Record(c, reason)
+	c.AbortWithStatusJSON(status, responses.APIResponse{
+		Code:    code,
+		Message: message,
+		Data:    nil,
+	})
+}
@@ -0,0 +1,138 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the ReplayGuard, which rejects submissions replaying a signed form
+// token or an idempotency key after its validity window. Within the window, form tokens are
+// redeemed once by FormTokens and repeated idempotency keys are answered by Idempotency;
+// once it passed, both would otherwise be forgotten and the submission processed again.
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/router"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// replaySweepInterval is how often Run removes the credentials to forget.
+const replaySweepInterval = time.Hour
+
+// ReplayWindows are the validity windows of the credentials checked by a ReplayGuard.
+// Credentials with a zero window are not checked.
+type ReplayWindows struct {
+	// FormToken is the lifetime of form tokens, FORM_TOKEN_TTL.
+	FormToken time.Duration
+	// IdempotencyKey is the time idempotency keys are remembered, IDEMPOTENCY_TTL.
+	IdempotencyKey time.Duration
+}
+
+// ReplayGuard remembers the credentials of accepted submissions for a retention period
+// beyond their validity window.
+type ReplayGuard struct {
+	repository repositories.SeenCredentialRepository
+	windows    ReplayWindows
+	retention  time.Duration
+	rejections *RejectionLog
+}
+
+// NewReplayGuard creates a ReplayGuard remembering credentials for retention after their
+// validity window, and recording replays in rejections.
+func NewReplayGuard(repository repositories.SeenCredentialRepository, windows ReplayWindows, retention time.Duration, rejections *RejectionLog) *ReplayGuard {
+	return &ReplayGuard{repository: repository, windows: windows, retention: retention, rejections: rejections}
+}
+
+// Run removes the credentials to forget every hour until ctx is cancelled.
+func (g *ReplayGuard) Run(ctx context.Context) {
+	ticker := time.NewTicker(replaySweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := g.repository.DeleteExpired(time.Now()); err != nil {
+				log.Printf("Expired seen credentials not removed: %v", err)
+			}
+		}
+	}
+}
+
+// Middleware rejects submissions carrying a credential accepted before and past its
+// validity window with a 409 status code, and records them as replay rejections, which
+// count towards flagging the later submissions of the client IP as spam. The credentials
+// of submissions answered with a 2xx status code are remembered.
+//
+// Idempotency keys are scoped like in Idempotency, so the middleware must follow the
+// Authenticator. Submissions are processed normally when the credentials cannot be read.
+func (g *ReplayGuard) Middleware() router.HandlerFunc {
+	return func(c *router.Context) {
+		credentials := g.credentials(c)
+		if len(credentials) == 0 {
+			c.Next()
+			return
+		}
+
+		// Reject the submission when one of its credentials was seen and expired.
+		now := time.Now()
+		for _, credential := range credentials {
+			seen, err := g.repository.Find(credential.Hash)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				log.Printf("Seen credential not checked, processing the request without it: %v", err)
+				continue
+			}
+			if now.After(seen.ValidUntil) {
+				log.Printf("Replayed %s rejected from %s, first accepted at %s", seen.Kind, c.ClientIP(), seen.CreatedAt.Format(time.RFC3339))
+				reject(c, g.rejections, models.RejectionReplay, http.StatusConflict,
+					"CONFLICT", "This submission was already made and cannot be replayed")
+				return
+			}
+		}
+
+		c.Next()
+
+		// Remember the credentials of accepted submissions.
+		if status := c.Writer.Status(); status < http.StatusOK || status >= http.StatusMultipleChoices {
+			return
+		}
+		for i := range credentials {
+			if err := g.repository.Remember(&credentials[i]); err != nil {
+				log.Printf("Seen credential not stored: %v", err)
+			}
+		}
+	}
+}
+
+// credentials returns the credentials carried by the request of c whose kind is checked,
+// with the validity window they would get if the submission is accepted now.
+func (g *ReplayGuard) credentials(c *router.Context) []models.SeenCredential {
+	now := time.Now()
+	var credentials []models.SeenCredential
+	add := func(kind models.CredentialKind, hash string, window time.Duration) {
+		credentials = append(credentials, models.SeenCredential{
+			Hash:       hash,
+			Kind:       kind,
+			ValidUntil: now.Add(window),
+			ForgetAt:   now.Add(window + g.retention),
+		})
+	}
+
+	if token := c.GetHeader("X-Form-Token"); token != "" && g.windows.FormToken > 0 {
+		sum := sha256.Sum256([]byte(string(models.CredentialFormToken) + "\n" + token))
+		add(models.CredentialFormToken, hex.EncodeToString(sum[:]), g.windows.FormToken)
+	}
+	if key := c.GetHeader(IdempotencyKeyHeader); key != "" && len(key) <= maxIdempotencyKeyLength && g.windows.IdempotencyKey > 0 {
+		add(models.CredentialIdempotencyKey, hashIdempotencyKey(c, key), g.windows.IdempotencyKey)
+	}
+	return credentials
+}
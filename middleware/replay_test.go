@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/router"
+	"api-contact-form/router/nethttp"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReplayGuard(t *testing.T) {
+	db := openDB(t, &models.SeenCredential{})
+	rejections := NewRejectionLog(nil, 10)
+	guard := NewReplayGuard(repositories.NewSeenCredentialRepository(db),
+		ReplayWindows{FormToken: time.Hour, IdempotencyKey: time.Hour}, 24*time.Hour, rejections)
+	status := http.StatusCreated
+	engine := router.New(nethttp.New())
+	engine.POST("/contacts", guard.Middleware(), func(c *router.Context) {
+		c.Status(status)
+	})
+	send := func(header, value string) int {
+		r := httptest.NewRequest(http.MethodPost, "/contacts", nil)
+		r.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		return w.Code
+	}
+	// expire ends the validity window of the credentials seen so far.
+	expire := func() {
+		t.Helper()
+		if err := db.Model(&models.SeenCredential{}).Where("1 = 1").Update("valid_until", time.Now().Add(-time.Second)).Error; err != nil {
+			t.Fatalf("expire: %v", err)
+		}
+	}
+
+	for _, header := range []string{"X-Form-Token", IdempotencyKeyHeader} {
+		t.Run(header, func(t *testing.T) {
+			// Rejected submissions do not consume the credential.
+			status = http.StatusBadRequest
+			if got := send(header, "rejected"); got != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", got, http.StatusBadRequest)
+			}
+			expire()
+			status = http.StatusCreated
+			if got := send(header, "rejected"); got != http.StatusCreated {
+				t.Errorf("credential of a rejected submission: status = %d, want %d", got, http.StatusCreated)
+			}
+
+			// Within the window, repeats are left to FormTokens and Idempotency.
+			if got := send(header, "accepted"); got != http.StatusCreated {
+				t.Fatalf("status = %d, want %d", got, http.StatusCreated)
+			}
+			if got := send(header, "accepted"); got != http.StatusCreated {
+				t.Errorf("repeat within the window: status = %d, want %d", got, http.StatusCreated)
+			}
+
+			expire()
+			if got := send(header, "accepted"); got != http.StatusConflict {
+				t.Errorf("replay after the window: status = %d, want %d", got, http.StatusConflict)
+			}
+			if reasons := rejected(rejections); !slices.Equal(reasons, []models.RejectionReason{models.RejectionReplay}) {
+				t.Errorf("rejections = %v, want the replay", reasons)
+			}
+		})
+	}
+}
+
+func TestReplayGuardSkipsUncheckedKinds(t *testing.T) {
+	db := openDB(t, &models.SeenCredential{})
+	guard := NewReplayGuard(repositories.NewSeenCredentialRepository(db), ReplayWindows{FormToken: time.Hour}, time.Hour, nil)
+	engine := router.New(nethttp.New())
+	engine.POST("/contacts", guard.Middleware(), func(c *router.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/contacts", nil)
+	r.Header.Set(IdempotencyKeyHeader, "key-1")
+	engine.ServeHTTP(httptest.NewRecorder(), r)
+
+	var count int64
+	if err := db.Model(&models.SeenCredential{}).Count(&count).Error; err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("%d credentials remembered, want none", count)
+	}
+}
@@ -0,0 +1,288 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the ResponseCache, which serves the responses of public GET
+// endpoints, such as the status lookup, from memory for a short while, and sets the
+// Cache-Control and Surrogate-Control headers that let CDNs offload them during spikes.
+// The cache can be warmed up on startup, so that it is not empty after every deploy.
+package middleware
+
+import (
+	"api-contact-form/router"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCachedBodySize is the size of the largest response body kept by the ResponseCache.
+const maxCachedBodySize = 64 << 10
+
+// CachePolicy is how long the responses of an endpoint may be cached.
+type CachePolicy struct {
+	// MaxAge is how long browsers may reuse a response, and SharedMaxAge how long CDNs and
+	// the ResponseCache may; a zero SharedMaxAge uses MaxAge. A zero policy forbids caching.
+	MaxAge       time.Duration
+	SharedMaxAge time.Duration
+	// StaleWhileRevalidate is how long CDNs may serve an expired response while they fetch
+	// a fresh one.
+	StaleWhileRevalidate time.Duration
+	// SurrogateKey tags the responses at CDNs supporting it, so that they can be purged
+	// together.
+	SurrogateKey string
+	// QueryParams lists the query parameters the handler reads. Responses are cached per
+	// URL path and value of these parameters; any other parameter is ignored, so that
+	// arbitrary query strings cannot fill the ResponseCache.
+	QueryParams []string
+}
+
+// shared returns how long shared caches may reuse a response.
+func (p CachePolicy) shared() time.Duration {
+	if p.SharedMaxAge > 0 {
+		return p.SharedMaxAge
+	}
+	return p.MaxAge
+}
+
+// cacheControl returns the Cache-Control header of the policy.
+func (p CachePolicy) cacheControl() string {
+	if p.shared() <= 0 {
+		return "no-store"
+	}
+	directives := []string{"public", fmt.Sprintf("max-age=%d", int(p.MaxAge.Seconds()))}
+	if p.SharedMaxAge > 0 {
+		directives = append(directives, fmt.Sprintf("s-maxage=%d", int(p.SharedMaxAge.Seconds())))
+	}
+	if p.StaleWhileRevalidate > 0 {
+		directives = append(directives, fmt.Sprintf("stale-while-revalidate=%d", int(p.StaleWhileRevalidate.Seconds())))
+	}
+	return strings.Join(directives, ", ")
+}
+
+// cachedResponse is a response kept by the ResponseCache.
+type cachedResponse struct {
+	key         string
+	contentType string
+	etag        string
+	body        []byte
+	storedAt    time.Time
+	expiresAt   time.Time
+}
+
+// ResponseCache keeps the successful responses of public endpoints in memory, keyed by their
+// URL path and the query parameters of their CachePolicy. When it is full, the least
+// recently used response is forgotten. It is safe for concurrent use, and a nil
+// ResponseCache only sets the headers.
+type ResponseCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// recent orders the *cachedResponse values of entries, most recently used first.
+	recent *list.List
+}
+
+// NewResponseCache creates a ResponseCache keeping up to maxEntries responses.
+func NewResponseCache(maxEntries int) *ResponseCache {
+	return &ResponseCache{maxEntries: maxEntries, entries: make(map[string]*list.Element), recent: list.New()}
+}
+
+// Middleware caches the responses of the route according to policy. Requests carrying
+// credentials bypass the cache and are answered as private, as their responses may differ.
+// Responses served from memory carry an Age header, and are answered with a 304 status
+// code when the client already has them.
+func (rc *ResponseCache) Middleware(policy CachePolicy) router.HandlerFunc {
+	return func(c *router.Context) {
+		if credential, _ := credentials(c); credential != "" {
+			c.Header("Cache-Control", "private, no-store")
+			c.Next()
+			return
+		}
+		c.Header("Cache-Control", policy.cacheControl())
+		if policy.shared() > 0 {
+			c.Header("Surrogate-Control", fmt.Sprintf("max-age=%d", int(policy.shared().Seconds())))
+			if policy.SurrogateKey != "" {
+				c.Header("Surrogate-Key", policy.SurrogateKey)
+			}
+		}
+		if rc == nil || policy.shared() <= 0 || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		// Serve the response from memory while it is fresh.
+		key := cacheKey(c.Request.URL, policy.QueryParams)
+		now := time.Now()
+		if cached := rc.get(key, now); cached != nil {
+			c.Header("Age", strconv.Itoa(int(now.Sub(cached.storedAt).Seconds())))
+			c.Header("ETag", cached.etag)
+			c.Header("X-Cache", "HIT")
+			if c.GetHeader("If-None-Match") == cached.etag {
+				c.AbortWithStatus(http.StatusNotModified)
+				return
+			}
+			c.Data(http.StatusOK, cached.contentType, cached.body)
+			c.Abort()
+			return
+		}
+
+		// Write the response through, keeping a copy of it.
+		c.Header("X-Cache", "MISS")
+		writer := &cacheWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.Status() != http.StatusOK || writer.overflow {
+			return
+		}
+		sum := sha256.Sum256(writer.body)
+		rc.put(key, &cachedResponse{
+			contentType: writer.Header().Get("Content-Type"),
+			etag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+			body:        writer.body,
+			storedAt:    now,
+			expiresAt:   now.Add(policy.shared()),
+		})
+	}
+}
+
+// Warm fills the cache with the responses of handler, the API routing through the
+// Middleware of the cache, to GET requests for paths, such as right after a deploy. It
+// stops once ctx is done, and returns the number of successful responses, which are the
+// ones cached.
+func (rc *ResponseCache) Warm(ctx context.Context, handler http.Handler, paths []string) int {
+	if rc == nil {
+		return 0
+	}
+	warmed := 0
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			continue
+		}
+		writer := &discardWriter{header: http.Header{}}
+		handler.ServeHTTP(writer, req)
+		if writer.status == http.StatusOK {
+			warmed++
+		}
+	}
+	return warmed
+}
+
+// discardWriter is an http.ResponseWriter keeping the status code of the responses to the
+// requests made by Warm, and discarding their body.
+type discardWriter struct {
+	header http.Header
+	status int
+}
+
+// Header returns the headers of the response.
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+// Write discards data, answering with a 200 status code when none was written.
+func (w *discardWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return len(data), nil
+}
+
+// WriteHeader keeps the first status code written.
+func (w *discardWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// cacheKey returns the key of the response to the request for u: its path, followed by the
+// values of params in a canonical order.
+func cacheKey(u *url.URL, params []string) string {
+	if len(params) == 0 {
+		return u.Path
+	}
+	query := u.Query()
+	kept := url.Values{}
+	for _, param := range params {
+		if values, ok := query[param]; ok {
+			kept[param] = values
+		}
+	}
+	return u.Path + "?" + kept.Encode()
+}
+
+// get returns the fresh response stored for key, or nil. An expired response is forgotten.
+func (rc *ResponseCache) get(key string, now time.Time) *cachedResponse {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	element, ok := rc.entries[key]
+	if !ok {
+		return nil
+	}
+	cached := element.Value.(*cachedResponse)
+	if !now.Before(cached.expiresAt) {
+		rc.recent.Remove(element)
+		delete(rc.entries, key)
+		return nil
+	}
+	rc.recent.MoveToFront(element)
+	return cached
+}
+
+// put stores response for key, forgetting the least recently used responses beyond
+// maxEntries.
+func (rc *ResponseCache) put(key string, response *cachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.maxEntries <= 0 {
+		return
+	}
+	response.key = key
+	if element, ok := rc.entries[key]; ok {
+		element.Value = response
+		rc.recent.MoveToFront(element)
+		return
+	}
+	rc.entries[key] = rc.recent.PushFront(response)
+	for len(rc.entries) > rc.maxEntries {
+		oldest := rc.recent.Back()
+		rc.recent.Remove(oldest)
+		delete(rc.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// cacheWriter writes the response through and keeps a copy of its body, up to
+// maxCachedBodySize.
+type cacheWriter struct {
+	router.ResponseWriter
+	body     []byte
+	overflow bool
+}
+
+// Write writes data through and appends it to the copy.
+func (w *cacheWriter) Write(data []byte) (int, error) {
+	if len(w.body)+len(data) > maxCachedBodySize {
+		w.overflow = true
+	} else if !w.overflow {
+		w.body = append(w.body, data...)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes s through and appends it to the copy.
+func (w *cacheWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
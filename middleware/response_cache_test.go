@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"api-contact-form/router"
+	"api-contact-form/router/nethttp"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// cachedEngine routes GET /status/:id through the middleware of cache with policy. The
+// handler answers with the number of times it was called, and the caller when the request
+// carries an API key.
+func cachedEngine(cache *ResponseCache, policy CachePolicy) *router.Engine {
+	calls := 0
+	engine := router.New(nethttp.New())
+	engine.GET("/status/:id", cache.Middleware(policy), func(c *router.Context) {
+		calls++
+		body := fmt.Sprintf("%s %d", c.Param("id"), calls)
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			body += " for " + key
+		}
+		if c.Query("fail") != "" {
+			c.Data(http.StatusNotFound, "text/plain", []byte(body))
+			return
+		}
+		c.Data(http.StatusOK, "text/plain", []byte(body))
+	})
+	return engine
+}
+
+// get requests target from engine with the given header.
+func get(engine http.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	return w
+}
+
+func TestResponseCache(t *testing.T) {
+	engine := cachedEngine(NewResponseCache(10), CachePolicy{
+		MaxAge:       time.Minute,
+		SharedMaxAge: 5 * time.Minute,
+		SurrogateKey: "status",
+		QueryParams:  []string{"lang", "fail"},
+	})
+
+	first := get(engine, "/status/a", nil)
+	if first.Body.String() != "a 1" || first.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("first request = %q %s, want a miss", first.Body, first.Header().Get("X-Cache"))
+	}
+	for header, want := range map[string]string{
+		"Cache-Control":     "public, max-age=60, s-maxage=300",
+		"Surrogate-Control": "max-age=300",
+		"Surrogate-Key":     "status",
+	} {
+		if got := first.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	second := get(engine, "/status/a", nil)
+	if second.Body.String() != "a 1" || second.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("second request = %q %s, want a hit", second.Body, second.Header().Get("X-Cache"))
+	}
+	if second.Header().Get("Age") != "0" || second.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("hit: Age = %q, Content-Type = %q", second.Header().Get("Age"), second.Header().Get("Content-Type"))
+	}
+
+	etag := second.Header().Get("ETag")
+	if w := get(engine, "/status/a", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("conditional request = %d %q, want %d", w.Code, w.Body, http.StatusNotModified)
+	}
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	engine := cachedEngine(NewResponseCache(10), CachePolicy{MaxAge: time.Minute, QueryParams: []string{"lang", "fail"}})
+
+	tests := []struct {
+		target, body string
+	}{
+		{"/status/a", "a 1"},
+		{"/status/b", "b 2"},
+		{"/status/a?utm_source=mail", "a 1"},
+		{"/status/a?lang=fr", "a 3"},
+		{"/status/a?utm_source=mail&lang=fr", "a 3"},
+		{"/status/a?lang=fr&fail=", "a 4"},
+		{"/status/a?fail=&lang=fr", "a 4"},
+		{"/status/a?lang=de", "a 5"},
+		// Error responses are not kept.
+		{"/status/c?fail=1", "c 6"},
+		{"/status/c?fail=1", "c 7"},
+	}
+	for _, tt := range tests {
+		if w := get(engine, tt.target, nil); w.Body.String() != tt.body {
+			t.Errorf("GET %s = %q, want %q", tt.target, w.Body, tt.body)
+		}
+	}
+}
+
+func TestResponseCacheBypassesAuthenticatedRequests(t *testing.T) {
+	engine := cachedEngine(NewResponseCache(10), CachePolicy{MaxAge: time.Minute})
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   string
+		cache  string
+	}{
+		{"API key", http.Header{"X-Api-Key": {"admin"}}, "a 1 for admin", ""},
+		{"anonymous request after an authenticated one", nil, "a 2", "MISS"},
+		{"API key after an anonymous request", http.Header{"X-Api-Key": {"admin"}}, "a 3 for admin", ""},
+		{"bearer token after an anonymous request", http.Header{"Authorization": {"Bearer token"}}, "a 4", ""},
+		{"anonymous request", nil, "a 2", "HIT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(engine, "/status/a", tt.header)
+			if w.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", w.Body, tt.body)
+			}
+			if got := w.Header().Get("X-Cache"); got != tt.cache {
+				t.Errorf("X-Cache = %q, want %q", got, tt.cache)
+			}
+			wantControl := "public, max-age=60"
+			if tt.header != nil {
+				wantControl = "private, no-store"
+			}
+			if got := w.Header().Get("Cache-Control"); got != wantControl {
+				t.Errorf("Cache-Control = %q, want %q", got, wantControl)
+			}
+		})
+	}
+}
+
+func TestResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	engine := cachedEngine(NewResponseCache(2), CachePolicy{MaxAge: time.Minute})
+
+	tests := []struct {
+		path, body string
+	}{
+		{"/status/a", "a 1"},
+		{"/status/b", "b 2"},
+		{"/status/a", "a 1"}, // a is now more recent than b
+		{"/status/c", "c 3"}, // b is evicted
+		{"/status/a", "a 1"},
+		{"/status/c", "c 3"},
+		{"/status/b", "b 4"}, // a is evicted
+		{"/status/a", "a 5"},
+	}
+	for _, tt := range tests {
+		if w := get(engine, tt.path, nil); w.Body.String() != tt.body {
+			t.Errorf("GET %s = %q, want %q", tt.path, w.Body, tt.body)
+		}
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	cache := NewResponseCache(10)
+	now := time.Now()
+	cache.put("/status/a", &cachedResponse{body: []byte("a"), storedAt: now, expiresAt: now.Add(time.Minute)})
+
+	if cache.get("/status/a", now.Add(59*time.Second)) == nil {
+		t.Error("fresh response not found")
+	}
+	if cache.get("/status/a", now.Add(time.Minute)) != nil {
+		t.Error("expired response found")
+	}
+	if len(cache.entries) != 0 || cache.recent.Len() != 0 {
+		t.Errorf("expired response kept: %d entries", len(cache.entries))
+	}
+}
+
+func TestResponseCacheWithoutCaching(t *testing.T) {
+	for name, engine := range map[string]*router.Engine{
+		"nil cache":   cachedEngine(nil, CachePolicy{MaxAge: time.Minute}),
+		"zero policy": cachedEngine(NewResponseCache(10), CachePolicy{}),
+	} {
+		get(engine, "/status/a", nil)
+		w := get(engine, "/status/a", nil)
+		if w.Body.String() != "a 2" || w.Header().Get("X-Cache") != "" {
+			t.Errorf("%s: second request = %q %s, want it served by the handler", name, w.Body, w.Header().Get("X-Cache"))
+		}
+		if name == "zero policy" && w.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("%s: Cache-Control = %q, want no-store", name, w.Header().Get("Cache-Control"))
+		}
+	}
+}
+
+func TestResponseCacheWarm(t *testing.T) {
+	cache := NewResponseCache(10)
+	engine := cachedEngine(cache, CachePolicy{MaxAge: time.Minute})
+
+	warmed := cache.Warm(context.Background(), engine, []string{"/status/1", "/status/2", "/status/3?fail=1"})
+	if warmed != 2 {
+		t.Fatalf("Warm() = %d, want 2", warmed)
+	}
+
+	// The first requests are served from memory
+	for _, id := range []string{"1", "2"} {
+		w := get(engine, "/status/"+id, nil)
+		if w.Header().Get("X-Cache") != "HIT" || w.Body.String() != id+" "+id {
+			t.Errorf("GET /status/%s = %s %q, want a HIT of the warmed response", id, w.Header().Get("X-Cache"), w.Body.String())
+		}
+	}
+	if w := get(engine, "/status/3", nil); w.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("GET /status/3 = %s, want a MISS as the warmup failed", w.Header().Get("X-Cache"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if warmed := cache.Warm(ctx, engine, []string{"/status/4"}); warmed != 0 {
+		t.Errorf("Warm() with a cancelled context = %d, want 0", warmed)
+	}
+	if warmed := (*ResponseCache)(nil).Warm(context.Background(), engine, []string{"/status/4"}); warmed != 0 {
+		t.Errorf("Warm() of a nil cache = %d, want 0", warmed)
+	}
+}
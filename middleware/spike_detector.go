@@ -0,0 +1,183 @@
+// Package middleware provides the middleware shared by the routes of the API.
+//
+// This file implements the SpikeDetector, which compares the rate of submissions to its
+// rolling baseline to detect the spikes of a spam attack or a viral campaign.
+package middleware
+
+import (
+	"api-contact-form/observability"
+	"api-contact-form/router"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpikeConfig holds the settings of a SpikeDetector.
+type SpikeConfig struct {
+	// Interval is the period over which submissions are counted and compared to the baseline.
+	Interval time.Duration
+	// BaselineIntervals is the number of past intervals averaged into the baseline.
+	BaselineIntervals int
+	// Factor is how many times the baseline an interval must exceed to be a spike.
+	Factor float64
+	// MinSubmissions is the fewest submissions in an interval reported as a spike, so that
+	// a handful of submissions to a quiet form raise no alert.
+	MinSubmissions int
+}
+
+// Spike describes the start or the end of a spike.
+type Spike struct {
+	// Active is true when the spike starts, and false when it ends.
+	Active bool
+	// Submissions is the number of submissions in the last interval.
+	Submissions int
+	// Baseline is the average number of submissions per interval before the spike.
+	Baseline float64
+	// Interval is the period over which submissions are counted.
+	Interval time.Duration
+	// StartedAt is the end of the first interval of the spike.
+	StartedAt time.Time
+}
+
+// Summary describes the submissions of the last interval compared to the baseline, such as
+// "250 submissions in the last 1m, against a baseline of 12.5".
+func (s Spike) Summary() string {
+	return fmt.Sprintf("%d submissions in the last %s, against a baseline of %.1f",
+		s.Submissions, shortDuration(s.Interval), s.Baseline)
+}
+
+// SpikeDetector counts the submissions and, at the end of every interval, compares their
+// number to the average of the previous intervals. An interval with more than Factor times
+// the baseline, and at least MinSubmissions, starts a spike, which lasts until an interval
+// is back under that limit. The intervals of a spike are left out of the baseline, so that
+// a lasting attack does not become the norm. No spike is detected until the baseline
+// covers a quarter of BaselineIntervals. It is safe for concurrent use.
+type SpikeDetector struct {
+	config   SpikeConfig
+	onChange func(Spike)
+	count    atomic.Int64
+
+	mu      sync.Mutex
+	history []int
+	spike   *Spike
+}
+
+// NewSpikeDetector creates a SpikeDetector calling onChange when a spike starts and when
+// it ends.
+func NewSpikeDetector(cfg SpikeConfig, onChange func(Spike)) (*SpikeDetector, error) {
+	switch {
+	case cfg.Interval <= 0:
+		return nil, errors.New("spike detection interval must be positive")
+	case cfg.BaselineIntervals < 1:
+		return nil, errors.New("spike baseline must cover at least one interval")
+	case cfg.Factor <= 1:
+		return nil, fmt.Errorf("spike factor %v must be greater than 1", cfg.Factor)
+	}
+	return &SpikeDetector{config: cfg, onChange: onChange}, nil
+}
+
+// Middleware counts every submission attempt, including those rejected afterwards.
+func (d *SpikeDetector) Middleware() router.HandlerFunc {
+	return func(c *router.Context) {
+		d.count.Add(1)
+		c.Next()
+	}
+}
+
+// Run compares the submissions to the baseline at the end of every interval, until ctx
+// is cancelled.
+func (d *SpikeDetector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.evaluate(now)
+		}
+	}
+}
+
+// Active reports whether a spike is in progress.
+func (d *SpikeDetector) Active() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.spike != nil
+}
+
+// evaluate closes the interval ending at now, and starts or ends a spike.
+func (d *SpikeDetector) evaluate(now time.Time) {
+	submissions := int(d.count.Swap(0))
+
+	d.mu.Lock()
+	baseline := d.baseline()
+	spiking := len(d.history) >= max(1, d.config.BaselineIntervals/4) &&
+		submissions >= d.config.MinSubmissions &&
+		float64(submissions) > d.config.Factor*baseline
+
+	// Keep the quiet intervals in the baseline.
+	if !spiking {
+		d.history = append(d.history, submissions)
+		if len(d.history) > d.config.BaselineIntervals {
+			d.history = d.history[1:]
+		}
+	}
+
+	// Report the start or the end of a spike.
+	var change *Spike
+	switch {
+	case spiking && d.spike == nil:
+		d.spike = &Spike{Active: true, Submissions: submissions, Baseline: baseline, Interval: d.config.Interval, StartedAt: now}
+		change = d.spike
+	case !spiking && d.spike != nil:
+		change = &Spike{Submissions: submissions, Baseline: d.spike.Baseline, Interval: d.config.Interval, StartedAt: d.spike.StartedAt}
+		d.spike = nil
+	}
+	d.mu.Unlock()
+
+	if change == nil {
+		return
+	}
+	if change.Active {
+		observability.SubmissionSpike.Set(1)
+		log.Printf("Warning: submission spike detected: %s", change.Summary())
+	} else {
+		observability.SubmissionSpike.Set(0)
+		log.Printf("Submission spike ended after %s: %s", now.Sub(change.StartedAt).Round(time.Second), change.Summary())
+	}
+	if d.onChange != nil {
+		d.onChange(*change)
+	}
+}
+
+// baseline returns the average number of submissions per interval in the history. The
+// caller must hold mu.
+func (d *SpikeDetector) baseline() float64 {
+	if len(d.history) == 0 {
+		return 0
+	}
+	total := 0
+	for _, count := range d.history {
+		total += count
+	}
+	return float64(total) / float64(len(d.history))
+}
+
+// shortDuration formats d without its zero trailing units, such as "5m" rather than "5m0s".
+func shortDuration(d time.Duration) string {
+	text := d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
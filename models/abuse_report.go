@@ -0,0 +1,88 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// AbuseReport is a report, sent by a visitor of a site embedding the contact form, that the
+// form is being misused, such as for phishing or to impersonate another organization. It
+// stays open, flagged for the admins of the instance, until one of them resolves or
+// dismisses it.
+package models
+
+import "time"
+
+// AbuseCategory is the kind of misuse an abuse report is about.
+type AbuseCategory string
+
+const (
+	// AbuseSpam is used when the form sends unsolicited messages.
+	AbuseSpam AbuseCategory = "spam"
+	// AbusePhishing is used when the form collects credentials or payment details.
+	AbusePhishing AbuseCategory = "phishing"
+	// AbuseImpersonation is used when the form pretends to belong to someone else.
+	AbuseImpersonation AbuseCategory = "impersonation"
+	// AbuseHarassment is used when the form is used to harass people.
+	AbuseHarassment AbuseCategory = "harassment"
+	// AbuseOther is used for any other misuse, described in the details.
+	AbuseOther AbuseCategory = "other"
+)
+
+// Valid reports whether c is one of the known categories.
+func (c AbuseCategory) Valid() bool {
+	switch c {
+	case AbuseSpam, AbusePhishing, AbuseImpersonation, AbuseHarassment, AbuseOther:
+		return true
+	}
+	return false
+}
+
+// AbuseReportStatus is the handling status of an abuse report.
+type AbuseReportStatus string
+
+const (
+	// AbuseReportOpen is the status of reports waiting for an admin.
+	AbuseReportOpen AbuseReportStatus = "open"
+	// AbuseReportResolved is the status of reports an admin acted upon.
+	AbuseReportResolved AbuseReportStatus = "resolved"
+	// AbuseReportDismissed is the status of reports an admin found unfounded.
+	AbuseReportDismissed AbuseReportStatus = "dismissed"
+)
+
+// Valid reports whether s is one of the known statuses.
+func (s AbuseReportStatus) Valid() bool {
+	return s == AbuseReportOpen || s == AbuseReportResolved || s == AbuseReportDismissed
+}
+
+// AbuseReport represents a report of the misuse of an embedded form.
+type AbuseReport struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// PageURL is the address of the page embedding the reported form.
+	PageURL string `gorm:"column:page_url;type:VARCHAR(2048);not null" json:"page_url"`
+
+	// Category is the kind of misuse, and Details its description by the reporter.
+	Category AbuseCategory `gorm:"column:category;type:VARCHAR(20);not null" json:"category"`
+	Details  string        `gorm:"column:details;type:TEXT;not null" json:"details"`
+
+	// ReporterEmail is the optional address the reporter may be contacted at.
+	ReporterEmail string `gorm:"column:reporter_email;type:VARCHAR(100);not null;default:''" json:"reporter_email"`
+
+	// ClientIP and UserAgent identify the client the report was received from.
+	ClientIP  string `gorm:"column:client_ip;type:VARCHAR(45);not null" json:"client_ip"`
+	UserAgent string `gorm:"column:user_agent;type:VARCHAR(255);not null;default:''" json:"user_agent"`
+
+	// Status is the handling status; the index serves the listing of open reports.
+	Status AbuseReportStatus `gorm:"column:status;type:VARCHAR(20);not null;default:open;index" json:"status"`
+
+	// ResolvedBy is the actor who closed the report, as recorded in the audit trail, and
+	// ResolvedAt the time they did; both are empty while the report is open.
+	ResolvedBy string     `gorm:"column:resolved_by;type:VARCHAR(100);not null;default:''" json:"resolved_by"`
+	ResolvedAt *time.Time `gorm:"column:resolved_at" json:"resolved_at"`
+
+	// CreatedAt / UpdatedAt are automatically maintained by GORM.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (AbuseReport) TableName() string {
+	return "abuse_reports"
+}
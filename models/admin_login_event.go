@@ -0,0 +1,63 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// AdminLoginEvent is an entry of the audit log of the sign-in attempts of admin users:
+// successful sign-ins, wrong credentials, and the lockouts they caused. Attempts refused
+// while a lockout lasts are only counted in the metrics, so that a brute-force attack
+// cannot flood the log.
+package models
+
+import "time"
+
+// LoginOutcome is the result of a sign-in attempt.
+type LoginOutcome string
+
+const (
+	// LoginSucceeded is used for attempts with the right credentials.
+	LoginSucceeded LoginOutcome = "success"
+	// LoginFailed is used for attempts with a wrong email address, password or code.
+	LoginFailed LoginOutcome = "failure"
+	// LoginAccountLocked is used for the failed attempt that locked the account.
+	LoginAccountLocked LoginOutcome = "account_locked"
+	// LoginIPBlocked is used for the failed attempt that blocked the client IP address.
+	LoginIPBlocked LoginOutcome = "ip_blocked"
+)
+
+// Valid reports whether o is one of the known outcomes.
+func (o LoginOutcome) Valid() bool {
+	switch o {
+	case LoginSucceeded, LoginFailed, LoginAccountLocked, LoginIPBlocked:
+		return true
+	}
+	return false
+}
+
+// AdminLoginEvent represents a sign-in attempt of an admin user.
+type AdminLoginEvent struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// UserID is the admin user signing in, or nil for unknown email addresses. Events are
+	// removed together with their user.
+	UserID *uint      `gorm:"column:user_id;index" json:"user_id"`
+	User   *AdminUser `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+
+	// Email is the email address the attempt was made with, in lower case.
+	Email string `gorm:"column:email;type:VARCHAR(100);not null;index" json:"email"`
+
+	// Outcome is the result of the attempt.
+	Outcome LoginOutcome `gorm:"column:outcome;type:VARCHAR(20);not null;index" json:"outcome"`
+
+	// ClientIP is the IP address the attempt was received from.
+	ClientIP string `gorm:"column:client_ip;type:VARCHAR(45);index" json:"client_ip"`
+
+	// UserAgent is the User-Agent header of the attempt, truncated to 255 characters.
+	UserAgent string `gorm:"column:user_agent;type:VARCHAR(255)" json:"user_agent"`
+
+	// CreatedAt is the time of the attempt.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;index" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (AdminLoginEvent) TableName() string {
+	return "admin_login_events"
+}
@@ -0,0 +1,33 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// AdminRecoveryCode is a single-use code letting an admin user with two-factor
+// authentication sign in without their authenticator app. Only the hash of the code
+// is stored; the codes are shown once, when they are generated.
+package models
+
+import "time"
+
+// AdminRecoveryCode represents a recovery code of an admin user.
+type AdminRecoveryCode struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// UserID is the admin user the code belongs to. Codes are removed together with
+	// their user.
+	UserID uint       `gorm:"column:user_id;not null;index" json:"user_id"`
+	User   *AdminUser `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+
+	// CodeHash is the hex SHA-256 of the normalized code.
+	CodeHash string `gorm:"column:code_hash;type:VARCHAR(64);not null" json:"-"`
+
+	// UsedAt is the time the code was used to sign in, if it was.
+	UsedAt *time.Time `gorm:"column:used_at" json:"used_at"`
+
+	// CreatedAt is automatically maintained by GORM.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (AdminRecoveryCode) TableName() string {
+	return "admin_recovery_codes"
+}
@@ -0,0 +1,50 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// AdminSession is a signed-in device of an admin user. It is created at sign-in and holds
+// the refresh token that renews the short-lived access tokens of the device; the access
+// tokens carry the session ID, so that revoking the session signs the device out at once.
+package models
+
+import "time"
+
+// AdminSession represents a session of an admin user.
+type AdminSession struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// UserID is the admin user the session belongs to. Sessions are removed together
+	// with their user.
+	UserID uint       `gorm:"column:user_id;not null;index" json:"user_id"`
+	User   *AdminUser `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+
+	// RefreshTokenHash is the hex SHA-256 of the current refresh token. It changes every
+	// time the token is used.
+	RefreshTokenHash string `gorm:"column:refresh_token_hash;type:VARCHAR(64);not null;uniqueIndex" json:"-"`
+
+	// UserAgent and IP describe the device the session was last used from.
+	UserAgent string `gorm:"column:user_agent;type:VARCHAR(255);not null;default:''" json:"user_agent"`
+	IP        string `gorm:"column:ip;type:VARCHAR(45);not null;default:''" json:"ip"`
+
+	// LastUsedAt is the last time the session was used, to within a minute.
+	LastUsedAt time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+
+	// ExpiresAt is the time after which the refresh token is no longer accepted. It is
+	// extended every time the token is used.
+	ExpiresAt time.Time `gorm:"column:expires_at;not null" json:"expires_at"`
+
+	// RevokedAt is the time the session was signed out, if it was.
+	RevokedAt *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+
+	// CreatedAt is automatically maintained by GORM.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (AdminSession) TableName() string {
+	return "admin_sessions"
+}
+
+// Active reports whether the session is neither revoked nor expired at now.
+func (s *AdminSession) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
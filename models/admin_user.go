@@ -0,0 +1,83 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// AdminUser is a person allowed to sign in to the admin tools with an email address and
+// a password. Users are invited by another admin and choose their password through a
+// one-time setup link; only the hashes of the password and of the setup token are stored.
+// Users may add a second factor: a TOTP authenticator app, with single-use recovery codes.
+// Accounts are locked for a while after too many failed sign-in attempts.
+package models
+
+import "time"
+
+// AdminUser represents an administrator account.
+type AdminUser struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// Email is the sign-in name of the user, stored in lower case.
+	Email string `gorm:"column:email;type:VARCHAR(100);not null;uniqueIndex" json:"email"`
+
+	// Name is the display name of the user.
+	Name string `gorm:"column:name;type:VARCHAR(100);not null" json:"name"`
+
+	// PasswordHash is the bcrypt hash of the password. It is empty until the user
+	// completes the setup, and again after a password reset.
+	PasswordHash string `gorm:"column:password_hash;type:VARCHAR(100);not null;default:''" json:"-"`
+
+	// SetupTokenHash is the hex SHA-256 of the pending one-time setup token, if any.
+	SetupTokenHash *string `gorm:"column:setup_token_hash;type:VARCHAR(64);uniqueIndex" json:"-"`
+
+	// SetupExpiresAt is the time after which the pending setup token is no longer accepted.
+	SetupExpiresAt *time.Time `gorm:"column:setup_expires_at" json:"-"`
+
+	// DisabledAt is the time the user was disabled, if they are. Disabled users cannot
+	// sign in, and the tokens issued to them are no longer accepted.
+	DisabledAt *time.Time `gorm:"column:disabled_at" json:"disabled_at"`
+
+	// TOTPSecret is the base32 secret of the authenticator app of the user. It is set when
+	// the user starts enrolling and only required at sign-in once TOTPEnabledAt is set.
+	TOTPSecret string `gorm:"column:totp_secret;type:VARCHAR(64);not null;default:''" json:"-"`
+
+	// TOTPEnabledAt is the time the user activated two-factor authentication, if they did.
+	TOTPEnabledAt *time.Time `gorm:"column:totp_enabled_at" json:"totp_enabled_at"`
+
+	// TOTPLastStep is the time step of the last code accepted, so that a code cannot be
+	// used twice.
+	TOTPLastStep int64 `gorm:"column:totp_last_step;not null;default:0" json:"-"`
+
+	// FailedLogins is the number of failed sign-in attempts since the last successful one
+	// or the last lockout.
+	FailedLogins int `gorm:"column:failed_logins;not null;default:0" json:"-"`
+
+	// LockedUntil is the end of the lockout of the account after too many failed sign-in
+	// attempts, if any. The account cannot sign in until then.
+	LockedUntil *time.Time `gorm:"column:locked_until" json:"locked_until"`
+
+	// LastLoginAt is the last time the user signed in.
+	LastLoginAt *time.Time `gorm:"column:last_login_at" json:"last_login_at"`
+
+	// CreatedAt and UpdatedAt are automatically maintained by GORM.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (AdminUser) TableName() string {
+	return "admin_users"
+}
+
+// SetupPending reports whether the user has a setup link they have not used yet.
+func (u *AdminUser) SetupPending() bool {
+	return u.SetupTokenHash != nil
+}
+
+// TwoFactorEnabled reports whether the user must enter a code from their authenticator
+// app, or a recovery code, to sign in.
+func (u *AdminUser) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// Locked reports whether the account is locked out at now.
+func (u *AdminUser) Locked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
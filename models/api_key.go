@@ -0,0 +1,62 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// APIKey is a credential issued to an integration or an administrator. Only the
+// SHA-256 hash of the key is stored; the key itself is shown once, when it is issued.
+package models
+
+import "time"
+
+// Role is the set of permissions granted to a credential.
+type Role string
+
+const (
+	// RolePublic may only submit contacts. Submissions made with a public key are
+	// recorded with ChannelAPI.
+	RolePublic Role = "public"
+	// RoleViewer may read contacts and the inbox, with the fields redacted for viewers,
+	// but not change them.
+	RoleViewer Role = "viewer"
+	// RoleAdmin may read, update, delete and export contacts and manage API keys.
+	RoleAdmin Role = "admin"
+)
+
+// Valid reports whether r is one of the known roles.
+func (r Role) Valid() bool {
+	return r == RolePublic || r == RoleViewer || r == RoleAdmin
+}
+
+// APIKey represents an API key issued through the API.
+type APIKey struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// Name describes who or what uses the key.
+	Name string `gorm:"column:name;type:VARCHAR(100);not null" json:"name"`
+
+	// Prefix is the start of the key, kept so that administrators can recognize a key
+	// without it being stored.
+	Prefix string `gorm:"column:prefix;type:VARCHAR(12);not null" json:"prefix"`
+
+	// KeyHash is the hex SHA-256 of the key, used to look it up when it is presented.
+	KeyHash string `gorm:"column:key_hash;type:VARCHAR(64);not null;uniqueIndex" json:"-"`
+
+	// Role is the set of permissions granted by the key.
+	Role Role `gorm:"column:role;type:VARCHAR(20);not null" json:"role"`
+
+	// ExpiresAt is the time after which the key is no longer accepted, if any.
+	ExpiresAt *time.Time `gorm:"column:expires_at" json:"expires_at"`
+
+	// LastUsedAt is the last time the key was presented, updated at most once a minute.
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+
+	// RevokedAt is the time the key was revoked, if it was.
+	RevokedAt *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+
+	// CreatedAt is automatically maintained by GORM.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (APIKey) TableName() string {
+	return "api_keys"
+}
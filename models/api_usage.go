@@ -0,0 +1,38 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// APIUsage rolls up the requests made to an endpoint by a caller during an hour, so that
+// noisy integrations can be diagnosed and quota tiers planned without storing every
+// request.
+package models
+
+import "time"
+
+// APIUsage represents the requests made to an endpoint by a caller during an hour.
+type APIUsage struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// Hour is the start of the hour the requests were made in, in UTC.
+	Hour time.Time `gorm:"column:hour;not null;uniqueIndex:idx_api_usage_rollup,priority:1" json:"hour"`
+
+	// Caller identifies who made the requests: the subject of the API key or token, such
+	// as "key:2" or "user:1", or "anonymous" for public requests.
+	Caller string `gorm:"column:caller;type:VARCHAR(100);not null;uniqueIndex:idx_api_usage_rollup,priority:2" json:"caller"`
+
+	// Method and Route identify the endpoint, such as GET /contacts/:id.
+	Method string `gorm:"column:method;type:VARCHAR(10);not null;uniqueIndex:idx_api_usage_rollup,priority:3" json:"method"`
+	Route  string `gorm:"column:route;type:VARCHAR(255);not null;uniqueIndex:idx_api_usage_rollup,priority:4" json:"route"`
+
+	// Requests is the number of requests made.
+	Requests int64 `gorm:"column:requests;not null;default:0" json:"requests"`
+
+	// ClientErrors and ServerErrors are the number of requests answered with a 4xx and a
+	// 5xx status code.
+	ClientErrors int64 `gorm:"column:client_errors;not null;default:0" json:"client_errors"`
+	ServerErrors int64 `gorm:"column:server_errors;not null;default:0" json:"server_errors"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (APIUsage) TableName() string {
+	return "api_usage"
+}
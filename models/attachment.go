@@ -0,0 +1,42 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// Attachment is a file, such as a screenshot or a PDF, uploaded with a contact submission.
+// The file itself is kept in the attachment storage; the database holds its metadata and
+// the key it is stored under.
+package models
+
+import "time"
+
+// Attachment represents a file attached to a contact.
+type Attachment struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// ContactID is the contact the file was submitted with. Attachments are removed
+	// together with their contact when it is purged.
+	ContactID uint `gorm:"column:contact_id;not null;index" json:"contact_id"`
+
+	// Filename is the name of the uploaded file, without its directory.
+	Filename string `gorm:"column:filename;type:VARCHAR(255);not null" json:"filename"`
+
+	// ContentType is the media type detected from the content of the file.
+	ContentType string `gorm:"column:content_type;type:VARCHAR(100);not null" json:"content_type"`
+
+	// Size is the size of the file in bytes.
+	Size int64 `gorm:"column:size;not null" json:"size"`
+
+	// StorageKey is the key the file is stored under in the attachment storage.
+	StorageKey string `gorm:"column:storage_key;type:VARCHAR(255);not null;uniqueIndex" json:"-"`
+
+	// ThumbnailKey is the key the preview of an image is stored under, once it was
+	// generated. It is empty for other files.
+	ThumbnailKey string `gorm:"column:thumbnail_key;type:VARCHAR(255);not null;default:''" json:"-"`
+
+	// CreatedAt is automatically maintained by GORM.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (Attachment) TableName() string {
+	return "contact_attachments"
+}
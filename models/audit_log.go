@@ -0,0 +1,107 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// AuditLog is an entry of the audit trail of the changes admins make to contacts: who
+// changed or deleted which contact, when, and the values before and after the change.
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditAction is the kind of change recorded by an AuditLog.
+type AuditAction string
+
+const (
+	// AuditUpdated is used when the fields of a contact are edited.
+	AuditUpdated AuditAction = "update"
+	// AuditStatusChanged is used when the status of a contact changes.
+	AuditStatusChanged AuditAction = "status"
+	// AuditLegalHoldChanged is used when the legal hold of a contact is placed or lifted.
+	AuditLegalHoldChanged AuditAction = "legal_hold"
+	// AuditPinChanged is used when a contact is pinned to or unpinned from the inbox.
+	AuditPinChanged AuditAction = "pin"
+	// AuditSnoozed is used when a contact is snoozed, and when its snooze ends.
+	AuditSnoozed AuditAction = "snooze"
+	// AuditDeleted is used when a contact is soft-deleted.
+	AuditDeleted AuditAction = "delete"
+	// AuditMerged is used when a duplicate contact is merged into another one.
+	AuditMerged AuditAction = "merge"
+	// AuditRestored is used when the deletion of a contact is undone.
+	AuditRestored AuditAction = "restore"
+	// AuditPurged is used when a deleted contact is permanently removed.
+	AuditPurged AuditAction = "purge"
+	// AuditAnonymized is used when the personal data of a contact is anonymized.
+	AuditAnonymized AuditAction = "anonymize"
+	// AuditAttachmentsPurged is used when the expired attachments of a contact are removed.
+	AuditAttachmentsPurged AuditAction = "attachments_purge"
+	// AuditEmailIssue is used when the email provider reports the address of a contact as
+	// undeliverable.
+	AuditEmailIssue AuditAction = "email_issue"
+)
+
+// RedactedAuditValue replaces the personal data removed from the audit trail of a contact
+// that was anonymized or purged.
+const RedactedAuditValue = "[redacted]"
+
+// Valid reports whether a is one of the known actions.
+func (a AuditAction) Valid() bool {
+	switch a {
+	case AuditUpdated, AuditStatusChanged, AuditLegalHoldChanged, AuditPinChanged, AuditSnoozed, AuditDeleted, AuditMerged, AuditRestored, AuditPurged, AuditAnonymized, AuditAttachmentsPurged, AuditEmailIssue:
+		return true
+	}
+	return false
+}
+
+// AuditLog represents a change made to a contact.
+type AuditLog struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// Actor names who made the change, as the subject of the caller's identity: "user:<id>"
+	// for admin users, "key:<id>" for stored API keys, "env" for configured API keys,
+	// "anonymous" when authentication is disabled, or "system:<job>" for background jobs.
+	Actor string `gorm:"column:actor;type:VARCHAR(100);not null;index" json:"actor"`
+
+	// Action is the kind of change.
+	Action AuditAction `gorm:"column:action;type:VARCHAR(20);not null;index" json:"action"`
+
+	// ContactID is the changed contact. It is not a foreign key, so that the trail of
+	// purged contacts is kept.
+	ContactID uint `gorm:"column:contact_id;not null;index" json:"contact_id"`
+
+	// Changes is a JSON object mapping the changed fields to their values before and after
+	// the change, as {"field": {"before": ..., "after": ...}}.
+	Changes string `gorm:"column:changes;type:TEXT" json:"changes"`
+
+	// CreatedAt is the time of the change.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;index" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// RedactChanges returns Changes with every value recorded for fields, such as their values
+// before and after the change, replaced by RedactedAuditValue. Changes that cannot be
+// decoded are dropped, so that no personal data is kept by mistake.
+func (a AuditLog) RedactChanges(fields ...string) string {
+	var changes map[string]map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(a.Changes), &changes); err != nil {
+		return "{}"
+	}
+	redacted, _ := json.Marshal(RedactedAuditValue)
+	for _, field := range fields {
+		for key, value := range changes[field] {
+			if string(value) != "null" {
+				changes[field][key] = redacted
+			}
+		}
+	}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return "{}"
+	}
+	return string(encoded)
+}
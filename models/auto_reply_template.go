@@ -0,0 +1,29 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// AutoReplyTemplate is the acknowledgement emailed to submitters in one language. The
+// auto-reply of a contact uses the template of its language, falling back to the default
+// language of the instance.
+package models
+
+import "time"
+
+// AutoReplyTemplate represents the auto-reply sent in a language.
+type AutoReplyTemplate struct {
+	// Language is the lowercase language code of the template, such as "en" or "pt-br",
+	// and the primary key.
+	Language string `gorm:"primaryKey;column:language;type:VARCHAR(35)" json:"language"`
+
+	// Subject and Body are the text/template of the subject line and of the plain-text
+	// body, executed with the contact.
+	Subject string `gorm:"column:subject;type:VARCHAR(200);not null" json:"subject"`
+	Body    string `gorm:"column:body;type:TEXT;not null" json:"body"`
+
+	// CreatedAt / UpdatedAt are automatically maintained by GORM.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (AutoReplyTemplate) TableName() string {
+	return "auto_reply_templates"
+}
@@ -6,11 +6,111 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// Channel identifies the ingestion path a contact was submitted through.
+type Channel string
+
+const (
+	// ChannelWeb is used for submissions posted by the public contact form.
+	ChannelWeb Channel = "web"
+	// ChannelAPI is used for submissions made by integrations using an API key.
+	ChannelAPI Channel = "api"
+	// ChannelEmail is used for contacts created from inbound or polled emails.
+	ChannelEmail Channel = "email"
+	// ChannelImport is used for contacts loaded from legacy data imports.
+	ChannelImport Channel = "import"
+)
+
+// EmailIssue is a delivery problem reported by the email provider for the address of a
+// contact, which makes it undeliverable.
+type EmailIssue string
+
+const (
+	// EmailBounced is used when emails to the address bounced permanently.
+	EmailBounced EmailIssue = "bounce"
+	// EmailComplained is used when the recipient reported an email as spam.
+	EmailComplained EmailIssue = "complaint"
+)
+
+// Valid reports whether c is one of the known channels.
+func (c Channel) Valid() bool {
+	switch c {
+	case ChannelWeb, ChannelAPI, ChannelEmail, ChannelImport:
+		return true
+	}
+	return false
+}
+
+// Status tracks how far the team got in handling a contact.
+type Status string
+
+const (
+	// StatusNew is the status of contacts nobody has looked at yet.
+	StatusNew Status = "new"
+	// StatusRead is the status of contacts that were read but not answered.
+	StatusRead Status = "read"
+	// StatusReplied is the status of contacts that were answered.
+	StatusReplied Status = "replied"
+	// StatusArchived is the status of contacts that need no further handling.
+	StatusArchived Status = "archived"
+	// StatusSpam is the status of contacts flagged as spam, either automatically for
+	// email addresses over their daily submission limit or by the team.
+	StatusSpam Status = "spam"
+)
+
+// statusTransitions lists the statuses each status may change to.
+var statusTransitions = map[Status][]Status{
+	StatusNew:      {StatusRead, StatusReplied, StatusArchived, StatusSpam},
+	StatusRead:     {StatusNew, StatusReplied, StatusArchived, StatusSpam},
+	StatusReplied:  {StatusArchived},
+	StatusArchived: {StatusRead},
+	StatusSpam:     {StatusNew, StatusArchived},
+}
+
+// reviewedTransitions lists the transitions only allowed once someone reviewed the contact,
+// such as archiving a contact flagged as spam, which would otherwise bury a submission
+// flagged by mistake.
+var reviewedTransitions = map[Status][]Status{
+	StatusSpam: {StatusArchived},
+}
+
+// Valid reports whether s is one of the known statuses.
+func (s Status) Valid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether a contact with status s may change to next.
+func (s Status) CanTransitionTo(next Status) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Transitions returns the statuses a contact with status s may change to.
+func (s Status) Transitions() []Status {
+	return append([]Status(nil), statusTransitions[s]...)
+}
+
+// RequiresReview reports whether a contact with status s may only change to next once
+// it was reviewed.
+func (s Status) RequiresReview(next Status) bool {
+	for _, reviewed := range reviewedTransitions[s] {
+		if reviewed == next {
+			return true
+		}
+	}
+	return false
+}
+
 // Contact represents a contact message submitted through the API.
 //
 // Notes:
@@ -23,13 +123,21 @@ type Contact struct {
 	// by convention; explicit `primaryKey` keeps intent clear.
 	ID uint `gorm:"primaryKey;column:id" json:"id"`
 
+	// PublicID is the UUIDv7 shown to submitters instead of the ID, when public IDs are
+	// generated rather than derived from the ID. It is NULL otherwise, and for contacts
+	// created before public IDs were generated until they are backfilled.
+	PublicID *string `gorm:"column:public_id;type:VARCHAR(36);uniqueIndex" json:"public_id,omitempty"`
+
 	// FullName is the name of the person submitting the contact message.
 	// Keep length constraints here so migrations create appropriate columns.
-	FullName string `gorm:"column:full_name;type:VARCHAR(100);not null" json:"full_name"`
+	// The partial index serves listings sorted by name.
+	FullName string `gorm:"column:full_name;type:VARCHAR(100);not null;index:idx_contact_messages_live_full_name,where:deleted_at IS NULL" json:"full_name"`
 
 	// Email is the email address of the submitter.
 	// Consider adding a unique index at the DB level if you want to enforce uniqueness.
-	Email string `gorm:"column:email_address;type:VARCHAR(100);not null" json:"email"`
+	// The expression index serves the case-insensitive lookups of an address and its
+	// recent submissions; the expression is parenthesized as MySQL requires.
+	Email string `gorm:"column:email_address;type:VARCHAR(100);not null;index:idx_contact_messages_lower_email,expression:(LOWER(email_address)),priority:1" json:"email"`
 
 	// Phone is the phone number.
 	Phone string `gorm:"column:phone_number;type:VARCHAR(20);not null" json:"phone"`
@@ -37,18 +145,123 @@ type Contact struct {
 	// Message stores the contact message content.
 	Message string `gorm:"column:message_text;type:TEXT;not null" json:"message"`
 
+	// Channel records how the contact was submitted. It is set by the ingestion
+	// path and indexed so listings and statistics can be split by origin; the
+	// partial composite index serves the channel-filtered list query.
+	Channel Channel `gorm:"column:channel;type:VARCHAR(20);not null;default:web;index;index:idx_contact_messages_live_channel,priority:1" json:"channel"`
+
+	// FingerprintHash is the SHA-256 of the optional client fingerprint sent by the
+	// widget. Only the hash is kept; it lets repeated abusers be correlated across IPs.
+	FingerprintHash string `gorm:"column:fingerprint_hash;type:VARCHAR(64);index" json:"fingerprint_hash"`
+
+	// MessageHash is the SHA-256 of the normalized message, used to spot the same message
+	// submitted twice by the same email address.
+	MessageHash string `gorm:"column:message_hash;type:VARCHAR(64)" json:"-"`
+
+	// DuplicateOfID is the ID of an earlier contact that a submission repeats, when the
+	// duplicate heuristic flagged it. Flagged duplicates are stored without notification.
+	DuplicateOfID *uint `gorm:"column:duplicate_of_id" json:"duplicate_of_id"`
+
+	// ConsentGiven, ConsentVersion, ConsentAt and ConsentIP record the consent checkbox
+	// of the submission: its value, the version of the consent text shown to the
+	// submitter, and when and from which IP address it was given.
+	ConsentGiven   bool       `gorm:"column:consent_given;not null;default:false" json:"consent_given"`
+	ConsentVersion string     `gorm:"column:consent_version;type:VARCHAR(50)" json:"consent_version"`
+	ConsentAt      *time.Time `gorm:"column:consent_at" json:"consent_at"`
+	ConsentIP      string     `gorm:"column:consent_ip;type:VARCHAR(45)" json:"-"`
+
+	// PrivacyPolicyVersion and TermsVersion record which privacy policy and terms
+	// versions were in force when the contact was submitted.
+	PrivacyPolicyVersion string `gorm:"column:privacy_policy_version;type:VARCHAR(50)" json:"privacy_policy_version"`
+	TermsVersion         string `gorm:"column:terms_version;type:VARCHAR(50)" json:"terms_version"`
+
+	// Status tracks the handling of the contact; StatusChangedAt is the time it last changed.
+	// The partial composite index serves the status-filtered list query.
+	Status          Status     `gorm:"column:status;type:VARCHAR(20);not null;default:new;index:idx_contact_messages_live_status,priority:1" json:"status"`
+	StatusChangedAt *time.Time `gorm:"column:status_changed_at" json:"status_changed_at"`
+
+	// LeadScore is the score given to the submission by the lead scoring rules, so that
+	// the contacts most likely to come from prospects are handled first.
+	LeadScore int `gorm:"column:lead_score;not null;default:0;index" json:"lead_score"`
+
+	// CompanyDomain, CompanyName and CompanySize describe the company of the submitter, as
+	// derived from the domain of its email address by the enrichment, for sales triage.
+	// They are empty for free email providers and when the enrichment is disabled.
+	CompanyDomain string `gorm:"column:company_domain;type:VARCHAR(255);index" json:"company_domain"`
+	CompanyName   string `gorm:"column:company_name;type:VARCHAR(255)" json:"company_name"`
+	CompanySize   string `gorm:"column:company_size;type:VARCHAR(50)" json:"company_size"`
+
+	// Language is the language code of the submission, such as "en" or "pt-br", given by the
+	// submitter or detected from the message, which selects the language of the auto-reply.
+	// It is empty when it could not be detected.
+	Language string `gorm:"column:language;type:VARCHAR(35)" json:"language"`
+
+	// LegalHold blocks deletion and anonymization of the contact while set.
+	LegalHold bool `gorm:"column:legal_hold;not null;default:false" json:"legal_hold"`
+
+	// Pinned keeps the contact at the top of the inbox of the whole team while set.
+	Pinned bool `gorm:"column:pinned;not null;default:false" json:"pinned"`
+
+	// SnoozedUntil hides the contact from the default inbox until the given time, when the
+	// snooze job returns it to the new status and notifies the team again.
+	SnoozedUntil *time.Time `gorm:"column:snoozed_until;index" json:"snoozed_until"`
+
+	// MergedIntoID is the ID of the contact a duplicate was merged into. Merged
+	// contacts are soft-deleted; restoring them clears it.
+	MergedIntoID *uint `gorm:"column:merged_into_id" json:"merged_into_id"`
+
+	// AnonymizedAt is the time the retention job replaced the personal data of the
+	// contact: its name, email address, phone number, fingerprint and consent IP.
+	AnonymizedAt *time.Time `gorm:"column:anonymized_at" json:"anonymized_at"`
+
+	// AttachmentsPurgedAt is the last time the retention job removed the attachments of
+	// the contact uploaded before the attachment retention period, keeping the contact.
+	AttachmentsPurgedAt *time.Time `gorm:"column:attachments_purged_at" json:"attachments_purged_at"`
+
+	// EmailIssue records that the email provider reported the address of the contact as
+	// undeliverable, at EmailIssueAt. No email should be sent to such an address.
+	EmailIssue   EmailIssue `gorm:"column:email_issue;type:VARCHAR(20)" json:"email_issue"`
+	EmailIssueAt *time.Time `gorm:"column:email_issue_at" json:"email_issue_at"`
+
+	// MessageID is the Message-ID header of the email a contact was created from,
+	// without its angle brackets.
+	// It is NULL for web submissions; the unique index lets email ingestion
+	// deduplicate messages that are fetched or delivered more than once.
+	MessageID *string `gorm:"column:message_id;type:VARCHAR(255);uniqueIndex" json:"-"`
+
+	// Attachments are the files uploaded with the submission. They are only loaded when
+	// needed; the foreign key removes them together with a purged contact.
+	Attachments []Attachment `gorm:"foreignKey:ContactID;constraint:OnDelete:CASCADE" json:"attachments,omitempty"`
+
 	// CreatedAt / UpdatedAt are automatically maintained by GORM.
 	// Do NOT hardcode a DB-specific type like DATETIME — let GORM map time.Time
 	// to the appropriate type (TIMESTAMP/TIMESTAMPTZ for Postgres, DATETIME for MySQL).
-	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	//
+	// The list query returns live contacts newest first, so CreatedAt is covered by
+	// partial indexes restricted to rows that are not soft-deleted.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;index:idx_contact_messages_live,where:deleted_at IS NULL;index:idx_contact_messages_live_channel,priority:2,where:deleted_at IS NULL;index:idx_contact_messages_live_status,priority:2,where:deleted_at IS NULL;index:idx_contact_messages_lower_email,priority:2" json:"created_at"`
 	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
 
 	// DeletedAt enables GORM soft deletes. Use gorm.DeletedAt instead of time.Time
 	// so GORM can handle NULL vs timestamp semantics correctly across DB engines.
-	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index" json:"-"`
+	// It has no index of its own: nearly every query scans for deleted_at IS NULL,
+	// which the partial indexes on CreatedAt serve better than a full index.
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at" json:"-"`
 }
 
 // TableName overrides the default table name that GORM derives from the struct.
 func (Contact) TableName() string {
 	return "contact_messages"
 }
+
+// Anonymize replaces the personal data of the contact and records the time it was done.
+// The email address stays unique per contact, so that it never collides with the partial
+// unique index on open contacts.
+func (c *Contact) Anonymize(at time.Time) {
+	c.FullName = "Anonymized"
+	c.Email = fmt.Sprintf("anonymized-%d@anonymized.invalid", c.ID)
+	c.Phone = ""
+	c.FingerprintHash = ""
+	c.ConsentIP = ""
+	c.AnonymizedAt = &at
+}
@@ -0,0 +1,30 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// ContactStar marks a contact an agent wants to keep an eye on. Stars are personal: each
+// agent lists the contacts they starred, while pins are shared by the whole team.
+package models
+
+import "time"
+
+// ContactStar represents a contact starred by an agent.
+type ContactStar struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// Owner is the actor who starred the contact, as recorded in the audit trail, such as
+	// "user:1" or "key:2". An agent stars a contact at most once.
+	Owner string `gorm:"column:owner;type:VARCHAR(100);not null;uniqueIndex:idx_contact_star_owner_contact,priority:1" json:"owner"`
+
+	// ContactID is the starred contact. The foreign key removes the stars together with a
+	// purged contact.
+	ContactID uint     `gorm:"column:contact_id;not null;uniqueIndex:idx_contact_star_owner_contact,priority:2;index" json:"contact_id"`
+	Contact   *Contact `gorm:"foreignKey:ContactID;constraint:OnDelete:CASCADE" json:"-"`
+
+	// CreatedAt is the time the contact was starred.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (ContactStar) TableName() string {
+	return "contact_stars"
+}
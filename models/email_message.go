@@ -0,0 +1,56 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// EmailMessage is an email of the conversation with the submitter of a contact, other than
+// the email the contact was created from: the auto-reply sent to the submitter, and the
+// replies received from them. Inbound emails are matched to the conversation by the
+// Message-IDs of their In-Reply-To and References headers.
+package models
+
+import "time"
+
+// EmailDirection tells the emails sent to a submitter from those received from them.
+type EmailDirection string
+
+const (
+	// EmailOutbound is the direction of the emails sent to the submitter.
+	EmailOutbound EmailDirection = "outbound"
+	// EmailInbound is the direction of the emails received from the submitter.
+	EmailInbound EmailDirection = "inbound"
+)
+
+// EmailMessage represents an email of the conversation of a contact.
+type EmailMessage struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// ContactID is the contact of the conversation. The foreign key removes the emails
+	// together with a purged contact.
+	ContactID uint     `gorm:"column:contact_id;not null;index" json:"contact_id"`
+	Contact   *Contact `gorm:"foreignKey:ContactID;constraint:OnDelete:CASCADE" json:"-"`
+
+	// Direction tells whether the email was sent or received.
+	Direction EmailDirection `gorm:"column:direction;type:VARCHAR(10);not null" json:"direction"`
+
+	// MessageID is the Message-ID header of the email, without its angle brackets. It is
+	// unique, so that an email delivered twice is stored once; emails without one are
+	// stored with none.
+	MessageID *string `gorm:"column:message_id;type:VARCHAR(255);uniqueIndex" json:"message_id"`
+
+	// InReplyTo is the Message-ID of the email this one answers, without its angle brackets.
+	InReplyTo string `gorm:"column:in_reply_to;type:VARCHAR(255);not null;default:''" json:"in_reply_to"`
+
+	// FromEmail is the sender of the email.
+	FromEmail string `gorm:"column:from_email;type:VARCHAR(100);not null" json:"from_email"`
+
+	// Subject and Body are the subject line and the plain-text content of the email.
+	Subject string `gorm:"column:subject;type:VARCHAR(255);not null;default:''" json:"subject"`
+	Body    string `gorm:"column:body;type:TEXT;not null" json:"body"`
+
+	// CreatedAt is the time the email was sent or received.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (EmailMessage) TableName() string {
+	return "contact_email_messages"
+}
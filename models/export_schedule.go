@@ -0,0 +1,87 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// ExportSchedule is a recurring report of contacts, such as a weekly CSV of the replied
+// contacts, exported on a cron schedule and delivered to managers by email or uploaded
+// to S3, so that they get reports without logging in to the admin view.
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// ExportDestination is where the file of a scheduled export is delivered.
+type ExportDestination string
+
+const (
+	// DestinationEmail emails the file as an attachment to the recipients.
+	DestinationEmail ExportDestination = "email"
+	// DestinationS3 uploads the file to the export bucket and emails its location to the
+	// recipients, if any.
+	DestinationS3 ExportDestination = "s3"
+)
+
+// Valid reports whether d is one of the known destinations.
+func (d ExportDestination) Valid() bool {
+	return d == DestinationEmail || d == DestinationS3
+}
+
+// ExportSchedule represents a recurring export of contacts.
+type ExportSchedule struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// Name describes the report, such as "Weekly replied contacts"; it names the files.
+	Name string `gorm:"column:name;type:VARCHAR(100);not null" json:"name"`
+
+	// Cron is the cron expression of the runs, evaluated in the application timezone.
+	Cron string `gorm:"column:cron;type:VARCHAR(100);not null" json:"cron"`
+
+	// Format is the file format, csv, xlsx or vcf, and Mode the export mode, full or anonymized.
+	Format string `gorm:"column:format;type:VARCHAR(10);not null;default:csv" json:"format"`
+	Mode   string `gorm:"column:mode;type:VARCHAR(20);not null;default:full" json:"mode"`
+
+	// Status and Channel restrict the report to the contacts with the given status and
+	// channel when not empty.
+	Status  Status  `gorm:"column:status;type:VARCHAR(20);not null;default:''" json:"status"`
+	Channel Channel `gorm:"column:channel;type:VARCHAR(20);not null;default:''" json:"channel"`
+
+	// Period restricts the report to the contacts submitted within the period before the
+	// run, such as a week; zero exports every contact.
+	Period time.Duration `gorm:"column:period;not null;default:0" json:"period"`
+
+	// Destination is where the file is delivered, and S3Prefix the prefix of the keys of
+	// the files uploaded to S3.
+	Destination ExportDestination `gorm:"column:destination;type:VARCHAR(10);not null" json:"destination"`
+	S3Prefix    string            `gorm:"column:s3_prefix;type:VARCHAR(255);not null;default:''" json:"s3_prefix"`
+
+	// Recipients is the comma-separated list of the email addresses the report, or the
+	// notification of its upload, is sent to.
+	Recipients string `gorm:"column:recipients;type:TEXT;not null" json:"recipients"`
+
+	// Active is false for schedules that are paused.
+	Active bool `gorm:"column:active;not null;default:true" json:"active"`
+
+	// LastRunAt is the time of the last run, LastRows the number of contacts it exported,
+	// and LastError the reason it failed, empty when it succeeded.
+	LastRunAt *time.Time `gorm:"column:last_run_at" json:"last_run_at"`
+	LastRows  int        `gorm:"column:last_rows;not null;default:0" json:"last_rows"`
+	LastError string     `gorm:"column:last_error;type:TEXT;not null;default:''" json:"last_error"`
+
+	// CreatedAt / UpdatedAt are automatically maintained by GORM.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (ExportSchedule) TableName() string {
+	return "export_schedules"
+}
+
+// RecipientList returns the recipients of the schedule.
+func (s *ExportSchedule) RecipientList() []string {
+	if s.Recipients == "" {
+		return nil
+	}
+	return strings.Split(s.Recipients, ",")
+}
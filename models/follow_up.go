@@ -0,0 +1,37 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// FollowUp is the date an agent plans to get back to a contact. Each agent keeps their own
+// follow-ups, which are published to their calendar through a feed.
+package models
+
+import "time"
+
+// FollowUp represents the follow-up of a contact planned by an agent.
+type FollowUp struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// ContactID is the contact to follow up with. The foreign key removes the follow-ups
+	// together with a purged contact.
+	ContactID uint     `gorm:"column:contact_id;not null;uniqueIndex:idx_follow_up_owner_contact,priority:2" json:"contact_id"`
+	Contact   *Contact `gorm:"foreignKey:ContactID;constraint:OnDelete:CASCADE" json:"-"`
+
+	// Owner is the actor who planned the follow-up, as recorded in the audit trail, such
+	// as "user:1" or "key:2". An agent has at most one follow-up per contact.
+	Owner string `gorm:"column:owner;type:VARCHAR(100);not null;uniqueIndex:idx_follow_up_owner_contact,priority:1" json:"owner"`
+
+	// DueAt is when the agent should get back to the contact.
+	DueAt time.Time `gorm:"column:due_at;not null;index" json:"due_at"`
+
+	// Note reminds the agent of what to follow up on.
+	Note string `gorm:"column:note;type:VARCHAR(500);not null;default:''" json:"note"`
+
+	// CreatedAt / UpdatedAt are automatically maintained by GORM.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (FollowUp) TableName() string {
+	return "contact_follow_ups"
+}
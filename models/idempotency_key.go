@@ -0,0 +1,42 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// IdempotencyKey records a request sent with an Idempotency-Key header and the response
+// it was answered with, so that the request can be retried safely: a repeated request
+// with the same key is answered with the stored response instead of being processed again.
+package models
+
+import "time"
+
+// IdempotencyKey represents a request made with an Idempotency-Key header.
+type IdempotencyKey struct {
+	// KeyHash is the hex SHA-256 of the key, scoped to the route and the caller, and is
+	// the primary key.
+	KeyHash string `gorm:"primaryKey;column:key_hash;type:VARCHAR(64)" json:"key_hash"`
+
+	// RequestHash is the hex SHA-256 of the request, so that reusing a key for a different
+	// request is detected.
+	RequestHash string `gorm:"column:request_hash;type:VARCHAR(64);not null" json:"request_hash"`
+
+	// StatusCode is the status code of the response, or zero while the request is processed.
+	StatusCode int `gorm:"column:status_code;not null;default:0" json:"status_code"`
+
+	// ContentType and ResponseBody are the content type and body of the response.
+	ContentType  string `gorm:"column:content_type;type:VARCHAR(100);not null;default:''" json:"content_type"`
+	ResponseBody []byte `gorm:"column:response_body" json:"-"`
+
+	// ExpiresAt is the time after which the key is forgotten and may be used again.
+	ExpiresAt time.Time `gorm:"column:expires_at;not null;index" json:"expires_at"`
+
+	// CreatedAt is the time of the first request with the key.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// Completed reports whether the response of the request was stored.
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
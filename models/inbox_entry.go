@@ -0,0 +1,81 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// InboxEntry is the denormalized row of a live contact in the admin inbox, maintained
+// from the lifecycle events of contacts, so that the inbox is listed with a single
+// indexed query instead of reading and trimming whole contact messages.
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// InboxPreviewLength is the largest number of characters of the message kept in the
+// preview of an inbox entry.
+const InboxPreviewLength = 140
+
+// InboxEntry represents a live contact in the admin inbox.
+type InboxEntry struct {
+	// ContactID is the ID of the contact, and the primary key.
+	ContactID uint `gorm:"primaryKey;autoIncrement:false;column:contact_id" json:"contact_id"`
+
+	// FullName and Email identify the submitter.
+	FullName string `gorm:"column:full_name;type:VARCHAR(100);not null" json:"full_name"`
+	Email    string `gorm:"column:email_address;type:VARCHAR(100);not null" json:"email"`
+
+	// Preview is the beginning of the message, on a single line.
+	Preview string `gorm:"column:preview;type:VARCHAR(600);not null" json:"preview"`
+
+	// Channel and Status are those of the contact.
+	Channel Channel `gorm:"column:channel;type:VARCHAR(20);not null" json:"channel"`
+	Status  Status  `gorm:"column:status;type:VARCHAR(20);not null;index:idx_inbox_entries_status,priority:1" json:"status"`
+
+	// Unread is set while nobody has looked at the contact, that is while its status is new
+	// and it is not snoozed.
+	Unread bool `gorm:"column:unread;not null;default:false;index:idx_inbox_entries_unread,priority:1" json:"unread"`
+
+	// LeadScore is the lead score of the contact, shown to prioritize the inbox.
+	LeadScore int `gorm:"column:lead_score;not null;default:0" json:"lead_score"`
+
+	// Pinned is set for the contacts pinned by the team, listed before the others.
+	Pinned bool `gorm:"column:pinned;not null;default:false;index:idx_inbox_entries_pinned,priority:1" json:"pinned"`
+
+	// SnoozedUntil is the time the snooze of the contact ends. Snoozed contacts are left out
+	// of the inbox unless they are asked for.
+	SnoozedUntil *time.Time `gorm:"column:snoozed_until;index" json:"snoozed_until"`
+
+	// SubmittedAt is the time the contact was submitted.
+	SubmittedAt time.Time `gorm:"column:submitted_at;not null" json:"submitted_at"`
+
+	// LastActivityAt is the time the contact last changed. The inbox lists the pinned
+	// contacts, then the most recently active ones first; the composite indexes serve its
+	// order and filters.
+	LastActivityAt time.Time `gorm:"column:last_activity_at;not null;index:idx_inbox_entries_activity;index:idx_inbox_entries_status,priority:2;index:idx_inbox_entries_unread,priority:2;index:idx_inbox_entries_pinned,priority:2" json:"last_activity_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (InboxEntry) TableName() string {
+	return "inbox_entries"
+}
+
+// NewInboxEntry returns the inbox entry of contact.
+func NewInboxEntry(contact *Contact) InboxEntry {
+	preview := []rune(strings.Join(strings.Fields(contact.Message), " "))
+	if len(preview) > InboxPreviewLength {
+		preview = append(preview[:InboxPreviewLength-1], '…')
+	}
+	return InboxEntry{
+		ContactID:      contact.ID,
+		FullName:       contact.FullName,
+		Email:          contact.Email,
+		Preview:        string(preview),
+		Channel:        contact.Channel,
+		Status:         contact.Status,
+		Unread:         contact.Status == StatusNew && contact.SnoozedUntil == nil,
+		LeadScore:      contact.LeadScore,
+		Pinned:         contact.Pinned,
+		SnoozedUntil:   contact.SnoozedUntil,
+		SubmittedAt:    contact.CreatedAt,
+		LastActivityAt: contact.UpdatedAt,
+	}
+}
@@ -0,0 +1,49 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// RejectedSubmission records a submission turned away by the spam protection, so that
+// bot traffic can be observed without storing the rejected payloads.
+package models
+
+import "time"
+
+// RejectionReason identifies the spam protection that rejected a submission.
+type RejectionReason string
+
+const (
+	// RejectionRateLimit is used for submissions over the per-IP rate limit.
+	RejectionRateLimit RejectionReason = "rate_limit"
+	// RejectionHoneypot is used for submissions that filled in the hidden honeypot field.
+	RejectionHoneypot RejectionReason = "honeypot"
+	// RejectionCaptcha is used for submissions without a valid CAPTCHA token.
+	RejectionCaptcha RejectionReason = "captcha"
+	// RejectionReplay is used for submissions replaying a form token or an idempotency key
+	// after its validity window.
+	RejectionReplay RejectionReason = "replay"
+)
+
+// RejectedSubmission represents a submission rejected by the spam protection.
+// Only metadata about the request is kept, never its payload.
+type RejectedSubmission struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// Reason is the spam protection that rejected the submission.
+	Reason RejectionReason `gorm:"column:reason;type:VARCHAR(20);not null;index" json:"reason"`
+
+	// ClientIP is the IP address the submission was received from.
+	ClientIP string `gorm:"column:client_ip;type:VARCHAR(45);index" json:"client_ip"`
+
+	// UserAgent is the User-Agent header of the submission, truncated to 255 characters.
+	UserAgent string `gorm:"column:user_agent;type:VARCHAR(255)" json:"user_agent"`
+
+	// Path is the request path the submission was posted to.
+	Path string `gorm:"column:path;type:VARCHAR(255)" json:"path"`
+
+	// CreatedAt is the time of the rejection.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;index" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (RejectedSubmission) TableName() string {
+	return "rejected_submissions"
+}
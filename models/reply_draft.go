@@ -0,0 +1,36 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// ReplyDraft is the draft of the reply an agent is writing to a contact. A contact has at
+// most one draft, so that teammates see that a reply is in progress rather than write
+// another one at the same time.
+package models
+
+import "time"
+
+// ReplyDraft represents the reply being drafted to a contact.
+type ReplyDraft struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// ContactID is the contact the reply is for. The unique index keeps a single draft per
+	// contact, even when two agents start one at the same time, and the foreign key removes
+	// the draft together with a purged contact.
+	ContactID uint     `gorm:"column:contact_id;not null;uniqueIndex" json:"contact_id"`
+	Contact   *Contact `gorm:"foreignKey:ContactID;constraint:OnDelete:CASCADE" json:"-"`
+
+	// Body is the text of the reply.
+	Body string `gorm:"column:body;type:TEXT;not null" json:"body"`
+
+	// Author is the actor who last saved the draft, as recorded in the audit trail, such
+	// as "user:1" or "key:2".
+	Author string `gorm:"column:author;type:VARCHAR(100);not null" json:"author"`
+
+	// CreatedAt / UpdatedAt are automatically maintained by GORM.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (ReplyDraft) TableName() string {
+	return "contact_reply_drafts"
+}
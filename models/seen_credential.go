@@ -0,0 +1,43 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// SeenCredential records a signed form token or an idempotency key that a submission was
+// accepted with, well past its validity window, so that a later replay of the same
+// credential is recognized as such instead of being processed as a new submission.
+package models
+
+import "time"
+
+// CredentialKind identifies the kind of a credential carried by a submission.
+type CredentialKind string
+
+const (
+	// CredentialFormToken is a signed form token sent in the X-Form-Token header.
+	CredentialFormToken CredentialKind = "form_token"
+	// CredentialIdempotencyKey is a key sent in the Idempotency-Key header.
+	CredentialIdempotencyKey CredentialKind = "idempotency_key"
+)
+
+// SeenCredential represents a credential a submission was accepted with.
+type SeenCredential struct {
+	// Hash is the hex SHA-256 of the credential, scoped like IdempotencyKey for idempotency
+	// keys, and is the primary key.
+	Hash string `gorm:"primaryKey;column:hash;type:VARCHAR(64)" json:"hash"`
+
+	// Kind is the kind of the credential.
+	Kind CredentialKind `gorm:"column:kind;type:VARCHAR(20);not null" json:"kind"`
+
+	// ValidUntil is the end of the validity window of the credential. Submissions carrying
+	// it again later are replays.
+	ValidUntil time.Time `gorm:"column:valid_until;not null" json:"valid_until"`
+
+	// ForgetAt is the time after which the credential is forgotten.
+	ForgetAt time.Time `gorm:"column:forget_at;not null;index" json:"forget_at"`
+
+	// CreatedAt is the time the submission was accepted.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (SeenCredential) TableName() string {
+	return "seen_credentials"
+}
@@ -0,0 +1,62 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// SystemChange is an entry of the changelog of the instance: the schema migrations,
+// settings changes, template edits and failovers, with who made them and when, so that
+// operators can tell what changed before an incident.
+package models
+
+import "time"
+
+// ChangeKind is the kind of change recorded by a SystemChange.
+type ChangeKind string
+
+const (
+	// ChangeMigration is used when the migrations change the database schema.
+	ChangeMigration ChangeKind = "migration"
+	// ChangeSettings is used when a settings bundle is imported or a webhook subscription
+	// is created, updated or deleted.
+	ChangeSettings ChangeKind = "settings"
+	// ChangeTemplate is used when an auto-reply template is saved or deleted.
+	ChangeTemplate ChangeKind = "template"
+	// ChangeFailover is used when a standby region is promoted to primary.
+	ChangeFailover ChangeKind = "failover"
+)
+
+// Valid reports whether k is one of the known kinds.
+func (k ChangeKind) Valid() bool {
+	switch k {
+	case ChangeMigration, ChangeSettings, ChangeTemplate, ChangeFailover:
+		return true
+	}
+	return false
+}
+
+// SystemChange represents a change made to the schema, settings or templates of the instance.
+type SystemChange struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// Kind is the kind of change.
+	Kind ChangeKind `gorm:"column:kind;type:VARCHAR(20);not null;index" json:"kind"`
+
+	// Actor names who made the change, as in the audit trail: "user:<id>", "key:<id>",
+	// "env", "anonymous", or "system:<job>" for changes made at startup.
+	Actor string `gorm:"column:actor;type:VARCHAR(100);not null;index" json:"actor"`
+
+	// Subject is what changed, such as "schema", "webhook:3" or "auto-reply:en".
+	Subject string `gorm:"column:subject;type:VARCHAR(255);not null" json:"subject"`
+
+	// Summary describes the change in a sentence.
+	Summary string `gorm:"column:summary;type:VARCHAR(500);not null" json:"summary"`
+
+	// Details is a JSON value describing the change, whose layout depends on the kind.
+	Details string `gorm:"column:details;type:TEXT" json:"details"`
+
+	// CreatedAt is the time of the change.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;index" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (SystemChange) TableName() string {
+	return "system_changes"
+}
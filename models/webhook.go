@@ -0,0 +1,156 @@
+// Package models defines the data models for the API Contact Form application.
+//
+// WebhookSubscription registers a URL that receives contact lifecycle events,
+// WebhookDelivery records every attempt to deliver an event, for debugging failures, and
+// WebhookOutboxEvent keeps the published events, so that they can be replayed.
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// WebhookEvent identifies a contact lifecycle event delivered to webhooks.
+type WebhookEvent string
+
+const (
+	// EventContactCreated is sent when a contact is submitted.
+	EventContactCreated WebhookEvent = "contact.created"
+	// EventContactUpdated is sent when a contact, its status or its legal hold changes,
+	// and when a deleted contact is restored.
+	EventContactUpdated WebhookEvent = "contact.updated"
+	// EventContactDeleted is sent when a contact is deleted or merged into another contact.
+	EventContactDeleted WebhookEvent = "contact.deleted"
+	// EventContactStatusChanged is sent, after contact.updated, when the status of a contact
+	// changes, with the status it changed from.
+	EventContactStatusChanged WebhookEvent = "contact.status_changed"
+)
+
+// Valid reports whether e is one of the known events.
+func (e WebhookEvent) Valid() bool {
+	return e == EventContactCreated || e == EventContactUpdated || e == EventContactDeleted || e == EventContactStatusChanged
+}
+
+// WebhookSubscription represents a URL registered to receive contact lifecycle events.
+type WebhookSubscription struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// URL is the endpoint the events are POSTed to.
+	URL string `gorm:"column:url;type:VARCHAR(2048);not null" json:"url"`
+
+	// Secret signs the payloads delivered to the URL.
+	Secret string `gorm:"column:secret;type:VARCHAR(128);not null" json:"-"`
+
+	// Events is the comma-separated list of events delivered to the URL; empty means all events.
+	Events string `gorm:"column:events;type:VARCHAR(255);not null;default:''" json:"events"`
+
+	// Active is false for subscriptions that are paused.
+	Active bool `gorm:"column:active;not null;default:true" json:"active"`
+
+	// CreatedAt / UpdatedAt are automatically maintained by GORM.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// EventList returns the events of the subscription; nil means all events.
+func (s *WebhookSubscription) EventList() []WebhookEvent {
+	if s.Events == "" {
+		return nil
+	}
+	var events []WebhookEvent
+	for _, event := range strings.Split(s.Events, ",") {
+		events = append(events, WebhookEvent(event))
+	}
+	return events
+}
+
+// SetEventList stores the given events in the subscription; nil means all events.
+func (s *WebhookSubscription) SetEventList(events []WebhookEvent) {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, string(event))
+	}
+	s.Events = strings.Join(names, ",")
+}
+
+// Subscribes reports whether the subscription receives event.
+func (s *WebhookSubscription) Subscribes(event WebhookEvent) bool {
+	events := s.EventList()
+	return events == nil || slices.Contains(events, event)
+}
+
+// WebhookDelivery records one attempt to deliver an event to a webhook subscription.
+type WebhookDelivery struct {
+	// ID is the primary key.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// SubscriptionID is the subscription the event was delivered to. The composite index
+	// serves the delivery log of a subscription, newest first.
+	SubscriptionID uint `gorm:"column:subscription_id;not null;index:idx_webhook_deliveries_subscription,priority:1" json:"subscription_id"`
+
+	// EventID identifies the event; all attempts to deliver an event share it.
+	EventID string `gorm:"column:event_id;type:VARCHAR(32);not null" json:"event_id"`
+
+	// Event is the type of the event.
+	Event WebhookEvent `gorm:"column:event;type:VARCHAR(50);not null" json:"event"`
+
+	// ContactID is the contact the event is about.
+	ContactID uint `gorm:"column:contact_id;not null" json:"contact_id"`
+
+	// Attempt is the number of the attempt, starting at 1.
+	Attempt int `gorm:"column:attempt;not null" json:"attempt"`
+
+	// Success reports whether the endpoint answered with a 2xx status code.
+	Success bool `gorm:"column:success;not null" json:"success"`
+
+	// StatusCode is the HTTP status code of the response, or 0 when no response was received.
+	StatusCode int `gorm:"column:status_code" json:"status_code"`
+
+	// Error describes why the attempt failed, including the start of the response body.
+	Error string `gorm:"column:error;type:TEXT" json:"error"`
+
+	// DurationMs is the time the attempt took, in milliseconds.
+	DurationMs int64 `gorm:"column:duration_ms" json:"duration_ms"`
+
+	// CreatedAt is the time of the attempt.
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;index:idx_webhook_deliveries_subscription,priority:2" json:"created_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookOutboxEvent records an event published to the webhooks with its payload, so that
+// it can be sent again to a subscription that missed it.
+type WebhookOutboxEvent struct {
+	// ID is the primary key, increasing in publication order.
+	ID uint `gorm:"primaryKey;column:id" json:"id"`
+
+	// EventID identifies the event in its payload.
+	EventID string `gorm:"column:event_id;type:VARCHAR(32);not null;uniqueIndex" json:"event_id"`
+
+	// Event is the type of the event.
+	Event WebhookEvent `gorm:"column:event;type:VARCHAR(50);not null" json:"event"`
+
+	// ContactID is the contact the event is about.
+	ContactID uint `gorm:"column:contact_id;not null;index" json:"contact_id"`
+
+	// Payload is the JSON body delivered for the event.
+	Payload string `gorm:"column:payload;type:TEXT;not null" json:"-"`
+
+	// OccurredAt is the time of the event.
+	OccurredAt time.Time `gorm:"column:occurred_at;not null;index" json:"occurred_at"`
+}
+
+// TableName overrides the default table name that GORM derives from the struct.
+func (WebhookOutboxEvent) TableName() string {
+	return "webhook_outbox"
+}
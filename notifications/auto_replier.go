@@ -0,0 +1,162 @@
+// Package notifications sends notifications about new contacts to the team handling them.
+//
+// This file implements the AutoReplier, which emails submitters an acknowledgement of
+// their contact over the SMTP server of the email notifications, in the language of the
+// contact when a template exists for it. Auto-replies start the email conversation of the
+// contact, or answer the email it was created from, so that the replies of submitters are
+// threaded back to their contact.
+package notifications
+
+import (
+	"api-contact-form/config"
+	"api-contact-form/helpers"
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"strings"
+	"text/template"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AutoReplier emails the auto-reply of new contacts to their submitters. It is used as the
+// only Notifier of a Dispatcher of its own, which queues and retries the auto-replies.
+type AutoReplier struct {
+	config          config.SMTPConfig
+	templates       repositories.AutoReplyTemplateRepository
+	messages        repositories.EmailMessageRepository
+	defaultLanguage string
+}
+
+// NewAutoReplier creates an AutoReplier sending through the SMTP server of cfg with the
+// templates of repository, and recording the auto-replies sent in the conversations of
+// messages. Contacts in a language without a template receive the template of
+// defaultLanguage. The recipients and templates of cfg are not used.
+func NewAutoReplier(cfg config.SMTPConfig, templates repositories.AutoReplyTemplateRepository, messages repositories.EmailMessageRepository, defaultLanguage string) (*AutoReplier, error) {
+	if cfg.Host == "" || cfg.From == "" {
+		return nil, errors.New("SMTP_HOST and SMTP_FROM are required to send auto-replies")
+	}
+	return &AutoReplier{config: cfg, templates: templates, messages: messages, defaultLanguage: strings.ToLower(defaultLanguage)}, nil
+}
+
+// ParseAutoReplyTemplate parses the subject and body of an auto-reply template.
+func ParseAutoReplyTemplate(t *models.AutoReplyTemplate) (subject, body *template.Template, err error) {
+	if subject, err = template.New("subject").Parse(t.Subject); err != nil {
+		return nil, nil, fmt.Errorf("parse subject template: %w", err)
+	}
+	if body, err = template.New("body").Parse(t.Body); err != nil {
+		return nil, nil, fmt.Errorf("parse body template: %w", err)
+	}
+	return subject, body, nil
+}
+
+// Name implements Notifier.
+func (a *AutoReplier) Name() string {
+	return "auto-reply"
+}
+
+// Send implements Notifier by emailing the auto-reply of contact to its submitter. Nothing
+// is sent when neither the language of the contact nor the default language has a template.
+//
+// The auto-reply gets a new Message-ID and, for contacts created from an email, answers
+// that email. Once sent, it is recorded in the conversation of the contact, so that the
+// replies to it are added to the contact.
+func (a *AutoReplier) Send(ctx context.Context, contact models.Contact) error {
+	tmpl, err := a.Select(ctx, contact.Language)
+	if err != nil {
+		return err
+	}
+	if tmpl == nil {
+		log.Printf("No auto-reply template for contact %d in %q or the default language", contact.ID, contact.Language)
+		return nil
+	}
+
+	// Render the template with the contact.
+	subjectTemplate, bodyTemplate, err := ParseAutoReplyTemplate(tmpl)
+	if err != nil {
+		return err
+	}
+	var subject, body bytes.Buffer
+	if err := subjectTemplate.Execute(&subject, contact); err != nil {
+		return err
+	}
+	if err := bodyTemplate.Execute(&body, contact); err != nil {
+		return err
+	}
+
+	reply := models.EmailMessage{
+		ContactID: contact.ID,
+		Direction: models.EmailOutbound,
+		FromEmail: a.config.From,
+		Subject:   strings.Join(strings.Fields(subject.String()), " "),
+		Body:      body.String(),
+	}
+	messageID := helpers.NewMessageID(a.config.From)
+	reply.MessageID = &messageID
+	if contact.MessageID != nil {
+		reply.InReplyTo = helpers.NormalizeMessageID(*contact.MessageID)
+	}
+
+	msg := a.message(contact.Email, tmpl.Language, reply)
+	if err := sendMail(a.config, []string{contact.Email}, msg); err != nil {
+		return err
+	}
+	if a.messages != nil {
+		if err := a.messages.Create(ctx, &reply); err != nil {
+			log.Printf("Auto-reply of contact %d not recorded in its conversation: %v", contact.ID, err)
+		}
+	}
+	return nil
+}
+
+// SendDigest implements Notifier. Auto-replies are never coalesced, as every submitter
+// expects its own, so it sends nothing.
+func (a *AutoReplier) SendDigest(ctx context.Context, contacts []models.Contact, window time.Duration) error {
+	return nil
+}
+
+// Select returns the template of language, of the language without its region or other
+// subtags, such as "pt" for "pt-br", or of the default language, in that order. It returns
+// nil when none of them has a template.
+func (a *AutoReplier) Select(ctx context.Context, language string) (*models.AutoReplyTemplate, error) {
+	for _, candidate := range append(helpers.LanguageFallbacks(language), a.defaultLanguage) {
+		tmpl, err := a.templates.FindByLanguage(ctx, candidate)
+		switch {
+		case err == nil:
+			return tmpl, nil
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// message builds the auto-reply email to recipient from reply, whose subject is on a single
+// line. It carries the Message-ID of reply and, when reply answers an email, the
+// In-Reply-To and References headers of RFC 5322. It is marked as auto-replied, so that
+// the autoresponder of the submitter does not answer it in turn.
+func (a *AutoReplier) message(recipient, language string, reply models.EmailMessage) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", a.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", recipient)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", reply.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s>\r\n", *reply.MessageID)
+	if reply.InReplyTo != "" {
+		fmt.Fprintf(&msg, "In-Reply-To: <%s>\r\n", reply.InReplyTo)
+		fmt.Fprintf(&msg, "References: <%s>\r\n", reply.InReplyTo)
+	}
+	fmt.Fprintf(&msg, "Content-Language: %s\r\n", language)
+	msg.WriteString("Auto-Submitted: auto-replied\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(reply.Body, "\n", "\r\n"))
+	return msg.Bytes()
+}
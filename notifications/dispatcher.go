@@ -0,0 +1,282 @@
+// Package notifications sends notifications about new contacts to the team handling them.
+//
+// This file implements the Dispatcher, which delivers notifications through every
+// configured Notifier asynchronously. Background workers retry failed deliveries with
+// exponential backoff, so a slow or unavailable service never delays the HTTP response
+// of a submission. During spikes, such as a campaign launch, the notifications can be
+// coalesced into a single digest per channel. The channels able to can also deliver
+// operational alerts to the team, such as the detection of a spike.
+package notifications
+
+import (
+	"api-contact-form/jobs"
+	"api-contact-form/models"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Notifier delivers the notification of a new contact through one channel.
+type Notifier interface {
+	// Name identifies the channel in logs.
+	Name() string
+	// Send delivers the notification of contact.
+	Send(ctx context.Context, contact models.Contact) error
+	// SendDigest delivers a single notification summarizing contacts, which arrived
+	// within the last window.
+	SendDigest(ctx context.Context, contacts []models.Contact, window time.Duration) error
+}
+
+// Alert is an operational alert about the API itself, rather than about a contact.
+type Alert struct {
+	// Key identifies the alert, so that channels can recognize a retried delivery.
+	Key   string
+	Title string
+	Text  string
+}
+
+// Alerter is implemented by the notifiers able to deliver alerts.
+type Alerter interface {
+	// SendAlert delivers alert.
+	SendAlert(ctx context.Context, alert Alert) error
+}
+
+// delivery is a notification waiting to be sent through a notifier: a single contact,
+// the digest of several contacts when digest is set, or an alert when alert is set.
+type delivery struct {
+	notifier Notifier
+	contact  models.Contact
+	digest   []models.Contact
+	alert    *Alert
+}
+
+// Dispatcher queues notifications and sends them through its notifiers in the background.
+type Dispatcher struct {
+	notifiers   []Notifier
+	maxAttempts int
+	backoff     time.Duration
+
+	queue *jobs.Queue[delivery]
+	wg    sync.WaitGroup
+
+	// Coalescing of spikes, disabled while threshold is 0. arrivals holds the times of the
+	// contacts received within the last window, and pending the contacts waiting for the
+	// digest sent when flush fires.
+	threshold int
+	window    time.Duration
+	mu        sync.Mutex
+	arrivals  []time.Time
+	pending   []models.Contact
+	flush     *time.Timer
+}
+
+// NewDispatcher creates a Dispatcher sending through the given notifiers. Each delivery
+// is attempted up to maxAttempts times, waiting backoff before the first retry and
+// doubling the wait after every failure. At most queueSize deliveries wait to be sent, in
+// the queue reported as name.
+func NewDispatcher(name string, notifiers []Notifier, maxAttempts int, backoff time.Duration, queueSize int) *Dispatcher {
+	return &Dispatcher{
+		notifiers:   notifiers,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		queue:       jobs.NewQueue[delivery](name, queueSize),
+	}
+}
+
+// Coalesce enables the coalescing of spikes: once more than threshold contacts arrived
+// within window, the following ones are not notified individually but collected, and a
+// single digest of them is sent through every notifier when the window ends. It must be
+// called before Start.
+func (d *Dispatcher) Coalesce(threshold int, window time.Duration) {
+	d.threshold = threshold
+	d.window = window
+}
+
+// Start launches the given number of workers sending queued notifications. They stop
+// once ctx is cancelled; notifications still queued at that time are dropped.
+func (d *Dispatcher) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for {
+				job, ok := d.queue.Next(ctx)
+				if !ok {
+					return
+				}
+				d.deliver(ctx, job)
+			}
+		}()
+	}
+}
+
+// Wait blocks until the workers have stopped.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// QueueStats returns the health of the queue of the notifications.
+func (d *Dispatcher) QueueStats() jobs.Stats {
+	return d.queue.Stats()
+}
+
+// Notify queues the notification of contact on every notifier without blocking. When
+// the queue is full the notification is dropped and logged. A nil Dispatcher does nothing.
+func (d *Dispatcher) Notify(contact models.Contact) {
+	if d == nil {
+		return
+	}
+	if d.coalesce(contact) {
+		return
+	}
+
+	for _, notifier := range d.notifiers {
+		d.enqueue(delivery{notifier: notifier, contact: contact})
+	}
+}
+
+// Alert queues alert on every notifier implementing Alerter without blocking, and
+// reports whether one of them does. A nil Dispatcher does nothing.
+func (d *Dispatcher) Alert(alert Alert) bool {
+	if d == nil {
+		return false
+	}
+
+	queued := false
+	for _, notifier := range d.notifiers {
+		if _, ok := notifier.(Alerter); ok {
+			d.enqueue(delivery{notifier: notifier, alert: &alert})
+			queued = true
+		}
+	}
+	return queued
+}
+
+// coalesce records the arrival of contact and reports whether it was collected for the
+// next digest, rather than to be notified individually.
+func (d *Dispatcher) coalesce(contact models.Contact) bool {
+	if d.threshold <= 0 {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Forget the arrivals that fell out of the window
+	now := time.Now()
+	recent := d.arrivals[:0]
+	for _, arrival := range d.arrivals {
+		if now.Sub(arrival) < d.window {
+			recent = append(recent, arrival)
+		}
+	}
+	d.arrivals = append(recent, now)
+
+	// Collect the contact while a digest is pending or the threshold is exceeded
+	if len(d.pending) == 0 && len(d.arrivals) <= d.threshold {
+		return false
+	}
+	d.pending = append(d.pending, contact)
+	if d.flush == nil {
+		d.flush = time.AfterFunc(d.window, d.sendDigest)
+	}
+	return true
+}
+
+// sendDigest queues the digest of the pending contacts on every notifier.
+func (d *Dispatcher) sendDigest() {
+	d.mu.Lock()
+	contacts := d.pending
+	d.pending = nil
+	d.flush = nil
+	d.mu.Unlock()
+
+	for _, notifier := range d.notifiers {
+		d.enqueue(delivery{notifier: notifier, digest: contacts})
+	}
+}
+
+// enqueue queues job without blocking, and drops and logs it when the queue is full.
+func (d *Dispatcher) enqueue(job delivery) {
+	if !d.queue.Push(job) {
+		log.Printf("Notification queue full, dropping %s %s", job.notifier.Name(), job.describe())
+	}
+}
+
+// send makes a single attempt to deliver job.
+func (d *Dispatcher) send(ctx context.Context, job delivery) error {
+	if job.alert != nil {
+		return job.notifier.(Alerter).SendAlert(ctx, *job.alert)
+	}
+	if job.digest != nil {
+		return job.notifier.SendDigest(ctx, job.digest, d.window)
+	}
+	return job.notifier.Send(ctx, job.contact)
+}
+
+// describe names the notification of job in logs.
+func (job delivery) describe() string {
+	if job.alert != nil {
+		return fmt.Sprintf("alert %q", job.alert.Title)
+	}
+	if job.digest != nil {
+		return fmt.Sprintf("digest notification of %d contacts", len(job.digest))
+	}
+	return fmt.Sprintf("notification for contact %d", job.contact.ID)
+}
+
+// deliver sends a notification, retrying with exponential backoff.
+func (d *Dispatcher) deliver(ctx context.Context, job delivery) {
+	wait := d.backoff
+	for attempt := 1; ; attempt++ {
+		err := d.send(ctx, job)
+		if err == nil {
+			return
+		}
+		if attempt >= d.maxAttempts {
+			log.Printf("%s %s failed after %d attempts: %v", job.notifier.Name(), job.describe(), attempt, err)
+			d.queue.Failed()
+			return
+		}
+		log.Printf("%s %s failed (attempt %d), retrying in %s: %v", job.notifier.Name(), job.describe(), attempt, wait, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// maxDigestContacts is the number of contacts listed in a digest; the others are counted.
+const maxDigestContacts = 25
+
+// digestTitle summarizes a digest, such as "17 new contacts in the last 5 minutes".
+func digestTitle(count int, window time.Duration) string {
+	unit, size := "second", time.Second
+	switch {
+	case window >= time.Hour && window%time.Hour == 0:
+		unit, size = "hour", time.Hour
+	case window >= time.Minute && window%time.Minute == 0:
+		unit, size = "minute", time.Minute
+	case window < time.Second || window%time.Second != 0:
+		return fmt.Sprintf("%d new contacts in the last %s", count, window)
+	}
+	units := int64(window / size)
+	span := fmt.Sprintf("%d %ss", units, unit)
+	if units == 1 {
+		span = unit
+	}
+	return fmt.Sprintf("%d new contacts in the last %s", count, span)
+}
+
+// digestContacts returns the contacts listed in a digest, and the number of the others.
+func digestContacts(contacts []models.Contact) ([]models.Contact, int) {
+	if len(contacts) <= maxDigestContacts {
+		return contacts, 0
+	}
+	return contacts[:maxDigestContacts], len(contacts) - maxDigestContacts
+}
@@ -0,0 +1,189 @@
+// Package notifications sends notifications about new contacts to the team handling them.
+//
+// This file implements the EmailNotifier, which emails a templated summary of every new
+// contact to a list of recipients over SMTP.
+package notifications
+
+import (
+	"api-contact-form/config"
+	"api-contact-form/models"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultBodyTemplate is the body used when no template file is configured.
+const defaultBodyTemplate = `A new contact was submitted.
+
+Name:    {{.FullName}}
+Email:   {{.Email}}
+Phone:   {{.Phone}}
+Channel: {{.Channel}}
+Date:    {{.CreatedAt.Format "2006-01-02 15:04:05 MST"}}
+
+{{.Message}}
+`
+
+// EmailNotifier emails new contacts to the configured recipients.
+type EmailNotifier struct {
+	config  config.SMTPConfig
+	subject *template.Template
+	body    *template.Template
+}
+
+// NewEmailNotifier creates an EmailNotifier from the SMTP configuration.
+func NewEmailNotifier(cfg config.SMTPConfig) (*EmailNotifier, error) {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.Recipients) == 0 {
+		return nil, errors.New("SMTP host, sender and recipients are required")
+	}
+
+	// Parse the subject and body templates.
+	subject, err := template.New("subject").Parse(cfg.SubjectTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse subject template: %w", err)
+	}
+	bodyText := defaultBodyTemplate
+	if cfg.BodyTemplateFile != "" {
+		data, err := os.ReadFile(cfg.BodyTemplateFile)
+		if err != nil {
+			return nil, err
+		}
+		bodyText = string(data)
+	}
+	body, err := template.New("body").Parse(bodyText)
+	if err != nil {
+		return nil, fmt.Errorf("parse body template: %w", err)
+	}
+
+	return &EmailNotifier{config: cfg, subject: subject, body: body}, nil
+}
+
+// Name implements Notifier.
+func (n *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Send implements Notifier by emailing the contact to the recipients.
+func (n *EmailNotifier) Send(ctx context.Context, contact models.Contact) error {
+	msg, err := n.render(contact)
+	if err != nil {
+		return err
+	}
+	return n.send(msg)
+}
+
+// SendDigest implements Notifier by emailing the list of contacts to the recipients.
+func (n *EmailNotifier) SendDigest(ctx context.Context, contacts []models.Contact, window time.Duration) error {
+	listed, more := digestContacts(contacts)
+	var body strings.Builder
+	body.WriteString("New contacts were submitted.\n\n")
+	for _, contact := range listed {
+		fmt.Fprintf(&body, "%s  %s <%s>, %s\n",
+			contact.CreatedAt.Format("2006-01-02 15:04:05 MST"), contact.FullName, contact.Email, contact.Channel)
+	}
+	if more > 0 {
+		fmt.Fprintf(&body, "\n...and %d more.\n", more)
+	}
+	return n.send(n.message(digestTitle(len(contacts), window), body.String(), ""))
+}
+
+// SendAlert implements Alerter by emailing the alert to the recipients.
+func (n *EmailNotifier) SendAlert(ctx context.Context, alert Alert) error {
+	return n.send(n.message(alert.Title, alert.Text+"\n", ""))
+}
+
+// send sends msg to the recipients.
+func (n *EmailNotifier) send(msg []byte) error {
+	return sendMail(n.config, n.config.Recipients, msg)
+}
+
+// sendMail sends msg to the addresses to with the server of cfg, authenticating when cfg
+// has a username.
+func sendMail(cfg config.SMTPConfig, to []string, msg []byte) error {
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	send := cfg.SendMail
+	if send == nil {
+		send = smtp.SendMail
+	}
+	return send(cfg.Host+":"+cfg.Port, auth, cfg.From, to, msg)
+}
+
+// CheckSMTP connects to the SMTP server of cfg and authenticates as smtp.SendMail does,
+// upgrading to TLS when the server offers it, but sends no message.
+func CheckSMTP(ctx context.Context, cfg config.SMTPConfig) error {
+	if cfg.Host == "" {
+		return errors.New("SMTP_HOST is not set")
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(cfg.Host, cfg.Port))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("authenticate: %w", err)
+		}
+	}
+	return client.Quit()
+}
+
+// render builds the email message of a contact.
+func (n *EmailNotifier) render(contact models.Contact) ([]byte, error) {
+	var subject, body bytes.Buffer
+	if err := n.subject.Execute(&subject, contact); err != nil {
+		return nil, err
+	}
+	if err := n.body.Execute(&body, contact); err != nil {
+		return nil, err
+	}
+	return n.message(subject.String(), body.String(), contact.Email), nil
+}
+
+// message builds an email with the given subject and plain-text body, replying to
+// replyTo when it is not empty.
+func (n *EmailNotifier) message(subject, body, replyTo string) []byte {
+	// Keep submitted values from injecting headers through the subject line.
+	subjectLine := strings.Join(strings.Fields(subject), " ")
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.config.Recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subjectLine))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if replyTo != "" {
+		fmt.Fprintf(&msg, "Reply-To: %s\r\n", replyTo)
+	}
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return msg.Bytes()
+}
@@ -0,0 +1,137 @@
+// Package notifications sends notifications about new contacts to the team handling them.
+//
+// This file implements the MatrixNotifier, which posts a message for every new contact
+// to a Matrix room through the client-server API of a homeserver.
+package notifications
+
+import (
+	"api-contact-form/config"
+	"api-contact-form/models"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MatrixNotifier posts new contacts to a Matrix room.
+type MatrixNotifier struct {
+	homeserverURL string
+	accessToken   string
+	roomID        string
+	adminURL      *template.Template
+}
+
+// NewMatrixNotifier creates a MatrixNotifier from the Matrix configuration.
+func NewMatrixNotifier(cfg config.MatrixConfig) (*MatrixNotifier, error) {
+	if cfg.HomeserverURL == "" || cfg.AccessToken == "" || cfg.RoomID == "" {
+		return nil, errors.New("Matrix homeserver URL, access token and room ID are required")
+	}
+
+	adminURL, err := parseAdminURL(cfg.AdminURLTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &MatrixNotifier{
+		homeserverURL: strings.TrimRight(cfg.HomeserverURL, "/"),
+		accessToken:   cfg.AccessToken,
+		roomID:        cfg.RoomID,
+		adminURL:      adminURL,
+	}, nil
+}
+
+// Name implements Notifier.
+func (n *MatrixNotifier) Name() string {
+	return "matrix"
+}
+
+// Send implements Notifier by posting a text message describing the contact, with an
+// HTML version for clients that render it.
+//
+// The transaction ID is derived from the contact ID, so that the homeserver ignores
+// the message when a retry sends it again after a lost response.
+func (n *MatrixNotifier) Send(ctx context.Context, contact models.Contact) error {
+	link, err := renderAdminURL(n.adminURL, contact)
+	if err != nil {
+		return err
+	}
+
+	// Build the plain-text and HTML bodies.
+	plain := fmt.Sprintf("New contact from %s <%s>\nPhone: %s\nChannel: %s\n\n%s",
+		contact.FullName, contact.Email, contact.Phone, contact.Channel, contact.Message)
+	formatted := fmt.Sprintf("<strong>New contact from %s</strong> &lt;%s&gt;<br>Phone: %s<br>Channel: %s<br><br>%s",
+		html.EscapeString(contact.FullName), html.EscapeString(contact.Email), html.EscapeString(contact.Phone),
+		html.EscapeString(string(contact.Channel)),
+		strings.ReplaceAll(html.EscapeString(contact.Message), "\n", "<br>"))
+	if link != "" {
+		plain += "\n\n" + link
+		formatted += fmt.Sprintf(`<br><br><a href="%s">Open contact</a>`, html.EscapeString(link))
+	}
+
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/contact-%d",
+		n.homeserverURL, url.PathEscape(n.roomID), contact.ID)
+	payload := map[string]string{
+		"msgtype":        "m.text",
+		"body":           plain,
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted,
+	}
+	header := http.Header{"Authorization": {"Bearer " + n.accessToken}}
+	return sendJSON(ctx, http.MethodPut, endpoint, payload, header)
+}
+
+// SendDigest implements Notifier by posting a message listing the contacts, with an HTML
+// version for clients that render it.
+//
+// The transaction ID is derived from the listed contact IDs, for the same reason as in Send.
+func (n *MatrixNotifier) SendDigest(ctx context.Context, contacts []models.Contact, window time.Duration) error {
+	listed, more := digestContacts(contacts)
+	title := digestTitle(len(contacts), window)
+
+	// Build the plain-text and HTML lists.
+	plain := title
+	formatted := "<strong>" + html.EscapeString(title) + "</strong><ul>"
+	for _, contact := range listed {
+		plain += fmt.Sprintf("\n- %s <%s> (%s)", contact.FullName, contact.Email, contact.Channel)
+		formatted += fmt.Sprintf("<li>%s &lt;%s&gt; (%s)</li>", html.EscapeString(contact.FullName),
+			html.EscapeString(contact.Email), html.EscapeString(string(contact.Channel)))
+	}
+	formatted += "</ul>"
+	if more > 0 {
+		plain += fmt.Sprintf("\n...and %d more.", more)
+		formatted += fmt.Sprintf("...and %d more.", more)
+	}
+
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/digest-%d-%d-%d",
+		n.homeserverURL, url.PathEscape(n.roomID), contacts[0].ID, contacts[len(contacts)-1].ID, len(contacts))
+	payload := map[string]string{
+		"msgtype":        "m.text",
+		"body":           plain,
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted,
+	}
+	header := http.Header{"Authorization": {"Bearer " + n.accessToken}}
+	return sendJSON(ctx, http.MethodPut, endpoint, payload, header)
+}
+
+// SendAlert implements Alerter by posting a message with the alert, with an HTML version
+// for clients that render it.
+//
+// The transaction ID is derived from the alert key, for the same reason as in Send.
+func (n *MatrixNotifier) SendAlert(ctx context.Context, alert Alert) error {
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/alert-%s",
+		n.homeserverURL, url.PathEscape(n.roomID), url.PathEscape(alert.Key))
+	payload := map[string]string{
+		"msgtype": "m.text",
+		"body":    alert.Title + "\n" + alert.Text,
+		"format":  "org.matrix.custom.html",
+		"formatted_body": "<strong>" + html.EscapeString(alert.Title) + "</strong><br>" +
+			strings.ReplaceAll(html.EscapeString(alert.Text), "\n", "<br>"),
+	}
+	header := http.Header{"Authorization": {"Bearer " + n.accessToken}}
+	return sendJSON(ctx, http.MethodPut, endpoint, payload, header)
+}
@@ -0,0 +1,101 @@
+// Package notifications sends notifications about new contacts to the team handling them.
+//
+// This file implements the ReportMailer, which emails the reports of scheduled exports,
+// attached to the message or as the location of their upload, over the SMTP server of the
+// email notifications.
+package notifications
+
+import (
+	"api-contact-form/config"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Attachment is a file attached to an email.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// ReportMailer emails reports to their recipients.
+type ReportMailer struct {
+	config config.SMTPConfig
+}
+
+// NewReportMailer creates a ReportMailer sending through the SMTP server of cfg.
+// The recipients and templates of cfg are not used.
+func NewReportMailer(cfg config.SMTPConfig) (*ReportMailer, error) {
+	if cfg.Host == "" || cfg.From == "" {
+		return nil, errors.New("SMTP_HOST and SMTP_FROM are required to email reports")
+	}
+	return &ReportMailer{config: cfg}, nil
+}
+
+// SendReport emails the plain-text body to recipients with the given subject, and the
+// attachment when it is not nil.
+func (m *ReportMailer) SendReport(recipients []string, subject, body string, attachment *Attachment) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+
+	text := strings.ReplaceAll(body, "\n", "\r\n")
+	if attachment == nil {
+		msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+		msg.WriteString(text)
+	} else if err := writeMultipart(&msg, text, attachment); err != nil {
+		return err
+	}
+
+	return sendMail(m.config, recipients, msg.Bytes())
+}
+
+// writeMultipart writes the multipart/mixed content of a message with a plain-text part
+// and the attachment, encoded in base64, to msg.
+func writeMultipart(msg *bytes.Buffer, text string, attachment *Attachment) error {
+	parts := multipart.NewWriter(msg)
+	fmt.Fprintf(msg, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", parts.Boundary())
+
+	part, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write([]byte(text)); err != nil {
+		return err
+	}
+
+	part, err = parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {attachment.ContentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+	})
+	if err != nil {
+		return err
+	}
+	// Wrap the encoded file at 76 characters per line, as required by MIME.
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	for len(encoded) > 76 {
+		if _, err := part.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	if _, err := part.Write([]byte(encoded + "\r\n")); err != nil {
+		return err
+	}
+	return parts.Close()
+}
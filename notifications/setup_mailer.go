@@ -0,0 +1,52 @@
+// Package notifications sends notifications about new contacts to the team handling them.
+//
+// This file implements the SetupMailer, which emails the one-time setup link of an
+// invited or reset admin user over the SMTP server of the email notifications.
+package notifications
+
+import (
+	"api-contact-form/config"
+	"api-contact-form/models"
+	"bytes"
+	"fmt"
+	"mime"
+	"time"
+)
+
+// SetupMailer emails setup links to admin users.
+type SetupMailer struct {
+	config config.SMTPConfig
+}
+
+// NewSetupMailer creates a SetupMailer sending through the SMTP server of cfg.
+// The recipients and templates of cfg are not used.
+func NewSetupMailer(cfg config.SMTPConfig) (*SetupMailer, error) {
+	if cfg.Host == "" || cfg.From == "" {
+		return nil, fmt.Errorf("SMTP_HOST and SMTP_FROM are required to email setup links")
+	}
+	return &SetupMailer{config: cfg}, nil
+}
+
+// SendSetupLink emails link to user. reset selects the wording of a password reset
+// rather than of an invitation.
+func (m *SetupMailer) SendSetupLink(user models.AdminUser, link string, reset bool) error {
+	subject := "You are invited to the contact form admin"
+	intro := "An administrator invited you to the contact form admin."
+	if reset {
+		subject = "Reset your contact form admin password"
+		intro = "An administrator reset the password of your contact form admin account."
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", user.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	fmt.Fprintf(&msg, "Hello %s,\r\n\r\n%s\r\nChoose your password with the following link. It can only be used once.\r\n\r\n%s\r\n",
+		user.Name, intro, link)
+
+	return sendMail(m.config, []string{user.Email}, msg.Bytes())
+}
@@ -0,0 +1,225 @@
+// Package notifications sends notifications about new contacts to the team handling them.
+//
+// This file implements the TeamsNotifier, which posts an Adaptive Card for every new
+// contact to a Microsoft Teams incoming webhook.
+package notifications
+
+import (
+	"api-contact-form/config"
+	"api-contact-form/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// httpClient is used by the notifiers posting to HTTP APIs.
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// TeamsNotifier posts new contacts to a Microsoft Teams channel.
+type TeamsNotifier struct {
+	webhookURL string
+	adminURL   *template.Template
+}
+
+// NewTeamsNotifier creates a TeamsNotifier from the Teams configuration.
+func NewTeamsNotifier(cfg config.TeamsConfig) (*TeamsNotifier, error) {
+	if cfg.WebhookURL == "" {
+		return nil, errors.New("Teams webhook URL is required")
+	}
+
+	adminURL, err := parseAdminURL(cfg.AdminURLTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &TeamsNotifier{webhookURL: cfg.WebhookURL, adminURL: adminURL}, nil
+}
+
+// Name implements Notifier.
+func (n *TeamsNotifier) Name() string {
+	return "teams"
+}
+
+// Send implements Notifier by posting an Adaptive Card describing the contact.
+func (n *TeamsNotifier) Send(ctx context.Context, contact models.Contact) error {
+	// Build the card: a title, the contact details, the message and a link to the admin view.
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []interface{}{
+			map[string]interface{}{
+				"type":   "TextBlock",
+				"text":   "New contact from " + contact.FullName,
+				"size":   "Medium",
+				"weight": "Bolder",
+				"wrap":   true,
+			},
+			map[string]interface{}{
+				"type": "FactSet",
+				"facts": []map[string]string{
+					{"title": "Email", "value": contact.Email},
+					{"title": "Phone", "value": contact.Phone},
+					{"title": "Channel", "value": string(contact.Channel)},
+					{"title": "Date", "value": contact.CreatedAt.Format("2006-01-02 15:04:05 MST")},
+				},
+			},
+			map[string]interface{}{
+				"type": "TextBlock",
+				"text": contact.Message,
+				"wrap": true,
+			},
+		},
+	}
+	if link, err := renderAdminURL(n.adminURL, contact); err != nil {
+		return err
+	} else if link != "" {
+		card["actions"] = []map[string]string{
+			{"type": "Action.OpenUrl", "title": "Open contact", "url": link},
+		}
+	}
+
+	payload := map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+	return sendJSON(ctx, http.MethodPost, n.webhookURL, payload, nil)
+}
+
+// SendDigest implements Notifier by posting an Adaptive Card listing the contacts.
+func (n *TeamsNotifier) SendDigest(ctx context.Context, contacts []models.Contact, window time.Duration) error {
+	listed, more := digestContacts(contacts)
+	facts := make([]map[string]string, 0, len(listed))
+	for _, contact := range listed {
+		facts = append(facts, map[string]string{
+			"title": contact.FullName,
+			"value": fmt.Sprintf("%s (%s, %s)", contact.Email, contact.Channel, contact.CreatedAt.Format("15:04:05")),
+		})
+	}
+	body := []interface{}{
+		map[string]interface{}{
+			"type":   "TextBlock",
+			"text":   digestTitle(len(contacts), window),
+			"size":   "Medium",
+			"weight": "Bolder",
+			"wrap":   true,
+		},
+		map[string]interface{}{"type": "FactSet", "facts": facts},
+	}
+	if more > 0 {
+		body = append(body, map[string]interface{}{
+			"type": "TextBlock",
+			"text": fmt.Sprintf("...and %d more.", more),
+			"wrap": true,
+		})
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	payload := map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+	return sendJSON(ctx, http.MethodPost, n.webhookURL, payload, nil)
+}
+
+// SendAlert implements Alerter by posting an Adaptive Card with the alert.
+func (n *TeamsNotifier) SendAlert(ctx context.Context, alert Alert) error {
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []interface{}{
+			map[string]interface{}{
+				"type":   "TextBlock",
+				"text":   alert.Title,
+				"size":   "Medium",
+				"weight": "Bolder",
+				"color":  "Attention",
+				"wrap":   true,
+			},
+			map[string]interface{}{
+				"type": "TextBlock",
+				"text": alert.Text,
+				"wrap": true,
+			},
+		},
+	}
+	payload := map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+	return sendJSON(ctx, http.MethodPost, n.webhookURL, payload, nil)
+}
+
+// parseAdminURL parses the text/template of the admin view link of a contact.
+// An empty template yields a nil template, and no link.
+func parseAdminURL(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("admin-url").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse admin URL template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderAdminURL returns the admin view link of a contact, or an empty string when no
+// admin URL template is configured.
+func renderAdminURL(tmpl *template.Template, contact models.Contact) (string, error) {
+	if tmpl == nil {
+		return "", nil
+	}
+	var link strings.Builder
+	if err := tmpl.Execute(&link, contact); err != nil {
+		return "", err
+	}
+	return link.String(), nil
+}
+
+// sendJSON sends payload as JSON with the given method and extra headers, and fails
+// unless the response has a 2xx status code.
+func sendJSON(ctx context.Context, method, url string, payload interface{}, header http.Header) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
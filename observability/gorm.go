@@ -0,0 +1,69 @@
+// Package observability collects the Prometheus metrics of the API.
+//
+// This file implements the GORM plugin measuring the latency of database statements.
+package observability
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// startedAtKey is the statement setting holding the time a statement started.
+const startedAtKey = "observability:started_at"
+
+// DBMetrics is a GORM plugin observing every statement in DBDuration and DBErrors.
+type DBMetrics struct{}
+
+// Name implements gorm.Plugin.
+func (DBMetrics) Name() string {
+	return "observability:db_metrics"
+}
+
+// Initialize registers the callbacks timing the create, query, update, delete, row and
+// raw statements.
+func (DBMetrics) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	register := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+	for _, r := range register {
+		if err := r.before("observability:before_"+r.operation, start); err != nil {
+			return err
+		}
+		if err := r.after("observability:after_"+r.operation, observe(r.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// start records the time a statement starts.
+func start(db *gorm.DB) {
+	db.InstanceSet(startedAtKey, time.Now())
+}
+
+// observe returns the callback observing the duration and outcome of a statement, with the
+// trace ID of the request it was made for as exemplar.
+func observe(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startedAtKey)
+		if !ok {
+			return
+		}
+		ObserveWithTrace(db.Statement.Context, DBDuration.WithLabelValues(operation), time.Since(value.(time.Time)).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			DBErrors.WithLabelValues(operation).Inc()
+		}
+	}
+}
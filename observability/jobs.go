@@ -0,0 +1,56 @@
+package observability
+
+import (
+	"api-contact-form/jobs"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	jobQueueDepth = prometheus.NewDesc("job_queue_depth",
+		"Jobs waiting in a background queue, by queue.", []string{"queue"}, nil)
+	jobQueueOldestPending = prometheus.NewDesc("job_queue_oldest_pending_seconds",
+		"Time the oldest job waiting in a background queue has been queued for, by queue.", []string{"queue"}, nil)
+	jobFailures = prometheus.NewDesc("job_failures_total",
+		"Jobs the workers of a background queue failed, by queue.", []string{"queue"}, nil)
+)
+
+// jobQueues exports the health of the background job queues watched with WatchJobQueues,
+// read at every scrape, so that the age of the oldest job keeps growing while a queue is stuck.
+var jobQueues = &jobQueueCollector{}
+
+// jobQueueCollector is a prometheus.Collector reporting the Stats of job queues.
+type jobQueueCollector struct {
+	mu     sync.Mutex
+	queues []jobs.Monitor
+}
+
+// WatchJobQueues exports the health of queues in the metrics, replacing the queues
+// watched before.
+func WatchJobQueues(queues ...jobs.Monitor) {
+	jobQueues.mu.Lock()
+	defer jobQueues.mu.Unlock()
+	jobQueues.queues = queues
+}
+
+// Describe sends the descriptors of the job queue metrics.
+func (c *jobQueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- jobQueueDepth
+	ch <- jobQueueOldestPending
+	ch <- jobFailures
+}
+
+// Collect sends the current health of every watched queue.
+func (c *jobQueueCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	queues := c.queues
+	c.mu.Unlock()
+
+	for _, queue := range queues {
+		stats := queue.QueueStats()
+		ch <- prometheus.MustNewConstMetric(jobQueueDepth, prometheus.GaugeValue, float64(stats.Depth), stats.Name)
+		ch <- prometheus.MustNewConstMetric(jobQueueOldestPending, prometheus.GaugeValue, stats.OldestPending.Seconds(), stats.Name)
+		ch <- prometheus.MustNewConstMetric(jobFailures, prometheus.CounterValue, float64(stats.Failures), stats.Name)
+	}
+}
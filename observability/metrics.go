@@ -0,0 +1,106 @@
+// Package observability collects the Prometheus metrics of the API.
+//
+// The metrics are registered on a dedicated registry, served by Handler, together with
+// the Go runtime and process metrics.
+package observability
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registry holds every metric of the API.
+var registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts the handled requests by method, route and status code.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPErrors counts the requests answered with a 4xx or 5xx status code.
+	HTTPErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_request_errors_total",
+		Help: "HTTP requests answered with an error status code, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes the time taken to handle requests.
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP requests, by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// Submissions counts the stored contacts by channel and initial status, e.g. new or spam.
+	Submissions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "contact_submissions_total",
+		Help: "Contacts stored, by channel and initial status.",
+	}, []string{"channel", "status"})
+
+	// BufferedSubmissions is the number of submissions received while the database was
+	// unavailable and not stored yet.
+	BufferedSubmissions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "contact_submissions_buffered",
+		Help: "Submissions received during a database outage and waiting to be stored.",
+	})
+
+	// LoginAttempts counts the sign-in attempts of admin users by outcome: the outcomes of
+	// the audit log, and "refused" for attempts refused during a lockout.
+	LoginAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "admin_login_attempts_total",
+		Help: "Sign-in attempts of admin users, by outcome.",
+	}, []string{"outcome"})
+
+	// DBDuration observes the time taken by database statements.
+	DBDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Time taken by database statements, by operation.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	// DBErrors counts the database statements that failed, not counting missing records.
+	DBErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Database statements that failed, by operation.",
+	}, []string{"operation"})
+
+	// FaultsInjected counts the failures simulated by the fault injection layer, by
+	// target (db, smtp or webhook) and kind of fault.
+	FaultsInjected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "faults_injected_total",
+		Help: "Failures simulated for resilience testing, by target and kind.",
+	}, []string{"target", "kind"})
+
+	// ReplicationLag is the lag of the database replica of a standby region behind the
+	// primary database, as last measured by the replication probe.
+	ReplicationLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "replication_lag_seconds",
+		Help: "Lag of the database replica of a standby region behind the primary database.",
+	})
+
+	// SubmissionSpike is 1 while the spike detector reports submissions far above their
+	// baseline, and 0 otherwise.
+	SubmissionSpike = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "submission_spike",
+		Help: "Whether submissions are spiking above their baseline (1) or not (0).",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPErrors, HTTPDuration, Submissions, BufferedSubmissions, LoginAttempts, DBDuration, DBErrors,
+		FaultsInjected, ReplicationLag, SubmissionSpike, jobQueues,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format, or in the OpenMetrics
+// format, which carries the exemplars of the histograms, to scrapers asking for it.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
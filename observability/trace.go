@@ -0,0 +1,139 @@
+// Package observability collects the Prometheus metrics of the API.
+//
+// This file implements the W3C Trace Context correlation used by OpenTelemetry: the trace
+// of a request is read from its traceparent header, or started, and its trace ID is added
+// to the logs written during the request and to the exemplars of the latency histograms,
+// so that an operator can go from a slow bucket to the trace and the logs of a request.
+package observability
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// traceparentPattern matches the version 00 traceparent headers: the trace ID, the ID of
+// the parent span and the trace flags, in lowercase hex.
+var traceparentPattern = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+// sampledFlag is the trace flag telling that the caller records the trace.
+const sampledFlag = 0x01
+
+// spanKey is the context key of the SpanContext of a request.
+type spanKey struct{}
+
+// SpanContext identifies the span of a request within its trace.
+type SpanContext struct {
+	// TraceID is the 32 hex digits ID of the trace.
+	TraceID string
+	// SpanID is the 16 hex digits ID of the span of the request.
+	SpanID string
+	// ParentID is the ID of the span of the caller, empty when the request started the trace.
+	ParentID string
+	// Sampled reports whether the trace is recorded, as decided by the caller.
+	Sampled bool
+}
+
+// StartSpan returns the SpanContext of a request with the given traceparent header: a new
+// span in the trace of the caller, or in a new sampled trace when the header is missing
+// or invalid.
+func StartSpan(traceparent string) SpanContext {
+	span := SpanContext{SpanID: randomHex(8)}
+	match := traceparentPattern.FindStringSubmatch(traceparent)
+	if match == nil || isZeroHex(match[1]) || isZeroHex(match[2]) {
+		span.TraceID = randomHex(16)
+		span.Sampled = true
+		return span
+	}
+
+	flags, _ := hex.DecodeString(match[3])
+	span.TraceID = match[1]
+	span.ParentID = match[2]
+	span.Sampled = flags[0]&sampledFlag != 0
+	return span
+}
+
+// Traceparent returns the traceparent header propagating the span to downstream calls.
+func (s SpanContext) Traceparent() string {
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+	return "00-" + s.TraceID + "-" + s.SpanID + "-" + flags
+}
+
+// ContextWithSpan returns a copy of ctx carrying span.
+func ContextWithSpan(ctx context.Context, span SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx, if any.
+func SpanFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	span, ok := ctx.Value(spanKey{}).(SpanContext)
+	return span, ok
+}
+
+// ObserveWithTrace observes value in observer, with the trace ID of the span of ctx as
+// exemplar when the trace is sampled.
+func ObserveWithTrace(ctx context.Context, observer prometheus.Observer, value float64) {
+	span, ok := SpanFromContext(ctx)
+	exemplars, supported := observer.(prometheus.ExemplarObserver)
+	if !ok || !span.Sampled || !supported {
+		observer.Observe(value)
+		return
+	}
+	exemplars.ObserveWithExemplar(value, prometheus.Labels{"trace_id": span.TraceID})
+}
+
+// TraceHandler is a slog.Handler adding the trace_id and span_id of the span of the
+// context of every record to the records, before passing them to the wrapped handler.
+type TraceHandler struct {
+	slog.Handler
+}
+
+// NewTraceHandler wraps handler in a TraceHandler.
+func NewTraceHandler(handler slog.Handler) *TraceHandler {
+	return &TraceHandler{Handler: handler}
+}
+
+// Handle adds the trace attributes of ctx to record.
+func (h *TraceHandler) Handle(ctx context.Context, record slog.Record) error {
+	if span, ok := SpanFromContext(ctx); ok {
+		record.AddAttrs(slog.String("trace_id", span.TraceID), slog.String("span_id", span.SpanID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler, keeping the trace attributes.
+func (h *TraceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &TraceHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler, keeping the trace attributes.
+func (h *TraceHandler) WithGroup(name string) slog.Handler {
+	return &TraceHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// randomHex returns n random bytes in hex.
+func randomHex(n int) string {
+	random := make([]byte, n)
+	_, _ = rand.Read(random)
+	return hex.EncodeToString(random)
+}
+
+// isZeroHex reports whether id is made of zeros only, which the specification forbids.
+func isZeroHex(id string) bool {
+	for _, r := range id {
+		if r != '0' {
+			return false
+		}
+	}
+	return true
+}
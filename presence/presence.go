@@ -0,0 +1,241 @@
+// Package presence tracks which agents currently have a contact open, so that two agents
+// do not both answer the same message.
+//
+// Agents announce themselves with heartbeats while they view or reply to a contact, and
+// are forgotten when they leave or stop sending heartbeats. Every change of the agents
+// present on a contact is published to the subscribers, such as the event stream of the
+// admin view. The state is kept in memory: with several replicas, agents only see each
+// other when their requests reach the same replica, as with sticky sessions.
+package presence
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Activity is what an agent is doing with a contact.
+type Activity string
+
+const (
+	// Viewing is used while an agent has the contact open.
+	Viewing Activity = "viewing"
+	// Replying is used while an agent writes a reply to the contact.
+	Replying Activity = "replying"
+)
+
+// Valid reports whether a is one of the known activities.
+func (a Activity) Valid() bool {
+	return a == Viewing || a == Replying
+}
+
+// Viewer is an agent present on a contact.
+type Viewer struct {
+	// Actor identifies the agent, as recorded in the audit trail, such as "user:1".
+	Actor string
+	// Activity is what the agent is doing.
+	Activity Activity
+	// Since is the time the agent arrived.
+	Since time.Time
+	// ExpiresAt is the time the agent is forgotten without a new heartbeat.
+	ExpiresAt time.Time
+}
+
+// Change is the list of the agents present on a contact after it changed.
+type Change struct {
+	ContactID uint
+	Viewers   []Viewer
+}
+
+// subscriberBuffer is the number of changes a slow subscriber may lag behind before
+// changes are dropped for it.
+const subscriberBuffer = 64
+
+// Tracker tracks the agents present on contacts. It is safe for concurrent use.
+type Tracker struct {
+	ttl time.Duration
+
+	mu          sync.Mutex
+	contacts    map[uint]map[string]Viewer
+	subscribers map[chan Change]struct{}
+	closed      bool
+}
+
+// NewTracker creates a Tracker forgetting the agents that sent no heartbeat for ttl.
+func NewTracker(ttl time.Duration) *Tracker {
+	return &Tracker{
+		ttl:         ttl,
+		contacts:    make(map[uint]map[string]Viewer),
+		subscribers: make(map[chan Change]struct{}),
+	}
+}
+
+// TTL returns the time after which agents without a heartbeat are forgotten.
+func (t *Tracker) TTL() time.Duration {
+	return t.ttl
+}
+
+// Heartbeat records that actor is present on a contact with activity, and returns the
+// agents present on it. Arrivals and changes of activity are published.
+func (t *Tracker) Heartbeat(contactID uint, actor string, activity Activity) []Viewer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	viewers := t.contacts[contactID]
+	if viewers == nil {
+		viewers = make(map[string]Viewer)
+		t.contacts[contactID] = viewers
+	}
+	viewer, present := viewers[actor]
+	if !present {
+		viewer = Viewer{Actor: actor, Since: now}
+	}
+	changed := !present || viewer.Activity != activity
+	viewer.Activity = activity
+	viewer.ExpiresAt = now.Add(t.ttl)
+	viewers[actor] = viewer
+
+	if changed {
+		t.publish(contactID)
+	}
+	return t.list(contactID)
+}
+
+// Leave forgets that actor is present on a contact, and publishes the change.
+func (t *Tracker) Leave(contactID uint, actor string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, present := t.contacts[contactID][actor]; !present {
+		return
+	}
+	delete(t.contacts[contactID], actor)
+	if len(t.contacts[contactID]) == 0 {
+		delete(t.contacts, contactID)
+	}
+	t.publish(contactID)
+}
+
+// Viewers returns the agents present on a contact, in order of arrival.
+func (t *Tracker) Viewers(contactID uint) []Viewer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.list(contactID)
+}
+
+// Snapshot returns the agents present on every contact with at least one.
+func (t *Tracker) Snapshot() []Change {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	changes := make([]Change, 0, len(t.contacts))
+	for _, contactID := range slices.Sorted(maps.Keys(t.contacts)) {
+		changes = append(changes, Change{ContactID: contactID, Viewers: t.list(contactID)})
+	}
+	return changes
+}
+
+// Subscribe returns a channel receiving every change, and a function to call once done
+// with it. Changes are dropped for subscribers that do not keep up. The channel is closed
+// when the Tracker is closed.
+func (t *Tracker) Subscribe() (<-chan Change, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ch := make(chan Change, subscriberBuffer)
+	if t.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	t.subscribers[ch] = struct{}{}
+	return ch, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if _, ok := t.subscribers[ch]; ok {
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close closes the channels of the subscribers, so that long-lived streams end when the
+// server shuts down.
+func (t *Tracker) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	for ch := range t.subscribers {
+		delete(t.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Run forgets the agents whose heartbeats stopped, publishing the changes, until ctx is
+// cancelled.
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(max(t.ttl/2, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.expire(time.Now())
+		}
+	}
+}
+
+// expire forgets the agents expired at now.
+func (t *Tracker) expire(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for contactID, viewers := range t.contacts {
+		expired := false
+		for actor, viewer := range viewers {
+			if !now.Before(viewer.ExpiresAt) {
+				delete(viewers, actor)
+				expired = true
+			}
+		}
+		if len(viewers) == 0 {
+			delete(t.contacts, contactID)
+		}
+		if expired {
+			t.publish(contactID)
+		}
+	}
+}
+
+// list returns the agents present on a contact, in order of arrival. t.mu must be held.
+func (t *Tracker) list(contactID uint) []Viewer {
+	viewers := make([]Viewer, 0, len(t.contacts[contactID]))
+	for _, viewer := range t.contacts[contactID] {
+		viewers = append(viewers, viewer)
+	}
+	slices.SortFunc(viewers, func(a, b Viewer) int {
+		if c := a.Since.Compare(b.Since); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Actor, b.Actor)
+	})
+	return viewers
+}
+
+// publish sends the agents present on a contact to the subscribers without blocking.
+// t.mu must be held.
+func (t *Tracker) publish(contactID uint) {
+	change := Change{ContactID: contactID, Viewers: t.list(contactID)}
+	for ch := range t.subscribers {
+		select {
+		case ch <- change:
+		default:
+		}
+	}
+}
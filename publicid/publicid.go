@@ -0,0 +1,181 @@
+// Package publicid encodes the IDs of contacts into short, non-guessable references for
+// public-facing contexts, such as the receipt of a submission and the status lookup, for
+// deployments that do not want to migrate to UUIDs.
+//
+// References follow the Sqids algorithm (https://sqids.org), so that they can also be
+// decoded by the Sqids libraries of other languages given the same alphabet and minimum
+// length. The alphabet acts as a key: keep a custom one private, as anybody knowing it
+// can decode the references. Unlike the reference implementations, no blocklist of words
+// is applied.
+package publicid
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultAlphabet is the default alphabet of Sqids.
+const DefaultAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// maxMinLength is the largest minimum length of references.
+const maxMinLength = 255
+
+// ErrInvalidReference is returned by Decode for references that do not encode an ID.
+var ErrInvalidReference = errors.New("invalid reference")
+
+// Encoder encodes IDs into references and decodes them back. It is safe for concurrent
+// use. A nil Encoder encodes IDs as decimal numbers, for deployments without obfuscation.
+type Encoder struct {
+	alphabet  []byte
+	minLength int
+}
+
+// New creates an Encoder using alphabet, made of at least 3 distinct ASCII characters, and
+// padding references to at least minLength characters.
+func New(alphabet string, minLength int) (*Encoder, error) {
+	if len(alphabet) < 3 {
+		return nil, errors.New("alphabet must have at least 3 characters")
+	}
+	seen := make(map[byte]bool, len(alphabet))
+	for i := 0; i < len(alphabet); i++ {
+		if alphabet[i] > 127 {
+			return nil, errors.New("alphabet must only have ASCII characters")
+		}
+		if seen[alphabet[i]] {
+			return nil, fmt.Errorf("alphabet has %q more than once", alphabet[i])
+		}
+		seen[alphabet[i]] = true
+	}
+	if minLength < 0 || minLength > maxMinLength {
+		return nil, fmt.Errorf("minimum length must be between 0 and %d", maxMinLength)
+	}
+
+	return &Encoder{alphabet: shuffle([]byte(alphabet)), minLength: minLength}, nil
+}
+
+// Encode returns the reference of id.
+func (e *Encoder) Encode(id uint) string {
+	if e == nil {
+		return strconv.FormatUint(uint64(id), 10)
+	}
+	return e.encode(uint64(id))
+}
+
+// Decode returns the ID of reference. Only the references returned by Encode are
+// accepted, so that every ID has a single reference.
+func (e *Encoder) Decode(reference string) (uint, error) {
+	if e == nil {
+		id, err := strconv.ParseUint(reference, 10, 0)
+		if err != nil || id == 0 {
+			return 0, ErrInvalidReference
+		}
+		return uint(id), nil
+	}
+
+	id, ok := e.decode(reference)
+	if !ok || id == 0 || uint64(uint(id)) != id || e.encode(id) != reference {
+		return 0, ErrInvalidReference
+	}
+	return uint(id), nil
+}
+
+// encode encodes a single number as Sqids does.
+func (e *Encoder) encode(number uint64) string {
+	size := uint64(len(e.alphabet))
+
+	// The offset into the alphabet depends on the number.
+	offset := (1 + uint64(e.alphabet[number%size])) % size
+	alphabet := rotate(e.alphabet, int(offset))
+	prefix := alphabet[0]
+	reverse(alphabet)
+
+	id := append([]byte{prefix}, toID(number, alphabet[1:])...)
+
+	// Pad short references with the separator and slices of reshuffled alphabets.
+	if len(id) < e.minLength {
+		id = append(id, alphabet[0])
+		for len(id) < e.minLength {
+			alphabet = shuffle(alphabet)
+			id = append(id, alphabet[:min(e.minLength-len(id), len(alphabet))]...)
+		}
+	}
+	return string(id)
+}
+
+// decode decodes the first number of a Sqids reference.
+func (e *Encoder) decode(reference string) (uint64, bool) {
+	if reference == "" {
+		return 0, false
+	}
+	for i := 0; i < len(reference); i++ {
+		if !contains(e.alphabet, reference[i]) {
+			return 0, false
+		}
+	}
+
+	offset := strings.IndexByte(string(e.alphabet), reference[0])
+	alphabet := rotate(e.alphabet, offset)
+	reverse(alphabet)
+
+	chunk, _, _ := strings.Cut(reference[1:], string(alphabet[0]))
+	if chunk == "" {
+		return 0, false
+	}
+	return toNumber(chunk, alphabet[1:])
+}
+
+// toID writes number in the base of alphabet.
+func toID(number uint64, alphabet []byte) []byte {
+	size := uint64(len(alphabet))
+	var id []byte
+	for {
+		id = append([]byte{alphabet[number%size]}, id...)
+		number /= size
+		if number == 0 {
+			return id
+		}
+	}
+}
+
+// toNumber reads id in the base of alphabet, reporting overflows.
+func toNumber(id string, alphabet []byte) (uint64, bool) {
+	size := uint64(len(alphabet))
+	var number uint64
+	for i := 0; i < len(id); i++ {
+		digit := strings.IndexByte(string(alphabet), id[i])
+		if digit < 0 || number > (^uint64(0)-uint64(digit))/size {
+			return 0, false
+		}
+		number = number*size + uint64(digit)
+	}
+	return number, true
+}
+
+// shuffle returns a copy of alphabet shuffled deterministically, as Sqids does.
+func shuffle(alphabet []byte) []byte {
+	chars := append([]byte(nil), alphabet...)
+	for i, j := 0, len(chars)-1; j > 0; i, j = i+1, j-1 {
+		r := (i*j + int(chars[i]) + int(chars[j])) % len(chars)
+		chars[i], chars[r] = chars[r], chars[i]
+	}
+	return chars
+}
+
+// rotate returns a copy of alphabet starting at offset.
+func rotate(alphabet []byte, offset int) []byte {
+	return append(append([]byte(nil), alphabet[offset:]...), alphabet[:offset]...)
+}
+
+// reverse reverses alphabet in place.
+func reverse(alphabet []byte) {
+	for i, j := 0, len(alphabet)-1; i < j; i, j = i+1, j-1 {
+		alphabet[i], alphabet[j] = alphabet[j], alphabet[i]
+	}
+}
+
+// contains reports whether alphabet has c.
+func contains(alphabet []byte, c byte) bool {
+	return strings.IndexByte(string(alphabet), c) >= 0
+}
@@ -0,0 +1,62 @@
+// Package publicid encodes the IDs of contacts into short, non-guessable references for
+// public-facing contexts, such as the receipt of a submission and the status lookup, for
+// deployments that do not want to migrate to UUIDs.
+//
+// This file defines the Scheme shared by the Encoder and by the Generated public IDs that
+// deployments migrated to UUIDs store with their contacts.
+package publicid
+
+import (
+	"api-contact-form/ids"
+	"api-contact-form/models"
+	"strconv"
+)
+
+// Scheme gives contacts the public IDs shown to submitters, and tells how to find the
+// contact of a public ID.
+type Scheme interface {
+	// Reference returns the public ID of contact.
+	Reference(contact *models.Contact) string
+	// Resolve returns how to find the contact of reference. It returns
+	// ErrInvalidReference for references the scheme never produces.
+	Resolve(reference string) (Lookup, error)
+}
+
+// Lookup finds a contact: by ID when ID is set, and by its stored public ID otherwise.
+type Lookup struct {
+	ID       uint
+	PublicID string
+}
+
+// Reference returns the reference encoding the ID of contact.
+func (e *Encoder) Reference(contact *models.Contact) string {
+	return e.Encode(contact.ID)
+}
+
+// Resolve decodes reference into the ID of its contact.
+func (e *Encoder) Resolve(reference string) (Lookup, error) {
+	id, err := e.Decode(reference)
+	return Lookup{ID: id}, err
+}
+
+// Generated is the Scheme of the UUIDv7 public IDs generated when contacts are created and
+// stored with them.
+type Generated struct{}
+
+// Reference returns the stored public ID of contact. Contacts without one, which are only
+// found before the backfill at startup, are shown with their numeric ID, which Resolve
+// does not accept so that it cannot be guessed.
+func (Generated) Reference(contact *models.Contact) string {
+	if contact.PublicID == nil {
+		return strconv.FormatUint(uint64(contact.ID), 10)
+	}
+	return *contact.PublicID
+}
+
+// Resolve checks that reference is a UUID, to be looked up among the stored public IDs.
+func (Generated) Resolve(reference string) (Lookup, error) {
+	if !ids.ValidUUID(reference) {
+		return Lookup{}, ErrInvalidReference
+	}
+	return Lookup{PublicID: reference}, nil
+}
@@ -0,0 +1,46 @@
+// Package region runs the API in an active-passive multi-region deployment.
+//
+// This file implements the GORM plugin refusing the writes of a standby region, including
+// those of the background jobs, before they reach the read-only replica.
+package region
+
+import "gorm.io/gorm"
+
+// ReadOnly is a GORM plugin failing the create, update and delete statements with
+// ErrReadOnly while Region is a standby. Raw statements are not checked.
+type ReadOnly struct {
+	Region *Region
+}
+
+// Name implements gorm.Plugin.
+func (ReadOnly) Name() string {
+	return "region:read_only"
+}
+
+// Initialize registers the callbacks checking the role of the region before the create,
+// update and delete statements.
+func (p ReadOnly) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	register := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register},
+	}
+	for _, r := range register {
+		if err := r.before("region:before_"+r.operation, p.check); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check fails the statement while the region is a standby. The GORM callbacks skip
+// statements having an error.
+func (p ReadOnly) check(db *gorm.DB) {
+	if p.Region.Standby() {
+		db.AddError(ErrReadOnly)
+	}
+}
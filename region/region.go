@@ -0,0 +1,252 @@
+// Package region runs the API in an active-passive multi-region deployment.
+//
+// Every region runs the API against its own copy of the database. The primary region
+// serves reads and writes; standby regions run against a read-only replica of the primary
+// database and only serve reads, so that the regions never write conflicting data. When
+// the primary region fails, the failover tooling promotes a standby through its promotion
+// endpoint: the local database is promoted when configured to, the standby starts
+// accepting writes, and the promotion hook announces the new primary, e.g. to update DNS.
+package region
+
+import (
+	"api-contact-form/observability"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Role is the part a region plays in the deployment.
+type Role string
+
+const (
+	// RolePrimary serves reads and writes.
+	RolePrimary Role = "primary"
+	// RoleStandby serves reads from a replica, and writes nothing until it is promoted.
+	RoleStandby Role = "standby"
+)
+
+// Valid reports whether r is one of the known roles.
+func (r Role) Valid() bool {
+	return r == RolePrimary || r == RoleStandby
+}
+
+// ErrReadOnly is returned for the writes attempted in a standby region.
+var ErrReadOnly = errors.New("this region is a read-only standby")
+
+// ErrNotStandby is returned when promoting a region that is already the primary.
+var ErrNotStandby = errors.New("this region is already the primary")
+
+// ErrLagUnavailable is returned by ReplicationLag for databases whose replication lag
+// cannot be measured; only Postgres replicas report it.
+var ErrLagUnavailable = errors.New("replication lag is only available for Postgres replicas")
+
+// Config holds the settings of the region.
+type Config struct {
+	// Name identifies the region, such as "eu-west-1".
+	Name string
+	// Role is the role of the region at startup.
+	Role Role
+	// MaxReplicationLag is the replication lag above which a standby reports itself as
+	// lagging; zero disables the limit.
+	MaxReplicationLag time.Duration
+	// PromoteDatabase makes the promotion promote the local Postgres replica as well, for
+	// deployments whose failover tooling leaves it to the API.
+	PromoteDatabase bool
+	// PromoteHookURL receives a POST request announcing the promotion; no request is sent
+	// when it is empty.
+	PromoteHookURL string
+	// PromoteHookTimeout bounds the promotion hook request.
+	PromoteHookTimeout time.Duration
+}
+
+// Status is the replication state of the region.
+type Status struct {
+	Region string `json:"region"`
+	Role   Role   `json:"role"`
+	// ReplicationLag is the lag of the local replica behind the primary database, in
+	// seconds. It is nil on the primary, and when it cannot be measured.
+	ReplicationLag *float64 `json:"replication_lag_seconds"`
+	// MaxReplicationLag is the configured limit, in seconds; zero means no limit.
+	MaxReplicationLag float64 `json:"max_replication_lag_seconds"`
+	// Healthy is false for standbys lagging more than the limit, or whose lag cannot be
+	// measured while a limit is configured.
+	Healthy bool `json:"healthy"`
+	// Error explains why the lag could not be measured.
+	Error string `json:"error,omitempty"`
+}
+
+// Promotion is the outcome of a promotion.
+type Promotion struct {
+	Region     string    `json:"region"`
+	Role       Role      `json:"role"`
+	PromotedAt time.Time `json:"promoted_at"`
+	// DatabasePromoted reports whether the local database was promoted by the API.
+	DatabasePromoted bool `json:"database_promoted"`
+	// HookError explains why the promotion hook failed. The region is promoted anyway.
+	HookError string `json:"hook_error,omitempty"`
+}
+
+// Region tracks the role of the region and promotes it. It is safe for concurrent use.
+type Region struct {
+	config  Config
+	db      *gorm.DB
+	client  *http.Client
+	standby atomic.Bool
+
+	// promoting serializes the promotions.
+	promoting sync.Mutex
+}
+
+// New creates the Region described by cfg, whose database is db.
+func New(db *gorm.DB, cfg Config) (*Region, error) {
+	if !cfg.Role.Valid() {
+		return nil, fmt.Errorf("invalid region role %q, expected primary or standby", cfg.Role)
+	}
+	if cfg.PromoteDatabase && db.Dialector.Name() != "postgres" {
+		return nil, errors.New("only Postgres replicas can be promoted by the API")
+	}
+	r := &Region{config: cfg, db: db, client: &http.Client{Timeout: cfg.PromoteHookTimeout}}
+	r.standby.Store(cfg.Role == RoleStandby)
+	return r, nil
+}
+
+// Name returns the name of the region.
+func (r *Region) Name() string {
+	return r.config.Name
+}
+
+// Role returns the current role of the region.
+func (r *Region) Role() Role {
+	if r.Standby() {
+		return RoleStandby
+	}
+	return RolePrimary
+}
+
+// Standby reports whether the region is a standby, refusing writes.
+func (r *Region) Standby() bool {
+	return r.standby.Load()
+}
+
+// ReplicationLag measures how far the local replica is behind the primary database. A
+// replica that replayed everything it received has no lag, even when the primary was idle
+// for a while. It returns ErrLagUnavailable for databases other than Postgres.
+func (r *Region) ReplicationLag(ctx context.Context) (time.Duration, error) {
+	if r.db.Dialector.Name() != "postgres" {
+		return 0, ErrLagUnavailable
+	}
+
+	var seconds float64
+	err := r.db.WithContext(ctx).Raw(`SELECT CASE
+		WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`).Scan(&seconds).Error
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// Status reports the role of the region and, for standbys, the replication lag compared
+// to the configured limit.
+func (r *Region) Status(ctx context.Context) Status {
+	status := Status{
+		Region:            r.config.Name,
+		Role:              r.Role(),
+		MaxReplicationLag: r.config.MaxReplicationLag.Seconds(),
+		Healthy:           true,
+	}
+	if status.Role != RoleStandby {
+		return status
+	}
+
+	lag, err := r.ReplicationLag(ctx)
+	if err != nil {
+		status.Error = err.Error()
+		status.Healthy = r.config.MaxReplicationLag <= 0
+		return status
+	}
+	seconds := lag.Seconds()
+	observability.ReplicationLag.Set(seconds)
+	status.ReplicationLag = &seconds
+	status.Healthy = r.config.MaxReplicationLag <= 0 || lag <= r.config.MaxReplicationLag
+	return status
+}
+
+// Promote turns the standby region into the primary: the local Postgres replica is
+// promoted when configured to, writes are accepted from then on, and the promotion hook
+// is notified on behalf of actor. It returns ErrNotStandby for the primary region.
+//
+// A failing database promotion leaves the region a standby. A failing hook does not undo
+// the promotion and is reported in the Promotion.
+func (r *Region) Promote(ctx context.Context, actor string) (*Promotion, error) {
+	r.promoting.Lock()
+	defer r.promoting.Unlock()
+
+	if !r.Standby() {
+		return nil, ErrNotStandby
+	}
+
+	// Promote the replica and wait for it to accept writes.
+	promotion := &Promotion{Region: r.config.Name, Role: RolePrimary}
+	if r.config.PromoteDatabase {
+		var promoted bool
+		if err := r.db.WithContext(ctx).Raw("SELECT pg_promote(true, 60)").Scan(&promoted).Error; err != nil {
+			return nil, fmt.Errorf("promote the database: %w", err)
+		}
+		if !promoted {
+			return nil, errors.New("promote the database: the replica was not promoted within 60 seconds")
+		}
+		promotion.DatabasePromoted = true
+	}
+
+	r.standby.Store(false)
+	observability.ReplicationLag.Set(0)
+	promotion.PromotedAt = time.Now().UTC()
+	log.Printf("Region %s promoted to primary by %s", r.config.Name, actor)
+
+	if err := r.notify(ctx, promotion, actor); err != nil {
+		log.Printf("Promotion hook of region %s failed: %v", r.config.Name, err)
+		promotion.HookError = err.Error()
+	}
+	return promotion, nil
+}
+
+// notify posts the promotion to the promotion hook, when one is configured.
+func (r *Region) notify(ctx context.Context, promotion *Promotion, actor string) error {
+	if r.config.PromoteHookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(struct {
+		*Promotion
+		Actor string `json:"actor"`
+	}{promotion, actor})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(context.WithoutCancel(ctx), http.MethodPost, r.config.PromoteHookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("promotion hook answered %s", resp.Status)
+	}
+	return nil
+}
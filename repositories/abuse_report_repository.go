@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed AbuseReportRepository, which stores the reports of
+misused embedded forms sent by site visitors.
+*/
+
+// AbuseReportRepository defines the interface for abuse report data operations.
+type AbuseReportRepository interface {
+	// Create inserts a new report.
+	Create(ctx context.Context, report *models.AbuseReport) error
+
+	// FindPage retrieves the reports with the given status, or every report when status is
+	// empty, newest first, skipping offset and returning at most limit reports.
+	FindPage(ctx context.Context, status models.AbuseReportStatus, limit, offset int) ([]models.AbuseReport, error)
+
+	// FindByID retrieves a report by its ID. It returns gorm.ErrRecordNotFound when there
+	// is none.
+	FindByID(ctx context.Context, id uint) (*models.AbuseReport, error)
+
+	// Update saves the status and resolution of a report.
+	Update(ctx context.Context, report *models.AbuseReport) error
+}
+
+// abuseReportRepository is a GORM-based implementation of AbuseReportRepository.
+type abuseReportRepository struct {
+	db *gorm.DB
+}
+
+// NewAbuseReportRepository constructs a new AbuseReportRepository backed by the provided GORM DB.
+func NewAbuseReportRepository(db *gorm.DB) AbuseReportRepository {
+	return &abuseReportRepository{db: db}
+}
+
+// Create inserts the report using GORM.
+func (r *abuseReportRepository) Create(ctx context.Context, report *models.AbuseReport) error {
+	return r.db.WithContext(ctx).Create(report).Error
+}
+
+// FindPage lists the matching reports using GORM, ordered by ID so that pages are stable.
+func (r *abuseReportRepository) FindPage(ctx context.Context, status models.AbuseReportStatus, limit, offset int) ([]models.AbuseReport, error) {
+	query := r.db.WithContext(ctx).Model(&models.AbuseReport{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	var reports []models.AbuseReport
+	err := query.Order("id DESC").Find(&reports).Error
+	return reports, err
+}
+
+// FindByID looks up a report by its ID and returns it.
+func (r *abuseReportRepository) FindByID(ctx context.Context, id uint) (*models.AbuseReport, error) {
+	var report models.AbuseReport
+	if err := r.db.WithContext(ctx).First(&report, id).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Update saves the status columns of the report.
+func (r *abuseReportRepository) Update(ctx context.Context, report *models.AbuseReport) error {
+	return r.db.WithContext(ctx).Model(report).
+		Select("status", "resolved_by", "resolved_at").
+		Updates(report).Error
+}
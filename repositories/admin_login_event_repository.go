@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed AdminLoginEventRepository, which stores the audit
+log of the sign-in attempts of admin users.
+*/
+
+// LoginEventFilter narrows down the events returned by FindRecent.
+// Zero-valued fields are ignored.
+type LoginEventFilter struct {
+	// Email restricts the results to attempts made with an email address, case-insensitively.
+	Email string
+	// ClientIP restricts the results to attempts received from an IP address.
+	ClientIP string
+	// Outcome restricts the results to attempts with the given outcome.
+	Outcome models.LoginOutcome
+	// Limit is the largest number of events returned.
+	Limit int
+}
+
+// AdminLoginEventRepository defines the interface for sign-in audit log operations.
+type AdminLoginEventRepository interface {
+	// Create inserts a new event record into the database.
+	Create(event *models.AdminLoginEvent) error
+
+	// FindRecent retrieves the most recent events matching the filter, newest first.
+	FindRecent(filter LoginEventFilter) ([]models.AdminLoginEvent, error)
+}
+
+// adminLoginEventRepository is a GORM-based implementation of AdminLoginEventRepository.
+type adminLoginEventRepository struct {
+	db *gorm.DB
+}
+
+// NewAdminLoginEventRepository constructs a new AdminLoginEventRepository backed by the provided GORM DB.
+func NewAdminLoginEventRepository(db *gorm.DB) AdminLoginEventRepository {
+	return &adminLoginEventRepository{db: db}
+}
+
+// Create inserts a new event into the database using GORM.
+func (r *adminLoginEventRepository) Create(event *models.AdminLoginEvent) error {
+	return translateError(r.db.Create(event).Error)
+}
+
+// FindRecent returns the events matching the filter, newest first.
+func (r *adminLoginEventRepository) FindRecent(filter LoginEventFilter) ([]models.AdminLoginEvent, error) {
+	query := r.db.Model(&models.AdminLoginEvent{})
+	if filter.Email != "" {
+		query = query.Where("email = LOWER(?)", filter.Email)
+	}
+	if filter.ClientIP != "" {
+		query = query.Where("client_ip = ?", filter.ClientIP)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+
+	var events []models.AdminLoginEvent
+	if err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
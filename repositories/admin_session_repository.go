@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed AdminSessionRepository, which stores the sessions
+of the admin users signed in on their devices.
+*/
+
+// AdminSessionRepository defines the interface for admin session data operations.
+type AdminSessionRepository interface {
+	// Create inserts a new session record into the database.
+	Create(session *models.AdminSession) error
+
+	// FindByID retrieves a session by primary key, including revoked and expired ones.
+	FindByID(id uint) (*models.AdminSession, error)
+
+	// FindByRefreshToken retrieves the session whose current refresh token has the given
+	// hash, including revoked and expired ones.
+	FindByRefreshToken(hash string) (*models.AdminSession, error)
+
+	// FindActiveByUser retrieves the sessions of a user that are neither revoked nor
+	// expired, most recently used first.
+	FindActiveByUser(userID uint) ([]models.AdminSession, error)
+
+	// Update persists changes to an existing session.
+	Update(session *models.AdminSession) error
+
+	// TouchLastUsed records that the session was used at the given time.
+	TouchLastUsed(id uint, at time.Time) error
+
+	// Revoke revokes a session of a user. It returns gorm.ErrRecordNotFound when the user
+	// has no active session with the ID.
+	Revoke(userID, id uint, at time.Time) error
+
+	// RevokeAll revokes every active session of a user except the one with the ID except,
+	// which may be zero.
+	RevokeAll(userID, except uint, at time.Time) error
+}
+
+// adminSessionRepository is a GORM-based implementation of AdminSessionRepository.
+type adminSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewAdminSessionRepository constructs a new AdminSessionRepository backed by the provided GORM DB.
+func NewAdminSessionRepository(db *gorm.DB) AdminSessionRepository {
+	return &adminSessionRepository{db: db}
+}
+
+// Create inserts a new session into the database using GORM.
+func (r *adminSessionRepository) Create(session *models.AdminSession) error {
+	return translateError(r.db.Create(session).Error)
+}
+
+// FindByID looks up a session by primary key and returns it.
+func (r *adminSessionRepository) FindByID(id uint) (*models.AdminSession, error) {
+	var session models.AdminSession
+	if err := r.db.First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindByRefreshToken looks up a session by the hash of its refresh token.
+func (r *adminSessionRepository) FindByRefreshToken(hash string) (*models.AdminSession, error) {
+	var session models.AdminSession
+	if err := r.db.Where("refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindActiveByUser returns the active sessions of a user, most recently used first.
+func (r *adminSessionRepository) FindActiveByUser(userID uint) ([]models.AdminSession, error) {
+	var sessions []models.AdminSession
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Update persists changes to an existing session record.
+func (r *adminSessionRepository) Update(session *models.AdminSession) error {
+	return translateError(r.db.Save(session).Error)
+}
+
+// TouchLastUsed updates only the last_used_at column of a session.
+func (r *adminSessionRepository) TouchLastUsed(id uint, at time.Time) error {
+	return r.db.Model(&models.AdminSession{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+// Revoke sets revoked_at on an active session of the user.
+func (r *adminSessionRepository) Revoke(userID, id uint, at time.Time) error {
+	result := r.db.Model(&models.AdminSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeAll sets revoked_at on the active sessions of the user but one.
+func (r *adminSessionRepository) RevokeAll(userID, except uint, at time.Time) error {
+	return r.db.Model(&models.AdminSession{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, except).
+		Update("revoked_at", at).Error
+}
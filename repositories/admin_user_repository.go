@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed AdminUserRepository, which stores the accounts
+of the administrators signing in with an email address and a password.
+*/
+
+// AdminUserRepository defines the interface for admin user data operations.
+type AdminUserRepository interface {
+	// Create inserts a new admin user record into the database.
+	Create(user *models.AdminUser) error
+
+	// FindAll retrieves every admin user, including disabled ones, ordered by email.
+	FindAll() ([]models.AdminUser, error)
+
+	// FindByID retrieves an admin user by primary key.
+	FindByID(id uint) (*models.AdminUser, error)
+
+	// FindByEmail retrieves an admin user by email address, case-insensitively.
+	FindByEmail(email string) (*models.AdminUser, error)
+
+	// FindBySetupToken retrieves the user whose pending setup token has the given hash
+	// and has not expired. It returns gorm.ErrRecordNotFound otherwise.
+	FindBySetupToken(hash string) (*models.AdminUser, error)
+
+	// Update persists changes to an existing admin user.
+	Update(user *models.AdminUser) error
+
+	// TouchLastLogin records that the user signed in at the given time.
+	TouchLastLogin(id uint, at time.Time) error
+
+	// IncrementFailedLogins adds a failed sign-in attempt to the counter of a user and
+	// returns the new count.
+	IncrementFailedLogins(id uint) (int, error)
+
+	// Lock locks a user out until the given time and resets their failed attempt counter.
+	// A zero time unlocks the user.
+	Lock(id uint, until time.Time) error
+
+	// UseTOTPStep records step as the last time step of the authenticator app codes
+	// accepted for a user, unless a code of the same or a later step was accepted. It
+	// reports whether the step was recorded.
+	UseTOTPStep(id uint, step int64) (bool, error)
+
+	// ReplaceRecoveryCodes replaces every recovery code of a user with codes of the given
+	// hashes, in a single transaction. No hashes removes the codes.
+	ReplaceRecoveryCodes(userID uint, hashes []string) error
+
+	// UseRecoveryCode marks the unused recovery code of a user with the given hash as used.
+	// It reports whether such a code existed.
+	UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error)
+}
+
+// adminUserRepository is a GORM-based implementation of AdminUserRepository.
+type adminUserRepository struct {
+	db *gorm.DB
+}
+
+// NewAdminUserRepository constructs a new AdminUserRepository backed by the provided GORM DB.
+func NewAdminUserRepository(db *gorm.DB) AdminUserRepository {
+	return &adminUserRepository{db: db}
+}
+
+// Create inserts a new admin user into the database using GORM.
+func (r *adminUserRepository) Create(user *models.AdminUser) error {
+	return translateError(r.db.Create(user).Error)
+}
+
+// FindAll returns every admin user, ordered by email.
+func (r *adminUserRepository) FindAll() ([]models.AdminUser, error) {
+	var users []models.AdminUser
+	if err := r.db.Order("email").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// FindByID looks up an admin user by primary key and returns it.
+func (r *adminUserRepository) FindByID(id uint) (*models.AdminUser, error) {
+	var user models.AdminUser
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindByEmail looks up an admin user by email address.
+func (r *adminUserRepository) FindByEmail(email string) (*models.AdminUser, error) {
+	var user models.AdminUser
+	if err := r.db.Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindBySetupToken looks up the user with a pending, unexpired setup token by its hash.
+func (r *adminUserRepository) FindBySetupToken(hash string) (*models.AdminUser, error) {
+	var user models.AdminUser
+	err := r.db.Where("setup_token_hash = ? AND setup_expires_at > ?", hash, time.Now()).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Update persists changes to an existing admin user record.
+func (r *adminUserRepository) Update(user *models.AdminUser) error {
+	return translateError(r.db.Save(user).Error)
+}
+
+// TouchLastLogin updates only the last_login_at column of a user.
+func (r *adminUserRepository) TouchLastLogin(id uint, at time.Time) error {
+	return r.db.Model(&models.AdminUser{}).Where("id = ?", id).Update("last_login_at", at).Error
+}
+
+// IncrementFailedLogins increments the failed_logins column in the database, so that
+// concurrent attempts are all counted, and reads it back.
+func (r *adminUserRepository) IncrementFailedLogins(id uint) (int, error) {
+	var count int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.AdminUser{}).Where("id = ?", id).
+			UpdateColumn("failed_logins", gorm.Expr("failed_logins + 1")).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.AdminUser{}).Where("id = ?", id).Pluck("failed_logins", &count).Error
+	})
+	return count, err
+}
+
+// Lock sets the locked_until column of a user and clears its failed_logins column.
+func (r *adminUserRepository) Lock(id uint, until time.Time) error {
+	var lockedUntil *time.Time
+	if !until.IsZero() {
+		lockedUntil = &until
+	}
+	return r.db.Model(&models.AdminUser{}).Where("id = ?", id).
+		UpdateColumns(map[string]any{"locked_until": lockedUntil, "failed_logins": 0}).Error
+}
+
+// UseTOTPStep updates only the totp_last_step column; the condition on its current value
+// makes concurrent uses of the same code succeed only once.
+func (r *adminUserRepository) UseTOTPStep(id uint, step int64) (bool, error) {
+	result := r.db.Model(&models.AdminUser{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		UpdateColumn("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReplaceRecoveryCodes deletes the recovery codes of a user and inserts the new ones.
+func (r *adminUserRepository) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.AdminRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(hashes) == 0 {
+			return nil
+		}
+
+		codes := make([]models.AdminRecoveryCode, 0, len(hashes))
+		for _, hash := range hashes {
+			codes = append(codes, models.AdminRecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		return translateError(tx.Create(&codes).Error)
+	})
+}
+
+// UseRecoveryCode sets used_at on the matching unused code; the condition on used_at makes
+// concurrent uses of the same code succeed only once.
+func (r *adminUserRepository) UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error) {
+	result := r.db.Model(&models.AdminRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
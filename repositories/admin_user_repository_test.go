@@ -0,0 +1,46 @@
+package repositories_test
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"testing"
+)
+
+func TestUseTOTPStep(t *testing.T) {
+	db := openDB(t)
+	if err := db.AutoMigrate(&models.AdminUser{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	users := repositories.NewAdminUserRepository(db)
+	user := &models.AdminUser{Email: "admin@example.com", Name: "Admin"}
+	if err := users.Create(user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := users.IncrementFailedLogins(user.ID); err != nil {
+		t.Fatalf("IncrementFailedLogins: %v", err)
+	}
+
+	for _, tt := range []struct {
+		step int64
+		want bool
+	}{{100, true}, {100, false}, {99, false}, {101, true}} {
+		recorded, err := users.UseTOTPStep(user.ID, tt.step)
+		if err != nil {
+			t.Fatalf("UseTOTPStep(%d): %v", tt.step, err)
+		}
+		if recorded != tt.want {
+			t.Errorf("UseTOTPStep(%d) = %v, want %v", tt.step, recorded, tt.want)
+		}
+	}
+
+	stored, err := users.FindByID(user.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if stored.TOTPLastStep != 101 {
+		t.Errorf("TOTPLastStep = %d, want 101", stored.TOTPLastStep)
+	}
+	if stored.FailedLogins != 1 {
+		t.Errorf("FailedLogins = %d, want the failed attempt counted before", stored.FailedLogins)
+	}
+}
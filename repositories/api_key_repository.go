@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed APIKeyRepository, which stores the API keys
+issued to integrations and administrators.
+*/
+
+// APIKeyRepository defines the interface for API key data operations.
+type APIKeyRepository interface {
+	// Create inserts a new API key record into the database.
+	Create(key *models.APIKey) error
+
+	// FindAll retrieves every API key, including revoked and expired ones, newest first.
+	FindAll() ([]models.APIKey, error)
+
+	// FindByID retrieves an API key by primary key.
+	FindByID(id uint) (*models.APIKey, error)
+
+	// FindActiveByHash retrieves the key with the given hash when it is neither
+	// revoked nor expired. It returns gorm.ErrRecordNotFound otherwise.
+	FindActiveByHash(hash string) (*models.APIKey, error)
+
+	// Update persists changes to an existing API key.
+	Update(key *models.APIKey) error
+
+	// TouchLastUsed records that the key was used at the given time.
+	TouchLastUsed(id uint, at time.Time) error
+}
+
+// apiKeyRepository is a GORM-based implementation of APIKeyRepository.
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository constructs a new APIKeyRepository backed by the provided GORM DB.
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// Create inserts a new API key into the database using GORM.
+func (r *apiKeyRepository) Create(key *models.APIKey) error {
+	return translateError(r.db.Create(key).Error)
+}
+
+// FindAll returns every API key, newest first.
+func (r *apiKeyRepository) FindAll() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := r.db.Order("id DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// FindByID looks up an API key by primary key and returns it.
+func (r *apiKeyRepository) FindByID(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.First(&key, id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindActiveByHash looks up a key that is not revoked and has not expired by its hash.
+func (r *apiKeyRepository) FindActiveByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.
+		Where("key_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", hash, time.Now()).
+		First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Update persists changes to an existing API key record.
+func (r *apiKeyRepository) Update(key *models.APIKey) error {
+	return translateError(r.db.Save(key).Error)
+}
+
+// TouchLastUsed updates only the last_used_at column of a key.
+func (r *apiKeyRepository) TouchLastUsed(id uint, at time.Time) error {
+	return r.db.Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+This file provides the GORM-backed APIUsageRepository, which stores the hourly rollups
+of the requests made to the API per caller and endpoint.
+*/
+
+// APIUsageFilter narrows down the usage summarized by Summarize.
+// Zero-valued fields are ignored.
+type APIUsageFilter struct {
+	// From and To restrict the results to the hours starting within [From, To).
+	From time.Time
+	To   time.Time
+	// Caller restricts the results to the requests of a caller, such as "key:2".
+	Caller string
+	// Route restricts the results to the requests of a route, such as "/contacts/:id".
+	Route string
+	// Limit is the largest number of summaries returned.
+	Limit int
+}
+
+// APIUsageSummary is the usage of an endpoint by a caller over the hours of a filter.
+type APIUsageSummary struct {
+	Caller       string
+	Method       string
+	Route        string
+	Requests     int64
+	ClientErrors int64
+	ServerErrors int64
+}
+
+// APIUsageRepository defines the interface for API usage operations.
+type APIUsageRepository interface {
+	// Add adds the counts of the rollups to the stored ones of the same hour, caller and
+	// endpoint, creating the missing ones, in a single statement.
+	Add(ctx context.Context, usage []models.APIUsage) error
+
+	// Summarize sums the usage matching the filter per caller and endpoint, busiest first.
+	Summarize(ctx context.Context, filter APIUsageFilter) ([]APIUsageSummary, error)
+}
+
+// apiUsageRepository is a GORM-based implementation of APIUsageRepository.
+type apiUsageRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIUsageRepository constructs a new APIUsageRepository backed by the provided GORM DB.
+func NewAPIUsageRepository(db *gorm.DB) APIUsageRepository {
+	return &apiUsageRepository{db: db}
+}
+
+// Add upserts the rollups using GORM, incrementing the counts of the existing ones.
+func (r *apiUsageRepository) Add(ctx context.Context, usage []models.APIUsage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+
+	// MySQL refers to the inserted values with VALUES(), the others with "excluded".
+	increment := func(column string) clause.Assignment {
+		expr := gorm.Expr(fmt.Sprintf("%[1]s.%[2]s + excluded.%[2]s", models.APIUsage{}.TableName(), column))
+		if r.db.Dialector.Name() == "mysql" {
+			expr = gorm.Expr(fmt.Sprintf("%[1]s + VALUES(%[1]s)", column))
+		}
+		return clause.Assignment{Column: clause.Column{Name: column}, Value: expr}
+	}
+
+	return translateError(r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hour"}, {Name: "caller"}, {Name: "method"}, {Name: "route"}},
+		DoUpdates: clause.Set{increment("requests"), increment("client_errors"), increment("server_errors")},
+	}).Create(&usage).Error)
+}
+
+// Summarize returns the sums of the usage matching the filter, busiest first.
+func (r *apiUsageRepository) Summarize(ctx context.Context, filter APIUsageFilter) ([]APIUsageSummary, error) {
+	query := r.db.WithContext(ctx).Model(&models.APIUsage{}).
+		Select("caller, method, route, SUM(requests) AS requests, SUM(client_errors) AS client_errors, SUM(server_errors) AS server_errors")
+	if !filter.From.IsZero() {
+		query = query.Where("hour >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("hour < ?", filter.To.UTC())
+	}
+	if filter.Caller != "" {
+		query = query.Where("caller = ?", filter.Caller)
+	}
+	if filter.Route != "" {
+		query = query.Where("route = ?", filter.Route)
+	}
+
+	var summaries []APIUsageSummary
+	err := query.Group("caller, method, route").
+		Order("requests DESC, caller, method, route").
+		Limit(filter.Limit).
+		Scan(&summaries).Error
+	if err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed AttachmentRepository, which reads the metadata
+of the files attached to contacts. The records are inserted together with their contact
+by ContactRepository.Create, or added to an existing contact, such as with the email
+replies of its submitter; the files themselves are kept by the storage package.
+*/
+
+// AttachmentRepository defines the interface for attachment data operations.
+type AttachmentRepository interface {
+	// FindByContact retrieves the attachments of a contact in upload order.
+	FindByContact(contactID uint) ([]models.Attachment, error)
+
+	// FindByID retrieves an attachment of a contact by primary key. It returns
+	// gorm.ErrRecordNotFound when the attachment belongs to another contact.
+	FindByID(contactID, id uint) (*models.Attachment, error)
+
+	// Create inserts the attachments of an existing contact, setting their IDs.
+	Create(attachments []models.Attachment) error
+
+	// SetThumbnail records the storage key of the preview of an attachment, and reports
+	// whether the attachment still exists.
+	SetThumbnail(id uint, key string) (bool, error)
+
+	// FindExpired retrieves up to limit attachments uploaded before the given time, of
+	// live or deleted contacts not under legal hold, oldest first.
+	FindExpired(before time.Time, limit int) ([]models.Attachment, error)
+
+	// Purge removes the attachments of a contact with the given IDs and records the time
+	// of the purge on the contact, in a single transaction, and returns the removed
+	// attachments. Contacts placed under legal hold in the meantime are left untouched,
+	// and nothing is returned for them.
+	Purge(contactID uint, ids []uint, at time.Time) ([]models.Attachment, error)
+}
+
+// attachmentRepository is a GORM-based implementation of AttachmentRepository.
+type attachmentRepository struct {
+	db *gorm.DB
+}
+
+// NewAttachmentRepository constructs a new AttachmentRepository backed by the provided GORM DB.
+func NewAttachmentRepository(db *gorm.DB) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+// FindByContact returns the attachments of a contact, oldest first.
+func (r *attachmentRepository) FindByContact(contactID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	if err := r.db.Where("contact_id = ?", contactID).Order("id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// FindByID looks up an attachment of a contact and returns it.
+func (r *attachmentRepository) FindByID(contactID, id uint) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := r.db.Where("contact_id = ?", contactID).First(&attachment, id).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// Create inserts the attachments in a single statement.
+func (r *attachmentRepository) Create(attachments []models.Attachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+	return r.db.Create(&attachments).Error
+}
+
+// SetThumbnail updates the thumbnail key column alone, leaving the other columns as they are.
+func (r *attachmentRepository) SetThumbnail(id uint, key string) (bool, error) {
+	result := r.db.Model(&models.Attachment{}).Where("id = ?", id).UpdateColumn("thumbnail_key", key)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindExpired returns the attachments eligible for the attachment retention, including
+// those of soft-deleted contacts.
+func (r *attachmentRepository) FindExpired(before time.Time, limit int) ([]models.Attachment, error) {
+	held := r.db.Unscoped().Model(&models.Contact{}).Select("id").Where("legal_hold = ?", true)
+	var attachments []models.Attachment
+	err := r.db.Where("created_at < ? AND contact_id NOT IN (?)", before, held).
+		Order("id").Limit(limit).Find(&attachments).Error
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// Purge leaves the contact untouched when none of the attachments is left.
+func (r *attachmentRepository) Purge(contactID uint, ids []uint, at time.Time) ([]models.Attachment, error) {
+	var purged []models.Attachment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("contact_id = ? AND id IN ?", contactID, ids).Order("id").Find(&purged).Error; err != nil {
+			return err
+		}
+		if len(purged) == 0 {
+			return nil
+		}
+		result := tx.Unscoped().Model(&models.Contact{}).
+			Where("id = ? AND legal_hold = ?", contactID, false).
+			UpdateColumn("attachments_purged_at", at)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			purged = nil
+			return nil
+		}
+		return tx.Delete(&purged).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
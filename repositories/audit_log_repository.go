@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed AuditLogRepository, which stores the audit trail of
+the changes made to contacts.
+*/
+
+// AuditLogFilter narrows down the entries returned by FindRecent.
+// Zero-valued fields are ignored.
+type AuditLogFilter struct {
+	// ContactID restricts the results to the changes of a contact.
+	ContactID uint
+	// Actor restricts the results to the changes made by an actor.
+	Actor string
+	// Action restricts the results to the changes of the given kind.
+	Action models.AuditAction
+	// Limit is the largest number of entries returned.
+	Limit int
+}
+
+// AuditLogRepository defines the interface for audit trail operations. Entries are only
+// ever added and read.
+type AuditLogRepository interface {
+	// Create inserts the entries into the database in a single statement.
+	Create(entries []models.AuditLog) error
+
+	// FindRecent retrieves the most recent entries matching the filter, newest first.
+	FindRecent(filter AuditLogFilter) ([]models.AuditLog, error)
+
+	// FindByContacts retrieves every entry of the contacts with the given IDs, oldest first.
+	FindByContacts(contactIDs []uint) ([]models.AuditLog, error)
+}
+
+// auditLogRepository is a GORM-based implementation of AuditLogRepository.
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository constructs a new AuditLogRepository backed by the provided GORM DB.
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// Create inserts the entries into the database using GORM.
+func (r *auditLogRepository) Create(entries []models.AuditLog) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return translateError(r.db.Create(&entries).Error)
+}
+
+// FindRecent returns the entries matching the filter, newest first.
+func (r *auditLogRepository) FindRecent(filter AuditLogFilter) ([]models.AuditLog, error) {
+	query := r.db.Model(&models.AuditLog{})
+	if filter.ContactID != 0 {
+		query = query.Where("contact_id = ?", filter.ContactID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+
+	var entries []models.AuditLog
+	if err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// FindByContacts returns the entries of the contacts, oldest first.
+func (r *auditLogRepository) FindByContacts(contactIDs []uint) ([]models.AuditLog, error) {
+	var entries []models.AuditLog
+	if len(contactIDs) == 0 {
+		return entries, nil
+	}
+	if err := r.db.Where("contact_id IN ?", contactIDs).Order("created_at, id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+This file provides the GORM-backed AutoReplyTemplateRepository, which stores the
+templates of the auto-replies emailed to submitters, one per language.
+*/
+
+// AutoReplyTemplateRepository defines the interface for auto-reply template data operations.
+type AutoReplyTemplateRepository interface {
+	// FindAll retrieves every template, ordered by language.
+	FindAll(ctx context.Context) ([]models.AutoReplyTemplate, error)
+
+	// FindByLanguage retrieves the template of a language. It returns
+	// gorm.ErrRecordNotFound when the language has none.
+	FindByLanguage(ctx context.Context, language string) (*models.AutoReplyTemplate, error)
+
+	// Save creates the template of its language, or replaces the subject and body of the
+	// existing one.
+	Save(ctx context.Context, template *models.AutoReplyTemplate) error
+
+	// Delete removes the template of a language. It returns gorm.ErrRecordNotFound when
+	// the language has none.
+	Delete(ctx context.Context, language string) error
+}
+
+// autoReplyTemplateRepository is a GORM-based implementation of AutoReplyTemplateRepository.
+type autoReplyTemplateRepository struct {
+	db *gorm.DB
+}
+
+// NewAutoReplyTemplateRepository constructs a new AutoReplyTemplateRepository backed by the provided GORM DB.
+func NewAutoReplyTemplateRepository(db *gorm.DB) AutoReplyTemplateRepository {
+	return &autoReplyTemplateRepository{db: db}
+}
+
+// FindAll fetches every template from the database.
+func (r *autoReplyTemplateRepository) FindAll(ctx context.Context) ([]models.AutoReplyTemplate, error) {
+	var templates []models.AutoReplyTemplate
+	err := r.db.WithContext(ctx).Order("language ASC").Find(&templates).Error
+	return templates, err
+}
+
+// FindByLanguage looks up the template of a language and returns it.
+func (r *autoReplyTemplateRepository) FindByLanguage(ctx context.Context, language string) (*models.AutoReplyTemplate, error) {
+	var template models.AutoReplyTemplate
+	if err := r.db.WithContext(ctx).Where("language = ?", language).First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// Save upserts the template using GORM, keeping the creation time of an existing one.
+func (r *autoReplyTemplateRepository) Save(ctx context.Context, template *models.AutoReplyTemplate) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "language"}},
+		DoUpdates: clause.AssignmentColumns([]string{"subject", "body", "updated_at"}),
+	}).Create(template).Error
+}
+
+// Delete deletes the template of a language.
+func (r *autoReplyTemplateRepository) Delete(ctx context.Context, language string) error {
+	result := r.db.WithContext(ctx).Where("language = ?", language).Delete(&models.AutoReplyTemplate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+/*
+This file maps the constraint violations reported by the databases to domain errors, so
+that a write rejected by a unique index, a foreign key or a check constraint is reported
+to the client as a conflict or an invalid field rather than as an internal error.
+
+Every supported database reports violations differently: Postgres with SQLSTATE codes
+and the name of the constraint, MySQL with error numbers, and SQLite in the error
+message only. The violations are translated by the repositories, on every write.
+*/
+
+var (
+	// ErrUniqueViolation is wrapped by the ConstraintError of a value that must be unique
+	// and is already taken.
+	ErrUniqueViolation = errors.New("value already exists")
+	// ErrForeignKeyViolation is wrapped by the ConstraintError of a reference to a record
+	// that does not exist, or of the deletion of a record that is still referenced.
+	ErrForeignKeyViolation = errors.New("referenced record does not exist or is still referenced")
+	// ErrCheckViolation is wrapped by the ConstraintError of a value rejected by a check
+	// constraint.
+	ErrCheckViolation = errors.New("value violates a check constraint")
+)
+
+// ConstraintError reports a write rejected by a constraint of the database. It wraps
+// ErrUniqueViolation, ErrForeignKeyViolation or ErrCheckViolation.
+type ConstraintError struct {
+	// Err is the kind of violation.
+	Err error
+	// Constraint is the name of the violated constraint or index, when the database
+	// reports it.
+	Constraint string
+	// Table and Column locate the offending value, when the database reports them.
+	Table  string
+	Column string
+}
+
+// Error implements the error interface.
+func (e *ConstraintError) Error() string {
+	if e.Column == "" {
+		return e.Err.Error()
+	}
+	return e.Column + ": " + e.Err.Error()
+}
+
+// Unwrap returns the kind of violation, so that errors.Is matches it.
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// postgresViolations maps the SQLSTATE codes of Postgres to the kinds of violation.
+var postgresViolations = map[string]error{
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKeyViolation,
+	"23514": ErrCheckViolation,
+}
+
+// mysqlViolations maps the error numbers of MySQL to the kinds of violation.
+var mysqlViolations = map[uint16]error{
+	1062: ErrUniqueViolation,
+	1451: ErrForeignKeyViolation,
+	1452: ErrForeignKeyViolation,
+	3819: ErrCheckViolation,
+}
+
+// sqliteViolations maps the messages of SQLite to the kinds of violation. The messages
+// are followed by the offending columns, as "table.column", or by the name of the
+// violated index or constraint.
+var sqliteViolations = map[string]error{
+	"UNIQUE constraint failed":      ErrUniqueViolation,
+	"FOREIGN KEY constraint failed": ErrForeignKeyViolation,
+	"CHECK constraint failed":       ErrCheckViolation,
+}
+
+// postgresKeyColumn extracts the first column from the detail of a Postgres violation,
+// such as "Key (message_id)=(x) already exists." or "Key (lower(email_address::text))=(x)".
+var postgresKeyColumn = regexp.MustCompile(`^Key \((?:\w+\()?(\w+)`)
+
+// mysqlKeyName extracts the table and index from the message of a MySQL duplicate entry,
+// such as "Duplicate entry 'x' for key 'table.index'".
+var mysqlKeyName = regexp.MustCompile(`for key '(?:(\w+)\.)?(\w+)'`)
+
+// translateError maps unique violations of OpenEmailIndex to ErrOpenContactExists and
+// other constraint violations to a *ConstraintError, and returns other errors unchanged.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		kind, ok := postgresViolations[pgErr.Code]
+		if !ok {
+			return err
+		}
+		if pgErr.ConstraintName == OpenEmailIndex {
+			return ErrOpenContactExists
+		}
+		column := pgErr.ColumnName
+		if match := postgresKeyColumn.FindStringSubmatch(pgErr.Detail); column == "" && match != nil {
+			column = match[1]
+		}
+		return &ConstraintError{Err: kind, Constraint: pgErr.ConstraintName, Table: pgErr.TableName, Column: column}
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		kind, ok := mysqlViolations[mysqlErr.Number]
+		if !ok {
+			return err
+		}
+		violation := &ConstraintError{Err: kind}
+		if match := mysqlKeyName.FindStringSubmatch(mysqlErr.Message); match != nil {
+			violation.Table, violation.Constraint = match[1], match[2]
+			// GORM names the index of a single column idx_<table>_<column>
+			if column, ok := strings.CutPrefix(match[2], "idx_"+match[1]+"_"); ok && match[1] != "" {
+				violation.Column = column
+			}
+		}
+		return violation
+	}
+
+	// SQLite only reports the violation in the error message
+	message := err.Error()
+	for prefix, kind := range sqliteViolations {
+		_, target, found := strings.Cut(message, prefix)
+		if !found {
+			continue
+		}
+		violation := &ConstraintError{Err: kind}
+		target = strings.TrimLeft(target, ": ")
+		switch {
+		case strings.HasPrefix(target, "index '"):
+			violation.Constraint, _, _ = strings.Cut(strings.TrimPrefix(target, "index '"), "'")
+		case target != "" && !strings.HasPrefix(target, "("):
+			// Only the first of several columns is kept, like on Postgres
+			name, _, _ := strings.Cut(target, " ")
+			name = strings.TrimSuffix(name, ",")
+			if table, column, ok := strings.Cut(name, "."); ok {
+				violation.Table, violation.Column = table, column
+			} else {
+				violation.Constraint = name
+			}
+		}
+		if violation.Constraint == OpenEmailIndex {
+			return ErrOpenContactExists
+		}
+		return violation
+	}
+	return err
+}
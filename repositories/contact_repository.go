@@ -2,8 +2,14 @@ package repositories
 
 import (
 	"api-contact-form/models"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 /*
@@ -18,26 +24,208 @@ on GORM's soft-delete behavior (gorm.DeletedAt in the model) so that:
 See GORM documentation for Delete / Soft Delete behavior.
 */
 
-// ContactRepository defines the interface for contact data operations.
+// OpenEmailIndex is the name of the optional partial unique index allowing a single
+// open (not soft-deleted) contact per email address.
+const OpenEmailIndex = "idx_contact_messages_open_email"
+
+// createBatchSize is the number of contacts CreateBatch inserts per statement.
+const createBatchSize = 100
+
+// ErrOpenContactExists is returned by Create and Update when the instance allows a single
+// open contact per email address and another one is still open.
+var ErrOpenContactExists = errors.New("an open contact with this email address already exists")
+
+// ErrUnsupportedByDriver is returned by queries relying on a feature the configured
+// database does not provide, such as the trigram similarity of Postgres.
+var ErrUnsupportedByDriver = errors.New("not supported by the configured database driver")
+
+// ContactFilter narrows down the contacts returned by FindAll.
+// Zero-valued fields are ignored.
+type ContactFilter struct {
+	// Channel restricts the results to contacts submitted through the given channel.
+	Channel models.Channel
+	// FingerprintHash restricts the results to contacts sent from the same client fingerprint.
+	FingerprintHash string
+	// Status restricts the results to contacts with the given status.
+	Status models.Status
+	// Email restricts the results to contacts of an email address, case-insensitively.
+	Email string
+	// CreatedFrom and CreatedTo restrict the results to contacts submitted in [CreatedFrom, CreatedTo).
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// MinLeadScore restricts the results to contacts with at least the given lead score.
+	MinLeadScore *int
+	// Search restricts the results to contacts whose message contains the text, case-insensitively,
+	// or whose name or email address resembles it, so that misspelled names are still found.
+	Search string
+	// SearchThreshold is the smallest trigram word similarity, between 0 and 1, of a name or
+	// email address matching Search. DefaultSearchThreshold is used when it is zero.
+	SearchThreshold float64
+	// StarredBy restricts the results to the contacts starred by an agent, such as "user:1".
+	StarredBy string
+}
+
+// DefaultSearchThreshold is the search similarity used when ContactFilter.SearchThreshold is zero.
+// It is low enough for "jonh" to find "John".
+const DefaultSearchThreshold = 0.3
+
+// ContactRepository defines the interface for contact data operations. Every method runs
+// its queries with the given context, so that they are cancelled together with it.
+// Writes rejected by a constraint of the database return a *ConstraintError.
 type ContactRepository interface {
-	// Create inserts a new contact record into the database.
-	Create(contact *models.Contact) error
+	// WithTx runs fn in a database transaction, with a repository whose methods all take
+	// part in it. The transaction is committed when fn returns nil and rolled back when it
+	// returns an error or panics. Calling WithTx on the repository given to fn nests a
+	// transaction, using a savepoint.
+	WithTx(ctx context.Context, fn func(repo ContactRepository) error) error
+
+	// Create inserts a new contact record into the database, together with its
+	// attachments in the same transaction.
+	// It returns ErrOpenContactExists when OpenEmailIndex rejects the contact.
+	Create(ctx context.Context, contact *models.Contact) error
 
-	// FindAll retrieves all non-deleted contacts.
+	// FindAll retrieves all non-deleted contacts matching the filter, newest first.
 	// Note: GORM automatically excludes soft-deleted rows when the model
 	// uses gorm.DeletedAt.
-	FindAll() ([]models.Contact, error)
+	FindAll(ctx context.Context, filter ContactFilter) ([]models.Contact, error)
+
+	// FindInBatches calls fn with consecutive batches of at most batchSize non-deleted
+	// contacts matching the filter, in ID order, until all were read or fn fails.
+	FindInBatches(ctx context.Context, filter ContactFilter, batchSize int, fn func(contacts []models.Contact) error) error
+
+	// FindPaged retrieves a sorted page of non-deleted contacts matching the
+	// filter of params, along with the number of matching contacts.
+	FindPaged(ctx context.Context, params ListParams) (*ContactPage, error)
 
 	// FindByID retrieves a contact by primary key (ID). Soft-deleted records
 	// are excluded by default.
-	FindByID(id uint) (*models.Contact, error)
+	FindByID(ctx context.Context, id uint) (*models.Contact, error)
+
+	// FindByPublicID retrieves a non-deleted contact by its generated public ID. It
+	// returns gorm.ErrRecordNotFound when there is none.
+	FindByPublicID(ctx context.Context, publicID string) (*models.Contact, error)
+
+	// FindByIDs retrieves the non-deleted contacts with the given IDs, ordered by ID.
+	// It returns gorm.ErrRecordNotFound when any of them does not exist.
+	FindByIDs(ctx context.Context, ids []uint) ([]models.Contact, error)
+
+	// FindExistingByIDs retrieves the non-deleted contacts among the given IDs, ordered
+	// by ID. Unlike FindByIDs, missing contacts are skipped rather than an error.
+	FindExistingByIDs(ctx context.Context, ids []uint) ([]models.Contact, error)
+
+	// Search retrieves a page of the non-deleted contacts whose message matches a
+	// full-text query, most relevant first. See SearchParams.
+	Search(ctx context.Context, params SearchParams) (*SearchPage, error)
+
+	// FindDuplicateGroups groups the non-deleted contacts that are likely duplicates of
+	// each other. See DuplicateCriteria.
+	FindDuplicateGroups(ctx context.Context, criteria DuplicateCriteria) ([]DuplicateGroup, error)
+
+	// FindAllByEmail retrieves every contact submitted with the given email
+	// address, including soft-deleted contacts.
+	FindAllByEmail(ctx context.Context, email string) ([]models.Contact, error)
+
+	// CountByEmailSince counts the contacts submitted with the given email address,
+	// case-insensitively, since the given time, including soft-deleted contacts.
+	CountByEmailSince(ctx context.Context, email string, since time.Time) (int64, error)
+
+	// CountByChannelsSince counts the contacts submitted through one of the given channels
+	// since the given time, including soft-deleted contacts.
+	CountByChannelsSince(ctx context.Context, channels []models.Channel, since time.Time) (int64, error)
+
+	// FindRepeatedSince retrieves the earliest non-deleted contact of an email address,
+	// case-insensitively, with the given message hash, submitted since the given time.
+	// It returns gorm.ErrRecordNotFound when there is none.
+	FindRepeatedSince(ctx context.Context, email, messageHash string, since time.Time) (*models.Contact, error)
+
+	// ExistsByMessageID reports whether a contact was already created from the
+	// email with the given Message-ID, including soft-deleted contacts. Message-IDs
+	// are stored and compared without their angle brackets.
+	ExistsByMessageID(ctx context.Context, messageID string) (bool, error)
 
 	// Update persists changes to an existing contact.
-	Update(contact *models.Contact) error
+	// It returns ErrOpenContactExists when OpenEmailIndex rejects the change.
+	Update(ctx context.Context, contact *models.Contact) error
+
+	// UpdateStatus sets the status of a contact and records when it changed.
+	// It returns gorm.ErrRecordNotFound when no contact has the ID.
+	UpdateStatus(ctx context.Context, id uint, status models.Status) error
+
+	// FindSnoozeDue retrieves up to limit live contacts whose snooze ended at the given
+	// time, the earliest ending first.
+	FindSnoozeDue(ctx context.Context, at time.Time, limit int) ([]models.Contact, error)
+
+	// EndSnooze clears the snooze of a live contact whose snooze ended at the given time,
+	// and sets its status back to new. It returns gorm.ErrRecordNotFound when no such
+	// contact has the ID, such as when it was snoozed again in the meantime.
+	EndSnooze(ctx context.Context, id uint, at time.Time) error
 
 	// Delete performs a soft-delete for the provided contact (sets deleted_at).
-	// For a hard delete, callers can use db.Unscoped().Delete(...) directly.
-	Delete(contact *models.Contact) error
+	// For a hard delete of a soft-deleted contact, use HardDelete.
+	Delete(ctx context.Context, contact *models.Contact) error
+
+	// DeleteMany soft-deletes the contacts with the given IDs. When mergedInto is not
+	// zero, the contacts are recorded as merged into that contact.
+	DeleteMany(ctx context.Context, ids []uint, mergedInto uint) error
+
+	// FindDeleted retrieves a page of soft-deleted contacts, most recently deleted first,
+	// along with the number of deleted contacts. Only the Limit, Offset and Cursor of
+	// params are used.
+	FindDeleted(ctx context.Context, params ListParams) (*ContactPage, error)
+
+	// FindDeletedByID retrieves a soft-deleted contact by primary key. It returns
+	// gorm.ErrRecordNotFound when no deleted contact has the ID.
+	FindDeletedByID(ctx context.Context, id uint) (*models.Contact, error)
+
+	// Restore undoes the soft-delete of a contact. It returns gorm.ErrRecordNotFound
+	// when no soft-deleted contact has the ID, and ErrOpenContactExists when
+	// OpenEmailIndex rejects the restored contact.
+	Restore(ctx context.Context, id uint) error
+
+	// CreateBatch inserts several contacts in batches, in a single transaction: either
+	// every contact is inserted or none is.
+	CreateBatch(ctx context.Context, contacts []models.Contact) error
+
+	// DeleteByIDs soft-deletes the contacts with the given IDs in a single transaction.
+	// Contacts under legal hold are left untouched, even when the hold was placed after
+	// the caller checked it.
+	DeleteByIDs(ctx context.Context, ids []uint) error
+
+	// UpdateStatusByIDs sets the status of the contacts with the given IDs in a single
+	// transaction, and records the time of the change.
+	UpdateStatusByIDs(ctx context.Context, ids []uint, status models.Status) error
+
+	// SetCompany records the company found by the enrichment on a contact, without changing
+	// its other fields. It returns gorm.ErrRecordNotFound when no live contact has the ID.
+	SetCompany(ctx context.Context, id uint, name, size string) error
+
+	// SetStarred stars the live contact with the ID for owner, or removes the star. Starring
+	// a contact twice or removing a missing star changes nothing. It returns
+	// gorm.ErrRecordNotFound when no live contact has the ID.
+	SetStarred(ctx context.Context, owner string, id uint, starred bool) error
+
+	// SetEmailIssue records an email issue reported at the given time on the non-deleted
+	// contacts with the given IDs, in a single transaction.
+	SetEmailIssue(ctx context.Context, ids []uint, issue models.EmailIssue, at time.Time) error
+
+	// HardDelete permanently removes a soft-deleted contact that is not under legal hold,
+	// and redacts the personal data of its audit trail in the same transaction.
+	// It returns gorm.ErrRecordNotFound when no such contact has the ID.
+	HardDelete(ctx context.Context, id uint) error
+
+	// FindExpiredDeleted retrieves up to limit contacts soft-deleted before the given time
+	// and not under legal hold, oldest first.
+	FindExpiredDeleted(ctx context.Context, before time.Time, limit int) ([]models.Contact, error)
+
+	// FindAnonymizable retrieves up to limit live contacts with one of the given statuses
+	// since before the given time, that are neither anonymized nor under legal hold.
+	FindAnonymizable(ctx context.Context, before time.Time, statuses []models.Status, limit int) ([]models.Contact, error)
+
+	// Anonymize writes the anonymized personal data of the contacts, as set by
+	// models.Contact.Anonymize, deletes the emails of their conversations and redacts the
+	// personal data of their audit trail, in a single transaction. Contacts placed under
+	// legal hold in the meantime are left untouched.
+	Anonymize(ctx context.Context, contacts []models.Contact) error
 }
 
 // contactRepository is a GORM-based implementation of ContactRepository.
@@ -50,49 +238,578 @@ func NewContactRepository(db *gorm.DB) ContactRepository {
 	return &contactRepository{db: db}
 }
 
+// WithTx runs fn with a repository bound to a GORM transaction.
+func (r *contactRepository) WithTx(ctx context.Context, fn func(repo ContactRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&contactRepository{db: tx})
+	})
+}
+
 // Create inserts a new contact into the database using GORM.
 //
-// On success, the contact struct will have its ID and timestamps populated by GORM.
-func (r *contactRepository) Create(contact *models.Contact) error {
-	return r.db.Create(contact).Error
+// On success, the contact struct will have its ID and timestamps populated by GORM. GORM
+// saves the Attachments association in the transaction of the insert.
+func (r *contactRepository) Create(ctx context.Context, contact *models.Contact) error {
+	return translateError(r.db.WithContext(ctx).Create(contact).Error)
 }
 
-// FindAll returns all contacts that are not soft-deleted.
+// FindAll returns all contacts that are not soft-deleted and match the filter, newest first.
 //
 // This relies on GORM's global soft-delete scope (models with gorm.DeletedAt
 // are excluded automatically from normal queries).
-func (r *contactRepository) FindAll() ([]models.Contact, error) {
+func (r *contactRepository) FindAll(ctx context.Context, filter ContactFilter) ([]models.Contact, error) {
 	var contacts []models.Contact
-	if err := r.db.Find(&contacts).Error; err != nil {
+	err := r.withSearch(ctx, filter, func(db *gorm.DB) error {
+		return applyFilter(db, filter).Order("created_at DESC, id DESC").Find(&contacts).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return contacts, nil
 }
 
+// FindInBatches reads the matching contacts with GORM's FindInBatches, which pages
+// through them by primary key so that only one batch is held in memory at a time.
+func (r *contactRepository) FindInBatches(ctx context.Context, filter ContactFilter, batchSize int, fn func(contacts []models.Contact) error) error {
+	var batch []models.Contact
+	return r.withSearch(ctx, filter, func(db *gorm.DB) error {
+		return applyFilter(db.Model(&models.Contact{}), filter).
+			FindInBatches(&batch, batchSize, func(*gorm.DB, int) error {
+				return fn(batch)
+			}).Error
+	})
+}
+
+// FindPaged returns a page of contacts that are not soft-deleted and match the filter.
+//
+// Contacts are sorted on params.SortBy with the ID as tie-breaker, so that pages are
+// stable when several contacts share a sort value. The page is selected by cursor
+// when params.Cursor is set, and by offset otherwise.
+func (r *contactRepository) FindPaged(ctx context.Context, params ListParams) (*ContactPage, error) {
+	sortBy := params.SortBy
+	if sortBy == "" {
+		sortBy = SortByCreatedAt
+	}
+	if !sortBy.Valid() {
+		return nil, errors.New("invalid sort field")
+	}
+
+	limit := pageLimit(params.Limit)
+
+	page := &ContactPage{}
+	err := r.withSearch(ctx, params.Filter, func(db *gorm.DB) error {
+		// Count every contact matching the filter.
+		query := applyFilter(db.Model(&models.Contact{}), params.Filter)
+		if err := query.Count(&page.Total).Error; err != nil {
+			return err
+		}
+
+		// Position the page after the cursor, or at the offset.
+		direction, comparison := "DESC", "<"
+		if params.Ascending {
+			direction, comparison = "ASC", ">"
+		}
+		column := string(sortBy)
+
+		if params.Cursor != "" {
+			value, id, err := DecodeCursor(sortBy, params.Cursor)
+			if err != nil {
+				return err
+			}
+			query = query.Where("("+column+", id) "+comparison+" (?, ?)", value, id)
+		} else if params.Offset > 0 {
+			query = query.Offset(params.Offset)
+		}
+
+		// Fetch one extra contact to learn whether another page follows.
+		err := query.Order(column + " " + direction + ", id " + direction).Limit(limit + 1).Find(&page.Contacts).Error
+		if err != nil {
+			return err
+		}
+		if len(page.Contacts) > limit {
+			page.Contacts = page.Contacts[:limit]
+			page.NextCursor = EncodeCursor(sortBy, &page.Contacts[limit-1])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
 // FindByID looks up a contact by primary key and returns it.
 //
 // If no record is found, GORM will return an error (e.g., gorm.ErrRecordNotFound).
-// Soft-deleted records are excluded by default; use r.db.Unscoped().First(...) if you
+// Soft-deleted records are excluded by default; use r.db.WithContext(ctx).Unscoped().First(...) if you
 // intentionally need deleted records.
-func (r *contactRepository) FindByID(id uint) (*models.Contact, error) {
+func (r *contactRepository) FindByID(ctx context.Context, id uint) (*models.Contact, error) {
+	var contact models.Contact
+	if err := r.db.WithContext(ctx).First(&contact, id).Error; err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// FindByPublicID looks up a contact by its public ID and returns it.
+func (r *contactRepository) FindByPublicID(ctx context.Context, publicID string) (*models.Contact, error) {
 	var contact models.Contact
-	if err := r.db.First(&contact, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("public_id = ?", publicID).First(&contact).Error; err != nil {
 		return nil, err
 	}
 	return &contact, nil
 }
 
+// FindByIDs looks up several contacts by primary key.
+func (r *contactRepository) FindByIDs(ctx context.Context, ids []uint) ([]models.Contact, error) {
+	var contacts []models.Contact
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("id").Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	if len(contacts) != len(ids) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return contacts, nil
+}
+
+// FindAllByEmail returns every contact submitted with the given email address.
+//
+// The match is case-insensitive and Unscoped, so soft-deleted contacts are included;
+// this is what data subject requests need, since the rows are still stored.
+func (r *contactRepository) FindAllByEmail(ctx context.Context, email string) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := r.db.WithContext(ctx).Unscoped().Where("LOWER(email_address) = LOWER(?)", email).Order("id").Find(&contacts).Error
+	if err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+// CountByEmailSince counts the recent contacts of an email address. Deleted contacts
+// are counted too, so that deleting spam does not reset the count of its sender.
+func (r *contactRepository) CountByEmailSince(ctx context.Context, email string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Contact{}).
+		Where("LOWER(email_address) = LOWER(?) AND created_at >= ?", email, since).
+		Count(&count).Error
+	return count, err
+}
+
+// CountByChannelsSince counts the recent contacts of some channels. Deleted contacts are
+// counted too, so that deleting a submission does not free a place under a cap.
+func (r *contactRepository) CountByChannelsSince(ctx context.Context, channels []models.Channel, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Contact{}).
+		Where("channel IN ? AND created_at >= ?", channels, since).
+		Count(&count).Error
+	return count, err
+}
+
+// FindRepeatedSince looks up the earliest recent contact repeating a message. The
+// lookup of the address and its recent contacts is served by the lower-case email index.
+func (r *contactRepository) FindRepeatedSince(ctx context.Context, email, messageHash string, since time.Time) (*models.Contact, error) {
+	var contact models.Contact
+	err := r.db.WithContext(ctx).Where("LOWER(email_address) = LOWER(?) AND created_at >= ? AND message_hash = ?", email, since, messageHash).
+		Order("id").
+		First(&contact).Error
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// ExistsByMessageID reports whether a contact with the given Message-ID exists.
+//
+// The lookup is Unscoped so that an email whose contact was deleted is not
+// ingested again on the next delivery or poll.
+func (r *contactRepository) ExistsByMessageID(ctx context.Context, messageID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Contact{}).Where("message_id = ?", messageID).Count(&count).Error
+	return count > 0, err
+}
+
 // Update persists changes to an existing contact record.
 //
 // This uses Save(...) which performs an update based on the primary key.
-func (r *contactRepository) Update(contact *models.Contact) error {
-	return r.db.Save(contact).Error
+func (r *contactRepository) Update(ctx context.Context, contact *models.Contact) error {
+	return translateError(r.db.WithContext(ctx).Save(contact).Error)
+}
+
+// UpdateStatus updates only the status columns of a contact, so that concurrent
+// edits of its other fields are not overwritten.
+func (r *contactRepository) UpdateStatus(ctx context.Context, id uint, status models.Status) error {
+	result := r.db.WithContext(ctx).Model(&models.Contact{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":            status,
+		"status_changed_at": time.Now(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindSnoozeDue lists the contacts snoozed until the given time at the latest using GORM.
+func (r *contactRepository) FindSnoozeDue(ctx context.Context, at time.Time, limit int) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := r.db.WithContext(ctx).Where("snoozed_until <= ?", at).
+		Order("snoozed_until, id").Limit(limit).Find(&contacts).Error
+	if err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+// EndSnooze updates only the snooze and status columns of the contact, provided that its
+// snooze still ends by the given time, so that a contact snoozed again is left alone.
+func (r *contactRepository) EndSnooze(ctx context.Context, id uint, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.Contact{}).Where("id = ? AND snoozed_until <= ?", id, at).Updates(map[string]interface{}{
+		"snoozed_until":     nil,
+		"status":            models.StatusNew,
+		"status_changed_at": time.Now(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 // Delete performs a soft delete using GORM's Delete(...) method.
 //
 // GORM will set the model's DeletedAt timestamp rather than physically removing
 // the row. To permanently remove rows, use Unscoped().Delete(...).
-func (r *contactRepository) Delete(contact *models.Contact) error {
-	return r.db.Delete(contact).Error
+func (r *contactRepository) Delete(ctx context.Context, contact *models.Contact) error {
+	return r.db.WithContext(ctx).Delete(contact).Error
+}
+
+// FindExistingByIDs looks up several contacts by primary key, skipping missing ones.
+func (r *contactRepository) FindExistingByIDs(ctx context.Context, ids []uint) ([]models.Contact, error) {
+	var contacts []models.Contact
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("id").Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+// CreateBatch inserts the contacts with GORM's CreateInBatches. On success, the contacts
+// have their IDs and timestamps populated.
+func (r *contactRepository) CreateBatch(ctx context.Context, contacts []models.Contact) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return translateError(tx.CreateInBatches(contacts, createBatchSize).Error)
+	})
+}
+
+// DeleteByIDs soft-deletes the contacts that are not under legal hold.
+func (r *contactRepository) DeleteByIDs(ctx context.Context, ids []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Where("id IN ? AND legal_hold = ?", ids, false).Delete(&models.Contact{}).Error
+	})
+}
+
+// UpdateStatusByIDs updates the status and status_changed_at columns of the contacts.
+func (r *contactRepository) UpdateStatusByIDs(ctx context.Context, ids []uint, status models.Status) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Model(&models.Contact{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":            status,
+			"status_changed_at": time.Now(),
+		}).Error
+	})
+}
+
+// SetEmailIssue updates the email_issue and email_issue_at columns of the contacts.
+func (r *contactRepository) SetEmailIssue(ctx context.Context, ids []uint, issue models.EmailIssue, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Model(&models.Contact{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"email_issue":    issue,
+			"email_issue_at": at,
+		}).Error
+	})
+}
+
+// SetCompany updates only the company columns of a contact, so that concurrent edits of
+// its other fields are not overwritten.
+func (r *contactRepository) SetCompany(ctx context.Context, id uint, name, size string) error {
+	result := r.db.WithContext(ctx).Model(&models.Contact{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"company_name": name,
+		"company_size": size,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetStarred checks that the contact is live, then inserts the star, ignoring an existing
+// one, or deletes it using GORM.
+func (r *contactRepository) SetStarred(ctx context.Context, owner string, id uint, starred bool) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.Contact{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	if !starred {
+		return r.db.WithContext(ctx).Where("owner = ? AND contact_id = ?", owner, id).Delete(&models.ContactStar{}).Error
+	}
+	star := &models.ContactStar{Owner: owner, ContactID: id}
+	return translateError(r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "owner"}, {Name: "contact_id"}},
+		DoNothing: true,
+	}).Create(star).Error)
+}
+
+// DeleteMany soft-deletes several contacts in one transaction, recording the contact
+// they were merged into first.
+func (r *contactRepository) DeleteMany(ctx context.Context, ids []uint, mergedInto uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if mergedInto != 0 {
+			err := tx.Model(&models.Contact{}).Where("id IN ?", ids).Update("merged_into_id", mergedInto).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Where("id IN ?", ids).Delete(&models.Contact{}).Error
+	})
+}
+
+// FindDeleted returns a page of soft-deleted contacts, most recently deleted first, selected
+// by cursor when params.Cursor is set and by offset otherwise.
+//
+// The query is Unscoped so that GORM's soft-delete scope does not hide the rows.
+func (r *contactRepository) FindDeleted(ctx context.Context, params ListParams) (*ContactPage, error) {
+	limit := pageLimit(params.Limit)
+
+	// Count every deleted contact.
+	page := &ContactPage{}
+	query := r.db.WithContext(ctx).Unscoped().Model(&models.Contact{}).Where("deleted_at IS NOT NULL")
+	if err := query.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	// Position the page after the cursor, or at the offset.
+	if params.Cursor != "" {
+		deletedAt, id, err := DecodeCursor(SortByDeletedAt, params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("(deleted_at, id) < (?, ?)", deletedAt, id)
+	} else if params.Offset > 0 {
+		query = query.Offset(params.Offset)
+	}
+
+	// Fetch one extra contact to learn whether another page follows.
+	if err := query.Order("deleted_at DESC, id DESC").Limit(limit + 1).Find(&page.Contacts).Error; err != nil {
+		return nil, err
+	}
+	if len(page.Contacts) > limit {
+		page.Contacts = page.Contacts[:limit]
+		page.NextCursor = EncodeCursor(SortByDeletedAt, &page.Contacts[limit-1])
+	}
+	return page, nil
 }
+
+// FindDeletedByID looks up a soft-deleted contact, bypassing GORM's soft-delete scope.
+func (r *contactRepository) FindDeletedByID(ctx context.Context, id uint) (*models.Contact, error) {
+	var contact models.Contact
+	if err := r.db.WithContext(ctx).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&contact).Error; err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// Restore clears the deleted_at timestamp of a soft-deleted contact.
+func (r *contactRepository) Restore(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Contact{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]any{"deleted_at": nil, "merged_into_id": nil})
+	if err := translateError(result.Error); err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// HardDelete physically removes a soft-deleted contact, and redacts the personal data of
+// its audit trail in the same transaction. The trail itself is kept.
+//
+// Only contacts that are already soft-deleted can be purged, so that a single request
+// can never destroy a live contact. Contacts under legal hold are never removed.
+func (r *contactRepository) HardDelete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().
+			Where("id = ? AND deleted_at IS NOT NULL AND legal_hold = ?", id, false).
+			Delete(&models.Contact{})
+		if result.Error != nil {
+			return translateError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return redactAuditLogs(tx, []uint{id}, personalAuditFields)
+	})
+}
+
+// FindExpiredDeleted returns the soft-deleted contacts eligible for purging, bypassing
+// GORM's soft-delete scope.
+func (r *contactRepository) FindExpiredDeleted(ctx context.Context, before time.Time, limit int) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND legal_hold = ?", before, false).
+		Order("id").Limit(limit).Find(&contacts).Error
+	if err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+// FindAnonymizable returns the resolved contacts eligible for anonymization. Contacts
+// whose status never changed are aged by their creation time.
+func (r *contactRepository) FindAnonymizable(ctx context.Context, before time.Time, statuses []models.Status, limit int) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := r.db.WithContext(ctx).
+		Where("anonymized_at IS NULL AND legal_hold = ? AND status IN ?", false, statuses).
+		Where("COALESCE(status_changed_at, created_at) < ?", before).
+		Order("id").Limit(limit).Find(&contacts).Error
+	if err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+// Anonymize updates only the personal data columns of the contacts, deletes the emails
+// of their conversations, which hold the address, the subjects and the bodies written by
+// the submitter, and redacts the personal data of their audit trail.
+func (r *contactRepository) Anonymize(ctx context.Context, contacts []models.Contact) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, contact := range contacts {
+			err := tx.Model(&models.Contact{}).Where("id = ? AND legal_hold = ?", contact.ID, false).Updates(map[string]any{
+				"full_name":        contact.FullName,
+				"email_address":    contact.Email,
+				"phone_number":     contact.Phone,
+				"fingerprint_hash": contact.FingerprintHash,
+				"consent_ip":       contact.ConsentIP,
+				"anonymized_at":    contact.AnonymizedAt,
+			}).Error
+			if err != nil {
+				return err
+			}
+
+			anonymized := tx.Model(&models.Contact{}).Select("id").Where("id = ? AND legal_hold = ?", contact.ID, false)
+			if err := tx.Where("contact_id IN (?)", anonymized).Delete(&models.EmailMessage{}).Error; err != nil {
+				return err
+			}
+			if err := redactAuditLogs(tx, anonymized, personalAuditFields); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// personalAuditFields are the fields of the audit trail holding the personal data of a
+// contact: the values written by the submitter. They are redacted when the contact is
+// purged or anonymized.
+var personalAuditFields = []string{"name", "email", "phone", "message"}
+
+// redactAuditLogs redacts the values of fields in the audit trail of the contacts matched by
+// contactIDs, a list of IDs or a subquery. audit_logs has no foreign key to the contacts,
+// so its entries are kept once the personal data is removed from them.
+func redactAuditLogs(tx *gorm.DB, contactIDs any, fields []string) error {
+	var entries []models.AuditLog
+	if err := tx.Where("contact_id IN (?)", contactIDs).Find(&entries).Error; err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		redacted := entry.RedactChanges(fields...)
+		if redacted == entry.Changes {
+			continue
+		}
+		if err := tx.Model(&models.AuditLog{}).Where("id = ?", entry.ID).Update("changes", redacted).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyFilter narrows query down to the contacts matching filter.
+func applyFilter(query *gorm.DB, filter ContactFilter) *gorm.DB {
+	if filter.Channel != "" {
+		query = query.Where("channel = ?", filter.Channel)
+	}
+	if filter.FingerprintHash != "" {
+		query = query.Where("fingerprint_hash = ?", filter.FingerprintHash)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Email != "" {
+		query = query.Where("LOWER(email_address) = LOWER(?)", filter.Email)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedTo)
+	}
+	if filter.MinLeadScore != nil {
+		query = query.Where("lead_score >= ?", *filter.MinLeadScore)
+	}
+	if filter.StarredBy != "" {
+		query = query.Where("contact_messages.id IN (SELECT contact_id FROM contact_stars WHERE owner = ?)", filter.StarredBy)
+	}
+	if filter.Search != "" && query.Dialector.Name() == "postgres" {
+		query = query.Where("(message_text ILIKE ? OR ? <% full_name OR ? <% email_address)",
+			"%"+likeEscaper.Replace(filter.Search)+"%", filter.Search, filter.Search)
+	} else if filter.Search != "" {
+		// Without pg_trgm, fall back to a case-insensitive substring match of every column
+		pattern := "%" + strings.ToLower(portableLikeEscaper.Replace(filter.Search)) + "%"
+		query = query.Where("(LOWER(message_text) LIKE ? ESCAPE '!' OR LOWER(full_name) LIKE ? ESCAPE '!' OR LOWER(email_address) LIKE ? ESCAPE '!')",
+			pattern, pattern, pattern)
+	}
+	return query
+}
+
+// withSearch runs fn with the word similarity threshold of pg_trgm set to the search
+// threshold of the filter. The threshold is a session setting, so it is set locally to a
+// transaction around fn; filters without a search, and databases other than Postgres,
+// run fn directly.
+//
+// The <% operator is used rather than comparing the word_similarity function with the
+// threshold because only the operator can use the trigram indexes.
+func (r *contactRepository) withSearch(ctx context.Context, filter ContactFilter, fn func(db *gorm.DB) error) error {
+	if filter.Search == "" || r.db.Dialector.Name() != "postgres" {
+		return fn(r.db.WithContext(ctx))
+	}
+
+	threshold := filter.SearchThreshold
+	if threshold == 0 {
+		threshold = DefaultSearchThreshold
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)",
+			strconv.FormatFloat(threshold, 'f', -1, 64)).Error
+		if err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// likeEscaper escapes the wildcard characters of LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// portableLikeEscaper escapes the wildcard characters of LIKE patterns with "!", which,
+// unlike the backslash, needs no escaping in the string literals of any supported database.
+var portableLikeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"api-contact-form/internal/benchdata"
+	"api-contact-form/models"
+	"testing"
+)
+
+func BenchmarkCreate(b *testing.B) {
+	repo := NewContactRepository(benchdata.OpenDB(b))
+	contacts := benchdata.Contacts(1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		contact := contacts[i%len(contacts)]
+		if err := repo.Create(b.Context(), &contact); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFindAll(b *testing.B) {
+	db := benchdata.OpenDB(b)
+	benchdata.Load(b, db, 1000)
+	repo := NewContactRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.FindAll(b.Context(), ContactFilter{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFindAllByChannel(b *testing.B) {
+	db := benchdata.OpenDB(b)
+	benchdata.Load(b, db, 1000)
+	repo := NewContactRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.FindAll(b.Context(), ContactFilter{Channel: models.ChannelEmail}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFindPaged(b *testing.B) {
+	db := benchdata.OpenDB(b)
+	benchdata.Load(b, db, 1000)
+	repo := NewContactRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.FindPaged(b.Context(), ListParams{Limit: DefaultPageSize, Offset: 200}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFindPagedCursor(b *testing.B) {
+	db := benchdata.OpenDB(b)
+	benchdata.Load(b, db, 1000)
+	repo := NewContactRepository(db)
+
+	first, err := repo.FindPaged(b.Context(), ListParams{Limit: DefaultPageSize})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.FindPaged(b.Context(), ListParams{Limit: DefaultPageSize, Cursor: first.NextCursor}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package repositories_test
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/repositories/repotest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+func TestContactRepository(t *testing.T) {
+	repotest.TestContactRepository(t, func(t *testing.T) repositories.ContactRepository {
+		return repositories.NewContactRepository(openDB(t))
+	})
+}
+
+func TestEmailMessageRepository(t *testing.T) {
+	repotest.TestEmailMessageRepository(t, func(t *testing.T) (repositories.ContactRepository, repositories.EmailMessageRepository) {
+		db := openDB(t)
+		return repositories.NewContactRepository(db), repositories.NewEmailMessageRepository(db)
+	})
+}
+
+func TestHardDeleteRedactsAuditLogs(t *testing.T) {
+	db := openDB(t)
+	contacts := repositories.NewContactRepository(db)
+	ctx := t.Context()
+	contact := createAuditedContact(t, db)
+	if err := contacts.Delete(ctx, contact); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if err := contacts.HardDelete(ctx, contact.ID); err != nil {
+		t.Fatalf("HardDelete: %v", err)
+	}
+
+	checkAuditLogsRedacted(t, db, contact.ID)
+}
+
+func TestAnonymizeRedactsAuditLogs(t *testing.T) {
+	db := openDB(t)
+	contacts := repositories.NewContactRepository(db)
+	contact := createAuditedContact(t, db)
+	held := createAuditedContact(t, db)
+	if err := db.Model(held).Update("legal_hold", true).Error; err != nil {
+		t.Fatalf("place the legal hold: %v", err)
+	}
+
+	anonymized := []models.Contact{*contact, *held}
+	for i := range anonymized {
+		anonymized[i].Anonymize(time.Now())
+	}
+	if err := contacts.Anonymize(t.Context(), anonymized); err != nil {
+		t.Fatalf("Anonymize: %v", err)
+	}
+
+	checkAuditLogsRedacted(t, db, contact.ID)
+	entries, err := repositories.NewAuditLogRepository(db).FindRecent(repositories.AuditLogFilter{ContactID: held.ID, Limit: 10})
+	if err != nil {
+		t.Fatalf("FindRecent: %v", err)
+	}
+	for _, entry := range entries {
+		if !strings.Contains(strings.ToLower(entry.Changes), "alice") {
+			t.Errorf("entry %d of the contact under legal hold was redacted: %s", entry.ID, entry.Changes)
+		}
+	}
+}
+
+// createAuditedContact creates a contact with two entries in its audit trail holding its
+// personal data.
+func createAuditedContact(t *testing.T, db *gorm.DB) *models.Contact {
+	t.Helper()
+	contact := &models.Contact{FullName: "Alice Smith", Email: "alice@example.com", Phone: "+628123456789", Message: "Call me at home", Status: models.StatusNew}
+	if err := repositories.NewContactRepository(db).Create(t.Context(), contact); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	err := repositories.NewAuditLogRepository(db).Create([]models.AuditLog{
+		{Actor: "user:1", Action: models.AuditUpdated, ContactID: contact.ID, Changes: `{"email":{"before":"alice@example.com","after":"alice@example.org"},"phone":{"before":null,"after":"+628123456789"}}`},
+		{Actor: "user:1", Action: models.AuditDeleted, ContactID: contact.ID, Changes: `{"name":{"before":"Alice Smith","after":null},"message":{"before":"Call me at home","after":null},"status":{"before":"new","after":"read"}}`},
+	})
+	if err != nil {
+		t.Fatalf("audits.Create: %v", err)
+	}
+	return contact
+}
+
+// checkAuditLogsRedacted checks that the audit trail of the contact created by
+// createAuditedContact holds none of its personal data, and still holds the other changes.
+func checkAuditLogsRedacted(t *testing.T, db *gorm.DB, contactID uint) {
+	t.Helper()
+	entries, err := repositories.NewAuditLogRepository(db).FindRecent(repositories.AuditLogFilter{ContactID: contactID, Limit: 10})
+	if err != nil {
+		t.Fatalf("FindRecent: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("FindRecent = %d entries, want the 2 entries of the trail", len(entries))
+	}
+	for _, entry := range entries {
+		for _, pii := range []string{"Alice", "alice@", "+628123456789", "Call me"} {
+			if strings.Contains(entry.Changes, pii) {
+				t.Errorf("entry %d (%s) still holds %q: %s", entry.ID, entry.Action, pii, entry.Changes)
+			}
+		}
+	}
+	if want := `"status":{"after":"read","before":"new"}`; !strings.Contains(entries[0].Changes, want) {
+		t.Errorf("changes = %s, want the other fields kept: %s", entries[0].Changes, want)
+	}
+}
+
+// openDB opens a new SQLite database holding the tables of the contacts.
+func openDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "contacts.db")), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Contact{}, &models.Attachment{}, &models.ContactStar{}, &models.EmailMessage{}, &models.AuditLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"context"
+	"slices"
+	"time"
+)
+
+/*
+This file implements ContactRepository.FindDuplicateGroups, which finds submissions
+that were likely sent more than once: contacts of the same email address, submitted
+close together, whose messages are similar according to the trigram similarity of
+the pg_trgm extension. It is only available on Postgres.
+*/
+
+// maxDuplicatePairs bounds the number of similar contact pairs a single report reads.
+const maxDuplicatePairs = 10000
+
+// DuplicateCriteria describes which contacts FindDuplicateGroups considers duplicates.
+type DuplicateCriteria struct {
+	// Within is the largest time between two submissions that are considered duplicates.
+	Within time.Duration
+	// Threshold is the smallest trigram similarity, between 0 and 1, of the messages of
+	// two submissions that are considered duplicates.
+	Threshold float64
+}
+
+// DuplicateGroup is a set of contacts that are likely duplicates of each other.
+type DuplicateGroup struct {
+	// Email is the email address the contacts were submitted with.
+	Email string
+	// Similarity is the highest similarity between two messages of the group.
+	Similarity float64
+	// Contacts are the contacts of the group, oldest first.
+	Contacts []models.Contact
+}
+
+// duplicatePair is a pair of contacts matching the DuplicateCriteria.
+type duplicatePair struct {
+	ID          uint
+	DuplicateID uint
+	Similarity  float64
+}
+
+// FindDuplicateGroups finds the pairs of similar contacts in the database and joins
+// pairs sharing a contact into groups, so that a contact submitted three times forms
+// a single group. Groups are returned most recent first.
+// Databases other than Postgres return ErrUnsupportedByDriver.
+func (r *contactRepository) FindDuplicateGroups(ctx context.Context, criteria DuplicateCriteria) ([]DuplicateGroup, error) {
+	if r.db.Dialector.Name() != "postgres" {
+		return nil, ErrUnsupportedByDriver
+	}
+
+	// Find the similar pairs of contacts with the same email address.
+	var pairs []duplicatePair
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT a.id AS id, b.id AS duplicate_id, similarity(a.message_text, b.message_text) AS similarity
+		FROM contact_messages a
+		JOIN contact_messages b ON LOWER(b.email_address) = LOWER(a.email_address) AND b.id > a.id
+		WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL
+			AND ABS(EXTRACT(EPOCH FROM b.created_at - a.created_at)) <= ?
+			AND similarity(a.message_text, b.message_text) >= ?
+		LIMIT ?`,
+		criteria.Within.Seconds(), criteria.Threshold, maxDuplicatePairs,
+	).Scan(&pairs).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(pairs) == 0 {
+		return []DuplicateGroup{}, nil
+	}
+
+	// Join the pairs into groups, keyed by the smallest contact ID of the group.
+	parent := map[uint]uint{}
+	var find func(id uint) uint
+	find = func(id uint) uint {
+		p, ok := parent[id]
+		if !ok || p == id {
+			parent[id] = id
+			return id
+		}
+		root := find(p)
+		parent[id] = root
+		return root
+	}
+	for _, pair := range pairs {
+		a, b := find(pair.ID), find(pair.DuplicateID)
+		parent[max(a, b)] = min(a, b)
+	}
+
+	similarity := map[uint]float64{}
+	for _, pair := range pairs {
+		root := find(pair.ID)
+		similarity[root] = max(similarity[root], pair.Similarity)
+	}
+
+	// Load the contacts and sort them into their groups.
+	ids := make([]uint, 0, len(parent))
+	for id := range parent {
+		ids = append(ids, id)
+	}
+	var contacts []models.Contact
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("created_at, id").Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+
+	byRoot := map[uint]*DuplicateGroup{}
+	var groups []*DuplicateGroup
+	for _, contact := range contacts {
+		root := find(contact.ID)
+		group, ok := byRoot[root]
+		if !ok {
+			group = &DuplicateGroup{Email: contact.Email, Similarity: similarity[root]}
+			byRoot[root] = group
+			groups = append(groups, group)
+		}
+		group.Contacts = append(group.Contacts, contact)
+	}
+
+	// Return the groups whose latest submission is most recent first.
+	slices.SortFunc(groups, func(a, b *DuplicateGroup) int {
+		return b.Contacts[len(b.Contacts)-1].CreatedAt.Compare(a.Contacts[len(a.Contacts)-1].CreatedAt)
+	})
+	result := make([]DuplicateGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	return result, nil
+}
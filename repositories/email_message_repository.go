@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed EmailMessageRepository, which stores the emails of the
+conversations with the submitters of contacts and finds the conversation an inbound email
+replies to. The emails are removed by the database together with a purged contact, and by
+ContactRepository.Anonymize together with the personal data of an anonymized contact.
+*/
+
+// EmailMessageRepository defines the interface for email conversation data operations.
+type EmailMessageRepository interface {
+	// Create inserts an email. It returns a *ConstraintError wrapping ErrUniqueViolation
+	// when an email with the same Message-ID is already stored.
+	Create(ctx context.Context, message *models.EmailMessage) error
+
+	// FindByContact retrieves the emails of the conversation of a contact, oldest first.
+	FindByContact(ctx context.Context, contactID uint) ([]models.EmailMessage, error)
+
+	// FindByContacts retrieves the emails of the conversations of several contacts,
+	// deleted or not, oldest first.
+	FindByContacts(ctx context.Context, contactIDs []uint) ([]models.EmailMessage, error)
+
+	// FindThreadContact returns the ID of the live contact whose conversation holds one of
+	// messageIDs, either as the email the contact was created from or as a stored email.
+	// Message-IDs are compared without their angle brackets. When several contacts match,
+	// the most recent one is returned. It returns gorm.ErrRecordNotFound when none does.
+	FindThreadContact(ctx context.Context, messageIDs []string) (uint, error)
+}
+
+// emailMessageRepository is a GORM-based implementation of EmailMessageRepository.
+type emailMessageRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailMessageRepository constructs a new EmailMessageRepository backed by the provided GORM DB.
+func NewEmailMessageRepository(db *gorm.DB) EmailMessageRepository {
+	return &emailMessageRepository{db: db}
+}
+
+// Create inserts the email, leaving its contact untouched.
+func (r *emailMessageRepository) Create(ctx context.Context, message *models.EmailMessage) error {
+	return translateError(r.db.WithContext(ctx).Omit("Contact").Create(message).Error)
+}
+
+// FindByContact lists the emails of a contact in the order they were stored.
+func (r *emailMessageRepository) FindByContact(ctx context.Context, contactID uint) ([]models.EmailMessage, error) {
+	var messages []models.EmailMessage
+	err := r.db.WithContext(ctx).Where("contact_id = ?", contactID).Order("created_at ASC, id ASC").Find(&messages).Error
+	return messages, err
+}
+
+// FindByContacts lists the emails of the contacts in the order they were stored.
+func (r *emailMessageRepository) FindByContacts(ctx context.Context, contactIDs []uint) ([]models.EmailMessage, error) {
+	messages := []models.EmailMessage{}
+	if len(contactIDs) == 0 {
+		return messages, nil
+	}
+	err := r.db.WithContext(ctx).Where("contact_id IN ?", contactIDs).Order("created_at ASC, id ASC").Find(&messages).Error
+	return messages, err
+}
+
+// FindThreadContact looks up the contact created from, or having stored, one of the emails.
+func (r *emailMessageRepository) FindThreadContact(ctx context.Context, messageIDs []string) (uint, error) {
+	if len(messageIDs) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+
+	db := r.db.WithContext(ctx)
+	threads := db.Model(&models.EmailMessage{}).Select("contact_id").Where("message_id IN ?", messageIDs)
+	var contact models.Contact
+	err := db.Select("id").
+		Where(db.Where("message_id IN ?", messageIDs).Or("id IN (?)", threads)).
+		Order("id DESC").
+		First(&contact).Error
+	if err != nil {
+		return 0, err
+	}
+	return contact.ID, nil
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed ExportScheduleRepository, which stores the recurring
+exports of contacts and the outcome of their last run.
+*/
+
+// ExportScheduleRepository defines the interface for export schedule data operations.
+type ExportScheduleRepository interface {
+	// FindAll retrieves every schedule, ordered by ID.
+	FindAll(ctx context.Context) ([]models.ExportSchedule, error)
+
+	// FindActive retrieves the schedules that are not paused.
+	FindActive(ctx context.Context) ([]models.ExportSchedule, error)
+
+	// FindByID retrieves a schedule by its ID. It returns gorm.ErrRecordNotFound when
+	// there is none.
+	FindByID(ctx context.Context, id uint) (*models.ExportSchedule, error)
+
+	// Create inserts a new schedule.
+	Create(ctx context.Context, schedule *models.ExportSchedule) error
+
+	// Update saves the settings of a schedule, leaving the outcome of its last run untouched.
+	Update(ctx context.Context, schedule *models.ExportSchedule) error
+
+	// Delete removes a schedule. It returns gorm.ErrRecordNotFound when there is none.
+	Delete(ctx context.Context, id uint) error
+
+	// Claim records that a run of a schedule started at, provided that its last run is still
+	// previous, and reports whether it did, so that a single replica runs each occurrence.
+	Claim(ctx context.Context, id uint, previous *time.Time, at time.Time) (bool, error)
+
+	// RecordResult records the number of contacts exported by the last run of a schedule,
+	// and the reason it failed, empty when it succeeded.
+	RecordResult(ctx context.Context, id uint, rows int, runErr string) error
+}
+
+// exportScheduleRepository is a GORM-based implementation of ExportScheduleRepository.
+type exportScheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewExportScheduleRepository constructs a new ExportScheduleRepository backed by the provided GORM DB.
+func NewExportScheduleRepository(db *gorm.DB) ExportScheduleRepository {
+	return &exportScheduleRepository{db: db}
+}
+
+// FindAll fetches every schedule from the database.
+func (r *exportScheduleRepository) FindAll(ctx context.Context) ([]models.ExportSchedule, error) {
+	var schedules []models.ExportSchedule
+	err := r.db.WithContext(ctx).Order("id ASC").Find(&schedules).Error
+	return schedules, err
+}
+
+// FindActive fetches the active schedules from the database.
+func (r *exportScheduleRepository) FindActive(ctx context.Context) ([]models.ExportSchedule, error) {
+	var schedules []models.ExportSchedule
+	err := r.db.WithContext(ctx).Where("active = ?", true).Order("id ASC").Find(&schedules).Error
+	return schedules, err
+}
+
+// FindByID looks up a schedule by its ID and returns it.
+func (r *exportScheduleRepository) FindByID(ctx context.Context, id uint) (*models.ExportSchedule, error) {
+	var schedule models.ExportSchedule
+	if err := r.db.WithContext(ctx).First(&schedule, id).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// Create inserts the schedule using GORM.
+func (r *exportScheduleRepository) Create(ctx context.Context, schedule *models.ExportSchedule) error {
+	return r.db.WithContext(ctx).Create(schedule).Error
+}
+
+// Update saves the settings columns of the schedule.
+func (r *exportScheduleRepository) Update(ctx context.Context, schedule *models.ExportSchedule) error {
+	return r.db.WithContext(ctx).Model(schedule).
+		Select("name", "cron", "format", "mode", "status", "channel", "period", "destination", "s3_prefix", "recipients", "active").
+		Updates(schedule).Error
+}
+
+// Delete deletes the schedule with the given ID.
+func (r *exportScheduleRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.ExportSchedule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Claim sets the last run time of the schedule with a conditional update, which only one
+// of concurrent callers wins.
+func (r *exportScheduleRepository) Claim(ctx context.Context, id uint, previous *time.Time, at time.Time) (bool, error) {
+	query := r.db.WithContext(ctx).Model(&models.ExportSchedule{}).Where("id = ?", id)
+	if previous == nil {
+		query = query.Where("last_run_at IS NULL")
+	} else {
+		query = query.Where("last_run_at = ?", *previous)
+	}
+	result := query.Update("last_run_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+// RecordResult updates the outcome columns of the schedule.
+func (r *exportScheduleRepository) RecordResult(ctx context.Context, id uint, rows int, runErr string) error {
+	return r.db.WithContext(ctx).Model(&models.ExportSchedule{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_rows":  rows,
+		"last_error": runErr,
+	}).Error
+}
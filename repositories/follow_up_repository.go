@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed FollowUpRepository, which stores the follow-ups agents
+plan with contacts. An agent has at most one follow-up per contact; they are removed by
+the database together with a purged contact.
+*/
+
+// FollowUpRepository defines the interface for follow-up data operations.
+type FollowUpRepository interface {
+	// FindByOwner retrieves the follow-ups of an agent, with their contact, by due date.
+	// Follow-ups of trashed contacts are left out.
+	FindByOwner(ctx context.Context, owner string) ([]models.FollowUp, error)
+
+	// FindByContact retrieves the follow-up of a contact planned by an agent. It returns
+	// gorm.ErrRecordNotFound when there is none.
+	FindByContact(ctx context.Context, owner string, contactID uint) (*models.FollowUp, error)
+
+	// Save inserts a follow-up without an ID, or updates an existing one. It returns a
+	// *ConstraintError wrapping ErrUniqueViolation when the agent planned another
+	// follow-up of the contact in the meantime.
+	Save(ctx context.Context, followUp *models.FollowUp) error
+
+	// DeleteByContact removes the follow-up of a contact planned by an agent. It returns
+	// gorm.ErrRecordNotFound when there is none.
+	DeleteByContact(ctx context.Context, owner string, contactID uint) error
+}
+
+// followUpRepository is a GORM-based implementation of FollowUpRepository.
+type followUpRepository struct {
+	db *gorm.DB
+}
+
+// NewFollowUpRepository constructs a new FollowUpRepository backed by the provided GORM DB.
+func NewFollowUpRepository(db *gorm.DB) FollowUpRepository {
+	return &followUpRepository{db: db}
+}
+
+// FindByOwner lists the follow-ups of the agent using GORM. The inner join leaves out the
+// follow-ups whose contact is trashed.
+func (r *followUpRepository) FindByOwner(ctx context.Context, owner string) ([]models.FollowUp, error) {
+	var followUps []models.FollowUp
+	err := r.db.WithContext(ctx).
+		InnerJoins("Contact").
+		Where("contact_follow_ups.owner = ?", owner).
+		Order("contact_follow_ups.due_at, contact_follow_ups.id").
+		Find(&followUps).Error
+	return followUps, err
+}
+
+// FindByContact looks up the follow-up and returns it.
+func (r *followUpRepository) FindByContact(ctx context.Context, owner string, contactID uint) (*models.FollowUp, error) {
+	var followUp models.FollowUp
+	if err := r.db.WithContext(ctx).Where("owner = ? AND contact_id = ?", owner, contactID).First(&followUp).Error; err != nil {
+		return nil, err
+	}
+	return &followUp, nil
+}
+
+// Save persists the follow-up, leaving its contact untouched.
+func (r *followUpRepository) Save(ctx context.Context, followUp *models.FollowUp) error {
+	return translateError(r.db.WithContext(ctx).Omit("Contact").Save(followUp).Error)
+}
+
+// DeleteByContact deletes the follow-up.
+func (r *followUpRepository) DeleteByContact(ctx context.Context, owner string, contactID uint) error {
+	result := r.db.WithContext(ctx).Where("owner = ? AND contact_id = ?", owner, contactID).Delete(&models.FollowUp{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+This file provides the GORM-backed IdempotencyRepository, which stores the requests
+made with an Idempotency-Key header and their responses.
+*/
+
+// IdempotencyRepository defines the interface for idempotency key data operations.
+type IdempotencyRepository interface {
+	// Reserve inserts the key unless an unexpired key with the same hash exists. It
+	// reports whether the key was inserted; when it was not, the existing key is returned.
+	Reserve(key *models.IdempotencyKey) (bool, *models.IdempotencyKey, error)
+
+	// Complete stores the response of the request of a reserved key.
+	Complete(keyHash string, statusCode int, contentType string, body []byte) error
+
+	// Delete removes a key, so that the request can be made again.
+	Delete(keyHash string) error
+
+	// DeleteExpired removes the keys that expired before the given time and returns how
+	// many were removed.
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// idempotencyRepository is a GORM-based implementation of IdempotencyRepository.
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository constructs a new IdempotencyRepository backed by the provided GORM DB.
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Reserve removes an expired key with the same hash, then inserts the key unless it
+// exists. The insert ignores conflicts, so that of two concurrent requests with the same
+// key, exactly one reserves it.
+func (r *idempotencyRepository) Reserve(key *models.IdempotencyKey) (bool, *models.IdempotencyKey, error) {
+	err := r.db.Where("key_hash = ? AND expires_at <= ?", key.KeyHash, time.Now()).
+		Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return false, nil, err
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	if result.Error != nil {
+		return false, nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil, nil
+	}
+
+	var existing models.IdempotencyKey
+	if err := r.db.Where("key_hash = ?", key.KeyHash).First(&existing).Error; err != nil {
+		return false, nil, err
+	}
+	return false, &existing, nil
+}
+
+// Complete updates the response columns of a key.
+func (r *idempotencyRepository) Complete(keyHash string, statusCode int, contentType string, body []byte) error {
+	return r.db.Model(&models.IdempotencyKey{}).Where("key_hash = ?", keyHash).
+		Updates(map[string]any{"status_code": statusCode, "content_type": contentType, "response_body": body}).Error
+}
+
+// Delete removes a key by its hash.
+func (r *idempotencyRepository) Delete(keyHash string) error {
+	return r.db.Where("key_hash = ?", keyHash).Delete(&models.IdempotencyKey{}).Error
+}
+
+// DeleteExpired removes the expired keys.
+func (r *idempotencyRepository) DeleteExpired(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", before).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
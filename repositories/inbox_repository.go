@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+This file provides the GORM-backed InboxRepository, which stores the inbox projection:
+one denormalized row per live contact, listed by the admin inbox with a single query.
+*/
+
+// InboxFilter narrows down the entries returned by FindPage.
+// Zero-valued fields are ignored, except Snoozed: snoozed contacts are only listed when
+// it is set.
+type InboxFilter struct {
+	// Snoozed restricts the results to the entries of snoozed contacts, which are otherwise
+	// left out.
+	Snoozed bool
+	// UnreadOnly restricts the results to the entries of unread contacts.
+	UnreadOnly bool
+	// Status restricts the results to the entries of contacts with the given status.
+	Status models.Status
+	// Limit is the largest number of entries returned, and Offset the number skipped.
+	Limit  int
+	Offset int
+}
+
+// InboxRepository defines the interface for inbox projection operations.
+type InboxRepository interface {
+	// Save creates or replaces the entry of a contact.
+	Save(ctx context.Context, entry *models.InboxEntry) error
+
+	// Delete removes the entry of a contact, if any.
+	Delete(ctx context.Context, contactID uint) error
+
+	// FindPage retrieves the entries matching the filter, pinned contacts first, then the
+	// most recently active first.
+	FindPage(ctx context.Context, filter InboxFilter) ([]models.InboxEntry, error)
+
+	// Count returns the number of entries.
+	Count(ctx context.Context) (int64, error)
+
+	// CountUnread returns the number of entries of unread contacts.
+	CountUnread(ctx context.Context) (int64, error)
+
+	// Rebuild replaces every entry with the entries of the live contacts, read in batches
+	// of batchSize, in a single transaction, and returns the number of entries.
+	Rebuild(ctx context.Context, batchSize int) (int64, error)
+}
+
+// inboxRepository is a GORM-based implementation of InboxRepository.
+type inboxRepository struct {
+	db *gorm.DB
+}
+
+// NewInboxRepository constructs a new InboxRepository backed by the provided GORM DB.
+func NewInboxRepository(db *gorm.DB) InboxRepository {
+	return &inboxRepository{db: db}
+}
+
+// Save upserts the entry using GORM, replacing every column of an existing one.
+func (r *inboxRepository) Save(ctx context.Context, entry *models.InboxEntry) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "contact_id"}},
+		UpdateAll: true,
+	}).Create(entry).Error
+}
+
+// Delete removes the entry of a contact using GORM.
+func (r *inboxRepository) Delete(ctx context.Context, contactID uint) error {
+	return r.db.WithContext(ctx).Where("contact_id = ?", contactID).Delete(&models.InboxEntry{}).Error
+}
+
+// FindPage lists the matching entries using GORM, ordered by pin, last activity then
+// contact ID so that pages are stable.
+func (r *inboxRepository) FindPage(ctx context.Context, filter InboxFilter) ([]models.InboxEntry, error) {
+	query := r.db.WithContext(ctx).Model(&models.InboxEntry{})
+	if filter.Snoozed {
+		query = query.Where("snoozed_until IS NOT NULL")
+	} else {
+		query = query.Where("snoozed_until IS NULL")
+	}
+	if filter.UnreadOnly {
+		query = query.Where("unread = ?", true)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	var entries []models.InboxEntry
+	err := query.Order("pinned DESC, last_activity_at DESC, contact_id DESC").Find(&entries).Error
+	return entries, err
+}
+
+// Count counts the entries using GORM.
+func (r *inboxRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.InboxEntry{}).Count(&count).Error
+	return count, err
+}
+
+// CountUnread counts the unread entries using GORM.
+func (r *inboxRepository) CountUnread(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.InboxEntry{}).Where("unread = ?", true).Count(&count).Error
+	return count, err
+}
+
+// Rebuild empties the projection and fills it again from the contacts that are not
+// soft-deleted, in a transaction, so that the inbox never shows a partial projection.
+func (r *inboxRepository) Rebuild(ctx context.Context, batchSize int) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.InboxEntry{}).Error; err != nil {
+			return err
+		}
+
+		var contacts []models.Contact
+		return tx.Order("id").FindInBatches(&contacts, batchSize, func(batch *gorm.DB, _ int) error {
+			if len(contacts) == 0 {
+				return nil
+			}
+			entries := make([]models.InboxEntry, len(contacts))
+			for i := range contacts {
+				entries[i] = models.NewInboxEntry(&contacts[i])
+			}
+			total += int64(len(entries))
+			return tx.Create(&entries).Error
+		}).Error
+	})
+	return total, err
+}
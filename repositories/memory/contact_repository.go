@@ -0,0 +1,881 @@
+// Package memory provides in-memory implementations of repositories.ContactRepository
+// and repositories.EmailMessageRepository, so that the consumers of the repositories, such
+// as services and handlers, can be tested without a database.
+//
+// The repository behaves like the GORM implementation on databases other than Postgres,
+// as checked by the conformance suite of package repotest: IDs are assigned in increasing
+// order, soft-deleted contacts are hidden from the queries that exclude them, searches
+// match every word as a case-insensitive substring and FindDuplicateGroups returns
+// repositories.ErrUnsupportedByDriver. The optional unique index on the email addresses
+// of open contacts, repositories.OpenEmailIndex, is not enforced.
+package memory
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"cmp"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// errInvalidSortField mirrors the error of the GORM implementation for unknown sort fields.
+var errInvalidSortField = errors.New("invalid sort field")
+
+// store holds the contacts of a repository, keyed by ID, the stars agents gave them and
+// the emails of their conversations, keyed by ID.
+type store struct {
+	contacts         map[uint]models.Contact
+	stars            map[star]bool
+	messages         map[uint]models.EmailMessage
+	lastID           uint
+	lastAttachmentID uint
+	lastMessageID    uint
+}
+
+// star identifies the star of a contact by an agent.
+type star struct {
+	owner     string
+	contactID uint
+}
+
+// clone returns a copy of s that can be changed without affecting s.
+func (s *store) clone() *store {
+	copied := *s
+	copied.contacts = maps.Clone(s.contacts)
+	copied.stars = maps.Clone(s.stars)
+	copied.messages = maps.Clone(s.messages)
+	return &copied
+}
+
+// contactRepository is a map-backed implementation of repositories.ContactRepository.
+// It is safe for concurrent use, except for the repositories given to WithTx callbacks,
+// which, like GORM transactions, must only be used by the goroutine running the callback.
+type contactRepository struct {
+	mu    *sync.RWMutex
+	store *store
+	// tx is set on the repositories given to WithTx callbacks, which run with mu held.
+	tx bool
+}
+
+// NewContactRepository constructs a new, empty in-memory ContactRepository.
+func NewContactRepository() repositories.ContactRepository {
+	return &contactRepository{mu: &sync.RWMutex{}, store: &store{contacts: map[uint]models.Contact{}, stars: map[star]bool{}, messages: map[uint]models.EmailMessage{}}}
+}
+
+// read runs fn with the store locked for reading, unless ctx is already done.
+func (r *contactRepository) read(ctx context.Context, fn func(s *store) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !r.tx {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+	}
+	return fn(r.store)
+}
+
+// write runs fn with the store locked for writing, unless ctx is already done.
+func (r *contactRepository) write(ctx context.Context, fn func(s *store) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !r.tx {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+	}
+	return fn(r.store)
+}
+
+// WithTx runs fn on a copy of the contacts, which replaces them when fn succeeds. Other
+// callers wait until fn returns, as if the transaction were serializable.
+func (r *contactRepository) WithTx(ctx context.Context, fn func(repo repositories.ContactRepository) error) error {
+	return r.write(ctx, func(s *store) error {
+		tx := &contactRepository{mu: r.mu, store: s.clone(), tx: true}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		*s = *tx.store
+		return nil
+	})
+}
+
+// Create stores a copy of the contact, assigning its ID, timestamps and column defaults,
+// and the IDs of its attachments.
+func (r *contactRepository) Create(ctx context.Context, contact *models.Contact) error {
+	return r.write(ctx, func(s *store) error {
+		if err := s.checkUnique([]models.Contact{*contact}); err != nil {
+			return err
+		}
+		s.insert(contact, time.Now())
+		return nil
+	})
+}
+
+// FindAll returns the live contacts matching the filter, newest first.
+func (r *contactRepository) FindAll(ctx context.Context, filter repositories.ContactFilter) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := r.read(ctx, func(s *store) error {
+		contacts = s.find(func(c *models.Contact) bool { return live(c) && s.matches(c, filter) })
+		slices.SortFunc(contacts, newestFirst)
+		return nil
+	})
+	return contacts, err
+}
+
+// FindInBatches calls fn with the matching contacts in ID order. The contacts are copied
+// before fn runs, so that fn may use the repository.
+func (r *contactRepository) FindInBatches(ctx context.Context, filter repositories.ContactFilter, batchSize int, fn func(contacts []models.Contact) error) error {
+	var contacts []models.Contact
+	err := r.read(ctx, func(s *store) error {
+		contacts = s.find(func(c *models.Contact) bool { return live(c) && s.matches(c, filter) })
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for batch := range slices.Chunk(contacts, max(batchSize, 1)) {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindPaged returns a page of the live contacts matching the filter, sorted like the
+// GORM implementation and positioned by cursor or offset.
+func (r *contactRepository) FindPaged(ctx context.Context, params repositories.ListParams) (*repositories.ContactPage, error) {
+	sortBy := cmp.Or(params.SortBy, repositories.SortByCreatedAt)
+	if !sortBy.Valid() {
+		return nil, errInvalidSortField
+	}
+	limit := pageLimit(params.Limit)
+
+	var contacts []models.Contact
+	err := r.read(ctx, func(s *store) error {
+		contacts = s.find(func(c *models.Contact) bool { return live(c) && s.matches(c, params.Filter) })
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort on the field with the ID as tie-breaker, descending unless asked otherwise.
+	compare := func(a, b *models.Contact) int {
+		if sortBy == repositories.SortByFullName {
+			return cmp.Or(strings.Compare(a.FullName, b.FullName), cmp.Compare(a.ID, b.ID))
+		}
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	}
+	if !params.Ascending {
+		ascending := compare
+		compare = func(a, b *models.Contact) int { return ascending(b, a) }
+	}
+	slices.SortFunc(contacts, func(a, b models.Contact) int { return compare(&a, &b) })
+	page := &repositories.ContactPage{Total: int64(len(contacts))}
+
+	// Position the page after the cursor, or at the offset.
+	if params.Cursor != "" {
+		value, id, err := repositories.DecodeCursor(sortBy, params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		last := models.Contact{ID: id}
+		if sortBy == repositories.SortByFullName {
+			last.FullName = value.(string)
+		} else {
+			last.CreatedAt = value.(time.Time)
+		}
+		start := slices.IndexFunc(contacts, func(c models.Contact) bool { return compare(&c, &last) > 0 })
+		if start < 0 {
+			start = len(contacts)
+		}
+		contacts = contacts[start:]
+	} else {
+		contacts = contacts[min(max(params.Offset, 0), len(contacts)):]
+	}
+
+	if len(contacts) > limit {
+		contacts = contacts[:limit]
+		page.NextCursor = repositories.EncodeCursor(sortBy, &contacts[limit-1])
+	}
+	page.Contacts = contacts
+	return page, nil
+}
+
+// FindByID returns the live contact with the ID, or gorm.ErrRecordNotFound.
+func (r *contactRepository) FindByID(ctx context.Context, id uint) (*models.Contact, error) {
+	var contact *models.Contact
+	err := r.read(ctx, func(s *store) error {
+		stored, ok := s.contacts[id]
+		if !ok || !live(&stored) {
+			return gorm.ErrRecordNotFound
+		}
+		contact = clone(stored)
+		return nil
+	})
+	return contact, err
+}
+
+// FindByPublicID returns the live contact with the public ID, or gorm.ErrRecordNotFound.
+func (r *contactRepository) FindByPublicID(ctx context.Context, publicID string) (*models.Contact, error) {
+	var contact *models.Contact
+	err := r.read(ctx, func(s *store) error {
+		for _, stored := range s.contacts {
+			if stored.PublicID != nil && *stored.PublicID == publicID && live(&stored) {
+				contact = clone(stored)
+				return nil
+			}
+		}
+		return gorm.ErrRecordNotFound
+	})
+	return contact, err
+}
+
+// FindByIDs returns the live contacts with the IDs in ID order, or gorm.ErrRecordNotFound
+// when fewer contacts than IDs are found.
+func (r *contactRepository) FindByIDs(ctx context.Context, ids []uint) ([]models.Contact, error) {
+	contacts, err := r.FindExistingByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(contacts) != len(ids) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return contacts, nil
+}
+
+// FindExistingByIDs returns the live contacts with the IDs in ID order.
+func (r *contactRepository) FindExistingByIDs(ctx context.Context, ids []uint) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := r.read(ctx, func(s *store) error {
+		contacts = s.find(func(c *models.Contact) bool { return live(c) && slices.Contains(ids, c.ID) })
+		return nil
+	})
+	return contacts, err
+}
+
+// Search returns a page of the live contacts whose message contains every word of the
+// query, newest first, with a zero rank.
+func (r *contactRepository) Search(ctx context.Context, params repositories.SearchParams) (*repositories.SearchPage, error) {
+	limit := pageLimit(params.Limit)
+	words := strings.Fields(strings.ToLower(params.Query))
+
+	var contacts []models.Contact
+	err := r.read(ctx, func(s *store) error {
+		contacts = s.find(func(c *models.Contact) bool {
+			if !live(c) || !s.matches(c, params.Filter) {
+				return false
+			}
+			message := strings.ToLower(c.Message)
+			for _, word := range words {
+				if !strings.Contains(message, word) {
+					return false
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(contacts, newestFirst)
+
+	page := &repositories.SearchPage{Total: int64(len(contacts))}
+	contacts = contacts[min(max(params.Offset, 0), len(contacts)):]
+	if len(contacts) > limit {
+		contacts = contacts[:limit]
+		page.More = true
+	}
+	for _, contact := range contacts {
+		page.Results = append(page.Results, repositories.SearchResult{Contact: contact})
+	}
+	return page, nil
+}
+
+// FindDuplicateGroups is not supported, as it relies on the trigram similarity of Postgres.
+func (r *contactRepository) FindDuplicateGroups(ctx context.Context, criteria repositories.DuplicateCriteria) ([]repositories.DuplicateGroup, error) {
+	return nil, repositories.ErrUnsupportedByDriver
+}
+
+// FindAllByEmail returns the contacts of an email address, deleted or not, in ID order.
+func (r *contactRepository) FindAllByEmail(ctx context.Context, email string) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := r.read(ctx, func(s *store) error {
+		contacts = s.find(func(c *models.Contact) bool { return strings.EqualFold(c.Email, email) })
+		return nil
+	})
+	return contacts, err
+}
+
+// CountByEmailSince counts the contacts of an email address created since the given
+// time, deleted or not.
+func (r *contactRepository) CountByEmailSince(ctx context.Context, email string, since time.Time) (int64, error) {
+	var count int64
+	err := r.read(ctx, func(s *store) error {
+		for _, contact := range s.contacts {
+			if strings.EqualFold(contact.Email, email) && !contact.CreatedAt.Before(since) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// CountByChannelsSince counts the contacts of some channels created since the given
+// time, deleted or not.
+func (r *contactRepository) CountByChannelsSince(ctx context.Context, channels []models.Channel, since time.Time) (int64, error) {
+	var count int64
+	err := r.read(ctx, func(s *store) error {
+		for _, contact := range s.contacts {
+			if slices.Contains(channels, contact.Channel) && !contact.CreatedAt.Before(since) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// FindRepeatedSince returns the live contact with the lowest ID repeating a message of an
+// email address since the given time, or gorm.ErrRecordNotFound.
+func (r *contactRepository) FindRepeatedSince(ctx context.Context, email, messageHash string, since time.Time) (*models.Contact, error) {
+	var contact *models.Contact
+	err := r.read(ctx, func(s *store) error {
+		found := s.find(func(c *models.Contact) bool {
+			return live(c) && strings.EqualFold(c.Email, email) && !c.CreatedAt.Before(since) && c.MessageHash == messageHash
+		})
+		if len(found) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		contact = &found[0]
+		return nil
+	})
+	return contact, err
+}
+
+// ExistsByMessageID reports whether a contact, deleted or not, has the Message-ID.
+func (r *contactRepository) ExistsByMessageID(ctx context.Context, messageID string) (bool, error) {
+	var exists bool
+	err := r.read(ctx, func(s *store) error {
+		for _, contact := range s.contacts {
+			if contact.MessageID != nil && *contact.MessageID == messageID {
+				exists = true
+			}
+		}
+		return nil
+	})
+	return exists, err
+}
+
+// Update stores every field of the contact, like GORM's Save, which inserts contacts that
+// do not exist.
+func (r *contactRepository) Update(ctx context.Context, contact *models.Contact) error {
+	return r.write(ctx, func(s *store) error {
+		if err := s.checkUnique([]models.Contact{*contact}); err != nil {
+			return err
+		}
+		if _, ok := s.contacts[contact.ID]; !ok || contact.ID == 0 {
+			s.insert(contact, time.Now())
+			return nil
+		}
+		contact.UpdatedAt = time.Now()
+		s.contacts[contact.ID] = *clone(*contact)
+		return nil
+	})
+}
+
+// UpdateStatus sets the status of a live contact, or returns gorm.ErrRecordNotFound.
+func (r *contactRepository) UpdateStatus(ctx context.Context, id uint, status models.Status) error {
+	return r.write(ctx, func(s *store) error {
+		if s.update([]uint{id}, setStatus(status)) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// FindSnoozeDue lists the live contacts snoozed until at at the latest, the earliest
+// ending first.
+func (r *contactRepository) FindSnoozeDue(ctx context.Context, at time.Time, limit int) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := r.read(ctx, func(s *store) error {
+		due := s.find(func(c *models.Contact) bool {
+			return live(c) && c.SnoozedUntil != nil && !c.SnoozedUntil.After(at)
+		})
+		slices.SortStableFunc(due, func(a, b models.Contact) int {
+			return a.SnoozedUntil.Compare(*b.SnoozedUntil)
+		})
+		contacts = limitTo(due, limit)
+		return nil
+	})
+	return contacts, err
+}
+
+// EndSnooze clears the snooze of a live contact snoozed until at at the latest and sets
+// it back to new, or returns gorm.ErrRecordNotFound.
+func (r *contactRepository) EndSnooze(ctx context.Context, id uint, at time.Time) error {
+	return r.write(ctx, func(s *store) error {
+		contact, ok := s.contacts[id]
+		if !ok || contact.SnoozedUntil == nil || contact.SnoozedUntil.After(at) {
+			return gorm.ErrRecordNotFound
+		}
+		if s.update([]uint{id}, func(c *models.Contact) {
+			c.SnoozedUntil = nil
+			setStatus(models.StatusNew)(c)
+		}) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Delete soft-deletes the live contact with the ID of contact and sets its DeletedAt, as
+// GORM does.
+func (r *contactRepository) Delete(ctx context.Context, contact *models.Contact) error {
+	if contact.ID == 0 {
+		return gorm.ErrMissingWhereClause
+	}
+	return r.write(ctx, func(s *store) error {
+		now := time.Now()
+		if s.softDelete([]uint{contact.ID}, now) > 0 {
+			contact.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		}
+		return nil
+	})
+}
+
+// DeleteMany soft-deletes the live contacts with the IDs, recording the contact they
+// were merged into when mergedInto is not zero.
+func (r *contactRepository) DeleteMany(ctx context.Context, ids []uint, mergedInto uint) error {
+	return r.write(ctx, func(s *store) error {
+		if mergedInto != 0 {
+			s.update(ids, func(c *models.Contact) { c.MergedIntoID = &mergedInto })
+		}
+		s.softDelete(ids, time.Now())
+		return nil
+	})
+}
+
+// FindDeleted returns a page of the soft-deleted contacts, most recently deleted first.
+func (r *contactRepository) FindDeleted(ctx context.Context, params repositories.ListParams) (*repositories.ContactPage, error) {
+	limit := pageLimit(params.Limit)
+
+	var contacts []models.Contact
+	err := r.read(ctx, func(s *store) error {
+		contacts = s.find(func(c *models.Contact) bool { return !live(c) })
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	compare := func(a, b *models.Contact) int {
+		return cmp.Or(b.DeletedAt.Time.Compare(a.DeletedAt.Time), cmp.Compare(b.ID, a.ID))
+	}
+	slices.SortFunc(contacts, func(a, b models.Contact) int { return compare(&a, &b) })
+	page := &repositories.ContactPage{Total: int64(len(contacts))}
+
+	// Position the page after the cursor, or at the offset.
+	if params.Cursor != "" {
+		value, id, err := repositories.DecodeCursor(repositories.SortByDeletedAt, params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		last := models.Contact{ID: id, DeletedAt: gorm.DeletedAt{Time: value.(time.Time), Valid: true}}
+		start := slices.IndexFunc(contacts, func(c models.Contact) bool { return compare(&c, &last) > 0 })
+		if start < 0 {
+			start = len(contacts)
+		}
+		contacts = contacts[start:]
+	} else {
+		contacts = contacts[min(max(params.Offset, 0), len(contacts)):]
+	}
+
+	if len(contacts) > limit {
+		contacts = contacts[:limit]
+		page.NextCursor = repositories.EncodeCursor(repositories.SortByDeletedAt, &contacts[limit-1])
+	}
+	page.Contacts = contacts
+	return page, nil
+}
+
+// FindDeletedByID returns the soft-deleted contact with the ID, or gorm.ErrRecordNotFound.
+func (r *contactRepository) FindDeletedByID(ctx context.Context, id uint) (*models.Contact, error) {
+	var contact *models.Contact
+	err := r.read(ctx, func(s *store) error {
+		stored, ok := s.contacts[id]
+		if !ok || live(&stored) {
+			return gorm.ErrRecordNotFound
+		}
+		contact = clone(stored)
+		return nil
+	})
+	return contact, err
+}
+
+// Restore clears the deletion and merge of a soft-deleted contact, or returns
+// gorm.ErrRecordNotFound.
+func (r *contactRepository) Restore(ctx context.Context, id uint) error {
+	return r.write(ctx, func(s *store) error {
+		contact, ok := s.contacts[id]
+		if !ok || live(&contact) {
+			return gorm.ErrRecordNotFound
+		}
+		contact.DeletedAt = gorm.DeletedAt{}
+		contact.MergedIntoID = nil
+		contact.UpdatedAt = time.Now()
+		s.contacts[id] = contact
+		return nil
+	})
+}
+
+// CreateBatch stores copies of the contacts, all or none.
+func (r *contactRepository) CreateBatch(ctx context.Context, contacts []models.Contact) error {
+	return r.write(ctx, func(s *store) error {
+		if err := s.checkUnique(contacts); err != nil {
+			return err
+		}
+		now := time.Now()
+		for i := range contacts {
+			s.insert(&contacts[i], now)
+		}
+		return nil
+	})
+}
+
+// DeleteByIDs soft-deletes the live contacts with the IDs that are not under legal hold.
+func (r *contactRepository) DeleteByIDs(ctx context.Context, ids []uint) error {
+	return r.write(ctx, func(s *store) error {
+		deletable := make([]uint, 0, len(ids))
+		for _, id := range ids {
+			if contact, ok := s.contacts[id]; ok && !contact.LegalHold {
+				deletable = append(deletable, id)
+			}
+		}
+		s.softDelete(deletable, time.Now())
+		return nil
+	})
+}
+
+// UpdateStatusByIDs sets the status of the live contacts with the IDs.
+func (r *contactRepository) UpdateStatusByIDs(ctx context.Context, ids []uint, status models.Status) error {
+	return r.write(ctx, func(s *store) error {
+		s.update(ids, setStatus(status))
+		return nil
+	})
+}
+
+// SetEmailIssue records the email issue of the live contacts with the IDs.
+func (r *contactRepository) SetEmailIssue(ctx context.Context, ids []uint, issue models.EmailIssue, at time.Time) error {
+	return r.write(ctx, func(s *store) error {
+		s.update(ids, func(c *models.Contact) {
+			c.EmailIssue = issue
+			c.EmailIssueAt = &at
+		})
+		return nil
+	})
+}
+
+// SetCompany sets the company of a live contact, or returns gorm.ErrRecordNotFound.
+func (r *contactRepository) SetCompany(ctx context.Context, id uint, name, size string) error {
+	return r.write(ctx, func(s *store) error {
+		changed := s.update([]uint{id}, func(c *models.Contact) {
+			c.CompanyName = name
+			c.CompanySize = size
+		})
+		if changed == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// SetStarred adds or removes the star of a live contact, or returns gorm.ErrRecordNotFound.
+func (r *contactRepository) SetStarred(ctx context.Context, owner string, id uint, starred bool) error {
+	return r.write(ctx, func(s *store) error {
+		contact, ok := s.contacts[id]
+		if !ok || !live(&contact) {
+			return gorm.ErrRecordNotFound
+		}
+		if starred {
+			s.stars[star{owner, id}] = true
+		} else {
+			delete(s.stars, star{owner, id})
+		}
+		return nil
+	})
+}
+
+// HardDelete removes a soft-deleted contact that is not under legal hold, or returns
+// gorm.ErrRecordNotFound.
+func (r *contactRepository) HardDelete(ctx context.Context, id uint) error {
+	return r.write(ctx, func(s *store) error {
+		contact, ok := s.contacts[id]
+		if !ok || live(&contact) || contact.LegalHold {
+			return gorm.ErrRecordNotFound
+		}
+		delete(s.contacts, id)
+		maps.DeleteFunc(s.stars, func(key star, _ bool) bool { return key.contactID == id })
+		s.deleteMessages(id)
+		return nil
+	})
+}
+
+// FindExpiredDeleted returns up to limit contacts soft-deleted before the given time and
+// not under legal hold, in ID order.
+func (r *contactRepository) FindExpiredDeleted(ctx context.Context, before time.Time, limit int) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := r.read(ctx, func(s *store) error {
+		contacts = limitTo(s.find(func(c *models.Contact) bool {
+			return !live(c) && c.DeletedAt.Time.Before(before) && !c.LegalHold
+		}), limit)
+		return nil
+	})
+	return contacts, err
+}
+
+// FindAnonymizable returns up to limit live contacts with one of the statuses since
+// before the given time, neither anonymized nor under legal hold, in ID order.
+func (r *contactRepository) FindAnonymizable(ctx context.Context, before time.Time, statuses []models.Status, limit int) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := r.read(ctx, func(s *store) error {
+		contacts = limitTo(s.find(func(c *models.Contact) bool {
+			changedAt := c.CreatedAt
+			if c.StatusChangedAt != nil {
+				changedAt = *c.StatusChangedAt
+			}
+			return live(c) && c.AnonymizedAt == nil && !c.LegalHold && slices.Contains(statuses, c.Status) && changedAt.Before(before)
+		}), limit)
+		return nil
+	})
+	return contacts, err
+}
+
+// Anonymize writes the personal data columns of the live contacts not under legal hold,
+// and deletes the emails of their conversations.
+func (r *contactRepository) Anonymize(ctx context.Context, contacts []models.Contact) error {
+	return r.write(ctx, func(s *store) error {
+		for _, anonymized := range contacts {
+			stored, ok := s.contacts[anonymized.ID]
+			if !ok || stored.LegalHold {
+				continue
+			}
+			if s.update([]uint{anonymized.ID}, func(c *models.Contact) {
+				c.FullName = anonymized.FullName
+				c.Email = anonymized.Email
+				c.Phone = anonymized.Phone
+				c.FingerprintHash = anonymized.FingerprintHash
+				c.ConsentIP = anonymized.ConsentIP
+				c.AnonymizedAt = copyOf(anonymized.AnonymizedAt)
+			}) > 0 {
+				s.deleteMessages(anonymized.ID)
+			}
+		}
+		return nil
+	})
+}
+
+// insert stores a copy of contact, assigning what the database would: the ID, the
+// timestamps and column defaults, and the IDs of the attachments.
+func (s *store) insert(contact *models.Contact, now time.Time) {
+	if contact.ID == 0 {
+		contact.ID = s.lastID + 1
+	}
+	s.lastID = max(s.lastID, contact.ID)
+	if contact.CreatedAt.IsZero() {
+		contact.CreatedAt = now
+	}
+	if contact.UpdatedAt.IsZero() {
+		contact.UpdatedAt = now
+	}
+	contact.Channel = cmp.Or(contact.Channel, models.ChannelWeb)
+	contact.Status = cmp.Or(contact.Status, models.StatusNew)
+
+	for i := range contact.Attachments {
+		attachment := &contact.Attachments[i]
+		attachment.ContactID = contact.ID
+		if attachment.ID == 0 {
+			attachment.ID = s.lastAttachmentID + 1
+		}
+		s.lastAttachmentID = max(s.lastAttachmentID, attachment.ID)
+		if attachment.CreatedAt.IsZero() {
+			attachment.CreatedAt = now
+		}
+	}
+	s.contacts[contact.ID] = *clone(*contact)
+}
+
+// uniqueColumns are the columns of contacts with a unique index, and their value.
+var uniqueColumns = []struct {
+	name  string
+	value func(c *models.Contact) *string
+}{
+	{"message_id", func(c *models.Contact) *string { return c.MessageID }},
+	{"public_id", func(c *models.Contact) *string { return c.PublicID }},
+}
+
+// checkUnique rejects contacts whose Message-ID or public ID is already stored or repeated
+// with the *repositories.ConstraintError of the unique index of the column.
+func (s *store) checkUnique(contacts []models.Contact) error {
+	for _, column := range uniqueColumns {
+		seen := make(map[string]uint)
+		for _, stored := range s.contacts {
+			if value := column.value(&stored); value != nil {
+				seen[*value] = stored.ID
+			}
+		}
+		for _, contact := range contacts {
+			value := column.value(&contact)
+			if value == nil {
+				continue
+			}
+			if id, ok := seen[*value]; ok && (id != contact.ID || contact.ID == 0) {
+				return &repositories.ConstraintError{
+					Err:    repositories.ErrUniqueViolation,
+					Table:  models.Contact{}.TableName(),
+					Column: column.name,
+				}
+			}
+			seen[*value] = contact.ID
+		}
+	}
+	return nil
+}
+
+// find returns copies of the contacts matching keep, in ID order.
+func (s *store) find(keep func(c *models.Contact) bool) []models.Contact {
+	var contacts []models.Contact
+	for _, id := range slices.Sorted(maps.Keys(s.contacts)) {
+		contact := s.contacts[id]
+		if keep(&contact) {
+			contacts = append(contacts, *clone(contact))
+		}
+	}
+	return contacts
+}
+
+// update applies change to the live contacts with the IDs, setting their UpdatedAt as
+// GORM does for column updates, and returns the number of contacts changed.
+func (s *store) update(ids []uint, change func(c *models.Contact)) int {
+	changed := 0
+	now := time.Now()
+	for _, id := range slices.Compact(slices.Sorted(slices.Values(ids))) {
+		contact, ok := s.contacts[id]
+		if !ok || !live(&contact) {
+			continue
+		}
+		change(&contact)
+		contact.UpdatedAt = now
+		s.contacts[id] = contact
+		changed++
+	}
+	return changed
+}
+
+// softDelete sets DeletedAt on the live contacts with the IDs, and returns the number of
+// contacts deleted. Like GORM's soft delete, it leaves UpdatedAt unchanged.
+func (s *store) softDelete(ids []uint, now time.Time) int {
+	deleted := 0
+	for _, id := range ids {
+		contact, ok := s.contacts[id]
+		if !ok || !live(&contact) {
+			continue
+		}
+		contact.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		s.contacts[id] = contact
+		deleted++
+	}
+	return deleted
+}
+
+// setStatus returns the change setting the status of a contact and the time it changed.
+func setStatus(status models.Status) func(c *models.Contact) {
+	return func(c *models.Contact) {
+		now := time.Now()
+		c.Status = status
+		c.StatusChangedAt = &now
+	}
+}
+
+// matches reports whether contact matches filter, like the filter of the GORM
+// implementation on databases without pg_trgm.
+func (s *store) matches(contact *models.Contact, filter repositories.ContactFilter) bool {
+	switch {
+	case filter.Channel != "" && contact.Channel != filter.Channel,
+		filter.FingerprintHash != "" && contact.FingerprintHash != filter.FingerprintHash,
+		filter.Status != "" && contact.Status != filter.Status,
+		filter.Email != "" && !strings.EqualFold(contact.Email, filter.Email),
+		!filter.CreatedFrom.IsZero() && contact.CreatedAt.Before(filter.CreatedFrom),
+		!filter.CreatedTo.IsZero() && !contact.CreatedAt.Before(filter.CreatedTo),
+		filter.MinLeadScore != nil && contact.LeadScore < *filter.MinLeadScore,
+		filter.StarredBy != "" && !s.stars[star{filter.StarredBy, contact.ID}]:
+		return false
+	}
+	if filter.Search == "" {
+		return true
+	}
+	search := strings.ToLower(filter.Search)
+	return strings.Contains(strings.ToLower(contact.Message), search) ||
+		strings.Contains(strings.ToLower(contact.FullName), search) ||
+		strings.Contains(strings.ToLower(contact.Email), search)
+}
+
+// live reports whether contact is not soft-deleted.
+func live(contact *models.Contact) bool {
+	return !contact.DeletedAt.Valid
+}
+
+// newestFirst orders contacts by creation time, then ID, descending.
+func newestFirst(a, b models.Contact) int {
+	return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+}
+
+// pageLimit returns the page size of a requested limit, as FindPaged and Search apply it.
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return repositories.DefaultPageSize
+	}
+	return min(limit, repositories.MaxPageSize)
+}
+
+// limitTo keeps the first limit contacts; a negative limit keeps all, as in GORM.
+func limitTo(contacts []models.Contact, limit int) []models.Contact {
+	if limit >= 0 && len(contacts) > limit {
+		return contacts[:limit]
+	}
+	return contacts
+}
+
+// clone returns a copy of contact sharing no memory with it. The attachments are left
+// out, as the GORM implementation does not load them.
+func clone(contact models.Contact) *models.Contact {
+	contact.DuplicateOfID = copyOf(contact.DuplicateOfID)
+	contact.ConsentAt = copyOf(contact.ConsentAt)
+	contact.StatusChangedAt = copyOf(contact.StatusChangedAt)
+	contact.MergedIntoID = copyOf(contact.MergedIntoID)
+	contact.AnonymizedAt = copyOf(contact.AnonymizedAt)
+	contact.AttachmentsPurgedAt = copyOf(contact.AttachmentsPurgedAt)
+	contact.SnoozedUntil = copyOf(contact.SnoozedUntil)
+	contact.MessageID = copyOf(contact.MessageID)
+	contact.PublicID = copyOf(contact.PublicID)
+	contact.Attachments = nil
+	return &contact
+}
+
+// copyOf returns a pointer to a copy of *p, or nil when p is nil.
+func copyOf[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	copied := *p
+	return &copied
+}
@@ -0,0 +1,20 @@
+package memory
+
+import (
+	"api-contact-form/repositories"
+	"api-contact-form/repositories/repotest"
+	"testing"
+)
+
+func TestContactRepository(t *testing.T) {
+	repotest.TestContactRepository(t, func(t *testing.T) repositories.ContactRepository {
+		return NewContactRepository()
+	})
+}
+
+func TestEmailMessageRepository(t *testing.T) {
+	repotest.TestEmailMessageRepository(t, func(t *testing.T) (repositories.ContactRepository, repositories.EmailMessageRepository) {
+		contacts := NewContactRepository()
+		return contacts, NewEmailMessageRepository(contacts)
+	})
+}
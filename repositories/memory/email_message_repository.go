@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// emailMessageRepository is a map-backed implementation of
+// repositories.EmailMessageRepository. It shares the store of the contact repository it
+// was created from, so that the emails follow the contacts when they are anonymized or
+// purged.
+type emailMessageRepository struct {
+	contacts *contactRepository
+}
+
+// NewEmailMessageRepository constructs an in-memory EmailMessageRepository storing the
+// emails of the contacts of a repository returned by NewContactRepository.
+func NewEmailMessageRepository(contacts repositories.ContactRepository) repositories.EmailMessageRepository {
+	return &emailMessageRepository{contacts: contacts.(*contactRepository)}
+}
+
+// Create stores a copy of the email, assigning its ID and creation time.
+func (r *emailMessageRepository) Create(ctx context.Context, message *models.EmailMessage) error {
+	return r.contacts.write(ctx, func(s *store) error {
+		if message.MessageID != nil {
+			for _, stored := range s.messages {
+				if stored.MessageID != nil && *stored.MessageID == *message.MessageID {
+					return &repositories.ConstraintError{
+						Err:    repositories.ErrUniqueViolation,
+						Table:  models.EmailMessage{}.TableName(),
+						Column: "message_id",
+					}
+				}
+			}
+		}
+
+		if message.ID == 0 {
+			message.ID = s.lastMessageID + 1
+		}
+		s.lastMessageID = max(s.lastMessageID, message.ID)
+		if message.CreatedAt.IsZero() {
+			message.CreatedAt = time.Now()
+		}
+		stored := *message
+		stored.MessageID = copyOf(message.MessageID)
+		stored.Contact = nil
+		s.messages[stored.ID] = stored
+		return nil
+	})
+}
+
+// FindByContact lists the emails of a contact in the order they were stored.
+func (r *emailMessageRepository) FindByContact(ctx context.Context, contactID uint) ([]models.EmailMessage, error) {
+	var messages []models.EmailMessage
+	err := r.contacts.read(ctx, func(s *store) error {
+		messages = s.findMessages(func(m *models.EmailMessage) bool { return m.ContactID == contactID })
+		return nil
+	})
+	return messages, err
+}
+
+// FindByContacts lists the emails of the contacts in the order they were stored.
+func (r *emailMessageRepository) FindByContacts(ctx context.Context, contactIDs []uint) ([]models.EmailMessage, error) {
+	messages := []models.EmailMessage{}
+	err := r.contacts.read(ctx, func(s *store) error {
+		messages = append(messages, s.findMessages(func(m *models.EmailMessage) bool {
+			return slices.Contains(contactIDs, m.ContactID)
+		})...)
+		return nil
+	})
+	return messages, err
+}
+
+// FindThreadContact returns the highest ID of the live contacts created from, or having
+// stored, one of the emails.
+func (r *emailMessageRepository) FindThreadContact(ctx context.Context, messageIDs []string) (uint, error) {
+	var found uint
+	err := r.contacts.read(ctx, func(s *store) error {
+		threads := make(map[uint]bool)
+		for _, message := range s.messages {
+			if message.MessageID != nil && slices.Contains(messageIDs, *message.MessageID) {
+				threads[message.ContactID] = true
+			}
+		}
+		for id, contact := range s.contacts {
+			created := contact.MessageID != nil && slices.Contains(messageIDs, *contact.MessageID)
+			if live(&contact) && (created || threads[id]) {
+				found = max(found, id)
+			}
+		}
+		if found == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	return found, err
+}
+
+// findMessages returns copies of the emails kept by keep, oldest first.
+func (s *store) findMessages(keep func(m *models.EmailMessage) bool) []models.EmailMessage {
+	var messages []models.EmailMessage
+	for _, id := range slices.Sorted(maps.Keys(s.messages)) {
+		message := s.messages[id]
+		if keep(&message) {
+			message.MessageID = copyOf(message.MessageID)
+			messages = append(messages, message)
+		}
+	}
+	slices.SortStableFunc(messages, func(a, b models.EmailMessage) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return messages
+}
+
+// deleteMessages deletes the emails of the conversation of a contact.
+func (s *store) deleteMessages(contactID uint) {
+	maps.DeleteFunc(s.messages, func(_ uint, message models.EmailMessage) bool {
+		return message.ContactID == contactID
+	})
+}
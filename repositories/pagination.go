@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+/*
+This file defines the paging parameters and results of ContactRepository.FindPaged and
+FindDeleted.
+
+Pages are requested either by offset or by cursor. Offsets are convenient for
+numbered pages; cursors continue right after the last contact of the previous page
+(keyset pagination), which stays fast and stable on large tables while new
+contacts keep arriving.
+*/
+
+const (
+	// DefaultPageSize is the page size used when ListParams.Limit is not set.
+	DefaultPageSize = 20
+	// MaxPageSize is the largest page size FindPaged returns.
+	MaxPageSize = 100
+)
+
+// SortField is a column contacts can be sorted on.
+type SortField string
+
+const (
+	// SortByCreatedAt sorts contacts by submission time. It is the default.
+	SortByCreatedAt SortField = "created_at"
+	// SortByFullName sorts contacts alphabetically by name.
+	SortByFullName SortField = "full_name"
+	// SortByDeletedAt sorts deleted contacts by deletion time. It is the order of
+	// FindDeleted, and cannot be requested from FindPaged.
+	SortByDeletedAt SortField = "deleted_at"
+)
+
+// Valid reports whether f is one of the sortable columns.
+func (f SortField) Valid() bool {
+	return f == SortByCreatedAt || f == SortByFullName
+}
+
+// ErrInvalidCursor is returned by FindPaged for cursors it did not issue or that
+// were issued for a different sort field.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListParams describes a page of contacts requested from FindPaged.
+type ListParams struct {
+	// Filter narrows down the contacts; zero-valued fields are ignored.
+	Filter ContactFilter
+	// SortBy is the sort column, SortByCreatedAt when empty.
+	SortBy SortField
+	// Ascending sorts in ascending order instead of the default descending order.
+	Ascending bool
+	// Limit is the page size, DefaultPageSize when zero and at most MaxPageSize.
+	Limit int
+	// Offset skips the given number of contacts. It is ignored when Cursor is set.
+	Offset int
+	// Cursor is the NextCursor of the previous page.
+	Cursor string
+}
+
+// ContactPage is a page of contacts returned by FindPaged.
+type ContactPage struct {
+	// Contacts are the contacts of the page.
+	Contacts []models.Contact
+	// Total is the number of contacts matching the filter, across all pages.
+	Total int64
+	// NextCursor requests the following page, or is empty on the last page.
+	NextCursor string
+}
+
+// cursor is the position after which the next page starts: the sort value and ID
+// of the last contact of the previous page.
+type cursor struct {
+	SortBy SortField `json:"s"`
+	Value  string    `json:"v"`
+	ID     uint      `json:"id"`
+}
+
+// EncodeCursor returns the opaque cursor continuing after contact. It is exported, with
+// DecodeCursor, so that other implementations of ContactRepository issue the same cursors.
+func EncodeCursor(sortBy SortField, contact *models.Contact) string {
+	c := cursor{SortBy: sortBy, ID: contact.ID, Value: contact.FullName}
+	switch sortBy {
+	case SortByCreatedAt:
+		c.Value = contact.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case SortByDeletedAt:
+		c.Value = contact.DeletedAt.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses an opaque cursor and returns the sort value as the type of its
+// column: a time.Time for SortByCreatedAt and SortByDeletedAt, and a string for
+// SortByFullName. It returns
+// ErrInvalidCursor for cursors that were not issued for sortBy.
+func DecodeCursor(sortBy SortField, token string) (interface{}, uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.SortBy != sortBy {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	if sortBy == SortByCreatedAt || sortBy == SortByDeletedAt {
+		at, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return at, c.ID, nil
+	}
+	return c.Value, c.ID, nil
+}
+
+// pageLimit returns the page size of a requested limit: DefaultPageSize when it is not
+// set, and at most MaxPageSize.
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	return min(limit, MaxPageSize)
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed RejectionRepository, which stores the submissions
+rejected by the spam protection.
+*/
+
+// RejectionRepository defines the interface for rejected submission data operations.
+type RejectionRepository interface {
+	// Create inserts a new rejected submission record into the database.
+	Create(rejection *models.RejectedSubmission) error
+
+	// CountByClientIPSince counts the submissions of a client IP address rejected for the
+	// given reason at or after since.
+	CountByClientIPSince(ctx context.Context, clientIP string, reason models.RejectionReason, since time.Time) (int64, error)
+}
+
+// rejectionRepository is a GORM-based implementation of RejectionRepository.
+type rejectionRepository struct {
+	db *gorm.DB
+}
+
+// NewRejectionRepository constructs a new RejectionRepository backed by the provided GORM DB.
+func NewRejectionRepository(db *gorm.DB) RejectionRepository {
+	return &rejectionRepository{db: db}
+}
+
+// Create inserts a new rejected submission into the database using GORM.
+func (r *rejectionRepository) Create(rejection *models.RejectedSubmission) error {
+	return translateError(r.db.Create(rejection).Error)
+}
+
+// CountByClientIPSince counts the matching rejected submissions in the database.
+func (r *rejectionRepository) CountByClientIPSince(ctx context.Context, clientIP string, reason models.RejectionReason, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RejectedSubmission{}).
+		Where("client_ip = ? AND reason = ? AND created_at >= ?", clientIP, reason, since).
+		Count(&count).Error
+	return count, err
+}
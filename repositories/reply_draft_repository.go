@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed ReplyDraftRepository, which stores the draft replies
+to contacts. A contact has at most one draft; it is removed by the database together
+with a purged contact.
+*/
+
+// ReplyDraftRepository defines the interface for reply draft data operations.
+type ReplyDraftRepository interface {
+	// FindByContact retrieves the draft of a contact. It returns gorm.ErrRecordNotFound
+	// when the contact has none.
+	FindByContact(ctx context.Context, contactID uint) (*models.ReplyDraft, error)
+
+	// Save inserts a draft without an ID, or updates an existing one. It returns a
+	// *ConstraintError wrapping ErrUniqueViolation when another draft was inserted for
+	// the contact in the meantime.
+	Save(ctx context.Context, draft *models.ReplyDraft) error
+
+	// DeleteByContact removes the draft of a contact. It returns gorm.ErrRecordNotFound
+	// when the contact has none.
+	DeleteByContact(ctx context.Context, contactID uint) error
+}
+
+// replyDraftRepository is a GORM-based implementation of ReplyDraftRepository.
+type replyDraftRepository struct {
+	db *gorm.DB
+}
+
+// NewReplyDraftRepository constructs a new ReplyDraftRepository backed by the provided GORM DB.
+func NewReplyDraftRepository(db *gorm.DB) ReplyDraftRepository {
+	return &replyDraftRepository{db: db}
+}
+
+// FindByContact looks up the draft of a contact and returns it.
+func (r *replyDraftRepository) FindByContact(ctx context.Context, contactID uint) (*models.ReplyDraft, error) {
+	var draft models.ReplyDraft
+	if err := r.db.WithContext(ctx).Where("contact_id = ?", contactID).First(&draft).Error; err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// Save persists the draft, leaving its contact untouched.
+func (r *replyDraftRepository) Save(ctx context.Context, draft *models.ReplyDraft) error {
+	return translateError(r.db.WithContext(ctx).Omit("Contact").Save(draft).Error)
+}
+
+// DeleteByContact deletes the draft of a contact.
+func (r *replyDraftRepository) DeleteByContact(ctx context.Context, contactID uint) error {
+	result := r.db.WithContext(ctx).Where("contact_id = ?", contactID).Delete(&models.ReplyDraft{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,1129 @@
+// Package repotest implements conformance suites for the implementations of
+// repositories.ContactRepository and repositories.EmailMessageRepository, so that the
+// in-memory repositories used to test services and handlers behave like the GORM
+// repositories used in production.
+//
+// The suite only relies on the behavior shared by every supported database. Each
+// implementation runs it from its own tests:
+//
+//	func TestContactRepository(t *testing.T) {
+//		repotest.TestContactRepository(t, func(t *testing.T) repositories.ContactRepository {
+//			return memory.NewContactRepository()
+//		})
+//	}
+package repotest
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// epoch is the creation time of the first contact of the suite. Contacts are created at
+// explicit times, so that their order does not depend on the speed of the test.
+var epoch = time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+// TestContactRepository runs the conformance suite against the repositories returned by
+// newRepository, which must be empty and independent of each other.
+func TestContactRepository(t *testing.T, newRepository func(t *testing.T) repositories.ContactRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo repositories.ContactRepository)
+	}{
+		{"Create", testCreate},
+		{"SoftDelete", testSoftDelete},
+		{"Purge", testPurge},
+		{"Filter", testFilter},
+		{"FindPaged", testFindPaged},
+		{"FindPagedCursor", testFindPagedCursor},
+		{"FindDeletedPaged", testFindDeletedPaged},
+		{"FindInBatches", testFindInBatches},
+		{"FindByIDs", testFindByIDs},
+		{"Email", testEmail},
+		{"MessageID", testMessageID},
+		{"PublicID", testPublicID},
+		{"Update", testUpdate},
+		{"Star", testStar},
+		{"Bulk", testBulk},
+		{"CreateBatch", testCreateBatch},
+		{"WithTx", testWithTx},
+		{"Search", testSearch},
+		{"Retention", testRetention},
+		{"Snooze", testSnooze},
+		{"Context", testContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+// TestEmailMessageRepository runs the conformance suite of the email conversations against
+// the repositories returned by newRepositories, which must be empty and independent of each
+// other. The email repository must store the emails of the contacts of the other one.
+func TestEmailMessageRepository(t *testing.T, newRepositories func(t *testing.T) (repositories.ContactRepository, repositories.EmailMessageRepository)) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo repositories.ContactRepository, messages repositories.EmailMessageRepository)
+	}{
+		{"Create", testCreateEmail},
+		{"FindThreadContact", testFindThreadContact},
+		{"Anonymize", testAnonymizeEmails},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, messages := newRepositories(t)
+			tt.test(t, repo, messages)
+		})
+	}
+}
+
+// newContact returns a valid contact created the given number of minutes after epoch.
+func newContact(name string, minutes int) models.Contact {
+	return models.Contact{
+		FullName:  name,
+		Email:     fmt.Sprintf("%s@example.com", name),
+		Phone:     "+628123456789",
+		Message:   "Hello from " + name,
+		CreatedAt: epoch.Add(time.Duration(minutes) * time.Minute),
+	}
+}
+
+// create stores the contacts in order and returns them with their IDs.
+func create(t *testing.T, repo repositories.ContactRepository, contacts ...models.Contact) []models.Contact {
+	t.Helper()
+	for i := range contacts {
+		if err := repo.Create(t.Context(), &contacts[i]); err != nil {
+			t.Fatalf("Create(%s): %v", contacts[i].FullName, err)
+		}
+	}
+	return contacts
+}
+
+// ids returns the IDs of the contacts.
+func ids(contacts []models.Contact) []uint {
+	result := make([]uint, 0, len(contacts))
+	for _, contact := range contacts {
+		result = append(result, contact.ID)
+	}
+	return result
+}
+
+// names returns the names of the contacts.
+func names(contacts []models.Contact) []string {
+	result := make([]string, 0, len(contacts))
+	for _, contact := range contacts {
+		result = append(result, contact.FullName)
+	}
+	return result
+}
+
+// checkNames fails the test when the contacts do not have the wanted names, in order.
+func checkNames(t *testing.T, call string, contacts []models.Contact, want ...string) {
+	t.Helper()
+	if got := names(contacts); !slices.Equal(got, want) {
+		t.Errorf("%s = %q, want %q", call, got, want)
+	}
+}
+
+// checkNotFound fails the test when err is not gorm.ErrRecordNotFound.
+func checkNotFound(t *testing.T, call string, err error) {
+	t.Helper()
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("%s error = %v, want gorm.ErrRecordNotFound", call, err)
+	}
+}
+
+// find returns the live contact with the ID, failing the test when there is none.
+func find(t *testing.T, repo repositories.ContactRepository, id uint) *models.Contact {
+	t.Helper()
+	contact, err := repo.FindByID(t.Context(), id)
+	if err != nil {
+		t.Fatalf("FindByID(%d): %v", id, err)
+	}
+	return contact
+}
+
+// testCreate checks the IDs, timestamps and defaults assigned by Create.
+func testCreate(t *testing.T, repo repositories.ContactRepository) {
+	contacts := create(t, repo, newContact("alice", 0), newContact("bob", 1))
+	if contacts[0].ID == 0 || contacts[1].ID <= contacts[0].ID {
+		t.Fatalf("Create assigned IDs %v, want increasing non-zero IDs", ids(contacts))
+	}
+	if contacts[0].UpdatedAt.IsZero() {
+		t.Error("Create did not set UpdatedAt")
+	}
+
+	got := find(t, repo, contacts[0].ID)
+	if got.FullName != "alice" || got.Email != "alice@example.com" || !got.CreatedAt.Equal(epoch) {
+		t.Errorf("FindByID = %s <%s> created %v, want alice <alice@example.com> created %v", got.FullName, got.Email, got.CreatedAt, epoch)
+	}
+	if got.Status != models.StatusNew || got.Channel != models.ChannelWeb {
+		t.Errorf("FindByID status and channel = %q, %q, want the defaults %q, %q", got.Status, got.Channel, models.StatusNew, models.ChannelWeb)
+	}
+
+	// Changing a returned contact must not change the stored one.
+	got.FullName = "changed"
+	if again := find(t, repo, contacts[0].ID); again.FullName != "alice" {
+		t.Errorf("FindByID after changing a result = %q, want alice", again.FullName)
+	}
+
+	_, err := repo.FindByID(t.Context(), contacts[1].ID+100)
+	checkNotFound(t, "FindByID(missing)", err)
+
+	// Attachments are stored with the contact and get their own IDs.
+	withAttachment := newContact("carol", 2)
+	withAttachment.Attachments = []models.Attachment{{Filename: "a.txt", ContentType: "text/plain", Size: 1, StorageKey: "a"}}
+	withAttachment = create(t, repo, withAttachment)[0]
+	if attachment := withAttachment.Attachments[0]; attachment.ID == 0 || attachment.ContactID != withAttachment.ID {
+		t.Errorf("Create attachment ID and contact ID = %d, %d, want non-zero and %d", attachment.ID, attachment.ContactID, withAttachment.ID)
+	}
+}
+
+// testSoftDelete checks that deleted contacts are hidden from live queries until restored.
+func testSoftDelete(t *testing.T, repo repositories.ContactRepository) {
+	contacts := create(t, repo, newContact("alice", 0), newContact("bob", 1))
+	alice := contacts[0]
+
+	if err := repo.Delete(t.Context(), &alice); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if !alice.DeletedAt.Valid {
+		t.Error("Delete did not set DeletedAt")
+	}
+	_, err := repo.FindByID(t.Context(), alice.ID)
+	checkNotFound(t, "FindByID(deleted)", err)
+
+	all, err := repo.FindAll(t.Context(), repositories.ContactFilter{})
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	checkNames(t, "FindAll", all, "bob")
+
+	deleted, err := repo.FindDeleted(t.Context(), repositories.ListParams{})
+	if err != nil {
+		t.Fatalf("FindDeleted: %v", err)
+	}
+	checkNames(t, "FindDeleted", deleted.Contacts, "alice")
+	if _, err := repo.FindDeletedByID(t.Context(), alice.ID); err != nil {
+		t.Errorf("FindDeletedByID: %v", err)
+	}
+	_, err = repo.FindDeletedByID(t.Context(), contacts[1].ID)
+	checkNotFound(t, "FindDeletedByID(live)", err)
+
+	// Deleted contacts still count for the lookups of their sender.
+	byEmail, err := repo.FindAllByEmail(t.Context(), "alice@example.com")
+	if err != nil {
+		t.Fatalf("FindAllByEmail: %v", err)
+	}
+	checkNames(t, "FindAllByEmail(deleted)", byEmail, "alice")
+
+	if err := repo.Restore(t.Context(), alice.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored := find(t, repo, alice.ID); restored.DeletedAt.Valid {
+		t.Error("Restore left DeletedAt set")
+	}
+	checkNotFound(t, "Restore(live)", repo.Restore(t.Context(), alice.ID))
+}
+
+// testPurge checks that only deleted contacts not under legal hold are removed for good.
+func testPurge(t *testing.T, repo repositories.ContactRepository) {
+	contacts := create(t, repo, newContact("alice", 0), newContact("bob", 1))
+	alice, bob := contacts[0], contacts[1]
+
+	checkNotFound(t, "HardDelete(live)", repo.HardDelete(t.Context(), alice.ID))
+
+	bob.LegalHold = true
+	if err := repo.Update(t.Context(), &bob); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repo.DeleteMany(t.Context(), []uint{alice.ID, bob.ID}, 0); err != nil {
+		t.Fatalf("DeleteMany: %v", err)
+	}
+	checkNotFound(t, "HardDelete(legal hold)", repo.HardDelete(t.Context(), bob.ID))
+
+	if err := repo.HardDelete(t.Context(), alice.ID); err != nil {
+		t.Fatalf("HardDelete: %v", err)
+	}
+	_, err := repo.FindDeletedByID(t.Context(), alice.ID)
+	checkNotFound(t, "FindDeletedByID(purged)", err)
+	checkNotFound(t, "Restore(purged)", repo.Restore(t.Context(), alice.ID))
+}
+
+// testFilter checks every field of ContactFilter.
+func testFilter(t *testing.T, repo repositories.ContactRepository) {
+	alice := newContact("alice", 0)
+	alice.Channel = models.ChannelEmail
+	alice.FingerprintHash = "f1"
+	bob := newContact("bob", 10)
+	bob.Message = "Please send an INVOICE"
+	bob.Status = models.StatusRead
+	carol := newContact("carol", 20)
+	carol.Email = "Carol@Example.com"
+	carol.LeadScore = 30
+	minLeadScore := 30
+	created := create(t, repo, alice, bob, carol)
+	bob = created[1]
+
+	tests := []struct {
+		name   string
+		filter repositories.ContactFilter
+		want   []string
+	}{
+		{"none", repositories.ContactFilter{}, []string{"carol", "bob", "alice"}},
+		{"channel", repositories.ContactFilter{Channel: models.ChannelEmail}, []string{"alice"}},
+		{"fingerprint", repositories.ContactFilter{FingerprintHash: "f1"}, []string{"alice"}},
+		{"status", repositories.ContactFilter{Status: models.StatusRead}, []string{"bob"}},
+		{"email", repositories.ContactFilter{Email: "CAROL@example.COM"}, []string{"carol"}},
+		{"created from", repositories.ContactFilter{CreatedFrom: bob.CreatedAt}, []string{"carol", "bob"}},
+		{"created to", repositories.ContactFilter{CreatedTo: bob.CreatedAt}, []string{"alice"}},
+		{"min lead score", repositories.ContactFilter{MinLeadScore: &minLeadScore}, []string{"carol"}},
+		{"search message", repositories.ContactFilter{Search: "invoice"}, []string{"bob"}},
+		{"search email", repositories.ContactFilter{Search: "carol@"}, []string{"carol"}},
+		{"search wildcard", repositories.ContactFilter{Search: "%"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contacts, err := repo.FindAll(t.Context(), tt.filter)
+			if err != nil {
+				t.Fatalf("FindAll: %v", err)
+			}
+			checkNames(t, "FindAll", contacts, tt.want...)
+		})
+	}
+
+	// Counts include deleted contacts
+	if err := repo.Delete(t.Context(), &bob); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	count, err := repo.CountByChannelsSince(t.Context(), []models.Channel{models.ChannelWeb, models.ChannelAPI}, bob.CreatedAt)
+	if err != nil {
+		t.Fatalf("CountByChannelsSince: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("CountByChannelsSince = %d, want 2", count)
+	}
+}
+
+// testFindPaged checks the sorting, limits and offsets of FindPaged.
+func testFindPaged(t *testing.T, repo repositories.ContactRepository) {
+	create(t, repo, newContact("carol", 0), newContact("alice", 1), newContact("bob", 1))
+
+	tests := []struct {
+		name      string
+		params    repositories.ListParams
+		want      []string
+		hasCursor bool
+	}{
+		{"default", repositories.ListParams{}, []string{"bob", "alice", "carol"}, false},
+		{"ascending", repositories.ListParams{Ascending: true}, []string{"carol", "alice", "bob"}, false},
+		{"by name", repositories.ListParams{SortBy: repositories.SortByFullName, Ascending: true}, []string{"alice", "bob", "carol"}, false},
+		{"limit", repositories.ListParams{Limit: 2}, []string{"bob", "alice"}, true},
+		{"offset", repositories.ListParams{Limit: 2, Offset: 2}, []string{"carol"}, false},
+		{"offset past the end", repositories.ListParams{Offset: 5}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.FindPaged(t.Context(), tt.params)
+			if err != nil {
+				t.Fatalf("FindPaged: %v", err)
+			}
+			checkNames(t, "FindPaged", page.Contacts, tt.want...)
+			if page.Total != 3 {
+				t.Errorf("FindPaged total = %d, want 3", page.Total)
+			}
+			if (page.NextCursor != "") != tt.hasCursor {
+				t.Errorf("FindPaged next cursor = %q, want one: %v", page.NextCursor, tt.hasCursor)
+			}
+		})
+	}
+
+	if _, err := repo.FindPaged(t.Context(), repositories.ListParams{SortBy: "email_address"}); err == nil {
+		t.Error("FindPaged(invalid sort field) succeeded, want an error")
+	}
+}
+
+// testFindPagedCursor checks that following the cursors visits every contact once, in
+// every sort order.
+func testFindPagedCursor(t *testing.T, repo repositories.ContactRepository) {
+	var contacts []models.Contact
+	for i := range 7 {
+		// Pairs of contacts share their creation time and name, so that the ID breaks ties.
+		contacts = append(contacts, newContact(fmt.Sprintf("contact%d", i/2), i/2))
+	}
+	create(t, repo, contacts...)
+
+	for _, sortBy := range []repositories.SortField{repositories.SortByCreatedAt, repositories.SortByFullName} {
+		for _, ascending := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s ascending %v", sortBy, ascending), func(t *testing.T) {
+				want := ids(contacts)
+				if !ascending {
+					slices.Reverse(want)
+				}
+
+				var got []uint
+				params := repositories.ListParams{SortBy: sortBy, Ascending: ascending, Limit: 3}
+				for range len(contacts) {
+					page, err := repo.FindPaged(t.Context(), params)
+					if err != nil {
+						t.Fatalf("FindPaged: %v", err)
+					}
+					got = append(got, ids(page.Contacts)...)
+					if page.NextCursor == "" {
+						break
+					}
+					params.Cursor = page.NextCursor
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("FindPaged pages = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+
+	first, err := repo.FindPaged(t.Context(), repositories.ListParams{Limit: 1})
+	if err != nil {
+		t.Fatalf("FindPaged: %v", err)
+	}
+	for _, params := range []repositories.ListParams{
+		{Cursor: "not a cursor"},
+		{Cursor: first.NextCursor, SortBy: repositories.SortByFullName},
+	} {
+		if _, err := repo.FindPaged(t.Context(), params); !errors.Is(err, repositories.ErrInvalidCursor) {
+			t.Errorf("FindPaged(cursor %q, sort %q) error = %v, want ErrInvalidCursor", params.Cursor, params.SortBy, err)
+		}
+	}
+}
+
+// testFindDeletedPaged checks that the trash is paged by offset and by cursor, most
+// recently deleted first, and leaves out the live contacts.
+func testFindDeletedPaged(t *testing.T, repo repositories.ContactRepository) {
+	var contacts []models.Contact
+	for i := range 5 {
+		contacts = append(contacts, newContact(fmt.Sprintf("contact%d", i), i))
+	}
+	contacts = create(t, repo, contacts...)
+	for i := range 4 {
+		if err := repo.Delete(t.Context(), &contacts[i]); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+	want := ids(contacts[:4])
+	slices.Reverse(want)
+
+	page, err := repo.FindDeleted(t.Context(), repositories.ListParams{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("FindDeleted(offset): %v", err)
+	}
+	if got := ids(page.Contacts); !slices.Equal(got, want[1:3]) || page.Total != 4 {
+		t.Errorf("FindDeleted(offset) = %v of %d, want %v of 4", got, page.Total, want[1:3])
+	}
+
+	var got []uint
+	params := repositories.ListParams{Limit: 3}
+	for range len(contacts) {
+		page, err := repo.FindDeleted(t.Context(), params)
+		if err != nil {
+			t.Fatalf("FindDeleted: %v", err)
+		}
+		got = append(got, ids(page.Contacts)...)
+		if page.NextCursor == "" {
+			break
+		}
+		params.Cursor = page.NextCursor
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("FindDeleted pages = %v, want %v", got, want)
+	}
+
+	live, err := repo.FindPaged(t.Context(), repositories.ListParams{Limit: 1})
+	if err != nil {
+		t.Fatalf("FindPaged: %v", err)
+	}
+	_, err = repo.FindDeleted(t.Context(), repositories.ListParams{Cursor: repositories.EncodeCursor(repositories.SortByCreatedAt, &live.Contacts[0])})
+	if !errors.Is(err, repositories.ErrInvalidCursor) {
+		t.Errorf("FindDeleted(FindPaged cursor) error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+// testFindInBatches checks that FindInBatches visits the matching contacts in ID order.
+func testFindInBatches(t *testing.T, repo repositories.ContactRepository) {
+	var contacts []models.Contact
+	for i := range 5 {
+		contacts = append(contacts, newContact(fmt.Sprintf("contact%d", i), 10-i))
+	}
+	contacts = create(t, repo, contacts...)
+	if err := repo.Delete(t.Context(), &contacts[1]); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	var batches [][]uint
+	err := repo.FindInBatches(t.Context(), repositories.ContactFilter{}, 2, func(batch []models.Contact) error {
+		batches = append(batches, ids(batch))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("FindInBatches: %v", err)
+	}
+	want := [][]uint{{contacts[0].ID, contacts[2].ID}, {contacts[3].ID, contacts[4].ID}}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Errorf("FindInBatches batches = %v, want %v", batches, want)
+	}
+
+	// An error stops the iteration and is returned.
+	stop := errors.New("stop")
+	calls := 0
+	err = repo.FindInBatches(t.Context(), repositories.ContactFilter{}, 1, func([]models.Contact) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("FindInBatches with a failing callback = %v after %d calls, want stop after 1", err, calls)
+	}
+
+	err = repo.FindInBatches(t.Context(), repositories.ContactFilter{Email: "nobody@example.com"}, 2, func([]models.Contact) error {
+		t.Error("FindInBatches called fn without matching contacts")
+		return nil
+	})
+	if err != nil {
+		t.Errorf("FindInBatches(no match): %v", err)
+	}
+}
+
+// testFindByIDs checks the lookups of several contacts.
+func testFindByIDs(t *testing.T, repo repositories.ContactRepository) {
+	contacts := create(t, repo, newContact("alice", 0), newContact("bob", 1), newContact("carol", 2))
+	if err := repo.Delete(t.Context(), &contacts[2]); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	missing := contacts[2].ID + 100
+
+	found, err := repo.FindByIDs(t.Context(), []uint{contacts[1].ID, contacts[0].ID})
+	if err != nil {
+		t.Fatalf("FindByIDs: %v", err)
+	}
+	checkNames(t, "FindByIDs", found, "alice", "bob")
+
+	_, err = repo.FindByIDs(t.Context(), []uint{contacts[0].ID, missing})
+	checkNotFound(t, "FindByIDs(missing)", err)
+	_, err = repo.FindByIDs(t.Context(), []uint{contacts[0].ID, contacts[2].ID})
+	checkNotFound(t, "FindByIDs(deleted)", err)
+
+	existing, err := repo.FindExistingByIDs(t.Context(), []uint{missing, contacts[2].ID, contacts[1].ID, contacts[0].ID})
+	if err != nil {
+		t.Fatalf("FindExistingByIDs: %v", err)
+	}
+	checkNames(t, "FindExistingByIDs", existing, "alice", "bob")
+}
+
+// testEmail checks the lookups of the contacts of an email address.
+func testEmail(t *testing.T, repo repositories.ContactRepository) {
+	first := newContact("alice", 0)
+	first.MessageHash = "h1"
+	second := newContact("alice", 10)
+	second.Email = "ALICE@example.com"
+	second.MessageHash = "h1"
+	third := newContact("alice", 20)
+	third.MessageHash = "h2"
+	contacts := create(t, repo, first, second, third, newContact("bob", 30))
+
+	all, err := repo.FindAllByEmail(t.Context(), "Alice@Example.com")
+	if err != nil {
+		t.Fatalf("FindAllByEmail: %v", err)
+	}
+	if got, want := ids(all), ids(contacts[:3]); !slices.Equal(got, want) {
+		t.Errorf("FindAllByEmail = %v, want %v", got, want)
+	}
+
+	count, err := repo.CountByEmailSince(t.Context(), "alice@example.com", second.CreatedAt)
+	if err != nil {
+		t.Fatalf("CountByEmailSince: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("CountByEmailSince = %d, want 2", count)
+	}
+
+	repeated, err := repo.FindRepeatedSince(t.Context(), "alice@example.com", "h1", epoch)
+	if err != nil {
+		t.Fatalf("FindRepeatedSince: %v", err)
+	}
+	if repeated.ID != contacts[0].ID {
+		t.Errorf("FindRepeatedSince = %d, want the earliest contact %d", repeated.ID, contacts[0].ID)
+	}
+	repeated, err = repo.FindRepeatedSince(t.Context(), "alice@example.com", "h1", second.CreatedAt)
+	if err != nil {
+		t.Fatalf("FindRepeatedSince: %v", err)
+	}
+	if repeated.ID != contacts[1].ID {
+		t.Errorf("FindRepeatedSince(later) = %d, want %d", repeated.ID, contacts[1].ID)
+	}
+	_, err = repo.FindRepeatedSince(t.Context(), "alice@example.com", "h3", epoch)
+	checkNotFound(t, "FindRepeatedSince(other message)", err)
+}
+
+// testMessageID checks that Message-IDs are unique and found even on deleted contacts.
+func testMessageID(t *testing.T, repo repositories.ContactRepository) {
+	messageID := "<1@example.com>"
+	contact := newContact("alice", 0)
+	contact.MessageID = &messageID
+	contact = create(t, repo, contact)[0]
+
+	duplicate := newContact("bob", 1)
+	duplicate.MessageID = &messageID
+	var violation *repositories.ConstraintError
+	err := repo.Create(t.Context(), &duplicate)
+	if !errors.As(err, &violation) || !errors.Is(err, repositories.ErrUniqueViolation) || violation.Column != "message_id" {
+		t.Errorf("Create(duplicate Message-ID) error = %v, want a unique violation of message_id", err)
+	}
+
+	if err := repo.Delete(t.Context(), &contact); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	exists, err := repo.ExistsByMessageID(t.Context(), messageID)
+	if err != nil || !exists {
+		t.Errorf("ExistsByMessageID(deleted) = %v, %v, want true", exists, err)
+	}
+	exists, err = repo.ExistsByMessageID(t.Context(), "<2@example.com>")
+	if err != nil || exists {
+		t.Errorf("ExistsByMessageID(unknown) = %v, %v, want false", exists, err)
+	}
+}
+
+// testPublicID checks that public IDs are unique and only find live contacts.
+func testPublicID(t *testing.T, repo repositories.ContactRepository) {
+	publicID := "0190a7c2-5b1e-7c3d-9f00-000000000001"
+	contact := newContact("alice", 0)
+	contact.PublicID = &publicID
+	contact = create(t, repo, contact)[0]
+
+	duplicate := newContact("bob", 1)
+	duplicate.PublicID = &publicID
+	var violation *repositories.ConstraintError
+	err := repo.Create(t.Context(), &duplicate)
+	if !errors.As(err, &violation) || !errors.Is(err, repositories.ErrUniqueViolation) || violation.Column != "public_id" {
+		t.Errorf("Create(duplicate public ID) error = %v, want a unique violation of public_id", err)
+	}
+
+	found, err := repo.FindByPublicID(t.Context(), publicID)
+	if err != nil || found.ID != contact.ID {
+		t.Errorf("FindByPublicID = %v, %v, want contact %d", found, err, contact.ID)
+	}
+	_, err = repo.FindByPublicID(t.Context(), "0190a7c2-5b1e-7c3d-9f00-000000000002")
+	checkNotFound(t, "FindByPublicID(unknown)", err)
+
+	if err := repo.Delete(t.Context(), &contact); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, err = repo.FindByPublicID(t.Context(), publicID)
+	checkNotFound(t, "FindByPublicID(deleted)", err)
+}
+
+// testUpdate checks Update, UpdateStatus and SetCompany.
+func testUpdate(t *testing.T, repo repositories.ContactRepository) {
+	contacts := create(t, repo, newContact("alice", 0), newContact("bob", 1))
+	alice := contacts[0]
+
+	alice.FullName = "Alice Smith"
+	alice.LegalHold = true
+	if err := repo.Update(t.Context(), &alice); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got := find(t, repo, alice.ID)
+	if got.FullName != "Alice Smith" || !got.LegalHold || !got.CreatedAt.Equal(epoch) {
+		t.Errorf("FindByID after Update = %q, legal hold %v, created %v", got.FullName, got.LegalHold, got.CreatedAt)
+	}
+
+	if err := repo.UpdateStatus(t.Context(), alice.ID, models.StatusArchived); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	got = find(t, repo, alice.ID)
+	if got.Status != models.StatusArchived || got.StatusChangedAt == nil || got.FullName != "Alice Smith" {
+		t.Errorf("FindByID after UpdateStatus = status %q changed at %v, name %q", got.Status, got.StatusChangedAt, got.FullName)
+	}
+
+	if err := repo.SetCompany(t.Context(), alice.ID, "Acme", "51-250"); err != nil {
+		t.Fatalf("SetCompany: %v", err)
+	}
+	got = find(t, repo, alice.ID)
+	if got.CompanyName != "Acme" || got.CompanySize != "51-250" || got.Status != models.StatusArchived {
+		t.Errorf("FindByID after SetCompany = company %q size %q, status %q", got.CompanyName, got.CompanySize, got.Status)
+	}
+
+	checkNotFound(t, "UpdateStatus(missing)", repo.UpdateStatus(t.Context(), contacts[1].ID+100, models.StatusRead))
+	if err := repo.Delete(t.Context(), &contacts[1]); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	checkNotFound(t, "UpdateStatus(deleted)", repo.UpdateStatus(t.Context(), contacts[1].ID, models.StatusRead))
+	checkNotFound(t, "SetCompany(deleted)", repo.SetCompany(t.Context(), contacts[1].ID, "Acme", ""))
+}
+
+// testStar checks SetStarred and the StarredBy filter.
+func testStar(t *testing.T, repo repositories.ContactRepository) {
+	contacts := create(t, repo, newContact("alice", 0), newContact("bob", 1), newContact("carol", 2))
+	starred := func(owner string) []models.Contact {
+		t.Helper()
+		got, err := repo.FindAll(t.Context(), repositories.ContactFilter{StarredBy: owner})
+		if err != nil {
+			t.Fatalf("FindAll(starred by %s): %v", owner, err)
+		}
+		return got
+	}
+
+	for _, star := range []struct {
+		owner string
+		id    uint
+	}{{"ann", contacts[0].ID}, {"ann", contacts[2].ID}, {"ann", contacts[2].ID}, {"ben", contacts[1].ID}} {
+		if err := repo.SetStarred(t.Context(), star.owner, star.id, true); err != nil {
+			t.Fatalf("SetStarred(%s, %d): %v", star.owner, star.id, err)
+		}
+	}
+	checkNames(t, "FindAll(starred by ann)", starred("ann"), "carol", "alice")
+	checkNames(t, "FindAll(starred by ben)", starred("ben"), "bob")
+
+	if err := repo.SetStarred(t.Context(), "ann", contacts[0].ID, false); err != nil {
+		t.Fatalf("SetStarred(unstar): %v", err)
+	}
+	if err := repo.SetStarred(t.Context(), "ann", contacts[1].ID, false); err != nil {
+		t.Fatalf("SetStarred(unstar unstarred): %v", err)
+	}
+	checkNames(t, "FindAll(starred by ann) after unstar", starred("ann"), "carol")
+
+	checkNotFound(t, "SetStarred(missing)", repo.SetStarred(t.Context(), "ann", contacts[2].ID+100, true))
+	if err := repo.Delete(t.Context(), &contacts[2]); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	checkNotFound(t, "SetStarred(deleted)", repo.SetStarred(t.Context(), "ann", contacts[2].ID, true))
+	checkNames(t, "FindAll(starred by ann) after delete", starred("ann"))
+}
+
+// testBulk checks the operations on several contacts.
+func testBulk(t *testing.T, repo repositories.ContactRepository) {
+	held := newContact("held", 2)
+	held.LegalHold = true
+	contacts := create(t, repo, newContact("alice", 0), newContact("bob", 1), held, newContact("dave", 3))
+	alice, bob := contacts[0], contacts[1]
+
+	if err := repo.UpdateStatusByIDs(t.Context(), []uint{alice.ID, bob.ID}, models.StatusRead); err != nil {
+		t.Fatalf("UpdateStatusByIDs: %v", err)
+	}
+	read, err := repo.FindAll(t.Context(), repositories.ContactFilter{Status: models.StatusRead})
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	checkNames(t, "FindAll(read)", read, "bob", "alice")
+
+	// Contacts under legal hold are not deleted.
+	if err := repo.DeleteByIDs(t.Context(), []uint{alice.ID, contacts[2].ID}); err != nil {
+		t.Fatalf("DeleteByIDs: %v", err)
+	}
+	live, err := repo.FindAll(t.Context(), repositories.ContactFilter{})
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	checkNames(t, "FindAll after DeleteByIDs", live, "dave", "held", "bob")
+
+	// Email issues are only recorded on live contacts.
+	if err := repo.SetEmailIssue(t.Context(), []uint{alice.ID, contacts[3].ID}, models.EmailBounced, epoch); err != nil {
+		t.Fatalf("SetEmailIssue: %v", err)
+	}
+	if dave := find(t, repo, contacts[3].ID); dave.EmailIssue != models.EmailBounced || dave.EmailIssueAt == nil || !dave.EmailIssueAt.Equal(epoch) {
+		t.Errorf("SetEmailIssue = %q at %v, want %q at %v", dave.EmailIssue, dave.EmailIssueAt, models.EmailBounced, epoch)
+	}
+	deleted, err := repo.FindDeletedByID(t.Context(), alice.ID)
+	if err != nil {
+		t.Fatalf("FindDeletedByID: %v", err)
+	}
+	if deleted.EmailIssue != "" {
+		t.Errorf("SetEmailIssue changed deleted contact to %q", deleted.EmailIssue)
+	}
+
+	// Merged contacts record the contact they were merged into until restored.
+	if err := repo.DeleteMany(t.Context(), []uint{bob.ID}, contacts[3].ID); err != nil {
+		t.Fatalf("DeleteMany: %v", err)
+	}
+	merged, err := repo.FindDeletedByID(t.Context(), bob.ID)
+	if err != nil {
+		t.Fatalf("FindDeletedByID: %v", err)
+	}
+	if merged.MergedIntoID == nil || *merged.MergedIntoID != contacts[3].ID {
+		t.Errorf("FindDeletedByID merged into = %v, want %d", merged.MergedIntoID, contacts[3].ID)
+	}
+	if err := repo.Restore(t.Context(), bob.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored := find(t, repo, bob.ID); restored.MergedIntoID != nil {
+		t.Errorf("Restore left merged into = %d", *restored.MergedIntoID)
+	}
+}
+
+// testCreateBatch checks that CreateBatch inserts every contact or none.
+func testCreateBatch(t *testing.T, repo repositories.ContactRepository) {
+	contacts := []models.Contact{newContact("alice", 0), newContact("bob", 1)}
+	if err := repo.CreateBatch(t.Context(), contacts); err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+	if contacts[0].ID == 0 || contacts[1].ID == 0 {
+		t.Errorf("CreateBatch assigned IDs %v, want non-zero IDs", ids(contacts))
+	}
+
+	messageID := "<1@example.com>"
+	failing := []models.Contact{newContact("carol", 2), newContact("dave", 3), newContact("erin", 4)}
+	failing[0].MessageID = &messageID
+	failing[2].MessageID = &messageID
+	if err := repo.CreateBatch(t.Context(), failing); !errors.Is(err, repositories.ErrUniqueViolation) {
+		t.Errorf("CreateBatch(duplicate Message-ID) error = %v, want a unique violation", err)
+	}
+	all, err := repo.FindAll(t.Context(), repositories.ContactFilter{})
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	checkNames(t, "FindAll after a failed CreateBatch", all, "bob", "alice")
+}
+
+// testWithTx checks that the changes of a transaction are committed or rolled back together.
+func testWithTx(t *testing.T, repo repositories.ContactRepository) {
+	contacts := create(t, repo, newContact("alice", 0), newContact("bob", 1))
+
+	err := repo.WithTx(t.Context(), func(tx repositories.ContactRepository) error {
+		if err := tx.UpdateStatus(t.Context(), contacts[0].ID, models.StatusRead); err != nil {
+			return err
+		}
+		carol := newContact("carol", 2)
+		return tx.Create(t.Context(), &carol)
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if got := find(t, repo, contacts[0].ID); got.Status != models.StatusRead {
+		t.Errorf("status after a committed transaction = %q, want read", got.Status)
+	}
+
+	rollback := errors.New("rollback")
+	err = repo.WithTx(t.Context(), func(tx repositories.ContactRepository) error {
+		if err := tx.DeleteMany(t.Context(), []uint{contacts[1].ID}, 0); err != nil {
+			return err
+		}
+		// Changes are visible inside the transaction, including to nested ones.
+		err := tx.WithTx(t.Context(), func(nested repositories.ContactRepository) error {
+			_, err := nested.FindByID(t.Context(), contacts[1].ID)
+			checkNotFound(t, "FindByID(deleted in the transaction)", err)
+			dave := newContact("dave", 3)
+			return nested.Create(t.Context(), &dave)
+		})
+		if err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("WithTx error = %v, want rollback", err)
+	}
+
+	all, err := repo.FindAll(t.Context(), repositories.ContactFilter{})
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	checkNames(t, "FindAll after a rolled back transaction", all, "carol", "bob", "alice")
+}
+
+// testSearch checks the substring search of the messages shared by every database.
+func testSearch(t *testing.T, repo repositories.ContactRepository) {
+	alice := newContact("alice", 0)
+	alice.Message = "Please send the invoice for March"
+	bob := newContact("bob", 1)
+	bob.Message = "Where is my invoice?"
+	bob.Channel = models.ChannelEmail
+	carol := newContact("carol", 2)
+	carol.Message = "The March invoice is wrong"
+	create(t, repo, alice, bob, carol, newContact("dave", 3))
+
+	tests := []struct {
+		name   string
+		params repositories.SearchParams
+		want   []string
+		total  int64
+		more   bool
+	}{
+		{"one word", repositories.SearchParams{Query: "invoice"}, []string{"carol", "bob", "alice"}, 3, false},
+		{"every word", repositories.SearchParams{Query: "invoice march"}, []string{"carol", "alice"}, 2, false},
+		{"filter", repositories.SearchParams{Query: "invoice", Filter: repositories.ContactFilter{Channel: models.ChannelEmail}}, []string{"bob"}, 1, false},
+		{"limit", repositories.SearchParams{Query: "invoice", Limit: 2}, []string{"carol", "bob"}, 3, true},
+		{"offset", repositories.SearchParams{Query: "invoice", Limit: 2, Offset: 2}, []string{"alice"}, 3, false},
+		{"no match", repositories.SearchParams{Query: "refund"}, nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.Search(t.Context(), tt.params)
+			if err != nil {
+				if errors.Is(err, repositories.ErrUnsupportedByDriver) {
+					t.Skip("Search is not supported")
+				}
+				t.Fatalf("Search: %v", err)
+			}
+			var contacts []models.Contact
+			for _, result := range page.Results {
+				contacts = append(contacts, result.Contact)
+			}
+			checkNames(t, "Search", contacts, tt.want...)
+			if page.Total != tt.total || page.More != tt.more {
+				t.Errorf("Search total and more = %d, %v, want %d, %v", page.Total, page.More, tt.total, tt.more)
+			}
+		})
+	}
+}
+
+// testRetention checks the queries of the retention policy and Anonymize.
+func testRetention(t *testing.T, repo repositories.ContactRepository) {
+	held := newContact("held", 2)
+	held.LegalHold = true
+	contacts := create(t, repo, newContact("alice", 0), newContact("bob", 1), held, newContact("dave", 3), newContact("erin", 4))
+	alice, bob, dave, erin := contacts[0], contacts[1], contacts[3], contacts[4]
+
+	// Only deleted contacts not under legal hold expire, oldest first.
+	if err := repo.DeleteMany(t.Context(), []uint{alice.ID, bob.ID, contacts[2].ID}, 0); err != nil {
+		t.Fatalf("DeleteMany: %v", err)
+	}
+	future := time.Now().Add(time.Hour)
+	expired, err := repo.FindExpiredDeleted(t.Context(), future, 10)
+	if err != nil {
+		t.Fatalf("FindExpiredDeleted: %v", err)
+	}
+	checkNames(t, "FindExpiredDeleted", expired, "alice", "bob")
+	if expired, err = repo.FindExpiredDeleted(t.Context(), future, 1); err != nil {
+		t.Fatalf("FindExpiredDeleted: %v", err)
+	}
+	checkNames(t, "FindExpiredDeleted(limit 1)", expired, "alice")
+	if expired, err = repo.FindExpiredDeleted(t.Context(), time.Now().Add(-time.Hour), 10); err != nil {
+		t.Fatalf("FindExpiredDeleted: %v", err)
+	}
+	checkNames(t, "FindExpiredDeleted(before the deletion)", expired)
+
+	// Resolved contacts are aged from their last status change, or their creation.
+	if err := repo.UpdateStatus(t.Context(), dave.ID, models.StatusReplied); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	erin.Status = models.StatusSpam
+	if err := repo.Update(t.Context(), &erin); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	resolved := []models.Status{models.StatusReplied, models.StatusSpam}
+	anonymizable, err := repo.FindAnonymizable(t.Context(), future, resolved, 10)
+	if err != nil {
+		t.Fatalf("FindAnonymizable: %v", err)
+	}
+	checkNames(t, "FindAnonymizable", anonymizable, "dave", "erin")
+	if anonymizable, err = repo.FindAnonymizable(t.Context(), epoch.Add(time.Hour), resolved, 10); err != nil {
+		t.Fatalf("FindAnonymizable: %v", err)
+	}
+	checkNames(t, "FindAnonymizable(before the status change)", anonymizable, "erin")
+
+	// Anonymized contacts are no longer returned.
+	erin.Anonymize(time.Now())
+	if err := repo.Anonymize(t.Context(), []models.Contact{erin}); err != nil {
+		t.Fatalf("Anonymize: %v", err)
+	}
+	got := find(t, repo, erin.ID)
+	if got.FullName != erin.FullName || got.Email != erin.Email || got.AnonymizedAt == nil || got.Message != "Hello from erin" {
+		t.Errorf("FindByID after Anonymize = %q <%s>, anonymized at %v, message %q", got.FullName, got.Email, got.AnonymizedAt, got.Message)
+	}
+	if anonymizable, err = repo.FindAnonymizable(t.Context(), future, resolved, 10); err != nil {
+		t.Fatalf("FindAnonymizable: %v", err)
+	}
+	checkNames(t, "FindAnonymizable after Anonymize", anonymizable, "dave")
+}
+
+// testSnooze checks that the contacts whose snooze ended are found, earliest first, and
+// set back to new once.
+func testSnooze(t *testing.T, repo repositories.ContactRepository) {
+	contacts := create(t, repo, newContact("alice", 0), newContact("bob", 1), newContact("carol", 2), newContact("dave", 3))
+	now := time.Now()
+	for i, until := range []time.Time{now.Add(-time.Minute), now.Add(-time.Hour), now.Add(time.Hour), now.Add(-time.Hour)} {
+		contacts[i].Status = models.StatusRead
+		contacts[i].SnoozedUntil = &until
+		if err := repo.Update(t.Context(), &contacts[i]); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+	}
+	alice, bob, dave := contacts[0], contacts[1], contacts[3]
+	if err := repo.DeleteMany(t.Context(), []uint{dave.ID}, 0); err != nil {
+		t.Fatalf("DeleteMany: %v", err)
+	}
+
+	// Live contacts whose snooze ended are due, the earliest ending first.
+	due, err := repo.FindSnoozeDue(t.Context(), now, 10)
+	if err != nil {
+		t.Fatalf("FindSnoozeDue: %v", err)
+	}
+	checkNames(t, "FindSnoozeDue", due, "bob", "alice")
+	if due, err = repo.FindSnoozeDue(t.Context(), now, 1); err != nil {
+		t.Fatalf("FindSnoozeDue: %v", err)
+	}
+	checkNames(t, "FindSnoozeDue(limit 1)", due, "bob")
+
+	// Ending a snooze sets the contact back to new, and only once.
+	if err := repo.EndSnooze(t.Context(), bob.ID, now); err != nil {
+		t.Fatalf("EndSnooze: %v", err)
+	}
+	if got := find(t, repo, bob.ID); got.SnoozedUntil != nil || got.Status != models.StatusNew || got.StatusChangedAt == nil {
+		t.Errorf("FindByID after EndSnooze = snoozed until %v, status %s changed at %v", got.SnoozedUntil, got.Status, got.StatusChangedAt)
+	}
+	checkNotFound(t, "EndSnooze(ended)", repo.EndSnooze(t.Context(), bob.ID, now))
+	checkNotFound(t, "EndSnooze(not due)", repo.EndSnooze(t.Context(), contacts[2].ID, now))
+	checkNotFound(t, "EndSnooze(deleted)", repo.EndSnooze(t.Context(), dave.ID, now))
+	if due, err = repo.FindSnoozeDue(t.Context(), now, 10); err != nil {
+		t.Fatalf("FindSnoozeDue: %v", err)
+	}
+	checkNames(t, "FindSnoozeDue after EndSnooze", due, alice.FullName)
+}
+
+// testContext checks that a cancelled context fails the queries.
+func testContext(t *testing.T, repo repositories.ContactRepository) {
+	contacts := create(t, repo, newContact("alice", 0))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := repo.FindByID(ctx, contacts[0].ID); err == nil {
+		t.Error("FindByID(cancelled) succeeded, want an error")
+	}
+	bob := newContact("bob", 1)
+	if err := repo.Create(ctx, &bob); err == nil {
+		t.Error("Create(cancelled) succeeded, want an error")
+	}
+	all, err := repo.FindAll(t.Context(), repositories.ContactFilter{})
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	checkNames(t, "FindAll after a cancelled Create", all, "alice")
+}
+
+// newEmail returns an email of the conversation of a contact sent the given number of
+// minutes after epoch.
+func newEmail(contactID uint, messageID string, minutes int) models.EmailMessage {
+	return models.EmailMessage{
+		ContactID: contactID,
+		Direction: models.EmailInbound,
+		MessageID: &messageID,
+		FromEmail: "submitter@example.com",
+		Subject:   "Re: " + messageID,
+		Body:      "Reply " + messageID,
+		CreatedAt: epoch.Add(time.Duration(minutes) * time.Minute),
+	}
+}
+
+// createEmails stores the emails in order.
+func createEmails(t *testing.T, messages repositories.EmailMessageRepository, emails ...models.EmailMessage) {
+	t.Helper()
+	for i := range emails {
+		if err := messages.Create(t.Context(), &emails[i]); err != nil {
+			t.Fatalf("Create(%s): %v", *emails[i].MessageID, err)
+		}
+	}
+}
+
+// emailSubjects returns the subjects of the emails, in order.
+func emailSubjects(emails []models.EmailMessage) []string {
+	subjects := make([]string, len(emails))
+	for i, email := range emails {
+		subjects[i] = email.Subject
+	}
+	return subjects
+}
+
+// checkEmails fails the test unless the emails have the subjects, in order.
+func checkEmails(t *testing.T, call string, emails []models.EmailMessage, want ...string) {
+	t.Helper()
+	if got := emailSubjects(emails); !slices.Equal(got, want) {
+		t.Errorf("%s = %q, want %q", call, got, want)
+	}
+}
+
+// testCreateEmail checks that emails are listed oldest first and their Message-IDs unique.
+func testCreateEmail(t *testing.T, repo repositories.ContactRepository, messages repositories.EmailMessageRepository) {
+	contacts := create(t, repo, newContact("alice", 0), newContact("bob", 1))
+	alice, bob := contacts[0], contacts[1]
+	createEmails(t, messages, newEmail(alice.ID, "2@example.com", 2), newEmail(bob.ID, "3@example.com", 3), newEmail(alice.ID, "1@example.com", 1))
+
+	emails, err := messages.FindByContact(t.Context(), alice.ID)
+	if err != nil {
+		t.Fatalf("FindByContact: %v", err)
+	}
+	checkEmails(t, "FindByContact", emails, "Re: 1@example.com", "Re: 2@example.com")
+	if emails, err = messages.FindByContacts(t.Context(), []uint{alice.ID, bob.ID}); err != nil {
+		t.Fatalf("FindByContacts: %v", err)
+	}
+	checkEmails(t, "FindByContacts", emails, "Re: 1@example.com", "Re: 2@example.com", "Re: 3@example.com")
+	if emails, err = messages.FindByContacts(t.Context(), nil); err != nil || emails == nil {
+		t.Errorf("FindByContacts(none) = %v, %v, want an empty list", emails, err)
+	}
+
+	duplicate := newEmail(bob.ID, "1@example.com", 4)
+	var violation *repositories.ConstraintError
+	err = messages.Create(t.Context(), &duplicate)
+	if !errors.As(err, &violation) || !errors.Is(err, repositories.ErrUniqueViolation) || violation.Column != "message_id" {
+		t.Errorf("Create(duplicate Message-ID) error = %v, want a unique violation of message_id", err)
+	}
+}
+
+// testFindThreadContact checks that replies are matched to the most recent live contact
+// created from, or having stored, the emails they answer.
+func testFindThreadContact(t *testing.T, repo repositories.ContactRepository, messages repositories.EmailMessageRepository) {
+	original := "original@example.com"
+	alice := newContact("alice", 0)
+	alice.MessageID = &original
+	contacts := create(t, repo, alice, newContact("bob", 1), newContact("carol", 2))
+	alice, bob, carol := contacts[0], contacts[1], contacts[2]
+	createEmails(t, messages, newEmail(bob.ID, "bob@example.com", 3), newEmail(carol.ID, "carol@example.com", 4))
+
+	lookups := []struct {
+		messageIDs []string
+		want       uint
+	}{
+		{[]string{original}, alice.ID},
+		{[]string{"bob@example.com"}, bob.ID},
+		{[]string{original, "bob@example.com"}, bob.ID},
+	}
+	for _, lookup := range lookups {
+		if got, err := messages.FindThreadContact(t.Context(), lookup.messageIDs); err != nil || got != lookup.want {
+			t.Errorf("FindThreadContact(%q) = %d, %v, want %d", lookup.messageIDs, got, err, lookup.want)
+		}
+	}
+
+	if err := repo.Delete(t.Context(), &carol); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	for _, messageIDs := range [][]string{{"carol@example.com"}, {"unknown@example.com"}, nil} {
+		_, err := messages.FindThreadContact(t.Context(), messageIDs)
+		checkNotFound(t, fmt.Sprintf("FindThreadContact(%q)", messageIDs), err)
+	}
+}
+
+// testAnonymizeEmails checks that Anonymize deletes the conversations of the anonymized
+// contacts, and only theirs.
+func testAnonymizeEmails(t *testing.T, repo repositories.ContactRepository, messages repositories.EmailMessageRepository) {
+	held := newContact("held", 2)
+	held.LegalHold = true
+	contacts := create(t, repo, newContact("alice", 0), newContact("bob", 1), held)
+	alice, bob := contacts[0], contacts[1]
+	held = contacts[2]
+	createEmails(t, messages, newEmail(alice.ID, "alice@example.com", 3), newEmail(bob.ID, "bob@example.com", 4), newEmail(held.ID, "held@example.com", 5))
+
+	alice.Anonymize(time.Now())
+	held.Anonymize(time.Now())
+	if err := repo.Anonymize(t.Context(), []models.Contact{alice, held}); err != nil {
+		t.Fatalf("Anonymize: %v", err)
+	}
+	emails, err := messages.FindByContacts(t.Context(), ids(contacts))
+	if err != nil {
+		t.Fatalf("FindByContacts: %v", err)
+	}
+	checkEmails(t, "FindByContacts after Anonymize", emails, "Re: bob@example.com", "Re: held@example.com")
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"context"
+	"strings"
+)
+
+/*
+This file implements ContactRepository.Search, the full-text search of the contact
+messages. On Postgres, messages are matched and ranked with text search: the query is
+parsed with websearch_to_tsquery, so that it accepts quoted phrases, "or" and -excluded
+words, and the FullTextIndex GIN index covers the searched expression. Other databases
+fall back to a case-insensitive substring match of every word of the query.
+*/
+
+// FullTextIndex is the name of the GIN index of the text search vector of the messages.
+const FullTextIndex = "idx_contact_messages_message_fts"
+
+// FullTextConfig is the text search configuration of the messages. The simple
+// configuration does not stem words or drop stop words, as messages are written in
+// any language. The index is only used by queries with the same configuration.
+const FullTextConfig = "simple"
+
+// fullTextVector is the text search vector of the messages, as covered by FullTextIndex.
+const fullTextVector = "to_tsvector('" + FullTextConfig + "', message_text)"
+
+// fullTextQuery parses a search query into a text search query.
+const fullTextQuery = "websearch_to_tsquery('" + FullTextConfig + "', ?)"
+
+// SearchParams describes a page of results requested from Search.
+type SearchParams struct {
+	// Query is the text searched for in the messages.
+	Query string
+	// Filter narrows down the contacts searched; zero-valued fields are ignored, and
+	// Filter.Search must be empty.
+	Filter ContactFilter
+	// Limit is the page size, DefaultPageSize when zero and at most MaxPageSize.
+	Limit int
+	// Offset skips the given number of results.
+	Offset int
+}
+
+// SearchResult is a contact matching a search.
+type SearchResult struct {
+	// Contact is the matching contact.
+	Contact models.Contact
+	// Rank is the relevance of the message to the query; higher is more relevant. It is
+	// zero on databases without text search, where results are sorted newest first.
+	Rank float64
+}
+
+// SearchPage is a page of results returned by Search.
+type SearchPage struct {
+	// Results are the results of the page, most relevant first.
+	Results []SearchResult
+	// Total is the number of contacts matching the search, across all pages.
+	Total int64
+	// More reports whether another page follows.
+	More bool
+}
+
+// searchHit is the ID and rank of a contact matching a search. The rank is not called
+// "rank", a reserved word in MySQL.
+type searchHit struct {
+	ID   uint
+	Rank float64 `gorm:"column:search_rank"`
+}
+
+// Search returns a page of the non-deleted contacts whose message matches the query,
+// most relevant first, with the ID as tie-breaker so that pages are stable.
+func (r *contactRepository) Search(ctx context.Context, params SearchParams) (*SearchPage, error) {
+	limit := params.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	// Match the query, ranking the matches where the database supports it.
+	query := applyFilter(r.db.WithContext(ctx).Model(&models.Contact{}), params.Filter)
+	selection, selectionArgs, order := "id, 0 AS search_rank", []any{}, "created_at DESC, id DESC"
+	if r.db.Dialector.Name() == "postgres" {
+		query = query.Where(fullTextVector+" @@ "+fullTextQuery, params.Query)
+		selection = "id, ts_rank(" + fullTextVector + ", " + fullTextQuery + ") AS search_rank"
+		selectionArgs = []any{params.Query}
+		order = "search_rank DESC, id DESC"
+	} else {
+		for _, word := range strings.Fields(params.Query) {
+			pattern := "%" + strings.ToLower(portableLikeEscaper.Replace(word)) + "%"
+			query = query.Where("LOWER(message_text) LIKE ? ESCAPE '!'", pattern)
+		}
+	}
+
+	page := &SearchPage{}
+	if err := query.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	// Rank the page, fetching one extra hit to learn whether another page follows.
+	var hits []searchHit
+	err := query.Select(selection, selectionArgs...).Order(order).Limit(limit + 1).Offset(params.Offset).Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+		page.More = true
+	}
+	if len(hits) == 0 {
+		return page, nil
+	}
+
+	// Load the contacts of the page and return them in the order of their rank.
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	var contacts []models.Contact
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Contact, len(contacts))
+	for _, contact := range contacts {
+		byID[contact.ID] = contact
+	}
+	for _, hit := range hits {
+		if contact, ok := byID[hit.ID]; ok {
+			page.Results = append(page.Results, SearchResult{Contact: contact, Rank: hit.Rank})
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+This file provides the GORM-backed SeenCredentialRepository, which stores the form tokens
+and idempotency keys that submissions were accepted with.
+*/
+
+// SeenCredentialRepository defines the interface for seen credential data operations.
+type SeenCredentialRepository interface {
+	// Find retrieves a credential by its hash. It returns gorm.ErrRecordNotFound when the
+	// credential was not seen or was forgotten.
+	Find(hash string) (*models.SeenCredential, error)
+
+	// Remember inserts the credential, keeping the existing one when it was already seen.
+	Remember(credential *models.SeenCredential) error
+
+	// DeleteExpired removes the credentials to forget before the given time and returns how
+	// many were removed.
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// seenCredentialRepository is a GORM-based implementation of SeenCredentialRepository.
+type seenCredentialRepository struct {
+	db *gorm.DB
+}
+
+// NewSeenCredentialRepository constructs a new SeenCredentialRepository backed by the provided GORM DB.
+func NewSeenCredentialRepository(db *gorm.DB) SeenCredentialRepository {
+	return &seenCredentialRepository{db: db}
+}
+
+// Find looks up an unforgotten credential by its hash.
+func (r *seenCredentialRepository) Find(hash string) (*models.SeenCredential, error) {
+	var credential models.SeenCredential
+	if err := r.db.Where("hash = ? AND forget_at > ?", hash, time.Now()).First(&credential).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// Remember inserts the credential, ignoring conflicts, so that the first acceptance of a
+// credential defines its validity window.
+func (r *seenCredentialRepository) Remember(credential *models.SeenCredential) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(credential).Error
+}
+
+// DeleteExpired removes the credentials to forget.
+func (r *seenCredentialRepository) DeleteExpired(before time.Time) (int64, error) {
+	result := r.db.Where("forget_at <= ?", before).Delete(&models.SeenCredential{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed SystemChangeRepository, which stores the changelog of
+the schema migrations, settings changes and template edits of the instance.
+*/
+
+// SystemChangeFilter narrows down the entries returned by FindRecent.
+// Zero-valued fields are ignored.
+type SystemChangeFilter struct {
+	// Kind restricts the results to the changes of the given kind.
+	Kind models.ChangeKind
+	// Actor restricts the results to the changes made by an actor.
+	Actor string
+	// Since and Until restrict the results to the changes made in [Since, Until).
+	Since time.Time
+	Until time.Time
+	// Limit is the largest number of entries returned.
+	Limit int
+}
+
+// SystemChangeRepository defines the interface for changelog operations. Entries are only
+// ever added and read.
+type SystemChangeRepository interface {
+	// Create inserts an entry into the database.
+	Create(ctx context.Context, change *models.SystemChange) error
+
+	// FindRecent retrieves the most recent entries matching the filter, newest first.
+	FindRecent(ctx context.Context, filter SystemChangeFilter) ([]models.SystemChange, error)
+}
+
+// systemChangeRepository is a GORM-based implementation of SystemChangeRepository.
+type systemChangeRepository struct {
+	db *gorm.DB
+}
+
+// NewSystemChangeRepository constructs a new SystemChangeRepository backed by the provided GORM DB.
+func NewSystemChangeRepository(db *gorm.DB) SystemChangeRepository {
+	return &systemChangeRepository{db: db}
+}
+
+// Create inserts the entry into the database using GORM.
+func (r *systemChangeRepository) Create(ctx context.Context, change *models.SystemChange) error {
+	return translateError(r.db.WithContext(ctx).Create(change).Error)
+}
+
+// FindRecent returns the entries matching the filter, newest first.
+func (r *systemChangeRepository) FindRecent(ctx context.Context, filter SystemChangeFilter) ([]models.SystemChange, error) {
+	query := r.db.WithContext(ctx).Model(&models.SystemChange{})
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	var changes []models.SystemChange
+	if err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
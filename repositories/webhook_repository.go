@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"api-contact-form/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+This file provides the GORM-backed WebhookRepository, which stores the webhook
+subscriptions, the log of their deliveries and the outbox of the published events.
+*/
+
+// WebhookRepository defines the interface for webhook data operations.
+type WebhookRepository interface {
+	// CreateSubscription inserts a new subscription record into the database.
+	CreateSubscription(subscription *models.WebhookSubscription) error
+
+	// FindSubscriptions retrieves every subscription, oldest first.
+	FindSubscriptions() ([]models.WebhookSubscription, error)
+
+	// FindActiveSubscriptions retrieves the subscriptions that are not paused.
+	FindActiveSubscriptions() ([]models.WebhookSubscription, error)
+
+	// FindSubscriptionByID retrieves a subscription by primary key.
+	FindSubscriptionByID(id uint) (*models.WebhookSubscription, error)
+
+	// UpdateSubscription persists changes to an existing subscription.
+	UpdateSubscription(subscription *models.WebhookSubscription) error
+
+	// DeleteSubscription removes a subscription and its delivery log.
+	// It returns gorm.ErrRecordNotFound when no subscription has the ID.
+	DeleteSubscription(id uint) error
+
+	// CreateDelivery inserts a delivery attempt into the delivery log.
+	CreateDelivery(delivery *models.WebhookDelivery) error
+
+	// FindDeliveries retrieves the latest delivery attempts of a subscription, newest first.
+	FindDeliveries(subscriptionID uint, limit int) ([]models.WebhookDelivery, error)
+
+	// CreateOutboxEvent inserts a published event into the outbox.
+	CreateOutboxEvent(event *models.WebhookOutboxEvent) error
+
+	// CountOutboxEvents counts the events of the outbox that occurred within [from, to)
+	// and are of one of the given types; nil means all types.
+	CountOutboxEvents(from, to time.Time, events []models.WebhookEvent) (int64, error)
+
+	// FindOutboxEvents retrieves at most limit events of the outbox that occurred within
+	// [from, to), are of one of the given types and have an ID above afterID, in ID order.
+	FindOutboxEvents(from, to time.Time, events []models.WebhookEvent, afterID uint, limit int) ([]models.WebhookOutboxEvent, error)
+
+	// DeleteOutboxEventsBefore removes the events of the outbox that occurred before the
+	// given time, and returns how many were removed.
+	DeleteOutboxEventsBefore(before time.Time) (int64, error)
+}
+
+// webhookRepository is a GORM-based implementation of WebhookRepository.
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository constructs a new WebhookRepository backed by the provided GORM DB.
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// CreateSubscription inserts a new subscription into the database using GORM.
+func (r *webhookRepository) CreateSubscription(subscription *models.WebhookSubscription) error {
+	return translateError(r.db.Create(subscription).Error)
+}
+
+// FindSubscriptions returns every subscription, oldest first.
+func (r *webhookRepository) FindSubscriptions() ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := r.db.Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// FindActiveSubscriptions returns the subscriptions that are not paused.
+func (r *webhookRepository) FindActiveSubscriptions() ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := r.db.Where("active = ?", true).Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// FindSubscriptionByID looks up a subscription by primary key and returns it.
+func (r *webhookRepository) FindSubscriptionByID(id uint) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := r.db.First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// UpdateSubscription persists changes to an existing subscription record.
+func (r *webhookRepository) UpdateSubscription(subscription *models.WebhookSubscription) error {
+	return translateError(r.db.Save(subscription).Error)
+}
+
+// DeleteSubscription removes a subscription together with its delivery log in one transaction.
+func (r *webhookRepository) DeleteSubscription(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.WebhookSubscription{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error
+	})
+}
+
+// CreateDelivery inserts a delivery attempt into the database using GORM.
+func (r *webhookRepository) CreateDelivery(delivery *models.WebhookDelivery) error {
+	return translateError(r.db.Create(delivery).Error)
+}
+
+// FindDeliveries returns the latest delivery attempts of a subscription, newest first.
+func (r *webhookRepository) FindDeliveries(subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("subscription_id = ?", subscriptionID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// CreateOutboxEvent inserts a published event into the database using GORM.
+func (r *webhookRepository) CreateOutboxEvent(event *models.WebhookOutboxEvent) error {
+	return translateError(r.db.Create(event).Error)
+}
+
+// CountOutboxEvents counts the matching events of the outbox.
+func (r *webhookRepository) CountOutboxEvents(from, to time.Time, events []models.WebhookEvent) (int64, error) {
+	var count int64
+	err := r.outboxQuery(from, to, events).Count(&count).Error
+	return count, err
+}
+
+// FindOutboxEvents returns the next page of matching events of the outbox, in ID order.
+func (r *webhookRepository) FindOutboxEvents(from, to time.Time, events []models.WebhookEvent, afterID uint, limit int) ([]models.WebhookOutboxEvent, error) {
+	var outbox []models.WebhookOutboxEvent
+	err := r.outboxQuery(from, to, events).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&outbox).Error
+	if err != nil {
+		return nil, err
+	}
+	return outbox, nil
+}
+
+// DeleteOutboxEventsBefore removes the events of the outbox that occurred before the given time.
+func (r *webhookRepository) DeleteOutboxEventsBefore(before time.Time) (int64, error) {
+	result := r.db.Where("occurred_at < ?", before.UTC()).Delete(&models.WebhookOutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// outboxQuery selects the events of the outbox that occurred within [from, to) and are of
+// one of the given types; nil means all types.
+func (r *webhookRepository) outboxQuery(from, to time.Time, events []models.WebhookEvent) *gorm.DB {
+	query := r.db.Model(&models.WebhookOutboxEvent{}).Where("occurred_at >= ? AND occurred_at < ?", from.UTC(), to.UTC())
+	if events != nil {
+		query = query.Where("event IN ?", events)
+	}
+	return query
+}
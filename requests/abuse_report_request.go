@@ -0,0 +1,31 @@
+// Package requests defines the request payload structures for the API Contact Form application.
+//
+// This file contains the payloads of the abuse report endpoints.
+package requests
+
+import "api-contact-form/models"
+
+// AbuseReportRequest represents the payload for reporting the misuse of an embedded form.
+type AbuseReportRequest struct {
+	// PageURL is the http or https address of the page embedding the form.
+	// It is a required field with a maximum length of 2048 characters.
+	PageURL string `json:"page_url" binding:"required,max=2048"`
+
+	// Category is the kind of misuse: spam, phishing, impersonation, harassment or other.
+	// It is a required field.
+	Category models.AbuseCategory `json:"category" binding:"required"`
+
+	// Details describes the misuse.
+	// It is a required field with a maximum length of 2000 characters.
+	Details string `json:"details" binding:"required,max=2000"`
+
+	// Email is the optional address the reporter may be contacted at.
+	Email string `json:"email" binding:"omitempty,email,max=100"`
+}
+
+// AbuseReportStatusRequest represents the payload for closing or reopening an abuse report.
+type AbuseReportStatusRequest struct {
+	// Status is the new status: open, resolved or dismissed.
+	// It is a required field.
+	Status models.AbuseReportStatus `json:"status" binding:"required"`
+}
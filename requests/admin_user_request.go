@@ -0,0 +1,75 @@
+// Package requests defines the request payload structures for the API Contact Form application.
+//
+// This file contains the payloads of the admin user management and sign-in endpoints.
+package requests
+
+// InviteUserRequest represents the payload for inviting a new admin user.
+type InviteUserRequest struct {
+	// Email is the sign-in name of the user, where the setup link is sent.
+	// It is a required field and must be a valid email address.
+	Email string `json:"email" binding:"required,email,max=100"`
+
+	// Name is the display name of the user.
+	// It is a required field with a maximum length of 100 characters.
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// DisableUserRequest represents the payload for disabling or re-enabling an admin user.
+type DisableUserRequest struct {
+	// Disabled selects whether the user is disabled.
+	Disabled *bool `json:"disabled" binding:"required"`
+}
+
+// SetupPasswordRequest represents the payload for choosing a password with a setup link.
+type SetupPasswordRequest struct {
+	// Token is the one-time token of the setup link.
+	Token string `json:"token" binding:"required"`
+
+	// Password is the chosen password, at most 72 characters, also checked against the
+	// password policy.
+	Password string `json:"password" binding:"required,max=72"`
+}
+
+// LoginRequest represents the payload for signing in as an admin user.
+type LoginRequest struct {
+	// Email is the sign-in name of the user.
+	Email string `json:"email" binding:"required"`
+
+	// Password is the password of the user.
+	Password string `json:"password" binding:"required"`
+
+	// Code is the current code of the authenticator app, or a recovery code. It is
+	// required once the user enabled two-factor authentication.
+	Code string `json:"code"`
+}
+
+// TwoFactorRequest represents the payload for managing the two-factor authentication of
+// an admin user. As these endpoints precede signing in when two-factor authentication
+// is required, they take the credentials of the user rather than a token.
+type TwoFactorRequest struct {
+	// Email is the sign-in name of the user.
+	Email string `json:"email" binding:"required"`
+
+	// Password is the password of the user.
+	Password string `json:"password" binding:"required"`
+
+	// Code is the current code of the authenticator app, or a recovery code where
+	// accepted. It is not needed to start enrolling.
+	Code string `json:"code"`
+}
+
+// RefreshTokenRequest represents the payload for renewing the token of an admin user.
+type RefreshTokenRequest struct {
+	// RefreshToken is the refresh token returned with the previous token.
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// ChangePasswordRequest represents the payload for an admin user changing their password.
+type ChangePasswordRequest struct {
+	// CurrentPassword is the password being replaced.
+	CurrentPassword string `json:"current_password" binding:"required"`
+
+	// Password is the new password, at most 72 characters, also checked against the
+	// password policy.
+	Password string `json:"password" binding:"required,max=72"`
+}
@@ -0,0 +1,30 @@
+// Package requests defines the request payload structures for the API Contact Form application.
+//
+// This file contains the payloads of the API key management endpoints.
+package requests
+
+import (
+	"api-contact-form/models"
+	"time"
+)
+
+// APIKeyRequest represents the payload for issuing a new API key.
+type APIKeyRequest struct {
+	// Name describes who or what will use the key.
+	// It is a required field with a maximum length of 100 characters.
+	Name string `json:"name" binding:"required,max=100"`
+
+	// Role is the role granted by the key: public, viewer or admin.
+	// It is a required field.
+	Role models.Role `json:"role" binding:"required"`
+
+	// ExpiresAt is the optional time after which the key is no longer accepted.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// RotateAPIKeyRequest represents the optional payload for rotating an API key.
+type RotateAPIKeyRequest struct {
+	// GracePeriod is how long the old key stays valid after rotation, as a duration
+	// such as "24h". When empty, the old key is revoked immediately.
+	GracePeriod string `json:"grace_period"`
+}
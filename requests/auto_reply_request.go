@@ -0,0 +1,15 @@
+// Package requests defines the request payload structures for the API Contact Form application.
+//
+// This file contains the payload of the auto-reply template endpoint.
+package requests
+
+// AutoReplyTemplateRequest represents the payload for saving the auto-reply template of a language.
+type AutoReplyTemplateRequest struct {
+	// Subject is the text/template of the subject line, executed with the contact.
+	// It is a required field with a maximum length of 200 characters.
+	Subject string `json:"subject" binding:"required,max=200"`
+
+	// Body is the text/template of the plain-text body, executed with the contact.
+	// It is a required field with a maximum length of 10000 characters.
+	Body string `json:"body" binding:"required,max=10000"`
+}
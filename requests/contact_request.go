@@ -5,21 +5,143 @@
 
 package requests
 
+import (
+	"api-contact-form/ids"
+	"api-contact-form/models"
+	"encoding/json"
+	"mime/multipart"
+	"time"
+)
+
 // ContactRequest represents the payload for creating or updating a contact message.
+// Its fields are trimmed and validated by the service layer, which reports every
+// invalid field at once. The fields with a label are described by the form schema, from
+// which frontends render the form.
 type ContactRequest struct {
 	// Name is the full name of the person submitting the contact message.
 	// It is a required field with a maximum length of 100 characters.
-	Name string `json:"name" binding:"required,max=100"`
+	Name string `json:"name" validate:"required,max=100" label:"Full name"`
 
 	// Email is the email address of the person submitting the contact message.
 	// It is a required field with a maximum length of 100 characters and must follow a valid email format.
-	Email string `json:"email" binding:"required,email,max=100"`
+	Email string `json:"email" validate:"required,email,max=100" label:"Email address"`
 
 	// Phone is the phone number of the person submitting the contact message.
-	// It is a required field with a maximum length of 20 characters.
-	Phone string `json:"phone" binding:"required,max=20"`
+	// It is a required field in international E.164 format; spaces, dashes, dots and
+	// parentheses are stripped before validation.
+	Phone string `json:"phone" validate:"required,e164" label:"Phone number"`
 
 	// Message is the content of the contact message.
+	// It is a required field with a maximum length of 5000 characters.
+	Message string `json:"message" validate:"required,max=5000" label:"Message"`
+
+	// Consent is the value of the consent checkbox. It is required to be true when
+	// the instance is configured to make consent mandatory.
+	Consent *bool `json:"consent" label:"I agree to the privacy policy and terms"`
+
+	// ConsentVersion identifies the version of the consent text shown to the submitter.
+	// It has a maximum length of 50 characters.
+	ConsentVersion string `json:"consent_version" validate:"max=50"`
+
+	// Lang is the optional language code of the submission, such as "en" or "pt-BR", which
+	// selects the language of the auto-reply. It is detected from the message when omitted.
+	Lang string `json:"lang" validate:"omitempty,bcp47_language_tag,max=35"`
+
+	// Fingerprint is an optional client fingerprint/telemetry blob sent by the widget.
+	// Any JSON value is accepted up to 8 KB; only its hash is stored.
+	Fingerprint json.RawMessage `json:"fingerprint" validate:"max=8192"`
+
+	// Attachments are the files uploaded with a multipart/form-data submission. They are
+	// checked against the attachment policy by the service layer.
+	Attachments []*multipart.FileHeader `json:"-"`
+}
+
+// SubmissionMeta carries information about a submission that is not part of its payload,
+// such as the client address, collected by the handler and passed to the service layer.
+type SubmissionMeta struct {
+	// ClientIP is the IP address the submission was received from.
+	ClientIP string
+	// Channel is the ingestion path of the submission; web when empty.
+	Channel models.Channel
+	// ReceivedAt is the time the submission was received when it is stored later, such as
+	// after a database outage; the contact is created at that time instead of now.
+	ReceivedAt time.Time
+}
+
+// StatusRequest represents the payload for changing the status of a contact.
+type StatusRequest struct {
+	// Status is the new status: new, read, replied, archived or spam.
+	// It is a required field.
+	Status models.Status `json:"status" binding:"required"`
+	// Reviewed confirms that the contact was reviewed, which some transitions require,
+	// such as archiving a contact flagged as spam.
+	Reviewed bool `json:"reviewed"`
+}
+
+// LegalHoldRequest represents the payload for placing or lifting a legal hold on a contact.
+type LegalHoldRequest struct {
+	// LegalHold is the new hold state. It is a required field; a pointer is used so that
+	// an explicit false is not mistaken for a missing value.
+	LegalHold *bool `json:"legal_hold" binding:"required"`
+}
+
+// PinRequest represents the payload for pinning or unpinning a contact.
+type PinRequest struct {
+	// Pinned is the new pin state. It is a required field; a pointer is used so that an
+	// explicit false is not mistaken for a missing value.
+	Pinned *bool `json:"pinned" binding:"required"`
+}
+
+// SnoozeRequest represents the payload for snoozing a contact.
+type SnoozeRequest struct {
+	// Until is when the contact returns to the inbox, as an RFC 3339 time in the future.
+	// It is a required field.
+	Until time.Time `json:"until" binding:"required"`
+}
+
+// MergeRequest represents the payload for merging duplicate contacts into one contact.
+type MergeRequest struct {
+	// KeepID is the ID of the contact that is kept. It is a required field.
+	KeepID ids.ID `json:"keep_id" binding:"required"`
+	// IDs are the IDs of the duplicates merged into it, at most 100.
+	// It is a required field.
+	IDs []ids.ID `json:"ids" binding:"required,min=1,max=100"`
+	// Resolutions maps the fields with conflicting values among the contacts, name, email
+	// or phone, to the ID of the contact whose value is kept.
+	Resolutions map[string]ids.ID `json:"resolutions"`
+}
+
+// BulkStatusRequest represents the payload for changing the status of several contacts at once.
+type BulkStatusRequest struct {
+	// IDs are the IDs of the contacts to update, at most 100.
+	// It is a required field.
+	IDs []ids.ID `json:"ids" binding:"required,min=1,max=100"`
+	// Status is the new status: new, read, replied, archived or spam.
+	// It is a required field.
+	Status models.Status `json:"status" binding:"required"`
+	// Reviewed confirms that the contacts were reviewed, which some transitions require.
+	Reviewed bool `json:"reviewed"`
+}
+
+// ImportContactRequest represents a contact of a legacy data import. It has the fields
+// of a ContactRequest, and the time the contact was originally submitted.
+type ImportContactRequest struct {
+	ContactRequest
+
+	// CreatedAt is the original submission time; the time of the import when omitted.
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// ImportContactsRequest represents the payload for importing several contacts at once.
+type ImportContactsRequest struct {
+	// Contacts are the contacts to import, at most 1000.
+	// It is a required field.
+	Contacts []ImportContactRequest `json:"contacts" binding:"required,min=1,max=1000"`
+}
+
+// BulkDeleteRequest represents the payload for deleting several contacts at once.
+type BulkDeleteRequest struct {
+	// IDs are the IDs of the contacts to delete, at most 100.
 	// It is a required field.
-	Message string `json:"message" binding:"required"`
-}
\ No newline at end of file
+	IDs []ids.ID `json:"ids" binding:"required,min=1,max=100"`
+}
@@ -0,0 +1,45 @@
+// Package requests defines the request payload structures for the API Contact Form application.
+//
+// This file contains the payload of the export schedule endpoints.
+package requests
+
+import "api-contact-form/models"
+
+// ExportScheduleRequest represents the payload for creating or changing a scheduled export.
+type ExportScheduleRequest struct {
+	// Name describes the report, such as "Weekly replied contacts".
+	// It is a required field with a maximum length of 100 characters.
+	Name string `json:"name" binding:"required,max=100"`
+
+	// Cron is the cron expression of the runs, such as "0 8 * * 1" for every Monday at
+	// 8:00, or a shortcut such as "@weekly". It is a required field.
+	Cron string `json:"cron" binding:"required,max=100"`
+
+	// Format is the file format, csv (the default), xlsx or vcf.
+	Format string `json:"format"`
+
+	// Mode is the export mode, full (the default) or anonymized.
+	Mode string `json:"mode"`
+
+	// Status and Channel restrict the report to the contacts with the given status and channel.
+	Status  models.Status  `json:"status"`
+	Channel models.Channel `json:"channel"`
+
+	// Period restricts the report to the contacts submitted within the given duration
+	// before the run, such as "168h" for a week; empty exports every contact.
+	Period string `json:"period"`
+
+	// Destination is where the file is delivered: email, as an attachment, or s3, with a
+	// notification of its location emailed to the recipients. It is a required field.
+	Destination models.ExportDestination `json:"destination" binding:"required"`
+
+	// S3Prefix is the prefix of the keys of the files uploaded to S3, such as "reports/".
+	S3Prefix string `json:"s3_prefix" binding:"max=255"`
+
+	// Recipients are the email addresses the report or its notification is sent to, at
+	// most 20. They are required for the email destination.
+	Recipients []string `json:"recipients" binding:"max=20,dive,email"`
+
+	// Active pauses the schedule when false. It defaults to true.
+	Active *bool `json:"active"`
+}
@@ -0,0 +1,17 @@
+// Package requests defines the request payload structures for the API Contact Form application.
+//
+// This file contains the payload of the follow-up endpoint.
+package requests
+
+import "time"
+
+// FollowUpRequest represents the payload for planning the follow-up of a contact.
+type FollowUpRequest struct {
+	// DueAt is when to get back to the contact, as an RFC 3339 time.
+	// It is a required field.
+	DueAt time.Time `json:"due_at" binding:"required"`
+
+	// Note reminds of what to follow up on.
+	// It is an optional field with a maximum length of 500 characters.
+	Note string `json:"note" binding:"max=500"`
+}
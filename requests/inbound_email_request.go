@@ -0,0 +1,54 @@
+// Package requests defines the request payload structures for the API Contact Form application.
+//
+// It includes the InboundEmailRequest struct, which represents an email received through
+// an email provider's inbound parse webhook or fetched from an IMAP mailbox, and the
+// EmailEventRequest struct, which represents a delivery problem reported by the provider.
+
+package requests
+
+import (
+	"api-contact-form/models"
+	"mime/multipart"
+)
+
+// InboundEmailRequest represents an inbound email normalized from the provider-specific
+// form fields posted by SendGrid or Mailgun inbound parse webhooks,
+// or from a message fetched by the IMAP poller.
+type InboundEmailRequest struct {
+	// FromName is the display name of the sender, if the From header carried one.
+	FromName string
+
+	// FromEmail is the email address of the sender.
+	// It is a required field with a maximum length of 100 characters and must follow a valid email format.
+	FromEmail string `validate:"required,email,max=100"`
+
+	// Subject is the subject line of the email.
+	Subject string
+
+	// MessageID is the Message-ID header of the email, used to skip emails that were already ingested.
+	MessageID string
+
+	// InReplyTo is the Message-ID of the email this one answers, and References those of
+	// the earlier emails of the conversation, without their angle brackets. They match a
+	// reply to the contact of the conversation.
+	InReplyTo  string
+	References []string
+
+	// Body is the plain-text content of the email.
+	// It is a required field.
+	Body string `validate:"required"`
+
+	// Attachments are the files attached to the email. Those allowed by the attachment
+	// policy are stored with the contact.
+	Attachments []*multipart.FileHeader
+}
+
+// EmailEventRequest represents a bounce or spam complaint normalized from the SendGrid
+// event webhook or the Mailgun webhooks.
+type EmailEventRequest struct {
+	// Email is the address the event is about.
+	Email string
+
+	// Issue is the delivery problem the event reports.
+	Issue models.EmailIssue
+}
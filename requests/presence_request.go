@@ -0,0 +1,13 @@
+// Package requests defines the request payload structures for the API Contact Form application.
+//
+// This file contains the payload of the presence heartbeat endpoint.
+package requests
+
+import "api-contact-form/presence"
+
+// PresenceRequest represents the payload of a heartbeat of an agent on a contact.
+type PresenceRequest struct {
+	// Activity is what the agent is doing: viewing or replying.
+	// It is optional and defaults to viewing.
+	Activity presence.Activity `json:"activity"`
+}
@@ -0,0 +1,11 @@
+// Package requests defines the request payload structures for the API Contact Form application.
+//
+// This file contains the payload of the reply draft endpoint.
+package requests
+
+// ReplyDraftRequest represents the payload for saving the draft of a reply to a contact.
+type ReplyDraftRequest struct {
+	// Body is the text of the reply.
+	// It is a required field with a maximum length of 10000 characters.
+	Body string `json:"body" binding:"required,max=10000"`
+}
@@ -0,0 +1,19 @@
+// Package requests defines the request payload structures for the API Contact Form application.
+//
+// This file contains the payload of the webhook subscription endpoints.
+package requests
+
+import "api-contact-form/models"
+
+// WebhookRequest represents the payload for registering or changing a webhook subscription.
+type WebhookRequest struct {
+	// URL is the http or https endpoint the events are POSTed to.
+	// It is a required field with a maximum length of 2048 characters.
+	URL string `json:"url" binding:"required,max=2048"`
+
+	// Events lists the events delivered to the URL; empty means all events.
+	Events []models.WebhookEvent `json:"events"`
+
+	// Active pauses the subscription when false. It defaults to true.
+	Active *bool `json:"active"`
+}
@@ -0,0 +1,63 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the representation of the reports of misused embedded forms.
+package responses
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/ids"
+	"api-contact-form/models"
+)
+
+// AbuseReportResponse represents an abuse report in API responses.
+type AbuseReportResponse struct {
+	// ID is the unique identifier of the report.
+	ID ids.ID `json:"id"`
+	// PageURL is the address of the page embedding the reported form.
+	PageURL string `json:"page_url"`
+	// Category is the kind of misuse, and Details its description.
+	Category string `json:"category"`
+	Details  string `json:"details"`
+	// ReporterEmail is the address the reporter may be contacted at, if given.
+	ReporterEmail string `json:"reporter_email,omitempty"`
+	// ClientIP and UserAgent identify the client the report was received from.
+	ClientIP  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	// Status is the handling status: open, resolved or dismissed.
+	Status string `json:"status"`
+	// ResolvedBy is the actor who closed the report, such as "user:1".
+	ResolvedBy string `json:"resolved_by,omitempty"`
+	// ResolvedAt is the time the report was closed, formatted as a human-readable string.
+	ResolvedAt string `json:"resolved_at,omitempty"`
+	// CreatedAt is the time the report was received, formatted as a human-readable string.
+	CreatedAt string `json:"created_at"`
+}
+
+// AbuseReportResponseFromModel converts an AbuseReport model to an AbuseReportResponse.
+func AbuseReportResponseFromModel(report *models.AbuseReport) AbuseReportResponse {
+	response := AbuseReportResponse{
+		ID:            ids.ID(report.ID),
+		PageURL:       report.PageURL,
+		Category:      string(report.Category),
+		Details:       report.Details,
+		ReporterEmail: report.ReporterEmail,
+		ClientIP:      report.ClientIP,
+		UserAgent:     report.UserAgent,
+		Status:        string(report.Status),
+		ResolvedBy:    report.ResolvedBy,
+		CreatedAt:     helpers.FormatTimeHuman(report.CreatedAt),
+	}
+	if report.ResolvedAt != nil {
+		response.ResolvedAt = helpers.FormatTimeHuman(*report.ResolvedAt)
+	}
+	return response
+}
+
+// AbuseReportResponsesFromModels converts AbuseReport models to AbuseReportResponses.
+func AbuseReportResponsesFromModels(reports []models.AbuseReport) []AbuseReportResponse {
+	responses := make([]AbuseReportResponse, 0, len(reports))
+	for i := range reports {
+		responses = append(responses, AbuseReportResponseFromModel(&reports[i]))
+	}
+	return responses
+}
@@ -0,0 +1,129 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the AdminUserResponse returned by the admin user management endpoints,
+// and the responses of the two-factor authentication, session and sign-in audit endpoints.
+package responses
+
+import (
+	"api-contact-form/ids"
+	"api-contact-form/models"
+	"api-contact-form/services"
+	"time"
+)
+
+// AdminUserResponse represents an admin user, without their password or setup token.
+type AdminUserResponse struct {
+	ID           ids.ID     `json:"id"`
+	Email        string     `json:"email"`
+	Name         string     `json:"name"`
+	Disabled     bool       `json:"disabled"`
+	DisabledAt   *time.Time `json:"disabled_at"`
+	SetupPending bool       `json:"setup_pending"`
+	// TwoFactorEnabled reports whether the user signs in with a second factor.
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
+	// LockedUntil is the end of the lockout of the account after too many failed sign-in
+	// attempts, while it lasts.
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// SetupLink is the one-time setup link. It is only present in the responses that
+	// created it: the invitation and the password reset.
+	SetupLink *SetupLinkResponse `json:"setup_link,omitempty"`
+}
+
+// SetupLinkResponse represents a one-time setup link.
+type SetupLinkResponse struct {
+	Token string `json:"token"`
+	// URL is the setup page with the token, when a setup URL is configured.
+	URL string `json:"url,omitempty"`
+	// Sent reports whether the link was emailed to the user.
+	Sent bool `json:"sent"`
+}
+
+// AdminUserResponseFromModel converts an AdminUser model to an AdminUserResponse.
+func AdminUserResponseFromModel(user *models.AdminUser) AdminUserResponse {
+	response := AdminUserResponse{
+		ID:           ids.ID(user.ID),
+		Email:        user.Email,
+		Name:         user.Name,
+		Disabled:     user.DisabledAt != nil,
+		DisabledAt:   user.DisabledAt,
+		SetupPending: user.SetupPending(),
+		LastLoginAt:  user.LastLoginAt,
+		CreatedAt:    user.CreatedAt,
+
+		TwoFactorEnabled: user.TwoFactorEnabled(),
+	}
+	if user.Locked(time.Now()) {
+		response.LockedUntil = user.LockedUntil
+	}
+	return response
+}
+
+// SetupLinkResponseFromLink converts a services.SetupLink to a SetupLinkResponse.
+func SetupLinkResponseFromLink(link *services.SetupLink) *SetupLinkResponse {
+	return &SetupLinkResponse{Token: link.Token, URL: link.URL, Sent: link.Sent}
+}
+
+// TwoFactorEnrollmentResponse represents the secret of an authenticator app being set up.
+type TwoFactorEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	// URL is the otpauth:// URL of the secret, to be shown as a QR code.
+	URL string `json:"url"`
+}
+
+// RecoveryCodesResponse represents newly generated recovery codes. They are only shown once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// AdminSessionResponse represents a session of an admin user, without its refresh token.
+type AdminSessionResponse struct {
+	ID         ids.ID    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current reports whether this is the session of the request.
+	Current bool `json:"current"`
+}
+
+// AdminSessionResponseFromModel converts an AdminSession model to an AdminSessionResponse,
+// marking it as current when its ID is currentID.
+func AdminSessionResponseFromModel(session *models.AdminSession, currentID uint) AdminSessionResponse {
+	return AdminSessionResponse{
+		ID:         ids.ID(session.ID),
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+		ExpiresAt:  session.ExpiresAt,
+		Current:    session.ID == currentID,
+	}
+}
+
+// LoginEventResponse represents a sign-in attempt of the audit log.
+type LoginEventResponse struct {
+	ID ids.ID `json:"id"`
+	// UserID is the admin user signing in, or null for unknown email addresses.
+	UserID    *ids.ID             `json:"user_id"`
+	Email     string              `json:"email"`
+	Outcome   models.LoginOutcome `json:"outcome"`
+	ClientIP  string              `json:"client_ip"`
+	UserAgent string              `json:"user_agent"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// LoginEventResponseFromModel converts an AdminLoginEvent model to a LoginEventResponse.
+func LoginEventResponseFromModel(event *models.AdminLoginEvent) LoginEventResponse {
+	return LoginEventResponse{
+		ID:        ids.ID(event.ID),
+		UserID:    ids.IDOf(event.UserID),
+		Email:     event.Email,
+		Outcome:   event.Outcome,
+		ClientIP:  event.ClientIP,
+		UserAgent: event.UserAgent,
+		CreatedAt: event.CreatedAt,
+	}
+}
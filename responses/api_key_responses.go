@@ -0,0 +1,50 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the APIKeyResponse returned by the API key management endpoints
+// and the TokenResponse returned when a JWT is issued.
+package responses
+
+import (
+	"api-contact-form/ids"
+	"api-contact-form/models"
+	"time"
+)
+
+// APIKeyResponse represents an issued API key, without the key itself.
+type APIKeyResponse struct {
+	ID         ids.ID     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Role       string     `json:"role"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Key is the API key itself. It is only present in the response that issued it.
+	Key string `json:"key,omitempty"`
+}
+
+// APIKeyResponseFromModel converts an APIKey model to an APIKeyResponse.
+func APIKeyResponseFromModel(key *models.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         ids.ID(key.ID),
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Role:       string(key.Role),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// TokenResponse represents a JWT bearer token issued to an authenticated caller.
+type TokenResponse struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// RefreshToken renews the token of an admin user once it expires, and can only be
+	// used once. It is only present for admin users signing in with a password.
+	RefreshToken     string     `json:"refresh_token,omitempty"`
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"`
+}
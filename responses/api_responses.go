@@ -9,7 +9,10 @@ package responses
 
 import (
 	"api-contact-form/helpers"
+	"api-contact-form/ids"
 	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"fmt"
 )
 
 // APIResponse represents the standard structure for API responses.
@@ -25,7 +28,9 @@ type APIResponse struct {
 // ContactResponse represents the structure of a contact in API responses.
 type ContactResponse struct {
 	// ID is the unique identifier of the contact.
-	ID uint `json:"id"`
+	ID ids.ID `json:"id"`
+	// PublicID is the UUIDv7 shown to the submitter, when public IDs are generated.
+	PublicID string `json:"public_id,omitempty"`
 	// Name is the full name of the contact.
 	Name string `json:"name"`
 	// Email is the email address of the contact.
@@ -34,10 +39,98 @@ type ContactResponse struct {
 	Phone string `json:"phone"`
 	// Message is the message content provided by the contact.
 	Message string `json:"message"`
+	// Channel is the ingestion path the contact was submitted through.
+	Channel string `json:"channel"`
+	// FingerprintHash is the hash of the client fingerprint sent with the submission, if any.
+	FingerprintHash string `json:"fingerprint_hash,omitempty"`
+	// ConsentGiven reports whether the submitter ticked the consent checkbox.
+	ConsentGiven bool `json:"consent_given"`
+	// ConsentVersion is the version of the consent text the submitter agreed to.
+	ConsentVersion string `json:"consent_version,omitempty"`
+	// ConsentAt is the time consent was given, formatted as a human-readable string.
+	ConsentAt string `json:"consent_at,omitempty"`
+	// PrivacyPolicyVersion is the privacy policy version in force at submission time.
+	PrivacyPolicyVersion string `json:"privacy_policy_version,omitempty"`
+	// TermsVersion is the terms version in force at submission time.
+	TermsVersion string `json:"terms_version,omitempty"`
+	// Status is the handling status of the contact.
+	Status string `json:"status"`
+	// StatusChangedAt is the time the status last changed, formatted as a human-readable string.
+	StatusChangedAt string `json:"status_changed_at,omitempty"`
+	// LeadScore is the score given to the submission by the lead scoring rules.
+	LeadScore int `json:"lead_score"`
+	// CompanyDomain, CompanyName and CompanySize describe the company of the submitter, as
+	// found by the enrichment. They are only present for enriched contacts.
+	CompanyDomain string `json:"company_domain,omitempty"`
+	CompanyName   string `json:"company_name,omitempty"`
+	CompanySize   string `json:"company_size,omitempty"`
+	// Language is the language code of the submission, given by the submitter or detected.
+	Language string `json:"language,omitempty"`
+	// LegalHold reports whether the contact is protected from deletion and anonymization.
+	LegalHold bool `json:"legal_hold"`
+	// Pinned reports whether the contact is pinned to the top of the inbox.
+	Pinned bool `json:"pinned"`
+	// SnoozedUntil is the time the snooze of the contact ends, formatted as a human-readable
+	// string. It is only present for snoozed contacts.
+	SnoozedUntil string `json:"snoozed_until,omitempty"`
+	// MergedIntoID is the ID of the contact a deleted duplicate was merged into.
+	MergedIntoID *ids.ID `json:"merged_into_id,omitempty"`
+	// DuplicateOfID is the ID of the earlier contact a submission was flagged as repeating.
+	DuplicateOfID *ids.ID `json:"duplicate_of_id,omitempty"`
+	// AnonymizedAt is the time the personal data of the contact was anonymized, formatted
+	// as a human-readable string. It is only present for anonymized contacts.
+	AnonymizedAt string `json:"anonymized_at,omitempty"`
+	// AttachmentsPurgedAt is the last time the expired attachments of the contact were
+	// removed, formatted as a human-readable string. It is only present once they were.
+	AttachmentsPurgedAt string `json:"attachments_purged_at,omitempty"`
+	// EmailIssue is the delivery problem reported by the email provider for the address,
+	// "bounce" or "complaint". It is only present for undeliverable addresses.
+	EmailIssue string `json:"email_issue,omitempty"`
+	// EmailIssueAt is the time the email issue was reported, formatted as a human-readable string.
+	EmailIssueAt string `json:"email_issue_at,omitempty"`
 	// CreatedAt is the timestamp when the contact was created, formatted as a human-readable string.
 	CreatedAt string `json:"created_at"`
 	// UpdatedAt is the timestamp when the contact was last updated, formatted as a human-readable string.
 	UpdatedAt string `json:"updated_at"`
+	// DeletedAt is the timestamp when the contact was deleted, formatted as a human-readable string.
+	// It is only present for deleted contacts.
+	DeletedAt string `json:"deleted_at,omitempty"`
+	// Attachments are the files uploaded with the contact. They are only present in the
+	// response to the submission.
+	Attachments []AttachmentResponse `json:"attachments,omitempty"`
+}
+
+// AttachmentResponse represents the structure of an attachment in API responses.
+type AttachmentResponse struct {
+	// ID is the unique identifier of the attachment.
+	ID ids.ID `json:"id"`
+	// Filename is the name of the uploaded file.
+	Filename string `json:"filename"`
+	// ContentType is the media type detected from the file content.
+	ContentType string `json:"content_type"`
+	// Size is the size of the file in bytes.
+	Size int64 `json:"size"`
+	// ThumbnailURL is the path of the preview of an image, relative to the API, once it was
+	// generated.
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	// CreatedAt is the timestamp when the file was uploaded, formatted as a human-readable string.
+	CreatedAt string `json:"created_at"`
+}
+
+// AttachmentResponseFromModel converts an Attachment model to an AttachmentResponse.
+func AttachmentResponseFromModel(attachment *models.Attachment) AttachmentResponse {
+	var thumbnailURL string
+	if attachment.ThumbnailKey != "" {
+		thumbnailURL = fmt.Sprintf("/contacts/%d/attachments/%d/thumbnail", attachment.ContactID, attachment.ID)
+	}
+	return AttachmentResponse{
+		ID:           ids.ID(attachment.ID),
+		Filename:     attachment.Filename,
+		ContentType:  attachment.ContentType,
+		Size:         attachment.Size,
+		ThumbnailURL: thumbnailURL,
+		CreatedAt:    helpers.FormatTimeHuman(attachment.CreatedAt),
+	}
 }
 
 // ContactResponseFromModel converts a Contact model to a ContactResponse.
@@ -48,13 +141,162 @@ type ContactResponse struct {
 // Returns:
 //   - A ContactResponse struct populated with data from the Contact model.
 func ContactResponseFromModel(contact *models.Contact) ContactResponse {
+	var consentAt string
+	if contact.ConsentAt != nil {
+		consentAt = helpers.FormatTimeHuman(*contact.ConsentAt)
+	}
+	var statusChangedAt string
+	if contact.StatusChangedAt != nil {
+		statusChangedAt = helpers.FormatTimeHuman(*contact.StatusChangedAt)
+	}
+	var snoozedUntil string
+	if contact.SnoozedUntil != nil {
+		snoozedUntil = helpers.FormatTimeHuman(*contact.SnoozedUntil)
+	}
+	var anonymizedAt string
+	if contact.AnonymizedAt != nil {
+		anonymizedAt = helpers.FormatTimeHuman(*contact.AnonymizedAt)
+	}
+	var attachmentsPurgedAt string
+	if contact.AttachmentsPurgedAt != nil {
+		attachmentsPurgedAt = helpers.FormatTimeHuman(*contact.AttachmentsPurgedAt)
+	}
+	var emailIssueAt string
+	if contact.EmailIssueAt != nil {
+		emailIssueAt = helpers.FormatTimeHuman(*contact.EmailIssueAt)
+	}
+	var deletedAt string
+	if contact.DeletedAt.Valid {
+		deletedAt = helpers.FormatTimeHuman(contact.DeletedAt.Time)
+	}
+	var publicID string
+	if contact.PublicID != nil {
+		publicID = *contact.PublicID
+	}
+	var attachments []AttachmentResponse
+	for i := range contact.Attachments {
+		attachments = append(attachments, AttachmentResponseFromModel(&contact.Attachments[i]))
+	}
+
 	return ContactResponse{
-		ID:        contact.ID,
-		Name:      contact.FullName,
-		Email:     contact.Email,
-		Phone:     contact.Phone,
-		Message:   contact.Message,
-		CreatedAt: helpers.FormatTimeHuman(contact.CreatedAt),
-		UpdatedAt: helpers.FormatTimeHuman(contact.UpdatedAt),
-	}
-}
\ No newline at end of file
+		ID:              ids.ID(contact.ID),
+		PublicID:        publicID,
+		Name:            contact.FullName,
+		Email:           contact.Email,
+		Phone:           contact.Phone,
+		Message:         contact.Message,
+		Channel:         string(contact.Channel),
+		FingerprintHash: contact.FingerprintHash,
+		ConsentGiven:    contact.ConsentGiven,
+		ConsentVersion:  contact.ConsentVersion,
+		ConsentAt:       consentAt,
+		Status:          string(contact.Status),
+		StatusChangedAt: statusChangedAt,
+		LeadScore:       contact.LeadScore,
+		CompanyDomain:   contact.CompanyDomain,
+		CompanyName:     contact.CompanyName,
+		CompanySize:     contact.CompanySize,
+		Language:        contact.Language,
+		LegalHold:       contact.LegalHold,
+		Pinned:          contact.Pinned,
+		SnoozedUntil:    snoozedUntil,
+		MergedIntoID:    ids.IDOf(contact.MergedIntoID),
+		DuplicateOfID:   ids.IDOf(contact.DuplicateOfID),
+		AnonymizedAt:    anonymizedAt,
+		EmailIssue:      string(contact.EmailIssue),
+		EmailIssueAt:    emailIssueAt,
+		CreatedAt:       helpers.FormatTimeHuman(contact.CreatedAt),
+		UpdatedAt:       helpers.FormatTimeHuman(contact.UpdatedAt),
+		DeletedAt:       deletedAt,
+		Attachments:     attachments,
+
+		PrivacyPolicyVersion: contact.PrivacyPolicyVersion,
+		TermsVersion:         contact.TermsVersion,
+		AttachmentsPurgedAt:  attachmentsPurgedAt,
+	}
+}
+
+// ContactPageResponse represents a page of contacts in API responses.
+type ContactPageResponse struct {
+	// Contacts are the contacts of the page.
+	Contacts []ContactResponse `json:"contacts"`
+	// Total is the number of contacts matching the filters, across all pages.
+	Total int64 `json:"total"`
+	// NextCursor requests the following page; it is omitted on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ContactPageResponseFromPage converts a ContactPage to a ContactPageResponse.
+func ContactPageResponseFromPage(page *repositories.ContactPage) ContactPageResponse {
+	contacts := make([]ContactResponse, 0, len(page.Contacts))
+	for i := range page.Contacts {
+		contacts = append(contacts, ContactResponseFromModel(&page.Contacts[i]))
+	}
+
+	return ContactPageResponse{
+		Contacts:   contacts,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+	}
+}
+
+// SearchResultResponse represents a contact matching a full-text search in API responses.
+type SearchResultResponse struct {
+	ContactResponse
+	// Rank is the relevance of the message to the query; higher is more relevant.
+	Rank float64 `json:"rank"`
+}
+
+// SearchPageResponse represents a page of full-text search results in API responses.
+type SearchPageResponse struct {
+	// Results are the results of the page, most relevant first.
+	Results []SearchResultResponse `json:"results"`
+	// Total is the number of contacts matching the search, across all pages.
+	Total int64 `json:"total"`
+	// NextOffset requests the following page; it is omitted on the last page.
+	NextOffset int `json:"next_offset,omitempty"`
+}
+
+// SearchPageResponseFromPage converts a SearchPage starting at offset to a SearchPageResponse.
+func SearchPageResponseFromPage(page *repositories.SearchPage, offset int) SearchPageResponse {
+	results := make([]SearchResultResponse, 0, len(page.Results))
+	for i := range page.Results {
+		results = append(results, SearchResultResponse{
+			ContactResponse: ContactResponseFromModel(&page.Results[i].Contact),
+			Rank:            page.Results[i].Rank,
+		})
+	}
+
+	response := SearchPageResponse{Results: results, Total: page.Total}
+	if page.More {
+		response.NextOffset = offset + len(results)
+	}
+	return response
+}
+
+// DuplicateGroupResponse represents a group of likely duplicate contacts in API responses.
+type DuplicateGroupResponse struct {
+	// Email is the email address the contacts were submitted with.
+	Email string `json:"email"`
+	// Similarity is the highest similarity between two messages of the group, between 0 and 1.
+	Similarity float64 `json:"similarity"`
+	// Contacts are the contacts of the group, oldest first.
+	Contacts []ContactResponse `json:"contacts"`
+}
+
+// DuplicateGroupResponsesFromGroups converts DuplicateGroups to DuplicateGroupResponses.
+func DuplicateGroupResponsesFromGroups(groups []repositories.DuplicateGroup) []DuplicateGroupResponse {
+	result := make([]DuplicateGroupResponse, 0, len(groups))
+	for _, group := range groups {
+		contacts := make([]ContactResponse, 0, len(group.Contacts))
+		for i := range group.Contacts {
+			contacts = append(contacts, ContactResponseFromModel(&group.Contacts[i]))
+		}
+		result = append(result, DuplicateGroupResponse{
+			Email:      group.Email,
+			Similarity: group.Similarity,
+			Contacts:   contacts,
+		})
+	}
+	return result
+}
@@ -0,0 +1,29 @@
+package responses
+
+import (
+	"api-contact-form/internal/benchdata"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func BenchmarkContactListJSON(b *testing.B) {
+	contacts := benchdata.Contacts(100)
+	for i := range contacts {
+		contacts[i].ID = uint(i + 1)
+		contacts[i].CreatedAt = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		contacts[i].UpdatedAt = contacts[i].CreatedAt
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data := make([]ContactResponse, 0, len(contacts))
+		for j := range contacts {
+			data = append(data, ContactResponseFromModel(&contacts[j]))
+		}
+		if _, err := json.Marshal(APIResponse{Code: "SUCCESS", Data: data}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the APIUsageResponse returned by the API usage statistics endpoint.
+package responses
+
+import (
+	"api-contact-form/repositories"
+	"time"
+)
+
+// APIUsageResponse represents the usage of the API per caller and endpoint over a period.
+type APIUsageResponse struct {
+	// From and To bound the period, rounded to the hours of the rollups.
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Endpoints lists the usage per caller and endpoint, busiest first.
+	Endpoints []EndpointUsageResponse `json:"endpoints"`
+}
+
+// EndpointUsageResponse represents the usage of an endpoint by a caller.
+type EndpointUsageResponse struct {
+	Caller       string `json:"caller"`
+	Method       string `json:"method"`
+	Route        string `json:"route"`
+	Requests     int64  `json:"requests"`
+	ClientErrors int64  `json:"client_errors"`
+	ServerErrors int64  `json:"server_errors"`
+	// ErrorRate is the share of the requests answered with a 4xx or 5xx status code,
+	// between 0 and 1.
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// APIUsageResponseFromSummaries converts the usage summaries of a period to an APIUsageResponse.
+func APIUsageResponseFromSummaries(from, to time.Time, summaries []repositories.APIUsageSummary) APIUsageResponse {
+	response := APIUsageResponse{From: from, To: to, Endpoints: make([]EndpointUsageResponse, 0, len(summaries))}
+	for _, summary := range summaries {
+		endpoint := EndpointUsageResponse{
+			Caller:       summary.Caller,
+			Method:       summary.Method,
+			Route:        summary.Route,
+			Requests:     summary.Requests,
+			ClientErrors: summary.ClientErrors,
+			ServerErrors: summary.ServerErrors,
+		}
+		if summary.Requests > 0 {
+			endpoint.ErrorRate = float64(summary.ClientErrors+summary.ServerErrors) / float64(summary.Requests)
+		}
+		response.Endpoints = append(response.Endpoints, endpoint)
+	}
+	return response
+}
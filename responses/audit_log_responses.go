@@ -0,0 +1,38 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the AuditLogResponse returned by the audit trail endpoint.
+package responses
+
+import (
+	"api-contact-form/ids"
+	"api-contact-form/models"
+	"encoding/json"
+	"time"
+)
+
+// AuditLogResponse represents a change of a contact in the audit trail.
+type AuditLogResponse struct {
+	ID        ids.ID             `json:"id"`
+	Actor     string             `json:"actor"`
+	Action    models.AuditAction `json:"action"`
+	ContactID ids.ID             `json:"contact_id"`
+	// Changes maps the changed fields to their values before and after the change.
+	Changes   json.RawMessage `json:"changes"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditLogResponseFromModel converts an AuditLog model to an AuditLogResponse.
+func AuditLogResponseFromModel(entry *models.AuditLog) AuditLogResponse {
+	changes := json.RawMessage(entry.Changes)
+	if !json.Valid(changes) {
+		changes = json.RawMessage("{}")
+	}
+	return AuditLogResponse{
+		ID:        ids.ID(entry.ID),
+		Actor:     entry.Actor,
+		Action:    entry.Action,
+		ContactID: ids.ID(entry.ContactID),
+		Changes:   changes,
+		CreatedAt: entry.CreatedAt,
+	}
+}
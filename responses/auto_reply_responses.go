@@ -0,0 +1,33 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the representation of the auto-reply templates.
+package responses
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/models"
+)
+
+// AutoReplyTemplateResponse represents the auto-reply template of a language in API responses.
+type AutoReplyTemplateResponse struct {
+	// Language is the lowercase language code of the template.
+	Language string `json:"language"`
+	// Subject and Body are the text/template of the subject line and of the body.
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	// CreatedAt is the timestamp when the template was created, formatted as a human-readable string.
+	CreatedAt string `json:"created_at"`
+	// UpdatedAt is the timestamp when the template was last saved, formatted as a human-readable string.
+	UpdatedAt string `json:"updated_at"`
+}
+
+// AutoReplyTemplateResponseFromModel converts an AutoReplyTemplate model to an AutoReplyTemplateResponse.
+func AutoReplyTemplateResponseFromModel(template *models.AutoReplyTemplate) AutoReplyTemplateResponse {
+	return AutoReplyTemplateResponse{
+		Language:  template.Language,
+		Subject:   template.Subject,
+		Body:      template.Body,
+		CreatedAt: helpers.FormatTimeHuman(template.CreatedAt),
+		UpdatedAt: helpers.FormatTimeHuman(template.UpdatedAt),
+	}
+}
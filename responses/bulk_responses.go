@@ -0,0 +1,93 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the responses of the bulk endpoints, which report the contacts that
+// were processed and those that were skipped, and the conflicts of merges.
+package responses
+
+import "api-contact-form/ids"
+
+import "api-contact-form/services"
+
+// BulkResultResponse represents the outcome of a bulk operation on existing contacts.
+type BulkResultResponse struct {
+	// Succeeded lists the IDs of the contacts that were processed.
+	Succeeded []ids.ID `json:"succeeded"`
+	// Failed lists the contacts that were skipped, with the reason.
+	Failed []BulkFailureResponse `json:"failed"`
+}
+
+// BulkFailureResponse represents a contact skipped by a bulk operation.
+type BulkFailureResponse struct {
+	ID     ids.ID `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// ImportResultResponse represents the outcome of an import.
+type ImportResultResponse struct {
+	// Created lists the IDs of the imported contacts, in the order of the request.
+	Created []ids.ID `json:"created"`
+	// Failed lists the rejected contacts by their position in the request.
+	Failed []ImportFailureResponse `json:"failed"`
+}
+
+// ImportFailureResponse represents a contact rejected by an import.
+type ImportFailureResponse struct {
+	Index  int                   `json:"index"`
+	Reason string                `json:"reason"`
+	Fields []services.FieldError `json:"fields,omitempty"`
+}
+
+// BulkResultResponseFromResult converts a services.BulkResult to a BulkResultResponse.
+func BulkResultResponseFromResult(result *services.BulkResult) BulkResultResponse {
+	response := BulkResultResponse{
+		Succeeded: ids.IDsOf(result.Succeeded),
+		Failed:    make([]BulkFailureResponse, 0, len(result.Failed)),
+	}
+	for _, failure := range result.Failed {
+		response.Failed = append(response.Failed, BulkFailureResponse{ID: ids.ID(failure.ID), Reason: failure.Reason})
+	}
+	return response
+}
+
+// ImportResultResponseFromResult converts a services.ImportResult to an ImportResultResponse.
+func ImportResultResponseFromResult(result *services.ImportResult) ImportResultResponse {
+	response := ImportResultResponse{
+		Created: ids.IDsOf(result.Created),
+		Failed:  make([]ImportFailureResponse, 0, len(result.Failed)),
+	}
+	for _, failure := range result.Failed {
+		response.Failed = append(response.Failed, ImportFailureResponse{
+			Index:  failure.Index,
+			Reason: failure.Reason,
+			Fields: failure.Fields,
+		})
+	}
+	return response
+}
+
+// MergeConflictResponse represents a field with different values among merged contacts.
+type MergeConflictResponse struct {
+	// Field is the name of the field: name, email or phone.
+	Field string `json:"field"`
+	// Values lists the distinct values with the first contact having each, kept contact first.
+	Values []MergeValueResponse `json:"values"`
+}
+
+// MergeValueResponse represents the value of a field of one of the merged contacts.
+type MergeValueResponse struct {
+	ContactID ids.ID `json:"contact_id"`
+	Value     string `json:"value"`
+}
+
+// MergeConflictResponsesFromConflicts converts services.MergeConflicts to MergeConflictResponses.
+func MergeConflictResponsesFromConflicts(conflicts []services.MergeConflict) []MergeConflictResponse {
+	result := make([]MergeConflictResponse, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		values := make([]MergeValueResponse, 0, len(conflict.Values))
+		for _, value := range conflict.Values {
+			values = append(values, MergeValueResponse{ContactID: ids.ID(value.ContactID), Value: value.Value})
+		}
+		result = append(result, MergeConflictResponse{Field: conflict.Field, Values: values})
+	}
+	return result
+}
@@ -0,0 +1,79 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the representations of a contact shown to its submitter when public
+// references are enabled: the receipt of the submission and the status lookup.
+package responses
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/ids"
+	"api-contact-form/models"
+)
+
+// The statuses shown to submitters by the status lookup.
+const (
+	PublicStatusReceived = "received"
+	PublicStatusInReview = "in_review"
+	PublicStatusAnswered = "answered"
+	PublicStatusClosed   = "closed"
+)
+
+// publicStatuses maps the statuses of contacts to the ones shown to submitters. Contacts
+// flagged as spam are shown as received, so that spammers are not told.
+var publicStatuses = map[models.Status]string{
+	models.StatusNew:      PublicStatusReceived,
+	models.StatusRead:     PublicStatusInReview,
+	models.StatusReplied:  PublicStatusAnswered,
+	models.StatusArchived: PublicStatusClosed,
+	models.StatusSpam:     PublicStatusReceived,
+}
+
+// ContactReceiptResponse represents a contact in the response to its submission when
+// public references are enabled: the numeric ID is replaced with the reference, and the
+// ID of the earlier contact a duplicate repeats is left out.
+type ContactReceiptResponse struct {
+	ContactResponse
+	// ID is the public reference of the contact.
+	ID string `json:"id"`
+	// DuplicateOfID is always nil, hiding the field of ContactResponse.
+	DuplicateOfID *ids.ID `json:"duplicate_of_id,omitempty"`
+	// PublicID is always empty, hiding the field of ContactResponse, which repeats ID.
+	PublicID string `json:"public_id,omitempty"`
+}
+
+// ContactReceiptResponseFromModel converts a Contact model to a ContactReceiptResponse
+// identified by reference.
+func ContactReceiptResponseFromModel(contact *models.Contact, reference string) ContactReceiptResponse {
+	return ContactReceiptResponse{ContactResponse: ContactResponseFromModel(contact), ID: reference}
+}
+
+// ContactStatusResponse represents the progress of a contact, as shown to its submitter.
+type ContactStatusResponse struct {
+	// Reference is the public reference of the contact.
+	Reference string `json:"reference"`
+	// Status is received, in_review, answered or closed.
+	Status string `json:"status"`
+	// SubmittedAt is the time the contact was submitted, formatted as a human-readable string.
+	SubmittedAt string `json:"submitted_at"`
+	// StatusChangedAt is the time the status last changed, formatted as a human-readable
+	// string. It is omitted until the status changes.
+	StatusChangedAt string `json:"status_changed_at,omitempty"`
+}
+
+// ContactStatusResponseFromModel converts a Contact model to a ContactStatusResponse
+// identified by reference.
+func ContactStatusResponseFromModel(contact *models.Contact, reference string) ContactStatusResponse {
+	response := ContactStatusResponse{
+		Reference:   reference,
+		Status:      publicStatuses[contact.Status],
+		SubmittedAt: helpers.FormatTimeHuman(contact.CreatedAt),
+	}
+	if response.Status == "" {
+		response.Status = PublicStatusReceived
+	}
+	// Spam is flagged through a status change, which would tell spammers as well.
+	if contact.StatusChangedAt != nil && contact.Status != models.StatusSpam {
+		response.StatusChangedAt = helpers.FormatTimeHuman(*contact.StatusChangedAt)
+	}
+	return response
+}
@@ -0,0 +1,72 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the responses of the destructive admin operations run with
+// dry_run=true, which report the contacts that would be affected without changing them,
+// and of the data retention policy.
+package responses
+
+import "api-contact-form/ids"
+
+import "api-contact-form/services"
+
+// maxSampleIDs is the largest number of IDs listed by an AffectedContacts.
+const maxSampleIDs = 10
+
+// AffectedContacts summarizes the contacts affected by an operation.
+type AffectedContacts struct {
+	// Count is the number of contacts.
+	Count int `json:"count"`
+	// SampleIDs lists the IDs of the first contacts, at most 10.
+	SampleIDs []ids.ID `json:"sample_ids"`
+}
+
+// DryRunResponse represents the contacts an operation run with dry_run=true would affect.
+type DryRunResponse struct {
+	// DryRun is always true, so that the response is not mistaken for a real run.
+	DryRun bool `json:"dry_run"`
+	// Affected summarizes the contacts the operation would change.
+	Affected AffectedContacts `json:"affected"`
+	// Skipped lists the contacts the operation would skip, with the reason.
+	Skipped []BulkFailureResponse `json:"skipped"`
+}
+
+// RetentionReportResponse represents the contacts affected by a run of the data retention policy.
+type RetentionReportResponse struct {
+	// DryRun reports whether the contacts were only listed, without being changed.
+	DryRun bool `json:"dry_run"`
+	// Purged summarizes the deleted contacts permanently removed.
+	Purged AffectedContacts `json:"purged"`
+	// Anonymized summarizes the resolved contacts whose personal data was anonymized.
+	Anonymized AffectedContacts `json:"anonymized"`
+	// AttachmentsPurged summarizes the contacts whose expired attachments were removed.
+	AttachmentsPurged AffectedContacts `json:"attachments_purged"`
+}
+
+// AffectedContactsFromIDs summarizes the contacts with the given IDs.
+func AffectedContactsFromIDs(contactIDs []uint) AffectedContacts {
+	sample := contactIDs[:min(len(contactIDs), maxSampleIDs)]
+	return AffectedContacts{Count: len(contactIDs), SampleIDs: ids.IDsOf(sample)}
+}
+
+// DryRunResponseFromResult converts the services.BulkResult of a dry run to a DryRunResponse.
+func DryRunResponseFromResult(result *services.BulkResult) DryRunResponse {
+	response := DryRunResponse{
+		DryRun:   true,
+		Affected: AffectedContactsFromIDs(result.Succeeded),
+		Skipped:  make([]BulkFailureResponse, 0, len(result.Failed)),
+	}
+	for _, failure := range result.Failed {
+		response.Skipped = append(response.Skipped, BulkFailureResponse{ID: ids.ID(failure.ID), Reason: failure.Reason})
+	}
+	return response
+}
+
+// RetentionReportResponseFromReport converts a services.RetentionReport to a RetentionReportResponse.
+func RetentionReportResponseFromReport(report *services.RetentionReport) RetentionReportResponse {
+	return RetentionReportResponse{
+		DryRun:            report.DryRun,
+		Purged:            AffectedContactsFromIDs(report.Purged),
+		Anonymized:        AffectedContactsFromIDs(report.Anonymized),
+		AttachmentsPurged: AffectedContactsFromIDs(report.AttachmentsPurged),
+	}
+}
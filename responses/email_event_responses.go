@@ -0,0 +1,14 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the response to the delivery events posted by the email provider.
+package responses
+
+import "api-contact-form/ids"
+
+// EmailEventsResponse represents the outcome of a batch of email delivery events.
+type EmailEventsResponse struct {
+	// Events is the number of bounces and complaints in the batch. Other events are ignored.
+	Events int `json:"events"`
+	// Marked lists the IDs of the contacts newly marked as undeliverable.
+	Marked []ids.ID `json:"marked"`
+}
@@ -0,0 +1,46 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the representation of the emails of the conversation of a contact.
+package responses
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/ids"
+	"api-contact-form/models"
+)
+
+// EmailMessageResponse represents an email of the conversation of a contact in API responses.
+type EmailMessageResponse struct {
+	ID ids.ID `json:"id"`
+	// Direction is "outbound" for the emails sent to the submitter, and "inbound" for their replies.
+	Direction models.EmailDirection `json:"direction"`
+	// MessageID and InReplyTo are the Message-IDs threading the email, without angle brackets.
+	MessageID string `json:"message_id"`
+	InReplyTo string `json:"in_reply_to"`
+	FromEmail string `json:"from_email"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+	// CreatedAt is the timestamp when the email was sent or received, formatted as a human-readable string.
+	CreatedAt string `json:"created_at"`
+}
+
+// EmailMessageResponsesFromModels converts EmailMessage models to EmailMessageResponses.
+func EmailMessageResponsesFromModels(messages []models.EmailMessage) []EmailMessageResponse {
+	result := make([]EmailMessageResponse, 0, len(messages))
+	for _, message := range messages {
+		response := EmailMessageResponse{
+			ID:        ids.ID(message.ID),
+			Direction: message.Direction,
+			InReplyTo: message.InReplyTo,
+			FromEmail: message.FromEmail,
+			Subject:   message.Subject,
+			Body:      message.Body,
+			CreatedAt: helpers.FormatTimeHuman(message.CreatedAt),
+		}
+		if message.MessageID != nil {
+			response.MessageID = *message.MessageID
+		}
+		result = append(result, response)
+	}
+	return result
+}
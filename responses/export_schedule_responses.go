@@ -0,0 +1,65 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the representation of the scheduled exports.
+package responses
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/ids"
+	"api-contact-form/models"
+)
+
+// ExportScheduleResponse represents a scheduled export in API responses.
+type ExportScheduleResponse struct {
+	ID          ids.ID   `json:"id"`
+	Name        string   `json:"name"`
+	Cron        string   `json:"cron"`
+	Format      string   `json:"format"`
+	Mode        string   `json:"mode"`
+	Status      string   `json:"status,omitempty"`
+	Channel     string   `json:"channel,omitempty"`
+	Period      string   `json:"period,omitempty"`
+	Destination string   `json:"destination"`
+	S3Prefix    string   `json:"s3_prefix,omitempty"`
+	Recipients  []string `json:"recipients"`
+	Active      bool     `json:"active"`
+	// LastRunAt is the time of the last run, formatted as a human-readable string, and
+	// LastRows the number of contacts it exported.
+	LastRunAt string `json:"last_run_at,omitempty"`
+	LastRows  int    `json:"last_rows"`
+	// LastError is the reason the last run failed, empty when it succeeded.
+	LastError string `json:"last_error,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ExportScheduleResponseFromModel converts an ExportSchedule model to an ExportScheduleResponse.
+func ExportScheduleResponseFromModel(schedule *models.ExportSchedule) ExportScheduleResponse {
+	response := ExportScheduleResponse{
+		ID:          ids.ID(schedule.ID),
+		Name:        schedule.Name,
+		Cron:        schedule.Cron,
+		Format:      schedule.Format,
+		Mode:        schedule.Mode,
+		Status:      string(schedule.Status),
+		Channel:     string(schedule.Channel),
+		Destination: string(schedule.Destination),
+		S3Prefix:    schedule.S3Prefix,
+		Recipients:  schedule.RecipientList(),
+		Active:      schedule.Active,
+		LastRows:    schedule.LastRows,
+		LastError:   schedule.LastError,
+		CreatedAt:   helpers.FormatTimeHuman(schedule.CreatedAt),
+		UpdatedAt:   helpers.FormatTimeHuman(schedule.UpdatedAt),
+	}
+	if schedule.Period > 0 {
+		response.Period = schedule.Period.String()
+	}
+	if response.Recipients == nil {
+		response.Recipients = []string{}
+	}
+	if schedule.LastRunAt != nil {
+		response.LastRunAt = helpers.FormatTimeHuman(*schedule.LastRunAt)
+	}
+	return response
+}
@@ -0,0 +1,58 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the representation of the follow-ups of contacts and of their
+// calendar feed.
+package responses
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/ids"
+	"api-contact-form/models"
+	"time"
+)
+
+// FollowUpResponse represents a follow-up in API responses.
+type FollowUpResponse struct {
+	// ContactID is the contact to follow up with, and ContactName their name, when loaded.
+	ContactID   ids.ID `json:"contact_id"`
+	ContactName string `json:"contact_name,omitempty"`
+	// DueAt is when to get back to the contact.
+	DueAt time.Time `json:"due_at"`
+	// Note reminds of what to follow up on.
+	Note string `json:"note"`
+	// CreatedAt is the timestamp when the follow-up was planned, formatted as a human-readable string.
+	CreatedAt string `json:"created_at"`
+	// UpdatedAt is the timestamp when the follow-up was last changed, formatted as a human-readable string.
+	UpdatedAt string `json:"updated_at"`
+}
+
+// FollowUpResponseFromModel converts a FollowUp model to a FollowUpResponse.
+func FollowUpResponseFromModel(followUp *models.FollowUp) FollowUpResponse {
+	response := FollowUpResponse{
+		ContactID: ids.ID(followUp.ContactID),
+		DueAt:     followUp.DueAt,
+		Note:      followUp.Note,
+		CreatedAt: helpers.FormatTimeHuman(followUp.CreatedAt),
+		UpdatedAt: helpers.FormatTimeHuman(followUp.UpdatedAt),
+	}
+	if followUp.Contact != nil {
+		response.ContactName = followUp.Contact.FullName
+	}
+	return response
+}
+
+// FollowUpResponsesFromModels converts FollowUp models to FollowUpResponses.
+func FollowUpResponsesFromModels(followUps []models.FollowUp) []FollowUpResponse {
+	result := make([]FollowUpResponse, 0, len(followUps))
+	for i := range followUps {
+		result = append(result, FollowUpResponseFromModel(&followUps[i]))
+	}
+	return result
+}
+
+// FollowUpFeedResponse represents the calendar feed of the follow-ups of an agent.
+type FollowUpFeedResponse struct {
+	// URL is the address calendar applications subscribe to. It carries a token granting
+	// access to the follow-ups, so it must be kept secret.
+	URL string `json:"url"`
+}
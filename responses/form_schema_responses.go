@@ -0,0 +1,82 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the schema of the contact form, from which frontends and the embed
+// widget render the form and validate it before submitting.
+package responses
+
+// FormSchemaResponse describes a form: its fields, and what a submission must carry to pass
+// the spam protection.
+type FormSchemaResponse struct {
+	// Slug identifies the form.
+	Slug string `json:"slug"`
+	// Endpoint is the path the submissions are POSTed to.
+	Endpoint string `json:"endpoint"`
+	// Fields lists the fields of the form, in display order.
+	Fields []FormFieldResponse `json:"fields"`
+	// Consent describes the consent checkbox.
+	Consent FormConsentResponse `json:"consent"`
+	// Attachments describes the files accepted with a multipart submission; nil when they
+	// are not accepted.
+	Attachments *FormAttachmentsResponse `json:"attachments"`
+	// Captcha describes the CAPTCHA to solve; nil when none is required.
+	Captcha *FormCaptchaResponse `json:"captcha"`
+	// HoneypotField is the name of the hidden field that must be left empty, if any.
+	HoneypotField string `json:"honeypot_field,omitempty"`
+	// Challenges lists the endpoints of the challenges to fetch before submitting, such
+	// as the proof of work, if any.
+	Challenges []FormChallengeResponse `json:"challenges"`
+}
+
+// FormFieldResponse describes a field of a form.
+type FormFieldResponse struct {
+	// Name is the JSON name of the field in the submission.
+	Name string `json:"name"`
+	// Type is the input type: text, email, tel, textarea or checkbox.
+	Type string `json:"type"`
+	// Label is the default label of the field.
+	Label string `json:"label"`
+	// Required is set when the field must be filled in.
+	Required bool `json:"required"`
+	// MaxLength is the largest number of characters accepted, if limited.
+	MaxLength int `json:"max_length,omitempty"`
+	// Format is the format the value must follow, such as email or e164, if any.
+	Format string `json:"format,omitempty"`
+}
+
+// FormConsentResponse describes the consent checkbox of a form.
+type FormConsentResponse struct {
+	// Required is set when submissions must carry consent=true.
+	Required bool `json:"required"`
+	// PolicyVersion and TermsVersion are the versions of the texts the submitter agrees to.
+	PolicyVersion string `json:"policy_version,omitempty"`
+	TermsVersion  string `json:"terms_version,omitempty"`
+}
+
+// FormAttachmentsResponse describes the files accepted with a submission.
+type FormAttachmentsResponse struct {
+	// Field is the name of the multipart field carrying the files.
+	Field string `json:"field"`
+	// MaxCount is the largest number of files, and MaxSize the largest size of each, in bytes.
+	MaxCount int   `json:"max_count"`
+	MaxSize  int64 `json:"max_size"`
+	// AllowedTypes lists the accepted MIME types.
+	AllowedTypes []string `json:"allowed_types"`
+}
+
+// FormCaptchaResponse describes the CAPTCHA of a form.
+type FormCaptchaResponse struct {
+	// Provider is the CAPTCHA provider: recaptcha or hcaptcha.
+	Provider string `json:"provider"`
+	// SiteKey is the public key the widget renders the CAPTCHA with.
+	SiteKey string `json:"site_key"`
+	// Header is the request header carrying the solved token.
+	Header string `json:"header"`
+}
+
+// FormChallengeResponse describes a challenge to fetch before submitting.
+type FormChallengeResponse struct {
+	// Name identifies the challenge: proof_of_work or form_token.
+	Name string `json:"name"`
+	// Endpoint is the path the challenge is fetched from.
+	Endpoint string `json:"endpoint"`
+}
@@ -0,0 +1,175 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// It includes the SubjectAccessExport struct, the machine-readable bundle returned for
+// data subject access requests, with the contacts, the metadata of their attachments, the
+// emails of their conversations and their audit trail.
+
+package responses
+
+import (
+	"api-contact-form/ids"
+	"api-contact-form/models"
+	"time"
+)
+
+// SubjectAccessExport is the bundle of all data held about an email address.
+//
+// Unlike ContactResponse, it uses RFC 3339 timestamps and includes soft-deleted
+// contacts and internal columns, since a subject access response must be complete.
+type SubjectAccessExport struct {
+	// Email is the email address the export was requested for.
+	Email string `json:"email"`
+	// GeneratedAt is the time the export was produced.
+	GeneratedAt time.Time `json:"generated_at"`
+	// Contacts lists every contact stored for the email address.
+	Contacts []SubjectContactRecord `json:"contacts"`
+}
+
+// SubjectContactRecord is the complete stored representation of a contact.
+type SubjectContactRecord struct {
+	ID             ids.ID     `json:"id"`
+	Name           string     `json:"name"`
+	Email          string     `json:"email"`
+	Phone          string     `json:"phone"`
+	Message        string     `json:"message"`
+	Channel        string     `json:"channel"`
+	Status         string     `json:"status"`
+	StatusChanged  *time.Time `json:"status_changed_at"`
+	MessageID      *string    `json:"message_id"`
+	Fingerprint    string     `json:"fingerprint_hash"`
+	ConsentGiven   bool       `json:"consent_given"`
+	ConsentVersion string     `json:"consent_version"`
+	ConsentAt      *time.Time `json:"consent_at"`
+	ConsentIP      string     `json:"consent_ip"`
+	PrivacyVersion string     `json:"privacy_policy_version"`
+	TermsVersion   string     `json:"terms_version"`
+	LeadScore      int        `json:"lead_score"`
+	CompanyDomain  string     `json:"company_domain"`
+	CompanyName    string     `json:"company_name"`
+	CompanySize    string     `json:"company_size"`
+	Language       string     `json:"language"`
+	LegalHold      bool       `json:"legal_hold"`
+	MergedIntoID   *ids.ID    `json:"merged_into_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at"`
+	// Attachments lists the files uploaded with the contact, in upload order.
+	Attachments []SubjectAttachmentRecord `json:"attachments"`
+	// Emails lists the emails of the conversation of the contact, oldest first.
+	Emails []SubjectEmailRecord `json:"emails"`
+	// AuditLog lists the changes made to the contact, oldest first.
+	AuditLog []AuditLogResponse `json:"audit_log"`
+}
+
+// SubjectAttachmentRecord is the stored metadata of a file attached to a contact. The
+// content of the file is downloaded from the attachment endpoint.
+type SubjectAttachmentRecord struct {
+	ID          ids.ID    `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SubjectEmailRecord is the complete stored representation of an email of the
+// conversation of a contact.
+type SubjectEmailRecord struct {
+	ID        ids.ID    `json:"id"`
+	Direction string    `json:"direction"`
+	MessageID *string   `json:"message_id"`
+	InReplyTo string    `json:"in_reply_to"`
+	FromEmail string    `json:"from_email"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SubjectAccessExportFromModels builds the export bundle for an email address.
+//
+// Parameters:
+//   - email: The email address the export was requested for.
+//   - contacts: The contacts stored for the email address.
+//   - attachments: The attachments of the contacts.
+//   - messages: The emails of the conversations of the contacts.
+//   - audits: The entries of the audit trail of the contacts.
+//
+// Returns:
+//   - A SubjectAccessExport populated with the given contacts, their attachments, their
+//     emails and their audit trail.
+func SubjectAccessExportFromModels(email string, contacts []models.Contact, attachments []models.Attachment, messages []models.EmailMessage, audits []models.AuditLog) SubjectAccessExport {
+	files := make(map[uint][]SubjectAttachmentRecord)
+	for _, attachment := range attachments {
+		files[attachment.ContactID] = append(files[attachment.ContactID], SubjectAttachmentRecord{
+			ID:          ids.ID(attachment.ID),
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Size:        attachment.Size,
+			CreatedAt:   attachment.CreatedAt,
+		})
+	}
+
+	emails := make(map[uint][]SubjectEmailRecord)
+	for _, message := range messages {
+		emails[message.ContactID] = append(emails[message.ContactID], SubjectEmailRecord{
+			ID:        ids.ID(message.ID),
+			Direction: string(message.Direction),
+			MessageID: message.MessageID,
+			InReplyTo: message.InReplyTo,
+			FromEmail: message.FromEmail,
+			Subject:   message.Subject,
+			Body:      message.Body,
+			CreatedAt: message.CreatedAt,
+		})
+	}
+
+	trails := make(map[uint][]AuditLogResponse)
+	for i := range audits {
+		trails[audits[i].ContactID] = append(trails[audits[i].ContactID], AuditLogResponseFromModel(&audits[i]))
+	}
+
+	records := make([]SubjectContactRecord, 0, len(contacts))
+	for _, contact := range contacts {
+		var deletedAt *time.Time
+		if contact.DeletedAt.Valid {
+			deletedAt = &contact.DeletedAt.Time
+		}
+
+		records = append(records, SubjectContactRecord{
+			ID:             ids.ID(contact.ID),
+			Name:           contact.FullName,
+			Email:          contact.Email,
+			Phone:          contact.Phone,
+			Message:        contact.Message,
+			Channel:        string(contact.Channel),
+			Status:         string(contact.Status),
+			StatusChanged:  contact.StatusChangedAt,
+			MessageID:      contact.MessageID,
+			Fingerprint:    contact.FingerprintHash,
+			ConsentGiven:   contact.ConsentGiven,
+			ConsentVersion: contact.ConsentVersion,
+			ConsentAt:      contact.ConsentAt,
+			ConsentIP:      contact.ConsentIP,
+			PrivacyVersion: contact.PrivacyPolicyVersion,
+			TermsVersion:   contact.TermsVersion,
+			LeadScore:      contact.LeadScore,
+			CompanyDomain:  contact.CompanyDomain,
+			CompanyName:    contact.CompanyName,
+			CompanySize:    contact.CompanySize,
+			Language:       contact.Language,
+			LegalHold:      contact.LegalHold,
+			MergedIntoID:   ids.IDOf(contact.MergedIntoID),
+			CreatedAt:      contact.CreatedAt,
+			UpdatedAt:      contact.UpdatedAt,
+			DeletedAt:      deletedAt,
+			Attachments:    append([]SubjectAttachmentRecord{}, files[contact.ID]...),
+			Emails:         append([]SubjectEmailRecord{}, emails[contact.ID]...),
+			AuditLog:       append([]AuditLogResponse{}, trails[contact.ID]...),
+		})
+	}
+
+	return SubjectAccessExport{
+		Email:       email,
+		GeneratedAt: time.Now().UTC(),
+		Contacts:    records,
+	}
+}
@@ -0,0 +1,102 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the representation of the entries of the admin inbox, and of the
+// updates of the inbox pushed to the admin view.
+package responses
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/ids"
+	"api-contact-form/inboxfeed"
+	"api-contact-form/models"
+)
+
+// InboxEntryResponse represents a contact in the admin inbox.
+type InboxEntryResponse struct {
+	// ContactID is the ID of the contact.
+	ContactID ids.ID `json:"contact_id"`
+	// Name and Email identify the submitter.
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// Preview is the beginning of the message, on a single line.
+	Preview string `json:"preview"`
+	// Channel is the ingestion path the contact was submitted through.
+	Channel string `json:"channel"`
+	// Status is the handling status of the contact.
+	Status string `json:"status"`
+	// Unread reports whether nobody has looked at the contact yet.
+	Unread bool `json:"unread"`
+	// LeadScore is the lead score of the contact.
+	LeadScore int `json:"lead_score"`
+	// Pinned reports whether the contact is pinned to the top of the inbox.
+	Pinned bool `json:"pinned"`
+	// SnoozedUntil is the time the snooze of the contact ends, formatted as a human-readable
+	// string. It is only present for snoozed contacts.
+	SnoozedUntil string `json:"snoozed_until,omitempty"`
+	// SubmittedAt is the time the contact was submitted, formatted as a human-readable string.
+	SubmittedAt string `json:"submitted_at"`
+	// LastActivityAt is the time the contact last changed, formatted as a human-readable string.
+	LastActivityAt string `json:"last_activity_at"`
+}
+
+// InboxUpdateResponse represents a message pushed to the admin view by the inbox feed.
+type InboxUpdateResponse struct {
+	// Type is "counters" for the first message of a connection, and "update" for the
+	// messages sent when a contact changes.
+	Type string `json:"type"`
+	// Unread is the number of unread contacts in the inbox.
+	Unread int64 `json:"unread"`
+	// New is the number of contacts submitted since the connection was opened.
+	New int `json:"new"`
+	// Event is the lifecycle event of the contact that changed, such as contact.created.
+	Event string `json:"event,omitempty"`
+	// Removed is set when the contact left the inbox.
+	Removed bool `json:"removed,omitempty"`
+	// Contact summarizes the contact that changed.
+	Contact *InboxEntryResponse `json:"contact,omitempty"`
+}
+
+// InboxEntryResponseFromModel converts an InboxEntry model to an InboxEntryResponse.
+func InboxEntryResponseFromModel(entry *models.InboxEntry) InboxEntryResponse {
+	var snoozedUntil string
+	if entry.SnoozedUntil != nil {
+		snoozedUntil = helpers.FormatTimeHuman(*entry.SnoozedUntil)
+	}
+	return InboxEntryResponse{
+		ContactID:      ids.ID(entry.ContactID),
+		Name:           entry.FullName,
+		Email:          entry.Email,
+		Preview:        entry.Preview,
+		Channel:        string(entry.Channel),
+		Status:         string(entry.Status),
+		Unread:         entry.Unread,
+		LeadScore:      entry.LeadScore,
+		Pinned:         entry.Pinned,
+		SnoozedUntil:   snoozedUntil,
+		SubmittedAt:    helpers.FormatTimeHuman(entry.SubmittedAt),
+		LastActivityAt: helpers.FormatTimeHuman(entry.LastActivityAt),
+	}
+}
+
+// InboxEntryResponsesFromModels converts InboxEntry models to InboxEntryResponses.
+func InboxEntryResponsesFromModels(entries []models.InboxEntry) []InboxEntryResponse {
+	responses := make([]InboxEntryResponse, 0, len(entries))
+	for i := range entries {
+		responses = append(responses, InboxEntryResponseFromModel(&entries[i]))
+	}
+	return responses
+}
+
+// InboxUpdateResponseFromUpdate converts an inbox feed Update to an InboxUpdateResponse,
+// with the number of contacts submitted since the connection was opened.
+func InboxUpdateResponseFromUpdate(update *inboxfeed.Update, submitted int) InboxUpdateResponse {
+	contact := InboxEntryResponseFromModel(&update.Entry)
+	return InboxUpdateResponse{
+		Type:    "update",
+		Unread:  update.Unread,
+		New:     submitted,
+		Event:   string(update.Event),
+		Removed: update.Removed,
+		Contact: &contact,
+	}
+}
@@ -0,0 +1,39 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the representation of the health of the background job queues, as
+// returned by the job health endpoint.
+package responses
+
+import (
+	"api-contact-form/jobs"
+	"time"
+)
+
+// JobQueueResponse represents the health of a background job queue.
+type JobQueueResponse struct {
+	// Name identifies the queue, such as "notifications" or "webhooks".
+	Name string `json:"name"`
+	// Depth is the number of jobs waiting in the queue.
+	Depth int `json:"depth"`
+	// Capacity is the number of jobs the queue holds before dropping new ones.
+	Capacity int `json:"capacity"`
+	// OldestPendingSeconds is the time the oldest waiting job has been queued for, in seconds.
+	OldestPendingSeconds float64 `json:"oldest_pending_seconds"`
+	// Failures is the number of jobs the workers failed since the start.
+	Failures uint64 `json:"failures"`
+	// Stalled reports whether the oldest waiting job has been queued for too long.
+	Stalled bool `json:"stalled"`
+}
+
+// JobQueueResponseFromStats converts the jobs.Stats of a queue to a JobQueueResponse,
+// stalled when its oldest job has been waiting for longer than stallAfter.
+func JobQueueResponseFromStats(stats jobs.Stats, stallAfter time.Duration) JobQueueResponse {
+	return JobQueueResponse{
+		Name:                 stats.Name,
+		Depth:                stats.Depth,
+		Capacity:             stats.Capacity,
+		OldestPendingSeconds: stats.OldestPending.Seconds(),
+		Failures:             stats.Failures,
+		Stalled:              stats.OldestPending > stallAfter,
+	}
+}
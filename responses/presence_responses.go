@@ -0,0 +1,46 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the representation of the agents present on a contact, as returned
+// by the presence endpoints and sent by the presence event stream.
+package responses
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/ids"
+	"api-contact-form/presence"
+)
+
+// PresenceResponse represents the agents present on a contact.
+type PresenceResponse struct {
+	// ContactID is the contact the agents are present on.
+	ContactID ids.ID `json:"contact_id"`
+	// Viewers lists the agents present, in order of arrival.
+	Viewers []ViewerResponse `json:"viewers"`
+}
+
+// ViewerResponse represents an agent present on a contact.
+type ViewerResponse struct {
+	// Actor identifies the agent, such as "user:1".
+	Actor string `json:"actor"`
+	// Activity is what the agent is doing: viewing or replying.
+	Activity string `json:"activity"`
+	// Since is the time the agent arrived, formatted as a human-readable string.
+	Since string `json:"since"`
+	// ExpiresAt is the time the agent is forgotten without a new heartbeat, formatted as
+	// a human-readable string.
+	ExpiresAt string `json:"expires_at"`
+}
+
+// PresenceResponseFromViewers converts the agents present on a contact to a PresenceResponse.
+func PresenceResponseFromViewers(contactID uint, viewers []presence.Viewer) PresenceResponse {
+	response := PresenceResponse{ContactID: ids.ID(contactID), Viewers: make([]ViewerResponse, 0, len(viewers))}
+	for _, viewer := range viewers {
+		response.Viewers = append(response.Viewers, ViewerResponse{
+			Actor:     viewer.Actor,
+			Activity:  string(viewer.Activity),
+			Since:     helpers.FormatTimeHuman(viewer.Since),
+			ExpiresAt: helpers.FormatTimeHuman(viewer.ExpiresAt),
+		})
+	}
+	return response
+}
@@ -0,0 +1,35 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the representation of the draft of a reply to a contact.
+package responses
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/ids"
+	"api-contact-form/models"
+)
+
+// ReplyDraftResponse represents the draft of a reply in API responses.
+type ReplyDraftResponse struct {
+	// ContactID is the contact the reply is for.
+	ContactID ids.ID `json:"contact_id"`
+	// Body is the text of the reply.
+	Body string `json:"body"`
+	// Author is the actor who last saved the draft, such as "user:1".
+	Author string `json:"author"`
+	// CreatedAt is the timestamp when the draft was started, formatted as a human-readable string.
+	CreatedAt string `json:"created_at"`
+	// UpdatedAt is the timestamp when the draft was last saved, formatted as a human-readable string.
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ReplyDraftResponseFromModel converts a ReplyDraft model to a ReplyDraftResponse.
+func ReplyDraftResponseFromModel(draft *models.ReplyDraft) ReplyDraftResponse {
+	return ReplyDraftResponse{
+		ContactID: ids.ID(draft.ContactID),
+		Body:      draft.Body,
+		Author:    draft.Author,
+		CreatedAt: helpers.FormatTimeHuman(draft.CreatedAt),
+		UpdatedAt: helpers.FormatTimeHuman(draft.UpdatedAt),
+	}
+}
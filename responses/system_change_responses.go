@@ -0,0 +1,40 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the SystemChangeResponse returned by the changelog endpoint.
+package responses
+
+import (
+	"api-contact-form/ids"
+	"api-contact-form/models"
+	"encoding/json"
+	"time"
+)
+
+// SystemChangeResponse represents a change of the schema, settings or templates in the changelog.
+type SystemChangeResponse struct {
+	ID      ids.ID            `json:"id"`
+	Kind    models.ChangeKind `json:"kind"`
+	Actor   string            `json:"actor"`
+	Subject string            `json:"subject"`
+	Summary string            `json:"summary"`
+	// Details describes the change; its layout depends on the kind.
+	Details   json.RawMessage `json:"details"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// SystemChangeResponseFromModel converts a SystemChange model to a SystemChangeResponse.
+func SystemChangeResponseFromModel(change *models.SystemChange) SystemChangeResponse {
+	details := json.RawMessage(change.Details)
+	if !json.Valid(details) {
+		details = json.RawMessage("null")
+	}
+	return SystemChangeResponse{
+		ID:        ids.ID(change.ID),
+		Kind:      change.Kind,
+		Actor:     change.Actor,
+		Subject:   change.Subject,
+		Summary:   change.Summary,
+		Details:   details,
+		CreatedAt: change.CreatedAt,
+	}
+}
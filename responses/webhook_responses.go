@@ -0,0 +1,72 @@
+// Package responses defines the response payload structures for the API Contact Form application.
+//
+// This file contains the responses of the webhook subscription endpoints.
+package responses
+
+import (
+	"api-contact-form/ids"
+	"api-contact-form/models"
+	"time"
+)
+
+// WebhookResponse represents a webhook subscription, without its signing secret.
+type WebhookResponse struct {
+	ID        ids.ID                `json:"id"`
+	URL       string                `json:"url"`
+	Events    []models.WebhookEvent `json:"events"`
+	Active    bool                  `json:"active"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	// Secret signs the payloads of the subscription. It is only present in the response
+	// that created the subscription.
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookResponseFromModel converts a WebhookSubscription model to a WebhookResponse.
+// Subscriptions receiving all events list every event.
+func WebhookResponseFromModel(subscription *models.WebhookSubscription) WebhookResponse {
+	events := subscription.EventList()
+	if events == nil {
+		events = []models.WebhookEvent{models.EventContactCreated, models.EventContactUpdated, models.EventContactDeleted}
+	}
+	return WebhookResponse{
+		ID:        ids.ID(subscription.ID),
+		URL:       subscription.URL,
+		Events:    events,
+		Active:    subscription.Active,
+		CreatedAt: subscription.CreatedAt,
+		UpdatedAt: subscription.UpdatedAt,
+	}
+}
+
+// WebhookDeliveryResponse represents an attempt to deliver an event to a webhook subscription.
+type WebhookDeliveryResponse struct {
+	ID             ids.ID              `json:"id"`
+	SubscriptionID ids.ID              `json:"subscription_id"`
+	EventID        string              `json:"event_id"`
+	Event          models.WebhookEvent `json:"event"`
+	ContactID      ids.ID              `json:"contact_id"`
+	Attempt        int                 `json:"attempt"`
+	Success        bool                `json:"success"`
+	StatusCode     int                 `json:"status_code"`
+	Error          string              `json:"error"`
+	DurationMs     int64               `json:"duration_ms"`
+	CreatedAt      time.Time           `json:"created_at"`
+}
+
+// WebhookDeliveryResponseFromModel converts a WebhookDelivery model to a WebhookDeliveryResponse.
+func WebhookDeliveryResponseFromModel(delivery *models.WebhookDelivery) WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:             ids.ID(delivery.ID),
+		SubscriptionID: ids.ID(delivery.SubscriptionID),
+		EventID:        delivery.EventID,
+		Event:          delivery.Event,
+		ContactID:      ids.ID(delivery.ContactID),
+		Attempt:        delivery.Attempt,
+		Success:        delivery.Success,
+		StatusCode:     delivery.StatusCode,
+		Error:          delivery.Error,
+		DurationMs:     delivery.DurationMs,
+		CreatedAt:      delivery.CreatedAt,
+	}
+}
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	validatorOnce sync.Once
+	validatorInst *validator.Validate
+)
+
+// validate checks a struct, or the structs of a slice, against its binding tags, as Gin
+// does after decoding a request body.
+func validate(obj any) error {
+	value := reflect.ValueOf(obj)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		return structValidator().Struct(value.Interface())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := validate(value.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// structValidator returns the validator reading the binding tags.
+func structValidator() *validator.Validate {
+	validatorOnce.Do(func() {
+		validatorInst = validator.New()
+		validatorInst.SetTagName("binding")
+	})
+	return validatorInst
+}
@@ -0,0 +1,295 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// abortIndex is the chain index of an aborted Context.
+const abortIndex = math.MaxInt8 / 2
+
+// defaultMultipartMemory is the memory used by MultipartForm before spilling file parts to
+// disk, as in Gin.
+const defaultMultipartMemory = 32 << 20
+
+// MIMEMultipartPOSTForm is the content type of multipart forms.
+const MIMEMultipartPOSTForm = "multipart/form-data"
+
+// H is a shortcut for a JSON object.
+type H map[string]any
+
+// Context carries a request through the chain of handlers of its route.
+type Context struct {
+	Request *http.Request
+	Writer  ResponseWriter
+
+	base     *responseWriter
+	params   Params
+	fullPath string
+	handlers []HandlerFunc
+	index    int
+
+	mu   sync.RWMutex
+	keys map[string]any
+}
+
+// newContext creates the Context of a request matched to the route fullPath.
+func newContext(w http.ResponseWriter, r *http.Request, params Params, fullPath string, handlers []HandlerFunc) *Context {
+	base := &responseWriter{ResponseWriter: w, status: http.StatusOK, size: noWritten}
+	if params == nil {
+		params = func(string) string { return "" }
+	}
+	return &Context{Request: r, Writer: base, base: base, params: params, fullPath: fullPath, handlers: handlers, index: -1}
+}
+
+// Next runs the remaining handlers of the chain.
+func (c *Context) Next() {
+	c.index++
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+// Abort prevents the remaining handlers of the chain from running.
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted reports whether the chain was aborted.
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// AbortWithStatus aborts the chain and writes the status code with no body.
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.Writer.WriteHeaderNow()
+	c.Abort()
+}
+
+// AbortWithStatusJSON aborts the chain and writes obj as JSON with the status code.
+func (c *Context) AbortWithStatusJSON(code int, obj any) {
+	c.Abort()
+	c.JSON(code, obj)
+}
+
+// FullPath returns the path of the matched route, or an empty string when none matched.
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
+// Param returns the value of a path parameter.
+func (c *Context) Param(name string) string {
+	return c.params(name)
+}
+
+// Query returns the first value of a query parameter.
+func (c *Context) Query(key string) string {
+	return c.Request.URL.Query().Get(key)
+}
+
+// DefaultQuery returns the first value of a query parameter, or def when it is absent.
+func (c *Context) DefaultQuery(key, def string) string {
+	if values, ok := c.Request.URL.Query()[key]; ok && len(values) > 0 {
+		return values[0]
+	}
+	return def
+}
+
+// PostForm returns the first value of a field of a URL-encoded or multipart form.
+func (c *Context) PostForm(key string) string {
+	if c.Request.PostForm == nil {
+		if err := c.Request.ParseMultipartForm(defaultMultipartMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return ""
+		}
+	}
+	return c.Request.PostForm.Get(key)
+}
+
+// MultipartForm parses the request body as a multipart form.
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	err := c.Request.ParseMultipartForm(defaultMultipartMemory)
+	return c.Request.MultipartForm, err
+}
+
+// ShouldBindJSON decodes the JSON request body into obj, then validates it against its
+// binding tags.
+func (c *Context) ShouldBindJSON(obj any) error {
+	if c.Request == nil || c.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// ContentType returns the media type of the request, without its parameters.
+func (c *Context) ContentType() string {
+	mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil {
+		mediaType, _, _ = strings.Cut(c.GetHeader("Content-Type"), ";")
+		return strings.TrimSpace(mediaType)
+	}
+	return mediaType
+}
+
+// GetHeader returns a request header.
+func (c *Context) GetHeader(key string) string {
+	return c.Request.Header.Get(key)
+}
+
+// ClientIP returns the address of the client, taken from the X-Forwarded-For and
+// X-Real-IP headers before the remote address of the connection, as Gin does with its
+// default configuration.
+func (c *Context) ClientIP() string {
+	if ip := forwardedIP(c.GetHeader("X-Forwarded-For")); ip != "" {
+		return ip
+	}
+	if ip := strings.TrimSpace(c.GetHeader("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// forwardedIP returns the first address of an X-Forwarded-For header when every entry of
+// the header is a valid address.
+func forwardedIP(header string) string {
+	if header == "" {
+		return ""
+	}
+	items := strings.Split(header, ",")
+	for _, item := range items {
+		if net.ParseIP(strings.TrimSpace(item)) == nil {
+			return ""
+		}
+	}
+	return strings.TrimSpace(items[0])
+}
+
+// Set stores a value for the lifetime of the request.
+func (c *Context) Set(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.keys == nil {
+		c.keys = make(map[string]any)
+	}
+	c.keys[key] = value
+}
+
+// Get returns a value stored with Set.
+func (c *Context) Get(key string) (any, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, ok := c.keys[key]
+	return value, ok
+}
+
+// GetString returns a string stored with Set, or an empty string.
+func (c *Context) GetString(key string) string {
+	value, _ := c.Get(key)
+	s, _ := value.(string)
+	return s
+}
+
+// Status sets the status code of the response.
+func (c *Context) Status(code int) {
+	c.Writer.WriteHeader(code)
+}
+
+// Header sets a response header, or deletes it when value is empty.
+func (c *Context) Header(key, value string) {
+	if value == "" {
+		c.Writer.Header().Del(key)
+		return
+	}
+	c.Writer.Header().Set(key, value)
+}
+
+// JSON writes obj as JSON with the status code.
+func (c *Context) JSON(code int, obj any) {
+	c.Status(code)
+	header := c.Writer.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	if !bodyAllowed(code) {
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		panic(err)
+	}
+	_, _ = c.Writer.Write(body)
+}
+
+// Data writes data with the status code and content type.
+func (c *Context) Data(code int, contentType string, data []byte) {
+	c.Status(code)
+	if c.Writer.Header().Get("Content-Type") == "" {
+		c.Writer.Header().Set("Content-Type", contentType)
+	}
+	if !bodyAllowed(code) {
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	_, _ = c.Writer.Write(data)
+}
+
+// SSEvent writes a server-sent event named name with data encoded as JSON, unless it is a
+// string.
+func (c *Context) SSEvent(name string, data any) {
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream;charset=utf-8")
+	if header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", "no-cache")
+	}
+	var event strings.Builder
+	if name != "" {
+		event.WriteString("event:" + escapeEventField(name) + "\n")
+	}
+	event.WriteString("data:")
+	switch value := data.(type) {
+	case string:
+		event.WriteString(strings.ReplaceAll(value, "\n", "\ndata:"))
+		event.WriteString("\n")
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			panic(err)
+		}
+		event.Write(encoded)
+		event.WriteString("\n")
+	}
+	event.WriteString("\n")
+	_, _ = c.Writer.WriteString(event.String())
+}
+
+// escapeEventField removes the line breaks of a field of a server-sent event.
+func escapeEventField(value string) string {
+	return strings.NewReplacer("\n", "\\n", "\r", "\\r").Replace(value)
+}
+
+// bodyAllowed reports whether a response with the status code can have a body.
+func bodyAllowed(code int) bool {
+	switch {
+	case code >= 100 && code <= 199:
+		return false
+	case code == http.StatusNoContent, code == http.StatusNotModified:
+		return false
+	}
+	return true
+}
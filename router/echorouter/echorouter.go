@@ -0,0 +1,114 @@
+// Package echorouter serves the routes of a router.Engine with Echo.
+package echorouter
+
+import (
+	"api-contact-form/router"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Adapter is the router.Adapter of Echo.
+type Adapter struct {
+	echo     *echo.Echo
+	notFound router.ServeFunc
+	options  map[string]bool
+}
+
+// New creates an Adapter registering the routes on e, which should have no middleware: the
+// router.Engine runs the middleware of the application. New replaces the HTTP error
+// handler of e, so that the requests matching no route, including those matching a route
+// of another method, reach the not found handler of the Engine as they do with Gin.
+func New(e *echo.Echo) *Adapter {
+	a := &Adapter{echo: e, options: make(map[string]bool)}
+	e.HTTPErrorHandler = a.handleError
+	return a
+}
+
+// Handle registers serve for the requests with method and path. A catch-all parameter
+// "*name" becomes the wildcard of Echo, whose value is given the leading slash it has with
+// Gin.
+func (a *Adapter) Handle(method, path string, serve router.ServeFunc) {
+	wildcard := ""
+	if i := strings.LastIndex(path, "/*"); i >= 0 {
+		wildcard = path[i+2:]
+		path = path[:i+2]
+	}
+	a.echo.Add(method, path, func(c echo.Context) error {
+		if spansSegments(c) {
+			return echo.ErrNotFound
+		}
+		serve(c.Response(), c.Request(), params(c, wildcard))
+		return nil
+	})
+	// Echo answers the OPTIONS requests of the paths of its routes itself, which would keep
+	// CORS preflight requests from the middleware of the Engine; they are sent to the not
+	// found handler instead, unless an OPTIONS route is registered.
+	if method == http.MethodOptions {
+		a.options[path] = true
+	} else if !a.options[path] {
+		a.options[path] = true
+		a.echo.Add(http.MethodOptions, path, func(c echo.Context) error {
+			return echo.ErrNotFound
+		})
+	}
+}
+
+// NotFound registers serve for the requests matching no route.
+func (a *Adapter) NotFound(serve router.ServeFunc) {
+	a.notFound = serve
+}
+
+// ServeHTTP serves a request with Echo.
+func (a *Adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.echo.ServeHTTP(w, r)
+}
+
+// handleError serves the requests matching no route with the not found handler, and the
+// other errors with the default handler of Echo.
+func (a *Adapter) handleError(err error, c echo.Context) {
+	if a.notFound != nil && (errors.Is(err, echo.ErrNotFound) || errors.Is(err, echo.ErrMethodNotAllowed)) {
+		if c.Response().Committed {
+			return
+		}
+		c.Response().Header().Del(echo.HeaderAllow)
+		a.notFound(c.Response(), c.Request(), nil)
+		return
+	}
+	a.echo.DefaultHTTPErrorHandler(err, c)
+}
+
+// spansSegments reports whether a parameter of the route matched by c has a value made of
+// several path segments, which Echo can give to a last parameter after backtracking.
+func spansSegments(c echo.Context) bool {
+	values := c.ParamValues()
+	for i, name := range c.ParamNames() {
+		if name != "*" && i < len(values) && strings.Contains(values[i], "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// params returns the router.Params of the route matched by c. The values are unescaped,
+// since Echo matches the raw path when the request has one.
+func params(c echo.Context, wildcard string) router.Params {
+	escaped := c.Request().URL.RawPath != ""
+	return func(name string) string {
+		var value string
+		if wildcard != "" && name == wildcard {
+			value = "/" + c.Param("*")
+		} else {
+			value = c.Param(name)
+		}
+		if escaped {
+			if unescaped, err := url.PathUnescape(value); err == nil {
+				return unescaped
+			}
+		}
+		return value
+	}
+}
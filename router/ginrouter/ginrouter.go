@@ -0,0 +1,42 @@
+// Package ginrouter serves the routes of a router.Engine with Gin.
+package ginrouter
+
+import (
+	"api-contact-form/router"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Adapter is the router.Adapter of Gin.
+type Adapter struct {
+	engine *gin.Engine
+}
+
+// New creates an Adapter registering the routes on engine, which should have no
+// middleware: the router.Engine runs the middleware of the application.
+func New(engine *gin.Engine) *Adapter {
+	return &Adapter{engine: engine}
+}
+
+// Handle registers serve for the requests with method and path.
+func (a *Adapter) Handle(method, path string, serve router.ServeFunc) {
+	a.engine.Handle(method, path, wrap(serve))
+}
+
+// NotFound registers serve for the requests matching no route.
+func (a *Adapter) NotFound(serve router.ServeFunc) {
+	a.engine.NoRoute(wrap(serve))
+}
+
+// ServeHTTP serves a request with the Gin engine.
+func (a *Adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.engine.ServeHTTP(w, r)
+}
+
+// wrap adapts serve to a Gin handler.
+func wrap(serve router.ServeFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serve(c.Writer, c.Request, c.Param)
+	}
+}
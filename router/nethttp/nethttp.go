@@ -0,0 +1,154 @@
+// Package nethttp serves the routes of a router.Engine with the standard library alone.
+//
+// http.ServeMux is not used because it rejects routes that Gin accepts, such as a static
+// segment and a parameter at the same depth under different prefixes; the adapter matches
+// the requests with a tree of path segments instead, preferring static segments, then
+// parameters, then catch-all parameters, and backtracking when a branch does not match.
+package nethttp
+
+import (
+	"api-contact-form/router"
+	"net/http"
+	"strings"
+)
+
+// Adapter is the router.Adapter of the standard library.
+type Adapter struct {
+	trees    map[string]*node
+	notFound router.ServeFunc
+}
+
+// New creates an Adapter with no routes.
+func New() *Adapter {
+	return &Adapter{trees: make(map[string]*node)}
+}
+
+// node is a segment of the routes of a method.
+type node struct {
+	static   map[string]*node
+	param    *node
+	name     string
+	catchAll *node
+	serve    router.ServeFunc
+}
+
+// param is the value of a path parameter of a matched route.
+type param struct {
+	name, value string
+}
+
+// Handle registers serve for the requests with method and path.
+func (a *Adapter) Handle(method, path string, serve router.ServeFunc) {
+	root, ok := a.trees[method]
+	if !ok {
+		root = &node{}
+		a.trees[method] = root
+	}
+	current := root
+	for _, segment := range splitPath(path) {
+		switch {
+		case strings.HasPrefix(segment, "*"):
+			if current.catchAll == nil {
+				current.catchAll = &node{name: segment[1:]}
+			}
+			current = current.catchAll
+		case strings.HasPrefix(segment, ":"):
+			if current.param == nil {
+				current.param = &node{name: segment[1:]}
+			} else if current.param.name != segment[1:] {
+				panic("nethttp: conflicting parameter names " + current.param.name + " and " + segment[1:] + " in " + path)
+			}
+			current = current.param
+		default:
+			if current.static == nil {
+				current.static = make(map[string]*node)
+			}
+			child, ok := current.static[segment]
+			if !ok {
+				child = &node{}
+				current.static[segment] = child
+			}
+			current = child
+		}
+	}
+	if current.serve != nil {
+		panic("nethttp: duplicate route " + method + " " + path)
+	}
+	current.serve = serve
+}
+
+// NotFound registers serve for the requests matching no route.
+func (a *Adapter) NotFound(serve router.ServeFunc) {
+	a.notFound = serve
+}
+
+// ServeHTTP serves a request with the route matching its method and path.
+func (a *Adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if root, ok := a.trees[r.Method]; ok {
+		var params []param
+		if serve, ok := root.match(splitPath(r.URL.Path), &params); ok {
+			serve(w, r, lookup(params))
+			return
+		}
+	}
+	if a.notFound != nil {
+		a.notFound(w, r, nil)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// match finds the route of the segments under n, appending the values of its parameters.
+func (n *node) match(segments []string, params *[]param) (router.ServeFunc, bool) {
+	if len(segments) == 0 {
+		if n.serve != nil {
+			return n.serve, true
+		}
+		// A catch-all parameter also matches an empty rest of the path.
+		if n.catchAll != nil && n.catchAll.serve != nil {
+			*params = append(*params, param{name: n.catchAll.name, value: "/"})
+			return n.catchAll.serve, true
+		}
+		return nil, false
+	}
+	segment, rest := segments[0], segments[1:]
+	if child, ok := n.static[segment]; ok {
+		if serve, ok := child.match(rest, params); ok {
+			return serve, true
+		}
+	}
+	if n.param != nil && segment != "" {
+		mark := len(*params)
+		*params = append(*params, param{name: n.param.name, value: segment})
+		if serve, ok := n.param.match(rest, params); ok {
+			return serve, true
+		}
+		*params = (*params)[:mark]
+	}
+	if n.catchAll != nil && n.catchAll.serve != nil {
+		*params = append(*params, param{name: n.catchAll.name, value: "/" + strings.Join(segments, "/")})
+		return n.catchAll.serve, true
+	}
+	return nil, false
+}
+
+// splitPath returns the segments of a path; a trailing slash yields a last empty segment.
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// lookup returns the router.Params of the values of the parameters.
+func lookup(params []param) router.Params {
+	return func(name string) string {
+		for _, p := range params {
+			if p.name == name {
+				return p.value
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,110 @@
+package router
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// noWritten is the size of a response whose header was not written.
+const noWritten = -1
+
+// ResponseWriter is the http.ResponseWriter of a Context. As in Gin, the status code is only
+// sent with the first write of the body, or by WriteHeaderNow, so that middleware can still
+// change it after the handlers ran.
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Hijacker
+	http.Flusher
+
+	// Status returns the status code of the response.
+	Status() int
+	// Size returns the number of bytes of the body written, or -1 before the header.
+	Size() int
+	// WriteString writes s to the body.
+	WriteString(s string) (int, error)
+	// Written reports whether the header was written.
+	Written() bool
+	// WriteHeaderNow writes the header unless it was written.
+	WriteHeaderNow()
+}
+
+// responseWriter is the ResponseWriter wrapping the writer of the adapter.
+type responseWriter struct {
+	http.ResponseWriter
+	size   int
+	status int
+}
+
+// WriteHeader sets the status code sent with the header, unless the header was written.
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && w.status != code && !w.Written() {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow writes the header unless it was written.
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// Write writes data to the body, after the header.
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+// WriteString writes s to the body, after the header.
+func (w *responseWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	n, err := io.WriteString(w.ResponseWriter, s)
+	w.size += n
+	return n, err
+}
+
+// Status returns the status code of the response.
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+// Size returns the number of bytes of the body written, or -1 before the header.
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+// Written reports whether the header was written.
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+// Hijack takes over the connection, for WebSocket handlers.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer does not support hijacking")
+	}
+	if w.size < 0 {
+		w.size = 0
+	}
+	return hijacker.Hijack()
+}
+
+// Flush sends the header and the buffered body to the client.
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the writer of the adapter, for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
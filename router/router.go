@@ -0,0 +1,172 @@
+// Package router is the thin routing layer between the handlers of the API and the HTTP
+// framework serving them.
+//
+// Handlers and middleware are HandlerFuncs taking a *Context, whose methods follow those
+// of Gin, and are registered on a Router. The Engine, the root Router, composes the
+// middleware chains and registers every route on an Adapter, which matches the requests
+// to the routes with the framework of the application: package nethttp for the standard
+// library alone, ginrouter for Gin and echorouter for Echo.
+//
+// Paths use the syntax of Gin: ":name" matches a path segment and a trailing "*name" the
+// rest of the path, including its leading slash. Static segments take precedence over
+// parameters.
+package router
+
+import (
+	"net/http"
+	"path"
+)
+
+// HandlerFunc handles a request, or runs as middleware around the handlers following it
+// in the chain of its route, which it calls with Context.Next.
+type HandlerFunc func(*Context)
+
+// Router registers routes and the middleware running before their handlers.
+type Router interface {
+	// Use adds middleware to the routes registered afterwards.
+	Use(middleware ...HandlerFunc)
+	// Group returns a Router registering its routes under prefix, after middleware.
+	Group(prefix string, middleware ...HandlerFunc) Router
+	// Handle registers the chain of handlers for the requests with method and path.
+	Handle(method, path string, handlers ...HandlerFunc)
+	// GET, POST, PUT, PATCH and DELETE are shortcuts of Handle.
+	GET(path string, handlers ...HandlerFunc)
+	POST(path string, handlers ...HandlerFunc)
+	PUT(path string, handlers ...HandlerFunc)
+	PATCH(path string, handlers ...HandlerFunc)
+	DELETE(path string, handlers ...HandlerFunc)
+}
+
+// Params returns the value of a path parameter of the matched route, or an empty string.
+type Params func(name string) string
+
+// ServeFunc serves a request matched to a route, with the values of its path parameters.
+type ServeFunc func(w http.ResponseWriter, r *http.Request, params Params)
+
+// Adapter is the HTTP framework matching the requests to the routes of an Engine.
+type Adapter interface {
+	http.Handler
+	// Handle registers serve for the requests with method and path, in the syntax of the
+	// package documentation.
+	Handle(method, path string, serve ServeFunc)
+	// NotFound registers serve for the requests matching no route, whatever their method.
+	NotFound(serve ServeFunc)
+}
+
+// Engine is the root Router of an application, serving its routes through an Adapter.
+// Like Gin, it answers the requests matching no route with a 404 status code, after the
+// middleware added to the Engine itself, so that it handles CORS preflight requests.
+type Engine struct {
+	group
+	adapter Adapter
+}
+
+// New creates an Engine serving its routes with adapter.
+func New(adapter Adapter) *Engine {
+	e := &Engine{adapter: adapter}
+	e.group = group{engine: e, prefix: "/"}
+	adapter.NotFound(e.notFound)
+	return e
+}
+
+// ServeHTTP serves a request with the adapter.
+func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.adapter.ServeHTTP(w, r)
+}
+
+// notFound runs the middleware of the Engine, then answers with a 404 status code unless
+// the middleware responded.
+func (e *Engine) notFound(w http.ResponseWriter, r *http.Request, params Params) {
+	c := newContext(w, r, params, "", e.handlers)
+	c.base.status = http.StatusNotFound
+	c.Next()
+	if c.base.Written() {
+		return
+	}
+	if c.base.Status() == http.StatusNotFound {
+		c.base.Header().Set("Content-Type", "text/plain")
+		_, _ = c.base.WriteString("404 page not found")
+		return
+	}
+	c.base.WriteHeaderNow()
+}
+
+// group is a Router registering its routes under a prefix, after its middleware.
+type group struct {
+	engine   *Engine
+	prefix   string
+	handlers []HandlerFunc
+}
+
+// Use appends middleware to the chain of the group.
+func (g *group) Use(middleware ...HandlerFunc) {
+	g.handlers = append(g.handlers, middleware...)
+}
+
+// Group returns a child group of g.
+func (g *group) Group(prefix string, middleware ...HandlerFunc) Router {
+	return &group{engine: g.engine, prefix: joinPaths(g.prefix, prefix), handlers: g.combine(middleware)}
+}
+
+// Handle registers the route on the adapter of the Engine, with the middleware of the group
+// added so far.
+func (g *group) Handle(method, relativePath string, handlers ...HandlerFunc) {
+	fullPath := joinPaths(g.prefix, relativePath)
+	chain := g.combine(handlers)
+	g.engine.adapter.Handle(method, fullPath, func(w http.ResponseWriter, r *http.Request, params Params) {
+		c := newContext(w, r, params, fullPath, chain)
+		c.Next()
+		c.base.WriteHeaderNow()
+	})
+}
+
+// GET registers a route for GET requests.
+func (g *group) GET(path string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodGet, path, handlers...)
+}
+
+// POST registers a route for POST requests.
+func (g *group) POST(path string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodPost, path, handlers...)
+}
+
+// PUT registers a route for PUT requests.
+func (g *group) PUT(path string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodPut, path, handlers...)
+}
+
+// PATCH registers a route for PATCH requests.
+func (g *group) PATCH(path string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodPatch, path, handlers...)
+}
+
+// DELETE registers a route for DELETE requests.
+func (g *group) DELETE(path string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodDelete, path, handlers...)
+}
+
+// combine returns the middleware of the group followed by handlers, in a new slice.
+func (g *group) combine(handlers []HandlerFunc) []HandlerFunc {
+	chain := make([]HandlerFunc, 0, len(g.handlers)+len(handlers))
+	chain = append(chain, g.handlers...)
+	return append(chain, handlers...)
+}
+
+// joinPaths appends relative to absolute, keeping the trailing slash of relative.
+func joinPaths(absolute, relative string) string {
+	if relative == "" {
+		return absolute
+	}
+	joined := path.Join(absolute, relative)
+	if relative[len(relative)-1] == '/' && joined[len(joined)-1] != '/' {
+		return joined + "/"
+	}
+	return joined
+}
+
+// WrapH adapts an http.Handler, such as the Prometheus handler, to a HandlerFunc.
+func WrapH(handler http.Handler) HandlerFunc {
+	return func(c *Context) {
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,155 @@
+package router_test
+
+import (
+	"api-contact-form/router"
+	"api-contact-form/router/echorouter"
+	"api-contact-form/router/ginrouter"
+	"api-contact-form/router/nethttp"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+// adapters returns a constructor of each adapter.
+func adapters() map[string]func() router.Adapter {
+	gin.SetMode(gin.TestMode)
+	return map[string]func() router.Adapter{
+		"nethttp": func() router.Adapter { return nethttp.New() },
+		"gin":     func() router.Adapter { return ginrouter.New(gin.New()) },
+		"echo":    func() router.Adapter { return echorouter.New(echo.New()) },
+	}
+}
+
+// newEngine registers the routes of the tests on an Engine served by adapter.
+func newEngine(adapter router.Adapter) *router.Engine {
+	engine := router.New(adapter)
+	engine.Use(func(c *router.Context) {
+		c.Header("X-Root", "1")
+		c.Next()
+		c.Header("X-Status", http.StatusText(c.Writer.Status()))
+	})
+	echoRoute := func(c *router.Context) {
+		c.JSON(http.StatusOK, router.H{"route": c.FullPath(), "id": c.Param("id"), "reference": c.Param("reference"), "path": c.Param("path")})
+	}
+	contacts := engine.Group("/api/v1/contacts")
+	contacts.GET("/reference/:reference", echoRoute)
+	contacts.GET("/:id", echoRoute)
+	contacts.GET("/:id/emails", echoRoute)
+	contacts.DELETE("/:id", func(c *router.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	guarded := engine.Group("/guarded", func(c *router.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, router.H{"code": "UNAUTHORIZED"})
+		}
+	})
+	guarded.GET("/files/*path", echoRoute)
+	return engine
+}
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		name, method, target, auth string
+		status                     int
+		body                       string
+	}{
+		{"static segment before parameter", http.MethodGet, "/api/v1/contacts/reference/ABC", "", http.StatusOK,
+			`{"id":"","path":"","reference":"ABC","route":"/api/v1/contacts/reference/:reference"}`},
+		{"parameter", http.MethodGet, "/api/v1/contacts/7", "", http.StatusOK,
+			`{"id":"7","path":"","reference":"","route":"/api/v1/contacts/:id"}`},
+		{"backtracking from static segment", http.MethodGet, "/api/v1/contacts/reference/emails", "", http.StatusOK,
+			`{"id":"","path":"","reference":"emails","route":"/api/v1/contacts/reference/:reference"}`},
+		{"parameter then static segment", http.MethodGet, "/api/v1/contacts/reference/emails/x", "", http.StatusNotFound,
+			"404 page not found"},
+		{"nested route", http.MethodGet, "/api/v1/contacts/7/emails", "", http.StatusOK,
+			`{"id":"7","path":"","reference":"","route":"/api/v1/contacts/:id/emails"}`},
+		{"catch-all parameter", http.MethodGet, "/guarded/files/a/b.txt", "token", http.StatusOK,
+			`{"id":"","path":"/a/b.txt","reference":"","route":"/guarded/files/*path"}`},
+		{"aborted chain", http.MethodGet, "/guarded/files/a", "", http.StatusUnauthorized, `{"code":"UNAUTHORIZED"}`},
+		{"no content", http.MethodDelete, "/api/v1/contacts/7", "", http.StatusNoContent, ""},
+		{"unknown path", http.MethodGet, "/api/v2", "", http.StatusNotFound, "404 page not found"},
+		{"options request", http.MethodOptions, "/api/v1/contacts/7", "", http.StatusNotFound, "404 page not found"},
+		{"unknown method", http.MethodPut, "/api/v1/contacts/7", "", http.StatusNotFound, "404 page not found"},
+	}
+	for adapterName, newAdapter := range adapters() {
+		engine := newEngine(newAdapter())
+		for _, tt := range tests {
+			t.Run(adapterName+"/"+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, tt.target, nil)
+				if tt.auth != "" {
+					req.Header.Set("Authorization", tt.auth)
+				}
+				rec := httptest.NewRecorder()
+				engine.ServeHTTP(rec, req)
+
+				if rec.Code != tt.status {
+					t.Errorf("status = %d, want %d", rec.Code, tt.status)
+				}
+				if body := strings.TrimSpace(rec.Body.String()); body != tt.body {
+					t.Errorf("body = %s, want %s", body, tt.body)
+				}
+				if rec.Header().Get("X-Root") != "1" {
+					t.Error("root middleware did not run")
+				}
+				if rec.Header().Get("Allow") != "" {
+					t.Errorf("Allow = %q, want none", rec.Header().Get("Allow"))
+				}
+			})
+		}
+	}
+}
+
+func TestStatusChangedByMiddleware(t *testing.T) {
+	for adapterName, newAdapter := range adapters() {
+		t.Run(adapterName, func(t *testing.T) {
+			engine := router.New(newAdapter())
+			engine.Use(func(c *router.Context) {
+				c.Next()
+				if c.Writer.Written() {
+					t.Error("header written before the end of the chain")
+				}
+				c.Status(http.StatusAccepted)
+			})
+			engine.POST("/jobs", func(c *router.Context) {
+				c.Header("Location", "/jobs/1")
+			})
+
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", nil))
+
+			if rec.Code != http.StatusAccepted {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusAccepted)
+			}
+			if rec.Header().Get("Location") != "/jobs/1" {
+				t.Errorf("Location = %q", rec.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestShouldBindJSONValidatesBindingTags(t *testing.T) {
+	type request struct {
+		Name string `json:"name" binding:"required,max=5"`
+	}
+	engine := router.New(nethttp.New())
+	engine.POST("/", func(c *router.Context) {
+		var req request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, router.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, req)
+	})
+
+	for body, status := range map[string]int{`{"name":"ok"}`: http.StatusOK, `{}`: http.StatusBadRequest, `{"name":"too long"}`: http.StatusBadRequest, `{`: http.StatusBadRequest} {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		if rec.Code != status {
+			t.Errorf("%s: status = %d, want %d", body, rec.Code, status)
+		}
+	}
+}
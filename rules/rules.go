@@ -0,0 +1,162 @@
+// Package rules implements custom validation rules loaded from a configuration file.
+//
+// Rules let operators tighten submission validation without recompiling: a regular
+// expression per field, a list of banned email domains, and conditional requirements
+// ("phone is required when message matches ..."). The same file configures the lead
+// scoring of submissions, described in scoring.go. The file may be YAML or JSON:
+//
+//	fields:
+//	  phone:
+//	    pattern: '^\+?[0-9 ]+$'
+//	    message: phone may only contain digits and spaces
+//	banned_email_domains:
+//	  - mailinator.com
+//	conditions:
+//	  - when: { field: message, matches: '(?i)call me' }
+//	    require: [phone]
+package rules
+
+import (
+	"api-contact-form/helpers"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Rules is the set of custom validations loaded from the rules file.
+type Rules struct {
+	// Fields maps a request field name (its JSON name) to the rule it must satisfy.
+	Fields map[string]*FieldRule `json:"fields" yaml:"fields"`
+	// BannedEmailDomains lists email domains, including their subdomains, that are rejected.
+	BannedEmailDomains []string `json:"banned_email_domains" yaml:"banned_email_domains"`
+	// Conditions lists fields that become required when another field matches a pattern.
+	Conditions []*Condition `json:"conditions" yaml:"conditions"`
+	// LeadScoring gives points to submissions; submissions are not scored when it is nil.
+	LeadScoring *LeadScoring `json:"lead_scoring,omitempty" yaml:"lead_scoring,omitempty"`
+}
+
+// FieldRule constrains the value of a single field.
+type FieldRule struct {
+	// Pattern is a regular expression non-empty values must match.
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// Message is returned when the pattern does not match.
+	Message string `json:"message" yaml:"message"`
+
+	pattern *regexp.Regexp
+}
+
+// Condition makes fields required when a field matches a pattern.
+type Condition struct {
+	When struct {
+		// Field is the field whose value is tested.
+		Field string `json:"field" yaml:"field"`
+		// Matches is the regular expression the field value is tested against.
+		Matches string `json:"matches" yaml:"matches"`
+	} `json:"when" yaml:"when"`
+	// Require lists the fields that must not be empty when the condition applies.
+	Require []string `json:"require" yaml:"require"`
+
+	matches *regexp.Regexp
+}
+
+// Violation describes a field that failed a custom rule.
+type Violation struct {
+	// Field is the JSON name of the offending field.
+	Field string `json:"field"`
+	// Message explains why the value was rejected.
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when custom rules reject a submission.
+type ValidationError struct {
+	// Violations lists every failed rule.
+	Violations []Violation
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Field+": "+v.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Compile prepares the regular expressions of the rules and reports invalid ones. Rules
+// must be compiled before they validate anything.
+func (r *Rules) Compile() error {
+	for field, rule := range r.Fields {
+		if rule == nil {
+			return fmt.Errorf("field %s: missing rule", field)
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field, err)
+		}
+		rule.pattern = pattern
+	}
+	for i, cond := range r.Conditions {
+		if cond == nil {
+			return fmt.Errorf("condition %d: missing condition", i+1)
+		}
+		matches, err := regexp.Compile(cond.When.Matches)
+		if err != nil {
+			return fmt.Errorf("condition %d: %w", i+1, err)
+		}
+		cond.matches = matches
+	}
+	for i, domain := range r.BannedEmailDomains {
+		r.BannedEmailDomains[i] = strings.ToLower(strings.TrimSpace(domain))
+	}
+	if r.LeadScoring != nil {
+		return r.LeadScoring.compile()
+	}
+	return nil
+}
+
+// Validate checks the given field values against the rules.
+// Fields are keyed by their JSON names. It returns a *ValidationError listing
+// every violation, or nil when the values pass. A nil Rules accepts everything.
+func (r *Rules) Validate(fields map[string]string) error {
+	if r == nil {
+		return nil
+	}
+
+	var violations []Violation
+
+	for field, rule := range r.Fields {
+		if value := fields[field]; value != "" && !rule.pattern.MatchString(value) {
+			message := rule.Message
+			if message == "" {
+				message = "does not match the required format"
+			}
+			violations = append(violations, Violation{Field: field, Message: message})
+		}
+	}
+
+	if email := strings.ToLower(fields["email"]); email != "" {
+		domain := email[strings.LastIndex(email, "@")+1:]
+		if helpers.MatchesDomain(domain, r.BannedEmailDomains) {
+			violations = append(violations, Violation{Field: "email", Message: "email domain is not accepted"})
+		}
+	}
+
+	for _, cond := range r.Conditions {
+		if !cond.matches.MatchString(fields[cond.When.Field]) {
+			continue
+		}
+		for _, required := range cond.Require {
+			if fields[required] == "" {
+				violations = append(violations, Violation{
+					Field:   required,
+					Message: fmt.Sprintf("is required when %s matches %q", cond.When.Field, cond.When.Matches),
+				})
+			}
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
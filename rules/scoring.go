@@ -0,0 +1,122 @@
+// Package rules implements custom validation rules loaded from a configuration file.
+//
+// This file implements the lead scoring of the rules file, which gives points to the
+// submissions most likely to come from prospects, so that sales can handle them first:
+//
+//	lead_scoring:
+//	  company_email: 20
+//	  message_length:
+//	    - { min: 300, points: 10 }
+//	    - { min: 100, points: 5 }
+//	  keywords:
+//	    - { matches: '(?i)\b(pricing|quote|demo)\b', points: 15 }
+//	    - { matches: '(?i)unsubscribe', points: -20 }
+//	  countries:
+//	    - { phone_prefix: '+62', points: 10 }
+package rules
+
+import (
+	"api-contact-form/helpers"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// LeadScoring gives points to submissions. The score of a submission is the sum of the
+// points of every criterion it meets; points may be negative.
+type LeadScoring struct {
+	// CompanyEmail is given to email addresses outside of the free email domains.
+	CompanyEmail int `json:"company_email" yaml:"company_email"`
+	// FreeEmailDomains replaces the built-in list of free email providers,
+	// helpers.FreeEmailDomains, including their subdomains.
+	FreeEmailDomains []string `json:"free_email_domains,omitempty" yaml:"free_email_domains,omitempty"`
+	// MessageLength gives the points of the longest minimum length the message reaches.
+	MessageLength []LengthPoints `json:"message_length" yaml:"message_length"`
+	// Keywords gives the points of every pattern the message matches.
+	Keywords []KeywordPoints `json:"keywords" yaml:"keywords"`
+	// Countries gives the points of the longest prefix the phone number starts with, as the
+	// country calling code tells the country of the submitter.
+	Countries []CountryPoints `json:"countries" yaml:"countries"`
+}
+
+// LengthPoints gives points to messages of at least Min characters.
+type LengthPoints struct {
+	Min    int `json:"min" yaml:"min"`
+	Points int `json:"points" yaml:"points"`
+}
+
+// KeywordPoints gives points to messages matching a regular expression.
+type KeywordPoints struct {
+	Matches string `json:"matches" yaml:"matches"`
+	Points  int    `json:"points" yaml:"points"`
+
+	matches *regexp.Regexp
+}
+
+// CountryPoints gives points to phone numbers in E.164 format starting with PhonePrefix,
+// such as "+62" for Indonesia.
+type CountryPoints struct {
+	PhonePrefix string `json:"phone_prefix" yaml:"phone_prefix"`
+	Points      int    `json:"points" yaml:"points"`
+}
+
+// compile prepares the regular expressions of the lead scoring and reports invalid ones.
+func (s *LeadScoring) compile() error {
+	for i := range s.Keywords {
+		matches, err := regexp.Compile(s.Keywords[i].Matches)
+		if err != nil {
+			return fmt.Errorf("lead scoring keyword %d: %w", i+1, err)
+		}
+		s.Keywords[i].matches = matches
+	}
+	for i, domain := range s.FreeEmailDomains {
+		s.FreeEmailDomains[i] = strings.ToLower(strings.TrimSpace(domain))
+	}
+	return nil
+}
+
+// Score returns the lead score of the given field values, keyed by their JSON names.
+// Rules without lead scoring, and a nil Rules, score every submission 0.
+func (r *Rules) Score(fields map[string]string) int {
+	if r == nil || r.LeadScoring == nil {
+		return 0
+	}
+	s := r.LeadScoring
+	score := 0
+
+	if domain := helpers.EmailDomain(fields["email"]); domain != "" {
+		freeDomains := s.FreeEmailDomains
+		if len(freeDomains) == 0 {
+			freeDomains = helpers.FreeEmailDomains
+		}
+		if !helpers.MatchesDomain(domain, freeDomains) {
+			score += s.CompanyEmail
+		}
+	}
+
+	message := fields["message"]
+	length, longest := utf8.RuneCountInString(message), -1
+	lengthPoints := 0
+	for _, l := range s.MessageLength {
+		if length >= l.Min && l.Min > longest {
+			longest, lengthPoints = l.Min, l.Points
+		}
+	}
+	score += lengthPoints
+
+	for _, keyword := range s.Keywords {
+		if keyword.matches.MatchString(message) {
+			score += keyword.Points
+		}
+	}
+
+	phone := fields["phone"]
+	prefix, points := "", 0
+	for _, country := range s.Countries {
+		if strings.HasPrefix(phone, country.PhonePrefix) && len(country.PhonePrefix) > len(prefix) {
+			prefix, points = country.PhonePrefix, country.Points
+		}
+	}
+	return score + points
+}
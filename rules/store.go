@@ -0,0 +1,148 @@
+// Package rules implements custom validation rules loaded from a configuration file.
+//
+// This file provides the Store, which loads the rules file and hot-reloads it when
+// it changes on disk, and Save, which writes a rules file.
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Store holds the currently active Rules of a rules file.
+// It is safe for concurrent use.
+type Store struct {
+	path    string
+	current atomic.Pointer[Rules]
+	modTime time.Time
+}
+
+// NewStore creates a Store for the rules file at path and loads it.
+// Files ending in .json are parsed as JSON, anything else as YAML.
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Current returns the active rules. A nil Store has no rules.
+func (s *Store) Current() *Rules {
+	if s == nil {
+		return nil
+	}
+	return s.current.Load()
+}
+
+// Watch reloads the rules file every interval when its modification time changed,
+// until ctx is cancelled. A file that fails to load is logged and the previously
+// loaded rules stay active.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(s.path)
+		if err != nil {
+			log.Printf("Rules file %s not readable: %v", s.path, err)
+			continue
+		}
+		if info.ModTime().Equal(s.modTime) {
+			continue
+		}
+
+		if err := s.load(); err != nil {
+			log.Printf("Rules file %s not reloaded: %v", s.path, err)
+			continue
+		}
+		log.Printf("Rules file %s reloaded", s.path)
+	}
+}
+
+// load reads, parses and activates the rules file.
+func (s *Store) load() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	var rules Rules
+	if filepath.Ext(s.path) == ".json" {
+		err = json.Unmarshal(data, &rules)
+	} else {
+		err = yaml.Unmarshal(data, &rules)
+	}
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	if err := rules.Compile(); err != nil {
+		return fmt.Errorf("compile %s: %w", s.path, err)
+	}
+
+	s.current.Store(&rules)
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// Replace writes rules to the rules file and activates them immediately, without waiting
+// for the file to be reloaded.
+func (s *Store) Replace(rules *Rules) error {
+	if err := Save(s.path, rules); err != nil {
+		return err
+	}
+	s.current.Store(rules)
+	return nil
+}
+
+// Save compiles rules and writes them to the file at path, as JSON for files ending in
+// .json and as YAML otherwise. The file is replaced by renaming a temporary file, so that
+// a Store watching it never loads a partially written file.
+func Save(path string, rules *Rules) error {
+	if err := rules.Compile(); err != nil {
+		return err
+	}
+
+	var data []byte
+	var err error
+	if filepath.Ext(path) == ".json" {
+		data, err = json.MarshalIndent(rules, "", "  ")
+	} else {
+		data, err = yaml.Marshal(rules)
+	}
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".rules-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,51 @@
+package rules_test
+
+import (
+	"api-contact-form/rules"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStoreRejectsMalformedFiles(t *testing.T) {
+	tests := []struct {
+		name, file, content string
+	}{
+		{"empty field rule", "rules.yaml", "fields:\n  phone:\n"},
+		{"null condition", "rules.yaml", "conditions:\n  - null\n"},
+		{"null field rule in JSON", "rules.json", `{"fields": {"phone": null}}`},
+		{"null condition in JSON", "rules.json", `{"conditions": [null]}`},
+		{"invalid pattern", "rules.yaml", "fields:\n  phone:\n    pattern: '['\n"},
+		{"not a mapping", "rules.yaml", "- phone\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := rules.NewStore(path); err == nil {
+				t.Errorf("NewStore(%q) = nil error, want an error", tt.content)
+			}
+		})
+	}
+}
+
+func TestNewStoreLoadsRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	content := "fields:\n  phone:\n    pattern: '^[0-9]+$'\nconditions:\n  - when: { field: message, matches: '(?i)call me' }\n    require: [phone]\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := rules.NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+
+	if err := store.Current().Validate(map[string]string{"phone": "12345", "message": "hello"}); err != nil {
+		t.Errorf("Validate(valid) = %v, want nil", err)
+	}
+	if err := store.Current().Validate(map[string]string{"message": "Please call me"}); err == nil {
+		t.Error("Validate(missing phone) = nil, want a violation")
+	}
+}
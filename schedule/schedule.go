@@ -0,0 +1,168 @@
+// Package schedule runs background jobs at the times given by cron expressions.
+//
+// Expressions have the five standard fields, minute, hour, day of month, month and day of
+// week (0 or 7 is Sunday), each a "*", a value, a range "a-b" or a comma-separated list
+// of those, optionally with a step "/n". The shortcuts @hourly, @daily, @weekly and
+// @monthly are accepted as well. As in cron, a day matches when either the day of month
+// or the day of week matches, if both are restricted.
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// shortcuts maps the supported shortcuts to their expressions.
+var shortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// maxLookahead bounds the search for the next matching time, for expressions such as
+// "0 0 31 2 *" that never match.
+const maxLookahead = 5 * 366 * 24 * time.Hour
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// anyDay and anyWeekday report whether the day of month or day of week is "*".
+	anyDay, anyWeekday bool
+	location           *time.Location
+}
+
+// Parse parses a cron expression evaluated in loc.
+func Parse(expr string, loc *time.Location) (*Schedule, error) {
+	if shortcut, ok := shortcuts[strings.TrimSpace(expr)]; ok {
+		expr = shortcut
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	s := &Schedule{location: loc, anyDay: fields[2] == "*", anyWeekday: fields[4] == "*"}
+	var err error
+	if s.minutes, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if s.hours, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if s.days, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if s.months, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if s.weekdays, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	// Sunday may be written as 7.
+	if s.weekdays&(1<<7) != 0 {
+		s.weekdays |= 1
+	}
+	return s, nil
+}
+
+// parseField returns the bit set of the values of a field between min and max.
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		// Find the bounds of the range.
+		low, high := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid value %q", from)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid value %q", to)
+				}
+			} else if hasStep {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is out of range %d-%d", rangePart, min, max)
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first time after t matching the schedule, or the zero time when none
+// matches within five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxLookahead)
+
+	// Skip whole months, days and hours that do not match before checking minutes.
+	for t.Before(limit) {
+		switch {
+		case s.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+		case s.hours&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+		case s.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay reports whether the day of t matches the day of month and day of week fields.
+func (s *Schedule) matchesDay(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// Run calls job at every time of the schedule until ctx is cancelled. Runs never overlap:
+// a run taking longer than the interval delays the next one.
+func Run(ctx context.Context, name string, s *Schedule, job func()) {
+	for {
+		next := s.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Job %s has no next run time", name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		job()
+	}
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"api-contact-form/router"
+	"api-contact-form/router/echorouter"
+	"api-contact-form/router/ginrouter"
+	"api-contact-form/router/nethttp"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+// Routers lists the HTTP frameworks that can serve the routes of the API, as named in the
+// HTTP_ROUTER setting.
+var Routers = []string{"gin", "nethttp", "echo"}
+
+// NewAdapter returns the router.Adapter of the HTTP framework named name: "gin", "nethttp"
+// for the standard library alone, or "echo".
+func NewAdapter(name string) (router.Adapter, error) {
+	switch name {
+	case "gin":
+		return ginrouter.New(gin.New()), nil
+	case "nethttp":
+		return nethttp.New(), nil
+	case "echo":
+		return echorouter.New(echo.New()), nil
+	}
+	return nil, fmt.Errorf("unknown router %q, expected one of %v", name, Routers)
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Mount serves handler, such as the Handler of a Server, under prefix on a net/http
+// ServeMux, such as "/api/contact-form". The prefix is stripped before the request reaches
+// handler, which sees the paths of the API, such as "/contacts". An empty prefix serves
+// handler for every path mux does not route elsewhere.
+func Mount(mux *http.ServeMux, prefix string, handler http.Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		mux.Handle("/", handler)
+		return
+	}
+	mux.Handle(prefix+"/", http.StripPrefix(prefix, handler))
+}
+
+// MountGin serves handler under prefix on a Gin router or route group, like Mount. The
+// prefix must not be empty, as Gin does not let a catch-all route share its path with
+// other routes.
+func MountGin(router gin.IRouter, prefix string, handler http.Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	router.Any(prefix+"/*path", func(c *gin.Context) {
+		r := c.Request.Clone(c.Request.Context())
+		r.URL.Path = c.Param("path")
+		r.URL.RawPath = ""
+		handler.ServeHTTP(c.Writer, r)
+	})
+}
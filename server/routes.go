@@ -0,0 +1,34 @@
+package server
+
+import (
+	"api-contact-form/handlers"
+	"api-contact-form/router"
+)
+
+// ContactRoutes registers the routes reading and managing the stored contacts, shared by
+// the API and the backend embedded with package contactform. The routes reading contacts
+// are registered on reader, the others on admin; lowPriority guards the listings and
+// exports, which are shed first under load.
+func ContactRoutes(reader, admin router.Router, lowPriority []router.HandlerFunc, contacts *handlers.ContactHandler, exports *handlers.ExportHandler) {
+	reader.GET("/contacts", append(lowPriority, contacts.GetContacts)...)
+	reader.GET("/contacts/search", append(lowPriority, contacts.SearchContacts)...)
+	reader.GET("/contacts/:id", contacts.GetContact)
+	admin.GET("/contacts/trash", append(lowPriority, contacts.GetDeletedContacts)...)
+	admin.GET("/contacts/export", append(lowPriority, exports.ExportContacts)...)
+	admin.GET("/contacts/duplicates", append(lowPriority, contacts.GetDuplicates)...)
+	admin.POST("/contacts/merge", contacts.MergeContacts)
+	admin.POST("/contacts/bulk-delete", contacts.DeleteContacts)
+	admin.POST("/contacts/bulk-status", contacts.UpdateStatuses)
+	admin.POST("/contacts/import", contacts.ImportContacts)
+	admin.PUT("/contacts/:id", contacts.UpdateContact)
+	admin.DELETE("/contacts/:id", contacts.DeleteContact)
+	admin.PATCH("/contacts/:id/status", contacts.UpdateStatus)
+	admin.PUT("/contacts/:id/legal-hold", contacts.SetLegalHold)
+	admin.PUT("/contacts/:id/pin", contacts.SetPinned)
+	admin.POST("/contacts/:id/snooze", contacts.SnoozeContact)
+	admin.GET("/contacts/:id/emails", contacts.GetEmailThread)
+	admin.PUT("/contacts/:id/star", contacts.StarContact)
+	admin.DELETE("/contacts/:id/star", contacts.UnstarContact)
+	admin.POST("/contacts/:id/restore", contacts.RestoreContact)
+	admin.DELETE("/contacts/:id/purge", contacts.PurgeContact)
+}
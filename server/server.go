@@ -0,0 +1,1022 @@
+// Package server wires the repositories, services, handlers and middleware of the API
+// into an http.Handler, configured from the environment as documented in .env.example.
+//
+// The routes are matched by the HTTP framework named by HTTP_ROUTER; see NewAdapter. The
+// binary serves the handler on its own listener. Other Go programs serve the whole API
+// from their existing HTTP server by mounting it on their router: on a net/http ServeMux
+// with Mount, or on a Gin router with MountGin. Programs needing only the contacts, with
+// their settings given in code, embed package contactform instead.
+package server
+
+import (
+	"api-contact-form/challenges"
+	"api-contact-form/config"
+	"api-contact-form/connectors"
+	"api-contact-form/enrichment"
+	"api-contact-form/faults"
+	"api-contact-form/handlers"
+	"api-contact-form/helpers"
+	"api-contact-form/hooks"
+	"api-contact-form/ids"
+	"api-contact-form/inboxfeed"
+	"api-contact-form/jobs"
+	"api-contact-form/middleware"
+	"api-contact-form/models"
+	"api-contact-form/notifications"
+	"api-contact-form/observability"
+	"api-contact-form/presence"
+	"api-contact-form/publicid"
+	"api-contact-form/region"
+	"api-contact-form/repositories"
+	"api-contact-form/router"
+	"api-contact-form/rules"
+	"api-contact-form/schedule"
+	"api-contact-form/services"
+	"api-contact-form/startup"
+	"api-contact-form/storage"
+	"api-contact-form/thumbnails"
+	"api-contact-form/webhooks"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Config holds what New needs besides the database and the environment.
+type Config struct {
+	// Region is the region the server runs in, whose standby role makes the API read-only.
+	Region *region.Region
+	// Startup tracks the initialization for the startup probe. New marks the "scheduler"
+	// step done; the caller marks the others.
+	Startup *startup.Tracker
+	// Logger writes the request logs.
+	Logger *slog.Logger
+}
+
+// Server is the API wired on a database, with the background workers it started.
+type Server struct {
+	// Handler serves every route of the API.
+	Handler http.Handler
+
+	health    *handlers.HealthHandler
+	closers   []func()
+	waiters   []func()
+	warmCache func(ctx context.Context)
+}
+
+// New wires the API on db, reading its settings from the environment, and starts its
+// background workers, such as the webhook deliveries and the scheduled jobs, until workers
+// is done. When New fails, the caller cancels workers to stop the ones already started.
+func New(workers context.Context, db *gorm.DB, cfg Config) (*Server, error) {
+	if cfg.Region == nil || cfg.Startup == nil || cfg.Logger == nil {
+		return nil, errors.New("server: a region, a startup tracker and a logger are required")
+	}
+
+	// Generate the primary keys and public IDs of new rows according to the ID strategies,
+	// and give a public ID to the contacts created before public IDs were generated.
+	idStrategy := ids.Strategy(config.GetEnv("ID_STRATEGY", string(ids.StrategyAutoIncrement)))
+	primaryIDs, err := ids.New(idStrategy, uint(helpers.GetEnvInt("ID_NODE", 0)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID_STRATEGY or ID_NODE: %w", err)
+	}
+	// Send Snowflake IDs as strings, as JavaScript numbers would round them.
+	ids.SetJSONStrings(idStrategy == ids.StrategySnowflake)
+	publicIDsEnabled := helpers.GetEnvBool("PUBLIC_IDS_ENABLED", false)
+	publicIDStrategy := config.GetEnv("PUBLIC_ID_STRATEGY", "sqids")
+	if publicIDStrategy != "sqids" && publicIDStrategy != string(ids.StrategyUUIDv7) {
+		return nil, fmt.Errorf("invalid PUBLIC_ID_STRATEGY %q, expected sqids or uuidv7", publicIDStrategy)
+	}
+	idPlugin := ids.Plugin{Primary: primaryIDs}
+	if publicIDsEnabled && publicIDStrategy == string(ids.StrategyUUIDv7) {
+		idPlugin.PublicID = ids.NewUUIDv7
+	}
+	if err := db.Use(idPlugin); err != nil {
+		return nil, fmt.Errorf("register the ID generator: %w", err)
+	}
+	if idPlugin.PublicID != nil && !cfg.Region.Standby() {
+		backfilled, err := ids.BackfillPublicIDs(context.Background(), db, &models.Contact{}, idPlugin.PublicID)
+		if err != nil {
+			return nil, fmt.Errorf("backfill the public IDs of contacts: %w", err)
+		}
+		if backfilled > 0 {
+			log.Printf("Gave a public ID to %d existing contacts", backfilled)
+		}
+	}
+
+	// Simulate failures of the database, the SMTP server and the webhook receivers when
+	// fault injection is enabled, to exercise the retries and outbox in staging.
+	faultInjector, err := faults.NewFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("invalid fault injection settings: %w", err)
+	}
+	if faultInjector != nil {
+		if err := db.Use(faultInjector); err != nil {
+			return nil, fmt.Errorf("register the database fault injection: %w", err)
+		}
+	}
+	smtpConfig := config.LoadSMTPConfig()
+	if faultInjector != nil {
+		smtpConfig.SendMail = faultInjector.SendMail(nil)
+	}
+
+	// Load the optional custom validation rules and reload them when the file changes.
+	var rulesStore *rules.Store
+	if rulesFile := config.GetEnv("RULES_FILE", ""); rulesFile != "" {
+		rulesStore, err = rules.NewStore(rulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("load rules file: %w", err)
+		}
+		go rulesStore.Watch(workers, helpers.GetEnvDuration("RULES_RELOAD_INTERVAL", 30*time.Second))
+	}
+
+	// Start the optional notifications sent for every new contact.
+	var channels []notifications.Notifier
+	if helpers.GetEnvBool("NOTIFY_EMAIL_ENABLED", false) {
+		emailNotifier, err := notifications.NewEmailNotifier(smtpConfig)
+		if err != nil {
+			return nil, fmt.Errorf("configure email notifications: %w", err)
+		}
+		channels = append(channels, emailNotifier)
+	}
+	if helpers.GetEnvBool("NOTIFY_TEAMS_ENABLED", false) {
+		teamsNotifier, err := notifications.NewTeamsNotifier(config.LoadTeamsConfig())
+		if err != nil {
+			return nil, fmt.Errorf("configure Teams notifications: %w", err)
+		}
+		channels = append(channels, teamsNotifier)
+	}
+	if helpers.GetEnvBool("NOTIFY_MATRIX_ENABLED", false) {
+		matrixNotifier, err := notifications.NewMatrixNotifier(config.LoadMatrixConfig())
+		if err != nil {
+			return nil, fmt.Errorf("configure Matrix notifications: %w", err)
+		}
+		channels = append(channels, matrixNotifier)
+	}
+	var notifier *notifications.Dispatcher
+	if len(channels) > 0 {
+		notifier = notifications.NewDispatcher("notifications", channels,
+			helpers.GetEnvInt("NOTIFY_MAX_ATTEMPTS", 5),
+			helpers.GetEnvDuration("NOTIFY_RETRY_BACKOFF", 10*time.Second),
+			helpers.GetEnvInt("NOTIFY_QUEUE_SIZE", 1000),
+		)
+		notifier.Coalesce(
+			helpers.GetEnvInt("NOTIFY_COALESCE_THRESHOLD", 0),
+			helpers.GetEnvDuration("NOTIFY_COALESCE_WINDOW", 5*time.Minute),
+		)
+		notifier.Start(workers, helpers.GetEnvInt("NOTIFY_WORKERS", 2))
+	}
+
+	// Start the optional auto-replies emailed to submitters in the language of their contact.
+	autoReplyTemplates := repositories.NewAutoReplyTemplateRepository(db)
+	emailMessages := repositories.NewEmailMessageRepository(db)
+	var autoReplies *notifications.Dispatcher
+	if helpers.GetEnvBool("AUTO_REPLY_ENABLED", false) {
+		autoReplier, err := notifications.NewAutoReplier(smtpConfig, autoReplyTemplates, emailMessages,
+			config.GetEnv("AUTO_REPLY_DEFAULT_LANGUAGE", "en"))
+		if err != nil {
+			return nil, fmt.Errorf("configure auto-replies: %w", err)
+		}
+		autoReplies = notifications.NewDispatcher("auto_replies", []notifications.Notifier{autoReplier},
+			helpers.GetEnvInt("NOTIFY_MAX_ATTEMPTS", 5),
+			helpers.GetEnvDuration("NOTIFY_RETRY_BACKOFF", 10*time.Second),
+			helpers.GetEnvInt("NOTIFY_QUEUE_SIZE", 1000),
+		)
+		autoReplies.Start(workers, helpers.GetEnvInt("NOTIFY_WORKERS", 2))
+	}
+
+	// Start the delivery of contact lifecycle events to the registered webhooks.
+	webhookRepository := repositories.NewWebhookRepository(db)
+	webhookDispatcher := webhooks.NewDispatcher(webhookRepository,
+		helpers.GetEnvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		helpers.GetEnvDuration("WEBHOOK_RETRY_BACKOFF", 10*time.Second),
+		helpers.GetEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		helpers.GetEnvInt("WEBHOOK_QUEUE_SIZE", 1000),
+	)
+	if faultInjector != nil {
+		webhookDispatcher.SetTransport(faultInjector.Transport(nil))
+	}
+	webhookDispatcher.Start(workers, helpers.GetEnvInt("WEBHOOK_WORKERS", 2))
+	if outboxRetention := helpers.GetEnvDuration("WEBHOOK_OUTBOX_RETENTION", 7*24*time.Hour); outboxRetention > 0 {
+		go webhookDispatcher.PruneOutbox(workers, outboxRetention)
+	}
+	webhookReplayer := webhooks.NewReplayer(webhookDispatcher, float64(max(helpers.GetEnvInt("WEBHOOK_REPLAY_RATE", 5), 1)))
+	go webhookReplayer.Run(workers)
+
+	// Initialize repositories, services, and handlers.
+	mainHandler := handlers.NewMainHandler()
+	healthHandler := handlers.NewHealthHandler(db, helpers.GetEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second), cfg.Startup)
+	contactRepository := repositories.NewContactRepository(db)
+	auditLogRepository := repositories.NewAuditLogRepository(db)
+	apiUsageRepository := repositories.NewAPIUsageRepository(db)
+	deleteMode := services.DeleteMode(config.GetEnv("CONTACT_DELETE_MODE", string(services.DeleteSoft)))
+	if !deleteMode.Valid() {
+		return nil, fmt.Errorf("invalid CONTACT_DELETE_MODE %q, expected soft or hard", deleteMode)
+	}
+
+	// Derive the company of submitters from their email domain, and look it up with the
+	// company data API when one is configured.
+	var enricher *enrichment.Enricher
+	if helpers.GetEnvBool("ENRICHMENT_ENABLED", false) {
+		var provider enrichment.Provider
+		if apiURL := config.GetEnv("ENRICHMENT_API_URL", ""); apiURL != "" {
+			apiProvider, err := enrichment.NewAPIProvider(apiURL, config.GetEnv("ENRICHMENT_API_KEY", ""))
+			if err != nil {
+				return nil, fmt.Errorf("configure the enrichment: %w", err)
+			}
+			provider = apiProvider
+		}
+		enricher = enrichment.NewEnricher(provider, contactRepository,
+			helpers.GetEnvDuration("ENRICHMENT_TIMEOUT", 5*time.Second),
+			helpers.GetEnvDuration("ENRICHMENT_CACHE_TTL", 24*time.Hour),
+			helpers.GetEnvInt("ENRICHMENT_QUEUE_SIZE", 1000),
+		)
+		enricher.Start(workers, helpers.GetEnvInt("ENRICHMENT_WORKERS", 1))
+	}
+	// Keep the inbox projection up to date, and build it on the first start with it; its
+	// changes are pushed to the admin view through the inbox feed.
+	inboxRepository := repositories.NewInboxRepository(db)
+	inboxFeed := inboxfeed.NewFeed()
+	inboxService := services.NewInboxService(inboxRepository)
+	go func() {
+		if err := inboxService.RebuildInboxIfEmpty(workers); err != nil {
+			log.Printf("Failed to build the inbox: %v", err)
+		}
+	}()
+	rejectionRepository := repositories.NewRejectionRepository(db)
+	consentRequired := helpers.GetEnvBool("CONSENT_REQUIRED", false)
+	contactServiceOptions := []services.ContactServiceOption{
+		services.WithConsentRequired(consentRequired),
+		services.WithPolicyVersions(config.GetEnv("PRIVACY_POLICY_VERSION", ""), config.GetEnv("TERMS_VERSION", "")),
+		services.WithRules(rulesStore),
+		services.WithHooks(hooks.Default),
+		services.WithNotifier(notifier),
+		services.WithAutoReplies(autoReplies),
+		services.WithEmailDailyLimit(helpers.GetEnvInt("EMAIL_DAILY_LIMIT", 0)),
+		services.WithReplaySignals(rejectionRepository, helpers.GetEnvInt("REPLAY_SPAM_THRESHOLD", 1)),
+		services.WithDuplicatePolicy(services.DuplicatePolicy{
+			Window: helpers.GetEnvDuration("DUPLICATE_WINDOW", 0),
+			Reject: config.GetEnv("DUPLICATE_ACTION", "flag") == "reject",
+		}),
+		services.WithDeleteMode(deleteMode),
+		services.WithWebhooks(webhookDispatcher),
+		services.WithEnrichment(enricher),
+		services.WithInboxProjection(services.NewInboxProjection(inboxRepository, inboxFeed)),
+		services.WithAuditLog(auditLogRepository),
+		services.WithEmailThreads(emailMessages),
+		services.WithRetentionPolicy(services.RetentionPolicy{
+			DeletedFor:     time.Duration(helpers.GetEnvInt("RETENTION_DELETED_DAYS", 0)) * 24 * time.Hour,
+			ResolvedFor:    time.Duration(helpers.GetEnvInt("RETENTION_RESOLVED_DAYS", 0)) * 24 * time.Hour,
+			AttachmentsFor: time.Duration(helpers.GetEnvInt("RETENTION_ATTACHMENT_DAYS", 0)) * 24 * time.Hour,
+			BatchSize:      helpers.GetEnvInt("RETENTION_BATCH_SIZE", 500),
+		}),
+	}
+
+	// Accept the optional file attachments of submissions.
+	maxBodySize := int64(helpers.GetEnvInt("REQUEST_MAX_BODY_SIZE", 1<<20))
+	var attachmentHandler *handlers.AttachmentHandler
+	var attachmentPolicy *services.AttachmentPolicy
+	var thumbnailGenerator *thumbnails.Generator
+	if helpers.GetEnvBool("ATTACHMENTS_ENABLED", false) {
+		policy := services.AttachmentPolicy{
+			MaxCount:     helpers.GetEnvInt("ATTACHMENT_MAX_COUNT", 5),
+			MaxSize:      int64(helpers.GetEnvInt("ATTACHMENT_MAX_SIZE", 5<<20)),
+			AllowedTypes: helpers.ParseEnvList("ATTACHMENT_ALLOWED_TYPES"),
+		}
+		if len(policy.AllowedTypes) == 0 {
+			policy.AllowedTypes = []string{"image/png", "image/jpeg", "application/pdf"}
+		}
+		attachmentStore, err := storage.NewFromEnv()
+		if err != nil {
+			return nil, fmt.Errorf("configure attachment storage: %w", err)
+		}
+		attachmentPolicy = &policy
+		// Generate the previews of the images in the background, unless THUMBNAIL_SIZE is 0.
+		attachmentRepository := repositories.NewAttachmentRepository(db)
+		if size := helpers.GetEnvInt("THUMBNAIL_SIZE", 320); size > 0 {
+			thumbnailGenerator = thumbnails.NewGenerator(attachmentRepository, attachmentStore, size,
+				helpers.GetEnvInt("THUMBNAIL_QUEUE_SIZE", 1000))
+			thumbnailGenerator.Start(workers, helpers.GetEnvInt("THUMBNAIL_WORKERS", 1))
+		}
+		attachmentService := services.NewAttachmentService(attachmentRepository, attachmentStore, policy, thumbnailGenerator)
+		attachmentHandler = handlers.NewAttachmentHandler(attachmentService)
+		contactServiceOptions = append(contactServiceOptions, services.WithAttachments(attachmentService))
+		maxBodySize += int64(policy.MaxCount) * policy.MaxSize
+	}
+
+	contactService := services.NewContactService(contactRepository, contactServiceOptions...)
+	// Show submitters non-guessable references instead of the IDs when enabled: the IDs
+	// encoded with Sqids, or the generated UUIDv7 public IDs.
+	var publicIDs publicid.Scheme
+	if publicIDsEnabled {
+		if publicIDStrategy == string(ids.StrategyUUIDv7) {
+			publicIDs = publicid.Generated{}
+		} else {
+			encoder, err := publicid.New(
+				config.GetEnv("PUBLIC_ID_ALPHABET", publicid.DefaultAlphabet),
+				helpers.GetEnvInt("PUBLIC_ID_MIN_LENGTH", 8),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("invalid PUBLIC_ID_ALPHABET or PUBLIC_ID_MIN_LENGTH: %w", err)
+			}
+			publicIDs = encoder
+		}
+	}
+	// Queue the submissions received during short database outages when the write-behind
+	// buffer is enabled, and store them once the database is back.
+	var submissionBuffer *services.SubmissionBuffer
+	if bufferSize := helpers.GetEnvInt("SUBMISSION_BUFFER_SIZE", 0); bufferSize > 0 {
+		submissionBuffer = services.NewSubmissionBuffer(contactService,
+			func(ctx context.Context) error {
+				sqlDB, err := db.DB()
+				if err != nil {
+					return err
+				}
+				return sqlDB.PingContext(ctx)
+			},
+			bufferSize,
+			helpers.GetEnvDuration("SUBMISSION_BUFFER_FLUSH_INTERVAL", 5*time.Second),
+		)
+		go submissionBuffer.Run(workers)
+	}
+	contactHandler := handlers.NewContactHandler(contactService, publicIDs, submissionBuffer)
+	gdprHandler := handlers.NewGDPRHandler(contactService)
+	exportHashKey := []byte(config.GetEnv("EXPORT_HASH_KEY", ""))
+	exportHandler := handlers.NewExportHandler(contactService, exportHashKey)
+	// Run the scheduled exports when they are due, delivering the reports by email or to S3.
+	var reportDelivery services.ReportDelivery
+	if mailer, err := notifications.NewReportMailer(smtpConfig); err == nil {
+		reportDelivery.Mailer = mailer
+	}
+	if bucket := config.GetEnv("EXPORT_S3_BUCKET", ""); bucket != "" {
+		reportDelivery.Storage, err = storage.NewS3Storage(storage.S3Config{
+			Endpoint:  config.GetEnv("S3_ENDPOINT", "s3.amazonaws.com"),
+			Region:    config.GetEnv("S3_REGION", ""),
+			Bucket:    bucket,
+			AccessKey: config.GetEnv("S3_ACCESS_KEY", ""),
+			SecretKey: config.GetEnv("S3_SECRET_KEY", ""),
+			UseSSL:    helpers.GetEnvBool("S3_USE_SSL", true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("configure the export bucket: %w", err)
+		}
+		reportDelivery.Bucket = bucket
+	}
+	exportScheduleService := services.NewExportScheduleService(repositories.NewExportScheduleRepository(db), contactService, reportDelivery, exportHashKey)
+	everyMinute, _ := schedule.Parse("* * * * *", helpers.AppTimezone())
+	go schedule.Run(workers, "export-schedules", everyMinute, func() {
+		exportScheduleService.RunDue(workers)
+	})
+	exportScheduleHandler := handlers.NewExportScheduleHandler(exportScheduleService)
+	// Record the settings changes and template edits made through the API in the changelog,
+	// next to the schema changes recorded by the migrations.
+	changeLogService := services.NewChangeLogService(repositories.NewSystemChangeRepository(db))
+	systemChangeHandler := handlers.NewSystemChangeHandler(changeLogService)
+	regionHandler := handlers.NewRegionHandler(cfg.Region, changeLogService)
+	settingsHandler := handlers.NewSettingsHandler(db, rulesStore, changeLogService)
+	auditLogHandler := handlers.NewAuditLogHandler(services.NewAuditService(auditLogRepository))
+	webhookHandler := handlers.NewWebhookHandler(services.NewWebhookService(webhookRepository, webhookReplayer), changeLogService)
+	replyDraftHandler := handlers.NewReplyDraftHandler(services.NewReplyDraftService(
+		repositories.NewReplyDraftRepository(db), contactRepository,
+		helpers.GetEnvDuration("REPLY_DRAFT_LOCK", 30*time.Minute),
+	))
+	followUpHandler := handlers.NewFollowUpHandler(services.NewFollowUpService(
+		repositories.NewFollowUpRepository(db), contactRepository,
+		repositories.NewAdminUserRepository(db), repositories.NewAPIKeyRepository(db),
+		challengeSecret("FOLLOW_UP_FEED_SECRET"),
+	), config.GetEnv("FOLLOW_UP_CALENDAR_NAME", "Contact follow-ups"))
+	// Track the agents viewing or replying to contacts, forgetting them once their heartbeats stop.
+	presenceTracker := presence.NewTracker(helpers.GetEnvDuration("PRESENCE_TTL", 30*time.Second))
+	go presenceTracker.Run(workers)
+	presenceHandler := handlers.NewPresenceHandler(presenceTracker, contactService)
+	inboxHandler := handlers.NewInboxHandler(inboxService)
+	autoReplyHandler := handlers.NewAutoReplyHandler(services.NewAutoReplyService(autoReplyTemplates), changeLogService)
+	apiUsageHandler := handlers.NewAPIUsageHandler(services.NewAPIUsageService(apiUsageRepository))
+	inboundEmailToken := config.GetEnv("INBOUND_EMAIL_TOKEN", "")
+	inboundEmailHandler := handlers.NewInboundEmailHandler(contactService, inboundEmailToken)
+
+	// Return the snoozed contacts to the inbox once their snooze ends.
+	snoozeSchedule, err := schedule.Parse(config.GetEnv("SNOOZE_SCHEDULE", "* * * * *"), helpers.AppTimezone())
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNOOZE_SCHEDULE: %w", err)
+	}
+	go schedule.Run(workers, "snooze", snoozeSchedule, func() {
+		if _, err := contactService.EndSnoozes(workers, services.SnoozeActor); err != nil {
+			log.Printf("Snooze job failed: %v", err)
+		}
+	})
+
+	// Run the optional data retention job on its schedule.
+	if retentionSchedule := config.GetEnv("RETENTION_SCHEDULE", ""); retentionSchedule != "" {
+		sched, err := schedule.Parse(retentionSchedule, helpers.AppTimezone())
+		if err != nil {
+			return nil, fmt.Errorf("invalid RETENTION_SCHEDULE: %w", err)
+		}
+		dryRun := helpers.GetEnvBool("RETENTION_DRY_RUN", false)
+		go schedule.Run(workers, "retention", sched, func() {
+			if _, err := contactService.ApplyRetention(workers, services.RetentionActor, dryRun); err != nil {
+				log.Printf("Retention job failed: %v", err)
+			}
+		})
+	}
+
+	// Start the optional IMAP poller for teams that cannot configure inbound webhooks.
+	if helpers.GetEnvBool("IMAP_ENABLED", false) {
+		imapPoller := connectors.NewIMAPPoller(connectors.IMAPConfig{
+			Host:     config.GetEnv("IMAP_HOST", ""),
+			Port:     config.GetEnv("IMAP_PORT", "993"),
+			Username: config.GetEnv("IMAP_USERNAME", ""),
+			Password: config.GetEnv("IMAP_PASSWORD", ""),
+			Mailbox:  config.GetEnv("IMAP_MAILBOX", "INBOX"),
+			Interval: helpers.GetEnvDuration("IMAP_POLL_INTERVAL", time.Minute),
+		}, contactService)
+		go imapPoller.Run(workers)
+	}
+	cfg.Startup.Done("scheduler")
+
+	// Collect the middleware guarding the public submission endpoint.
+	// Bodies are limited first, so that no guard reads an oversized one.
+	submissionGuards := []router.HandlerFunc{middleware.BodyLimit(maxBodySize)}
+
+	// Open and close the form at the optional times, or once it reached its optional cap
+	// of submissions through the form and the API. Closed forms are checked before the body.
+	opensAt, err := formTime("FORM_OPENS_AT")
+	if err != nil {
+		return nil, err
+	}
+	closesAt, err := formTime("FORM_CLOSES_AT")
+	if err != nil {
+		return nil, err
+	}
+	formSchedule := middleware.NewFormSchedule(opensAt, closesAt,
+		helpers.GetEnvInt("FORM_MAX_SUBMISSIONS", 0),
+		config.GetEnv("FORM_CLOSED_MESSAGE", "This form is closed"),
+		func(ctx context.Context, since time.Time) (int64, error) {
+			return contactRepository.CountByChannelsSince(ctx, []models.Channel{models.ChannelWeb, models.ChannelAPI}, since)
+		},
+	)
+	if formSchedule.Enabled() {
+		submissionGuards = append([]router.HandlerFunc{formSchedule.Middleware()}, submissionGuards...)
+	}
+
+	// Count the requests per caller and endpoint into hourly rollups for the usage statistics.
+	var apiUsageTracker *middleware.APIUsageTracker
+	if helpers.GetEnvBool("API_USAGE_ENABLED", true) {
+		apiUsageTracker = middleware.NewAPIUsageTracker(apiUsageRepository, helpers.GetEnvDuration("API_USAGE_FLUSH_INTERVAL", time.Minute))
+		go apiUsageTracker.Run(workers)
+	}
+
+	// Record the submissions rejected by the spam protection.
+	rejectionLog := middleware.NewRejectionLog(rejectionRepository, 1000)
+	go rejectionLog.Run(workers)
+
+	// Configure the optional per-IP rate limit of submissions.
+	var rateLimiter *middleware.RateLimiter
+	if helpers.GetEnvBool("RATE_LIMIT_ENABLED", false) {
+		rateLimiter = middleware.NewRateLimiter(
+			float64(helpers.GetEnvInt("RATE_LIMIT_PER_MINUTE", 5)),
+			helpers.GetEnvInt("RATE_LIMIT_BURST", 3),
+			rejectionLog,
+		)
+		submissionGuards = append(submissionGuards, rateLimiter.Middleware())
+	}
+
+	// Detect the spikes of submissions above their rolling baseline, alert the notification
+	// channels and optionally tighten the rate limit while they last. Every submission
+	// attempt is counted, including those the other guards reject.
+	if helpers.GetEnvBool("SPIKE_DETECTION_ENABLED", false) {
+		spikeScale := helpers.GetEnvFloat("SPIKE_RATE_LIMIT_SCALE", 1)
+		if spikeScale < 1 && rateLimiter == nil {
+			log.Printf("Warning: SPIKE_RATE_LIMIT_SCALE has no effect without RATE_LIMIT_ENABLED")
+		}
+		spikeDetector, err := middleware.NewSpikeDetector(middleware.SpikeConfig{
+			Interval:          helpers.GetEnvDuration("SPIKE_INTERVAL", time.Minute),
+			BaselineIntervals: helpers.GetEnvInt("SPIKE_BASELINE_INTERVALS", 60),
+			Factor:            helpers.GetEnvFloat("SPIKE_FACTOR", 5),
+			MinSubmissions:    helpers.GetEnvInt("SPIKE_MIN_SUBMISSIONS", 20),
+		}, func(spike middleware.Spike) {
+			alert := notifications.Alert{
+				Key:   fmt.Sprintf("spike-%d-start", spike.StartedAt.Unix()),
+				Title: "Submission spike detected",
+				Text:  spike.Summary() + ". This may be a spam attack or a viral campaign.",
+			}
+			if !spike.Active {
+				alert.Key = fmt.Sprintf("spike-%d-end", spike.StartedAt.Unix())
+				alert.Title = "Submission spike ended"
+				alert.Text = spike.Summary() + "."
+			}
+			if rateLimiter != nil && spikeScale < 1 {
+				scale := 1.0
+				if spike.Active {
+					scale = spikeScale
+					alert.Text += fmt.Sprintf(" The rate limit is tightened to %.0f%% until it ends.", spikeScale*100)
+				}
+				rateLimiter.SetScale(scale)
+			}
+			notifier.Alert(alert)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("configure spike detection: %w", err)
+		}
+		go spikeDetector.Run(workers)
+		submissionGuards = append([]router.HandlerFunc{spikeDetector.Middleware()}, submissionGuards...)
+	}
+
+	// Record the reports of misused embedded forms sent by site visitors, rate limited per IP
+	// address, and email them to ABUSE_REPORT_RECIPIENTS or else to the admin users.
+	abuseReportGuards := []router.HandlerFunc{
+		middleware.BodyLimit(maxBodySize),
+		middleware.NewRateLimiter(
+			float64(helpers.GetEnvInt("ABUSE_REPORT_RATE_PER_MINUTE", 2)),
+			helpers.GetEnvInt("ABUSE_REPORT_RATE_BURST", 3),
+			rejectionLog,
+		).Middleware(),
+	}
+	abuseReportHandler := handlers.NewAbuseReportHandler(services.NewAbuseReportService(
+		repositories.NewAbuseReportRepository(db), repositories.NewAdminUserRepository(db),
+		reportDelivery.Mailer, helpers.ParseEnvList("ABUSE_REPORT_RECIPIENTS"),
+	))
+
+	// Reject the submissions replaying a form token or an Idempotency-Key header after its
+	// validity window, and remember the credentials of accepted ones for REPLAY_RETENTION.
+	idempotencyTTL := helpers.GetEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+	formTokenEnabled := helpers.GetEnvBool("FORM_TOKEN_ENABLED", false)
+	formTokenTTL := helpers.GetEnvDuration("FORM_TOKEN_TTL", time.Hour)
+	if replayRetention := helpers.GetEnvDuration("REPLAY_RETENTION", 30*24*time.Hour); replayRetention > 0 {
+		replayWindows := middleware.ReplayWindows{IdempotencyKey: idempotencyTTL}
+		if formTokenEnabled {
+			replayWindows.FormToken = formTokenTTL
+		}
+		replayGuard := middleware.NewReplayGuard(repositories.NewSeenCredentialRepository(db), replayWindows, replayRetention, rejectionLog)
+		go replayGuard.Run(workers)
+		submissionGuards = append(submissionGuards, replayGuard.Middleware())
+	}
+
+	// Replay the response of a submission repeated with the same Idempotency-Key header.
+	if idempotencyTTL > 0 {
+		idempotency := middleware.NewIdempotency(repositories.NewIdempotencyRepository(db), idempotencyTTL)
+		go idempotency.Run(workers)
+		submissionGuards = append(submissionGuards, idempotency.Middleware())
+	}
+
+	// Configure the optional honeypot field check.
+	if honeypotField := config.GetEnv("HONEYPOT_FIELD", ""); honeypotField != "" {
+		submissionGuards = append(submissionGuards, middleware.Honeypot(honeypotField, rejectionLog))
+	}
+
+	// Configure the optional reCAPTCHA v3 or hCaptcha verification.
+	if captchaProvider := config.GetEnv("CAPTCHA_PROVIDER", ""); captchaProvider != "" {
+		minScore, err := strconv.ParseFloat(config.GetEnv("CAPTCHA_MIN_SCORE", "0.5"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CAPTCHA_MIN_SCORE: %w", err)
+		}
+		captchaVerifier, err := middleware.NewCaptchaVerifier(captchaProvider, config.GetEnv("CAPTCHA_SECRET", ""), minScore, rejectionLog)
+		if err != nil {
+			return nil, fmt.Errorf("configure CAPTCHA verification: %w", err)
+		}
+		submissionGuards = append(submissionGuards, captchaVerifier.Middleware())
+	}
+
+	// Configure the optional proof-of-work challenge.
+	var proofOfWork *challenges.ProofOfWork
+	if helpers.GetEnvBool("POW_ENABLED", false) {
+		proofOfWork = challenges.NewProofOfWork(challengeSecret("POW_SECRET"),
+			helpers.GetEnvInt("POW_DIFFICULTY", 16),
+			helpers.GetEnvDuration("POW_TTL", 5*time.Minute),
+		)
+		submissionGuards = append(submissionGuards, proofOfWork.Middleware())
+	}
+
+	// Configure the optional signed form-render tokens.
+	var formTokens *challenges.FormTokens
+	if formTokenEnabled {
+		formTokens = challenges.NewFormTokens(challengeSecret("FORM_TOKEN_SECRET"),
+			helpers.GetEnvDuration("FORM_TOKEN_MIN_FILL_TIME", 3*time.Second),
+			formTokenTTL,
+		)
+		submissionGuards = append(submissionGuards, formTokens.Middleware())
+	}
+	challengeHandler := handlers.NewChallengeHandler(proofOfWork, formTokens)
+
+	// Describe the contact form, so that frontends render it from its schema.
+	formSchemaHandler := handlers.NewFormSchemaHandler(config.GetEnv("FORM_SLUG", "contact"), handlers.FormSettings{
+		ConsentRequired: consentRequired,
+		PolicyVersion:   config.GetEnv("PRIVACY_POLICY_VERSION", ""),
+		TermsVersion:    config.GetEnv("TERMS_VERSION", ""),
+		Attachments:     attachmentPolicy,
+		CaptchaProvider: config.GetEnv("CAPTCHA_PROVIDER", ""),
+		CaptchaSiteKey:  config.GetEnv("CAPTCHA_SITE_KEY", ""),
+		HoneypotField:   config.GetEnv("HONEYPOT_FIELD", ""),
+		ProofOfWork:     proofOfWork != nil,
+		FormToken:       formTokens != nil,
+	})
+
+	// Configure the optional load shedding of low-priority endpoints, such as listings and
+	// exports, so that they are rejected first when the service is over its budgets.
+	var loadShedder *middleware.LoadShedder
+	var lowPriorityGuards []router.HandlerFunc
+	if helpers.GetEnvBool("LOAD_SHED_ENABLED", false) {
+		loadShedder = middleware.NewLoadShedder(
+			helpers.GetEnvInt("LOAD_SHED_MAX_IN_FLIGHT", 100),
+			helpers.GetEnvDuration("LOAD_SHED_LATENCY_BUDGET", 500*time.Millisecond),
+			helpers.GetEnvDuration("LOAD_SHED_RETRY_AFTER", 5*time.Second),
+		)
+		lowPriorityGuards = append(lowPriorityGuards, loadShedder.Shed())
+	}
+
+	// Configure the authentication of API keys and JWT bearer tokens. Admin routes require
+	// the admin role, and the routes reading contacts the viewer or admin role; anonymous
+	// callers and public keys may only submit contacts.
+	apiKeyService := services.NewAPIKeyService(repositories.NewAPIKeyRepository(db))
+	var setupMailer *notifications.SetupMailer
+	if helpers.GetEnvBool("ADMIN_SETUP_EMAIL_ENABLED", false) {
+		setupMailer, err = notifications.NewSetupMailer(smtpConfig)
+		if err != nil {
+			return nil, fmt.Errorf("configure setup link emails: %w", err)
+		}
+	}
+	passwordPolicy := services.PasswordPolicy{
+		MinLength:     helpers.GetEnvInt("ADMIN_PASSWORD_MIN_LENGTH", 12),
+		RequireUpper:  helpers.GetEnvBool("ADMIN_PASSWORD_REQUIRE_UPPER", false),
+		RequireLower:  helpers.GetEnvBool("ADMIN_PASSWORD_REQUIRE_LOWER", false),
+		RequireDigit:  helpers.GetEnvBool("ADMIN_PASSWORD_REQUIRE_DIGIT", false),
+		RequireSymbol: helpers.GetEnvBool("ADMIN_PASSWORD_REQUIRE_SYMBOL", false),
+	}
+	if helpers.GetEnvBool("ADMIN_PASSWORD_BREACH_CHECK", false) {
+		passwordPolicy.Breaches = services.NewPwnedPasswords()
+	}
+	adminUserService := services.NewAdminUserService(repositories.NewAdminUserRepository(db),
+		repositories.NewAdminSessionRepository(db), repositories.NewAdminLoginEventRepository(db), setupMailer,
+		config.GetEnv("ADMIN_SETUP_URL", ""),
+		helpers.GetEnvDuration("ADMIN_SETUP_TTL", 72*time.Hour),
+		helpers.GetEnvDuration("ADMIN_SESSION_TTL", 30*24*time.Hour),
+		services.TwoFactorPolicy{
+			Required: helpers.GetEnvBool("ADMIN_2FA_REQUIRED", false),
+			Issuer:   config.GetEnv("ADMIN_2FA_ISSUER", "Contact Form"),
+		},
+		services.LoginPolicy{
+			MaxFailures:     helpers.GetEnvInt("ADMIN_LOGIN_MAX_FAILURES", 5),
+			LockoutDuration: helpers.GetEnvDuration("ADMIN_LOGIN_LOCKOUT", 15*time.Minute),
+			MaxIPFailures:   helpers.GetEnvInt("ADMIN_LOGIN_IP_MAX_FAILURES", 20),
+			IPWindow:        helpers.GetEnvDuration("ADMIN_LOGIN_IP_WINDOW", 15*time.Minute),
+		},
+		passwordPolicy,
+	)
+	var authenticator *middleware.Authenticator
+	var adminGuards, readerGuards []router.HandlerFunc
+	if helpers.GetEnvBool("AUTH_ENABLED", true) {
+		authenticator = middleware.NewAuthenticator(apiKeyService, adminUserService,
+			helpers.ParseEnvList("ADMIN_API_KEYS"),
+			[]byte(config.GetEnv("JWT_SECRET", "")),
+			helpers.GetEnvDuration("JWT_TTL", time.Hour),
+		)
+		adminGuards = append(adminGuards, middleware.RequireRole(models.RoleAdmin))
+		readerGuards = append(readerGuards, middleware.RequireRole(models.RoleViewer, models.RoleAdmin))
+	} else {
+		log.Println("AUTH_ENABLED is false; admin endpoints are not protected")
+	}
+	// Redact the contact fields configured for the role of the caller, masking the emails
+	// and phones shown to viewers by default.
+	redactionPolicies := make(map[models.Role]middleware.RedactionPolicy)
+	for role, fallback := range map[models.Role]string{models.RoleViewer: "email,phone", models.RoleAdmin: ""} {
+		key := "REDACT_FIELDS_" + strings.ToUpper(string(role))
+		policy, err := middleware.ParseRedactionPolicy(config.GetEnv(key, fallback))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		redactionPolicies[role] = policy
+	}
+	inboxFeedHandler := handlers.NewInboxFeedHandler(inboxFeed, inboxService, redactionPolicies)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, authenticator)
+	adminUserHandler := handlers.NewAdminUserHandler(adminUserService, authenticator)
+
+	// Create the router on the HTTP framework chosen by HTTP_ROUTER, with the recovery
+	// middleware, correlation IDs, trace context, structured request logs and request metrics.
+	adapter, err := NewAdapter(config.GetEnv("HTTP_ROUTER", "gin"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP_ROUTER: %w", err)
+	}
+	engine := router.New(adapter)
+	engine.Use(middleware.Recovery(), middleware.RequestID())
+	if helpers.GetEnvBool("TRACING_ENABLED", true) {
+		engine.Use(middleware.Tracing())
+	}
+	engine.Use(middleware.RequestLogger(cfg.Logger), middleware.Metrics())
+	if apiUsageTracker != nil {
+		engine.Use(apiUsageTracker.Middleware())
+	}
+
+	// Configure CORS (Cross-Origin Resource Sharing) settings.
+	corsConfig := middleware.CORSConfig{
+		AllowOrigins:     helpers.ParseEnvList("CORS_ALLOWED_ORIGINS"),
+		AllowMethods:     helpers.ParseEnvList("CORS_ALLOWED_METHODS"),
+		AllowHeaders:     helpers.ParseEnvList("CORS_ALLOWED_HEADERS"),
+		AllowCredentials: helpers.GetEnvBool("CORS_ALLOW_CREDENTIALS", false),
+		ExposeHeaders:    helpers.ParseEnvList("CORS_EXPOSE_HEADERS"),
+		MaxAge:           12 * time.Hour,
+	}
+
+	// Apply the CORS middleware to the router.
+	corsMiddleware, err := middleware.CORS(corsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CORS_ALLOWED_ORIGINS: %w", err)
+	}
+	engine.Use(corsMiddleware)
+
+	// Send the writes to the primary region while this one is a standby; only the
+	// promotion itself is served.
+	engine.Use(middleware.ReadOnly(cfg.Region.Standby, "/system/promote"))
+
+	// Rewrite requests and responses for legacy frontends when the compatibility mode is enabled.
+	compatConfig := middleware.CompatConfig{
+		LegacyFieldNames: config.GetEnv("RESPONSE_FIELD_NAMES", "default") == "legacy",
+		Flat:             !helpers.GetEnvBool("RESPONSE_ENVELOPE", true),
+		MaxBodySize:      maxBodySize,
+	}
+	if compatConfig.Enabled() {
+		engine.Use(middleware.Compat(compatConfig))
+	}
+
+	// Measure the load of every request when load shedding is enabled.
+	if loadShedder != nil {
+		engine.Use(loadShedder.Track())
+	}
+
+	// Identify the caller of every request when authentication is enabled.
+	if authenticator != nil {
+		engine.Use(authenticator.Middleware())
+
+		engine.Use(middleware.Redaction(redactionPolicies))
+	}
+
+	// Cache the responses of the public GET endpoints in memory, and let CDNs cache them,
+	// so that they are offloaded during traffic spikes. Challenges are never cached, as each
+	// one may only be solved once.
+	var responseCache *middleware.ResponseCache
+	if helpers.GetEnvBool("RESPONSE_CACHE_ENABLED", true) {
+		responseCache = middleware.NewResponseCache(helpers.GetEnvInt("RESPONSE_CACHE_SIZE", 10000))
+	}
+	staleWhileRevalidate := helpers.GetEnvDuration("RESPONSE_CACHE_STALE_WHILE_REVALIDATE", time.Minute)
+	rootCache := responseCache.Middleware(middleware.CachePolicy{
+		MaxAge:               helpers.GetEnvDuration("RESPONSE_CACHE_ROOT_TTL", 5*time.Minute),
+		StaleWhileRevalidate: staleWhileRevalidate,
+		SurrogateKey:         "root",
+	})
+	statusCache := responseCache.Middleware(middleware.CachePolicy{
+		MaxAge:               helpers.GetEnvDuration("RESPONSE_CACHE_STATUS_BROWSER_TTL", 0),
+		SharedMaxAge:         helpers.GetEnvDuration("RESPONSE_CACHE_STATUS_TTL", 30*time.Second),
+		StaleWhileRevalidate: staleWhileRevalidate,
+		SurrogateKey:         "contact-status",
+	})
+	challengeCache := responseCache.Middleware(middleware.CachePolicy{})
+	schemaCache := responseCache.Middleware(middleware.CachePolicy{
+		MaxAge:               helpers.GetEnvDuration("RESPONSE_CACHE_SCHEMA_TTL", 5*time.Minute),
+		StaleWhileRevalidate: staleWhileRevalidate,
+		SurrogateKey:         "form-schema",
+	})
+
+	// Report the health of the background job queues in the metrics and on /healthz/jobs.
+	jobQueues := []jobs.Monitor{webhookDispatcher}
+	if notifier != nil {
+		jobQueues = append(jobQueues, notifier)
+	}
+	if autoReplies != nil {
+		jobQueues = append(jobQueues, autoReplies)
+	}
+	if enricher != nil {
+		jobQueues = append(jobQueues, enricher)
+	}
+	if thumbnailGenerator != nil {
+		jobQueues = append(jobQueues, thumbnailGenerator)
+	}
+	observability.WatchJobQueues(jobQueues...)
+	healthHandler.WatchJobQueues(helpers.GetEnvDuration("JOBS_STALL_AFTER", 5*time.Minute), jobQueues...)
+
+	// Define application routes and associate them with their respective handlers.
+	engine.GET("/", rootCache, mainHandler.MainHandler)
+	engine.GET("/health", healthHandler.HealthCheck)
+	engine.GET("/healthz", healthHandler.Healthz)
+	engine.GET("/healthz/jobs", healthHandler.Jobz)
+	engine.GET("/readyz", healthHandler.Readyz)
+	engine.GET("/startupz", healthHandler.Startupz)
+	engine.GET("/replicationz", regionHandler.GetReplicationStatus)
+	if helpers.GetEnvBool("METRICS_ENABLED", true) {
+		engine.GET("/metrics", router.WrapH(observability.Handler()))
+	}
+	engine.POST("/contacts", append(submissionGuards, contactHandler.CreateContact)...)
+	engine.GET("/forms/:slug/schema", schemaCache, formSchemaHandler.GetFormSchema)
+	if publicIDs != nil {
+		engine.GET("/contacts/status/:reference", statusCache, contactHandler.GetContactStatus)
+	}
+	engine.POST("/abuse-reports", append(abuseReportGuards, abuseReportHandler.CreateAbuseReport)...)
+	engine.GET("/follow-ups/calendar.ics", followUpHandler.GetFollowUpCalendar)
+	// Receive the inbound email webhooks only with a shared secret, as anyone could
+	// otherwise create contacts or mark them undeliverable. Their bodies are limited like
+	// submissions with attachments.
+	if inboundEmailToken != "" {
+		inbound := engine.Group("/inbound", middleware.BodyLimit(maxBodySize))
+		inbound.POST("/email", inboundEmailHandler.ReceiveEmail)
+		inbound.POST("/email-events", inboundEmailHandler.ReceiveEmailEvents)
+	} else {
+		log.Println("INBOUND_EMAIL_TOKEN is not set; the inbound email webhooks are disabled")
+	}
+
+	// Routes reading contacts are open to viewers; other routes reading or changing stored
+	// data are restricted to admins.
+	reader := engine.Group("", readerGuards...)
+	admin := engine.Group("", adminGuards...)
+	ContactRoutes(reader, admin, lowPriorityGuards, contactHandler, exportHandler)
+	reader.GET("/inbox", inboxHandler.GetInbox)
+	reader.GET("/inbox/stream", inboxFeedHandler.Stream)
+	admin.POST("/inbox/rebuild", append(lowPriorityGuards, inboxHandler.RebuildInbox)...)
+	if publicIDs != nil {
+		admin.GET("/contacts/reference/:reference", contactHandler.GetContactByReference)
+	}
+	admin.GET("/contacts/:id/reply/draft", replyDraftHandler.GetReplyDraft)
+	admin.PUT("/contacts/:id/reply/draft", replyDraftHandler.SaveReplyDraft)
+	admin.DELETE("/contacts/:id/reply/draft", replyDraftHandler.DiscardReplyDraft)
+	admin.PUT("/contacts/:id/follow-up", followUpHandler.SetFollowUp)
+	admin.DELETE("/contacts/:id/follow-up", followUpHandler.ClearFollowUp)
+	admin.GET("/follow-ups", followUpHandler.GetFollowUps)
+	admin.GET("/follow-ups/feed", followUpHandler.GetFollowUpFeed)
+	admin.GET("/contacts/:id/presence", presenceHandler.GetPresence)
+	admin.PUT("/contacts/:id/presence", presenceHandler.Heartbeat)
+	admin.DELETE("/contacts/:id/presence", presenceHandler.Leave)
+	admin.GET("/presence/stream", presenceHandler.Stream)
+	if attachmentHandler != nil {
+		admin.GET("/contacts/:id/attachments", attachmentHandler.GetAttachments)
+		admin.GET("/contacts/:id/attachments/:attachmentId", attachmentHandler.DownloadAttachment)
+		admin.GET("/contacts/:id/attachments/:attachmentId/thumbnail", attachmentHandler.DownloadThumbnail)
+	}
+	admin.GET("/abuse-reports", append(lowPriorityGuards, abuseReportHandler.GetAbuseReports)...)
+	admin.PATCH("/abuse-reports/:id", abuseReportHandler.UpdateAbuseReportStatus)
+	admin.GET("/gdpr/export", append(lowPriorityGuards, gdprHandler.ExportSubjectData)...)
+	admin.POST("/gdpr/retention", append(lowPriorityGuards, gdprHandler.RunRetention)...)
+	admin.GET("/audit-logs", auditLogHandler.GetAuditLogs)
+	admin.GET("/system/changes", systemChangeHandler.GetSystemChanges)
+	admin.POST("/system/promote", regionHandler.PromoteRegion)
+	admin.GET("/stats/api-usage", apiUsageHandler.GetAPIUsage)
+	admin.GET("/export-schedules", exportScheduleHandler.GetExportSchedules)
+	admin.POST("/export-schedules", exportScheduleHandler.CreateExportSchedule)
+	admin.GET("/export-schedules/:id", exportScheduleHandler.GetExportSchedule)
+	admin.PUT("/export-schedules/:id", exportScheduleHandler.UpdateExportSchedule)
+	admin.DELETE("/export-schedules/:id", exportScheduleHandler.DeleteExportSchedule)
+	admin.POST("/export-schedules/:id/run", append(lowPriorityGuards, exportScheduleHandler.RunExportSchedule)...)
+	admin.GET("/auto-replies", autoReplyHandler.GetAutoReplyTemplates)
+	admin.GET("/auto-replies/:lang", autoReplyHandler.GetAutoReplyTemplate)
+	admin.PUT("/auto-replies/:lang", autoReplyHandler.SaveAutoReplyTemplate)
+	admin.DELETE("/auto-replies/:lang", autoReplyHandler.DeleteAutoReplyTemplate)
+	admin.GET("/settings/export", settingsHandler.ExportSettings)
+	admin.POST("/settings/import", settingsHandler.ImportSettings)
+	admin.GET("/webhooks", webhookHandler.GetWebhooks)
+	admin.POST("/webhooks", webhookHandler.CreateWebhook)
+	admin.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
+	admin.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+	admin.GET("/webhooks/:id/deliveries", webhookHandler.GetWebhookDeliveries)
+	admin.POST("/webhooks/:id/replay", webhookHandler.ReplayWebhook)
+	admin.GET("/webhooks/:id/replay", webhookHandler.GetWebhookReplay)
+	admin.DELETE("/webhooks/:id/replay", webhookHandler.CancelWebhookReplay)
+	if authenticator != nil {
+		admin.GET("/api-keys", apiKeyHandler.GetAPIKeys)
+		admin.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+		admin.POST("/api-keys/:id/rotate", apiKeyHandler.RotateAPIKey)
+		admin.DELETE("/api-keys/:id", apiKeyHandler.RevokeAPIKey)
+		admin.POST("/auth/token", apiKeyHandler.IssueToken)
+		admin.GET("/users", adminUserHandler.GetUsers)
+		admin.POST("/users", adminUserHandler.InviteUser)
+		admin.PUT("/users/:id/disabled", adminUserHandler.DisableUser)
+		admin.POST("/users/:id/reset-password", adminUserHandler.ResetPassword)
+		admin.POST("/users/:id/reset-2fa", adminUserHandler.ResetTwoFactor)
+		admin.POST("/users/:id/unlock", adminUserHandler.UnlockUser)
+		admin.GET("/auth/login-events", adminUserHandler.GetLoginEvents)
+		admin.GET("/auth/sessions", adminUserHandler.GetSessions)
+		admin.DELETE("/auth/sessions", adminUserHandler.RevokeOtherSessions)
+		admin.DELETE("/auth/sessions/:id", adminUserHandler.RevokeSession)
+		admin.POST("/auth/password", adminUserHandler.ChangePassword)
+
+		// Signing in, refreshing tokens, choosing a password and managing the second factor are public,
+		// as they precede authentication; the two-factor endpoints take the credentials of the user instead.
+		engine.POST("/auth/login", adminUserHandler.Login)
+		engine.POST("/auth/refresh", adminUserHandler.RefreshToken)
+		engine.POST("/users/setup", adminUserHandler.SetupPassword)
+		engine.POST("/auth/2fa/enroll", adminUserHandler.EnrollTwoFactor)
+		engine.POST("/auth/2fa/activate", adminUserHandler.ActivateTwoFactor)
+		engine.POST("/auth/2fa/recovery-codes", adminUserHandler.RegenerateRecoveryCodes)
+		engine.POST("/auth/2fa/disable", adminUserHandler.DisableTwoFactor)
+	}
+
+	if proofOfWork != nil {
+		engine.GET("/challenges/pow", challengeCache, challengeHandler.IssueProofOfWork)
+	}
+	if formTokens != nil {
+		engine.GET("/challenges/form-token", challengeCache, challengeHandler.IssueFormToken)
+	}
+
+	s := &Server{Handler: engine, health: healthHandler}
+	// Preload the status of the latest contacts, which their submitters look up right
+	// after submitting, when RESPONSE_CACHE_WARMUP is set.
+	if warmup := min(helpers.GetEnvInt("RESPONSE_CACHE_WARMUP", 0), repositories.MaxPageSize); warmup > 0 && responseCache != nil && publicIDs != nil {
+		s.warmCache = func(ctx context.Context) {
+			page, err := contactService.ListContacts(ctx, repositories.ListParams{Limit: warmup})
+			if err != nil {
+				log.Printf("Response cache warmup failed: %v", err)
+				return
+			}
+			paths := make([]string, 0, len(page.Contacts))
+			for i := range page.Contacts {
+				paths = append(paths, "/contacts/status/"+url.PathEscape(publicIDs.Reference(&page.Contacts[i])))
+			}
+			warmed := responseCache.Warm(ctx, engine, paths)
+			log.Printf("Response cache warmed up with the status of %d contacts", warmed)
+		}
+	}
+	// End the presence streams and inbox feeds on shutdown, as they would otherwise never drain.
+	s.closers = append(s.closers, presenceTracker.Close, inboxFeed.Close)
+	if submissionBuffer != nil {
+		s.waiters = append(s.waiters, submissionBuffer.Wait)
+	}
+	if notifier != nil {
+		s.waiters = append(s.waiters, notifier.Wait)
+	}
+	if autoReplies != nil {
+		s.waiters = append(s.waiters, autoReplies.Wait)
+	}
+	s.waiters = append(s.waiters, webhookDispatcher.Wait)
+	if enricher != nil {
+		s.waiters = append(s.waiters, enricher.Wait)
+	}
+	if thumbnailGenerator != nil {
+		s.waiters = append(s.waiters, thumbnailGenerator.Wait)
+	}
+	if apiUsageTracker != nil {
+		s.waiters = append(s.waiters, apiUsageTracker.Wait)
+	}
+	return s, nil
+}
+
+// WarmCache preloads the response cache before the first requests, so that they are not
+// all answered from the database right after a deploy. It does nothing unless enabled.
+func (s *Server) WarmCache(ctx context.Context) {
+	if s.warmCache != nil {
+		s.warmCache(ctx)
+	}
+}
+
+// Drain reports the server unready, so that load balancers stop routing requests to it
+// before it shuts down.
+func (s *Server) Drain() {
+	s.health.Drain()
+}
+
+// Close ends the long-lived responses, the presence streams and inbox feeds, which would
+// otherwise keep an http.Server from shutting down. Register it with
+// http.Server.RegisterOnShutdown.
+func (s *Server) Close() {
+	for _, end := range s.closers {
+		end()
+	}
+}
+
+// Wait waits for the background workers to finish their work once workers is done, such
+// as the notifications and webhook deliveries still queued. The database must stay open
+// until it returns.
+func (s *Server) Wait() {
+	for _, wait := range s.waiters {
+		wait()
+	}
+}
+
+// challengeSecret returns the signing secret configured in the named environment variable.
+// When it is not set, a random secret is generated; tokens issued with it do not survive
+// a restart and are not accepted by other replicas.
+func challengeSecret(key string) []byte {
+	secret := []byte(config.GetEnv(key, ""))
+	if len(secret) == 0 {
+		log.Printf("%s is not set; using a random secret, issued tokens will not survive a restart", key)
+		secret = make([]byte, 32)
+		_, _ = rand.Read(secret)
+	}
+	return secret
+}
+
+// formTime reads an optional RFC 3339 time of the form schedule, and fails when it is
+// invalid rather than leave the form open or closed by mistake.
+func formTime(key string) (time.Time, error) {
+	value := config.GetEnv(key, "")
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s, expected an RFC 3339 time: %w", key, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,158 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file defines the AbuseReportService, which records the reports of misused embedded
+// forms sent by site visitors, emails them to the admins of the instance, and lets the
+// admins resolve or dismiss them.
+package services
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/notifications"
+	"api-contact-form/repositories"
+	"api-contact-form/requests"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAbuseReport is returned when an abuse report has an invalid page URL or category.
+var ErrInvalidAbuseReport = errors.New("invalid abuse report")
+
+// ErrInvalidAbuseReportStatus is returned when an abuse report is given an unknown status.
+var ErrInvalidAbuseReportStatus = errors.New("invalid status, expected open, resolved or dismissed")
+
+// AbuseReportService defines the business logic interface for abuse reports.
+type AbuseReportService interface {
+	// Report records a report received from clientIP with userAgent, and notifies the admins.
+	Report(ctx context.Context, req *requests.AbuseReportRequest, clientIP, userAgent string) (*models.AbuseReport, error)
+	// ListReports retrieves the reports with the given status, or every report when status
+	// is empty, newest first.
+	ListReports(ctx context.Context, status models.AbuseReportStatus, limit, offset int) ([]models.AbuseReport, error)
+	// SetStatus changes the status of a report identified by its ID on behalf of actor.
+	SetStatus(ctx context.Context, actor string, id uint, status models.AbuseReportStatus) (*models.AbuseReport, error)
+}
+
+// abuseReportService is the concrete implementation of AbuseReportService.
+type abuseReportService struct {
+	reports    repositories.AbuseReportRepository
+	users      repositories.AdminUserRepository
+	mailer     *notifications.ReportMailer
+	recipients []string
+}
+
+// NewAbuseReportService creates a new instance of AbuseReportService storing the reports in
+// reports. New reports are emailed with mailer, when not nil, to recipients, or to the
+// enabled admin users of users when recipients is empty.
+func NewAbuseReportService(reports repositories.AbuseReportRepository, users repositories.AdminUserRepository, mailer *notifications.ReportMailer, recipients []string) AbuseReportService {
+	return &abuseReportService{reports: reports, users: users, mailer: mailer, recipients: recipients}
+}
+
+// Report validates and stores the report, then emails it in the background, so that a
+// slow mail server does not delay the response to the reporter.
+func (s *abuseReportService) Report(ctx context.Context, req *requests.AbuseReportRequest, clientIP, userAgent string) (*models.AbuseReport, error) {
+	page, err := url.Parse(req.PageURL)
+	if err != nil || (page.Scheme != "http" && page.Scheme != "https") || page.Host == "" {
+		return nil, fmt.Errorf("%w: page_url must be an absolute http or https URL", ErrInvalidAbuseReport)
+	}
+	if !req.Category.Valid() {
+		return nil, fmt.Errorf("%w: unknown category %q", ErrInvalidAbuseReport, req.Category)
+	}
+
+	report := &models.AbuseReport{
+		PageURL:       req.PageURL,
+		Category:      req.Category,
+		Details:       strings.TrimSpace(req.Details),
+		ReporterEmail: strings.ToLower(strings.TrimSpace(req.Email)),
+		ClientIP:      clientIP,
+		UserAgent:     truncate(userAgent, 255),
+		Status:        models.AbuseReportOpen,
+	}
+	if err := s.reports.Create(ctx, report); err != nil {
+		return nil, err
+	}
+
+	if s.mailer != nil {
+		go s.notify(*report)
+	}
+	return report, nil
+}
+
+// ListReports retrieves the matching reports from the repository.
+func (s *abuseReportService) ListReports(ctx context.Context, status models.AbuseReportStatus, limit, offset int) ([]models.AbuseReport, error) {
+	if status != "" && !status.Valid() {
+		return nil, ErrInvalidAbuseReportStatus
+	}
+	return s.reports.FindPage(ctx, status, limit, offset)
+}
+
+// SetStatus records the new status of the report, and who closed it and when, if it is
+// no longer open. It returns gorm.ErrRecordNotFound when the report does not exist.
+func (s *abuseReportService) SetStatus(ctx context.Context, actor string, id uint, status models.AbuseReportStatus) (*models.AbuseReport, error) {
+	if !status.Valid() {
+		return nil, ErrInvalidAbuseReportStatus
+	}
+	report, err := s.reports.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Status = status
+	if status == models.AbuseReportOpen {
+		report.ResolvedBy, report.ResolvedAt = "", nil
+	} else {
+		now := time.Now()
+		report.ResolvedBy, report.ResolvedAt = actor, &now
+	}
+	if err := s.reports.Update(ctx, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// notify emails report to the admins. Failures are only logged, as the report is stored
+// and listed to the admins anyway.
+func (s *abuseReportService) notify(report models.AbuseReport) {
+	recipients, err := s.adminEmails()
+	if err != nil {
+		log.Printf("Abuse report %d not emailed: %v", report.ID, err)
+		return
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	subject := fmt.Sprintf("Abuse report #%d: %s", report.ID, report.Category)
+	var body strings.Builder
+	fmt.Fprintf(&body, "A visitor reported the misuse of a form.\n\n")
+	fmt.Fprintf(&body, "Page: %s\nCategory: %s\n", report.PageURL, report.Category)
+	if report.ReporterEmail != "" {
+		fmt.Fprintf(&body, "Reporter: %s\n", report.ReporterEmail)
+	}
+	fmt.Fprintf(&body, "Client IP: %s\n\n%s\n", report.ClientIP, report.Details)
+	if err := s.mailer.SendReport(recipients, subject, body.String(), nil); err != nil {
+		log.Printf("Abuse report %d not emailed: %v", report.ID, err)
+	}
+}
+
+// adminEmails returns the configured recipients, or else the emails of the admin users who
+// are not disabled.
+func (s *abuseReportService) adminEmails() ([]string, error) {
+	if len(s.recipients) > 0 {
+		return s.recipients, nil
+	}
+	users, err := s.users.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	var emails []string
+	for _, user := range users {
+		if user.DisabledAt == nil {
+			emails = append(emails, user.Email)
+		}
+	}
+	return emails, nil
+}
@@ -0,0 +1,806 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file defines the AdminUserService, which invites, disables and signs in the admin
+// users, lets them choose their password through a one-time setup link, and manages their
+// two-factor authentication and the sessions of their devices. Sign-in attempts are
+// audited, and throttled per account and per client IP address.
+package services
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/notifications"
+	"api-contact-form/observability"
+	"api-contact-form/repositories"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUserExists is returned when a user is invited with an email address already in use.
+	ErrUserExists = errors.New("an admin user with this email address already exists")
+	// ErrInvalidSetupToken is returned for setup tokens that are unknown, used or expired.
+	ErrInvalidSetupToken = errors.New("invalid or expired setup link")
+	// ErrInvalidCredentials is returned when signing in fails, whatever the reason.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrTwoFactorRequired is returned when a user with two-factor authentication signs in
+	// with the right password but without a code.
+	ErrTwoFactorRequired = errors.New("a code from the authenticator app or a recovery code is required")
+	// ErrTwoFactorEnrollmentRequired is returned when a user without two-factor authentication
+	// signs in while the instance requires it.
+	ErrTwoFactorEnrollmentRequired = errors.New("two-factor authentication must be set up before signing in")
+	// ErrTwoFactorEnabled is returned when enrolling a user whose two-factor authentication is already active.
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned for operations requiring active two-factor authentication.
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorPolicy is returned when a user disables two-factor authentication while
+	// the instance requires it.
+	ErrTwoFactorPolicy = errors.New("two-factor authentication is required on this instance")
+	// ErrInvalidRefreshToken is returned for refresh tokens that are unknown, already used,
+	// revoked or expired, and for those of users who can no longer sign in.
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrIncorrectPassword is returned when changing a password with a wrong current password.
+	ErrIncorrectPassword = errors.New("the current password is incorrect")
+)
+
+// recoveryCodeCount is the number of recovery codes generated at once.
+const recoveryCodeCount = 10
+
+// sessionTouchInterval is how often the last use of a session is recorded, so that
+// authenticating every request does not write to the database every time.
+const sessionTouchInterval = time.Minute
+
+// dummyPasswordHash is compared against when signing in with an unknown email address,
+// so that unknown addresses take as long to reject as wrong passwords.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// SetupLink is a one-time link letting a user choose their password.
+type SetupLink struct {
+	// Token is the one-time token. It is not stored and cannot be retrieved later.
+	Token string
+	// URL is the setup page with the token, or empty when no setup URL is configured.
+	URL string
+	// Sent reports whether the link was emailed to the user.
+	Sent bool
+}
+
+// TwoFactorPolicy configures the two-factor authentication of admin users.
+type TwoFactorPolicy struct {
+	// Required makes two-factor authentication mandatory: users without it cannot sign in
+	// until they enroll, and their tokens are no longer accepted.
+	Required bool
+	// Issuer is the name authenticator apps show for the account.
+	Issuer string
+}
+
+// TwoFactorEnrollment is the secret of an authenticator app being set up.
+type TwoFactorEnrollment struct {
+	// Secret is the base32 secret, for apps where it is typed in.
+	Secret string
+	// URL is the otpauth:// URL of the secret, to be shown as a QR code.
+	URL string
+}
+
+// AdminUserService defines the business logic interface for admin user operations.
+type AdminUserService interface {
+	// InviteUser creates a user without a password and a setup link for them.
+	InviteUser(email, name string) (*models.AdminUser, *SetupLink, error)
+	// ListUsers retrieves every user, including disabled ones.
+	ListUsers() ([]models.AdminUser, error)
+	// SetDisabled disables or re-enables the user identified by its ID.
+	SetDisabled(id uint, disabled bool) (*models.AdminUser, error)
+	// ResetPassword clears the password of the user identified by its ID and creates a
+	// new setup link for them.
+	ResetPassword(id uint) (*models.AdminUser, *SetupLink, error)
+	// CompleteSetup sets the password of the user of a setup token and uses the token up.
+	// Passwords not satisfying the password policy are rejected with a ValidationError.
+	CompleteSetup(token, password string) (*models.AdminUser, error)
+	// Login verifies the credentials of an enabled user and records the sign-in. code is
+	// the authenticator app or recovery code, required once two-factor authentication is enabled.
+	Login(email, password, code string, client LoginClient) (*models.AdminUser, error)
+	// Active reports whether the user identified by its ID exists, is not disabled, and
+	// complies with the two-factor policy, and whether its session sessionID is active.
+	Active(id, sessionID uint) (bool, error)
+	// EnrollTwoFactor verifies the credentials of a user and generates the secret of their
+	// authenticator app, which is activated with ActivateTwoFactor.
+	EnrollTwoFactor(email, password string, client LoginClient) (*TwoFactorEnrollment, error)
+	// ActivateTwoFactor verifies the credentials of an enrolling user and a code of their
+	// authenticator app, enables two-factor authentication and returns new recovery codes.
+	ActivateTwoFactor(email, password, code string, client LoginClient) ([]string, error)
+	// RegenerateRecoveryCodes verifies the credentials and code of a user, and replaces
+	// their recovery codes.
+	RegenerateRecoveryCodes(email, password, code string, client LoginClient) ([]string, error)
+	// DisableTwoFactor verifies the credentials and code of a user, and disables their
+	// two-factor authentication, unless the instance requires it.
+	DisableTwoFactor(email, password, code string, client LoginClient) error
+	// ResetTwoFactor disables the two-factor authentication of the user identified by its
+	// ID, e.g. when they lost their authenticator app and recovery codes.
+	ResetTwoFactor(id uint) (*models.AdminUser, error)
+	// StartSession creates a session for a user who just signed in from the device of
+	// client, and returns it with its refresh token.
+	StartSession(user *models.AdminUser, client LoginClient) (*models.AdminSession, string, error)
+	// RefreshSession exchanges a refresh token for a new one, extending its session, and
+	// returns the user and the session it belongs to.
+	RefreshSession(token string, client LoginClient) (*models.AdminUser, *models.AdminSession, string, error)
+	// ListSessions retrieves the active sessions of the user identified by its ID.
+	ListSessions(userID uint) ([]models.AdminSession, error)
+	// RevokeSession signs out a session of the user identified by its ID.
+	RevokeSession(userID, sessionID uint) error
+	// RevokeSessions signs out every session of the user identified by its ID, except
+	// the session identified by except, which may be zero.
+	RevokeSessions(userID, except uint) error
+	// ChangePassword verifies the current password of the user identified by its ID, sets
+	// the new one, and signs out every other session than sessionID. New passwords not
+	// satisfying the password policy are rejected with a ValidationError.
+	ChangePassword(userID, sessionID uint, current, password string) error
+	// UnlockUser ends the lockout of the user identified by its ID and resets their
+	// failed sign-in attempts.
+	UnlockUser(id uint) (*models.AdminUser, error)
+	// ListLoginEvents retrieves the most recent sign-in attempts matching the filter.
+	ListLoginEvents(filter repositories.LoginEventFilter) ([]models.AdminLoginEvent, error)
+}
+
+// adminUserService is the concrete implementation of AdminUserService.
+type adminUserService struct {
+	repository repositories.AdminUserRepository
+	sessions   repositories.AdminSessionRepository
+	events     repositories.AdminLoginEventRepository
+	mailer     *notifications.SetupMailer
+	setupURL   string
+	setupTTL   time.Duration
+	sessionTTL time.Duration
+	twoFactor  TwoFactorPolicy
+	login      LoginPolicy
+	passwords  PasswordPolicy
+	throttle   *loginThrottle
+}
+
+// NewAdminUserService creates a new instance of AdminUserService with the provided
+// AdminUserRepository, AdminSessionRepository and AdminLoginEventRepository. Setup links
+// are setupURL with the token appended, valid for setupTTL, and are emailed with mailer
+// when it is not nil. Sessions expire when their refresh token is not used for sessionTTL.
+// Two-factor authentication follows twoFactor, failed sign-in attempts login, and new
+// passwords must satisfy passwords.
+func NewAdminUserService(repository repositories.AdminUserRepository, sessions repositories.AdminSessionRepository, events repositories.AdminLoginEventRepository, mailer *notifications.SetupMailer, setupURL string, setupTTL, sessionTTL time.Duration, twoFactor TwoFactorPolicy, login LoginPolicy, passwords PasswordPolicy) AdminUserService {
+	return &adminUserService{
+		repository: repository,
+		sessions:   sessions,
+		events:     events,
+		mailer:     mailer,
+		setupURL:   setupURL,
+		setupTTL:   setupTTL,
+		sessionTTL: sessionTTL,
+		twoFactor:  twoFactor,
+		login:      login,
+		passwords:  passwords,
+		throttle:   newLoginThrottle(login.MaxIPFailures, login.IPWindow),
+	}
+}
+
+// InviteUser stores the new user with a pending setup token and sends them the link.
+func (s *adminUserService) InviteUser(email, name string) (*models.AdminUser, *SetupLink, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if _, err := s.repository.FindByEmail(email); err == nil {
+		return nil, nil, ErrUserExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	user := &models.AdminUser{Email: email, Name: name}
+	token, err := s.newSetupToken(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.repository.Create(user); err != nil {
+		return nil, nil, err
+	}
+	return user, s.sendSetupLink(user, token, false), nil
+}
+
+// ListUsers retrieves every user from the repository.
+func (s *adminUserService) ListUsers() ([]models.AdminUser, error) {
+	return s.repository.FindAll()
+}
+
+// SetDisabled sets or clears the disabled time of the user. Disabling a user signs out
+// all their sessions.
+func (s *adminUserService) SetDisabled(id uint, disabled bool) (*models.AdminUser, error) {
+	user, err := s.repository.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if disabled && user.DisabledAt == nil {
+		now := time.Now()
+		user.DisabledAt = &now
+	} else if !disabled {
+		user.DisabledAt = nil
+	}
+	if err := s.repository.Update(user); err != nil {
+		return nil, err
+	}
+	if disabled {
+		if err := s.sessions.RevokeAll(user.ID, 0, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// ResetPassword replaces the password of the user with a pending setup token, so that
+// the old password and all sessions stop working immediately.
+func (s *adminUserService) ResetPassword(id uint) (*models.AdminUser, *SetupLink, error) {
+	user, err := s.repository.FindByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user.PasswordHash = ""
+	token, err := s.newSetupToken(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.repository.Update(user); err != nil {
+		return nil, nil, err
+	}
+	if err := s.sessions.RevokeAll(user.ID, 0, time.Now()); err != nil {
+		return nil, nil, err
+	}
+	return user, s.sendSetupLink(user, token, true), nil
+}
+
+// CompleteSetup checks the chosen password against the password policy, hashes it and
+// clears the setup token.
+func (s *adminUserService) CompleteSetup(token, password string) (*models.AdminUser, error) {
+	user, err := s.repository.FindBySetupToken(hashSetupToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidSetupToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.passwords.check(password); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = string(hash)
+	user.SetupTokenHash = nil
+	user.SetupExpiresAt = nil
+	if err := s.repository.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Login checks the credentials, then the second factor of users who enabled it. Unknown,
+// disabled and not yet set up users are rejected with the same error as wrong passwords;
+// the second factor is only asked for once the password is right. Wrong codes count as
+// failed attempts, like wrong passwords.
+func (s *adminUserService) Login(email, password, code string, client LoginClient) (*models.AdminUser, error) {
+	user, err := s.authenticate(email, password, client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Require the second factor, or its enrollment when the instance makes it mandatory
+	if user.TwoFactorEnabled() {
+		if code == "" {
+			return nil, ErrTwoFactorRequired
+		}
+		if err := s.verifySecondFactor(user, code, true); err != nil {
+			return nil, s.loginFailed(user, email, client, err)
+		}
+	} else if s.twoFactor.Required {
+		return nil, ErrTwoFactorEnrollmentRequired
+	}
+	s.loginSucceeded(user, client, true)
+
+	now := time.Now()
+	if err := s.repository.TouchLastLogin(user.ID, now); err != nil {
+		log.Printf("Failed to record sign-in of admin user %d: %v", user.ID, err)
+	}
+	user.LastLoginAt = &now
+	return user, nil
+}
+
+// Active looks the session and the user up and checks that neither is revoked or disabled.
+// Tokens without a session, issued before sessions were tracked, are no longer accepted.
+func (s *adminUserService) Active(id, sessionID uint) (bool, error) {
+	if sessionID == 0 {
+		return false, nil
+	}
+	session, err := s.sessions.FindByID(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	if session.UserID != id || !session.Active(now) {
+		return false, nil
+	}
+	if now.Sub(session.LastUsedAt) >= sessionTouchInterval {
+		if err := s.sessions.TouchLastUsed(session.ID, now); err != nil {
+			log.Printf("Failed to record the use of admin session %d: %v", session.ID, err)
+		}
+	}
+
+	user, err := s.repository.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if s.twoFactor.Required && !user.TwoFactorEnabled() {
+		return false, nil
+	}
+	return user.DisabledAt == nil, nil
+}
+
+// EnrollTwoFactor stores a new pending secret on the user. Enrolling again before
+// activating replaces the secret.
+func (s *adminUserService) EnrollTwoFactor(email, password string, client LoginClient) (*TwoFactorEnrollment, error) {
+	user, err := s.authenticate(email, password, client)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	s.loginSucceeded(user, client, false)
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := s.repository.Update(user); err != nil {
+		return nil, err
+	}
+	return &TwoFactorEnrollment{Secret: secret, URL: totpURL(s.twoFactor.Issuer, user.Email, secret)}, nil
+}
+
+// ActivateTwoFactor checks that the app of the user generates the right codes before
+// requiring them at sign-in.
+func (s *adminUserService) ActivateTwoFactor(email, password, code string, client LoginClient) ([]string, error) {
+	user, err := s.authenticate(email, password, client)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.verifySecondFactor(user, code, false); err != nil {
+		return nil, s.loginFailed(user, email, client, err)
+	}
+	s.loginSucceeded(user, client, false)
+
+	now := time.Now()
+	user.TOTPEnabledAt = &now
+	if err := s.repository.Update(user); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(user.ID)
+}
+
+// RegenerateRecoveryCodes invalidates the previous recovery codes of the user.
+func (s *adminUserService) RegenerateRecoveryCodes(email, password, code string, client LoginClient) ([]string, error) {
+	user, err := s.authenticateTwoFactor(email, password, code, client)
+	if err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(user.ID)
+}
+
+// DisableTwoFactor clears the secret and the recovery codes of the user.
+func (s *adminUserService) DisableTwoFactor(email, password, code string, client LoginClient) error {
+	if s.twoFactor.Required {
+		return ErrTwoFactorPolicy
+	}
+	user, err := s.authenticateTwoFactor(email, password, code, client)
+	if err != nil {
+		return err
+	}
+	return s.clearTwoFactor(user)
+}
+
+// ResetTwoFactor clears the secret and the recovery codes of the user. When the instance
+// requires two-factor authentication, the user has to enroll again before signing in.
+func (s *adminUserService) ResetTwoFactor(id uint) (*models.AdminUser, error) {
+	user, err := s.repository.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.clearTwoFactor(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UnlockUser clears the lockout and the failed attempt counter of the user.
+func (s *adminUserService) UnlockUser(id uint) (*models.AdminUser, error) {
+	user, err := s.repository.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repository.Lock(user.ID, time.Time{}); err != nil {
+		return nil, err
+	}
+	user.FailedLogins = 0
+	user.LockedUntil = nil
+	return user, nil
+}
+
+// ListLoginEvents retrieves the sign-in attempts from the audit log.
+func (s *adminUserService) ListLoginEvents(filter repositories.LoginEventFilter) ([]models.AdminLoginEvent, error) {
+	return s.events.FindRecent(filter)
+}
+
+// StartSession stores the session with the hash of a new refresh token.
+func (s *adminUserService) StartSession(user *models.AdminUser, client LoginClient) (*models.AdminSession, string, error) {
+	token, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &models.AdminSession{
+		UserID:           user.ID,
+		RefreshTokenHash: hashRefreshToken(token),
+		UserAgent:        truncate(client.UserAgent, 255),
+		IP:               client.IP,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(s.sessionTTL),
+	}
+	if err := s.sessions.Create(session); err != nil {
+		return nil, "", err
+	}
+	return session, token, nil
+}
+
+// RefreshSession rotates the refresh token of the session, so that each token can only be
+// used once, and moves its expiry forward. The user must still be able to sign in.
+func (s *adminUserService) RefreshSession(token string, client LoginClient) (*models.AdminUser, *models.AdminSession, string, error) {
+	session, err := s.sessions.FindByRefreshToken(hashRefreshToken(strings.TrimSpace(token)))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	now := time.Now()
+	if !session.Active(now) {
+		return nil, nil, "", ErrInvalidRefreshToken
+	}
+
+	// Check the user as at sign-in: it must be enabled, set up and compliant with the
+	// two-factor policy
+	user, err := s.repository.FindByID(session.UserID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if user.DisabledAt != nil || user.PasswordHash == "" || user.Locked(now) || (s.twoFactor.Required && !user.TwoFactorEnabled()) {
+		return nil, nil, "", ErrInvalidRefreshToken
+	}
+
+	next, err := newRefreshToken()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	session.RefreshTokenHash = hashRefreshToken(next)
+	session.UserAgent = truncate(client.UserAgent, 255)
+	session.IP = client.IP
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(s.sessionTTL)
+	if err := s.sessions.Update(session); err != nil {
+		return nil, nil, "", err
+	}
+	return user, session, next, nil
+}
+
+// ListSessions retrieves the active sessions of the user from the repository.
+func (s *adminUserService) ListSessions(userID uint) ([]models.AdminSession, error) {
+	return s.sessions.FindActiveByUser(userID)
+}
+
+// RevokeSession revokes the session, which must be an active session of the user.
+func (s *adminUserService) RevokeSession(userID, sessionID uint) error {
+	return s.sessions.Revoke(userID, sessionID, time.Now())
+}
+
+// RevokeSessions revokes the active sessions of the user but one.
+func (s *adminUserService) RevokeSessions(userID, except uint) error {
+	return s.sessions.RevokeAll(userID, except, time.Now())
+}
+
+// ChangePassword replaces the password hash of the user, so that a leaked password or
+// session stops working everywhere but on the device changing it.
+func (s *adminUserService) ChangePassword(userID, sessionID uint, current, password string) error {
+	user, err := s.repository.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(current)) != nil {
+		return ErrIncorrectPassword
+	}
+	if err := s.passwords.check(password); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hash)
+	if err := s.repository.Update(user); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAll(user.ID, sessionID, time.Now())
+}
+
+// authenticate verifies the email address and password of an enabled user, taking as long
+// for unknown addresses as for wrong passwords. Blocked clients and locked accounts are
+// refused without checking the password; other failures are counted and audited.
+func (s *adminUserService) authenticate(email, password string, client LoginClient) (*models.AdminUser, error) {
+	now := time.Now()
+	if retryAfter := s.throttle.blocked(client.IP, now); retryAfter > 0 {
+		observability.LoginAttempts.WithLabelValues("refused").Inc()
+		return nil, &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	user, err := s.repository.FindByEmail(strings.TrimSpace(email))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, s.loginFailed(nil, email, client, ErrInvalidCredentials)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Locked(now) {
+		observability.LoginAttempts.WithLabelValues("refused").Inc()
+		return nil, &LoginLockedError{RetryAfter: user.LockedUntil.Sub(now), Account: true}
+	}
+
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, s.loginFailed(user, email, client, ErrInvalidCredentials)
+	}
+	if user.DisabledAt != nil {
+		return nil, s.loginFailed(user, email, client, ErrInvalidCredentials)
+	}
+	return user, nil
+}
+
+// authenticateTwoFactor verifies the credentials and the second factor of a user who
+// enabled two-factor authentication.
+func (s *adminUserService) authenticateTwoFactor(email, password, code string, client LoginClient) (*models.AdminUser, error) {
+	user, err := s.authenticate(email, password, client)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.verifySecondFactor(user, code, true); err != nil {
+		return nil, s.loginFailed(user, email, client, err)
+	}
+	s.loginSucceeded(user, client, false)
+	return user, nil
+}
+
+// loginFailed counts a failed attempt against the client and, when known, the user,
+// locking them out once they reach the limits of the policy, and audits it. It returns err.
+// Errors other than ErrInvalidCredentials are returned as they are, without counting.
+func (s *adminUserService) loginFailed(user *models.AdminUser, email string, client LoginClient, err error) error {
+	if !errors.Is(err, ErrInvalidCredentials) {
+		return err
+	}
+
+	now := time.Now()
+	outcome := models.LoginFailed
+	if s.throttle.fail(client.IP, now) {
+		outcome = models.LoginIPBlocked
+		log.Printf("Blocked sign-in attempts from %s for %s after too many failures", client.IP, s.login.IPWindow)
+	}
+
+	// Count the failure against the account, and lock it at the limit
+	if user != nil && s.login.MaxFailures > 0 {
+		count, countErr := s.repository.IncrementFailedLogins(user.ID)
+		if countErr != nil {
+			log.Printf("Failed to count the failed sign-in of admin user %d: %v", user.ID, countErr)
+		} else if count >= s.login.MaxFailures {
+			if lockErr := s.repository.Lock(user.ID, now.Add(s.login.LockoutDuration)); lockErr != nil {
+				log.Printf("Failed to lock admin user %d: %v", user.ID, lockErr)
+			} else {
+				outcome = models.LoginAccountLocked
+				log.Printf("Locked admin user %d for %s after %d failed sign-in attempts", user.ID, s.login.LockoutDuration, count)
+			}
+		}
+	}
+
+	s.audit(user, email, client, outcome)
+	return err
+}
+
+// loginSucceeded resets the failed attempts of user after the full credentials were
+// verified, and audits the attempt when it was a sign-in. It must not be called after
+// checking only the password of a user with two-factor authentication, or guessing codes
+// could be interleaved with resets.
+func (s *adminUserService) loginSucceeded(user *models.AdminUser, client LoginClient, signIn bool) {
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		if err := s.repository.Lock(user.ID, time.Time{}); err != nil {
+			log.Printf("Failed to reset the failed sign-ins of admin user %d: %v", user.ID, err)
+		}
+		user.FailedLogins = 0
+		user.LockedUntil = nil
+	}
+	if signIn {
+		s.audit(user, user.Email, client, models.LoginSucceeded)
+	}
+}
+
+// audit stores a sign-in attempt in the audit log and counts it in the metrics. Failing
+// to store it is logged, so that the audit log never prevents signing in.
+func (s *adminUserService) audit(user *models.AdminUser, email string, client LoginClient, outcome models.LoginOutcome) {
+	observability.LoginAttempts.WithLabelValues(string(outcome)).Inc()
+
+	event := &models.AdminLoginEvent{
+		Email:     truncate(strings.ToLower(strings.TrimSpace(email)), 100),
+		Outcome:   outcome,
+		ClientIP:  client.IP,
+		UserAgent: truncate(client.UserAgent, 255),
+	}
+	if user != nil {
+		event.UserID = &user.ID
+	}
+	if err := s.events.Create(event); err != nil {
+		log.Printf("Failed to audit the sign-in attempt of %s: %v", event.Email, err)
+	}
+}
+
+// verifySecondFactor checks a code of the authenticator app of user and records its step,
+// so that it cannot be replayed. When allowRecovery is set, other codes are checked as
+// recovery codes and used up.
+func (s *adminUserService) verifySecondFactor(user *models.AdminUser, code string, allowRecovery bool) error {
+	code = strings.TrimSpace(code)
+	if step, ok := verifyTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep); ok {
+		// Record the step alone, so that a concurrent request using the same code fails
+		// and the failed attempts counted meanwhile are kept.
+		recorded, err := s.repository.UseTOTPStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if recorded {
+			user.TOTPLastStep = step
+			return nil
+		}
+	}
+
+	if allowRecovery && code != "" {
+		used, err := s.repository.UseRecoveryCode(user.ID, hashRecoveryCode(code), time.Now())
+		if err != nil {
+			return err
+		}
+		if used {
+			log.Printf("Admin user %d signed in with a recovery code", user.ID)
+			return nil
+		}
+	}
+	return ErrInvalidCredentials
+}
+
+// newRecoveryCodes replaces the recovery codes of a user and returns the new codes, in the
+// form xxxxx-xxxxx.
+func (s *adminUserService) newRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		random := make([]byte, 7)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+		encoded := strings.ToLower(totpEncoding.EncodeToString(random))[:10]
+		code := encoded[:5] + "-" + encoded[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	if err := s.repository.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// clearTwoFactor disables the two-factor authentication of user and removes its recovery codes.
+func (s *adminUserService) clearTwoFactor(user *models.AdminUser) error {
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	if err := s.repository.Update(user); err != nil {
+		return err
+	}
+	return s.repository.ReplaceRecoveryCodes(user.ID, nil)
+}
+
+// newSetupToken generates a setup token and stores its hash and expiry on user.
+func (s *adminUserService) newSetupToken(user *models.AdminUser) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	hash := hashSetupToken(token)
+	expiresAt := time.Now().Add(s.setupTTL)
+	user.SetupTokenHash = &hash
+	user.SetupExpiresAt = &expiresAt
+	return token, nil
+}
+
+// sendSetupLink builds the setup link of token and emails it to user when a mailer and
+// a setup URL are configured. Failing to send is logged; the link is still returned so
+// that the inviting admin can pass it on.
+func (s *adminUserService) sendSetupLink(user *models.AdminUser, token string, reset bool) *SetupLink {
+	link := &SetupLink{Token: token}
+	if s.setupURL == "" {
+		return link
+	}
+	link.URL = s.setupURL + url.QueryEscape(token)
+
+	if s.mailer != nil {
+		if err := s.mailer.SendSetupLink(*user, link.URL, reset); err != nil {
+			log.Printf("Failed to email the setup link of admin user %d: %v", user.ID, err)
+		} else {
+			link.Sent = true
+		}
+	}
+	return link
+}
+
+// newRefreshToken generates a random refresh token.
+func newRefreshToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashRefreshToken returns the hex SHA-256 of a refresh token, as stored in
+// models.AdminSession.RefreshTokenHash.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashRecoveryCode returns the hex SHA-256 of a recovery code, ignoring case, spaces and
+// dashes, as stored in models.AdminRecoveryCode.CodeHash.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashSetupToken returns the hex SHA-256 of a setup token, as stored in models.AdminUser.SetupTokenHash.
+func hashSetupToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,310 @@
+package services_test
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/services"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// adminPassword is the password of the users of the tests.
+const adminPassword = "correct horse battery staple"
+
+// client is the client of the sign-in attempts of the tests.
+var client = services.LoginClient{IP: "192.0.2.1", UserAgent: "test"}
+
+// newAdminUserService creates an AdminUserService on an empty database with policy, and
+// returns the database too.
+func newAdminUserService(t *testing.T, policy services.LoginPolicy) (services.AdminUserService, *gorm.DB) {
+	t.Helper()
+	db := openDB(t, &models.AdminUser{}, &models.AdminSession{}, &models.AdminLoginEvent{}, &models.AdminRecoveryCode{})
+	users := services.NewAdminUserService(repositories.NewAdminUserRepository(db), repositories.NewAdminSessionRepository(db),
+		repositories.NewAdminLoginEventRepository(db), nil, "", time.Hour, time.Hour,
+		services.TwoFactorPolicy{Issuer: "Test"}, policy, services.PasswordPolicy{MinLength: 8})
+	return users, db
+}
+
+// createAdminUser stores a user with adminPassword.
+func createAdminUser(t *testing.T, db *gorm.DB, email string) *models.AdminUser {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(adminPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	user := &models.AdminUser{Email: email, Name: "Admin", PasswordHash: string(hash)}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// authenticatorApp is the authenticator app of a user of the tests.
+type authenticatorApp struct {
+	secret      string
+	activatedAt time.Time
+}
+
+// code returns the code of the app steps after the one used to activate it.
+func (a authenticatorApp) code(steps int) string {
+	return services.TOTPCode(a.secret, a.activatedAt.Add(time.Duration(steps)*30*time.Second))
+}
+
+// enableTwoFactor enrolls and activates the authenticator app of the user, and returns the
+// app and the recovery codes.
+func enableTwoFactor(t *testing.T, users services.AdminUserService, email string) (authenticatorApp, []string) {
+	t.Helper()
+	enrollment, err := users.EnrollTwoFactor(email, adminPassword, client)
+	if err != nil {
+		t.Fatalf("EnrollTwoFactor: %v", err)
+	}
+	app := authenticatorApp{secret: enrollment.Secret, activatedAt: time.Now()}
+	codes, err := users.ActivateTwoFactor(email, adminPassword, app.code(0), client)
+	if err != nil {
+		t.Fatalf("ActivateTwoFactor: %v", err)
+	}
+	return app, codes
+}
+
+func TestLoginWithTwoFactor(t *testing.T) {
+	users, db := newAdminUserService(t, services.LoginPolicy{})
+	createAdminUser(t, db, "admin@example.com")
+	app, _ := enableTwoFactor(t, users, "admin@example.com")
+
+	if _, err := users.Login("admin@example.com", adminPassword, "", client); !errors.Is(err, services.ErrTwoFactorRequired) {
+		t.Errorf("Login without a code = %v, want ErrTwoFactorRequired", err)
+	}
+	if _, err := users.Login("admin@example.com", adminPassword, "000000", client); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Errorf("Login with a wrong code = %v, want ErrInvalidCredentials", err)
+	}
+	next := app.code(1)
+	if _, err := users.Login("admin@example.com", "wrong password", next, client); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Errorf("Login with a wrong password = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := users.Login("admin@example.com", adminPassword, next, client); err != nil {
+		t.Errorf("Login with the code of the next step: %v", err)
+	}
+}
+
+func TestLoginRejectsReplayedCodes(t *testing.T) {
+	users, db := newAdminUserService(t, services.LoginPolicy{})
+	createAdminUser(t, db, "admin@example.com")
+	app, _ := enableTwoFactor(t, users, "admin@example.com")
+
+	if _, err := users.Login("admin@example.com", adminPassword, app.code(0), client); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Errorf("Login with the code used to activate the app = %v, want ErrInvalidCredentials", err)
+	}
+
+	next := app.code(1)
+	if _, err := users.Login("admin@example.com", adminPassword, next, client); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if _, err := users.Login("admin@example.com", adminPassword, next, client); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Errorf("Login replaying the code = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := users.Login("admin@example.com", adminPassword, app.code(0), client); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Errorf("Login with the code of an earlier step = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestLoginWithRecoveryCode(t *testing.T) {
+	users, db := newAdminUserService(t, services.LoginPolicy{})
+	createAdminUser(t, db, "admin@example.com")
+	_, codes := enableTwoFactor(t, users, "admin@example.com")
+	if len(codes) == 0 {
+		t.Fatal("ActivateTwoFactor returned no recovery codes")
+	}
+
+	if _, err := users.Login("admin@example.com", adminPassword, codes[0], client); err != nil {
+		t.Fatalf("Login with a recovery code: %v", err)
+	}
+	if _, err := users.Login("admin@example.com", adminPassword, codes[0], client); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Errorf("Login reusing the recovery code = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := users.Login("admin@example.com", adminPassword, codes[1], client); err != nil {
+		t.Errorf("Login with another recovery code: %v", err)
+	}
+	if _, err := users.Login("admin@example.com", adminPassword, "aaaaa-bbbbb", client); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Errorf("Login with an unknown recovery code = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestLoginLocksAccountAtThreshold(t *testing.T) {
+	users, db := newAdminUserService(t, services.LoginPolicy{MaxFailures: 3, LockoutDuration: time.Hour})
+	createAdminUser(t, db, "admin@example.com")
+	createAdminUser(t, db, "other@example.com")
+
+	for i := range 3 {
+		if _, err := users.Login("admin@example.com", "wrong password", "", client); !errors.Is(err, services.ErrInvalidCredentials) {
+			t.Fatalf("failed attempt %d = %v, want ErrInvalidCredentials", i+1, err)
+		}
+	}
+
+	_, err := users.Login("admin@example.com", adminPassword, "", client)
+	var locked *services.LoginLockedError
+	if !errors.As(err, &locked) || !locked.Account {
+		t.Fatalf("Login after 3 failures = %v, want the account locked", err)
+	}
+	if locked.RetryAfter <= 59*time.Minute || locked.RetryAfter > time.Hour {
+		t.Errorf("RetryAfter = %s, want the lockout duration", locked.RetryAfter)
+	}
+	if _, err := users.Login("other@example.com", adminPassword, "", client); err != nil {
+		t.Errorf("Login of another user: %v", err)
+	}
+}
+
+func TestLoginBelowThresholdResetsFailures(t *testing.T) {
+	users, db := newAdminUserService(t, services.LoginPolicy{MaxFailures: 3, LockoutDuration: time.Hour})
+	createAdminUser(t, db, "admin@example.com")
+
+	// Two failures, a success, then two failures again stay below the threshold.
+	for _, password := range []string{"wrong", "wrong", adminPassword, "wrong", "wrong", adminPassword} {
+		_, err := users.Login("admin@example.com", password, "", client)
+		if password == adminPassword && err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+	}
+}
+
+func TestLoginLockoutExpires(t *testing.T) {
+	users, db := newAdminUserService(t, services.LoginPolicy{MaxFailures: 1, LockoutDuration: time.Hour})
+	user := createAdminUser(t, db, "admin@example.com")
+	if _, err := users.Login("admin@example.com", "wrong password", "", client); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Fatalf("Login = %v, want ErrInvalidCredentials", err)
+	}
+	var locked *services.LoginLockedError
+	if _, err := users.Login("admin@example.com", adminPassword, "", client); !errors.As(err, &locked) {
+		t.Fatalf("Login = %v, want the account locked", err)
+	}
+
+	// Move the end of the lockout into the past.
+	if err := db.Model(user).Update("locked_until", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("expire the lockout: %v", err)
+	}
+	if _, err := users.Login("admin@example.com", adminPassword, "", client); err != nil {
+		t.Fatalf("Login after the lockout: %v", err)
+	}
+
+	stored, err := repositories.NewAdminUserRepository(db).FindByID(user.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if stored.FailedLogins != 0 || stored.LockedUntil != nil {
+		t.Errorf("after sign-in: FailedLogins = %d, LockedUntil = %v, want both cleared", stored.FailedLogins, stored.LockedUntil)
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+	users, db := newAdminUserService(t, services.LoginPolicy{MaxFailures: 1, LockoutDuration: time.Hour})
+	user := createAdminUser(t, db, "admin@example.com")
+	if _, err := users.Login("admin@example.com", "wrong password", "", client); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Fatalf("Login = %v, want ErrInvalidCredentials", err)
+	}
+
+	if _, err := users.UnlockUser(user.ID); err != nil {
+		t.Fatalf("UnlockUser: %v", err)
+	}
+	if _, err := users.Login("admin@example.com", adminPassword, "", client); err != nil {
+		t.Errorf("Login after UnlockUser: %v", err)
+	}
+}
+
+func TestLoginBlocksClientAddress(t *testing.T) {
+	users, db := newAdminUserService(t, services.LoginPolicy{MaxIPFailures: 2, IPWindow: time.Hour})
+	createAdminUser(t, db, "admin@example.com")
+
+	for _, email := range []string{"admin@example.com", "unknown@example.com"} {
+		if _, err := users.Login(email, "wrong password", "", client); !errors.Is(err, services.ErrInvalidCredentials) {
+			t.Fatalf("Login(%s) = %v, want ErrInvalidCredentials", email, err)
+		}
+	}
+
+	var locked *services.LoginLockedError
+	if _, err := users.Login("admin@example.com", adminPassword, "", client); !errors.As(err, &locked) || locked.Account {
+		t.Errorf("Login from the blocked address = %v, want the address blocked", err)
+	}
+	other := services.LoginClient{IP: "192.0.2.2"}
+	if _, err := users.Login("admin@example.com", adminPassword, "", other); err != nil {
+		t.Errorf("Login from another address: %v", err)
+	}
+}
+
+// startSessions signs the user in n times and returns the sessions and their refresh tokens.
+func startSessions(t *testing.T, users services.AdminUserService, n int) ([]*models.AdminSession, []string) {
+	t.Helper()
+	user, err := users.Login("admin@example.com", adminPassword, "", client)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	sessions := make([]*models.AdminSession, n)
+	tokens := make([]string, n)
+	for i := range n {
+		if sessions[i], tokens[i], err = users.StartSession(user, client); err != nil {
+			t.Fatalf("StartSession: %v", err)
+		}
+	}
+	return sessions, tokens
+}
+
+// checkActive fails the test unless the sessions of the user are active as wanted.
+func checkActive(t *testing.T, users services.AdminUserService, userID uint, sessions []*models.AdminSession, want ...bool) {
+	t.Helper()
+	for i, session := range sessions {
+		active, err := users.Active(userID, session.ID)
+		if err != nil {
+			t.Fatalf("Active: %v", err)
+		}
+		if active != want[i] {
+			t.Errorf("session %d active = %v, want %v", i, active, want[i])
+		}
+	}
+}
+
+func TestResetPasswordRevokesSessions(t *testing.T) {
+	users, db := newAdminUserService(t, services.LoginPolicy{})
+	user := createAdminUser(t, db, "admin@example.com")
+	sessions, tokens := startSessions(t, users, 2)
+	checkActive(t, users, user.ID, sessions, true, true)
+
+	if _, _, err := users.ResetPassword(user.ID); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+
+	checkActive(t, users, user.ID, sessions, false, false)
+	if _, _, _, err := users.RefreshSession(tokens[0], client); err == nil {
+		t.Error("RefreshSession after ResetPassword succeeded")
+	}
+	if _, err := users.Login("admin@example.com", adminPassword, "", client); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Errorf("Login with the old password = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	users, db := newAdminUserService(t, services.LoginPolicy{})
+	user := createAdminUser(t, db, "admin@example.com")
+	sessions, tokens := startSessions(t, users, 2)
+
+	if err := users.ChangePassword(user.ID, sessions[0].ID, "wrong password", "a new passphrase"); !errors.Is(err, services.ErrIncorrectPassword) {
+		t.Fatalf("ChangePassword with a wrong password = %v, want ErrIncorrectPassword", err)
+	}
+	checkActive(t, users, user.ID, sessions, true, true)
+
+	if err := users.ChangePassword(user.ID, sessions[0].ID, adminPassword, "a new passphrase"); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	checkActive(t, users, user.ID, sessions, true, false)
+	if _, _, _, err := users.RefreshSession(tokens[1], client); err == nil {
+		t.Error("RefreshSession of a revoked session succeeded")
+	}
+	if _, err := users.Login("admin@example.com", adminPassword, "", client); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Errorf("Login with the old password = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := users.Login("admin@example.com", "a new passphrase", "", client); err != nil {
+		t.Errorf("Login with the new password: %v", err)
+	}
+}
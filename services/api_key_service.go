@@ -0,0 +1,161 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file defines the APIKeyService, which issues, rotates, revokes and verifies the
+// API keys used to authenticate against the API.
+package services
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix starts every issued key, so that leaked keys are easy to recognize.
+const apiKeyPrefix = "acf_"
+
+// lastUsedResolution is how often the last use of a key is written to the database.
+const lastUsedResolution = time.Minute
+
+// ErrInvalidAPIKey is returned when a presented key is unknown, revoked or expired.
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// ErrInvalidRole is returned when a key is requested with an unknown role.
+var ErrInvalidRole = errors.New("role must be public or admin")
+
+// APIKeyService defines the business logic interface for API key operations.
+type APIKeyService interface {
+	// IssueKey creates a new key and returns its record together with the key itself,
+	// which is not stored and cannot be retrieved later.
+	IssueKey(name string, role models.Role, expiresAt *time.Time) (*models.APIKey, string, error)
+	// ListKeys retrieves every issued key.
+	ListKeys() ([]models.APIKey, error)
+	// RotateKey issues a replacement of the key identified by its ID, with the same name,
+	// role and expiry. The old key stays valid for the given grace period.
+	RotateKey(id uint, grace time.Duration) (*models.APIKey, string, error)
+	// RevokeKey immediately invalidates the key identified by its ID.
+	RevokeKey(id uint) (*models.APIKey, error)
+	// Authenticate returns the active key matching the presented key.
+	Authenticate(key string) (*models.APIKey, error)
+}
+
+// apiKeyService is the concrete implementation of APIKeyService.
+type apiKeyService struct {
+	repository repositories.APIKeyRepository
+}
+
+// NewAPIKeyService creates a new instance of APIKeyService with the provided APIKeyRepository.
+func NewAPIKeyService(repository repositories.APIKeyRepository) APIKeyService {
+	return &apiKeyService{repository: repository}
+}
+
+// IssueKey generates a random key and stores its hash.
+func (s *apiKeyService) IssueKey(name string, role models.Role, expiresAt *time.Time) (*models.APIKey, string, error) {
+	if !role.Valid() {
+		return nil, "", ErrInvalidRole
+	}
+
+	// Generate 32 random bytes, shown to the caller once.
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	record := &models.APIKey{
+		Name:      name,
+		Prefix:    key[:len(apiKeyPrefix)+8],
+		KeyHash:   HashAPIKey(key),
+		Role:      role,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repository.Create(record); err != nil {
+		return nil, "", err
+	}
+	return record, key, nil
+}
+
+// ListKeys retrieves every issued key from the repository.
+func (s *apiKeyService) ListKeys() ([]models.APIKey, error) {
+	return s.repository.FindAll()
+}
+
+// RotateKey issues the replacement key first, then shortens the lifetime of the old
+// key to the grace period, so that clients can switch over without downtime.
+func (s *apiKeyService) RotateKey(id uint, grace time.Duration) (*models.APIKey, string, error) {
+	old, err := s.repository.FindByID(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if old.RevokedAt != nil {
+		return nil, "", ErrInvalidAPIKey
+	}
+
+	record, key, err := s.IssueKey(old.Name, old.Role, old.ExpiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Retire the old key once the grace period has elapsed.
+	now := time.Now()
+	if grace <= 0 {
+		old.RevokedAt = &now
+	} else if retireAt := now.Add(grace); old.ExpiresAt == nil || old.ExpiresAt.After(retireAt) {
+		old.ExpiresAt = &retireAt
+	}
+	if err := s.repository.Update(old); err != nil {
+		return nil, "", err
+	}
+	return record, key, nil
+}
+
+// RevokeKey sets the revocation time of the key identified by its ID.
+func (s *apiKeyService) RevokeKey(id uint) (*models.APIKey, error) {
+	key, err := s.repository.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		key.RevokedAt = &now
+		if err := s.repository.Update(key); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Authenticate looks up the hash of the presented key and records its use.
+func (s *apiKeyService) Authenticate(key string) (*models.APIKey, error) {
+	record, err := s.repository.FindActiveByHash(HashAPIKey(key))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the use of the key, at most once per resolution so that busy keys
+	// do not cause a write on every request.
+	now := time.Now()
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= lastUsedResolution {
+		if err := s.repository.TouchLastUsed(record.ID, now); err != nil {
+			log.Printf("Failed to record use of API key %d: %v", record.ID, err)
+		}
+		record.LastUsedAt = &now
+	}
+	return record, nil
+}
+
+// HashAPIKey returns the hex SHA-256 of a key, as stored in models.APIKey.KeyHash.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
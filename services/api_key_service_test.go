@@ -0,0 +1,133 @@
+package services_test
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/services"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// openDB opens an empty SQLite database with the tables of models.
+func openDB(t *testing.T, models ...any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "services.db")), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func newAPIKeyService(t *testing.T) services.APIKeyService {
+	return services.NewAPIKeyService(repositories.NewAPIKeyRepository(openDB(t, &models.APIKey{})))
+}
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	keys := newAPIKeyService(t)
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	record, key, err := keys.IssueKey("ci", models.RoleViewer, &future)
+	if err != nil {
+		t.Fatalf("IssueKey: %v", err)
+	}
+	_, expiredKey, err := keys.IssueKey("expired", models.RoleAdmin, &past)
+	if err != nil {
+		t.Fatalf("IssueKey: %v", err)
+	}
+	revoked, revokedKey, err := keys.IssueKey("revoked", models.RoleAdmin, nil)
+	if err != nil {
+		t.Fatalf("IssueKey: %v", err)
+	}
+	if _, err := keys.RevokeKey(revoked.ID); err != nil {
+		t.Fatalf("RevokeKey: %v", err)
+	}
+
+	got, err := keys.Authenticate(key)
+	if err != nil {
+		t.Fatalf("Authenticate(issued key): %v", err)
+	}
+	if got.ID != record.ID || got.Role != models.RoleViewer || got.LastUsedAt == nil {
+		t.Errorf("Authenticate(issued key) = %+v, want key %d with the viewer role and its last use", got, record.ID)
+	}
+	for name, key := range map[string]string{
+		"expired key": expiredKey,
+		"revoked key": revokedKey,
+		"unknown key": "acf_unknown",
+		"empty key":   "",
+	} {
+		if _, err := keys.Authenticate(key); !errors.Is(err, services.ErrInvalidAPIKey) {
+			t.Errorf("Authenticate(%s) = %v, want ErrInvalidAPIKey", name, err)
+		}
+	}
+}
+
+func TestAPIKeyIssueRejectsUnknownRoles(t *testing.T) {
+	keys := newAPIKeyService(t)
+	if _, _, err := keys.IssueKey("ci", models.Role("root"), nil); !errors.Is(err, services.ErrInvalidRole) {
+		t.Errorf("IssueKey(root) = %v, want ErrInvalidRole", err)
+	}
+}
+
+func TestAPIKeyRotate(t *testing.T) {
+	tests := []struct {
+		name     string
+		grace    time.Duration
+		oldValid bool
+	}{
+		{"with a grace period", time.Hour, true},
+		{"without a grace period", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := newAPIKeyService(t)
+			old, oldKey, err := keys.IssueKey("ci", models.RoleAdmin, nil)
+			if err != nil {
+				t.Fatalf("IssueKey: %v", err)
+			}
+
+			replacement, newKey, err := keys.RotateKey(old.ID, tt.grace)
+			if err != nil {
+				t.Fatalf("RotateKey: %v", err)
+			}
+
+			if replacement.Name != old.Name || replacement.Role != old.Role {
+				t.Errorf("replacement = %q %s, want %q %s", replacement.Name, replacement.Role, old.Name, old.Role)
+			}
+			if _, err := keys.Authenticate(newKey); err != nil {
+				t.Errorf("Authenticate(new key): %v", err)
+			}
+			if _, err := keys.Authenticate(oldKey); (err == nil) != tt.oldValid {
+				t.Errorf("Authenticate(old key) = %v, want valid %v", err, tt.oldValid)
+			}
+		})
+	}
+}
+
+func TestAPIKeyRotateRevokedKey(t *testing.T) {
+	keys := newAPIKeyService(t)
+	record, _, err := keys.IssueKey("ci", models.RoleAdmin, nil)
+	if err != nil {
+		t.Fatalf("IssueKey: %v", err)
+	}
+	if _, err := keys.RevokeKey(record.ID); err != nil {
+		t.Fatalf("RevokeKey: %v", err)
+	}
+
+	if _, _, err := keys.RotateKey(record.ID, time.Hour); !errors.Is(err, services.ErrInvalidAPIKey) {
+		t.Errorf("RotateKey(revoked key) = %v, want ErrInvalidAPIKey", err)
+	}
+}
@@ -0,0 +1,31 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file defines the APIUsageService, which reports the usage of the API per caller
+// and endpoint.
+package services
+
+import (
+	"api-contact-form/repositories"
+	"context"
+)
+
+// APIUsageService defines the business logic interface for API usage statistics.
+type APIUsageService interface {
+	// SummarizeUsage sums the usage matching the filter per caller and endpoint, busiest first.
+	SummarizeUsage(ctx context.Context, filter repositories.APIUsageFilter) ([]repositories.APIUsageSummary, error)
+}
+
+// apiUsageService is the concrete implementation of APIUsageService.
+type apiUsageService struct {
+	repository repositories.APIUsageRepository
+}
+
+// NewAPIUsageService creates a new instance of APIUsageService with the provided APIUsageRepository.
+func NewAPIUsageService(repository repositories.APIUsageRepository) APIUsageService {
+	return &apiUsageService{repository: repository}
+}
+
+// SummarizeUsage retrieves the sums of the matching usage from the repository.
+func (s *apiUsageService) SummarizeUsage(ctx context.Context, filter repositories.APIUsageFilter) ([]repositories.APIUsageSummary, error) {
+	return s.repository.Summarize(ctx, filter)
+}
@@ -0,0 +1,298 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file defines the AttachmentService, which checks the files uploaded with a contact
+// submission against the attachment policy, keeps them in the attachment storage and
+// serves them back to the team, with the previews of the images.
+package services
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/storage"
+	"api-contact-form/thumbnails"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AttachmentPolicy limits the files that can be attached to a submission.
+type AttachmentPolicy struct {
+	// MaxCount is the maximum number of files per submission.
+	MaxCount int
+	// MaxSize is the maximum size of a single file in bytes.
+	MaxSize int64
+	// AllowedTypes lists the accepted media types, as detected from the file content.
+	AllowedTypes []string
+}
+
+// AttachmentService defines the business logic interface for attachment operations.
+type AttachmentService interface {
+	// Validate checks the uploaded files against the policy, returning a *ValidationError
+	// for the "attachments" field when any is rejected.
+	Validate(files []*multipart.FileHeader) error
+	// Upload validates the files and stores them, returning the attachments to record
+	// together with the contact they belong to.
+	Upload(ctx context.Context, files []*multipart.FileHeader) ([]models.Attachment, error)
+	// Attach validates the files and stores them as attachments of an existing contact,
+	// such as those of an email reply to its conversation.
+	Attach(ctx context.Context, contactID uint, files []*multipart.FileHeader) ([]models.Attachment, error)
+	// Recorded queues the generation of the previews of the images among attachments, once
+	// they are recorded together with their contact.
+	Recorded(attachments []models.Attachment)
+	// Discard removes the stored files of attachments and their previews, e.g. when their
+	// contact could not be created or was purged. Failures are logged.
+	Discard(ctx context.Context, attachments []models.Attachment)
+	// List retrieves the attachments of the contact identified by its ID.
+	List(contactID uint) ([]models.Attachment, error)
+	// Open retrieves an attachment of a contact with a reader of its content, which the
+	// caller must close.
+	Open(ctx context.Context, contactID, id uint) (*models.Attachment, io.ReadCloser, error)
+	// OpenThumbnail retrieves an attachment of a contact with a reader of its preview, which
+	// the caller must close. It returns storage.ErrNotFound when the attachment has no preview.
+	OpenThumbnail(ctx context.Context, contactID, id uint) (*models.Attachment, io.ReadCloser, error)
+	// Expired retrieves up to limit attachments uploaded before the given time, of contacts
+	// not under legal hold, oldest first.
+	Expired(before time.Time, limit int) ([]models.Attachment, error)
+	// Purge removes attachments of the contact identified by its ID with their stored files,
+	// records the time of the purge on the contact and returns the removed attachments. Nothing
+	// is removed from contacts under legal hold.
+	Purge(ctx context.Context, contactID uint, attachments []models.Attachment, at time.Time) ([]models.Attachment, error)
+}
+
+// attachmentService is the concrete implementation of AttachmentService.
+type attachmentService struct {
+	repository repositories.AttachmentRepository
+	storage    storage.Storage
+	policy     AttachmentPolicy
+	thumbnails *thumbnails.Generator
+}
+
+// NewAttachmentService creates a new instance of AttachmentService with the provided
+// AttachmentRepository, keeping the files in store and accepting those allowed by policy.
+// The previews of the images are generated by previews, or not at all when it is nil.
+func NewAttachmentService(repository repositories.AttachmentRepository, store storage.Storage, policy AttachmentPolicy, previews *thumbnails.Generator) AttachmentService {
+	return &attachmentService{
+		repository: repository,
+		storage:    store,
+		policy:     policy,
+		thumbnails: previews,
+	}
+}
+
+// Validate checks the number of files, then the size and detected type of each of them.
+func (s *attachmentService) Validate(files []*multipart.FileHeader) error {
+	_, err := s.inspect(files)
+	return err
+}
+
+// Upload stores every file under a new random key. When storing a file fails, the files
+// stored before it are removed again.
+func (s *attachmentService) Upload(ctx context.Context, files []*multipart.FileHeader) ([]models.Attachment, error) {
+	// Validate the files and detect their types
+	attachments, err := s.inspect(files)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the files
+	for i, file := range files {
+		key, err := newStorageKey()
+		if err == nil {
+			err = s.put(ctx, key, file, attachments[i].ContentType)
+		}
+		if err != nil {
+			s.Discard(ctx, attachments[:i])
+			return nil, fmt.Errorf("store attachment %q: %w", attachments[i].Filename, err)
+		}
+		attachments[i].StorageKey = key
+	}
+	return attachments, nil
+}
+
+// Attach uploads the files, then records them for the contact. When recording them fails,
+// the stored files are removed again.
+func (s *attachmentService) Attach(ctx context.Context, contactID uint, files []*multipart.FileHeader) ([]models.Attachment, error) {
+	attachments, err := s.Upload(ctx, files)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		attachments[i].ContactID = contactID
+	}
+	if err := s.repository.Create(attachments); err != nil {
+		s.Discard(ctx, attachments)
+		return nil, err
+	}
+	s.Recorded(attachments)
+	return attachments, nil
+}
+
+// Recorded hands the attachments to the thumbnail generator, which skips other files than images.
+func (s *attachmentService) Recorded(attachments []models.Attachment) {
+	s.thumbnails.Enqueue(attachments)
+}
+
+// Discard deletes the stored file of every attachment, and its preview. The preview of an
+// image is deleted even when its key was not recorded yet, as it may have been stored already.
+func (s *attachmentService) Discard(ctx context.Context, attachments []models.Attachment) {
+	for _, attachment := range attachments {
+		keys := []string{attachment.StorageKey}
+		if attachment.ThumbnailKey != "" || thumbnails.Supported(attachment.ContentType) {
+			keys = append(keys, thumbnails.Key(attachment))
+		}
+		for _, key := range keys {
+			if err := s.storage.Delete(ctx, key); err != nil {
+				log.Printf("Failed to delete stored attachment %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// List retrieves the attachments of the contact from the repository.
+func (s *attachmentService) List(contactID uint) ([]models.Attachment, error) {
+	return s.repository.FindByContact(contactID)
+}
+
+// Open looks the attachment up and opens its stored file.
+func (s *attachmentService) Open(ctx context.Context, contactID, id uint) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.repository.FindByID(contactID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := s.storage.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, reader, nil
+}
+
+// OpenThumbnail looks the attachment up and opens the stored file of its preview.
+func (s *attachmentService) OpenThumbnail(ctx context.Context, contactID, id uint) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.repository.FindByID(contactID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment.ThumbnailKey == "" {
+		return nil, nil, storage.ErrNotFound
+	}
+	reader, err := s.storage.Open(ctx, attachment.ThumbnailKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, reader, nil
+}
+
+// Expired retrieves the expired attachments from the repository.
+func (s *attachmentService) Expired(before time.Time, limit int) ([]models.Attachment, error) {
+	return s.repository.FindExpired(before, limit)
+}
+
+// Purge removes the records of the attachments first, then their files, so that no record
+// is left pointing to a removed file.
+func (s *attachmentService) Purge(ctx context.Context, contactID uint, attachments []models.Attachment, at time.Time) ([]models.Attachment, error) {
+	ids := make([]uint, len(attachments))
+	for i, attachment := range attachments {
+		ids[i] = attachment.ID
+	}
+	purged, err := s.repository.Purge(contactID, ids, at)
+	if err != nil {
+		return nil, err
+	}
+	s.Discard(ctx, purged)
+	return purged, nil
+}
+
+// inspect checks the files against the policy and returns an attachment, without storage
+// key, for each of them. The type of a file is sniffed from its first 512 bytes rather than
+// taken from the client, which can claim any type.
+func (s *attachmentService) inspect(files []*multipart.FileHeader) ([]models.Attachment, error) {
+	if s.policy.MaxCount > 0 && len(files) > s.policy.MaxCount {
+		return nil, attachmentError(fmt.Sprintf("must contain at most %d files", s.policy.MaxCount))
+	}
+
+	attachments := make([]models.Attachment, 0, len(files))
+	for _, file := range files {
+		name := filepath.Base(strings.ReplaceAll(file.Filename, "\\", "/"))
+		if s.policy.MaxSize > 0 && file.Size > s.policy.MaxSize {
+			return nil, attachmentError(fmt.Sprintf("file %q must be at most %d bytes", name, s.policy.MaxSize))
+		}
+		if file.Size == 0 {
+			return nil, attachmentError(fmt.Sprintf("file %q must not be empty", name))
+		}
+
+		contentType, err := sniffContentType(file)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(s.policy.AllowedTypes, contentType) {
+			return nil, attachmentError(fmt.Sprintf("file %q has a type that is not allowed: %s", name, contentType))
+		}
+
+		attachments = append(attachments, models.Attachment{
+			Filename:    truncate(name, 255),
+			ContentType: contentType,
+			Size:        file.Size,
+		})
+	}
+	return attachments, nil
+}
+
+// put copies an uploaded file into the storage.
+func (s *attachmentService) put(ctx context.Context, key string, file *multipart.FileHeader, contentType string) error {
+	f, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.storage.Put(ctx, key, f, file.Size, contentType)
+}
+
+// sniffContentType detects the media type of an uploaded file from its content, without
+// parameters such as the charset.
+func sniffContentType(file *multipart.FileHeader) (string, error) {
+	f, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head[:n]), ";")
+	return contentType, nil
+}
+
+// newStorageKey returns a random storage key, prefixed with the upload date so that
+// stored files are spread over directories.
+func newStorageKey() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return "attachments/" + time.Now().UTC().Format("2006/01/02") + "/" + hex.EncodeToString(random), nil
+}
+
+// attachmentError returns a *ValidationError of the "attachments" field.
+func attachmentError(message string) error {
+	return &ValidationError{Fields: []FieldError{{Field: "attachments", Message: message}}}
+}
+
+// truncate shortens s to at most n bytes, dropping a rune cut in half.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,101 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file defines the AutoReplyService, which manages the templates of the auto-replies
+// emailed to submitters, one per language code.
+package services
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/notifications"
+	"api-contact-form/repositories"
+	"api-contact-form/requests"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrInvalidLanguage is returned for language codes that are not BCP 47 language tags.
+var ErrInvalidLanguage = errors.New("invalid language code, expected e.g. en or pt-BR")
+
+// ErrInvalidAutoReplyTemplate is returned for templates that cannot be parsed.
+var ErrInvalidAutoReplyTemplate = errors.New("invalid auto-reply template")
+
+// AutoReplyService defines the business logic interface for auto-reply templates.
+type AutoReplyService interface {
+	// ListTemplates retrieves the template of every language.
+	ListTemplates(ctx context.Context) ([]models.AutoReplyTemplate, error)
+	// GetTemplate retrieves the template of a language.
+	GetTemplate(ctx context.Context, language string) (*models.AutoReplyTemplate, error)
+	// SaveTemplate creates or replaces the template of a language.
+	SaveTemplate(ctx context.Context, language string, req *requests.AutoReplyTemplateRequest) (*models.AutoReplyTemplate, error)
+	// DeleteTemplate removes the template of a language.
+	DeleteTemplate(ctx context.Context, language string) error
+}
+
+// autoReplyService is the concrete implementation of AutoReplyService.
+type autoReplyService struct {
+	repository repositories.AutoReplyTemplateRepository
+	validate   *validator.Validate
+}
+
+// NewAutoReplyService creates a new instance of AutoReplyService with the provided AutoReplyTemplateRepository.
+func NewAutoReplyService(repository repositories.AutoReplyTemplateRepository) AutoReplyService {
+	return &autoReplyService{repository: repository, validate: validator.New()}
+}
+
+// ListTemplates retrieves every template from the repository.
+func (s *autoReplyService) ListTemplates(ctx context.Context) ([]models.AutoReplyTemplate, error) {
+	return s.repository.FindAll(ctx)
+}
+
+// GetTemplate retrieves the template of a language from the repository. It returns
+// ErrInvalidLanguage for invalid language codes, and gorm.ErrRecordNotFound when the
+// language has no template.
+func (s *autoReplyService) GetTemplate(ctx context.Context, language string) (*models.AutoReplyTemplate, error) {
+	language, err := s.checkLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	return s.repository.FindByLanguage(ctx, language)
+}
+
+// SaveTemplate checks that the subject and body parse as templates and stores them. It
+// returns ErrInvalidLanguage for invalid language codes, and an error wrapping
+// ErrInvalidAutoReplyTemplate for templates that do not parse.
+func (s *autoReplyService) SaveTemplate(ctx context.Context, language string, req *requests.AutoReplyTemplateRequest) (*models.AutoReplyTemplate, error) {
+	language, err := s.checkLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &models.AutoReplyTemplate{Language: language, Subject: req.Subject, Body: req.Body}
+	if _, _, err := notifications.ParseAutoReplyTemplate(template); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAutoReplyTemplate, err)
+	}
+	if err := s.repository.Save(ctx, template); err != nil {
+		return nil, err
+	}
+	return s.repository.FindByLanguage(ctx, language)
+}
+
+// DeleteTemplate deletes the template of a language from the repository. It returns
+// ErrInvalidLanguage for invalid language codes, and gorm.ErrRecordNotFound when the
+// language has no template.
+func (s *autoReplyService) DeleteTemplate(ctx context.Context, language string) error {
+	language, err := s.checkLanguage(language)
+	if err != nil {
+		return err
+	}
+	return s.repository.Delete(ctx, language)
+}
+
+// checkLanguage normalizes language and checks that it is a BCP 47 language tag.
+func (s *autoReplyService) checkLanguage(language string) (string, error) {
+	language = NormalizeLanguage(language)
+	if len(language) > 35 || s.validate.Var(language, "required,bcp47_language_tag") != nil {
+		return "", ErrInvalidLanguage
+	}
+	return language, nil
+}
@@ -0,0 +1,54 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file implements the changelog of the instance: the ChangeLogService records the
+// settings changes and template edits made through the API, next to the schema
+// migrations recorded at startup, and reads them back.
+package services
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"context"
+	"encoding/json"
+	"log"
+)
+
+// ChangeLogService defines the business logic interface for the changelog.
+type ChangeLogService interface {
+	// Record adds a change made by actor to the changelog. details is encoded as JSON and
+	// may be nil. The change is already made, so failures are only logged.
+	Record(ctx context.Context, kind models.ChangeKind, actor, subject, summary string, details any)
+	// ListChanges retrieves the most recent changes matching the filter, newest first.
+	ListChanges(ctx context.Context, filter repositories.SystemChangeFilter) ([]models.SystemChange, error)
+}
+
+// changeLogService is the concrete implementation of ChangeLogService.
+type changeLogService struct {
+	repository repositories.SystemChangeRepository
+}
+
+// NewChangeLogService creates a new instance of ChangeLogService with the provided SystemChangeRepository.
+func NewChangeLogService(repository repositories.SystemChangeRepository) ChangeLogService {
+	return &changeLogService{repository: repository}
+}
+
+// Record stores the change in the repository.
+func (s *changeLogService) Record(ctx context.Context, kind models.ChangeKind, actor, subject, summary string, details any) {
+	change := &models.SystemChange{Kind: kind, Actor: actor, Subject: subject, Summary: summary}
+	if details != nil {
+		encoded, err := json.Marshal(details)
+		if err != nil {
+			log.Printf("Failed to encode the details of the %s change of %s: %v", kind, subject, err)
+		} else {
+			change.Details = string(encoded)
+		}
+	}
+	if err := s.repository.Create(context.WithoutCancel(ctx), change); err != nil {
+		log.Printf("Failed to record the %s change of %s: %v", kind, subject, err)
+	}
+}
+
+// ListChanges retrieves the matching entries from the repository.
+func (s *changeLogService) ListChanges(ctx context.Context, filter repositories.SystemChangeFilter) ([]models.SystemChange, error) {
+	return s.repository.FindRecent(ctx, filter)
+}
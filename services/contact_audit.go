@@ -0,0 +1,143 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file implements the audit trail of the changes made to contacts: the entries the
+// ContactService records for every change with the values before and after it, and the
+// AuditService reading the trail back.
+package services
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"maps"
+	"slices"
+	"time"
+)
+
+// AnonymousActor is the actor recorded for changes made while authentication is disabled.
+const AnonymousActor = "anonymous"
+
+// AuditService defines the business logic interface for reading the audit trail.
+type AuditService interface {
+	// ListAuditLogs retrieves the most recent changes matching the filter, newest first.
+	ListAuditLogs(filter repositories.AuditLogFilter) ([]models.AuditLog, error)
+}
+
+// auditService is the concrete implementation of AuditService.
+type auditService struct {
+	repository repositories.AuditLogRepository
+}
+
+// NewAuditService creates a new instance of AuditService with the provided AuditLogRepository.
+func NewAuditService(repository repositories.AuditLogRepository) AuditService {
+	return &auditService{repository: repository}
+}
+
+// ListAuditLogs retrieves the matching entries from the repository.
+func (s *auditService) ListAuditLogs(filter repositories.AuditLogFilter) ([]models.AuditLog, error) {
+	return s.repository.FindRecent(filter)
+}
+
+// WithAuditLog records every change made to contacts through the service in audits.
+func WithAuditLog(audits repositories.AuditLogRepository) ContactServiceOption {
+	return func(s *contactService) {
+		s.audits = audits
+	}
+}
+
+// auditChange is the value of a field before and after a change, and for merges, the
+// values of the merged contacts that lost.
+type auditChange struct {
+	Before    any          `json:"before"`
+	After     any          `json:"after"`
+	Discarded []MergeValue `json:"discarded,omitempty"`
+}
+
+// GetAuditTrails retrieves the entries of the audit trail of the contacts, oldest first,
+// such as for a data subject access request.
+func (s *contactService) GetAuditTrails(ctx context.Context, contactIDs []uint) ([]models.AuditLog, error) {
+	if s.audits == nil {
+		return []models.AuditLog{}, nil
+	}
+	return s.audits.FindByContacts(contactIDs)
+}
+
+// recordAudit stores the entries of the audit trail. The changes they describe are already
+// made, so failures are only logged.
+func (s *contactService) recordAudit(entries ...models.AuditLog) {
+	if s.audits == nil || len(entries) == 0 {
+		return
+	}
+	if err := s.audits.Create(entries); err != nil {
+		log.Printf("Failed to record %d audit log entries: %v", len(entries), err)
+	}
+}
+
+// newAuditLog describes the change of a contact by actor from before to after. Either
+// may be nil, for contacts that did not exist before or do not exist anymore.
+func newAuditLog(actor string, action models.AuditAction, before, after *models.Contact) models.AuditLog {
+	entry := models.AuditLog{Actor: actor, Action: action}
+	if before != nil {
+		entry.ContactID = before.ID
+	} else if after != nil {
+		entry.ContactID = after.ID
+	}
+
+	// Keep the fields whose JSON values differ
+	old, updated := auditedFields(before), auditedFields(after)
+	changes := make(map[string]auditChange)
+	for _, field := range slices.Sorted(maps.Keys(old)) {
+		oldJSON, _ := json.Marshal(old[field])
+		newJSON, _ := json.Marshal(updated[field])
+		if !bytes.Equal(oldJSON, newJSON) {
+			changes[field] = auditChange{Before: old[field], After: updated[field]}
+		}
+	}
+	entry.Changes = encodeAuditChanges(entry.ContactID, changes)
+	return entry
+}
+
+// encodeAuditChanges encodes the changes of an entry of the audit trail of a contact.
+func encodeAuditChanges(contactID uint, changes map[string]auditChange) string {
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		log.Printf("Failed to encode the audit log changes of contact %d: %v", contactID, err)
+		encoded = []byte("{}")
+	}
+	return string(encoded)
+}
+
+// auditedFields returns the values of the fields of a contact recorded in the audit trail,
+// keyed by their JSON names. Every value is nil for a nil contact.
+func auditedFields(contact *models.Contact) map[string]any {
+	if contact == nil {
+		return map[string]any{
+			"name": nil, "email": nil, "phone": nil, "message": nil, "status": nil,
+			"legal_hold": nil, "pinned": nil, "snoozed_until": nil, "merged_into_id": nil, "duplicate_of_id": nil,
+			"anonymized_at": nil, "email_issue": nil, "deleted_at": nil,
+		}
+	}
+
+	var deletedAt *time.Time
+	if contact.DeletedAt.Valid {
+		deletedAt = &contact.DeletedAt.Time
+	}
+	return map[string]any{
+		"name":            contact.FullName,
+		"email":           contact.Email,
+		"phone":           contact.Phone,
+		"message":         contact.Message,
+		"status":          contact.Status,
+		"legal_hold":      contact.LegalHold,
+		"pinned":          contact.Pinned,
+		"snoozed_until":   contact.SnoozedUntil,
+		"merged_into_id":  contact.MergedIntoID,
+		"duplicate_of_id": contact.DuplicateOfID,
+		"anonymized_at":   contact.AnonymizedAt,
+		"email_issue":     contact.EmailIssue,
+		"deleted_at":      deletedAt,
+	}
+}
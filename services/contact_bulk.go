@@ -0,0 +1,282 @@
+// Package services provides business logic implementations for contact-related operations
+// in the API Contact Form application.
+//
+// This file implements the bulk operations of the ContactService: importing legacy
+// contacts in batches, and deleting or changing the status of several contacts at once.
+// Each item is checked on its own, so that the items that can be processed are, and the
+// others are reported with the reason they were skipped.
+package services
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/requests"
+	"api-contact-form/rules"
+	"cmp"
+	"context"
+	"errors"
+	"log"
+	"slices"
+)
+
+// BulkResult reports the outcome of a bulk operation on existing contacts.
+type BulkResult struct {
+	// Succeeded lists the IDs of the contacts that were processed.
+	Succeeded []uint
+	// Failed lists the contacts that were skipped, with the reason.
+	Failed []BulkFailure
+}
+
+// BulkFailure describes a contact skipped by a bulk operation.
+type BulkFailure struct {
+	// ID is the ID of the contact.
+	ID uint
+	// Reason explains why the contact was skipped.
+	Reason string
+}
+
+// ImportResult reports the outcome of an import.
+type ImportResult struct {
+	// Created lists the IDs of the imported contacts, in the order of the request.
+	Created []uint
+	// Failed lists the contacts that were rejected, by their position in the request.
+	Failed []ImportFailure
+}
+
+// ImportFailure describes a contact rejected by an import.
+type ImportFailure struct {
+	// Index is the position of the contact in the request, starting at 0.
+	Index int
+	// Reason explains why the contact was rejected.
+	Reason string
+	// Fields lists the invalid fields, when the contact failed validation.
+	Fields []FieldError
+}
+
+// ImportContacts validates every contact and inserts the valid ones in batches, in a single
+// transaction. Imported contacts keep their original submission time when it is given and
+// are stored with the import channel; as they are not new submissions, no hooks,
+// notifications or webhooks run for them, and they only enter the inbox.
+func (s *contactService) ImportContacts(ctx context.Context, reqs []requests.ImportContactRequest) (*ImportResult, error) {
+	result := &ImportResult{}
+	contacts := make([]models.Contact, 0, len(reqs))
+
+	// Validate every contact, collecting the rejected ones
+	for i := range reqs {
+		req := &reqs[i]
+		normalizeContactRequest(&req.ContactRequest)
+		fields := contactRequestFields(&req.ContactRequest)
+		err := s.validateStruct(&req.ContactRequest)
+		if err == nil {
+			err = s.rules.Current().Validate(fields)
+		}
+		if err != nil {
+			result.Failed = append(result.Failed, importFailure(i, err))
+			continue
+		}
+
+		contact := models.Contact{
+			FullName: req.Name,
+			Email:    req.Email,
+			Phone:    req.Phone,
+			Message:  req.Message,
+			Channel:  models.ChannelImport,
+			Status:   models.StatusNew,
+
+			FingerprintHash: hashFingerprint(req.Fingerprint),
+			MessageHash:     hashMessage(req.Message),
+			LeadScore:       s.rules.Current().Score(fields),
+			Language:        contactLanguage(req.Lang, req.Message),
+		}
+		company := s.enricher.Derive(req.Email)
+		contact.CompanyDomain, contact.CompanyName = company.Domain, company.Name
+		if req.CreatedAt != nil {
+			contact.CreatedAt = *req.CreatedAt
+			contact.UpdatedAt = *req.CreatedAt
+		}
+		if req.Consent != nil && *req.Consent {
+			contact.ConsentGiven = true
+			contact.ConsentVersion = req.ConsentVersion
+			contact.ConsentAt = req.CreatedAt
+		}
+		contacts = append(contacts, contact)
+	}
+
+	// Insert the valid contacts in batches
+	if len(contacts) > 0 {
+		if err := s.repository.CreateBatch(ctx, contacts); err != nil {
+			return nil, err
+		}
+	}
+	for i := range contacts {
+		result.Created = append(result.Created, contacts[i].ID)
+		s.inbox.Apply(models.EventContactCreated, &contacts[i])
+	}
+
+	log.Printf("Imported %d contacts, rejected %d", len(result.Created), len(result.Failed))
+	return result, nil
+}
+
+// DeleteContacts soft-deletes the contacts that exist and are not under legal hold, in a
+// single transaction, or purges them with DeleteHard, and reports the others. With dryRun, the contacts that would be
+// deleted are reported as succeeded without being deleted.
+func (s *contactService) DeleteContacts(ctx context.Context, actor string, ids []uint, dryRun bool) (*BulkResult, error) {
+	var result *BulkResult
+	var deletable []models.Contact
+	var attachments []models.Attachment
+	err := s.repository.WithTx(ctx, func(repo repositories.ContactRepository) error {
+		contacts, found, err := findBulkTargets(ctx, repo, ids)
+		if err != nil {
+			return err
+		}
+		result = found
+
+		// Skip the contacts under legal hold
+		for _, contact := range contacts {
+			if contact.LegalHold {
+				result.Failed = append(result.Failed, BulkFailure{ID: contact.ID, Reason: ErrLegalHold.Error()})
+				continue
+			}
+			deletable = append(deletable, contact)
+		}
+		if dryRun || len(deletable) == 0 {
+			return nil
+		}
+		if attachments, err = s.purgedAttachments(contactIDs(deletable)); err != nil {
+			return err
+		}
+		if err := repo.DeleteByIDs(ctx, contactIDs(deletable)); err != nil {
+			return err
+		}
+		return s.purgeDeleted(ctx, repo, contactIDs(deletable))
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.discardAttachments(attachments)
+	if dryRun {
+		result.Succeeded = append(result.Succeeded, contactIDs(deletable)...)
+		return sortBulkResult(result), nil
+	}
+	if len(deletable) > 0 {
+		log.Printf("Contacts %v deleted", contactIDs(deletable))
+	}
+
+	entries := make([]models.AuditLog, 0, len(deletable))
+	for _, contact := range deletable {
+		result.Succeeded = append(result.Succeeded, contact.ID)
+		entries = append(entries, s.deletionAuditLog(actor, models.AuditDeleted, &contact, deletedContact(contact)))
+		s.publish(models.EventContactDeleted, contact)
+	}
+	s.recordAudit(entries...)
+	return sortBulkResult(result), nil
+}
+
+// UpdateStatuses sets the status of the contacts that exist and allow the transition, in
+// a single transaction, and reports the others. Transitions requiring a review are only
+// allowed when reviewed is true. Contacts already having the status count as succeeded.
+func (s *contactService) UpdateStatuses(ctx context.Context, actor string, ids []uint, status models.Status, reviewed bool) (*BulkResult, error) {
+	var result *BulkResult
+	var changed, updated []models.Contact
+	err := s.repository.WithTx(ctx, func(repo repositories.ContactRepository) error {
+		contacts, found, err := findBulkTargets(ctx, repo, ids)
+		if err != nil {
+			return err
+		}
+		result = found
+
+		// Skip the contacts that cannot move to the status
+		for _, contact := range contacts {
+			if contact.Status == status {
+				result.Succeeded = append(result.Succeeded, contact.ID)
+			} else if err := checkTransition(contact.Status, status, reviewed); err != nil {
+				result.Failed = append(result.Failed, BulkFailure{ID: contact.ID, Reason: err.Error()})
+			} else {
+				changed = append(changed, contact)
+			}
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+
+		// Read the stored values back, to publish the updates as committed
+		if err := repo.UpdateStatusByIDs(ctx, contactIDs(changed), status); err != nil {
+			return err
+		}
+		updated, err = repo.FindExistingByIDs(ctx, contactIDs(changed))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(changed) == 0 {
+		return sortBulkResult(result), nil
+	}
+	log.Printf("Contacts %v set to status %s", contactIDs(changed), status)
+
+	previous := make(map[uint]*models.Contact, len(changed))
+	for i := range changed {
+		previous[changed[i].ID] = &changed[i]
+	}
+	entries := make([]models.AuditLog, 0, len(updated))
+	for _, contact := range updated {
+		entries = append(entries, newAuditLog(actor, models.AuditStatusChanged, previous[contact.ID], &contact))
+		s.publish(models.EventContactUpdated, contact)
+		s.webhooks.PublishStatusChange(contact, previous[contact.ID].Status)
+	}
+	s.recordAudit(entries...)
+	result.Succeeded = append(result.Succeeded, contactIDs(changed)...)
+	return sortBulkResult(result), nil
+}
+
+// findBulkTargets looks up the contacts of a bulk operation in repo, ignoring duplicate
+// IDs, and returns a result reporting the missing ones.
+func findBulkTargets(ctx context.Context, repo repositories.ContactRepository, ids []uint) ([]models.Contact, *BulkResult, error) {
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	contacts, err := repo.FindExistingByIDs(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &BulkResult{}
+	found := contactIDs(contacts)
+	for _, id := range ids {
+		if !slices.Contains(found, id) {
+			result.Failed = append(result.Failed, BulkFailure{ID: id, Reason: "contact not found"})
+		}
+	}
+	return contacts, result, nil
+}
+
+// contactIDs returns the IDs of contacts.
+func contactIDs(contacts []models.Contact) []uint {
+	ids := make([]uint, 0, len(contacts))
+	for _, contact := range contacts {
+		ids = append(ids, contact.ID)
+	}
+	return ids
+}
+
+// sortBulkResult orders the succeeded and failed contacts of result by ID.
+func sortBulkResult(result *BulkResult) *BulkResult {
+	slices.Sort(result.Succeeded)
+	slices.SortFunc(result.Failed, func(a, b BulkFailure) int { return cmp.Compare(a.ID, b.ID) })
+	return result
+}
+
+// importFailure describes the rejection of the contact at index by err.
+func importFailure(index int, err error) ImportFailure {
+	failure := ImportFailure{Index: index, Reason: err.Error()}
+
+	var validationErr *ValidationError
+	var ruleErr *rules.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		failure.Fields = validationErr.Fields
+	case errors.As(err, &ruleErr):
+		for _, violation := range ruleErr.Violations {
+			failure.Fields = append(failure.Fields, FieldError{Field: violation.Field, Message: violation.Message})
+		}
+	}
+	return failure
+}
@@ -0,0 +1,57 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file implements the email issues of the ContactService: when the email provider
+// reports that emails to an address bounced or were reported as spam, the contacts of the
+// address are marked as undeliverable, so that no further email is sent to it and admins
+// see why in the contact details.
+package services
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"context"
+	"time"
+)
+
+// EmailEventsActor is the actor recorded for the email issues reported by the email provider.
+const EmailEventsActor = "system:email-events"
+
+// ReportEmailIssue marks the non-deleted contacts of an email address, case-insensitively,
+// as undeliverable because of issue, and returns their IDs. Contacts already marked with
+// the same issue are left untouched, so that providers may deliver an event twice.
+func (s *contactService) ReportEmailIssue(ctx context.Context, actor string, email string, issue models.EmailIssue) ([]uint, error) {
+	contacts, err := s.repository.FindAll(ctx, repositories.ContactFilter{Email: email})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the contacts that are not marked yet
+	var ids []uint
+	var before []models.Contact
+	for _, contact := range contacts {
+		if contact.EmailIssue != issue {
+			ids = append(ids, contact.ID)
+			before = append(before, contact)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	if err := s.repository.SetEmailIssue(ctx, ids, issue, now); err != nil {
+		return nil, err
+	}
+
+	// Record the changes and publish the updated contacts
+	entries := make([]models.AuditLog, 0, len(before))
+	for i := range before {
+		after := before[i]
+		after.EmailIssue = issue
+		after.EmailIssueAt = &now
+		entries = append(entries, newAuditLog(actor, models.AuditEmailIssue, &before[i], &after))
+		s.publish(models.EventContactUpdated, after)
+	}
+	s.recordAudit(entries...)
+	return ids, nil
+}
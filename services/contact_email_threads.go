@@ -0,0 +1,130 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file implements the email conversations of the ContactService: the replies of
+// submitters to the emails of a contact, such as its auto-reply, are matched to the contact
+// by the Message-IDs of their In-Reply-To and References headers and added to its
+// conversation, rather than creating a new contact for every email of the conversation.
+package services
+
+import (
+	"api-contact-form/helpers"
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/requests"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailThreadsActor is the actor recorded for the changes made by the replies of submitters.
+const EmailThreadsActor = "system:email-threads"
+
+// ErrNoEmailThread is returned for inbound emails that do not reply to the conversation of
+// a live contact, which start a new contact instead.
+var ErrNoEmailThread = errors.New("email does not reply to the conversation of a contact")
+
+// AddEmailReply adds an inbound email to the conversation of the contact it replies to,
+// found by the Message-IDs of its In-Reply-To and References headers. It returns
+// ErrNoEmailThread when the email replies to no known conversation, and
+// ErrDuplicateMessage when an email with the same Message-ID was already added.
+//
+// The contact is brought back to the attention of the team: it becomes active again in
+// the inbox, and a contact that was read is new again. The attached files allowed by the
+// attachment policy are added to the attachments of the contact.
+// Returns the updated Contact and any error encountered.
+func (s *contactService) AddEmailReply(ctx context.Context, req *requests.InboundEmailRequest) (*models.Contact, error) {
+	references := req.References
+	if req.InReplyTo != "" {
+		references = append([]string{req.InReplyTo}, references...)
+	}
+	if s.emailThreads == nil || len(references) == 0 {
+		return nil, ErrNoEmailThread
+	}
+
+	// Validate input
+	if err := s.validateStruct(req); err != nil {
+		return nil, err
+	}
+
+	// Find the contact of the conversation
+	id, err := s.emailThreads.FindThreadContact(ctx, references)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoEmailThread
+	}
+	if err != nil {
+		return nil, err
+	}
+	contact, err := s.repository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the email in the conversation, once
+	message := &models.EmailMessage{
+		ContactID: contact.ID,
+		Direction: models.EmailInbound,
+		InReplyTo: req.InReplyTo,
+		FromEmail: req.FromEmail,
+		Subject:   req.Subject,
+		Body:      req.Body,
+	}
+	if messageID := helpers.NormalizeMessageID(req.MessageID); messageID != "" {
+		message.MessageID = &messageID
+	}
+	if err := s.emailThreads.Create(ctx, message); err != nil {
+		if errors.Is(err, repositories.ErrUniqueViolation) {
+			return nil, ErrDuplicateMessage
+		}
+		return nil, err
+	}
+
+	// Mark the contact as active again, and unread when it was read
+	before := *contact
+	if contact.Status == models.StatusRead {
+		now := time.Now()
+		contact.Status = models.StatusNew
+		contact.StatusChangedAt = &now
+	}
+	if err := s.repository.Update(ctx, contact); err != nil {
+		return nil, err
+	}
+	if contact.Status != before.Status {
+		s.recordAudit(newAuditLog(EmailThreadsActor, models.AuditStatusChanged, &before, contact))
+	}
+
+	// Store the attached files allowed by the attachment policy with the contact. The email
+	// is kept when they cannot be stored, as the provider's retry would be a duplicate.
+	if files := s.emailAttachments(req.Attachments); len(files) > 0 {
+		if _, err := s.attachments.Attach(ctx, contact.ID, files); err != nil {
+			log.Printf("Failed to store the attachments of the email reply to contact %d: %v", contact.ID, err)
+		}
+	}
+
+	log.Printf("Email reply from %s added to contact %d", req.FromEmail, contact.ID)
+	s.publish(models.EventContactUpdated, *contact)
+	return contact, nil
+}
+
+// GetEmailThread retrieves the emails of the conversation of a contact identified by its
+// ID, oldest first. It returns gorm.ErrRecordNotFound when the contact does not exist.
+func (s *contactService) GetEmailThread(ctx context.Context, id uint) ([]models.EmailMessage, error) {
+	if _, err := s.repository.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	if s.emailThreads == nil {
+		return []models.EmailMessage{}, nil
+	}
+	return s.emailThreads.FindByContact(ctx, id)
+}
+
+// GetEmailThreads retrieves the emails of the conversations of the contacts, deleted or
+// not, oldest first, such as for a data subject access request.
+func (s *contactService) GetEmailThreads(ctx context.Context, contactIDs []uint) ([]models.EmailMessage, error) {
+	if s.emailThreads == nil {
+		return []models.EmailMessage{}, nil
+	}
+	return s.emailThreads.FindByContacts(ctx, contactIDs)
+}
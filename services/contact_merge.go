@@ -0,0 +1,146 @@
+// Package services provides business logic implementations for contact-related operations
+// in the API Contact Form application.
+//
+// This file implements the conflict resolution of merges: the fields on which the merged
+// contacts disagree, such as two different phone numbers, are reported until the merge
+// says which contact's value wins, and the losing values are kept in the audit trail.
+package services
+
+import (
+	"api-contact-form/models"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidResolution is returned when a merge resolves a field that cannot be merged, or
+// picks the value of a contact that is not part of the merge.
+var ErrInvalidResolution = errors.New("invalid merge resolution")
+
+// mergedFields are the JSON names of the fields compared and resolved by merges.
+var mergedFields = []string{"name", "email", "phone"}
+
+// MergeValue is the value of a field of one of the merged contacts.
+type MergeValue struct {
+	// ContactID is the ID of the contact.
+	ContactID uint `json:"contact_id"`
+	// Value is the value of the field.
+	Value string `json:"value"`
+}
+
+// MergeConflict is a field with different values among the merged contacts.
+type MergeConflict struct {
+	// Field is the JSON name of the field.
+	Field string
+	// Values lists the distinct values, with the first contact having each, kept contact first.
+	Values []MergeValue
+}
+
+// MergeConflictError is returned when merged contacts have conflicting values that the
+// merge does not resolve.
+type MergeConflictError struct {
+	Conflicts []MergeConflict
+}
+
+// Error lists the unresolved fields.
+func (e *MergeConflictError) Error() string {
+	fields := make([]string, len(e.Conflicts))
+	for i, conflict := range e.Conflicts {
+		fields[i] = conflict.Field
+	}
+	return "the merged contacts have conflicting values for " + strings.Join(fields, ", ")
+}
+
+// checkResolutions checks that resolutions, mapping fields to the ID of the contact whose
+// value wins, only name merged fields and contacts of the merge.
+func checkResolutions(resolutions map[string]uint, keepID uint, ids []uint) error {
+	for field, winner := range resolutions {
+		if !slices.Contains(mergedFields, field) {
+			return fmt.Errorf("%w: %s cannot be merged; the fields are %s", ErrInvalidResolution, field, strings.Join(mergedFields, ", "))
+		}
+		if winner != keepID && !slices.Contains(ids, winner) {
+			return fmt.Errorf("%w: contact %d chosen for %s is not merged", ErrInvalidResolution, winner, field)
+		}
+	}
+	return nil
+}
+
+// mergeConflicts returns the merged fields whose non-empty values differ between kept and
+// the duplicates.
+func mergeConflicts(kept *models.Contact, duplicates []models.Contact) []MergeConflict {
+	var conflicts []MergeConflict
+	for _, field := range mergedFields {
+		var values []MergeValue
+		for _, contact := range append([]models.Contact{*kept}, duplicates...) {
+			value := *mergedField(&contact, field)
+			if value == "" || slices.ContainsFunc(values, func(v MergeValue) bool { return v.Value == value }) {
+				continue
+			}
+			values = append(values, MergeValue{ContactID: contact.ID, Value: value})
+		}
+		if len(values) > 1 {
+			conflicts = append(conflicts, MergeConflict{Field: field, Values: values})
+		}
+	}
+	return conflicts
+}
+
+// resolveMerge sets the resolved fields of kept to the values of the winning contacts, and
+// returns the values that lost, keyed by field. It returns a *MergeConflictError listing
+// the conflicts that resolutions leaves open.
+func resolveMerge(kept *models.Contact, duplicates []models.Contact, resolutions map[string]uint) (map[string][]MergeValue, error) {
+	conflicts := mergeConflicts(kept, duplicates)
+	var open []MergeConflict
+	for _, conflict := range conflicts {
+		if _, ok := resolutions[conflict.Field]; !ok {
+			open = append(open, conflict)
+		}
+	}
+	if len(open) > 0 {
+		return nil, &MergeConflictError{Conflicts: open}
+	}
+
+	contacts := append([]models.Contact{*kept}, duplicates...)
+	discarded := make(map[string][]MergeValue)
+	for _, conflict := range conflicts {
+		winner := resolutions[conflict.Field]
+		i := slices.IndexFunc(contacts, func(c models.Contact) bool { return c.ID == winner })
+		value := *mergedField(&contacts[i], conflict.Field)
+		if value == "" {
+			return nil, fmt.Errorf("%w: contact %d has no %s", ErrInvalidResolution, winner, conflict.Field)
+		}
+		*mergedField(kept, conflict.Field) = value
+		for _, candidate := range conflict.Values {
+			if candidate.Value != value {
+				discarded[conflict.Field] = append(discarded[conflict.Field], candidate)
+			}
+		}
+	}
+	return discarded, nil
+}
+
+// mergedField returns the field of contact named by its JSON name, one of mergedFields.
+func mergedField(contact *models.Contact, field string) *string {
+	switch field {
+	case "name":
+		return &contact.FullName
+	case "email":
+		return &contact.Email
+	default:
+		return &contact.Phone
+	}
+}
+
+// mergeAuditLog describes the resolution of the conflicts of a merge into kept, from
+// before, with the values that lost.
+func mergeAuditLog(actor string, before, kept *models.Contact, discarded map[string][]MergeValue) models.AuditLog {
+	entry := newAuditLog(actor, models.AuditMerged, before, kept)
+	old, updated := auditedFields(before), auditedFields(kept)
+	changes := make(map[string]auditChange, len(discarded))
+	for field, values := range discarded {
+		changes[field] = auditChange{Before: old[field], After: updated[field], Discarded: values}
+	}
+	entry.Changes = encodeAuditChanges(entry.ContactID, changes)
+	return entry
+}
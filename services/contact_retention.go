@@ -0,0 +1,194 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file implements the data retention of the ContactService: contacts deleted long
+// enough ago are purged, the personal data of contacts resolved long enough ago is
+// anonymized, and attachments uploaded long enough ago are removed while their contacts
+// are kept, so that nothing is kept longer than the retention periods allow. Contacts
+// under legal hold are never touched.
+package services
+
+import (
+	"api-contact-form/models"
+	"context"
+	"log"
+	"time"
+)
+
+// RetentionActor is the actor recorded for the changes made by the retention job.
+const RetentionActor = "system:retention"
+
+// resolvedStatuses are the statuses of contacts whose handling is over.
+var resolvedStatuses = []models.Status{models.StatusReplied, models.StatusArchived, models.StatusSpam}
+
+// RetentionPolicy configures how long contacts are kept.
+type RetentionPolicy struct {
+	// DeletedFor is the time soft-deleted contacts are kept before being purged. Zero
+	// disables purging.
+	DeletedFor time.Duration
+	// ResolvedFor is the time resolved contacts keep their personal data before it is
+	// anonymized, counted from their last status change. Zero disables anonymization.
+	ResolvedFor time.Duration
+	// AttachmentsFor is the time attachments are kept after their upload before being
+	// removed, usually shorter than the contacts they belong to. Zero disables it.
+	AttachmentsFor time.Duration
+	// BatchSize is the largest number of contacts purged and anonymized, and of attachments
+	// removed, by a single run.
+	BatchSize int
+}
+
+// RetentionReport lists the contacts affected by a run of the retention policy.
+type RetentionReport struct {
+	// DryRun reports whether the contacts were only listed, without being changed.
+	DryRun bool
+	// Purged lists the IDs of the deleted contacts that were permanently removed.
+	Purged []uint
+	// Anonymized lists the IDs of the resolved contacts whose personal data was anonymized.
+	Anonymized []uint
+	// AttachmentsPurged lists the IDs of the contacts whose expired attachments were removed.
+	AttachmentsPurged []uint
+}
+
+// WithRetentionPolicy applies policy when ApplyRetention runs.
+func WithRetentionPolicy(policy RetentionPolicy) ContactServiceOption {
+	return func(s *contactService) {
+		s.retention = policy
+	}
+}
+
+// ApplyRetention purges the expired deleted contacts, anonymizes the expired resolved
+// ones and removes the expired attachments, on behalf of actor. With dryRun, the contacts
+// the run would change, at most BatchSize of each kind, are only reported.
+// Returns the affected contacts and any error encountered.
+func (s *contactService) ApplyRetention(ctx context.Context, actor string, dryRun bool) (*RetentionReport, error) {
+	report := &RetentionReport{DryRun: dryRun}
+	now := time.Now()
+
+	// Purge the contacts deleted before the retention period, one at a time so that
+	// their attachments are removed as well
+	if s.retention.DeletedFor > 0 {
+		expired, err := s.repository.FindExpiredDeleted(ctx, now.Add(-s.retention.DeletedFor), s.retention.BatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, contact := range expired {
+			if !dryRun {
+				if err := s.PurgeContact(ctx, actor, contact.ID, false); err != nil {
+					log.Printf("Retention failed to purge contact %d: %v", contact.ID, err)
+					continue
+				}
+			}
+			report.Purged = append(report.Purged, contact.ID)
+		}
+	}
+
+	// Anonymize the contacts resolved before the retention period
+	if s.retention.ResolvedFor > 0 {
+		expired, err := s.repository.FindAnonymizable(ctx, now.Add(-s.retention.ResolvedFor), resolvedStatuses, s.retention.BatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, contact := range expired {
+			report.Anonymized = append(report.Anonymized, contact.ID)
+		}
+		if !dryRun && len(expired) > 0 {
+			if err := s.anonymize(ctx, actor, expired, now); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Remove the attachments uploaded before their retention period, keeping their contacts
+	if s.retention.AttachmentsFor > 0 && s.attachments != nil {
+		expired, err := s.attachments.Expired(now.Add(-s.retention.AttachmentsFor), s.retention.BatchSize)
+		if err != nil {
+			return nil, err
+		}
+		contactIDs, byContact := groupAttachments(expired)
+		for _, id := range contactIDs {
+			if !dryRun && !s.purgeAttachments(ctx, actor, id, byContact[id], now) {
+				continue
+			}
+			report.AttachmentsPurged = append(report.AttachmentsPurged, id)
+		}
+	}
+
+	log.Printf("Retention %s: %d contacts purged, %d anonymized, %d with attachments purged", retentionMode(dryRun),
+		len(report.Purged), len(report.Anonymized), len(report.AttachmentsPurged))
+	return report, nil
+}
+
+// anonymize anonymizes the personal data of the contacts and records the changes. The
+// repository redacts the personal data of their audit trail in the same transaction, and
+// the new entries only record the time of the anonymization, so that the trail does not
+// keep the data that was removed.
+func (s *contactService) anonymize(ctx context.Context, actor string, contacts []models.Contact, at time.Time) error {
+	for i := range contacts {
+		contacts[i].Anonymize(at)
+	}
+	if err := s.repository.Anonymize(ctx, contacts); err != nil {
+		return err
+	}
+
+	entries := make([]models.AuditLog, 0, len(contacts))
+	for i := range contacts {
+		before := contacts[i]
+		before.AnonymizedAt = nil
+		entries = append(entries, newAuditLog(actor, models.AuditAnonymized, &before, &contacts[i]))
+		s.publish(models.EventContactUpdated, contacts[i])
+	}
+	s.recordAudit(entries...)
+	return nil
+}
+
+// purgeAttachments removes the expired attachments of a contact and records it, and
+// reports whether any was removed. Failures are logged, so that they do not stop the run.
+func (s *contactService) purgeAttachments(ctx context.Context, actor string, contactID uint, attachments []models.Attachment, at time.Time) bool {
+	purged, err := s.attachments.Purge(ctx, contactID, attachments, at)
+	if err != nil {
+		log.Printf("Retention failed to purge the attachments of contact %d: %v", contactID, err)
+		return false
+	}
+	if len(purged) == 0 {
+		return false
+	}
+
+	// The trail only records the IDs of the attachments, as their names may hold personal data
+	removed := make([]uint, len(purged))
+	for i, attachment := range purged {
+		removed[i] = attachment.ID
+	}
+	entry := newAuditLog(actor, models.AuditAttachmentsPurged, nil, nil)
+	entry.ContactID = contactID
+	entry.Changes = encodeAuditChanges(contactID, map[string]auditChange{
+		"attachments": {Before: removed, After: nil},
+	})
+	s.recordAudit(entry)
+
+	// Deleted contacts are not published
+	if contact, err := s.repository.FindByID(ctx, contactID); err == nil {
+		s.publish(models.EventContactUpdated, *contact)
+	}
+	return true
+}
+
+// groupAttachments groups attachments by contact, and returns the IDs of the contacts in
+// the order of their first attachment.
+func groupAttachments(attachments []models.Attachment) ([]uint, map[uint][]models.Attachment) {
+	var contactIDs []uint
+	byContact := make(map[uint][]models.Attachment)
+	for _, attachment := range attachments {
+		if _, ok := byContact[attachment.ContactID]; !ok {
+			contactIDs = append(contactIDs, attachment.ContactID)
+		}
+		byContact[attachment.ContactID] = append(byContact[attachment.ContactID], attachment)
+	}
+	return contactIDs, byContact
+}
+
+// retentionMode describes a run of the retention policy in the logs.
+func retentionMode(dryRun bool) string {
+	if dryRun {
+		return "dry run"
+	}
+	return "run"
+}
@@ -0,0 +1,165 @@
+package services_test
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/services"
+	"api-contact-form/storage"
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// retentionFixture holds a ContactService removing attachments after 30 days, with its
+// database and attachment storage.
+type retentionFixture struct {
+	db       *gorm.DB
+	store    *storage.LocalStorage
+	contacts services.ContactService
+}
+
+func newRetentionFixture(t *testing.T) retentionFixture {
+	db := openDB(t, &models.Contact{}, &models.Attachment{}, &models.AuditLog{})
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	attachments := services.NewAttachmentService(repositories.NewAttachmentRepository(db), store, services.AttachmentPolicy{}, nil)
+	contacts := services.NewContactService(repositories.NewContactRepository(db),
+		services.WithAttachments(attachments),
+		services.WithAuditLog(repositories.NewAuditLogRepository(db)),
+		services.WithRetentionPolicy(services.RetentionPolicy{AttachmentsFor: 30 * 24 * time.Hour, BatchSize: 10}),
+	)
+	return retentionFixture{db: db, store: store, contacts: contacts}
+}
+
+// contact records a contact with a stored attachment for each of the upload times.
+func (f retentionFixture) contact(t *testing.T, email string, uploadedAt ...time.Time) *models.Contact {
+	t.Helper()
+	contact := &models.Contact{FullName: "Jane", Email: email, Phone: "+15551234567", Message: "Hello"}
+	for i, at := range uploadedAt {
+		key := strings.NewReplacer("@", "-", ".", "-").Replace(email) + "-" + string(rune('a'+i))
+		if err := f.store.Put(context.Background(), key, strings.NewReader("file"), 4, "text/plain"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		contact.Attachments = append(contact.Attachments, models.Attachment{
+			Filename: "file.txt", ContentType: "text/plain", Size: 4, StorageKey: key, CreatedAt: at,
+		})
+	}
+	if err := f.db.Create(contact).Error; err != nil {
+		t.Fatalf("create contact: %v", err)
+	}
+	return contact
+}
+
+// stored reports whether the file of attachment is still stored.
+func (f retentionFixture) stored(t *testing.T, attachment models.Attachment) bool {
+	t.Helper()
+	reader, err := f.store.Open(context.Background(), attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	reader.Close()
+	return true
+}
+
+func TestApplyRetentionPurgesExpiredAttachments(t *testing.T) {
+	f := newRetentionFixture(t)
+	old, recent := time.Now().AddDate(0, 0, -31), time.Now().AddDate(0, 0, -1)
+	expired := f.contact(t, "expired@example.com", old, recent)
+	fresh := f.contact(t, "fresh@example.com", recent)
+	held := f.contact(t, "held@example.com", old)
+	if err := f.db.Model(held).Update("legal_hold", true).Error; err != nil {
+		t.Fatalf("hold: %v", err)
+	}
+	deleted := f.contact(t, "deleted@example.com", old)
+	if err := f.db.Delete(deleted).Error; err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	// A dry run only reports the contacts
+	report, err := f.contacts.ApplyRetention(context.Background(), services.RetentionActor, true)
+	if err != nil {
+		t.Fatalf("ApplyRetention(dry run): %v", err)
+	}
+	want := []uint{expired.ID, deleted.ID}
+	if !slices.Equal(report.AttachmentsPurged, want) {
+		t.Errorf("dry run AttachmentsPurged = %v, want %v", report.AttachmentsPurged, want)
+	}
+	if !f.stored(t, expired.Attachments[0]) {
+		t.Fatal("dry run removed an attachment")
+	}
+
+	report, err = f.contacts.ApplyRetention(context.Background(), services.RetentionActor, false)
+	if err != nil {
+		t.Fatalf("ApplyRetention: %v", err)
+	}
+	if !slices.Equal(report.AttachmentsPurged, want) {
+		t.Errorf("AttachmentsPurged = %v, want %v", report.AttachmentsPurged, want)
+	}
+
+	// The expired attachments are gone with their files, the others are kept
+	for _, check := range []struct {
+		attachment models.Attachment
+		kept       bool
+	}{
+		{expired.Attachments[0], false},
+		{expired.Attachments[1], true},
+		{fresh.Attachments[0], true},
+		{held.Attachments[0], true},
+		{deleted.Attachments[0], false},
+	} {
+		var count int64
+		f.db.Model(&models.Attachment{}).Where("id = ?", check.attachment.ID).Count(&count)
+		if (count == 1) != check.kept || f.stored(t, check.attachment) != check.kept {
+			t.Errorf("attachment %d: recorded %v, stored %v, want kept %v",
+				check.attachment.ID, count == 1, f.stored(t, check.attachment), check.kept)
+		}
+	}
+
+	// The contacts are kept, and record the purge
+	for _, check := range []struct {
+		contact *models.Contact
+		purged  bool
+	}{
+		{expired, true}, {fresh, false}, {held, false}, {deleted, true},
+	} {
+		var contact models.Contact
+		if err := f.db.Unscoped().First(&contact, check.contact.ID).Error; err != nil {
+			t.Fatalf("contact %d: %v", check.contact.ID, err)
+		}
+		if (contact.AttachmentsPurgedAt != nil) != check.purged {
+			t.Errorf("contact %d AttachmentsPurgedAt = %v, want set %v", contact.ID, contact.AttachmentsPurgedAt, check.purged)
+		}
+	}
+
+	// The trail records the IDs of the removed attachments
+	var entry models.AuditLog
+	if err := f.db.Where("contact_id = ? AND action = ?", expired.ID, models.AuditAttachmentsPurged).First(&entry).Error; err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	if wantChanges := `{"attachments":{"before":[` + strconv.FormatUint(uint64(expired.Attachments[0].ID), 10) + `],"after":null}}`; entry.Changes != wantChanges {
+		t.Errorf("Changes = %s, want %s", entry.Changes, wantChanges)
+	}
+	if entry.Actor != services.RetentionActor {
+		t.Errorf("Actor = %q, want %q", entry.Actor, services.RetentionActor)
+	}
+
+	// Nothing is left for the next run
+	report, err = f.contacts.ApplyRetention(context.Background(), services.RetentionActor, false)
+	if err != nil {
+		t.Fatalf("ApplyRetention(again): %v", err)
+	}
+	if len(report.AttachmentsPurged) != 0 {
+		t.Errorf("second run AttachmentsPurged = %v, want none", report.AttachmentsPurged)
+	}
+}
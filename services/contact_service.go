@@ -7,114 +7,1149 @@
 package services
 
 import (
+	"api-contact-form/enrichment"
+	"api-contact-form/helpers"
+	"api-contact-form/hooks"
 	"api-contact-form/models"
+	"api-contact-form/notifications"
+	"api-contact-form/observability"
 	"api-contact-form/repositories"
 	"api-contact-form/requests"
+	"api-contact-form/rules"
+	"api-contact-form/webhooks"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
 )
 
+// exportBatchSize is the number of contacts ExportContacts reads per query.
+const exportBatchSize = 500
+
+// ErrDuplicateMessage is returned when an inbound email with the same Message-ID was already ingested.
+var ErrDuplicateMessage = errors.New("email message already received")
+
+// ErrDuplicateSubmission is returned when a submission repeats a recent one of the same
+// email address and the duplicate policy rejects such submissions.
+var ErrDuplicateSubmission = errors.New("the same message was already submitted recently")
+
+// ErrLegalHold is returned when an operation would delete or anonymize a contact under legal hold.
+var ErrLegalHold = errors.New("contact is under legal hold")
+
+// ErrInvalidStatusTransition is returned when a contact may not change from its current status to the requested one,
+// or only once it was reviewed.
+var ErrInvalidStatusTransition = errors.New("status transition not allowed")
+
+// ErrMergeIntoItself is returned when a contact is to be merged into itself.
+var ErrMergeIntoItself = errors.New("a contact cannot be merged into itself")
+
+// ErrConsentRequired is returned when a submission lacks the consent that the instance makes mandatory.
+var ErrConsentRequired = errors.New("consent and consent_version are required")
+
 // ContactService defines the business logic interface for contact operations.
+//
+// Every method takes the context of the caller, which bounds and cancels its database
+// work. The methods changing existing contacts take the actor making the change, as
+// recorded in the audit trail.
 type ContactService interface {
-	// CreateContact creates a new contact based on the provided request.
-	CreateContact(req *requests.ContactRequest) (*models.Contact, error)
-	// GetAllContacts retrieves all non-deleted contacts.
-	GetAllContacts() ([]models.Contact, error)
+	// CreateContact creates a new contact based on the provided request and submission metadata.
+	CreateContact(ctx context.Context, req *requests.ContactRequest, meta requests.SubmissionMeta) (*models.Contact, error)
+	// CreateContactFromEmail creates a new contact from an inbound email.
+	CreateContactFromEmail(ctx context.Context, req *requests.InboundEmailRequest) (*models.Contact, error)
+	// AddEmailReply adds an inbound email to the conversation of the contact it replies to.
+	AddEmailReply(ctx context.Context, req *requests.InboundEmailRequest) (*models.Contact, error)
+	// GetEmailThread retrieves the emails of the conversation of a contact identified by its ID.
+	GetEmailThread(ctx context.Context, id uint) ([]models.EmailMessage, error)
+	// GetEmailThreads retrieves the emails of the conversations of several contacts,
+	// deleted or not.
+	GetEmailThreads(ctx context.Context, contactIDs []uint) ([]models.EmailMessage, error)
+	// GetAttachments retrieves the attachments of several contacts, deleted or not.
+	GetAttachments(ctx context.Context, contactIDs []uint) ([]models.Attachment, error)
+	// GetAuditTrails retrieves the audit trail of several contacts, deleted or not.
+	GetAuditTrails(ctx context.Context, contactIDs []uint) ([]models.AuditLog, error)
+	// ListContacts retrieves a sorted page of non-deleted contacts.
+	ListContacts(ctx context.Context, params repositories.ListParams) (*repositories.ContactPage, error)
+	// SearchContacts retrieves a page of the non-deleted contacts whose message matches a
+	// full-text query, most relevant first.
+	SearchContacts(ctx context.Context, params repositories.SearchParams) (*repositories.SearchPage, error)
+	// ExportContacts calls fn for every non-deleted contact matching the filter, in ID order.
+	ExportContacts(ctx context.Context, filter repositories.ContactFilter, fn func(contact *models.Contact) error) error
 	// GetContactByID retrieves a single contact by its ID.
-	GetContactByID(id uint) (*models.Contact, error)
+	GetContactByID(ctx context.Context, id uint) (*models.Contact, error)
+	// GetContactByPublicID retrieves a single contact by its generated public ID.
+	GetContactByPublicID(ctx context.Context, publicID string) (*models.Contact, error)
+	// GetContactsByEmail retrieves every stored contact of an email address, including deleted ones.
+	GetContactsByEmail(ctx context.Context, email string) ([]models.Contact, error)
 	// UpdateContact updates an existing contact identified by its ID.
-	UpdateContact(id uint, req *requests.ContactRequest) (*models.Contact, error)
+	UpdateContact(ctx context.Context, actor string, id uint, req *requests.ContactRequest) (*models.Contact, error)
 	// DeleteContact marks a contact as deleted based on its ID.
-	DeleteContact(id uint) error
+	DeleteContact(ctx context.Context, actor string, id uint) error
+	// DeleteContacts marks several contacts as deleted based on their IDs, skipping and
+	// reporting those that cannot be deleted.
+	DeleteContacts(ctx context.Context, actor string, ids []uint, dryRun bool) (*BulkResult, error)
+	// UpdateStatuses changes the status of several contacts based on their IDs, skipping
+	// and reporting those that cannot be changed.
+	UpdateStatuses(ctx context.Context, actor string, ids []uint, status models.Status, reviewed bool) (*BulkResult, error)
+	// ImportContacts creates contacts from legacy data in batches, skipping and reporting
+	// the invalid ones.
+	ImportContacts(ctx context.Context, reqs []requests.ImportContactRequest) (*ImportResult, error)
+	// FindDuplicates groups the contacts that are likely duplicates of each other.
+	FindDuplicates(ctx context.Context, criteria repositories.DuplicateCriteria) ([]repositories.DuplicateGroup, error)
+	// MergeContacts merges duplicate contacts into the contact identified by keepID, taking
+	// the values of the conflicting fields from the contacts chosen by resolutions.
+	MergeContacts(ctx context.Context, actor string, keepID uint, ids []uint, resolutions map[string]uint) (*models.Contact, error)
+	// SetLegalHold places or lifts the legal hold of a contact identified by its ID.
+	SetLegalHold(ctx context.Context, actor string, id uint, hold bool) (*models.Contact, error)
+	// SetPinned pins a contact identified by its ID to the top of the inbox of the whole
+	// team, or unpins it.
+	SetPinned(ctx context.Context, actor string, id uint, pinned bool) (*models.Contact, error)
+	// Snooze hides a contact identified by its ID from the default inbox until the given
+	// time, when it returns to the new status.
+	Snooze(ctx context.Context, actor string, id uint, until time.Time) (*models.Contact, error)
+	// EndSnoozes returns the contacts whose snooze ended to the new status and notifies
+	// them again.
+	EndSnoozes(ctx context.Context, actor string) ([]uint, error)
+	// SetStarred stars a contact identified by its ID for owner alone, or removes the star.
+	SetStarred(ctx context.Context, owner string, id uint, starred bool) error
+	// UpdateStatus changes the status of a contact identified by its ID. Transitions that
+	// require a review are only made when reviewed is true.
+	UpdateStatus(ctx context.Context, actor string, id uint, status models.Status, reviewed bool) (*models.Contact, error)
+	// GetDeletedContacts retrieves a page of soft-deleted contacts.
+	GetDeletedContacts(ctx context.Context, params repositories.ListParams) (*repositories.ContactPage, error)
+	// RestoreContact undoes the deletion of a contact identified by its ID.
+	RestoreContact(ctx context.Context, actor string, id uint) (*models.Contact, error)
+	// PurgeContact permanently removes a deleted contact identified by its ID.
+	PurgeContact(ctx context.Context, actor string, id uint, dryRun bool) error
+	// ApplyRetention purges and anonymizes the contacts, and removes the attachments, kept
+	// longer than the retention policy allows.
+	ApplyRetention(ctx context.Context, actor string, dryRun bool) (*RetentionReport, error)
+	// ReportEmailIssue marks the non-deleted contacts of an email address as undeliverable.
+	ReportEmailIssue(ctx context.Context, actor string, email string, issue models.EmailIssue) ([]uint, error)
 }
 
 // contactService is the concrete implementation of ContactService.
 // It interacts with the ContactRepository to perform data operations and uses
 // a validator to ensure request data integrity.
 type contactService struct {
-	repository repositories.ContactRepository
-	validate   *validator.Validate
+	repository      repositories.ContactRepository
+	validate        *validator.Validate
+	consentRequired bool
+	privacyVersion  string
+	termsVersion    string
+	rules           *rules.Store
+	hooks           *hooks.Registry
+	notifier        *notifications.Dispatcher
+	autoReplies     *notifications.Dispatcher
+	webhooks        *webhooks.Dispatcher
+	enricher        *enrichment.Enricher
+	inbox           *InboxProjection
+	emailDailyLimit int
+	replays         repositories.RejectionRepository
+	replayLimit     int
+	duplicates      DuplicatePolicy
+	deleteMode      DeleteMode
+	attachments     AttachmentService
+	audits          repositories.AuditLogRepository
+	retention       RetentionPolicy
+	emailThreads    repositories.EmailMessageRepository
+}
+
+// DuplicatePolicy configures the detection of repeated submissions: the same message,
+// ignoring case and whitespace, submitted again by the same email address.
+type DuplicatePolicy struct {
+	// Window is the time during which a repeated message is a duplicate. Zero disables
+	// the detection.
+	Window time.Duration
+	// Reject rejects duplicates with ErrDuplicateSubmission. Otherwise they are stored,
+	// flagged with the ID of the earlier contact, and not notified.
+	Reject bool
+}
+
+// DeleteMode tells how deleted contacts are removed.
+type DeleteMode string
+
+const (
+	// DeleteSoft keeps deleted contacts as tombstones, hidden from the listings, until they
+	// are restored or purged. It is the default.
+	DeleteSoft DeleteMode = "soft"
+	// DeleteHard purges deleted contacts right away, together with their attachments, for
+	// installations that must not retain deleted personal data even as tombstones.
+	DeleteHard DeleteMode = "hard"
+)
+
+// Valid reports whether m is one of the known delete modes.
+func (m DeleteMode) Valid() bool {
+	return m == DeleteSoft || m == DeleteHard
+}
+
+// ContactServiceOption configures optional behavior of the ContactService.
+type ContactServiceOption func(*contactService)
+
+// WithConsentRequired makes consent mandatory for contacts created through CreateContact.
+func WithConsentRequired(required bool) ContactServiceOption {
+	return func(s *contactService) {
+		s.consentRequired = required
+	}
+}
+
+// WithPolicyVersions sets the privacy policy and terms versions currently in force,
+// which are recorded on every contact created by the service.
+func WithPolicyVersions(privacyVersion, termsVersion string) ContactServiceOption {
+	return func(s *contactService) {
+		s.privacyVersion = privacyVersion
+		s.termsVersion = termsVersion
+	}
+}
+
+// WithRules applies the custom validation rules of the given store to every submission.
+func WithRules(store *rules.Store) ContactServiceOption {
+	return func(s *contactService) {
+		s.rules = store
+	}
+}
+
+// WithHooks runs the hooks of the given registry in the submission pipeline.
+func WithHooks(registry *hooks.Registry) ContactServiceOption {
+	return func(s *contactService) {
+		s.hooks = registry
+	}
+}
+
+// WithNotifier sends notifications through the given dispatcher for every new contact.
+func WithNotifier(notifier *notifications.Dispatcher) ContactServiceOption {
+	return func(s *contactService) {
+		s.notifier = notifier
+	}
+}
+
+// WithAutoReplies sends the auto-reply of every new contact to its submitter through the
+// given dispatcher, unless the contact was flagged as spam or as a duplicate.
+func WithAutoReplies(dispatcher *notifications.Dispatcher) ContactServiceOption {
+	return func(s *contactService) {
+		s.autoReplies = dispatcher
+	}
+}
+
+// WithEmailDailyLimit flags the submissions of an email address as spam once it made
+// limit submissions in the last 24 hours. Zero disables the limit.
+func WithEmailDailyLimit(limit int) ContactServiceOption {
+	return func(s *contactService) {
+		s.emailDailyLimit = limit
+	}
+}
+
+// WithReplaySignals flags the submissions of a client IP address as spam once limit of its
+// submissions in the last 24 hours were rejected as replays, as recorded in rejections.
+// Zero disables the limit.
+func WithReplaySignals(rejections repositories.RejectionRepository, limit int) ContactServiceOption {
+	return func(s *contactService) {
+		s.replays = rejections
+		s.replayLimit = limit
+	}
+}
+
+// WithDuplicatePolicy detects the submissions repeating a recent one according to policy.
+func WithDuplicatePolicy(policy DuplicatePolicy) ContactServiceOption {
+	return func(s *contactService) {
+		s.duplicates = policy
+	}
+}
+
+// WithDeleteMode removes deleted contacts, including merged duplicates, according to mode.
+func WithDeleteMode(mode DeleteMode) ContactServiceOption {
+	return func(s *contactService) {
+		s.deleteMode = mode
+	}
+}
+
+// WithWebhooks publishes the lifecycle events of contacts through the given dispatcher.
+func WithWebhooks(dispatcher *webhooks.Dispatcher) ContactServiceOption {
+	return func(s *contactService) {
+		s.webhooks = dispatcher
+	}
+}
+
+// WithEnrichment records the company of submitters found by the given enricher on their
+// contacts.
+func WithEnrichment(enricher *enrichment.Enricher) ContactServiceOption {
+	return func(s *contactService) {
+		s.enricher = enricher
+	}
+}
+
+// WithAttachments accepts files uploaded with submissions and keeps them through the given
+// service. Without it, submissions with attachments are rejected.
+func WithAttachments(attachments AttachmentService) ContactServiceOption {
+	return func(s *contactService) {
+		s.attachments = attachments
+	}
+}
+
+// WithEmailThreads adds the inbound emails replying to the conversation of a contact to
+// that contact, as stored in messages.
+func WithEmailThreads(messages repositories.EmailMessageRepository) ContactServiceOption {
+	return func(s *contactService) {
+		s.emailThreads = messages
+	}
 }
 
 // NewContactService creates a new instance of ContactService with the provided ContactRepository.
-// It initializes the validator for request validation.
-func NewContactService(repository repositories.ContactRepository) ContactService {
-	return &contactService{
+// It initializes the validator for request validation and applies the given options.
+func NewContactService(repository repositories.ContactRepository, opts ...ContactServiceOption) ContactService {
+	s := &contactService{
 		repository: repository,
-		validate:   validator.New(),
+		validate:   newValidator(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // CreateContact creates a new contact based on the provided ContactRequest.
-// It validates the request, maps it to the Contact model, and persists it using the repository.
+// It normalizes and validates the request, maps it to the Contact model, and persists it using the repository.
+// Invalid requests are rejected with a *ValidationError listing every invalid field.
+// When consent is given, the consent text version, time and client IP are recorded with the contact.
+// Pre-validate hooks run first and may modify or reject the request; once it is stored,
+// post-create hooks run and the notification is queued.
+// Attached files are stored before the contact and recorded with it; they are removed again
+// when the contact cannot be created. Repeated submissions are flagged or rejected with
+// ErrDuplicateSubmission according to the DuplicatePolicy.
 // Returns the created Contact and any error encountered.
-func (s *contactService) CreateContact(req *requests.ContactRequest) (*models.Contact, error) {
+func (s *contactService) CreateContact(ctx context.Context, req *requests.ContactRequest, meta requests.SubmissionMeta) (*models.Contact, error) {
+	// Normalize the input, then let hooks adjust or reject the submission
+	normalizeContactRequest(req)
+	if err := s.hooks.RunPreValidate(req, meta); err != nil {
+		return nil, err
+	}
+
 	// Validate input
-	if err := s.validate.Struct(req); err != nil {
+	if err := s.validateStruct(req); err != nil {
 		return nil, err
 	}
+	currentRules, fields := s.rules.Current(), contactRequestFields(req)
+	if err := currentRules.Validate(fields); err != nil {
+		return nil, err
+	}
+
+	consent := req.Consent != nil && *req.Consent
+	if s.consentRequired && (!consent || req.ConsentVersion == "") {
+		return nil, ErrConsentRequired
+	}
+	if len(req.Attachments) > 0 && s.attachments == nil {
+		return nil, attachmentError("are not accepted")
+	}
+
+	channel := models.ChannelWeb
+	if meta.Channel != "" {
+		channel = meta.Channel
+	}
 
 	// Map request to Contact model
+	company := s.enricher.Derive(req.Email)
 	contact := models.Contact{
 		FullName: req.Name,
 		Email:    req.Email,
 		Phone:    req.Phone,
 		Message:  req.Message,
+		Channel:  channel,
+		Status:   models.StatusNew,
+
+		CompanyDomain:        company.Domain,
+		CompanyName:          company.Name,
+		FingerprintHash:      hashFingerprint(req.Fingerprint),
+		MessageHash:          hashMessage(req.Message),
+		LeadScore:            currentRules.Score(fields),
+		Language:             contactLanguage(req.Lang, req.Message),
+		PrivacyPolicyVersion: s.privacyVersion,
+		TermsVersion:         s.termsVersion,
+		CreatedAt:            meta.ReceivedAt,
+	}
+
+	if err := s.flagOverEmailLimit(ctx, &contact); err != nil {
+		return nil, err
+	}
+	if err := s.flagReplaySource(ctx, &contact, meta.ClientIP); err != nil {
+		return nil, err
+	}
+	if err := s.checkDuplicate(ctx, &contact); err != nil {
+		return nil, err
+	}
+
+	// Record the consent evidence
+	if consent {
+		now := time.Now()
+		contact.ConsentGiven = true
+		contact.ConsentVersion = req.ConsentVersion
+		contact.ConsentAt = &now
+		contact.ConsentIP = meta.ClientIP
 	}
 
-	// Persist the contact using the repository
-	err := s.repository.Create(&contact)
-	return &contact, err
+	// Store the attached files
+	var attachments []models.Attachment
+	if len(req.Attachments) > 0 {
+		uploaded, err := s.attachments.Upload(ctx, req.Attachments)
+		if err != nil {
+			return nil, err
+		}
+		attachments = uploaded
+	}
+
+	// Persist the contact together with its attachments using the repository
+	contact.Attachments = attachments
+	if err := s.repository.Create(ctx, &contact); err != nil {
+		s.discardAttachments(attachments)
+		return &contact, err
+	}
+
+	s.afterCreate(&contact)
+	return &contact, nil
 }
 
-// GetAllContacts retrieves all non-deleted contacts from the repository.
-// Returns a slice of Contact models and any error encountered.
-func (s *contactService) GetAllContacts() ([]models.Contact, error) {
-	return s.repository.FindAll()
+// CreateContactFromEmail creates a new contact based on the provided InboundEmailRequest.
+// The sender's display name is used as the contact name, falling back to the email address,
+// and the subject (when present) is kept as the first line of the message.
+// Emails carrying a Message-ID that was already ingested are rejected with ErrDuplicateMessage.
+// The attached files allowed by the attachment policy are stored with the contact.
+// Once the contact is stored, post-create hooks run and the notification is queued.
+// Returns the created Contact and any error encountered.
+func (s *contactService) CreateContactFromEmail(ctx context.Context, req *requests.InboundEmailRequest) (*models.Contact, error) {
+	// Validate input
+	if err := s.validateStruct(req); err != nil {
+		return nil, err
+	}
+	fields := map[string]string{"name": req.FromName, "email": req.FromEmail, "message": req.Body}
+	currentRules := s.rules.Current()
+	if err := currentRules.Validate(fields); err != nil {
+		return nil, err
+	}
+
+	// Skip emails that were already turned into a contact. The webhooks pass the
+	// Message-ID in its angle brackets and the IMAP poller without them, so it is
+	// normalized before the lookup and stored normalized.
+	var messageID *string
+	if normalized := helpers.NormalizeMessageID(req.MessageID); normalized != "" {
+		exists, err := s.repository.ExistsByMessageID(ctx, normalized)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrDuplicateMessage
+		}
+		messageID = &normalized
+	}
+
+	name := req.FromName
+	if name == "" {
+		name = req.FromEmail
+	}
+
+	message := req.Body
+	if req.Subject != "" {
+		message = req.Subject + "\n\n" + req.Body
+	}
+
+	// Map email to Contact model
+	company := s.enricher.Derive(req.FromEmail)
+	contact := models.Contact{
+		FullName:  name,
+		Email:     req.FromEmail,
+		Message:   message,
+		Channel:   models.ChannelEmail,
+		Status:    models.StatusNew,
+		MessageID: messageID,
+
+		CompanyDomain:        company.Domain,
+		CompanyName:          company.Name,
+		MessageHash:          hashMessage(message),
+		LeadScore:            currentRules.Score(fields),
+		Language:             contactLanguage("", req.Body),
+		PrivacyPolicyVersion: s.privacyVersion,
+		TermsVersion:         s.termsVersion,
+	}
+	if err := s.flagOverEmailLimit(ctx, &contact); err != nil {
+		return nil, err
+	}
+
+	// Store the attached files allowed by the attachment policy
+	var attachments []models.Attachment
+	if files := s.emailAttachments(req.Attachments); len(files) > 0 {
+		uploaded, err := s.attachments.Upload(ctx, files)
+		if err != nil {
+			return nil, err
+		}
+		attachments = uploaded
+	}
+
+	// Persist the contact together with its attachments using the repository
+	contact.Attachments = attachments
+	if err := s.repository.Create(ctx, &contact); err != nil {
+		s.discardAttachments(attachments)
+		return &contact, err
+	}
+
+	s.afterCreate(&contact)
+	return &contact, nil
+}
+
+// emailAttachments returns the files of an inbound email allowed by the attachment policy.
+// The others, such as signature images of a type that is not allowed, are skipped and
+// logged rather than rejecting the email, which the provider would retry and then drop.
+func (s *contactService) emailAttachments(files []*multipart.FileHeader) []*multipart.FileHeader {
+	if len(files) == 0 {
+		return nil
+	}
+	if s.attachments == nil {
+		log.Printf("Skipped %d email attachments: attachments are not enabled", len(files))
+		return nil
+	}
+	var accepted []*multipart.FileHeader
+	for _, file := range files {
+		if err := s.attachments.Validate(append(accepted, file)); err != nil {
+			log.Printf("Skipped email attachment %q: %v", file.Filename, err)
+			continue
+		}
+		accepted = append(accepted, file)
+	}
+	return accepted
+}
+
+// ListContacts retrieves a sorted page of non-deleted contacts matching the filters from the repository.
+// Returns the ContactPage and any error encountered.
+func (s *contactService) ListContacts(ctx context.Context, params repositories.ListParams) (*repositories.ContactPage, error) {
+	return s.repository.FindPaged(ctx, params)
+}
+
+// SearchContacts searches the messages of the contacts through the repository.
+func (s *contactService) SearchContacts(ctx context.Context, params repositories.SearchParams) (*repositories.SearchPage, error) {
+	return s.repository.Search(ctx, params)
+}
+
+// ExportContacts streams the matching contacts from the repository in batches of
+// exportBatchSize, so that large exports do not have to fit in memory.
+func (s *contactService) ExportContacts(ctx context.Context, filter repositories.ContactFilter, fn func(contact *models.Contact) error) error {
+	return s.repository.FindInBatches(ctx, filter, exportBatchSize, func(contacts []models.Contact) error {
+		for i := range contacts {
+			if err := fn(&contacts[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // GetContactByID retrieves a single contact by its ID.
 // Returns the Contact model and any error encountered if the contact is not found.
-func (s *contactService) GetContactByID(id uint) (*models.Contact, error) {
-	return s.repository.FindByID(id)
+func (s *contactService) GetContactByID(ctx context.Context, id uint) (*models.Contact, error) {
+	return s.repository.FindByID(ctx, id)
+}
+
+// GetContactByPublicID retrieves a single contact by its generated public ID.
+// Returns the Contact model and any error encountered if the contact is not found.
+func (s *contactService) GetContactByPublicID(ctx context.Context, publicID string) (*models.Contact, error) {
+	return s.repository.FindByPublicID(ctx, publicID)
+}
+
+// GetContactsByEmail retrieves every contact stored for an email address, including soft-deleted ones.
+// It backs data subject access requests, which must cover all data still held about a person.
+// Returns a slice of Contact models and any error encountered.
+func (s *contactService) GetContactsByEmail(ctx context.Context, email string) ([]models.Contact, error) {
+	return s.repository.FindAllByEmail(ctx, email)
+}
+
+// GetAttachments retrieves the attachments of the contacts, deleted or not, in upload order
+// per contact, such as for a data subject access request.
+func (s *contactService) GetAttachments(ctx context.Context, contactIDs []uint) ([]models.Attachment, error) {
+	attachments := []models.Attachment{}
+	if s.attachments == nil {
+		return attachments, nil
+	}
+	for _, id := range contactIDs {
+		found, err := s.attachments.List(id)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, found...)
+	}
+	return attachments, nil
 }
 
 // UpdateContact updates an existing contact identified by its ID based on the provided ContactRequest.
-// It validates the request, retrieves the existing contact, updates its fields, and persists the changes.
+// It normalizes and validates the request, retrieves the existing contact, updates its fields, and persists the changes.
 // Returns the updated Contact and any error encountered.
-func (s *contactService) UpdateContact(id uint, req *requests.ContactRequest) (*models.Contact, error) {
-	// Validate input
-	if err := s.validate.Struct(req); err != nil {
+func (s *contactService) UpdateContact(ctx context.Context, actor string, id uint, req *requests.ContactRequest) (*models.Contact, error) {
+	// Normalize and validate input
+	normalizeContactRequest(req)
+	if err := s.validateStruct(req); err != nil {
+		return nil, err
+	}
+	currentRules, fields := s.rules.Current(), contactRequestFields(req)
+	if err := currentRules.Validate(fields); err != nil {
 		return nil, err
 	}
 
 	// Retrieve the existing contact
-	contact, err := s.repository.FindByID(id)
+	contact, err := s.repository.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	// Update contact fields
+	before := *contact
 	contact.FullName = req.Name
 	contact.Email = req.Email
 	contact.Phone = req.Phone
 	contact.Message = req.Message
+	contact.MessageHash = hashMessage(req.Message)
+	contact.LeadScore = currentRules.Score(fields)
+	if req.Lang != "" || contact.Language == "" {
+		contact.Language = contactLanguage(req.Lang, req.Message)
+	}
+	changedCompany := false
+	if company := s.enricher.Derive(req.Email); company.Domain != contact.CompanyDomain {
+		contact.CompanyDomain, contact.CompanyName, contact.CompanySize = company.Domain, company.Name, ""
+		changedCompany = true
+	}
 
 	// Persist the updated contact using the repository
-	err = s.repository.Update(contact)
-	return contact, err
+	if err := s.repository.Update(ctx, contact); err != nil {
+		return contact, err
+	}
+
+	s.recordAudit(newAuditLog(actor, models.AuditUpdated, &before, contact))
+	s.publish(models.EventContactUpdated, *contact)
+	if changedCompany {
+		s.enricher.Enqueue(contact.ID, contact.CompanyDomain)
+	}
+	return contact, nil
 }
 
 // DeleteContact marks a contact as deleted based on its ID.
-// It retrieves the contact and sets its DeletedAt field to the current time.
+// It retrieves the contact and sets its DeletedAt field to the current time, or purges it
+// right away with DeleteHard. Contacts under legal hold are not deleted and ErrLegalHold is
+// returned.
 // Returns any error encountered during the operation.
-func (s *contactService) DeleteContact(id uint) error {
+func (s *contactService) DeleteContact(ctx context.Context, actor string, id uint) error {
 	// Retrieve the contact to be deleted
-	contact, err := s.repository.FindByID(id)
+	contact, err := s.repository.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	// Mark the contact as deleted
-	return s.repository.Delete(contact)
-}
\ No newline at end of file
+	// Refuse to delete contacts under legal hold
+	if contact.LegalHold {
+		return ErrLegalHold
+	}
+
+	// Mark the contact as deleted; GORM sets its DeletedAt field
+	before := *contact
+	attachments, err := s.purgedAttachments([]uint{id})
+	if err != nil {
+		return err
+	}
+	err = s.repository.WithTx(ctx, func(repo repositories.ContactRepository) error {
+		if err := repo.Delete(ctx, contact); err != nil {
+			return err
+		}
+		return s.purgeDeleted(ctx, repo, []uint{id})
+	})
+	if err != nil {
+		return err
+	}
+	s.discardAttachments(attachments)
+
+	s.recordAudit(s.deletionAuditLog(actor, models.AuditDeleted, &before, contact))
+	s.publish(models.EventContactDeleted, *contact)
+	return nil
+}
+
+// FindDuplicates retrieves the groups of likely duplicate contacts from the repository.
+func (s *contactService) FindDuplicates(ctx context.Context, criteria repositories.DuplicateCriteria) ([]repositories.DuplicateGroup, error) {
+	return s.repository.FindDuplicateGroups(ctx, criteria)
+}
+
+// MergeContacts keeps the contact identified by keepID and soft-deletes the duplicates
+// identified by ids, recording that they were merged into it. With DeleteHard, the
+// duplicates are purged instead.
+// When the contacts have different names, emails or phones, resolutions must map each of
+// these fields to the ID of the contact whose value the kept contact takes; otherwise a
+// *MergeConflictError lists them. The values that lost are recorded in the audit trail.
+// Nothing is merged when any of the contacts does not exist or a duplicate is under legal hold.
+func (s *contactService) MergeContacts(ctx context.Context, actor string, keepID uint, ids []uint, resolutions map[string]uint) (*models.Contact, error) {
+	if slices.Contains(ids, keepID) {
+		return nil, ErrMergeIntoItself
+	}
+	if err := checkResolutions(resolutions, keepID, ids); err != nil {
+		return nil, err
+	}
+
+	// Check the contacts and merge them in one transaction, so that none changes in between
+	var kept *models.Contact
+	var before models.Contact
+	var duplicates []models.Contact
+	var discarded map[string][]MergeValue
+	attachments, err := s.purgedAttachments(ids)
+	if err != nil {
+		return nil, err
+	}
+	err = s.repository.WithTx(ctx, func(repo repositories.ContactRepository) error {
+		var err error
+		if kept, err = repo.FindByID(ctx, keepID); err != nil {
+			return err
+		}
+		if duplicates, err = findDeletable(ctx, repo, ids); err != nil {
+			return err
+		}
+		before = *kept
+		if discarded, err = resolveMerge(kept, duplicates, resolutions); err != nil {
+			return err
+		}
+		if err := repo.DeleteMany(ctx, ids, keepID); err != nil {
+			return err
+		}
+		if err := s.purgeDeleted(ctx, repo, contactIDs(duplicates)); err != nil {
+			return err
+		}
+
+		// Store the resolved values once the duplicates no longer hold the email address.
+		if len(discarded) == 0 {
+			return nil
+		}
+		if kept.Email != before.Email {
+			company := s.enricher.Derive(kept.Email)
+			kept.CompanyDomain, kept.CompanyName, kept.CompanySize = company.Domain, company.Name, ""
+		}
+		return repo.Update(ctx, kept)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.discardAttachments(attachments)
+	log.Printf("Contacts %v merged into contact %d", ids, keepID)
+
+	entries := make([]models.AuditLog, 0, len(duplicates))
+	for _, duplicate := range duplicates {
+		merged := deletedContact(duplicate)
+		merged.MergedIntoID = &keepID
+		entries = append(entries, s.deletionAuditLog(actor, models.AuditMerged, &duplicate, merged))
+		s.publish(models.EventContactDeleted, *merged)
+	}
+	if len(discarded) > 0 {
+		entries = append(entries, mergeAuditLog(actor, &before, kept, discarded))
+		s.publish(models.EventContactUpdated, *kept)
+		if kept.CompanyDomain != before.CompanyDomain {
+			s.enricher.Enqueue(kept.ID, kept.CompanyDomain)
+		}
+	}
+	s.recordAudit(entries...)
+	return kept, nil
+}
+
+// findDeletable retrieves the contacts with the given IDs from repo. It returns
+// gorm.ErrRecordNotFound when any of them does not exist and ErrLegalHold when any of
+// them is under legal hold.
+func findDeletable(ctx context.Context, repo repositories.ContactRepository, ids []uint) ([]models.Contact, error) {
+	contacts, err := repo.FindByIDs(ctx, slices.Compact(slices.Sorted(slices.Values(ids))))
+	if err != nil {
+		return nil, err
+	}
+	for _, contact := range contacts {
+		if contact.LegalHold {
+			return nil, ErrLegalHold
+		}
+	}
+	return contacts, nil
+}
+
+// SetLegalHold places or lifts the legal hold of a contact identified by its ID.
+// Every change of the hold is logged so it can be traced later.
+// Returns the updated Contact and any error encountered.
+func (s *contactService) SetLegalHold(ctx context.Context, actor string, id uint, hold bool) (*models.Contact, error) {
+	// Retrieve the existing contact
+	contact, err := s.repository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if contact.LegalHold == hold {
+		return contact, nil
+	}
+
+	// Persist the new hold state using the repository
+	before := *contact
+	contact.LegalHold = hold
+	if err := s.repository.Update(ctx, contact); err != nil {
+		return nil, err
+	}
+	s.recordAudit(newAuditLog(actor, models.AuditLegalHoldChanged, &before, contact))
+
+	log.Printf("Legal hold on contact %d changed to %t", id, hold)
+	s.publish(models.EventContactUpdated, *contact)
+	return contact, nil
+}
+
+// SetPinned pins a contact identified by its ID to the top of the inbox, or unpins it.
+// Pins are shared by the whole team; every change is audited and published so that the
+// inbox projection follows it.
+// Returns the updated Contact and any error encountered.
+func (s *contactService) SetPinned(ctx context.Context, actor string, id uint, pinned bool) (*models.Contact, error) {
+	// Retrieve the existing contact
+	contact, err := s.repository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if contact.Pinned == pinned {
+		return contact, nil
+	}
+
+	// Persist the new pin state using the repository
+	before := *contact
+	contact.Pinned = pinned
+	if err := s.repository.Update(ctx, contact); err != nil {
+		return nil, err
+	}
+	s.recordAudit(newAuditLog(actor, models.AuditPinChanged, &before, contact))
+
+	s.publish(models.EventContactUpdated, *contact)
+	return contact, nil
+}
+
+// SetStarred stars a contact identified by its ID for owner, or removes the star.
+// Stars are personal: they are neither audited nor published, and only select the
+// contacts listed to their owner with starred=true. Starring twice changes nothing.
+func (s *contactService) SetStarred(ctx context.Context, owner string, id uint, starred bool) error {
+	return s.repository.SetStarred(ctx, owner, id, starred)
+}
+
+// UpdateStatus changes the status of a contact identified by its ID.
+// Only the transitions allowed by models.Status.CanTransitionTo are accepted, and those
+// requiring a review only when reviewed is true; others are rejected with
+// ErrInvalidStatusTransition. Setting the current status again changes nothing.
+// The change is published as contact.updated, then contact.status_changed.
+// Returns the updated Contact and any error encountered.
+func (s *contactService) UpdateStatus(ctx context.Context, actor string, id uint, status models.Status, reviewed bool) (*models.Contact, error) {
+	// Retrieve the existing contact
+	contact, err := s.repository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if contact.Status == status {
+		return contact, nil
+	}
+	if err := checkTransition(contact.Status, status, reviewed); err != nil {
+		return nil, err
+	}
+
+	// Persist the new status using the repository
+	if err := s.repository.UpdateStatus(ctx, id, status); err != nil {
+		return nil, err
+	}
+	updated, err := s.publishUpdated(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.webhooks.PublishStatusChange(*updated, contact.Status)
+	s.recordAudit(newAuditLog(actor, models.AuditStatusChanged, contact, updated))
+	return updated, nil
+}
+
+// checkTransition returns an ErrInvalidStatusTransition when a contact may not change from
+// status from to status to, given whether it was reviewed.
+func checkTransition(from, to models.Status, reviewed bool) error {
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidStatusTransition, from, to)
+	}
+	if from.RequiresReview(to) && !reviewed {
+		return fmt.Errorf("%w: %s to %s requires a review", ErrInvalidStatusTransition, from, to)
+	}
+	return nil
+}
+
+// GetDeletedContacts retrieves a page of soft-deleted contacts from the repository, most recently deleted first.
+// Returns the page of Contact models and any error encountered.
+func (s *contactService) GetDeletedContacts(ctx context.Context, params repositories.ListParams) (*repositories.ContactPage, error) {
+	return s.repository.FindDeleted(ctx, params)
+}
+
+// RestoreContact undoes the deletion of a contact identified by its ID.
+// Returns the restored Contact and any error encountered, such as gorm.ErrRecordNotFound
+// when no deleted contact has the ID.
+func (s *contactService) RestoreContact(ctx context.Context, actor string, id uint) (*models.Contact, error) {
+	deleted, err := s.repository.FindDeletedByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repository.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Contact %d restored", id)
+	restored, err := s.publishUpdated(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.recordAudit(newAuditLog(actor, models.AuditRestored, deleted, restored))
+	return restored, nil
+}
+
+// PurgeContact permanently removes a contact that was deleted before.
+// Contacts that are not deleted, or that are under legal hold, are never purged. With
+// dryRun, the contact is only checked.
+// Returns any error encountered, such as gorm.ErrRecordNotFound when no such contact exists.
+func (s *contactService) PurgeContact(ctx context.Context, actor string, id uint, dryRun bool) error {
+	deleted, err := s.repository.FindDeletedByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		if deleted.LegalHold {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	}
+
+	// List the attachments first; their records are removed together with the contact
+	var attachments []models.Attachment
+	if s.attachments != nil {
+		found, err := s.attachments.List(id)
+		if err != nil {
+			return err
+		}
+		attachments = found
+	}
+
+	if err := s.repository.HardDelete(ctx, id); err != nil {
+		return err
+	}
+	s.discardAttachments(attachments)
+	s.recordAudit(purgeAuditLog(actor, id))
+
+	log.Printf("Contact %d purged", id)
+	return nil
+}
+
+// deletionAuditLog describes the deletion of a contact by actor with action. Contacts
+// purged with DeleteHard keep no personal data in the audit trail: the entry only records
+// the purge of their ID.
+func (s *contactService) deletionAuditLog(actor string, action models.AuditAction, before, after *models.Contact) models.AuditLog {
+	if s.deleteMode != DeleteHard {
+		return newAuditLog(actor, action, before, after)
+	}
+	if action == models.AuditDeleted {
+		return purgeAuditLog(actor, before.ID)
+	}
+	entry := newAuditLog(actor, action, nil, nil)
+	entry.ContactID = before.ID
+	return entry
+}
+
+// purgeAuditLog describes the purge of the contact with the given ID by actor. Like the
+// rest of the audit trail of a purged contact, it keeps none of its personal data.
+func purgeAuditLog(actor string, id uint) models.AuditLog {
+	entry := newAuditLog(actor, models.AuditPurged, nil, nil)
+	entry.ContactID = id
+	return entry
+}
+
+// purgedAttachments lists the attachments of the contacts with the given IDs when they are
+// to be purged with DeleteHard, as their records are removed together with the contacts.
+func (s *contactService) purgedAttachments(ids []uint) ([]models.Attachment, error) {
+	if s.deleteMode != DeleteHard || s.attachments == nil {
+		return nil, nil
+	}
+	var attachments []models.Attachment
+	for _, id := range ids {
+		found, err := s.attachments.List(id)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, found...)
+	}
+	return attachments, nil
+}
+
+// purgeDeleted permanently removes the contacts with the given IDs, just soft-deleted in
+// the transaction of repo, when deleted contacts are purged with DeleteHard.
+func (s *contactService) purgeDeleted(ctx context.Context, repo repositories.ContactRepository, ids []uint) error {
+	if s.deleteMode != DeleteHard {
+		return nil
+	}
+	for _, id := range ids {
+		if err := repo.HardDelete(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discardAttachments removes the stored files of attachments, if any. It runs without
+// the request context, so that the files are removed even when the request was cancelled.
+func (s *contactService) discardAttachments(attachments []models.Attachment) {
+	if len(attachments) > 0 {
+		s.attachments.Discard(context.Background(), attachments)
+	}
+}
+
+// afterCreate counts a stored contact in the submission metrics, runs its post-create
+// hooks, queues the previews of its images, its notification, unless it was flagged as
+// spam or as a duplicate or a pre-notify hook suppresses it, and its auto-reply, unless it
+// was flagged, and publishes its creation to webhooks.
+func (s *contactService) afterCreate(contact *models.Contact) {
+	observability.Submissions.WithLabelValues(string(contact.Channel), string(contact.Status)).Inc()
+	s.hooks.RunPostCreate(contact)
+	s.enricher.Enqueue(contact.ID, contact.CompanyDomain)
+	if s.attachments != nil {
+		s.attachments.Recorded(contact.Attachments)
+	}
+
+	if s.notifier != nil && contact.Status != models.StatusSpam && contact.DuplicateOfID == nil && s.hooks.RunPreNotify(contact) {
+		s.notifier.Notify(*contact)
+	}
+	if contact.Status != models.StatusSpam && contact.DuplicateOfID == nil {
+		s.autoReplies.Notify(*contact)
+	}
+	s.publish(models.EventContactCreated, *contact)
+}
+
+// flagOverEmailLimit sets the status of a new contact to spam when its email address
+// already reached the daily submission limit. The submission is still stored, so that
+// persistent abusers are visible to the team without being told they were caught.
+func (s *contactService) flagOverEmailLimit(ctx context.Context, contact *models.Contact) error {
+	if s.emailDailyLimit <= 0 {
+		return nil
+	}
+
+	count, err := s.repository.CountByEmailSince(ctx, contact.Email, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if count >= int64(s.emailDailyLimit) {
+		contact.Status = models.StatusSpam
+		log.Printf("Submission flagged as spam: email address over the daily limit of %d", s.emailDailyLimit)
+	}
+	return nil
+}
+
+// flagReplaySource sets the status of a new contact to spam when the client IP address it
+// was submitted from recently replayed submissions, as bots replaying captured requests
+// are likely behind its other submissions too.
+func (s *contactService) flagReplaySource(ctx context.Context, contact *models.Contact, clientIP string) error {
+	if s.replayLimit <= 0 || s.replays == nil || clientIP == "" {
+		return nil
+	}
+
+	count, err := s.replays.CountByClientIPSince(ctx, clientIP, models.RejectionReplay, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if count >= int64(s.replayLimit) {
+		contact.Status = models.StatusSpam
+		log.Printf("Submission flagged as spam: %d replay rejections of its client IP in 24 hours", count)
+	}
+	return nil
+}
+
+// checkDuplicate looks for a recent contact of the same email address with the same
+// message when the duplicate detection is enabled, and rejects the new contact or flags
+// it with the ID of the earliest such contact.
+func (s *contactService) checkDuplicate(ctx context.Context, contact *models.Contact) error {
+	if s.duplicates.Window <= 0 {
+		return nil
+	}
+
+	original, err := s.repository.FindRepeatedSince(ctx, contact.Email, contact.MessageHash, time.Now().Add(-s.duplicates.Window))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.duplicates.Reject {
+		return ErrDuplicateSubmission
+	}
+	contact.DuplicateOfID = &original.ID
+	log.Printf("Submission flagged as a duplicate of contact %d", original.ID)
+	return nil
+}
+
+// deletedContact returns a copy of contact as soft-deleted now.
+func deletedContact(contact models.Contact) *models.Contact {
+	contact.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return &contact
+}
+
+// publish applies event about contact to the inbox projection and publishes it to webhooks.
+func (s *contactService) publish(event models.WebhookEvent, contact models.Contact) {
+	s.inbox.Apply(event, &contact)
+	s.webhooks.Publish(event, contact)
+}
+
+// publishUpdated reloads a changed contact and publishes its update to webhooks.
+func (s *contactService) publishUpdated(ctx context.Context, id uint) (*models.Contact, error) {
+	contact, err := s.repository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(models.EventContactUpdated, *contact)
+	return contact, nil
+}
+
+// contactRequestFields returns the values of a ContactRequest keyed by their JSON names,
+// as expected by the custom validation rules.
+func contactRequestFields(req *requests.ContactRequest) map[string]string {
+	return map[string]string{
+		"name":            req.Name,
+		"email":           req.Email,
+		"phone":           req.Phone,
+		"message":         req.Message,
+		"consent_version": req.ConsentVersion,
+	}
+}
+
+// hashMessage returns the hex SHA-256 of a message, ignoring case and whitespace, as stored
+// in models.Contact.MessageHash.
+func hashMessage(message string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(message)), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashFingerprint returns the hex SHA-256 of a client fingerprint blob, or an empty string
+// when none was sent. The JSON is compacted first so that formatting differences between
+// widget versions do not change the hash.
+func hashFingerprint(fingerprint json.RawMessage) string {
+	if len(fingerprint) == 0 || string(fingerprint) == "null" {
+		return ""
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, fingerprint); err != nil {
+		compacted.Reset()
+		compacted.Write(fingerprint)
+	}
+
+	sum := sha256.Sum256(compacted.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// contactLanguage returns the language code of a submission: the one given by the
+// submitter, or the language detected from its message.
+func contactLanguage(lang, message string) string {
+	if lang != "" {
+		return lang
+	}
+	return helpers.DetectLanguage(message)
+}
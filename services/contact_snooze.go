@@ -0,0 +1,104 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file implements the snoozing of contacts by the ContactService: a snoozed contact
+// leaves the default inbox until the time chosen, when the snooze job sets it back to new
+// and notifies the team again, as for a new submission.
+package services
+
+import (
+	"api-contact-form/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SnoozeActor is the actor recorded for the snoozes ended by the snooze job.
+const SnoozeActor = "system:snooze"
+
+// snoozeBatchSize is the number of contacts read at once by the snooze job.
+const snoozeBatchSize = 100
+
+// ErrSnoozeInPast is returned when a contact is snoozed until a time that has passed.
+var ErrSnoozeInPast = errors.New("until must be in the future")
+
+// Snooze hides a contact identified by its ID from the default inbox until the given time,
+// when EndSnoozes returns it to the new status. Only contacts that may go back to new can
+// be snoozed; others are rejected with ErrInvalidStatusTransition. Snoozing a snoozed
+// contact moves the end of its snooze. The change is audited and published.
+// Returns the updated Contact and any error encountered.
+func (s *contactService) Snooze(ctx context.Context, actor string, id uint, until time.Time) (*models.Contact, error) {
+	if !until.After(time.Now()) {
+		return nil, ErrSnoozeInPast
+	}
+
+	// Retrieve the existing contact
+	contact, err := s.repository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if contact.Status != models.StatusNew {
+		if err := checkTransition(contact.Status, models.StatusNew, false); err != nil {
+			return nil, fmt.Errorf("%w, so it cannot be snoozed", err)
+		}
+	}
+
+	// Persist the snooze using the repository
+	before := *contact
+	contact.SnoozedUntil = &until
+	if err := s.repository.Update(ctx, contact); err != nil {
+		return nil, err
+	}
+	s.recordAudit(newAuditLog(actor, models.AuditSnoozed, &before, contact))
+
+	s.publish(models.EventContactUpdated, *contact)
+	return contact, nil
+}
+
+// EndSnoozes sets the contacts whose snooze has ended back to new, in batches, and notifies
+// them again. Every change is audited and published as contact.updated, then as
+// contact.status_changed when the status changed. Contacts snoozed again or deleted in the
+// meantime are skipped. Returns the IDs of the contacts returned to the inbox.
+func (s *contactService) EndSnoozes(ctx context.Context, actor string) ([]uint, error) {
+	var ended []uint
+	now := time.Now()
+	for {
+		contacts, err := s.repository.FindSnoozeDue(ctx, now, snoozeBatchSize)
+		if err != nil {
+			return ended, err
+		}
+		for i := range contacts {
+			contact := &contacts[i]
+			err := s.repository.EndSnooze(ctx, contact.ID, now)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				return ended, err
+			}
+			ended = append(ended, contact.ID)
+
+			updated, err := s.publishUpdated(ctx, contact.ID)
+			if err != nil {
+				log.Printf("Snooze of contact %d ended but not published: %v", contact.ID, err)
+				continue
+			}
+			if updated.Status != contact.Status {
+				s.webhooks.PublishStatusChange(*updated, contact.Status)
+			}
+			s.recordAudit(newAuditLog(actor, models.AuditSnoozed, contact, updated))
+			s.notifier.Notify(*updated)
+		}
+		if len(contacts) < snoozeBatchSize {
+			break
+		}
+	}
+
+	if len(ended) > 0 {
+		log.Printf("Snooze ended for %d contacts", len(ended))
+	}
+	return ended, nil
+}
@@ -0,0 +1,140 @@
+package services_test
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/notifications"
+	"api-contact-form/repositories"
+	"api-contact-form/services"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// notifiedContacts is a notifications.Notifier sending the IDs of the notified contacts
+// to a channel.
+type notifiedContacts chan uint
+
+func (n notifiedContacts) Name() string { return "test" }
+
+func (n notifiedContacts) Send(_ context.Context, contact models.Contact) error {
+	n <- contact.ID
+	return nil
+}
+
+func (n notifiedContacts) SendDigest(context.Context, []models.Contact, time.Duration) error {
+	return nil
+}
+
+func TestSnoozeAndEndSnoozes(t *testing.T) {
+	db := openDB(t, &models.Contact{}, &models.AuditLog{}, &models.InboxEntry{})
+	inbox := services.NewInboxService(repositories.NewInboxRepository(db))
+	notified := make(notifiedContacts, 10)
+	notifier := notifications.NewDispatcher("notifications", []notifications.Notifier{notified}, 1, 0, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	notifier.Start(ctx, 1)
+	contacts := services.NewContactService(repositories.NewContactRepository(db),
+		services.WithAuditLog(repositories.NewAuditLogRepository(db)),
+		services.WithInboxProjection(services.NewInboxProjection(repositories.NewInboxRepository(db), nil)),
+		services.WithNotifier(notifier),
+	)
+
+	create := func(email string, status models.Status) *models.Contact {
+		t.Helper()
+		contact := &models.Contact{FullName: "Jane", Email: email, Phone: "+15551234567", Message: "Hello", Status: status}
+		if err := db.Create(contact).Error; err != nil {
+			t.Fatalf("create contact: %v", err)
+		}
+		return contact
+	}
+	read := create("read@example.com", models.StatusRead)
+	later := create("later@example.com", models.StatusNew)
+	replied := create("replied@example.com", models.StatusReplied)
+	if _, err := inbox.RebuildInbox(ctx); err != nil {
+		t.Fatalf("RebuildInbox: %v", err)
+	}
+
+	// Snoozes end in the future, for contacts that may go back to new
+	if _, err := contacts.Snooze(ctx, "user:1", read.ID, time.Now().Add(-time.Minute)); !errors.Is(err, services.ErrSnoozeInPast) {
+		t.Errorf("Snooze(past) error = %v, want ErrSnoozeInPast", err)
+	}
+	if _, err := contacts.Snooze(ctx, "user:1", replied.ID, time.Now().Add(time.Hour)); !errors.Is(err, services.ErrInvalidStatusTransition) {
+		t.Errorf("Snooze(replied) error = %v, want ErrInvalidStatusTransition", err)
+	}
+	for _, contact := range []*models.Contact{read, later} {
+		snoozed, err := contacts.Snooze(ctx, "user:1", contact.ID, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Snooze(%d): %v", contact.ID, err)
+		}
+		if snoozed.SnoozedUntil == nil {
+			t.Errorf("Snooze(%d) SnoozedUntil = nil", contact.ID)
+		}
+	}
+
+	// Snoozed contacts leave the default inbox
+	listed := func(filter repositories.InboxFilter) []uint {
+		t.Helper()
+		entries, err := inbox.ListInbox(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListInbox: %v", err)
+		}
+		var ids []uint
+		for _, entry := range entries {
+			ids = append(ids, entry.ContactID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+	if got := listed(repositories.InboxFilter{}); !slices.Equal(got, []uint{replied.ID}) {
+		t.Errorf("inbox = %v, want only %d", got, replied.ID)
+	}
+	if got := listed(repositories.InboxFilter{Snoozed: true}); !slices.Equal(got, []uint{read.ID, later.ID}) {
+		t.Errorf("snoozed inbox = %v, want %d and %d", got, read.ID, later.ID)
+	}
+
+	// Once its snooze ends, a contact is new again and notified
+	if err := db.Model(read).Update("snoozed_until", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("end snooze: %v", err)
+	}
+	ended, err := contacts.EndSnoozes(ctx, services.SnoozeActor)
+	if err != nil {
+		t.Fatalf("EndSnoozes: %v", err)
+	}
+	if !slices.Equal(ended, []uint{read.ID}) {
+		t.Errorf("EndSnoozes = %v, want %d", ended, read.ID)
+	}
+	woken, err := contacts.GetContactByID(ctx, read.ID)
+	if err != nil {
+		t.Fatalf("GetContactByID: %v", err)
+	}
+	if woken.Status != models.StatusNew || woken.SnoozedUntil != nil {
+		t.Errorf("woken contact status %s, SnoozedUntil %v, want new and nil", woken.Status, woken.SnoozedUntil)
+	}
+	if got := listed(repositories.InboxFilter{UnreadOnly: true}); !slices.Equal(got, []uint{read.ID}) {
+		t.Errorf("unread inbox = %v, want %d", got, read.ID)
+	}
+	select {
+	case id := <-notified:
+		if id != read.ID {
+			t.Errorf("notified contact %d, want %d", id, read.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("woken contact not notified")
+	}
+
+	// The trail records the snooze and its end
+	var entries []models.AuditLog
+	if err := db.Where("contact_id = ? AND action = ?", read.ID, models.AuditSnoozed).Order("id").Find(&entries).Error; err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Actor != "user:1" || entries[1].Actor != services.SnoozeActor {
+		t.Errorf("audit entries = %+v, want the snooze by user:1 and its end by %s", entries, services.SnoozeActor)
+	}
+
+	// Nothing is left for the next run
+	if ended, err := contacts.EndSnoozes(ctx, services.SnoozeActor); err != nil || len(ended) != 0 {
+		t.Errorf("second EndSnoozes = %v, %v, want none", ended, err)
+	}
+}
@@ -0,0 +1,357 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file defines the ExportScheduleService, which manages the recurring exports of
+// contacts and runs them when they are due, delivering each report by email or to S3 so
+// that managers get it without logging in.
+package services
+
+import (
+	"api-contact-form/exports"
+	"api-contact-form/helpers"
+	"api-contact-form/models"
+	"api-contact-form/notifications"
+	"api-contact-form/repositories"
+	"api-contact-form/requests"
+	"api-contact-form/schedule"
+	"api-contact-form/storage"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrInvalidExportSchedule is returned for scheduled exports with invalid settings.
+var ErrInvalidExportSchedule = errors.New("invalid export schedule")
+
+// ErrExportDestinationUnavailable is returned for scheduled exports delivered to a
+// destination the instance is not configured for.
+var ErrExportDestinationUnavailable = errors.New("export destination is not configured")
+
+// ErrExportRunInProgress is returned when a scheduled export is run while another run of
+// it is starting.
+var ErrExportRunInProgress = errors.New("the export schedule is already running")
+
+// ReportDelivery holds the destinations scheduled exports can be delivered to.
+type ReportDelivery struct {
+	// Mailer emails the reports, or the notifications of their upload; nil when SMTP is
+	// not configured.
+	Mailer *notifications.ReportMailer
+	// Storage keeps the uploaded reports, and Bucket names it in notifications; Storage is
+	// nil when no export bucket is configured.
+	Storage storage.Storage
+	Bucket  string
+}
+
+// ExportScheduleService defines the business logic interface for scheduled exports.
+type ExportScheduleService interface {
+	// ListSchedules retrieves every scheduled export.
+	ListSchedules(ctx context.Context) ([]models.ExportSchedule, error)
+	// GetSchedule retrieves a scheduled export identified by its ID.
+	GetSchedule(ctx context.Context, id uint) (*models.ExportSchedule, error)
+	// CreateSchedule registers a new scheduled export.
+	CreateSchedule(ctx context.Context, req *requests.ExportScheduleRequest) (*models.ExportSchedule, error)
+	// UpdateSchedule changes the settings of a scheduled export identified by its ID.
+	UpdateSchedule(ctx context.Context, id uint, req *requests.ExportScheduleRequest) (*models.ExportSchedule, error)
+	// DeleteSchedule removes a scheduled export identified by its ID.
+	DeleteSchedule(ctx context.Context, id uint) error
+	// RunSchedule runs a scheduled export identified by its ID right away, and returns it
+	// with the outcome of the run.
+	RunSchedule(ctx context.Context, id uint) (*models.ExportSchedule, error)
+	// RunDue runs the active scheduled exports whose next run time has passed.
+	RunDue(ctx context.Context)
+}
+
+// exportScheduleService is the concrete implementation of ExportScheduleService.
+type exportScheduleService struct {
+	repository repositories.ExportScheduleRepository
+	contacts   ContactService
+	delivery   ReportDelivery
+	hashKey    []byte
+}
+
+// NewExportScheduleService creates a new instance of ExportScheduleService storing the
+// schedules with repository, exporting the contacts of contacts and delivering the reports
+// with delivery. Personal data of anonymized reports is hashed under hashKey.
+func NewExportScheduleService(repository repositories.ExportScheduleRepository, contacts ContactService, delivery ReportDelivery, hashKey []byte) ExportScheduleService {
+	return &exportScheduleService{repository: repository, contacts: contacts, delivery: delivery, hashKey: hashKey}
+}
+
+// ListSchedules retrieves every schedule from the repository.
+func (s *exportScheduleService) ListSchedules(ctx context.Context) ([]models.ExportSchedule, error) {
+	return s.repository.FindAll(ctx)
+}
+
+// GetSchedule retrieves a schedule from the repository. It returns gorm.ErrRecordNotFound
+// when there is none.
+func (s *exportScheduleService) GetSchedule(ctx context.Context, id uint) (*models.ExportSchedule, error) {
+	return s.repository.FindByID(ctx, id)
+}
+
+// CreateSchedule validates the request and stores the schedule. Its first run is the
+// first time of its cron expression after its creation.
+func (s *exportScheduleService) CreateSchedule(ctx context.Context, req *requests.ExportScheduleRequest) (*models.ExportSchedule, error) {
+	schedule := &models.ExportSchedule{Active: true}
+	if err := s.apply(schedule, req); err != nil {
+		return nil, err
+	}
+	if err := s.repository.Create(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule validates the request and saves the settings of the schedule. It returns
+// gorm.ErrRecordNotFound when there is none.
+func (s *exportScheduleService) UpdateSchedule(ctx context.Context, id uint, req *requests.ExportScheduleRequest) (*models.ExportSchedule, error) {
+	schedule, err := s.repository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(schedule, req); err != nil {
+		return nil, err
+	}
+	if err := s.repository.Update(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return s.repository.FindByID(ctx, id)
+}
+
+// DeleteSchedule deletes the schedule from the repository. It returns gorm.ErrRecordNotFound
+// when there is none.
+func (s *exportScheduleService) DeleteSchedule(ctx context.Context, id uint) error {
+	return s.repository.Delete(ctx, id)
+}
+
+// RunSchedule claims a run of the schedule, exports and delivers the report, and records
+// the outcome. The next scheduled run is the first time of the cron expression after this
+// one. It returns gorm.ErrRecordNotFound when there is no such schedule, and
+// ErrExportRunInProgress when another run claimed it first. The failures of the export or
+// its delivery are recorded on the schedule rather than returned.
+func (s *exportScheduleService) RunSchedule(ctx context.Context, id uint) (*models.ExportSchedule, error) {
+	schedule, err := s.repository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	claimed, err := s.repository.Claim(ctx, id, schedule.LastRunAt, time.Now().Truncate(time.Second))
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrExportRunInProgress
+	}
+
+	s.run(ctx, schedule)
+	return s.repository.FindByID(ctx, id)
+}
+
+// RunDue runs the due schedules one after the other. A schedule is due once the first
+// time of its cron expression after its last run, or after its creation, has passed;
+// occurrences missed while the server was down are caught up with a single run.
+func (s *exportScheduleService) RunDue(ctx context.Context) {
+	schedules, err := s.repository.FindActive(ctx)
+	if err != nil {
+		log.Printf("Failed to list the export schedules: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range schedules {
+		current := &schedules[i]
+		sched, err := schedule.Parse(current.Cron, helpers.AppTimezone())
+		if err != nil {
+			log.Printf("Export schedule %d has an invalid cron expression: %v", current.ID, err)
+			continue
+		}
+		since := current.CreatedAt
+		if current.LastRunAt != nil {
+			since = *current.LastRunAt
+		}
+		if next := sched.Next(since); next.IsZero() || next.After(now) {
+			continue
+		}
+
+		// Claim the run, so that other replicas skip it.
+		claimed, err := s.repository.Claim(ctx, current.ID, current.LastRunAt, now.Truncate(time.Second))
+		if err != nil {
+			log.Printf("Failed to claim export schedule %d: %v", current.ID, err)
+			continue
+		}
+		if claimed {
+			s.run(ctx, current)
+		}
+	}
+}
+
+// run exports and delivers the report of the schedule, and records the outcome.
+func (s *exportScheduleService) run(ctx context.Context, schedule *models.ExportSchedule) {
+	rows, err := s.export(ctx, schedule, time.Now())
+	runErr := ""
+	if err != nil {
+		runErr = err.Error()
+		log.Printf("Export schedule %d failed: %v", schedule.ID, err)
+	} else {
+		log.Printf("Export schedule %d delivered %d contacts to %s", schedule.ID, rows, schedule.Destination)
+	}
+	if err := s.repository.RecordResult(ctx, schedule.ID, rows, runErr); err != nil {
+		log.Printf("Failed to record the run of export schedule %d: %v", schedule.ID, err)
+	}
+}
+
+// export writes the report of the schedule as of at and delivers it. It returns the
+// number of contacts exported.
+func (s *exportScheduleService) export(ctx context.Context, schedule *models.ExportSchedule, at time.Time) (int, error) {
+	// Write the matching contacts to the file in memory.
+	filter := repositories.ContactFilter{Status: schedule.Status, Channel: schedule.Channel}
+	if schedule.Period > 0 {
+		filter.CreatedFrom = at.Add(-schedule.Period)
+	}
+	format := exports.Format(schedule.Format)
+	var file bytes.Buffer
+	var writer exports.Writer
+	var err error
+	if schedule.Mode == "anonymized" {
+		writer, err = exports.NewAnonymizedWriter(format, &file, s.hashKey)
+	} else {
+		writer, err = exports.NewWriter(format, &file)
+	}
+	if err != nil {
+		return 0, err
+	}
+	rows := 0
+	err = s.contacts.ExportContacts(ctx, filter, func(contact *models.Contact) error {
+		rows++
+		return writer.Write(contact)
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Deliver the file.
+	filename := fmt.Sprintf("%s-%s.%s", reportSlug(schedule.Name), at.In(helpers.AppTimezone()).Format("20060102-150405"), format)
+	subject := fmt.Sprintf("Report: %s", schedule.Name)
+	switch schedule.Destination {
+	case models.DestinationEmail:
+		if s.delivery.Mailer == nil {
+			return rows, ErrExportDestinationUnavailable
+		}
+		body := fmt.Sprintf("The report %q, with %d contacts, is attached.\n", schedule.Name, rows)
+		return rows, s.delivery.Mailer.SendReport(schedule.RecipientList(), subject, body, &notifications.Attachment{
+			Filename:    filename,
+			ContentType: format.ContentType(),
+			Data:        file.Bytes(),
+		})
+	case models.DestinationS3:
+		if s.delivery.Storage == nil {
+			return rows, ErrExportDestinationUnavailable
+		}
+		key := schedule.S3Prefix + filename
+		if err := s.delivery.Storage.Put(ctx, key, bytes.NewReader(file.Bytes()), int64(file.Len()), format.ContentType()); err != nil {
+			return rows, fmt.Errorf("upload report: %w", err)
+		}
+		if s.delivery.Mailer == nil || schedule.Recipients == "" {
+			return rows, nil
+		}
+		body := fmt.Sprintf("The report %q, with %d contacts, was uploaded to s3://%s/%s.\n", schedule.Name, rows, s.delivery.Bucket, key)
+		if err := s.delivery.Mailer.SendReport(schedule.RecipientList(), subject, body, nil); err != nil {
+			return rows, fmt.Errorf("report uploaded as %s, but the notification failed: %w", key, err)
+		}
+		return rows, nil
+	default:
+		return rows, ErrExportDestinationUnavailable
+	}
+}
+
+// apply validates the request and copies its settings to target.
+func (s *exportScheduleService) apply(target *models.ExportSchedule, req *requests.ExportScheduleRequest) error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidExportSchedule, fmt.Sprintf(format, args...))
+	}
+
+	if _, err := schedule.Parse(req.Cron, helpers.AppTimezone()); err != nil {
+		return invalid("cron: %v", err)
+	}
+	format := exports.Format(strings.ToLower(req.Format))
+	if format == "" {
+		format = exports.FormatCSV
+	}
+	if format != exports.FormatCSV && format != exports.FormatXLSX && format != exports.FormatVCard {
+		return invalid("%v", exports.ErrUnknownFormat)
+	}
+	mode := strings.ToLower(req.Mode)
+	if mode == "" {
+		mode = "full"
+	}
+	if mode != "full" && mode != "anonymized" {
+		return invalid("mode must be full or anonymized")
+	}
+	if mode == "anonymized" && format == exports.FormatVCard {
+		return invalid("%v", exports.ErrUnknownAnonymizedFormat)
+	}
+	if req.Status != "" && !req.Status.Valid() {
+		return invalid("unknown status %q", req.Status)
+	}
+	if req.Channel != "" && !req.Channel.Valid() {
+		return invalid("unknown channel %q", req.Channel)
+	}
+	var period time.Duration
+	if req.Period != "" {
+		var err error
+		if period, err = time.ParseDuration(req.Period); err != nil || period < 0 {
+			return invalid("period must be a positive duration, e.g. 168h")
+		}
+	}
+
+	// Check that the destination is available.
+	switch req.Destination {
+	case models.DestinationEmail:
+		if len(req.Recipients) == 0 {
+			return invalid("recipients are required for the email destination")
+		}
+		if s.delivery.Mailer == nil {
+			return fmt.Errorf("%w: email reports require SMTP_HOST and SMTP_FROM", ErrExportDestinationUnavailable)
+		}
+	case models.DestinationS3:
+		if s.delivery.Storage == nil {
+			return fmt.Errorf("%w: S3 reports require EXPORT_S3_BUCKET", ErrExportDestinationUnavailable)
+		}
+		if len(req.Recipients) > 0 && s.delivery.Mailer == nil {
+			return fmt.Errorf("%w: notifying recipients requires SMTP_HOST and SMTP_FROM", ErrExportDestinationUnavailable)
+		}
+	default:
+		return invalid("destination must be email or s3")
+	}
+
+	target.Name = strings.TrimSpace(req.Name)
+	target.Cron = strings.TrimSpace(req.Cron)
+	target.Format = string(format)
+	target.Mode = mode
+	target.Status = req.Status
+	target.Channel = req.Channel
+	target.Period = period
+	target.Destination = req.Destination
+	target.S3Prefix = req.S3Prefix
+	target.Recipients = strings.Join(req.Recipients, ",")
+	if req.Active != nil {
+		target.Active = *req.Active
+	}
+	return nil
+}
+
+// reportSlug turns the name of a report into a file name prefix, such as
+// "weekly-replied-contacts" for "Weekly replied contacts".
+func reportSlug(name string) string {
+	slug := strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), "-")
+	if slug == "" {
+		return "report"
+	}
+	return slug
+}
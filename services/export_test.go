@@ -0,0 +1,12 @@
+package services
+
+import "time"
+
+// TOTPCode returns the code of the authenticator app with secret at now.
+func TOTPCode(secret string, now time.Time) string {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		panic(err)
+	}
+	return totpCode(key, now.Unix()/int64(totpStep.Seconds()))
+}
@@ -0,0 +1,153 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file defines the FollowUpService, which keeps the follow-ups agents plan with
+// contacts, and signs the tokens of the calendar feeds publishing them. Calendar
+// applications cannot send credentials, so the feed of each agent is reached through a URL
+// carrying a token, which stops working once the agent's user is disabled or key revoked.
+package services
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/requests"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidFeedToken is returned when a calendar feed token is malformed, not signed with
+// the feed secret, or belongs to an agent who may no longer sign in.
+var ErrInvalidFeedToken = errors.New("invalid calendar feed token")
+
+// FollowUpService defines the business logic interface for follow-ups.
+type FollowUpService interface {
+	// ListFollowUps retrieves the follow-ups planned by owner, by due date.
+	ListFollowUps(ctx context.Context, owner string) ([]models.FollowUp, error)
+	// SetFollowUp plans or reschedules the follow-up of a contact identified by its ID, on
+	// behalf of owner.
+	SetFollowUp(ctx context.Context, owner string, contactID uint, req *requests.FollowUpRequest) (*models.FollowUp, error)
+	// ClearFollowUp removes the follow-up of a contact identified by its ID planned by owner.
+	ClearFollowUp(ctx context.Context, owner string, contactID uint) (*models.FollowUp, error)
+	// FeedToken returns the token of the calendar feed of owner.
+	FeedToken(owner string) string
+	// FeedOwner returns the owner of the calendar feed of token.
+	FeedOwner(ctx context.Context, token string) (string, error)
+}
+
+// followUpService is the concrete implementation of FollowUpService.
+type followUpService struct {
+	followUps  repositories.FollowUpRepository
+	contacts   repositories.ContactRepository
+	users      repositories.AdminUserRepository
+	keys       repositories.APIKeyRepository
+	feedSecret []byte
+}
+
+// NewFollowUpService creates a new instance of FollowUpService storing the follow-ups of
+// the contacts of contacts in followUps. Feed tokens are signed with feedSecret, so that
+// changing it revokes every feed; the tokens of admin users and stored API keys are
+// checked against users and keys.
+func NewFollowUpService(followUps repositories.FollowUpRepository, contacts repositories.ContactRepository, users repositories.AdminUserRepository, keys repositories.APIKeyRepository, feedSecret []byte) FollowUpService {
+	return &followUpService{followUps: followUps, contacts: contacts, users: users, keys: keys, feedSecret: feedSecret}
+}
+
+// ListFollowUps retrieves the follow-ups from the repository, with their contact.
+func (s *followUpService) ListFollowUps(ctx context.Context, owner string) ([]models.FollowUp, error) {
+	return s.followUps.FindByOwner(ctx, owner)
+}
+
+// SetFollowUp stores the follow-up. It returns gorm.ErrRecordNotFound when the contact
+// does not exist.
+func (s *followUpService) SetFollowUp(ctx context.Context, owner string, contactID uint, req *requests.FollowUpRequest) (*models.FollowUp, error) {
+	contact, err := s.contacts.FindByID(ctx, contactID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reschedule the existing follow-up, if any
+	followUp, err := s.followUps.FindByContact(ctx, owner, contactID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		followUp, err = &models.FollowUp{ContactID: contactID, Owner: owner}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	followUp.DueAt = req.DueAt.UTC()
+	followUp.Note = req.Note
+	if err := s.followUps.Save(ctx, followUp); err != nil {
+		return nil, err
+	}
+	followUp.Contact = contact
+	return followUp, nil
+}
+
+// ClearFollowUp removes the follow-up and returns it. It returns gorm.ErrRecordNotFound
+// when owner planned none for the contact.
+func (s *followUpService) ClearFollowUp(ctx context.Context, owner string, contactID uint) (*models.FollowUp, error) {
+	followUp, err := s.followUps.FindByContact(ctx, owner, contactID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.followUps.DeleteByContact(ctx, owner, contactID); err != nil {
+		return nil, err
+	}
+	return followUp, nil
+}
+
+// FeedToken returns the owner, encoded for URLs, followed by its signature.
+func (s *followUpService) FeedToken(owner string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(owner)) + "." + s.feedSignature(owner)
+}
+
+// FeedOwner verifies the signature of token and that its owner may still sign in.
+func (s *followUpService) FeedOwner(ctx context.Context, token string) (string, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrInvalidFeedToken
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidFeedToken
+	}
+	owner := string(decoded)
+	if !hmac.Equal([]byte(signature), []byte(s.feedSignature(owner))) {
+		return "", ErrInvalidFeedToken
+	}
+
+	// Stop publishing the follow-ups of disabled users and revoked keys
+	kind, value, _ := strings.Cut(owner, ":")
+	id, err := strconv.ParseUint(value, 10, 0)
+	switch {
+	case err != nil:
+		return owner, nil
+	case kind == "user":
+		user, err := s.users.FindByID(uint(id))
+		if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && user.DisabledAt != nil {
+			return "", ErrInvalidFeedToken
+		}
+		return owner, err
+	case kind == "key":
+		key, err := s.keys.FindByID(uint(id))
+		if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && (key.RevokedAt != nil || key.ExpiresAt != nil && key.ExpiresAt.Before(time.Now())) {
+			return "", ErrInvalidFeedToken
+		}
+		return owner, err
+	}
+	return owner, nil
+}
+
+// feedSignature returns the hex HMAC-SHA256 of the feed of owner.
+func (s *followUpService) feedSignature(owner string) string {
+	mac := hmac.New(sha256.New, s.feedSecret)
+	mac.Write([]byte("follow-up-feed:" + owner))
+	return hex.EncodeToString(mac.Sum(nil))
+}
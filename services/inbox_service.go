@@ -0,0 +1,132 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file defines the InboxService, which lists the admin inbox from its projection, and
+// the InboxProjection, which keeps the projection up to date with the lifecycle events of
+// contacts published by the ContactService, and broadcasts its changes to the inbox feed.
+package services
+
+import (
+	"api-contact-form/inboxfeed"
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"context"
+	"log"
+)
+
+// inboxRebuildBatchSize is the number of contacts read at once when the inbox is rebuilt.
+const inboxRebuildBatchSize = 500
+
+// InboxService defines the business logic interface for the admin inbox.
+type InboxService interface {
+	// ListInbox retrieves the inbox entries matching the filter, pinned contacts first, then
+	// the most recently active first.
+	ListInbox(ctx context.Context, filter repositories.InboxFilter) ([]models.InboxEntry, error)
+	// CountUnread returns the number of unread contacts in the inbox.
+	CountUnread(ctx context.Context) (int64, error)
+	// RebuildInbox rebuilds the inbox projection from the contacts and returns its number
+	// of entries.
+	RebuildInbox(ctx context.Context) (int64, error)
+	// RebuildInboxIfEmpty rebuilds the inbox projection when it has no entries, such as on
+	// the first start after the projection was added.
+	RebuildInboxIfEmpty(ctx context.Context) error
+}
+
+// inboxService is the concrete implementation of InboxService.
+type inboxService struct {
+	repository repositories.InboxRepository
+}
+
+// NewInboxService creates a new instance of InboxService with the provided InboxRepository.
+func NewInboxService(repository repositories.InboxRepository) InboxService {
+	return &inboxService{repository: repository}
+}
+
+// ListInbox retrieves the matching entries from the repository.
+func (s *inboxService) ListInbox(ctx context.Context, filter repositories.InboxFilter) ([]models.InboxEntry, error) {
+	return s.repository.FindPage(ctx, filter)
+}
+
+// CountUnread counts the unread entries of the repository.
+func (s *inboxService) CountUnread(ctx context.Context) (int64, error) {
+	return s.repository.CountUnread(ctx)
+}
+
+// RebuildInbox replaces the entries of the repository with those of the live contacts.
+func (s *inboxService) RebuildInbox(ctx context.Context) (int64, error) {
+	count, err := s.repository.Rebuild(ctx, inboxRebuildBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Inbox rebuilt with %d entries", count)
+	return count, nil
+}
+
+// RebuildInboxIfEmpty counts the entries of the repository and rebuilds them when there
+// are none.
+func (s *inboxService) RebuildInboxIfEmpty(ctx context.Context) error {
+	count, err := s.repository.Count(ctx)
+	if err != nil || count > 0 {
+		return err
+	}
+	_, err = s.RebuildInbox(ctx)
+	return err
+}
+
+// InboxProjection applies the lifecycle events of contacts to the inbox projection. A nil
+// InboxProjection does nothing.
+type InboxProjection struct {
+	repository repositories.InboxRepository
+	feed       *inboxfeed.Feed
+}
+
+// NewInboxProjection creates an InboxProjection storing the entries with repository and
+// publishing their changes to feed, which may be nil.
+func NewInboxProjection(repository repositories.InboxRepository, feed *inboxfeed.Feed) *InboxProjection {
+	return &InboxProjection{repository: repository, feed: feed}
+}
+
+// WithInboxProjection keeps projection up to date with the changes made to contacts
+// through the service.
+func WithInboxProjection(projection *InboxProjection) ContactServiceOption {
+	return func(s *contactService) {
+		s.inbox = projection
+	}
+}
+
+// Apply updates the entry of contact after event: contacts that were deleted leave the
+// inbox, the others enter it or have their entry replaced, and snoozed ones are published
+// as removed. The change is already made, so failures are only logged; RebuildInbox
+// repairs the projection. It runs without the request context, so that the projection is
+// updated even when the request was cancelled.
+// Successful changes are published to the feed with the number of unread contacts.
+func (p *InboxProjection) Apply(event models.WebhookEvent, contact *models.Contact) {
+	if p == nil {
+		return
+	}
+
+	var err error
+	entry := models.NewInboxEntry(contact)
+	removed := event == models.EventContactDeleted || contact.DeletedAt.Valid
+	if removed {
+		err = p.repository.Delete(context.Background(), contact.ID)
+	} else {
+		err = p.repository.Save(context.Background(), &entry)
+	}
+	if err != nil {
+		log.Printf("Inbox entry of contact %d not updated after %s: %v", contact.ID, event, err)
+		return
+	}
+
+	// Count the unread contacts only when somebody receives the update.
+	if !p.feed.Listening() {
+		return
+	}
+	unread, err := p.repository.CountUnread(context.Background())
+	if err != nil {
+		log.Printf("Inbox update of contact %d not published after %s: %v", contact.ID, event, err)
+		return
+	}
+	// Snoozed contacts keep their entry but leave the inbox shown by default.
+	removed = removed || entry.SnoozedUntil != nil
+	p.feed.Publish(inboxfeed.Update{Event: event, Entry: entry, Removed: removed, Unread: unread})
+}
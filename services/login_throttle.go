@@ -0,0 +1,135 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file implements the loginThrottle, which blocks the client IP addresses making too
+// many failed sign-in attempts, and the LoginPolicy configuring it along with the lockout
+// of the accounts.
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LoginPolicy configures the protection of the sign-in against brute-force attacks.
+// Zero limits disable the corresponding protection.
+type LoginPolicy struct {
+	// MaxFailures is the number of consecutive failed attempts after which an account is
+	// locked for LockoutDuration.
+	MaxFailures     int
+	LockoutDuration time.Duration
+	// MaxIPFailures is the number of failed attempts, on any account, after which a client
+	// IP address is blocked. Failures are counted over windows of IPWindow, and the address
+	// stays blocked for IPWindow.
+	MaxIPFailures int
+	IPWindow      time.Duration
+}
+
+// LoginClient describes the client making a sign-in attempt.
+type LoginClient struct {
+	// IP is the IP address of the client.
+	IP string
+	// UserAgent is the User-Agent header of the client.
+	UserAgent string
+}
+
+// LoginLockedError is returned when a sign-in attempt is refused without checking the
+// credentials, because of too many failed attempts on the account or from the client.
+type LoginLockedError struct {
+	// RetryAfter is the time until attempts are accepted again.
+	RetryAfter time.Duration
+	// Account reports whether the account is locked, rather than the client IP address blocked.
+	Account bool
+}
+
+// Error describes what is locked and for how long.
+func (e *LoginLockedError) Error() string {
+	retryAfter := e.RetryAfter.Round(time.Second)
+	if e.Account {
+		return fmt.Sprintf("account locked after too many failed sign-in attempts, retry in %s", retryAfter)
+	}
+	return fmt.Sprintf("too many failed sign-in attempts from this address, retry in %s", retryAfter)
+}
+
+// ipFailures counts the failed sign-in attempts of a client IP address.
+type ipFailures struct {
+	count        int
+	since        time.Time
+	blockedUntil time.Time
+}
+
+// loginThrottle counts the failed sign-in attempts of every client IP address in memory,
+// so that each instance of the API blocks the addresses it sees. It is safe for
+// concurrent use.
+type loginThrottle struct {
+	maxFailures int
+	window      time.Duration
+
+	mu        sync.Mutex
+	clients   map[string]*ipFailures
+	lastSweep time.Time
+}
+
+// newLoginThrottle creates a loginThrottle blocking an address for window once it failed
+// maxFailures times within window. A zero maxFailures never blocks.
+func newLoginThrottle(maxFailures int, window time.Duration) *loginThrottle {
+	return &loginThrottle{
+		maxFailures: maxFailures,
+		window:      window,
+		clients:     map[string]*ipFailures{},
+		lastSweep:   time.Now(),
+	}
+}
+
+// blocked returns the time until ip is unblocked, or zero when it is not blocked.
+func (t *loginThrottle) blocked(ip string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if f, ok := t.clients[ip]; ok && now.Before(f.blockedUntil) {
+		return f.blockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// fail counts a failed attempt of ip and reports whether it blocked the address.
+func (t *loginThrottle) fail(ip string, now time.Time) bool {
+	if t.maxFailures <= 0 {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sweep(now)
+
+	// Start a new window once the previous one is over.
+	f, ok := t.clients[ip]
+	if !ok || now.Sub(f.since) >= t.window {
+		f = &ipFailures{since: now}
+		t.clients[ip] = f
+	}
+
+	f.count++
+	if f.count < t.maxFailures {
+		return false
+	}
+	f.count = 0
+	f.since = now
+	f.blockedUntil = now.Add(t.window)
+	return true
+}
+
+// sweep forgets the addresses whose window and block are over, at most once a minute,
+// so that the memory used stays proportional to the recently failing clients.
+func (t *loginThrottle) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < time.Minute {
+		return
+	}
+	t.lastSweep = now
+
+	for ip, f := range t.clients {
+		if now.Sub(f.since) >= t.window && !now.Before(f.blockedUntil) {
+			delete(t.clients, ip)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file implements the PasswordPolicy enforced on the passwords chosen by admin users,
+// and PwnedPasswords, which checks them against the breached passwords known to the
+// HaveIBeenPwned Pwned Passwords service.
+package services
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pwnedPasswordsURL is the range endpoint of the Pwned Passwords service.
+const pwnedPasswordsURL = "https://api.pwnedpasswords.com/range/"
+
+// BreachChecker reports whether a password is known to have been exposed in a data breach.
+type BreachChecker interface {
+	Breached(password string) (bool, error)
+}
+
+// PasswordPolicy configures the requirements on the passwords chosen by admin users.
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters of a password.
+	MinLength int
+	// RequireUpper, RequireLower, RequireDigit and RequireSymbol require at least one
+	// character of each class.
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Breaches rejects the passwords it reports as breached. Nil disables the check.
+	Breaches BreachChecker
+}
+
+// check returns a ValidationError on the password field listing the requirements the
+// password misses. When the breach check itself fails, the password is accepted, so that
+// an outage of the service does not prevent admin users from setting passwords.
+func (p PasswordPolicy) check(password string) error {
+	var fields []FieldError
+	add := func(message string) {
+		fields = append(fields, FieldError{Field: "password", Message: message})
+	}
+
+	// Check the length and the character classes.
+	if utf8.RuneCountInString(password) < p.MinLength {
+		add(fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.RequireUpper && !strings.ContainsFunc(password, unicode.IsUpper) {
+		add("must contain an uppercase letter")
+	}
+	if p.RequireLower && !strings.ContainsFunc(password, unicode.IsLower) {
+		add("must contain a lowercase letter")
+	}
+	if p.RequireDigit && !strings.ContainsFunc(password, unicode.IsDigit) {
+		add("must contain a digit")
+	}
+	if p.RequireSymbol && !strings.ContainsFunc(password, isSymbol) {
+		add("must contain a symbol")
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+
+	// Only look up passwords that otherwise comply.
+	if p.Breaches != nil {
+		breached, err := p.Breaches.Breached(password)
+		if err != nil {
+			log.Printf("Failed to check the password against known breaches: %v", err)
+		} else if breached {
+			add("appears in a known data breach, choose another one")
+			return &ValidationError{Fields: fields}
+		}
+	}
+	return nil
+}
+
+// isSymbol reports whether r is neither a letter, a digit nor a space.
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
+
+// PwnedPasswords checks passwords with the k-anonymity range API of Pwned Passwords: only
+// the first five characters of the SHA-1 hash of a password are sent, and the returned
+// hash suffixes are compared locally.
+type PwnedPasswords struct {
+	url    string
+	client *http.Client
+}
+
+// NewPwnedPasswords creates a PwnedPasswords using the public Pwned Passwords service.
+func NewPwnedPasswords() *PwnedPasswords {
+	return &PwnedPasswords{
+		url:    pwnedPasswordsURL,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Breached looks up the hash suffixes sharing the prefix of the password hash. Responses
+// are padded with fake suffixes, which have a count of 0 and are ignored.
+func (p *PwnedPasswords) Breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequest(http.MethodGet, p.url+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Add-Padding", "true")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("pwned passwords returned status %d", resp.StatusCode)
+	}
+
+	// Each line is a hash suffix and the number of times it was seen in breaches.
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if candidate == suffix {
+			return count != "0", nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,111 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file defines the ReplyDraftService, which saves the drafts of the replies agents
+// write to contacts. While a draft is in progress, teammates cannot overwrite or discard
+// it, so that a contact does not get two replies written at the same time.
+package services
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/requests"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrReplyDraftInProgress is returned when a teammate's draft of a reply to the contact
+// is in progress.
+var ErrReplyDraftInProgress = errors.New("a reply to this contact is being drafted by a teammate")
+
+// ReplyDraftService defines the business logic interface for reply drafts.
+type ReplyDraftService interface {
+	// GetDraft retrieves the draft of the reply to a contact identified by its ID.
+	GetDraft(ctx context.Context, contactID uint) (*models.ReplyDraft, error)
+	// SaveDraft creates or updates the draft of the reply to a contact identified by its
+	// ID, on behalf of actor.
+	SaveDraft(ctx context.Context, actor string, contactID uint, req *requests.ReplyDraftRequest, force bool) (*models.ReplyDraft, error)
+	// DiscardDraft removes the draft of the reply to a contact identified by its ID, on
+	// behalf of actor.
+	DiscardDraft(ctx context.Context, actor string, contactID uint, force bool) (*models.ReplyDraft, error)
+}
+
+// replyDraftService is the concrete implementation of ReplyDraftService.
+type replyDraftService struct {
+	drafts   repositories.ReplyDraftRepository
+	contacts repositories.ContactRepository
+	lockFor  time.Duration
+}
+
+// NewReplyDraftService creates a new instance of ReplyDraftService storing the drafts of
+// the contacts of contacts in drafts. A draft saved less than lockFor ago is in progress:
+// only its author may change or discard it, unless forced.
+func NewReplyDraftService(drafts repositories.ReplyDraftRepository, contacts repositories.ContactRepository, lockFor time.Duration) ReplyDraftService {
+	return &replyDraftService{drafts: drafts, contacts: contacts, lockFor: lockFor}
+}
+
+// GetDraft retrieves the draft from the repository. It returns gorm.ErrRecordNotFound when
+// the contact does not exist or has no draft.
+func (s *replyDraftService) GetDraft(ctx context.Context, contactID uint) (*models.ReplyDraft, error) {
+	if _, err := s.contacts.FindByID(ctx, contactID); err != nil {
+		return nil, err
+	}
+	return s.drafts.FindByContact(ctx, contactID)
+}
+
+// SaveDraft stores the draft as written by actor. It returns gorm.ErrRecordNotFound when
+// the contact does not exist, and the draft of a teammate together with
+// ErrReplyDraftInProgress when that draft is in progress and force is not set.
+func (s *replyDraftService) SaveDraft(ctx context.Context, actor string, contactID uint, req *requests.ReplyDraftRequest, force bool) (*models.ReplyDraft, error) {
+	if _, err := s.contacts.FindByID(ctx, contactID); err != nil {
+		return nil, err
+	}
+
+	// Resume the existing draft, unless a teammate is working on it
+	draft, err := s.drafts.FindByContact(ctx, contactID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		draft = &models.ReplyDraft{ContactID: contactID}
+	case err != nil:
+		return nil, err
+	case !force && s.inProgress(draft, actor):
+		return draft, ErrReplyDraftInProgress
+	}
+
+	draft.Body = req.Body
+	draft.Author = actor
+	if err := s.drafts.Save(ctx, draft); err != nil {
+		// A teammate created the draft since it was looked up
+		if errors.Is(err, repositories.ErrUniqueViolation) {
+			if existing, findErr := s.drafts.FindByContact(ctx, contactID); findErr == nil {
+				return existing, ErrReplyDraftInProgress
+			}
+		}
+		return nil, err
+	}
+	return draft, nil
+}
+
+// DiscardDraft removes the draft and returns it. It returns gorm.ErrRecordNotFound when
+// the contact has no draft, and the draft of a teammate together with
+// ErrReplyDraftInProgress when that draft is in progress and force is not set.
+func (s *replyDraftService) DiscardDraft(ctx context.Context, actor string, contactID uint, force bool) (*models.ReplyDraft, error) {
+	draft, err := s.drafts.FindByContact(ctx, contactID)
+	if err != nil {
+		return nil, err
+	}
+	if !force && s.inProgress(draft, actor) {
+		return draft, ErrReplyDraftInProgress
+	}
+	if err := s.drafts.DeleteByContact(ctx, contactID); err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// inProgress reports whether draft is the recent work of another actor than actor.
+func (s *replyDraftService) inProgress(draft *models.ReplyDraft, actor string) bool {
+	return draft.Author != actor && time.Since(draft.UpdatedAt) < s.lockFor
+}
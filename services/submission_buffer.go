@@ -0,0 +1,147 @@
+package services
+
+import (
+	"api-contact-form/observability"
+	"api-contact-form/requests"
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+/*
+This file implements the SubmissionBuffer, the optional write-behind buffer of POST
+/contacts. Submissions that cannot be stored because the database is briefly unavailable
+are kept in a bounded in-memory queue and stored once it is back, so that submitters are
+told their submission was received instead of getting an error.
+*/
+
+// bufferPingTimeout bounds the database pings telling an outage from other failures.
+const bufferPingTimeout = 2 * time.Second
+
+// bufferedSubmission is a submission waiting for the database.
+type bufferedSubmission struct {
+	req  requests.ContactRequest
+	meta requests.SubmissionMeta
+}
+
+// SubmissionBuffer queues the submissions received during database outages and stores
+// them in order once the database answers again. Queued submissions are lost when the
+// process stops before they are stored. A nil SubmissionBuffer buffers nothing.
+type SubmissionBuffer struct {
+	service  ContactService
+	ping     func(ctx context.Context) error
+	size     int
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending []bufferedSubmission
+	done    chan struct{}
+}
+
+// NewSubmissionBuffer creates a SubmissionBuffer keeping at most size submissions, storing
+// them through service, and checking whether the database is back with ping every interval.
+func NewSubmissionBuffer(service ContactService, ping func(ctx context.Context) error, size int, interval time.Duration) *SubmissionBuffer {
+	return &SubmissionBuffer{service: service, ping: ping, size: size, interval: interval, done: make(chan struct{})}
+}
+
+// Buffer queues a submission whose creation failed with err, and reports whether it did.
+// Submissions are only queued when the database does not answer a ping, so that other
+// failures are still reported, and when they carry no attachments, as the uploaded files
+// do not outlive the request. When the queue is full, the submission is not queued.
+func (b *SubmissionBuffer) Buffer(ctx context.Context, req *requests.ContactRequest, meta requests.SubmissionMeta, err error) bool {
+	if b == nil || err == nil || len(req.Attachments) > 0 || b.available(ctx) {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending) >= b.size {
+		log.Printf("Submission not buffered, the buffer of %d submissions is full: %v", b.size, err)
+		return false
+	}
+	if meta.ReceivedAt.IsZero() {
+		meta.ReceivedAt = time.Now()
+	}
+	b.pending = append(b.pending, bufferedSubmission{req: *req, meta: meta})
+	observability.BufferedSubmissions.Set(float64(len(b.pending)))
+	log.Printf("Submission buffered while the database is unavailable (%d waiting): %v", len(b.pending), err)
+	return true
+}
+
+// Run stores the queued submissions every interval while the database answers, until ctx
+// is cancelled. It then makes a last attempt and reports the submissions left unstored.
+func (b *SubmissionBuffer) Run(ctx context.Context) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.Flush(context.Background())
+			if left := b.Len(); left > 0 {
+				log.Printf("%d buffered submissions were lost at shutdown", left)
+			}
+			return
+		case <-ticker.C:
+			b.Flush(ctx)
+		}
+	}
+}
+
+// Wait blocks until Run returned after its last attempt.
+func (b *SubmissionBuffer) Wait() {
+	<-b.done
+}
+
+// Flush stores the queued submissions in the order they were received, stopping at the
+// first one that cannot be stored because the database is unavailable again. Submissions
+// failing for another reason, such as a duplicate that arrived meanwhile, are dropped.
+func (b *SubmissionBuffer) Flush(ctx context.Context) {
+	if b.Len() == 0 || !b.available(ctx) {
+		return
+	}
+
+	for {
+		b.mu.Lock()
+		if len(b.pending) == 0 {
+			b.mu.Unlock()
+			return
+		}
+		next := b.pending[0]
+		b.mu.Unlock()
+
+		_, err := b.service.CreateContact(ctx, &next.req, next.meta)
+		if err != nil && !b.available(ctx) {
+			log.Printf("Buffered submissions not stored, the database is unavailable: %v", err)
+			return
+		}
+		if err != nil {
+			log.Printf("Buffered submission of %s dropped: %v", next.meta.ReceivedAt.Format(time.RFC3339), err)
+		}
+
+		b.mu.Lock()
+		b.pending = b.pending[1:]
+		observability.BufferedSubmissions.Set(float64(len(b.pending)))
+		b.mu.Unlock()
+	}
+}
+
+// Len returns the number of queued submissions.
+func (b *SubmissionBuffer) Len() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+// available reports whether the database answers a ping.
+func (b *SubmissionBuffer) available(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, bufferPingTimeout)
+	defer cancel()
+	return b.ping(ctx) == nil
+}
@@ -0,0 +1,85 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file implements the time-based one-time passwords (TOTP, RFC 6238) of the two-factor
+// authentication of admin users, with the parameters every authenticator app supports:
+// HMAC-SHA1, 6 digits and 30-second steps.
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// totpStep is the validity period of a code.
+	totpStep = 30 * time.Second
+	// totpDigits is the number of digits of a code.
+	totpDigits = 6
+	// totpSkew is the number of steps a code may be early or late, for clock drift.
+	totpSkew = 1
+)
+
+// totpEncoding encodes the secrets as authenticator apps expect them.
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newTOTPSecret returns a random 160-bit secret, base32-encoded.
+func newTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// totpURL returns the otpauth:// URL of secret, which authenticator apps read from a QR code.
+func totpURL(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpStep.Seconds())))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + query.Encode()
+}
+
+// verifyTOTP checks code against secret at now, allowing totpSkew steps of drift. Codes
+// of steps up to lastStep were used before and are rejected. It returns the step of the
+// code when it is valid.
+func verifyTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / int64(totpStep.Seconds())
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode computes the code of key for a time step.
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
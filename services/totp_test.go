@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 secret of the test vectors of RFC 6238, base32-encoded.
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// The vectors have 8 digits; the codes are their last 6.
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		if got := TOTPCode(rfc6238Secret, time.Unix(unix, 0)); got != want {
+			t.Errorf("code at %d = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := now.Unix() / 30
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		ok       bool
+	}{
+		{"current code", "081804", 0, step, true},
+		{"code of the previous step", TOTPCode(rfc6238Secret, now.Add(-30*time.Second)), 0, step - 1, true},
+		{"code of the next step", TOTPCode(rfc6238Secret, now.Add(30*time.Second)), 0, step + 1, true},
+		{"code two steps late", TOTPCode(rfc6238Secret, now.Add(-60*time.Second)), 0, 0, false},
+		{"replayed code", "081804", step, 0, false},
+		{"code before the last step used", TOTPCode(rfc6238Secret, now.Add(-30*time.Second)), step, 0, false},
+		{"wrong code", "000000", 0, 0, false},
+		{"code of the wrong length", "81804", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := verifyTOTP(rfc6238Secret, tt.code, now, tt.lastStep)
+			if ok != tt.ok || gotStep != tt.wantStep {
+				t.Errorf("verifyTOTP(%s) = %d, %v, want %d, %v", tt.code, gotStep, ok, tt.wantStep, tt.ok)
+			}
+		})
+	}
+}
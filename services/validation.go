@@ -0,0 +1,124 @@
+// Package services provides business logic implementations for contact-related operations
+// in the API Contact Form application.
+//
+// This file implements the normalization and validation of incoming requests, and the
+// field-level ValidationError returned when a request is rejected.
+package services
+
+import (
+	"api-contact-form/requests"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes a request field that failed validation.
+type FieldError struct {
+	// Field is the JSON name of the offending field.
+	Field string `json:"field"`
+	// Message explains why the value was rejected.
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when a request fails validation.
+type ValidationError struct {
+	// Fields lists every field that failed validation.
+	Fields []FieldError
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Field+" "+f.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// newValidator creates a validator that reports fields by their JSON names.
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+	return validate
+}
+
+// validateStruct validates a request and converts validation failures into a *ValidationError.
+func (s *contactService) validateStruct(req interface{}) error {
+	err := s.validate.Struct(req)
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+
+	validationErr := &ValidationError{}
+	for _, fieldErr := range fieldErrs {
+		validationErr.Fields = append(validationErr.Fields, FieldError{
+			Field:   fieldErr.Field(),
+			Message: fieldErrorMessage(fieldErr),
+		})
+	}
+	return validationErr
+}
+
+// fieldErrorMessage returns a human-readable explanation of a failed validation tag.
+func fieldErrorMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "e164":
+		return "must be a phone number in international E.164 format, e.g. +6281234567890"
+	case "bcp47_language_tag":
+		return "must be a language code, e.g. en or pt-BR"
+	case "max":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s bytes long", fieldErr.Param())
+	default:
+		return fmt.Sprintf("failed the %s validation", fieldErr.Tag())
+	}
+}
+
+// phoneFormatting holds the characters commonly used to format phone numbers,
+// removed when normalizing them.
+var phoneFormatting = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// normalizeContactRequest trims the fields of a ContactRequest, strips the formatting
+// characters of the phone number and lowercases the domain of the email address.
+func normalizeContactRequest(req *requests.ContactRequest) {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Email = normalizeEmail(req.Email)
+	req.Phone = phoneFormatting.Replace(strings.TrimSpace(req.Phone))
+	req.Message = strings.TrimSpace(req.Message)
+	req.ConsentVersion = strings.TrimSpace(req.ConsentVersion)
+	req.Lang = NormalizeLanguage(req.Lang)
+}
+
+// normalizeEmail trims an email address and lowercases its domain, which is case-insensitive.
+// The local part is kept as is, since mail servers may treat it case-sensitively.
+func normalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	return email[:at+1] + strings.ToLower(email[at+1:])
+}
+
+// NormalizeLanguage trims and lowercases a language code, which is case-insensitive, and
+// separates its subtags with dashes, as in "pt-br" for "pt_BR".
+func NormalizeLanguage(language string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(language)), "_", "-")
+}
@@ -0,0 +1,166 @@
+// Package services provides business logic implementations for the API Contact Form application.
+//
+// This file defines the WebhookService, which manages the webhook subscriptions that
+// receive contact lifecycle events, exposes their delivery log and replays their events.
+package services
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/requests"
+	"api-contact-form/webhooks"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// ErrInvalidWebhookURL is returned when a subscription URL is not an absolute http or https URL.
+var ErrInvalidWebhookURL = errors.New("url must be an absolute http or https URL")
+
+// ErrInvalidWebhookEvent is returned when a subscription lists an unknown event.
+var ErrInvalidWebhookEvent = errors.New("invalid webhook event")
+
+// ErrReplayNotFound is returned when a subscription has no replay to report or cancel.
+var ErrReplayNotFound = errors.New("replay not found")
+
+// WebhookService defines the business logic interface for webhook subscriptions.
+type WebhookService interface {
+	// ListSubscriptions retrieves every subscription.
+	ListSubscriptions() ([]models.WebhookSubscription, error)
+	// CreateSubscription registers a new subscription with a generated signing secret.
+	CreateSubscription(req *requests.WebhookRequest) (*models.WebhookSubscription, error)
+	// UpdateSubscription changes the URL, events or state of a subscription identified by its ID.
+	UpdateSubscription(id uint, req *requests.WebhookRequest) (*models.WebhookSubscription, error)
+	// DeleteSubscription removes a subscription identified by its ID and its delivery log.
+	DeleteSubscription(id uint) error
+	// ListDeliveries retrieves the latest delivery attempts of a subscription identified by its ID.
+	ListDeliveries(id uint, limit int) ([]models.WebhookDelivery, error)
+	// ReplayEvents queues the replay of the events that occurred within [from, to) to a
+	// subscription identified by its ID.
+	ReplayEvents(id uint, from, to time.Time) (webhooks.ReplayProgress, error)
+	// GetReplay retrieves the progress of the last replay of a subscription identified by its ID.
+	GetReplay(id uint) (webhooks.ReplayProgress, error)
+	// CancelReplay stops the replay in progress of a subscription identified by its ID.
+	CancelReplay(id uint) (webhooks.ReplayProgress, error)
+}
+
+// webhookService is the concrete implementation of WebhookService.
+type webhookService struct {
+	repository repositories.WebhookRepository
+	replayer   *webhooks.Replayer
+}
+
+// NewWebhookService creates a new instance of WebhookService with the provided WebhookRepository,
+// replaying events with replayer.
+func NewWebhookService(repository repositories.WebhookRepository, replayer *webhooks.Replayer) WebhookService {
+	return &webhookService{repository: repository, replayer: replayer}
+}
+
+// ListSubscriptions retrieves every subscription from the repository.
+func (s *webhookService) ListSubscriptions() ([]models.WebhookSubscription, error) {
+	return s.repository.FindSubscriptions()
+}
+
+// CreateSubscription validates the request, generates the signing secret and stores the subscription.
+func (s *webhookService) CreateSubscription(req *requests.WebhookRequest) (*models.WebhookSubscription, error) {
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := &models.WebhookSubscription{Secret: secret, Active: true}
+	if err := applyWebhookRequest(subscription, req); err != nil {
+		return nil, err
+	}
+	if err := s.repository.CreateSubscription(subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// UpdateSubscription validates the request and applies it to the stored subscription.
+// The signing secret is kept.
+func (s *webhookService) UpdateSubscription(id uint, req *requests.WebhookRequest) (*models.WebhookSubscription, error) {
+	subscription, err := s.repository.FindSubscriptionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyWebhookRequest(subscription, req); err != nil {
+		return nil, err
+	}
+	if err := s.repository.UpdateSubscription(subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// DeleteSubscription removes the subscription from the repository and stops its replay.
+func (s *webhookService) DeleteSubscription(id uint) error {
+	if err := s.repository.DeleteSubscription(id); err != nil {
+		return err
+	}
+	s.replayer.Forget(id)
+	return nil
+}
+
+// ListDeliveries checks that the subscription exists and retrieves its delivery log.
+func (s *webhookService) ListDeliveries(id uint, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.repository.FindSubscriptionByID(id); err != nil {
+		return nil, err
+	}
+	return s.repository.FindDeliveries(id, limit)
+}
+
+// ReplayEvents checks that the subscription exists and queues the replay of its events.
+func (s *webhookService) ReplayEvents(id uint, from, to time.Time) (webhooks.ReplayProgress, error) {
+	subscription, err := s.repository.FindSubscriptionByID(id)
+	if err != nil {
+		return webhooks.ReplayProgress{}, err
+	}
+	return s.replayer.Start(*subscription, from, to)
+}
+
+// GetReplay checks that the subscription exists and returns the progress of its last replay.
+func (s *webhookService) GetReplay(id uint) (webhooks.ReplayProgress, error) {
+	if _, err := s.repository.FindSubscriptionByID(id); err != nil {
+		return webhooks.ReplayProgress{}, err
+	}
+	progress, ok := s.replayer.Progress(id)
+	if !ok {
+		return webhooks.ReplayProgress{}, ErrReplayNotFound
+	}
+	return progress, nil
+}
+
+// CancelReplay checks that the subscription exists and stops its replay in progress.
+func (s *webhookService) CancelReplay(id uint) (webhooks.ReplayProgress, error) {
+	if _, err := s.repository.FindSubscriptionByID(id); err != nil {
+		return webhooks.ReplayProgress{}, err
+	}
+	progress, ok := s.replayer.Cancel(id)
+	if !ok {
+		return webhooks.ReplayProgress{}, ErrReplayNotFound
+	}
+	return progress, nil
+}
+
+// applyWebhookRequest validates req and copies it into subscription.
+func applyWebhookRequest(subscription *models.WebhookSubscription, req *requests.WebhookRequest) error {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	for _, event := range req.Events {
+		if !event.Valid() {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhookEvent, event)
+		}
+	}
+
+	subscription.URL = req.URL
+	subscription.SetEventList(req.Events)
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// Package settings exports and imports the instance-level settings as a YAML bundle, so
+// that a configuration tried on staging can be promoted to production as is.
+//
+// A bundle holds the custom validation rules and the webhook subscriptions. Everything
+// else is configured with environment variables, which differ between instances by
+// design, and contacts are moved with package backup instead. Webhook signing secrets
+// are only exported on request; subscriptions imported without one keep their current
+// secret, or get a new one when they are created.
+package settings
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/requests"
+	"api-contact-form/rules"
+	"api-contact-form/services"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"gorm.io/gorm"
+)
+
+// formatVersion is bumped whenever the bundle layout changes incompatibly.
+const formatVersion = 1
+
+// ErrInvalidBundle is returned for bundles that cannot be parsed or hold invalid settings.
+var ErrInvalidBundle = errors.New("invalid settings bundle")
+
+// ErrRulesNotConfigured is returned when importing a bundle with rules into an instance
+// without a rules file.
+var ErrRulesNotConfigured = errors.New("the bundle has validation rules but RULES_FILE is not configured")
+
+// Bundle is the exported settings of an instance.
+type Bundle struct {
+	// Version is the format version of the bundle.
+	Version int `yaml:"version"`
+	// ExportedAt is the time the bundle was exported.
+	ExportedAt time.Time `yaml:"exported_at"`
+	// Rules are the custom validation rules, or nil when the instance has none.
+	Rules *rules.Rules `yaml:"rules,omitempty"`
+	// Webhooks are the webhook subscriptions.
+	Webhooks []Webhook `yaml:"webhooks"`
+}
+
+// Webhook is a webhook subscription of a Bundle, identified by its URL.
+type Webhook struct {
+	URL string `yaml:"url"`
+	// Events lists the events delivered to the URL; empty means all events.
+	Events []models.WebhookEvent `yaml:"events,omitempty"`
+	Active bool                  `yaml:"active"`
+	// Secret is the signing secret, only exported on request.
+	Secret string `yaml:"secret,omitempty"`
+}
+
+// ImportResult summarizes the changes made by Import.
+type ImportResult struct {
+	// RulesReplaced reports whether the validation rules were replaced.
+	RulesReplaced bool `json:"rules_replaced"`
+	// WebhooksCreated is the number of subscriptions created for new URLs.
+	WebhooksCreated int `json:"webhooks_created"`
+	// WebhooksUpdated is the number of existing subscriptions updated.
+	WebhooksUpdated int `json:"webhooks_updated"`
+}
+
+// Export collects the settings of the instance: the current validation rules, which may
+// be nil, and the webhook subscriptions stored in db, with their secrets when
+// includeSecrets is set.
+func Export(db *gorm.DB, current *rules.Rules, includeSecrets bool) (*Bundle, error) {
+	subscriptions, err := repositories.NewWebhookRepository(db).FindSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{
+		Version:    formatVersion,
+		ExportedAt: time.Now().UTC(),
+		Rules:      current,
+		Webhooks:   make([]Webhook, 0, len(subscriptions)),
+	}
+	for i := range subscriptions {
+		webhook := Webhook{
+			URL:    subscriptions[i].URL,
+			Events: subscriptions[i].EventList(),
+			Active: subscriptions[i].Active,
+		}
+		if includeSecrets {
+			webhook.Secret = subscriptions[i].Secret
+		}
+		bundle.Webhooks = append(bundle.Webhooks, webhook)
+	}
+	return bundle, nil
+}
+
+// Import applies a bundle to the instance. Webhook subscriptions are matched by URL:
+// existing ones are updated and missing ones created, in a single transaction, while
+// subscriptions absent from the bundle are kept. The validation rules are then written
+// with saveRules, which is nil when the instance has no rules file.
+//
+// The rules are compiled before anything is changed, so that an invalid bundle is
+// rejected as a whole.
+func Import(db *gorm.DB, bundle *Bundle, saveRules func(*rules.Rules) error) (*ImportResult, error) {
+	if bundle.Rules != nil {
+		if saveRules == nil {
+			return nil, ErrRulesNotConfigured
+		}
+		if err := bundle.Rules.Compile(); err != nil {
+			return nil, fmt.Errorf("%w: rules: %v", ErrInvalidBundle, err)
+		}
+	}
+
+	result := &ImportResult{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		repository := repositories.NewWebhookRepository(tx)
+		existing, err := repository.FindSubscriptions()
+		if err != nil {
+			return err
+		}
+		byURL := make(map[string]uint, len(existing))
+		for _, subscription := range existing {
+			byURL[subscription.URL] = subscription.ID
+		}
+
+		// Create or update the subscriptions with the validation of the API; the import
+		// never replays events, so that no replayer is needed.
+		service := services.NewWebhookService(repository, nil)
+		for _, webhook := range bundle.Webhooks {
+			req := &requests.WebhookRequest{URL: webhook.URL, Events: webhook.Events, Active: &webhook.Active}
+			id, exists := byURL[webhook.URL]
+			var subscription *models.WebhookSubscription
+			if exists {
+				subscription, err = service.UpdateSubscription(id, req)
+			} else {
+				subscription, err = service.CreateSubscription(req)
+			}
+			if errors.Is(err, services.ErrInvalidWebhookURL) || errors.Is(err, services.ErrInvalidWebhookEvent) {
+				return fmt.Errorf("%w: webhook %s: %v", ErrInvalidBundle, webhook.URL, err)
+			}
+			if err != nil {
+				return err
+			}
+			if exists {
+				result.WebhooksUpdated++
+			} else {
+				result.WebhooksCreated++
+				byURL[webhook.URL] = subscription.ID
+			}
+
+			if webhook.Secret != "" && webhook.Secret != subscription.Secret {
+				subscription.Secret = webhook.Secret
+				if err := repository.UpdateSubscription(subscription); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if bundle.Rules != nil {
+		if err := saveRules(bundle.Rules); err != nil {
+			return nil, err
+		}
+		result.RulesReplaced = true
+	}
+	return result, nil
+}
+
+// Encode writes bundle to w as YAML.
+func Encode(w io.Writer, bundle *Bundle) error {
+	return yaml.NewEncoder(w).Encode(bundle)
+}
+
+// Decode reads a YAML bundle from r and checks its format version. Errors reading r are
+// wrapped together with ErrInvalidBundle.
+func Decode(r io.Reader) (*Bundle, error) {
+	var bundle Bundle
+	if err := yaml.NewDecoder(r).Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+	}
+	if bundle.Version != formatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d, expected %d", ErrInvalidBundle, bundle.Version, formatVersion)
+	}
+	return &bundle, nil
+}
@@ -0,0 +1,121 @@
+// Package startup tracks the initialization of the server, so that it can answer the
+// probes of the orchestrator while it is still starting.
+//
+// The server listens as soon as the process starts, with a Tracker as its handler. Until
+// every step of the initialization is done, the Tracker answers the liveness probe with
+// success, so that slow migrations do not get the replica restarted, and the startup and
+// readiness probes and every other request with a 503 status code listing the pending
+// steps, so that rolling deploys never route traffic to a half-initialized replica. Once
+// the application handler is served, every request goes to it.
+package startup
+
+import (
+	"api-contact-form/responses"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status is the progress of the initialization.
+type Status struct {
+	// Pending lists the steps not done yet, in order.
+	Pending []string `json:"pending"`
+	// Completed lists the steps done, in order.
+	Completed []string `json:"completed"`
+}
+
+// Tracker tracks the steps of the initialization and serves the requests received
+// meanwhile. It is safe for concurrent use.
+type Tracker struct {
+	started time.Time
+
+	mu    sync.Mutex
+	steps []string
+	done  map[string]bool
+
+	handler atomic.Pointer[http.Handler]
+}
+
+// NewTracker creates a Tracker waiting for the given steps, such as "migrations".
+func NewTracker(steps ...string) *Tracker {
+	return &Tracker{started: time.Now(), steps: steps, done: make(map[string]bool)}
+}
+
+// Done records that a step is done.
+func (t *Tracker) Done(step string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !slices.Contains(t.steps, step) {
+		log.Printf("Unknown startup step %q", step)
+		return
+	}
+	t.done[step] = true
+	log.Printf("Startup step %s done after %s", step, time.Since(t.started).Round(time.Millisecond))
+}
+
+// Status returns the progress of the initialization.
+func (t *Tracker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := Status{Pending: []string{}, Completed: []string{}}
+	for _, step := range t.steps {
+		if t.done[step] {
+			status.Completed = append(status.Completed, step)
+		} else {
+			status.Pending = append(status.Pending, step)
+		}
+	}
+	return status
+}
+
+// Started reports whether every step is done and the application handler is served.
+func (t *Tracker) Started() bool {
+	return t.handler.Load() != nil
+}
+
+// Serve sends every request to handler from now on. Steps that are still pending are
+// logged, as the application is then served regardless.
+func (t *Tracker) Serve(handler http.Handler) {
+	if pending := t.Status().Pending; len(pending) > 0 {
+		log.Printf("Serving before the startup steps %v are done", pending)
+	}
+	t.handler.Store(&handler)
+	log.Printf("Started after %s", time.Since(t.started).Round(time.Millisecond))
+}
+
+// ServeHTTP sends the request to the application handler once served, and answers the
+// probes meanwhile.
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler := t.handler.Load(); handler != nil {
+		(*handler).ServeHTTP(w, r)
+		return
+	}
+
+	if r.URL.Path == "/healthz" {
+		writeJSON(w, http.StatusOK, responses.APIResponse{
+			Code:    "SUCCESS",
+			Message: "API is alive.",
+			Data:    nil,
+		})
+		return
+	}
+	w.Header().Set("Retry-After", "1")
+	writeJSON(w, http.StatusServiceUnavailable, responses.APIResponse{
+		Code:    "SERVICE_UNAVAILABLE",
+		Message: "API is starting.",
+		Data:    t.Status(),
+	})
+}
+
+// writeJSON writes body as the JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
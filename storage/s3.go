@@ -0,0 +1,88 @@
+// Package storage stores the files attached to contact submissions.
+//
+// This file implements S3Storage, which keeps files in a bucket of an S3-compatible
+// object store such as AWS S3, MinIO or Cloudflare R2.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config holds the connection settings of an S3-compatible bucket.
+type S3Config struct {
+	// Endpoint is the host, and optional port, of the object store, e.g. "s3.amazonaws.com".
+	Endpoint string
+	// Region is the region of the bucket; it may be empty for stores without regions.
+	Region string
+	// Bucket is the name of the bucket, which must exist.
+	Bucket string
+	// AccessKey and SecretKey authenticate with the object store.
+	AccessKey string
+	SecretKey string
+	// UseSSL selects HTTPS.
+	UseSSL bool
+}
+
+// S3Storage stores files as objects of an S3-compatible bucket.
+type S3Storage struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Storage creates an S3Storage for the bucket of cfg.
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Storage{client: client, bucket: cfg.Bucket}, nil
+}
+
+// Put uploads the file as an object.
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+// Open downloads the object stored under key. The object is checked to exist first, as
+// the reader returned by the client only reports errors once it is read.
+func (s *S3Storage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return object, nil
+}
+
+// Delete removes the object stored under key.
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// Check verifies that the credentials are accepted and that the bucket exists.
+func (s *S3Storage) Check(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("bucket %q does not exist", s.bucket)
+	}
+	return nil
+}
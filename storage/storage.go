@@ -0,0 +1,50 @@
+// Package storage stores the files attached to contact submissions.
+//
+// Storage is implemented by LocalStorage, which keeps files in a directory on disk, and
+// S3Storage, which keeps them in a bucket of any S3-compatible object store. Files are
+// addressed by opaque keys generated by the caller.
+package storage
+
+import (
+	"api-contact-form/config"
+	"api-contact-form/helpers"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNotFound is returned by Open when no file is stored under a key.
+var ErrNotFound = errors.New("stored file not found")
+
+// Storage stores files under keys.
+type Storage interface {
+	// Put stores size bytes read from r under key.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns a reader of the file stored under key, which the caller must close.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the file stored under key. Deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+	// Check verifies that files can be stored, without storing any.
+	Check(ctx context.Context) error
+}
+
+// NewFromEnv creates the Storage selected by STORAGE_DRIVER: a local directory, or an
+// S3-compatible bucket.
+func NewFromEnv() (Storage, error) {
+	switch driver := config.GetEnv("STORAGE_DRIVER", "local"); driver {
+	case "local":
+		return NewLocalStorage(config.GetEnv("STORAGE_LOCAL_DIR", "uploads"))
+	case "s3":
+		return NewS3Storage(S3Config{
+			Endpoint:  config.GetEnv("S3_ENDPOINT", "s3.amazonaws.com"),
+			Region:    config.GetEnv("S3_REGION", ""),
+			Bucket:    config.GetEnv("S3_BUCKET", ""),
+			AccessKey: config.GetEnv("S3_ACCESS_KEY", ""),
+			SecretKey: config.GetEnv("S3_SECRET_KEY", ""),
+			UseSSL:    helpers.GetEnvBool("S3_USE_SSL", true),
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+}
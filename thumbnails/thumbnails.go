@@ -0,0 +1,209 @@
+// Package thumbnails generates the previews of the image attachments of contacts, so that
+// the admin list can show screenshots without downloading the full files.
+//
+// The Generator scales the images down in the background once they are uploaded, so that
+// decoding a large image never delays a submission. The previews are stored as JPEG next to
+// the original files, and their keys are recorded on the attachments.
+package thumbnails
+
+import (
+	"api-contact-form/jobs"
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/storage"
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	_ "image/png" // register the PNG decoder
+	"io"
+	"log"
+	"sync"
+)
+
+const (
+	// ContentType is the media type of the previews.
+	ContentType = "image/jpeg"
+	// maxPixels is the size of the largest image decoded, so that a small file declaring
+	// huge dimensions cannot exhaust the memory.
+	maxPixels = 50_000_000
+	// jpegQuality is the quality the previews are encoded with.
+	jpegQuality = 80
+)
+
+// supported lists the media types of the attachments that get a preview.
+var supported = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+}
+
+// Supported reports whether the attachments of contentType get a preview.
+func Supported(contentType string) bool {
+	return supported[contentType]
+}
+
+// Key returns the storage key of the preview of attachment.
+func Key(attachment models.Attachment) string {
+	return attachment.StorageKey + "-thumbnail"
+}
+
+// Generator generates the previews of the attachments queued with Enqueue in the
+// background. A nil Generator is disabled.
+type Generator struct {
+	repository repositories.AttachmentRepository
+	storage    storage.Storage
+	size       int
+
+	queue *jobs.Queue[models.Attachment]
+	wg    sync.WaitGroup
+}
+
+// NewGenerator creates a Generator reading the images from store and scaling them down to
+// fit in a square of size pixels. The previews are stored in store and recorded with
+// repository. At most queueSize attachments wait for their preview.
+func NewGenerator(repository repositories.AttachmentRepository, store storage.Storage, size, queueSize int) *Generator {
+	return &Generator{
+		repository: repository,
+		storage:    store,
+		size:       size,
+		queue:      jobs.NewQueue[models.Attachment]("thumbnails", queueSize),
+	}
+}
+
+// Enqueue queues the generation of the previews of the image attachments without
+// blocking. The attachments must be recorded already. When the queue is full, the
+// preview is not generated and the attachment is logged.
+func (g *Generator) Enqueue(attachments []models.Attachment) {
+	if g == nil {
+		return
+	}
+	for _, attachment := range attachments {
+		if !Supported(attachment.ContentType) {
+			continue
+		}
+		if !g.queue.Push(attachment) {
+			log.Printf("Thumbnail queue full, no preview for attachment %d", attachment.ID)
+		}
+	}
+}
+
+// Start launches the given number of workers generating the queued previews. They stop
+// once ctx is cancelled; attachments still queued at that time get no preview.
+func (g *Generator) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			for {
+				attachment, ok := g.queue.Next(ctx)
+				if !ok {
+					return
+				}
+				if err := g.Generate(ctx, attachment); err != nil {
+					log.Printf("Thumbnail of attachment %d not generated: %v", attachment.ID, err)
+					g.queue.Failed()
+				}
+			}
+		}()
+	}
+}
+
+// Wait blocks until the workers have stopped.
+func (g *Generator) Wait() {
+	g.wg.Wait()
+}
+
+// QueueStats returns the health of the queue of the previews.
+func (g *Generator) QueueStats() jobs.Stats {
+	return g.queue.Stats()
+}
+
+// Generate scales the image of attachment down, stores the preview and records its key.
+// When the attachment was removed in the meantime, the preview is removed again.
+func (g *Generator) Generate(ctx context.Context, attachment models.Attachment) error {
+	reader, err := g.storage.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var preview bytes.Buffer
+	if err := g.render(reader, &preview); err != nil {
+		return err
+	}
+
+	key := Key(attachment)
+	if err := g.storage.Put(ctx, key, &preview, int64(preview.Len()), ContentType); err != nil {
+		return err
+	}
+	recorded, err := g.repository.SetThumbnail(attachment.ID, key)
+	if err == nil && recorded {
+		return nil
+	}
+	if deleteErr := g.storage.Delete(ctx, key); deleteErr != nil {
+		log.Printf("Failed to delete stored thumbnail %s: %v", key, deleteErr)
+	}
+	return err
+}
+
+// render decodes the image read from r and writes its preview to w.
+func (g *Generator) render(r io.Reader, w io.Writer) error {
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return err
+	}
+	if config.Width*config.Height > maxPixels {
+		return fmt.Errorf("image of %dx%d pixels is too large", config.Width, config.Height)
+	}
+	src, _, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return err
+	}
+	return jpeg.Encode(w, scale(src, g.size), &jpeg.Options{Quality: jpegQuality})
+}
+
+// scale returns src fitted in a square of size pixels, keeping its aspect ratio, on a
+// white background replacing its transparent parts. Every pixel of the preview averages
+// the pixels of src it covers. Images smaller than the square are not enlarged.
+func scale(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if longest := max(width, height); longest > size {
+		width = max(1, width*size/longest)
+		height = max(1, height*size/longest)
+	}
+
+	// Flatten the image on white, so that transparent pixels do not turn black in JPEG.
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+	if width == bounds.Dx() && height == bounds.Dy() {
+		return flat
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*bounds.Dy()/height, max((y+1)*bounds.Dy()/height, y*bounds.Dy()/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*bounds.Dx()/width, max((x+1)*bounds.Dx()/width, x*bounds.Dx()/width+1)
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					offset := flat.PixOffset(sx, sy)
+					r += int(flat.Pix[offset])
+					g += int(flat.Pix[offset+1])
+					b += int(flat.Pix[offset+2])
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 0xff})
+		}
+	}
+	return dst
+}
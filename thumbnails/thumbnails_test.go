@@ -0,0 +1,226 @@
+package thumbnails_test
+
+import (
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"api-contact-form/storage"
+	"api-contact-form/thumbnails"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// fixture holds a Generator with its database and storage.
+type fixture struct {
+	db        *gorm.DB
+	store     storage.Storage
+	generator *thumbnails.Generator
+}
+
+// newFixture creates a Generator of 320 pixel previews on an empty database and storage.
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "thumbnails.db")), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Attachment{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	return fixture{db: db, store: store, generator: thumbnails.NewGenerator(repositories.NewAttachmentRepository(db), store, 320, 10)}
+}
+
+// attach stores content and records it as an attachment of contentType.
+func (f fixture) attach(t *testing.T, key, contentType string, content []byte) models.Attachment {
+	t.Helper()
+	if err := f.store.Put(context.Background(), key, bytes.NewReader(content), int64(len(content)), contentType); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	attachment := models.Attachment{ContactID: 1, Filename: key, ContentType: contentType, Size: int64(len(content)), StorageKey: key}
+	if err := f.db.Create(&attachment).Error; err != nil {
+		t.Fatalf("create attachment: %v", err)
+	}
+	return attachment
+}
+
+// thumbnailKey returns the recorded thumbnail key of the attachment.
+func (f fixture) thumbnailKey(t *testing.T, id uint) string {
+	t.Helper()
+	var attachment models.Attachment
+	if err := f.db.First(&attachment, id).Error; err != nil {
+		t.Fatalf("find attachment: %v", err)
+	}
+	return attachment.ThumbnailKey
+}
+
+// preview decodes the stored preview of the attachment.
+func (f fixture) preview(t *testing.T, attachment models.Attachment) image.Image {
+	t.Helper()
+	reader, err := f.store.Open(context.Background(), thumbnails.Key(attachment))
+	if err != nil {
+		t.Fatalf("open preview: %v", err)
+	}
+	defer reader.Close()
+	img, err := jpeg.Decode(reader)
+	if err != nil {
+		t.Fatalf("decode preview: %v", err)
+	}
+	return img
+}
+
+// encodePNG returns a PNG image of width by height pixels, red on the left half and
+// transparent on the right half.
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width/2; x++ {
+			img.Set(x, y, color.NRGBA{R: 0xff, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestGenerate(t *testing.T) {
+	f := newFixture(t)
+	attachment := f.attach(t, "attachments/wide", "image/png", encodePNG(t, 800, 400))
+
+	if err := f.generator.Generate(context.Background(), attachment); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	if got := f.thumbnailKey(t, attachment.ID); got != thumbnails.Key(attachment) {
+		t.Errorf("ThumbnailKey = %q, want %q", got, thumbnails.Key(attachment))
+	}
+	preview := f.preview(t, attachment)
+	if size := preview.Bounds().Size(); size != image.Pt(320, 160) {
+		t.Errorf("preview size = %v, want 320x160", size)
+	}
+	// The transparent half turns white rather than black.
+	for _, pt := range []struct {
+		x, y    int
+		r, g, b uint32
+	}{{40, 80, 0xff, 0, 0}, {280, 80, 0xff, 0xff, 0xff}} {
+		r, g, b, _ := preview.At(pt.x, pt.y).RGBA()
+		if diff(r>>8, pt.r) > 16 || diff(g>>8, pt.g) > 16 || diff(b>>8, pt.b) > 16 {
+			t.Errorf("pixel (%d, %d) = #%02x%02x%02x, want #%02x%02x%02x", pt.x, pt.y, r>>8, g>>8, b>>8, pt.r, pt.g, pt.b)
+		}
+	}
+}
+
+// diff returns the distance between two color components.
+func diff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func TestGenerateKeepsSmallImages(t *testing.T) {
+	f := newFixture(t)
+	attachment := f.attach(t, "attachments/small", "image/png", encodePNG(t, 100, 50))
+
+	if err := f.generator.Generate(context.Background(), attachment); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if size := f.preview(t, attachment).Bounds().Size(); size != image.Pt(100, 50) {
+		t.Errorf("preview size = %v, want 100x50", size)
+	}
+}
+
+func TestGenerateRejectsInvalidImages(t *testing.T) {
+	// A PNG declaring 10000x10000 pixels, which is never decoded.
+	huge := encodePNG(t, 1, 1)
+	binary.BigEndian.PutUint32(huge[16:], 10000)
+	binary.BigEndian.PutUint32(huge[20:], 10000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+
+	for name, content := range map[string][]byte{
+		"image too large": huge,
+		"corrupt image":   []byte("\x89PNG\r\n\x1a\nnot really"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			attachment := f.attach(t, "attachments/invalid", "image/png", content)
+
+			if err := f.generator.Generate(context.Background(), attachment); err == nil {
+				t.Fatal("Generate succeeded, want an error")
+			}
+			if got := f.thumbnailKey(t, attachment.ID); got != "" {
+				t.Errorf("ThumbnailKey = %q, want none", got)
+			}
+			if _, err := f.store.Open(context.Background(), thumbnails.Key(attachment)); !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("open preview = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestGenerateRemovedAttachment(t *testing.T) {
+	f := newFixture(t)
+	attachment := f.attach(t, "attachments/removed", "image/png", encodePNG(t, 10, 10))
+	if err := f.db.Delete(&attachment).Error; err != nil {
+		t.Fatalf("delete attachment: %v", err)
+	}
+
+	if err := f.generator.Generate(context.Background(), attachment); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, err := f.store.Open(context.Background(), thumbnails.Key(attachment)); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("open preview = %v, want the preview removed", err)
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	f := newFixture(t)
+	screenshot := f.attach(t, "attachments/screenshot", "image/png", encodePNG(t, 10, 10))
+	document := f.attach(t, "attachments/document", "application/pdf", []byte("%PDF-1.4"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.generator.Start(ctx, 1)
+	f.generator.Enqueue([]models.Attachment{document, screenshot})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for f.thumbnailKey(t, screenshot.ID) == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("preview of the image not generated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	f.generator.Wait()
+
+	if got := f.thumbnailKey(t, document.ID); got != "" {
+		t.Errorf("ThumbnailKey of a PDF = %q, want none", got)
+	}
+}
+
+func TestNilGenerator(t *testing.T) {
+	var generator *thumbnails.Generator
+	generator.Enqueue([]models.Attachment{{ContentType: "image/png"}})
+}
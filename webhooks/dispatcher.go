@@ -0,0 +1,283 @@
+// Package webhooks delivers contact lifecycle events to the URLs registered by admins.
+//
+// Events are published by the contact service and delivered in the background: every
+// active subscription interested in an event receives a signed JSON payload, failed
+// deliveries are retried with exponential backoff, and every attempt is recorded in
+// the delivery log. Published events are kept in an outbox, from which they can be
+// replayed to a subscription.
+package webhooks
+
+import (
+	"api-contact-form/jobs"
+	"api-contact-form/models"
+	"api-contact-form/repositories"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Payload is the JSON body POSTed to webhook URLs.
+type Payload struct {
+	// ID identifies the event. It is the same for every attempt and every subscription,
+	// so that receivers can ignore events they already processed.
+	ID string `json:"id"`
+	// Event is the type of the event.
+	Event models.WebhookEvent `json:"event"`
+	// OccurredAt is the time of the event.
+	OccurredAt time.Time `json:"occurred_at"`
+	// Data is the contact the event is about, as it was after the event.
+	Data models.Contact `json:"data"`
+	// PreviousStatus is the status the contact changed from, for contact.status_changed.
+	PreviousStatus models.Status `json:"previous_status,omitempty"`
+}
+
+// job is an event waiting to be delivered. Without a subscription, the job is
+// expanded into one job per interested subscription.
+type job struct {
+	payload      Payload
+	body         []byte
+	subscription *models.WebhookSubscription
+	// replay is true for events sent again from the outbox.
+	replay bool
+}
+
+// Dispatcher queues events and delivers them to the webhook subscriptions in the background.
+type Dispatcher struct {
+	repository  repositories.WebhookRepository
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+
+	queue *jobs.Queue[job]
+	wg    sync.WaitGroup
+}
+
+// NewDispatcher creates a Dispatcher delivering to the subscriptions of repository. Each
+// delivery is attempted up to maxAttempts times, waiting backoff before the first retry
+// and doubling the wait after every failure, and each attempt times out after timeout.
+// At most queueSize deliveries wait to be sent.
+func NewDispatcher(repository repositories.WebhookRepository, maxAttempts int, backoff, timeout time.Duration, queueSize int) *Dispatcher {
+	return &Dispatcher{
+		repository:  repository,
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		queue:       jobs.NewQueue[job]("webhooks", queueSize),
+	}
+}
+
+// SetTransport replaces the transport sending the deliveries, e.g. to simulate
+// unresponsive receivers. It must be called before Start.
+func (d *Dispatcher) SetTransport(transport http.RoundTripper) {
+	d.client.Transport = transport
+}
+
+// Start launches the given number of workers delivering queued events. They stop
+// once ctx is cancelled; events still queued at that time are dropped.
+func (d *Dispatcher) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for {
+				next, ok := d.queue.Next(ctx)
+				if !ok {
+					return
+				}
+				if next.subscription == nil {
+					d.expand(next)
+				} else {
+					d.deliver(ctx, next)
+				}
+			}
+		}()
+	}
+}
+
+// Wait blocks until the workers have stopped.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// QueueStats returns the health of the queue of the events and deliveries.
+func (d *Dispatcher) QueueStats() jobs.Stats {
+	return d.queue.Stats()
+}
+
+// Publish stores event about contact in the outbox and queues it without blocking. When
+// the queue is full the event is dropped and logged; it can still be replayed from the
+// outbox. A nil Dispatcher does nothing.
+func (d *Dispatcher) Publish(event models.WebhookEvent, contact models.Contact) {
+	if d == nil {
+		return
+	}
+	d.publish(Payload{Event: event, Data: contact})
+}
+
+// PublishStatusChange publishes contact.status_changed for contact, whose status changed
+// from previous, like Publish.
+func (d *Dispatcher) PublishStatusChange(contact models.Contact, previous models.Status) {
+	if d == nil {
+		return
+	}
+	d.publish(Payload{Event: models.EventContactStatusChanged, Data: contact, PreviousStatus: previous})
+}
+
+// publish assigns an ID and the current time to payload, stores it in the outbox and
+// queues it.
+func (d *Dispatcher) publish(payload Payload) {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	payload.ID = hex.EncodeToString(id)
+	payload.OccurredAt = time.Now()
+	event, contact := payload.Event, payload.Data
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Webhook event %s for contact %d not encoded: %v", event, contact.ID, err)
+		return
+	}
+	err = d.repository.CreateOutboxEvent(&models.WebhookOutboxEvent{
+		EventID:    payload.ID,
+		Event:      event,
+		ContactID:  contact.ID,
+		Payload:    string(body),
+		OccurredAt: payload.OccurredAt.UTC(),
+	})
+	if err != nil {
+		log.Printf("Webhook event %s for contact %d not stored in the outbox: %v", event, contact.ID, err)
+	}
+	d.enqueue(job{payload: payload, body: body})
+}
+
+// PruneOutbox removes the events older than retention from the outbox every hour, until
+// ctx is cancelled. They can no longer be replayed.
+func (d *Dispatcher) PruneOutbox(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if removed, err := d.repository.DeleteOutboxEventsBefore(time.Now().Add(-retention)); err != nil {
+			log.Printf("Webhook outbox not pruned: %v", err)
+		} else if removed > 0 {
+			log.Printf("Pruned %d events from the webhook outbox", removed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueue queues a job without blocking, dropping it when the queue is full.
+func (d *Dispatcher) enqueue(next job) {
+	if !d.queue.Push(next) {
+		log.Printf("Webhook queue full, dropping %s event for contact %d", next.payload.Event, next.payload.Data.ID)
+	}
+}
+
+// expand queues a delivery of the event of next for every active subscription interested in it.
+func (d *Dispatcher) expand(next job) {
+	subscriptions, err := d.repository.FindActiveSubscriptions()
+	if err != nil {
+		log.Printf("Webhook subscriptions not loaded, dropping %s event for contact %d: %v", next.payload.Event, next.payload.Data.ID, err)
+		d.queue.Failed()
+		return
+	}
+	for i := range subscriptions {
+		if subscriptions[i].Subscribes(next.payload.Event) {
+			d.enqueue(job{payload: next.payload, body: next.body, subscription: &subscriptions[i]})
+		}
+	}
+}
+
+// deliver sends an event to a subscription, retrying with exponential backoff.
+// Every attempt is recorded in the delivery log.
+func (d *Dispatcher) deliver(ctx context.Context, next job) {
+	wait := d.backoff
+	for attempt := 1; ; attempt++ {
+		err := d.send(ctx, next, attempt)
+		if err == nil {
+			return
+		}
+		if attempt >= d.maxAttempts {
+			log.Printf("Webhook %d %s event %s failed after %d attempts: %v", next.subscription.ID, next.payload.Event, next.payload.ID, attempt, err)
+			d.queue.Failed()
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// send POSTs the payload of next to its subscription once and records the attempt.
+func (d *Dispatcher) send(ctx context.Context, next job, attempt int) error {
+	record := &models.WebhookDelivery{
+		SubscriptionID: next.subscription.ID,
+		EventID:        next.payload.ID,
+		Event:          next.payload.Event,
+		ContactID:      next.payload.Data.ID,
+		Attempt:        attempt,
+	}
+
+	start := time.Now()
+	err := d.post(ctx, next, record)
+	record.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		record.Error = err.Error()
+	} else {
+		record.Success = true
+	}
+
+	if logErr := d.repository.CreateDelivery(record); logErr != nil {
+		log.Printf("Webhook delivery of event %s not recorded: %v", next.payload.ID, logErr)
+	}
+	return err
+}
+
+// post sends the signed request and stores the status code of the response in record.
+func (d *Dispatcher) post(ctx context.Context, next job, record *models.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, next.subscription.URL, bytes.NewReader(next.body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "api-contact-form-webhooks")
+	req.Header.Set("X-Webhook-ID", next.payload.ID)
+	req.Header.Set("X-Webhook-Event", string(next.payload.Event))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(next.subscription.Secret, timestamp, next.body))
+	if next.replay {
+		req.Header.Set("X-Webhook-Replay", "true")
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	record.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+// Package webhooks delivers contact lifecycle events to the URLs registered by admins.
+//
+// This file implements the Replayer, which sends the events of a period again from the
+// outbox to a subscription, such as after its receiver was down for a day.
+package webhooks
+
+import (
+	"api-contact-form/ids"
+	"api-contact-form/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ReplayState is the stage of a replay.
+type ReplayState string
+
+const (
+	// ReplayQueued is used while a replay waits for the one before it to finish.
+	ReplayQueued ReplayState = "queued"
+	// ReplayRunning is used while the events are sent.
+	ReplayRunning ReplayState = "running"
+	// ReplayCompleted is used once every event was sent, successfully or not.
+	ReplayCompleted ReplayState = "completed"
+	// ReplayCancelled is used for replays cancelled by an admin or by the shutdown.
+	ReplayCancelled ReplayState = "cancelled"
+	// ReplayFailed is used for replays stopped by errors.
+	ReplayFailed ReplayState = "failed"
+)
+
+// Finished reports whether the replay stopped.
+func (s ReplayState) Finished() bool {
+	return s == ReplayCompleted || s == ReplayCancelled || s == ReplayFailed
+}
+
+const (
+	// replayBatchSize is the number of events read from the outbox at once.
+	replayBatchSize = 100
+	// replayMaxConsecutiveFailures is the number of failed sends in a row after which a
+	// replay gives up, as the receiver is most likely down again.
+	replayMaxConsecutiveFailures = 10
+	// replayQueueSize is the number of replays that may wait for the running one.
+	replayQueueSize = 16
+)
+
+// ErrReplayInProgress is returned by Start while a replay of the subscription is queued
+// or running.
+var ErrReplayInProgress = errors.New("a replay of this webhook is already in progress")
+
+// ErrReplayQueueFull is returned by Start when too many replays are waiting.
+var ErrReplayQueueFull = errors.New("too many replays are waiting, try again later")
+
+// ReplayProgress reports the progress of the replay of a subscription.
+type ReplayProgress struct {
+	SubscriptionID ids.ID      `json:"subscription_id"`
+	From           time.Time   `json:"from"`
+	To             time.Time   `json:"to"`
+	State          ReplayState `json:"state"`
+	// Total is the number of events of the period the subscription is interested in.
+	Total int64 `json:"total"`
+	// Sent and Failed are the number of events delivered and not delivered so far. Events
+	// are sent once each; the failures are listed in the delivery log.
+	Sent   int `json:"sent"`
+	Failed int `json:"failed"`
+	// Error describes why a failed replay stopped.
+	Error      string     `json:"error,omitempty"`
+	QueuedAt   time.Time  `json:"queued_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// replay is a replay requested for a subscription.
+type replay struct {
+	subscription models.WebhookSubscription
+	progress     ReplayProgress
+	cancelled    bool
+	cancel       context.CancelFunc
+}
+
+// Replayer sends the events of the outbox again to subscriptions, one replay at a time,
+// at a limited rate, so that receivers catching up are not overwhelmed. Only the last
+// replay of each subscription is remembered, in memory. It is safe for concurrent use.
+type Replayer struct {
+	dispatcher *Dispatcher
+	interval   time.Duration
+
+	mu      sync.Mutex
+	replays map[uint]*replay
+	queue   chan *replay
+}
+
+// NewReplayer creates a Replayer sending at most rate events per second through dispatcher.
+func NewReplayer(dispatcher *Dispatcher, rate float64) *Replayer {
+	return &Replayer{
+		dispatcher: dispatcher,
+		interval:   time.Duration(float64(time.Second) / rate),
+		replays:    make(map[uint]*replay),
+		queue:      make(chan *replay, replayQueueSize),
+	}
+}
+
+// Start queues the replay of the events that occurred within [from, to) to subscription,
+// limited to the events it subscribes to, and returns its progress. Events are replayed
+// with their original ID and payload, so that receivers ignore the ones they processed.
+func (r *Replayer) Start(subscription models.WebhookSubscription, from, to time.Time) (ReplayProgress, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if previous := r.replays[subscription.ID]; previous != nil && !previous.progress.State.Finished() {
+		return previous.progress, ErrReplayInProgress
+	}
+
+	next := &replay{
+		subscription: subscription,
+		progress: ReplayProgress{
+			SubscriptionID: ids.ID(subscription.ID),
+			From:           from,
+			To:             to,
+			State:          ReplayQueued,
+			QueuedAt:       time.Now(),
+		},
+	}
+	select {
+	case r.queue <- next:
+	default:
+		return ReplayProgress{}, ErrReplayQueueFull
+	}
+	r.replays[subscription.ID] = next
+	return next.progress, nil
+}
+
+// Progress returns the progress of the last replay of a subscription, and false when
+// it was never replayed.
+func (r *Replayer) Progress(subscriptionID uint) (ReplayProgress, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.replays[subscriptionID]
+	if current == nil {
+		return ReplayProgress{}, false
+	}
+	return current.progress, true
+}
+
+// Cancel stops the queued or running replay of a subscription, and returns its progress.
+// It returns false when the subscription has no replay in progress.
+func (r *Replayer) Cancel(subscriptionID uint) (ReplayProgress, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.replays[subscriptionID]
+	if current == nil || current.progress.State.Finished() {
+		return ReplayProgress{}, false
+	}
+	current.cancelled = true
+	if current.cancel != nil {
+		current.cancel()
+	}
+	r.finish(current, ReplayCancelled, "")
+	return current.progress, true
+}
+
+// Forget drops the replay of a deleted subscription, cancelling it when in progress.
+func (r *Replayer) Forget(subscriptionID uint) {
+	r.Cancel(subscriptionID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.replays, subscriptionID)
+}
+
+// Run runs the queued replays one after the other until ctx is cancelled.
+func (r *Replayer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case next := <-r.queue:
+			r.run(ctx, next)
+		}
+	}
+}
+
+// run sends the events of a replay, unless it was cancelled while queued.
+func (r *Replayer) run(ctx context.Context, current *replay) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r.mu.Lock()
+	if current.cancelled {
+		r.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	current.cancel = cancel
+	current.progress.State = ReplayRunning
+	current.progress.StartedAt = &now
+	r.mu.Unlock()
+
+	state, err := r.send(ctx, current)
+	message := ""
+	if err != nil {
+		message = err.Error()
+		log.Printf("Replay of webhook %d stopped: %v", current.subscription.ID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if current.cancelled {
+		return
+	}
+	if state == ReplayFailed && ctx.Err() != nil {
+		state, message = ReplayCancelled, ""
+	}
+	r.finish(current, state, message)
+}
+
+// send sends the events of a replay at the rate of the Replayer and returns the state it
+// finished in.
+func (r *Replayer) send(ctx context.Context, current *replay) (ReplayState, error) {
+	repository := r.dispatcher.repository
+	subscription := &current.subscription
+	events := subscription.EventList()
+	from, to := current.progress.From, current.progress.To
+
+	total, err := repository.CountOutboxEvents(from, to, events)
+	if err != nil {
+		return ReplayFailed, err
+	}
+	r.update(current, func(progress *ReplayProgress) { progress.Total = total })
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	var afterID uint
+	consecutiveFailures := 0
+	for {
+		batch, err := repository.FindOutboxEvents(from, to, events, afterID, replayBatchSize)
+		if err != nil {
+			return ReplayFailed, err
+		}
+		if len(batch) == 0 {
+			return ReplayCompleted, nil
+		}
+
+		for _, event := range batch {
+			select {
+			case <-ctx.Done():
+				return ReplayCancelled, nil
+			case <-ticker.C:
+			}
+
+			err := r.dispatcher.send(ctx, job{
+				payload: Payload{
+					ID:         event.EventID,
+					Event:      event.Event,
+					OccurredAt: event.OccurredAt,
+					Data:       models.Contact{ID: event.ContactID},
+				},
+				body:         []byte(event.Payload),
+				subscription: subscription,
+				replay:       true,
+			}, 1)
+			if err != nil {
+				consecutiveFailures++
+				r.update(current, func(progress *ReplayProgress) { progress.Failed++ })
+				if consecutiveFailures >= replayMaxConsecutiveFailures {
+					return ReplayFailed, fmt.Errorf("%d deliveries failed in a row, last: %w", consecutiveFailures, err)
+				}
+			} else {
+				consecutiveFailures = 0
+				r.update(current, func(progress *ReplayProgress) { progress.Sent++ })
+			}
+			afterID = event.ID
+		}
+	}
+}
+
+// update changes the progress of a replay.
+func (r *Replayer) update(current *replay, change func(progress *ReplayProgress)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	change(&current.progress)
+}
+
+// finish records that a replay stopped in the given state. r.mu must be held.
+func (r *Replayer) finish(current *replay, state ReplayState, message string) {
+	now := time.Now()
+	current.progress.State = state
+	current.progress.Error = message
+	current.progress.FinishedAt = &now
+}
@@ -0,0 +1,32 @@
+// Package webhooks delivers contact lifecycle events to the URLs registered by admins.
+//
+// This file implements the payload signatures. Receivers verify a delivery by computing
+// the HMAC-SHA256 of the X-Webhook-Timestamp header, a dot and the raw body, keyed with
+// the secret of the subscription, and comparing it with the X-Webhook-Signature header
+// ("sha256=" followed by the hex digest). Rejecting old timestamps prevents replays.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Sign returns the hex HMAC-SHA256 of timestamp, a dot and body, keyed with secret.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a random signing secret for a subscription.
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
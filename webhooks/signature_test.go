@@ -0,0 +1,78 @@
+package webhooks
+
+import (
+	"api-contact-form/models"
+	"context"
+	"crypto/hmac"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The vector was computed independently with:
+//
+//	printf '%s' '1700000000.{"id":"evt_1","event":"contact.created"}' | openssl dgst -sha256 -hmac whsec_test
+const (
+	vectorSecret    = "whsec_test"
+	vectorTimestamp = "1700000000"
+	vectorBody      = `{"id":"evt_1","event":"contact.created"}`
+	vectorSignature = "d66dc07ff8b0d811e484a7144f616e8c90107113418fd11942fc618c728a7144"
+)
+
+func TestSign(t *testing.T) {
+	if got := Sign(vectorSecret, vectorTimestamp, []byte(vectorBody)); got != vectorSignature {
+		t.Errorf("Sign = %s, want %s", got, vectorSignature)
+	}
+
+	for name, got := range map[string]string{
+		"other secret":    Sign("whsec_other", vectorTimestamp, []byte(vectorBody)),
+		"other timestamp": Sign(vectorSecret, "1700000001", []byte(vectorBody)),
+		"other body":      Sign(vectorSecret, vectorTimestamp, []byte(vectorBody+" ")),
+	} {
+		if got == vectorSignature {
+			t.Errorf("Sign with the %s = the signature of the vector", name)
+		}
+	}
+}
+
+func TestDeliverySignature(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher(nil, 1, time.Second, time.Second, 1)
+	next := job{
+		payload:      Payload{ID: "evt_1", Event: models.EventContactCreated},
+		body:         []byte(vectorBody),
+		subscription: &models.WebhookSubscription{URL: receiver.URL, Secret: vectorSecret},
+	}
+	if err := d.post(context.Background(), next, &models.WebhookDelivery{}); err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	r := <-received
+
+	// Verify the delivery as a receiver would.
+	timestamp := r.Header.Get("X-Webhook-Timestamp")
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+		t.Errorf("X-Webhook-Timestamp = %q, want the current Unix time", timestamp)
+	}
+	signature, ok := strings.CutPrefix(r.Header.Get("X-Webhook-Signature"), "sha256=")
+	if !ok {
+		t.Fatalf("X-Webhook-Signature = %q, want a sha256= prefix", r.Header.Get("X-Webhook-Signature"))
+	}
+	if want := Sign(vectorSecret, timestamp, body); !hmac.Equal([]byte(signature), []byte(want)) {
+		t.Errorf("X-Webhook-Signature = %s, want %s", signature, want)
+	}
+	if string(body) != vectorBody {
+		t.Errorf("body = %s, want %s", body, vectorBody)
+	}
+}